@@ -42,6 +42,7 @@ import (
 
 	"github.com/splunk/splunk-operator/pkg/apis"
 	"github.com/splunk/splunk-operator/pkg/controller"
+	"github.com/splunk/splunk-operator/pkg/webhook"
 	"github.com/splunk/splunk-operator/version"
 )
 
@@ -51,6 +52,10 @@ var (
 	metricsPort         int32 = 8383
 	operatorMetricsPort int32 = 8686
 )
+
+// Change below variable to serve the admission webhooks on a different port.
+var webhookPort = 9443
+
 var log = logf.Log.WithName("cmd")
 
 func printVersion() {
@@ -112,6 +117,8 @@ func main() {
 	mgr, err := manager.New(cfg, manager.Options{
 		Namespace:          namespace,
 		MetricsBindAddress: fmt.Sprintf("%s:%d", metricsHost, metricsPort),
+		Port:               webhookPort,
+		CertDir:            os.Getenv("WEBHOOK_CERT_DIR"),
 	})
 	if err != nil {
 		log.Error(err, "")
@@ -132,6 +139,19 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Setup all Webhooks, which require a serving certificate, and rewrite the custom resources stored
+	// using older API versions, which the conversion webhook serves
+	if os.Getenv("ENABLE_WEBHOOKS") == "true" {
+		if err := webhook.AddToManager(mgr); err != nil {
+			log.Error(err, "")
+			os.Exit(1)
+		}
+		if err := controller.AddMigrationToManager(mgr, namespace); err != nil {
+			log.Error(err, "")
+			os.Exit(1)
+		}
+	}
+
 	// Add the Metrics Service
 	addMetrics(ctx, cfg)
 
@@ -0,0 +1,195 @@
+// Copyright (c) 2018-2021 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apis
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	enterpriseApi "github.com/splunk/splunk-operator/pkg/apis/enterprise/v2"
+	splcommon "github.com/splunk/splunk-operator/pkg/splunk/common"
+)
+
+// newTestCommonSplunkSpec returns a CommonSplunkSpec with most parameters set
+func newTestCommonSplunkSpec() enterpriseApi.CommonSplunkSpec {
+	spec := enterpriseApi.CommonSplunkSpec{
+		Spec: splcommon.Spec{
+			Image:           "splunk/splunk:latest",
+			ImagePullPolicy: "Always",
+			SchedulerName:   "custom-scheduler",
+		},
+		EtcVolumeStorageConfig:       enterpriseApi.StorageClassSpec{StorageClassName: "gp2", StorageCapacity: "15Gi"},
+		VarVolumeStorageConfig:       enterpriseApi.StorageClassSpec{EphemeralStorage: true},
+		Volumes:                      []corev1.Volume{{Name: "licenses", VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{}}}},
+		Defaults:                     "splunk:\n  hec_disabled: 0",
+		LicenseURL:                   "/mnt/licenses/enterprise.lic",
+		LicenseMasterRef:             corev1.ObjectReference{Name: "stack1"},
+		ClusterMasterRef:             corev1.ObjectReference{Name: "stack1"},
+		ServiceAccount:               "splunk",
+		ExtraEnv:                     []corev1.EnvVar{{Name: "SPLUNK_DEBUG", Value: "true"}},
+		ReadinessInitialDelaySeconds: 20,
+		LivenessInitialDelaySeconds:  400,
+	}
+	spec.Tolerations = []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "splunk"}}
+	return spec
+}
+
+// newTestHubObjects returns a custom resource of each kind of the hub version, with most parameters set
+func newTestHubObjects() []conversion.Hub {
+	meta := metav1.ObjectMeta{
+		Name:        "stack1",
+		Namespace:   "test",
+		Generation:  3,
+		Labels:      map[string]string{"app": "splunk"},
+		Annotations: map[string]string{"note": "conversion"},
+		Finalizers:  []string{"enterprise.splunk.com/delete-pvc"},
+	}
+	conditions := []splcommon.Condition{{Type: splcommon.ConditionReady, Status: corev1.ConditionTrue, ObservedGeneration: 3, Reason: "Ready"}}
+	smartstore := enterpriseApi.SmartStoreSpec{
+		VolList:   []enterpriseApi.VolumeSpec{{Name: "msos_s2s3_vol", Endpoint: "https://s3-eu-west-2.amazonaws.com", Path: "testbucket-rs-london", SecretRef: "s3-secret"}},
+		IndexList: []enterpriseApi.IndexSpec{{Name: "salesdata", RemotePath: "remotepath"}},
+	}
+	appFramework := enterpriseApi.AppFrameworkSpec{
+		VolList:              []enterpriseApi.VolumeSpec{{Name: "apps_vol", Endpoint: "https://s3-eu-west-2.amazonaws.com", Path: "apps", Type: "s3", Provider: "aws"}},
+		AppSources:           []enterpriseApi.AppSourceSpec{{Name: "adminApps", Location: "adminAppsRepo", AppSourceDefaultSpec: enterpriseApi.AppSourceDefaultSpec{VolName: "apps_vol", Scope: "local"}}},
+		AppsRepoPollInterval: 120,
+	}
+
+	standalone := &enterpriseApi.Standalone{ObjectMeta: *meta.DeepCopy()}
+	standalone.Spec.CommonSplunkSpec = newTestCommonSplunkSpec()
+	standalone.Spec.Replicas = 2
+	standalone.Spec.SmartStore = smartstore
+	standalone.Spec.AppFrameworkConfig = appFramework
+	standalone.Status.Phase = splcommon.PhaseReady
+	standalone.Status.Replicas = 2
+	standalone.Status.ResourceRevMap = map[string]string{"s3-secret": "1"}
+	standalone.Status.Conditions = conditions
+
+	lm := &enterpriseApi.LicenseMaster{ObjectMeta: *meta.DeepCopy()}
+	lm.Spec.CommonSplunkSpec = newTestCommonSplunkSpec()
+	lm.Spec.AppFrameworkConfig = appFramework
+	lm.Status.Phase = splcommon.PhaseReady
+	lm.Status.Conditions = conditions
+
+	shc := &enterpriseApi.SearchHeadCluster{ObjectMeta: *meta.DeepCopy()}
+	shc.Spec.CommonSplunkSpec = newTestCommonSplunkSpec()
+	shc.Spec.Replicas = 3
+	shc.Spec.AppFrameworkConfig = appFramework
+	shc.Status.Phase = splcommon.PhaseScalingUp
+	shc.Status.Members = []enterpriseApi.SearchHeadClusterMemberStatus{{Name: "splunk-stack1-search-head-0", Status: "Up"}}
+	shc.Status.Conditions = conditions
+
+	cm := &enterpriseApi.ClusterMaster{ObjectMeta: *meta.DeepCopy()}
+	cm.Spec.CommonSplunkSpec = newTestCommonSplunkSpec()
+	cm.Spec.SmartStore = smartstore
+	cm.Spec.AppFrameworkConfig = appFramework
+	cm.Spec.Multisite = enterpriseApi.MultisiteSpec{
+		Site:                  "site1",
+		AvailableSites:        []string{"site1", "site2"},
+		SiteReplicationFactor: enterpriseApi.SiteFactorSpec{Origin: 2, Total: 3},
+		SiteSearchFactor:      enterpriseApi.SiteFactorSpec{Origin: 1, Total: 2},
+	}
+	cm.Status.Phase = splcommon.PhaseReady
+	cm.Status.BundlePushTracker.NeedToPushMasterApps = true
+	cm.Status.Conditions = conditions
+
+	idxc := &enterpriseApi.IndexerCluster{ObjectMeta: *meta.DeepCopy()}
+	idxc.Spec.CommonSplunkSpec = newTestCommonSplunkSpec()
+	idxc.Spec.Replicas = 3
+	idxc.Spec.Site = "site2"
+	idxc.Spec.Zone = "us-west-2a"
+	idxc.Status.Phase = splcommon.PhaseUpdating
+	idxc.Status.Peers = []enterpriseApi.IndexerClusterMemberStatus{{Name: "splunk-stack1-indexer-0", Status: "Up", BucketCount: 12}}
+	idxc.Status.IdxcPasswordChangedSecrets = map[string]bool{"splunk-test-secret": true}
+	idxc.Status.Conditions = conditions
+
+	return []conversion.Hub{standalone, lm, shc, cm, idxc}
+}
+
+func TestConversionRoundTrip(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme() returned %v", err)
+	}
+
+	for _, version := range []string{"v1alpha2", "v1alpha3", "v1beta1", "v1"} {
+		for _, hub := range newTestHubObjects() {
+			kind := reflect.TypeOf(hub).Elem().Name()
+			method := "Convert(" + version + "/" + kind + ")"
+			gvk := schema.GroupVersionKind{Group: enterpriseApi.SchemeGroupVersion.Group, Version: version, Kind: kind}
+			obj, err := scheme.New(gvk)
+			if err != nil {
+				t.Errorf("%s: %v", method, err)
+				continue
+			}
+			spoke, ok := obj.(conversion.Convertible)
+			if !ok {
+				t.Errorf("%s: %T does not implement conversion.Convertible", method, obj)
+				continue
+			}
+
+			// hub -> spoke -> hub must not lose anything
+			if err := spoke.ConvertFrom(hub); err != nil {
+				t.Errorf("%s: ConvertFrom() returned %v", method, err)
+				continue
+			}
+			got := reflect.New(reflect.TypeOf(hub).Elem()).Interface().(conversion.Hub)
+			if err := spoke.ConvertTo(got); err != nil {
+				t.Errorf("%s: ConvertTo() returned %v", method, err)
+				continue
+			}
+			if !reflect.DeepEqual(got, hub) {
+				t.Errorf("%s: round trip got %v; want %v", method, got, hub)
+			}
+
+			// the spoke is served with the same spec and status as the hub
+			want, _ := json.Marshal(hub)
+			spokeJSON, _ := json.Marshal(spoke)
+			var wantMap, spokeMap map[string]interface{}
+			_ = json.Unmarshal(want, &wantMap)
+			_ = json.Unmarshal(spokeJSON, &spokeMap)
+			for _, field := range []string{"metadata", "spec", "status"} {
+				if !reflect.DeepEqual(spokeMap[field], wantMap[field]) {
+					t.Errorf("%s: %s = %v; want %v", method, field, spokeMap[field], wantMap[field])
+				}
+			}
+		}
+	}
+}
+
+func TestConvertObject(t *testing.T) {
+	hub := newTestHubObjects()[0].(*enterpriseApi.Standalone)
+	got := &enterpriseApi.Standalone{}
+	if err := enterpriseApi.ConvertObject(hub, got); err != nil {
+		t.Errorf("ConvertObject() returned %v", err)
+	}
+
+	// the converted custom resource does not share anything with its source
+	got.Spec.ExtraEnv[0].Value = "false"
+	if hub.Spec.ExtraEnv[0].Value != "true" {
+		t.Errorf("ConvertObject() should have copied the spec")
+	}
+
+	if err := enterpriseApi.ConvertObject(hub, &enterpriseApi.LicenseMaster{}); err == nil {
+		t.Errorf("ConvertObject() should have returned an error for different spec types")
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright (c) 2018-2021 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	enterpriseApi "github.com/splunk/splunk-operator/pkg/apis/enterprise/v2"
+)
+
+// blank assignments to verify that the custom resources of this version implement conversion.Convertible
+var (
+	_ conversion.Convertible = &Standalone{}
+	_ conversion.Convertible = &LicenseMaster{}
+	_ conversion.Convertible = &SearchHeadCluster{}
+	_ conversion.Convertible = &ClusterMaster{}
+	_ conversion.Convertible = &IndexerCluster{}
+)
+
+// ConvertTo converts this Standalone to the hub version (v2)
+func (src *Standalone) ConvertTo(dst conversion.Hub) error {
+	return enterpriseApi.ConvertObject(src, dst)
+}
+
+// ConvertFrom converts from the hub version (v2) to this version
+func (dst *Standalone) ConvertFrom(src conversion.Hub) error {
+	return enterpriseApi.ConvertObject(src, dst)
+}
+
+// ConvertTo converts this LicenseMaster to the hub version (v2)
+func (src *LicenseMaster) ConvertTo(dst conversion.Hub) error {
+	return enterpriseApi.ConvertObject(src, dst)
+}
+
+// ConvertFrom converts from the hub version (v2) to this version
+func (dst *LicenseMaster) ConvertFrom(src conversion.Hub) error {
+	return enterpriseApi.ConvertObject(src, dst)
+}
+
+// ConvertTo converts this SearchHeadCluster to the hub version (v2)
+func (src *SearchHeadCluster) ConvertTo(dst conversion.Hub) error {
+	return enterpriseApi.ConvertObject(src, dst)
+}
+
+// ConvertFrom converts from the hub version (v2) to this version
+func (dst *SearchHeadCluster) ConvertFrom(src conversion.Hub) error {
+	return enterpriseApi.ConvertObject(src, dst)
+}
+
+// ConvertTo converts this ClusterMaster to the hub version (v2)
+func (src *ClusterMaster) ConvertTo(dst conversion.Hub) error {
+	return enterpriseApi.ConvertObject(src, dst)
+}
+
+// ConvertFrom converts from the hub version (v2) to this version
+func (dst *ClusterMaster) ConvertFrom(src conversion.Hub) error {
+	return enterpriseApi.ConvertObject(src, dst)
+}
+
+// ConvertTo converts this IndexerCluster to the hub version (v2)
+func (src *IndexerCluster) ConvertTo(dst conversion.Hub) error {
+	return enterpriseApi.ConvertObject(src, dst)
+}
+
+// ConvertFrom converts from the hub version (v2) to this version
+func (dst *IndexerCluster) ConvertFrom(src conversion.Hub) error {
+	return enterpriseApi.ConvertObject(src, dst)
+}
@@ -36,6 +36,33 @@ type ClusterMasterSpec struct {
 
 	// Splunk Enterprise App repository. Specifies remote App location and scope for Splunk App management
 	AppFrameworkConfig AppFrameworkSpec `json:"appRepo,omitempty"`
+
+	// Multisite indexer cluster configuration. Refer to server.conf.spec [clustering] on docs.splunk.com
+	Multisite MultisiteSpec `json:"multisite,omitempty"`
+}
+
+// MultisiteSpec defines the multisite configuration of an indexer cluster
+type MultisiteSpec struct {
+	// Site of the cluster manager. Defaults to the first entry of availableSites
+	Site string `json:"site,omitempty"`
+
+	// List of all the sites of the indexer cluster (site1..site63). Multisite clustering is enabled when not empty
+	AvailableSites []string `json:"availableSites,omitempty"`
+
+	// Number of copies of raw data kept on the originating site and across all the sites
+	SiteReplicationFactor SiteFactorSpec `json:"siteReplicationFactor,omitempty"`
+
+	// Number of searchable copies kept on the originating site and across all the sites
+	SiteSearchFactor SiteFactorSpec `json:"siteSearchFactor,omitempty"`
+}
+
+// SiteFactorSpec defines a site replication or search factor
+type SiteFactorSpec struct {
+	// Number of copies on the site where the data originates
+	Origin int32 `json:"origin,omitempty"`
+
+	// Total number of copies across all the sites
+	Total int32 `json:"total,omitempty"`
 }
 
 // ClusterMasterStatus defines the observed state of ClusterMaster
@@ -57,6 +84,14 @@ type ClusterMasterStatus struct {
 
 	// App Framework status
 	AppContext AppDeploymentContext `json:"appContext"`
+
+	// progress of the expansion of the PersistentVolumeClaims, while the storage capacity is being increased
+	PvcExpansion *splcommon.PvcExpansionStatus `json:"pvcExpansion,omitempty"`
+
+	// conditions describing the latest observed state of the custom resource
+	// +listType=map
+	// +listMapKey=type
+	Conditions []splcommon.Condition `json:"conditions,omitempty"`
 }
 
 // BundlePushInfo Indicates if bundle push required
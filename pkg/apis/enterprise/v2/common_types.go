@@ -16,6 +16,7 @@ package v2
 
 import (
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 
 	splcommon "github.com/splunk/splunk-operator/pkg/splunk/common"
 )
@@ -50,6 +51,25 @@ const (
 	ScopeLocal                = "local"
 	ScopeCluster              = "cluster"
 	ScopeClusterWithPreConfig = "clusterWithPreConfig"
+	ScopePremiumApps          = "premiumApps"
+)
+
+// Values to represent the type of the premium Apps
+const (
+	PremiumAppsTypeEs = "enterpriseSecurity"
+)
+
+// Values to represent the SSL enablement mode of the Enterprise Security setup
+const (
+	SslEnablementStrict = "strict"
+	SslEnablementAuto   = "auto"
+	SslEnablementIgnore = "ignore"
+)
+
+// Values to represent the App Source delete policy
+const (
+	DeletePolicyDelete = "Delete"
+	DeletePolicyRetain = "Retain"
 )
 
 // AppDeploymentStatus represents the status of an App on the Pod
@@ -61,7 +81,6 @@ const (
 	DeployStatusPending AppDeploymentStatus = iota + 1
 
 	// App update on the Pod is in progress
-	//ToDo: Mostly transient state for Phase-2, more of Phase-3 status
 	DeployStatusInProgress
 
 	// App is update is complete on the Pod
@@ -124,6 +143,33 @@ type CommonSplunkSpec struct {
 	// LivenessInitialDelaySeconds defines initialDelaySeconds(See https://kubernetes.io/docs/tasks/configure-pod-container/configure-liveness-readiness-startup-probes/#define-a-liveness-command) for the Liveness probe
 	// Note: If needed, Operator overrides with a higher value
 	LivenessInitialDelaySeconds int32 `json:"livenessInitialDelaySeconds"`
+
+	// PodDisruptionBudget configures the PodDisruptionBudget created for each StatefulSet of the custom resource
+	PodDisruptionBudget PodDisruptionBudgetSpec `json:"podDisruptionBudget,omitempty"`
+
+	// RolloutStrategy controls how many pods are recycled at the same time when their template is updated, and allows
+	// to update a first set of pods (canary) before approving the update of the other ones.
+	// For indexers, maxUnavailable is limited to the search factor - 1, and for search heads to a minority of the members.
+	RolloutStrategy splcommon.RolloutStrategy `json:"rolloutStrategy,omitempty"`
+
+	// PvcRetentionPolicy defines whether the PersistentVolumeClaims of the pods removed by a scale down are deleted
+	// (Delete, the default), kept to be reused by a later scale up (RetainUntilCRDeleted), or never deleted, even by
+	// the enterprise.splunk.com/delete-pvc finalizer (Retain)
+	PvcRetentionPolicy splcommon.PvcRetentionPolicy `json:"pvcRetentionPolicy,omitempty"`
+}
+
+// PodDisruptionBudgetSpec defines the voluntary disruptions, such as node drains, allowed for the pods of a StatefulSet.
+// By default, up to replication factor - 1 indexers, a minority of the search head cluster members and one pod of the
+// other instance types can be evicted at the same time.
+type PodDisruptionBudgetSpec struct {
+	// If true, no PodDisruptionBudget is created
+	Disabled bool `json:"disabled,omitempty"`
+
+	// Maximum number, or percentage, of pods that can be unavailable after an eviction
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+
+	// Minimum number, or percentage, of pods that must be available after an eviction (can not be used with maxUnavailable)
+	MinAvailable *intstr.IntOrString `json:"minAvailable,omitempty"`
 }
 
 // StorageClassSpec defines storage class configuration
@@ -192,10 +238,10 @@ type VolumeSpec struct {
 	// Secret object name
 	SecretRef string `json:"secretRef"`
 
-	// Remote Storage type. Supported values: s3
+	// Remote Storage type. Supported values: s3, azure-blob (or blob), gcs, pvc, http
 	Type string `json:"storageType"`
 
-	// App Package Remote Store provider. Supported values: aws, minio
+	// App Package Remote Store provider. Supported values: aws, minio (s3), azure (blob), gcp (gcs), kubernetes (pvc), http (http)
 	Provider string `json:"provider"`
 }
 
@@ -212,6 +258,41 @@ type IndexSpec struct {
 	// Index location relative to the remote volume path
 	RemotePath string `json:"remotePath,omitempty"`
 
+	// Local indexes are stored on the local storage of the pods only, without remote volume, even when the
+	// defaults set a volume
+	Local bool `json:"local,omitempty"`
+
+	// Type of the index: event (the default) or metric
+	DataType string `json:"datatype,omitempty"`
+
+	// Disabled indexes keep their buckets but stop indexing and searching. Once the configuration of a disabled
+	// index is applied, the index can be removed from the list without confirmation.
+	Disabled bool `json:"disabled,omitempty"`
+
+	// Age in seconds after which the buckets of the index are frozen, i.e. removed or archived
+	FrozenTimePeriodInSecs uint `json:"frozenTimePeriodInSecs,omitempty"`
+
+	// Maximum size of a hot bucket: auto, auto_high_volume, or a size in MB
+	MaxDataSize string `json:"maxDataSize,omitempty"`
+
+	// Maximum size in MB of a local index, beyond which its oldest buckets are frozen
+	MaxTotalDataSizeMB uint `json:"maxTotalDataSizeMB,omitempty"`
+
+	// Maximum number of warm buckets of a local index, beyond which its oldest warm buckets roll to cold
+	MaxWarmDBCount uint `json:"maxWarmDBCount,omitempty"`
+
+	// Maximum size in MB of the hot and warm buckets of a local index
+	HomePathMaxDataSizeMB uint `json:"homePathMaxDataSizeMB,omitempty"`
+
+	// Maximum size in MB of the cold buckets of a local index
+	ColdPathMaxDataSizeMB uint `json:"coldPathMaxDataSizeMB,omitempty"`
+
+	// Directory where the frozen buckets of the index are archived
+	ColdToFrozenDir string `json:"coldToFrozenDir,omitempty"`
+
+	// Script run to archive the frozen buckets of the index
+	ColdToFrozenScript string `json:"coldToFrozenScript,omitempty"`
+
 	IndexAndCacheManagerCommonSpec `json:",inline"`
 
 	IndexAndGlobalCommonSpec `json:",inline"`
@@ -244,8 +325,25 @@ type AppSourceDefaultSpec struct {
 	// Remote Storage Volume name
 	VolName string `json:"volumeName,omitempty"`
 
-	// Scope of the App deployment: cluster, clusterWithPreConfig, local. Scope determines whether the App(s) is/are installed locally or cluster-wide
+	// Scope of the App deployment: cluster, clusterWithPreConfig, local, premiumApps. Scope determines whether the App(s) is/are installed locally or cluster-wide.
+	// The premiumApps scope installs the App(s) on the standalone instances or on the deployer, then runs their setup.
 	Scope string `json:"scope,omitempty"`
+
+	// Properties of the premium Apps, for the premiumApps scope
+	PremiumAppsProps PremiumAppsProps `json:"premiumAppsProps,omitempty"`
+
+	// Maximum time in seconds of the install of each App, and of the setup of each premium App. It defaults to
+	// 10 minutes for the install, and to 1 hour for the setup.
+	InstallTimeoutSeconds int `json:"installTimeoutSeconds,omitempty"`
+
+	// DeletePolicy defines whether the App(s) deleted from the remote storage are removed from the pods (Delete, the
+	// default), or kept installed (Retain)
+	DeletePolicy string `json:"deletePolicy,omitempty"`
+
+	// Name of the Secret holding the PEM encoded public key, under the public_key key, which verifies the detached
+	// signatures of the App packages. The signature of an App package is the file of the same name with a .sig
+	// extension in its location. The signatures are not verified when it is empty.
+	SignatureSecretRef string `json:"signatureSecretRef,omitempty"`
 }
 
 // AppSourceSpec defines list of App package (*.spl, *.tgz) locations on remote volumes
@@ -256,9 +354,45 @@ type AppSourceSpec struct {
 	// Location relative to the volume path
 	Location string `json:"location"`
 
+	// Apps of the location to deploy, optionally pinned to a version of their package. When set, the other Apps of
+	// the location are ignored.
+	Apps []AppVersionSpec `json:"apps,omitempty"`
+
+	// Names of the App sources whose Apps must all be installed before the Apps of this App source
+	DependsOn []string `json:"dependsOn,omitempty"`
+
 	AppSourceDefaultSpec `json:",inline"`
 }
 
+// AppVersionSpec selects an App of an App source, optionally pinned to a version of its package
+type AppVersionSpec struct {
+	// Name of the App package in the location of the App source (e.g. app1.tgz)
+	Name string `json:"name"`
+
+	// Object hash of the version of the App package to deploy, as reported in the App context of the status: the
+	// ETag of the S3, Azure Blob and GCS objects, or the sha256 of the files of HTTP(S) and PersistentVolumeClaim
+	// volumes. The latest version of the App package is deployed when it is empty.
+	ObjectHash string `json:"objectHash,omitempty"`
+}
+
+// PremiumAppsProps defines the setup of the premium Apps
+type PremiumAppsProps struct {
+	// Type of the premium Apps: enterpriseSecurity
+	Type string `json:"type,omitempty"`
+
+	// Setup of Enterprise Security
+	EsDefaults EsDefaults `json:"esDefaults,omitempty"`
+}
+
+// EsDefaults defines the setup of Enterprise Security
+type EsDefaults struct {
+	// SSL enablement mode of the essinstall setup command:
+	//   strict: the setup fails unless SSL is enabled in web.conf. This is the default.
+	//   auto: the setup enables SSL in etc/system/local/web.conf
+	//   ignore: the setup ignores whether SSL is enabled
+	SslEnablement string `json:"sslEnablement,omitempty"`
+}
+
 // AppFrameworkSpec defines the application package remote store repository
 type AppFrameworkSpec struct {
 	// Defines the default configuration settings for App sources
@@ -288,11 +422,34 @@ type AppDeploymentInfo struct {
 	Size             uint64              `json:"Size,omitempty"`
 	RepoState        AppRepoState        `json:"repoState"`
 	DeployStatus     AppDeploymentStatus `json:"deployStatus"`
+
+	// Deployment status of the App on each Pod, by Pod name. Apps with cluster scope are only tracked on the
+	// cluster manager or on the deployer, which push them to the peers or to the members. Once the App is deleted
+	// from the remote storage, only the Pods still carrying it are listed.
+	PodDeployStatus map[string]AppDeploymentStatus `json:"podDeployStatus,omitempty"`
+
+	// RestartRequired is set when the App declares that Splunk must be restarted once it is installed
+	RestartRequired bool `json:"restartRequired,omitempty"`
+
+	// Name of the directory of the App once installed, read from its package
+	AppDirectory string `json:"appDirectory,omitempty"`
+
+	// Object hashes of the last versions of the App package deployed on all its Pods, most recent first. Pinning
+	// one of them in the App source rolls the App back to this version.
+	DeployedObjectHashes []string `json:"deployedObjectHashes,omitempty"`
+
+	// Status of the setup of a premium App on each Pod, by Pod name. The setup runs in the background of the Pod
+	// once the App is installed, and the App is only deployed on the Pod once its setup is complete.
+	SetupStatus map[string]AppDeploymentStatus `json:"setupStatus,omitempty"`
 }
 
 // AppSrcDeployInfo represents deployment info for list of Apps
 type AppSrcDeployInfo struct {
 	AppDeploymentInfoList []AppDeploymentInfo `json:"appDeploymentInfo,omitempty"`
+
+	// Scope and delete policy of the App source, kept to remove its Apps once it is removed from the config
+	Scope        string `json:"scope,omitempty"`
+	DeletePolicy string `json:"deletePolicy,omitempty"`
 }
 
 // AppDeploymentContext for storing the Apps deployment information
@@ -309,6 +466,10 @@ type AppDeploymentContext struct {
 	// Represents the Apps deployment status
 	AppsSrcDeployStatus map[string]AppSrcDeployInfo `json:"appSrcDeployStatus,omitempty"`
 
+	// Pods where Splunk must be restarted to complete the install of Apps. They are restarted one at a time, once
+	// all the Pods are ready.
+	PendingRestartPods []string `json:"pendingRestartPods,omitempty"`
+
 	// This is set to the time when we get the list of apps from remote storage.
 	LastAppInfoCheckTime int64 `json:"lastAppInfoCheckTime"`
 
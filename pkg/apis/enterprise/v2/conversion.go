@@ -0,0 +1,73 @@
+// Copyright (c) 2018-2021 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v2
+
+import (
+	"fmt"
+	"reflect"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+)
+
+// The v2 version is the hub of the conversions: the custom resources of the other versions of the API
+// are converted to and from v2.
+
+// blank assignment to verify that Standalone implements conversion.Hub
+var _ conversion.Hub = &Standalone{}
+
+// Hub marks Standalone as a conversion hub
+func (*Standalone) Hub() {}
+
+// blank assignment to verify that LicenseMaster implements conversion.Hub
+var _ conversion.Hub = &LicenseMaster{}
+
+// Hub marks LicenseMaster as a conversion hub
+func (*LicenseMaster) Hub() {}
+
+// blank assignment to verify that SearchHeadCluster implements conversion.Hub
+var _ conversion.Hub = &SearchHeadCluster{}
+
+// Hub marks SearchHeadCluster as a conversion hub
+func (*SearchHeadCluster) Hub() {}
+
+// blank assignment to verify that ClusterMaster implements conversion.Hub
+var _ conversion.Hub = &ClusterMaster{}
+
+// Hub marks ClusterMaster as a conversion hub
+func (*ClusterMaster) Hub() {}
+
+// blank assignment to verify that IndexerCluster implements conversion.Hub
+var _ conversion.Hub = &IndexerCluster{}
+
+// Hub marks IndexerCluster as a conversion hub
+func (*IndexerCluster) Hub() {}
+
+// ConvertObject copies the metadata, spec and status of a custom resource to the same kind of custom resource in
+// another version of the API. The older versions share the spec and status types of v2, so the copy is lossless.
+// A version changing these types must map its fields in its own conversion functions instead.
+func ConvertObject(src, dst runtime.Object) error {
+	srcValue := reflect.ValueOf(src.DeepCopyObject()).Elem()
+	dstValue := reflect.ValueOf(dst).Elem()
+	for _, name := range []string{"ObjectMeta", "Spec", "Status"} {
+		srcField := srcValue.FieldByName(name)
+		dstField := dstValue.FieldByName(name)
+		if !srcField.IsValid() || !dstField.IsValid() || srcField.Type() != dstField.Type() {
+			return fmt.Errorf("Unable to convert %T to %T: the types of their %s differ", src, dst, name)
+		}
+		dstField.Set(srcField)
+	}
+	return nil
+}
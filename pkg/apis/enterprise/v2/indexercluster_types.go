@@ -34,6 +34,12 @@ type IndexerClusterSpec struct {
 
 	// Number of search head pods; a search head cluster will be created if > 1
 	Replicas int32 `json:"replicas"`
+
+	// Site of the indexer peers for multisite clusters; must be one of the availableSites of the ClusterMaster
+	Site string `json:"site,omitempty"`
+
+	// Availability zone the indexer peers are scheduled in (value of the topology.kubernetes.io/zone node label)
+	Zone string `json:"zone,omitempty"`
 }
 
 // IndexerClusterMemberStatus is used to track the status of each indexer cluster peer.
@@ -55,6 +61,24 @@ type IndexerClusterMemberStatus struct {
 
 	// Flag indicating if this peer belongs to the current committed generation and is searchable.
 	Searchable bool `json:"is_searchable"`
+
+	// Site the peer belongs to
+	Site string `json:"site,omitempty"`
+
+	// Flag indicating if this peer must be restarted to apply the latest configuration bundle.
+	RestartRequired bool `json:"restart_required,omitempty"`
+}
+
+// IndexerClusterSiteStatus is used to track the number of peers of each site of a multisite indexer cluster.
+type IndexerClusterSiteStatus struct {
+	// Name of the site
+	Name string `json:"name"`
+
+	// Number of peers known by the cluster manager for the site
+	Peers int32 `json:"peers"`
+
+	// Number of peers with status Up for the site
+	UpPeers int32 `json:"upPeers"`
 }
 
 // IndexerClusterStatus defines the observed state of a Splunk Enterprise indexer cluster
@@ -97,6 +121,55 @@ type IndexerClusterStatus struct {
 
 	// status of each indexer cluster peer
 	Peers []IndexerClusterMemberStatus `json:"peers"`
+
+	// peer counts of each site, for multisite indexer clusters
+	Sites []IndexerClusterSiteStatus `json:"sites,omitempty"`
+
+	// progress of the searchable rolling upgrade used to recycle the peers, if one is in progress
+	RollingUpgrade *IndexerClusterRollingUpgradeStatus `json:"rollingUpgrade,omitempty"`
+
+	// progress of the searchable rolling restart of the peers by the cluster manager, if one is in progress
+	RollingRestart *IndexerClusterRollingRestartStatus `json:"rollingRestart,omitempty"`
+
+	// PersistentVolumeClaims retained for the pods removed by a scale down, which are reused if scaled up again
+	OrphanedClaims []string `json:"orphanedClaims,omitempty"`
+
+	// progress of the expansion of the PersistentVolumeClaims, while the storage capacity is being increased
+	PvcExpansion *splcommon.PvcExpansionStatus `json:"pvcExpansion,omitempty"`
+
+	// conditions describing the latest observed state of the custom resource
+	// +listType=map
+	// +listMapKey=type
+	Conditions []splcommon.Condition `json:"conditions,omitempty"`
+}
+
+// IndexerClusterRollingUpgradeStatus tracks the searchable rolling upgrade started on the cluster manager while the
+// indexer cluster peers are recycled to apply the updates of their pod template
+type IndexerClusterRollingUpgradeStatus struct {
+	// revision of the indexer StatefulSet the peers are updated to
+	Revision string `json:"revision"`
+
+	// image the peers are updated to
+	Image string `json:"image"`
+
+	// number of peers running the revision
+	UpdatedPeers int32 `json:"updatedPeers"`
+
+	// number of peers to update
+	TotalPeers int32 `json:"totalPeers"`
+
+	// time the rolling upgrade was started on the cluster manager
+	StartTime metav1.Time `json:"startTime"`
+}
+
+// IndexerClusterRollingRestartStatus tracks the searchable rolling restart of the indexer cluster peers by the cluster
+// manager, when the peers must be restarted to apply the latest configuration bundle
+type IndexerClusterRollingRestartStatus struct {
+	// number of peers which still need to be restarted
+	PendingPeers int32 `json:"pendingPeers"`
+
+	// time the rolling restart was observed or started on the cluster manager
+	StartTime metav1.Time `json:"startTime"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
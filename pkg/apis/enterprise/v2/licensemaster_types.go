@@ -43,6 +43,14 @@ type LicenseMasterStatus struct {
 
 	// App Framework Context
 	AppContext AppDeploymentContext `json:"appContext"`
+
+	// progress of the expansion of the PersistentVolumeClaims, while the storage capacity is being increased
+	PvcExpansion *splcommon.PvcExpansionStatus `json:"pvcExpansion,omitempty"`
+
+	// conditions describing the latest observed state of the custom resource
+	// +listType=map
+	// +listMapKey=type
+	Conditions []splcommon.Condition `json:"conditions,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
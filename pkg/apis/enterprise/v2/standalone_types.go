@@ -64,6 +64,20 @@ type StandaloneStatus struct {
 
 	// App Framework Context
 	AppContext AppDeploymentContext `json:"appContext"`
+
+	// PersistentVolumeClaims retained for the pods removed by a scale down, which are reused if scaled up again
+	OrphanedClaims []string `json:"orphanedClaims,omitempty"`
+
+	// progress of the expansion of the PersistentVolumeClaims, while the storage capacity is being increased
+	PvcExpansion *splcommon.PvcExpansionStatus `json:"pvcExpansion,omitempty"`
+
+	// progress of the copy of the data of the pods to their new volumes, while they move to new storage
+	StorageMigration *splcommon.StorageMigrationStatus `json:"storageMigration,omitempty"`
+
+	// conditions describing the latest observed state of the custom resource
+	// +listType=map
+	// +listMapKey=type
+	Conditions []splcommon.Condition `json:"conditions,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
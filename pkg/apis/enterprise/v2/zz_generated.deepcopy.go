@@ -5,8 +5,10 @@
 package v2
 
 import (
+	common "github.com/splunk/splunk-operator/pkg/splunk/common"
 	v1 "k8s.io/api/core/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
+	intstr "k8s.io/apimachinery/pkg/util/intstr"
 )
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
@@ -20,6 +22,11 @@ func (in *AppDeploymentContext) DeepCopyInto(out *AppDeploymentContext) {
 			(*out)[key] = *val.DeepCopy()
 		}
 	}
+	if in.PendingRestartPods != nil {
+		in, out := &in.PendingRestartPods, &out.PendingRestartPods
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -36,6 +43,25 @@ func (in *AppDeploymentContext) DeepCopy() *AppDeploymentContext {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AppDeploymentInfo) DeepCopyInto(out *AppDeploymentInfo) {
 	*out = *in
+	if in.PodDeployStatus != nil {
+		in, out := &in.PodDeployStatus, &out.PodDeployStatus
+		*out = make(map[string]AppDeploymentStatus, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.DeployedObjectHashes != nil {
+		in, out := &in.DeployedObjectHashes, &out.DeployedObjectHashes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.SetupStatus != nil {
+		in, out := &in.SetupStatus, &out.SetupStatus
+		*out = make(map[string]AppDeploymentStatus, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	return
 }
 
@@ -61,7 +87,9 @@ func (in *AppFrameworkSpec) DeepCopyInto(out *AppFrameworkSpec) {
 	if in.AppSources != nil {
 		in, out := &in.AppSources, &out.AppSources
 		*out = make([]AppSourceSpec, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 	return
 }
@@ -79,6 +107,7 @@ func (in *AppFrameworkSpec) DeepCopy() *AppFrameworkSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AppSourceDefaultSpec) DeepCopyInto(out *AppSourceDefaultSpec) {
 	*out = *in
+	out.PremiumAppsProps = in.PremiumAppsProps
 	return
 }
 
@@ -95,6 +124,16 @@ func (in *AppSourceDefaultSpec) DeepCopy() *AppSourceDefaultSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AppSourceSpec) DeepCopyInto(out *AppSourceSpec) {
 	*out = *in
+	if in.Apps != nil {
+		in, out := &in.Apps, &out.Apps
+		*out = make([]AppVersionSpec, len(*in))
+		copy(*out, *in)
+	}
+	if in.DependsOn != nil {
+		in, out := &in.DependsOn, &out.DependsOn
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	out.AppSourceDefaultSpec = in.AppSourceDefaultSpec
 	return
 }
@@ -115,7 +154,9 @@ func (in *AppSrcDeployInfo) DeepCopyInto(out *AppSrcDeployInfo) {
 	if in.AppDeploymentInfoList != nil {
 		in, out := &in.AppDeploymentInfoList, &out.AppDeploymentInfoList
 		*out = make([]AppDeploymentInfo, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 	return
 }
@@ -130,6 +171,22 @@ func (in *AppSrcDeployInfo) DeepCopy() *AppSrcDeployInfo {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AppVersionSpec) DeepCopyInto(out *AppVersionSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AppVersionSpec.
+func (in *AppVersionSpec) DeepCopy() *AppVersionSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AppVersionSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BundlePushInfo) DeepCopyInto(out *BundlePushInfo) {
 	*out = *in
@@ -230,6 +287,7 @@ func (in *ClusterMasterSpec) DeepCopyInto(out *ClusterMasterSpec) {
 	in.CommonSplunkSpec.DeepCopyInto(&out.CommonSplunkSpec)
 	in.SmartStore.DeepCopyInto(&out.SmartStore)
 	in.AppFrameworkConfig.DeepCopyInto(&out.AppFrameworkConfig)
+	in.Multisite.DeepCopyInto(&out.Multisite)
 	return
 }
 
@@ -256,6 +314,17 @@ func (in *ClusterMasterStatus) DeepCopyInto(out *ClusterMasterStatus) {
 		}
 	}
 	in.AppContext.DeepCopyInto(&out.AppContext)
+	if in.PvcExpansion != nil {
+		in, out := &in.PvcExpansion, &out.PvcExpansion
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]common.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -291,6 +360,8 @@ func (in *CommonSplunkSpec) DeepCopyInto(out *CommonSplunkSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	in.PodDisruptionBudget.DeepCopyInto(&out.PodDisruptionBudget)
+	in.RolloutStrategy.DeepCopyInto(&out.RolloutStrategy)
 	return
 }
 
@@ -304,6 +375,22 @@ func (in *CommonSplunkSpec) DeepCopy() *CommonSplunkSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EsDefaults) DeepCopyInto(out *EsDefaults) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EsDefaults.
+func (in *EsDefaults) DeepCopy() *EsDefaults {
+	if in == nil {
+		return nil
+	}
+	out := new(EsDefaults)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *IndexAndCacheManagerCommonSpec) DeepCopyInto(out *IndexAndCacheManagerCommonSpec) {
 	*out = *in
@@ -448,6 +535,56 @@ func (in *IndexerClusterMemberStatus) DeepCopy() *IndexerClusterMemberStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IndexerClusterRollingRestartStatus) DeepCopyInto(out *IndexerClusterRollingRestartStatus) {
+	*out = *in
+	in.StartTime.DeepCopyInto(&out.StartTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IndexerClusterRollingRestartStatus.
+func (in *IndexerClusterRollingRestartStatus) DeepCopy() *IndexerClusterRollingRestartStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(IndexerClusterRollingRestartStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IndexerClusterRollingUpgradeStatus) DeepCopyInto(out *IndexerClusterRollingUpgradeStatus) {
+	*out = *in
+	in.StartTime.DeepCopyInto(&out.StartTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IndexerClusterRollingUpgradeStatus.
+func (in *IndexerClusterRollingUpgradeStatus) DeepCopy() *IndexerClusterRollingUpgradeStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(IndexerClusterRollingUpgradeStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IndexerClusterSiteStatus) DeepCopyInto(out *IndexerClusterSiteStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IndexerClusterSiteStatus.
+func (in *IndexerClusterSiteStatus) DeepCopy() *IndexerClusterSiteStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(IndexerClusterSiteStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *IndexerClusterSpec) DeepCopyInto(out *IndexerClusterSpec) {
 	*out = *in
@@ -485,6 +622,37 @@ func (in *IndexerClusterStatus) DeepCopyInto(out *IndexerClusterStatus) {
 		*out = make([]IndexerClusterMemberStatus, len(*in))
 		copy(*out, *in)
 	}
+	if in.Sites != nil {
+		in, out := &in.Sites, &out.Sites
+		*out = make([]IndexerClusterSiteStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.RollingUpgrade != nil {
+		in, out := &in.RollingUpgrade, &out.RollingUpgrade
+		*out = new(IndexerClusterRollingUpgradeStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RollingRestart != nil {
+		in, out := &in.RollingRestart, &out.RollingRestart
+		*out = new(IndexerClusterRollingRestartStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.OrphanedClaims != nil {
+		in, out := &in.OrphanedClaims, &out.OrphanedClaims
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PvcExpansion != nil {
+		in, out := &in.PvcExpansion, &out.PvcExpansion
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]common.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -581,6 +749,17 @@ func (in *LicenseMasterSpec) DeepCopy() *LicenseMasterSpec {
 func (in *LicenseMasterStatus) DeepCopyInto(out *LicenseMasterStatus) {
 	*out = *in
 	in.AppContext.DeepCopyInto(&out.AppContext)
+	if in.PvcExpansion != nil {
+		in, out := &in.PvcExpansion, &out.PvcExpansion
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]common.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -594,6 +773,72 @@ func (in *LicenseMasterStatus) DeepCopy() *LicenseMasterStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MultisiteSpec) DeepCopyInto(out *MultisiteSpec) {
+	*out = *in
+	if in.AvailableSites != nil {
+		in, out := &in.AvailableSites, &out.AvailableSites
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	out.SiteReplicationFactor = in.SiteReplicationFactor
+	out.SiteSearchFactor = in.SiteSearchFactor
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MultisiteSpec.
+func (in *MultisiteSpec) DeepCopy() *MultisiteSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MultisiteSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PodDisruptionBudgetSpec) DeepCopyInto(out *PodDisruptionBudgetSpec) {
+	*out = *in
+	if in.MaxUnavailable != nil {
+		in, out := &in.MaxUnavailable, &out.MaxUnavailable
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+	if in.MinAvailable != nil {
+		in, out := &in.MinAvailable, &out.MinAvailable
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodDisruptionBudgetSpec.
+func (in *PodDisruptionBudgetSpec) DeepCopy() *PodDisruptionBudgetSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PodDisruptionBudgetSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PremiumAppsProps) DeepCopyInto(out *PremiumAppsProps) {
+	*out = *in
+	out.EsDefaults = in.EsDefaults
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PremiumAppsProps.
+func (in *PremiumAppsProps) DeepCopy() *PremiumAppsProps {
+	if in == nil {
+		return nil
+	}
+	out := new(PremiumAppsProps)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SearchHeadCluster) DeepCopyInto(out *SearchHeadCluster) {
 	*out = *in
@@ -715,6 +960,26 @@ func (in *SearchHeadClusterStatus) DeepCopyInto(out *SearchHeadClusterStatus) {
 		copy(*out, *in)
 	}
 	in.AppContext.DeepCopyInto(&out.AppContext)
+	if in.OrphanedClaims != nil {
+		in, out := &in.OrphanedClaims, &out.OrphanedClaims
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PvcExpansion != nil {
+		in, out := &in.PvcExpansion, &out.PvcExpansion
+		*out = (*in).DeepCopy()
+	}
+	if in.StorageMigration != nil {
+		in, out := &in.StorageMigration, &out.StorageMigration
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]common.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -728,6 +993,22 @@ func (in *SearchHeadClusterStatus) DeepCopy() *SearchHeadClusterStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SiteFactorSpec) DeepCopyInto(out *SiteFactorSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SiteFactorSpec.
+func (in *SiteFactorSpec) DeepCopy() *SiteFactorSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SiteFactorSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SmartStoreSpec) DeepCopyInto(out *SmartStoreSpec) {
 	*out = *in
@@ -848,6 +1129,26 @@ func (in *StandaloneStatus) DeepCopyInto(out *StandaloneStatus) {
 		}
 	}
 	in.AppContext.DeepCopyInto(&out.AppContext)
+	if in.OrphanedClaims != nil {
+		in, out := &in.OrphanedClaims, &out.OrphanedClaims
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PvcExpansion != nil {
+		in, out := &in.PvcExpansion, &out.PvcExpansion
+		*out = (*in).DeepCopy()
+	}
+	if in.StorageMigration != nil {
+		in, out := &in.StorageMigration, &out.StorageMigration
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]common.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
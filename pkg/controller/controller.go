@@ -16,6 +16,7 @@ package controller
 
 import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 
 	splctrl "github.com/splunk/splunk-operator/pkg/splunk/controller"
@@ -44,3 +45,49 @@ func AddToManager(mgr manager.Manager) error {
 
 	return nil
 }
+
+// AddMigrationToManager adds a runnable to the Manager that rewrites all the Splunk custom resources
+// using the storage version of their CustomResourceDefinition, when the Manager is started. Each kind is
+// only rewritten once: the migration is recorded in the stored versions of the CustomResourceDefinitions
+// when the operator watches all namespaces, or else in a ConfigMap of the namespace it watches.
+func AddMigrationToManager(mgr manager.Manager, namespace string) error {
+	c, err := client.New(mgr.GetConfig(), client.Options{})
+	if err != nil {
+		return err
+	}
+
+	return mgr.Add(manager.RunnableFunc(func(stop <-chan struct{}) error {
+		scopedLog := logf.Log.WithName("splunk.migration").WithValues("namespace", namespace)
+		for _, ctrl := range SplunkControllersToAdd {
+			// errors are not returned, since they would stop the Manager
+			gvk := ctrl.GetInstance().GetObjectKind().GroupVersionKind()
+			mapping, err := mgr.GetRESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version)
+			if err != nil {
+				scopedLog.Error(err, "Failed to get resource name", "kind", gvk.Kind)
+				continue
+			}
+			crdName := mapping.Resource.Resource + "." + gvk.Group
+
+			migrated, err := splctrl.IsStorageVersionMigrated(c, gvk, crdName, namespace)
+			if err != nil {
+				scopedLog.Error(err, "Failed to check storage version migration", "kind", gvk.Kind)
+				continue
+			} else if migrated {
+				continue
+			}
+
+			failed, err := splctrl.MigrateStorageVersion(c, gvk, namespace)
+			if err != nil {
+				scopedLog.Error(err, "Failed to migrate storage version", "kind", gvk.Kind)
+				continue
+			} else if failed > 0 {
+				continue
+			}
+
+			if err = splctrl.SetStorageVersionMigrated(c, gvk, crdName, namespace); err != nil {
+				scopedLog.Error(err, "Failed to record storage version migration", "kind", gvk.Kind)
+			}
+		}
+		return nil
+	}))
+}
@@ -15,11 +15,15 @@
 package client
 
 import (
+	"bytes"
+	"crypto/md5"
 	"crypto/tls"
 	"encoding/json"
 	"fmt"
-	"path/filepath"
+	"io/ioutil"
 	"regexp"
+	"strings"
+	"time"
 
 	"net/http"
 
@@ -29,12 +33,15 @@ import (
 	"github.com/aws/aws-sdk-go/service/s3"
 )
 
-// blank assignment to verify that AWSS3Client implements S3Client
+// blank assignment to verify that AWSS3Client implements S3Client and S3VersionedClient
 var _ S3Client = &AWSS3Client{}
+var _ S3VersionedClient = &AWSS3Client{}
 
 // SplunkAWSS3Client is an interface to AWS S3 client
 type SplunkAWSS3Client interface {
 	ListObjectsV2(options *s3.ListObjectsV2Input) (*s3.ListObjectsV2Output, error)
+	GetObject(options *s3.GetObjectInput) (*s3.GetObjectOutput, error)
+	ListObjectVersions(options *s3.ListObjectVersionsInput) (*s3.ListObjectVersionsOutput, error)
 }
 
 // AWSS3Client is a client to implement S3 specific APIs
@@ -204,15 +211,149 @@ func (awsclient *AWSS3Client) GetAppsList() (S3Response, error) {
 	return s3Resp, nil
 }
 
-// GetInitContainerImage returns the initContainer image to be used with this s3 client
-func (awsclient *AWSS3Client) GetInitContainerImage() string {
-	return ("amazon/aws-cli")
+// getAWSContentMD5 returns the hex MD5 digest of the content of an object, which is its ETag, unless the object was
+// uploaded in multiple parts, or encrypted with SSE-KMS or SSE-C. It is empty when the ETag is not a MD5 digest.
+func getAWSContentMD5(resp *s3.GetObjectOutput) string {
+	etag := strings.Trim(aws.StringValue(resp.ETag), "\"")
+	if len(etag) != 2*md5.Size || strings.Contains(etag, "-") ||
+		aws.StringValue(resp.ServerSideEncryption) == s3.ServerSideEncryptionAwsKms || resp.SSECustomerAlgorithm != nil {
+		return ""
+	}
+	return etag
+}
+
+// DownloadApp downloads an app package from remote storage to a local file, failing if its etag changed. The
+// etag is not checked when it is empty.
+func (awsclient *AWSS3Client) DownloadApp(remoteFile string, localFile string, etag string) error {
+	scopedLog := log.WithName("DownloadApp")
+
+	options := &s3.GetObjectInput{
+		Bucket: aws.String(awsclient.BucketName),
+		Key:    aws.String(remoteFile),
+	}
+	if etag != "" {
+		options.IfMatch = aws.String(etag)
+	}
+
+	resp, err := awsclient.Client.GetObject(options)
+	if err != nil {
+		scopedLog.Error(err, "Unable to download item", "AWS S3 Bucket", awsclient.BucketName, "remoteFile", remoteFile)
+		return err
+	}
+	defer resp.Body.Close()
+
+	return writeLocalFile(localFile, resp.Body, getAWSContentMD5(resp))
+}
+
+// DownloadAppVersion downloads the version of an app package matching an etag, which may not be its latest version
+func (awsclient *AWSS3Client) DownloadAppVersion(remoteFile string, localFile string, etag string) error {
+	scopedLog := log.WithName("DownloadAppVersion")
+
+	resp, err := awsclient.Client.ListObjectVersions(&s3.ListObjectVersionsInput{
+		Bucket: aws.String(awsclient.BucketName),
+		Prefix: aws.String(remoteFile),
+	})
+	if err != nil {
+		scopedLog.Error(err, "Unable to list item versions", "AWS S3 Bucket", awsclient.BucketName, "remoteFile", remoteFile)
+		return err
+	}
+
+	for _, version := range resp.Versions {
+		if aws.StringValue(version.Key) != remoteFile || aws.StringValue(version.ETag) != etag {
+			continue
+		}
+
+		options := &s3.GetObjectInput{
+			Bucket:    aws.String(awsclient.BucketName),
+			Key:       aws.String(remoteFile),
+			VersionId: version.VersionId,
+		}
+		resp, err := awsclient.Client.GetObject(options)
+		if err != nil {
+			scopedLog.Error(err, "Unable to download item version", "AWS S3 Bucket", awsclient.BucketName, "remoteFile", remoteFile, "versionId", aws.StringValue(version.VersionId))
+			return err
+		}
+		defer resp.Body.Close()
+
+		return writeLocalFile(localFile, resp.Body, getAWSContentMD5(resp))
+	}
+
+	return fmt.Errorf("No version of %s with etag %s in the bucket: %s", remoteFile, etag, awsclient.BucketName)
 }
 
-// GetInitContainerCmd returns the init container command on a per app source basis to be used by the initContainer
-func (awsclient *AWSS3Client) GetInitContainerCmd(endpoint string, bucket string, path string, appSrcName string, appMnt string) []string {
-	s3AppSrcPath := filepath.Join(bucket, path) + "/"
-	podSyncPath := filepath.Join(appMnt, appSrcName) + "/"
+// CheckS3VolumeAccess checks that a remote storage volume of the S3 API is usable by Splunk, by listing the objects
+// under its prefix, then putting, getting back and deleting a test object under this prefix. The bucket is
+// addressed with a path-style URL, so that it works with the MinIO-compatible object stores. The default AWS
+// credential chain is used when the keys are empty.
+func CheckS3VolumeAccess(endpoint, bucket, prefix, objectName, accessKeyID, secretAccessKey string) error {
+	region := GetRegion(endpoint)
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	config := &aws.Config{
+		Endpoint:         aws.String(endpoint),
+		Region:           aws.String(region),
+		S3ForcePathStyle: aws.Bool(true),
+		MaxRetries:       aws.Int(1),
+		HTTPClient: &http.Client{
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{MinVersion: tls.VersionTLS12}},
+			Timeout:   30 * time.Second,
+		},
+	}
+	if accessKeyID != "" && secretAccessKey != "" {
+		config.WithCredentials(credentials.NewStaticCredentials(accessKeyID, secretAccessKey, ""))
+	}
+
+	sess, err := session.NewSession(config)
+	if err != nil {
+		return fmt.Errorf("Unable to create an S3 session: %v", err)
+	}
+	client := s3.New(sess)
+
+	_, err = client.ListObjectsV2(&s3.ListObjectsV2Input{
+		Bucket:  aws.String(bucket),
+		Prefix:  aws.String(prefix),
+		MaxKeys: aws.Int64(1),
+	})
+	if err != nil {
+		return fmt.Errorf("Unable to list the objects of %s/%s: %v", bucket, prefix, err)
+	}
+
+	key := prefix + objectName
+	content := []byte(fmt.Sprintf("splunk-operator access check %d", time.Now().UnixNano()))
+	_, err = client.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(content),
+	})
+	if err != nil {
+		return fmt.Errorf("Unable to put the object %s/%s: %v", bucket, key, err)
+	}
+
+	resp, err := client.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("Unable to get the object %s/%s: %v", bucket, key, err)
+	}
+	got, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return fmt.Errorf("Unable to read the object %s/%s: %v", bucket, key, err)
+	}
+	if !bytes.Equal(got, content) {
+		return fmt.Errorf("The object %s/%s read back does not match the object put", bucket, key)
+	}
+
+	_, err = client.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("Unable to delete the object %s/%s: %v", bucket, key, err)
+	}
 
-	return ([]string{fmt.Sprintf("--endpoint-url=%s", endpoint), "s3", "sync", fmt.Sprintf("s3://%s", s3AppSrcPath), podSyncPath})
+	return nil
 }
@@ -15,7 +15,13 @@
 package client
 
 import (
-	"reflect"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -49,21 +55,95 @@ func TestNewAWSS3Client(t *testing.T) {
 	}
 }
 
-func TestGetInitContainerImage(t *testing.T) {
-	awsClient := &AWSS3Client{}
+func TestAWSDownloadApp(t *testing.T) {
+	// the etag is the MD5 digest of the body, unless the object was uploaded in multiple parts
+	etag := "\"cf246eae6338b0c03234b0cab5b5c5de\""
+	key := "adminAppsRepo/admin_app.tgz"
+	corruptedKey := "adminAppsRepo/corrupted_app.tgz"
+	multipartEtag := "\"fb0b2f21e7cb70a9e6e77fbce4d2b8d4-2\""
+	multipartKey := "adminAppsRepo/multipart_app.tgz"
+	awsClient := &AWSS3Client{
+		BucketName: "sample_bucket",
+		Client: spltest.MockAWSS3Client{Objects: []*spltest.MockAWSS3Object{
+			{Etag: &etag, Key: &key, Body: "admin app v2"},
+			{Etag: &etag, Key: &corruptedKey, Body: "corrupted app"},
+			{Etag: &multipartEtag, Key: &multipartKey, Body: "multipart app"},
+		}},
+	}
+
+	dir, err := ioutil.TempDir("", "awss3client")
+	if err != nil {
+		t.Fatalf("Unable to create a temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	localFile := filepath.Join(dir, "adminApps", "admin_app.tgz")
+	err = awsClient.DownloadApp(key, localFile, etag)
+	if err != nil {
+		t.Fatalf("DownloadApp() returned %v", err)
+	}
+	if _, err = os.Stat(localFile); err != nil {
+		t.Errorf("DownloadApp() should have written %s: %v", localFile, err)
+	}
+
+	err = awsClient.DownloadApp(key, localFile, "\"5055a61b3d1b667a4c3279a381a2e7ae\"")
+	if err == nil {
+		t.Errorf("DownloadApp() should have returned an error for a changed etag")
+	}
+
+	err = awsClient.DownloadApp(key, localFile, "")
+	if err != nil {
+		t.Errorf("DownloadApp() without etag returned %v", err)
+	}
+
+	err = awsClient.DownloadApp(corruptedKey, filepath.Join(dir, "corrupted_app.tgz"), etag)
+	if err == nil {
+		t.Errorf("DownloadApp() should have returned an error for a content not matching its MD5 etag")
+	}
+
+	err = awsClient.DownloadApp(multipartKey, filepath.Join(dir, "multipart_app.tgz"), multipartEtag)
+	if err != nil {
+		t.Errorf("DownloadApp() of a multipart object returned %v", err)
+	}
 
-	if awsClient.GetInitContainerImage() != "amazon/aws-cli" {
-		t.Errorf("Got invalid init container image for AWS client.")
+	err = awsClient.DownloadApp("adminAppsRepo/security_app.tgz", filepath.Join(dir, "security_app.tgz"), etag)
+	if err == nil {
+		t.Errorf("DownloadApp() should have returned an error for a missing key")
 	}
 }
 
-func TestGetAWSInitContainerCmd(t *testing.T) {
-	wantCmd := []string{"--endpoint-url=https://s3.us-west-2.amazonaws.com", "s3", "sync", "s3://sample_bucket/admin/", "/mnt/apps-local/admin/"}
+func TestAWSDownloadAppVersion(t *testing.T) {
+	etag := "\"cf246eae6338b0c03234b0cab5b5c5de\""
+	previousEtag := "\"fb0b2f21e7cb70a9e6e77fbce4d2b8d4\""
+	key := "adminAppsRepo/admin_app.tgz"
+	awsClient := &AWSS3Client{
+		BucketName: "sample_bucket",
+		Client: spltest.MockAWSS3Client{
+			Objects:          []*spltest.MockAWSS3Object{{Etag: &etag, Key: &key, Body: "admin app v2"}},
+			PreviousVersions: []*spltest.MockAWSS3Object{{Etag: &previousEtag, Key: &key, Body: "admin app v1"}},
+		},
+	}
 
-	awsClient := &AWSS3Client{}
-	gotCmd := awsClient.GetInitContainerCmd("https://s3.us-west-2.amazonaws.com", "sample_bucket", "admin/", "admin", "/mnt/apps-local/")
-	if !reflect.DeepEqual(wantCmd, gotCmd) {
-		t.Errorf("Got incorrect Init container cmd")
+	dir, err := ioutil.TempDir("", "awss3client")
+	if err != nil {
+		t.Fatalf("Unable to create a temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	localFile := filepath.Join(dir, "adminApps", "admin_app.tgz")
+	for _, versionEtag := range []string{previousEtag, etag} {
+		err = awsClient.DownloadAppVersion(key, localFile, versionEtag)
+		if err != nil {
+			t.Fatalf("DownloadAppVersion(%s) returned %v", versionEtag, err)
+		}
+		if _, err = os.Stat(localFile); err != nil {
+			t.Errorf("DownloadAppVersion(%s) should have written %s: %v", versionEtag, localFile, err)
+		}
+	}
+
+	err = awsClient.DownloadAppVersion(key, localFile, "\"a80fb6cd5b6e1a9bd4dbd1c4b4d4e2f8\"")
+	if err == nil {
+		t.Errorf("DownloadAppVersion() should have returned an error for an unknown etag")
 	}
 }
 
@@ -290,3 +370,79 @@ func TestGetAppsListShouldFail(t *testing.T) {
 	}
 
 }
+
+// s3StandIn is an in-memory object store serving the path-style S3 API calls of CheckS3VolumeAccess
+type s3StandIn struct {
+	mutex   sync.Mutex
+	bucket  string
+	objects map[string][]byte
+	denied  string
+}
+
+func (s *s3StandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	path := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)
+	if path[0] != s.bucket {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`<Error><Code>NoSuchBucket</Code><Message>The specified bucket does not exist</Message></Error>`))
+		return
+	}
+	if r.Method == s.denied {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`<Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>`))
+		return
+	}
+
+	if len(path) == 1 || path[1] == "" {
+		w.Write([]byte(`<ListBucketResult><Name>` + s.bucket + `</Name><KeyCount>0</KeyCount></ListBucketResult>`))
+		return
+	}
+	switch r.Method {
+	case http.MethodPut:
+		body, _ := ioutil.ReadAll(r.Body)
+		s.objects[path[1]] = body
+	case http.MethodGet:
+		body, ok := s.objects[path[1]]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`<Error><Code>NoSuchKey</Code><Message>The specified key does not exist</Message></Error>`))
+			return
+		}
+		w.Write(body)
+	case http.MethodDelete:
+		delete(s.objects, path[1])
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestCheckS3VolumeAccess(t *testing.T) {
+	standIn := &s3StandIn{bucket: "smartstore", objects: map[string][]byte{}}
+	server := httptest.NewServer(standIn)
+	defer server.Close()
+
+	err := CheckS3VolumeAccess(server.URL, "smartstore", "london/", ".splunk-operator-check", "abcd", "1234")
+	if err != nil {
+		t.Errorf("CheckS3VolumeAccess() returned %v", err)
+	}
+	if len(standIn.objects) != 0 {
+		t.Errorf("CheckS3VolumeAccess() should have deleted its test object, got %v", standIn.objects)
+	}
+
+	err = CheckS3VolumeAccess(server.URL, "missing-bucket", "", ".splunk-operator-check", "abcd", "1234")
+	if err == nil || !strings.Contains(err.Error(), "Unable to list") {
+		t.Errorf("CheckS3VolumeAccess() should have failed to list a missing bucket, got %v", err)
+	}
+
+	for method, want := range map[string]string{
+		http.MethodPut:    "Unable to put",
+		http.MethodDelete: "Unable to delete",
+	} {
+		standIn.denied = method
+		err = CheckS3VolumeAccess(server.URL, "smartstore", "london/", ".splunk-operator-check", "abcd", "1234")
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("CheckS3VolumeAccess() with %s denied should have returned %q, got %v", method, want, err)
+		}
+	}
+}
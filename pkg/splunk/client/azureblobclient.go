@@ -0,0 +1,434 @@
+// Copyright (c) 2018-2021 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// blank assignment to verify that AzureBlobClient implements S3Client
+var _ S3Client = &AzureBlobClient{}
+
+// azureBlobAPIVersion is the version of the Blob service REST API used by AzureBlobClient
+const azureBlobAPIVersion = "2019-12-12"
+
+// azureStorageScope is the OAuth2 scope requested for the workload identity of AzureBlobClient
+const azureStorageScope = "https://storage.azure.com/.default"
+
+// environment variables set in the pods using Azure AD workload identity, by its mutating webhook
+const (
+	azureClientIDEnv           = "AZURE_CLIENT_ID"
+	azureTenantIDEnv           = "AZURE_TENANT_ID"
+	azureFederatedTokenFileEnv = "AZURE_FEDERATED_TOKEN_FILE"
+	azureAuthorityHostEnv      = "AZURE_AUTHORITY_HOST"
+)
+
+// azureDefaultAuthorityHost is the Azure AD endpoint of the public cloud
+const azureDefaultAuthorityHost = "https://login.microsoftonline.com/"
+
+// azureBlobStandardHeaders are the standard headers of a request included in its Shared Key signature, in order
+var azureBlobStandardHeaders = []string{"Content-Encoding", "Content-Language", "Content-Length", "Content-MD5", "Content-Type", "Date",
+	"If-Modified-Since", "If-Match", "If-None-Match", "If-Unmodified-Since", "Range"}
+
+// AzureBlobClient is a client to list the apps of an Azure Blob storage container
+type AzureBlobClient struct {
+	// Azure storage account endpoint (e.g. "https://account.blob.core.windows.net", or
+	// "http://127.0.0.1:10000/devstoreaccount1" for Azurite)
+	Endpoint string
+
+	// name of the container
+	ContainerName string
+
+	// name of the storage account
+	StorageAccountName string
+
+	// base64 encoded key of the storage account; requests are authenticated with the service principal when it is
+	// empty and there is a client secret, or else with the workload identity of the pod if there is one, or else they
+	// are not signed
+	StorageAccountKey string
+
+	// tenant, client id and client secret of a service principal
+	TenantID     string
+	ClientID     string
+	ClientSecret string
+
+	// only the blobs starting with this prefix are listed
+	Prefix string
+
+	// blob listed before the apps, which is excluded from the listing
+	StartAfter string
+
+	Client SplunkHTTPClient
+}
+
+// NewAzureBlobClient returns an Azure Blob client
+func NewAzureBlobClient(containerName string, storageAccountName string, storageAccountKey string, prefix string, startAfter string, endpoint string, fn GetInitFunc) (S3Client, error) {
+	cl := fn(endpoint, storageAccountName, storageAccountKey)
+	if cl == nil {
+		return nil, fmt.Errorf("Failed to create an Azure Blob client")
+	}
+
+	return &AzureBlobClient{
+		Endpoint:           strings.TrimSuffix(endpoint, "/"),
+		ContainerName:      containerName,
+		StorageAccountName: storageAccountName,
+		StorageAccountKey:  storageAccountKey,
+		Prefix:             prefix,
+		StartAfter:         startAfter,
+		Client:             cl.(SplunkHTTPClient),
+	}, nil
+}
+
+// RegisterAzureBlobClient will add the corresponding function pointer to the map
+func RegisterAzureBlobClient() {
+	wrapperObject := GetS3ClientWrapper{GetS3Client: NewAzureBlobClient, GetInitFunc: InitAzureBlobClientWrapper}
+	S3Clients["azure"] = wrapperObject
+}
+
+// InitAzureBlobClientWrapper is a wrapper around InitAzureBlobClientSession
+func InitAzureBlobClientWrapper(endpoint string, storageAccountName string, storageAccountKey string) interface{} {
+	return InitAzureBlobClientSession(endpoint, storageAccountName, storageAccountKey)
+}
+
+// InitAzureBlobClientSession returns the HTTP client used to send requests to the Blob service
+func InitAzureBlobClientSession(endpoint string, storageAccountName string, storageAccountKey string) SplunkHTTPClient {
+	scopedLog := log.WithName("InitAzureBlobClientSession")
+
+	if !strings.HasPrefix(endpoint, "https://") && !strings.HasPrefix(endpoint, "http://") {
+		scopedLog.Info("Unsupported endpoint for Azure Blob client", "endpoint", endpoint)
+		return nil
+	}
+	if storageAccountKey != "" {
+		if _, err := base64.StdEncoding.DecodeString(storageAccountKey); err != nil {
+			scopedLog.Info("Storage account key is not base64 encoded", "storageAccountName", storageAccountName)
+			return nil
+		}
+	}
+
+	return &http.Client{Timeout: 30 * time.Second}
+}
+
+// azureBlobListing is the response of the List Blobs operation of the Blob service
+type azureBlobListing struct {
+	Blobs []struct {
+		Name       string `xml:"Name"`
+		Properties struct {
+			LastModified  string `xml:"Last-Modified"`
+			Etag          string `xml:"Etag"`
+			ContentLength int64  `xml:"Content-Length"`
+			AccessTier    string `xml:"AccessTier"`
+		} `xml:"Properties"`
+	} `xml:"Blobs>Blob"`
+	NextMarker string `xml:"NextMarker"`
+}
+
+// GetAppsList get the list of apps from remote storage
+func (client *AzureBlobClient) GetAppsList() (S3Response, error) {
+	scopedLog := log.WithName("GetAppsList")
+
+	scopedLog.Info("Getting Apps list", "Azure Blob Container", client.ContainerName, "Prefix", client.Prefix)
+	s3Resp := S3Response{}
+
+	marker := ""
+	for {
+		listing, err := client.listBlobs(marker)
+		if err != nil {
+			scopedLog.Error(err, "Unable to list blobs in container", "Azure Blob Container", client.ContainerName)
+			return s3Resp, err
+		}
+
+		for _, blob := range listing.Blobs {
+			// exclude the directory itself from listing
+			if blob.Name == client.StartAfter {
+				continue
+			}
+			newKey := blob.Name
+			newEtag := blob.Properties.Etag
+			newSize := blob.Properties.ContentLength
+			newStorageClass := blob.Properties.AccessTier
+			newRemoteObject := RemoteObject{Etag: &newEtag, Key: &newKey, Size: &newSize, StorageClass: &newStorageClass}
+			if lastModified, err := time.Parse(time.RFC1123, blob.Properties.LastModified); err == nil {
+				newRemoteObject.LastModified = &lastModified
+			}
+			s3Resp.Objects = append(s3Resp.Objects, &newRemoteObject)
+		}
+
+		if listing.NextMarker == "" {
+			break
+		}
+		marker = listing.NextMarker
+	}
+
+	if len(s3Resp.Objects) == 0 {
+		return s3Resp, fmt.Errorf("Empty blobs list in the container: %s", client.ContainerName)
+	}
+
+	return s3Resp, nil
+}
+
+// listBlobs returns one page of the blobs of the container, limited to 1 level only
+func (client *AzureBlobClient) listBlobs(marker string) (*azureBlobListing, error) {
+	query := url.Values{}
+	query.Set("restype", "container")
+	query.Set("comp", "list")
+	query.Set("prefix", client.Prefix)
+	query.Set("delimiter", "/")
+	if marker != "" {
+		query.Set("marker", marker)
+	}
+
+	request, err := client.newRequest("GET", fmt.Sprintf("%s/%s?%s", client.Endpoint, client.ContainerName, query.Encode()), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := client.Client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Unexpected status code %d listing blobs of container %s", response.StatusCode, client.ContainerName)
+	}
+
+	listing := azureBlobListing{}
+	err = xml.NewDecoder(response.Body).Decode(&listing)
+	return &listing, err
+}
+
+// newRequest returns a request to the Blob service, signed with the storage account key if there is one, or else
+// authenticated with the service principal or the workload identity of the pod if there is one
+func (client *AzureBlobClient) newRequest(method string, rawURL string, body []byte, headers map[string]string) (*http.Request, error) {
+	request, err := http.NewRequest(method, rawURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range headers {
+		request.Header.Set(name, value)
+	}
+	// the Content-Length header is only signed, since the transport sets it from the body
+	if len(body) > 0 {
+		request.Header.Set("Content-Length", fmt.Sprintf("%d", len(body)))
+	}
+	request.Header.Set("x-ms-date", time.Now().UTC().Format(http.TimeFormat))
+	request.Header.Set("x-ms-version", azureBlobAPIVersion)
+	if client.StorageAccountKey != "" {
+		signature, err := client.sign(request)
+		if err != nil {
+			return nil, err
+		}
+		request.Header.Set("Authorization", fmt.Sprintf("SharedKey %s:%s", client.StorageAccountName, signature))
+	} else {
+		var token string
+		if client.ClientSecret != "" {
+			token, err = client.getClientSecretToken()
+		} else {
+			token, err = client.getWorkloadIdentityToken()
+		}
+		if err != nil {
+			return nil, err
+		}
+		if token != "" {
+			request.Header.Set("Authorization", "Bearer "+token)
+		}
+	}
+
+	return request, nil
+}
+
+// getWorkloadIdentityToken exchanges the federated token of the service account of the pod for an Azure AD access
+// token of its workload identity. It returns an empty token when the pod does not use workload identity.
+func (client *AzureBlobClient) getWorkloadIdentityToken() (string, error) {
+	tokenFile := os.Getenv(azureFederatedTokenFileEnv)
+	if tokenFile == "" {
+		return "", nil
+	}
+	federatedToken, err := ioutil.ReadFile(tokenFile)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{}
+	form.Set("client_id", os.Getenv(azureClientIDEnv))
+	form.Set("client_assertion_type", "urn:ietf:params:oauth:client-assertion-type:jwt-bearer")
+	form.Set("client_assertion", strings.TrimSpace(string(federatedToken)))
+	return client.getClientCredentialsToken(os.Getenv(azureTenantIDEnv), form)
+}
+
+// getClientSecretToken returns an Azure AD access token of the service principal, authenticated with its client secret
+func (client *AzureBlobClient) getClientSecretToken() (string, error) {
+	form := url.Values{}
+	form.Set("client_id", client.ClientID)
+	form.Set("client_secret", client.ClientSecret)
+	return client.getClientCredentialsToken(client.TenantID, form)
+}
+
+// getClientCredentialsToken requests an access token of the Blob service from Azure AD with the client credentials
+// grant, using the credentials of the form
+func (client *AzureBlobClient) getClientCredentialsToken(tenantID string, form url.Values) (string, error) {
+	authorityHost := os.Getenv(azureAuthorityHostEnv)
+	if authorityHost == "" {
+		authorityHost = azureDefaultAuthorityHost
+	}
+
+	form.Set("grant_type", "client_credentials")
+	form.Set("scope", azureStorageScope)
+	tokenURL := fmt.Sprintf("%s/%s/oauth2/v2.0/token", strings.TrimSuffix(authorityHost, "/"), tenantID)
+	request, err := http.NewRequest("POST", tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	return getOAuth2AccessToken(client.Client, request)
+}
+
+// sign returns the Shared Key signature of a request to the Blob service, whose Content-Length header is set when it
+// has a body
+func (client *AzureBlobClient) sign(request *http.Request) (string, error) {
+	key, err := base64.StdEncoding.DecodeString(client.StorageAccountKey)
+	if err != nil {
+		return "", err
+	}
+
+	// x-ms-* headers, sorted by name
+	var headerNames []string
+	for name := range request.Header {
+		if strings.HasPrefix(strings.ToLower(name), "x-ms-") {
+			headerNames = append(headerNames, strings.ToLower(name))
+		}
+	}
+	sort.Strings(headerNames)
+	var canonicalizedHeaders strings.Builder
+	for _, name := range headerNames {
+		fmt.Fprintf(&canonicalizedHeaders, "%s:%s\n", name, request.Header.Get(name))
+	}
+
+	// account and path of the resource, followed by the query parameters sorted by name
+	canonicalizedResource := fmt.Sprintf("/%s%s", client.StorageAccountName, request.URL.EscapedPath())
+	query := request.URL.Query()
+	var paramNames []string
+	for name := range query {
+		paramNames = append(paramNames, name)
+	}
+	sort.Strings(paramNames)
+	for _, name := range paramNames {
+		values := query[name]
+		sort.Strings(values)
+		canonicalizedResource += fmt.Sprintf("\n%s:%s", strings.ToLower(name), strings.Join(values, ","))
+	}
+
+	// verb, then the standard headers, which are empty for a GET except for its conditions
+	stringToSign := request.Method + "\n"
+	for _, name := range azureBlobStandardHeaders {
+		stringToSign += request.Header.Get(name) + "\n"
+	}
+	stringToSign += canonicalizedHeaders.String() + canonicalizedResource
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// DownloadApp downloads an app package from remote storage to a local file, failing if its etag changed. The
+// etag is not checked when it is empty.
+func (client *AzureBlobClient) DownloadApp(remoteFile string, localFile string, etag string) error {
+	scopedLog := log.WithName("DownloadApp")
+
+	headers := map[string]string{}
+	if etag != "" {
+		// the etags of the listing are not quoted, unlike those of the headers
+		if !strings.HasPrefix(etag, "\"") {
+			etag = "\"" + etag + "\""
+		}
+		headers["If-Match"] = etag
+	}
+	blobURL := url.URL{Path: "/" + client.ContainerName + "/" + remoteFile}
+	request, err := client.newRequest("GET", client.Endpoint+blobURL.EscapedPath(), nil, headers)
+	if err != nil {
+		return err
+	}
+
+	err = downloadHTTPObject(client.Client, request, localFile, "")
+	if err != nil {
+		scopedLog.Error(err, "Unable to download blob", "Azure Blob Container", client.ContainerName, "remoteFile", remoteFile)
+		return err
+	}
+
+	return nil
+}
+
+// CheckVolumeAccess checks that a remote storage volume is usable by Splunk, by listing the blobs under the prefix of
+// the client, then putting, getting back and deleting a test blob under this prefix
+func (client *AzureBlobClient) CheckVolumeAccess(blobName string) error {
+	_, err := client.listBlobs("")
+	if err != nil {
+		return fmt.Errorf("Unable to list the blobs of %s/%s: %v", client.ContainerName, client.Prefix, err)
+	}
+
+	name := client.Prefix + blobName
+	blobURL := url.URL{Path: "/" + client.ContainerName + "/" + name}
+	content := []byte(fmt.Sprintf("splunk-operator access check %d", time.Now().UnixNano()))
+	_, err = client.doBlobRequest("PUT", client.Endpoint+blobURL.EscapedPath(), content, map[string]string{"x-ms-blob-type": "BlockBlob"}, http.StatusCreated)
+	if err != nil {
+		return fmt.Errorf("Unable to put the blob %s/%s: %v", client.ContainerName, name, err)
+	}
+
+	got, err := client.doBlobRequest("GET", client.Endpoint+blobURL.EscapedPath(), nil, nil, http.StatusOK)
+	if err != nil {
+		return fmt.Errorf("Unable to get the blob %s/%s: %v", client.ContainerName, name, err)
+	}
+	if !bytes.Equal(got, content) {
+		return fmt.Errorf("The blob %s/%s read back does not match the blob put", client.ContainerName, name)
+	}
+
+	_, err = client.doBlobRequest("DELETE", client.Endpoint+blobURL.EscapedPath(), nil, nil, http.StatusAccepted)
+	if err != nil {
+		return fmt.Errorf("Unable to delete the blob %s/%s: %v", client.ContainerName, name, err)
+	}
+
+	return nil
+}
+
+// doBlobRequest sends a request to the Blob service and returns the body of its response, failing if its status code
+// is not the expected one
+func (client *AzureBlobClient) doBlobRequest(method string, rawURL string, body []byte, headers map[string]string, expectedStatus int) ([]byte, error) {
+	request, err := client.newRequest(method, rawURL, body, headers)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := client.Client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != expectedStatus {
+		return nil, fmt.Errorf("Unexpected status code %d", response.StatusCode)
+	}
+	return ioutil.ReadAll(response.Body)
+}
@@ -0,0 +1,313 @@
+// Copyright (c) 2018-2021 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// azuriteAccountKey is the well-known key of the devstoreaccount1 account of the Azurite emulator
+const azuriteAccountKey = "Eby8vdM02xNOcqFlqUwJPLlmEtlCDXJ1OUzFT50uSRZ6IFsuFq2UVErCz4I6tq/K1SZFPTOtr/KBHBeksoGMGw=="
+
+func TestInitAzureBlobClientWrapper(t *testing.T) {
+	if InitAzureBlobClientWrapper("http://127.0.0.1:10000/devstoreaccount1", "devstoreaccount1", azuriteAccountKey) == nil {
+		t.Errorf("We should have got a valid Azure Blob client object")
+	}
+
+	if InitAzureBlobClientWrapper("127.0.0.1:10000", "devstoreaccount1", azuriteAccountKey) != nil {
+		t.Errorf("We should not have got an Azure Blob client object for an endpoint without scheme")
+	}
+
+	if InitAzureBlobClientWrapper("http://127.0.0.1:10000/devstoreaccount1", "devstoreaccount1", "not base64!") != nil {
+		t.Errorf("We should not have got an Azure Blob client object for an invalid account key")
+	}
+}
+
+func TestNewAzureBlobClient(t *testing.T) {
+	azureClient, err := NewAzureBlobClient("apps", "devstoreaccount1", azuriteAccountKey, "adminApps/", "adminApps/", "https://devstoreaccount1.blob.core.windows.net/", InitAzureBlobClientWrapper)
+	if azureClient == nil || err != nil {
+		t.Fatalf("NewAzureBlobClient should have returned a valid Azure Blob client.")
+	}
+	if azureClient.(*AzureBlobClient).Endpoint != "https://devstoreaccount1.blob.core.windows.net" {
+		t.Errorf("NewAzureBlobClient should have trimmed the endpoint, got %s", azureClient.(*AzureBlobClient).Endpoint)
+	}
+
+	azureClient, err = NewAzureBlobClient("apps", "devstoreaccount1", azuriteAccountKey, "adminApps/", "adminApps/", "random-endpoint.com", InitAzureBlobClientWrapper)
+	if azureClient != nil || err == nil {
+		t.Errorf("NewAzureBlobClient should have returned a error.")
+	}
+}
+
+func TestAzureBlobSign(t *testing.T) {
+	azureClient := &AzureBlobClient{StorageAccountName: "devstoreaccount1", StorageAccountKey: azuriteAccountKey}
+	request, _ := http.NewRequest("GET", "http://127.0.0.1:10000/devstoreaccount1/apps?restype=container&comp=list&prefix=adminApps%2F&delimiter=%2F", nil)
+	request.Header.Set("x-ms-date", "Mon, 18 Oct 2021 10:00:00 GMT")
+	request.Header.Set("x-ms-version", azureBlobAPIVersion)
+
+	signature, err := azureClient.sign(request)
+	if err != nil || signature != "Grdr5p9uj/B6f76KxOuoN6VHL2q/FQHlu1HnMiKA/aI=" {
+		t.Errorf("sign() = %s, %v; want Grdr5p9uj/B6f76KxOuoN6VHL2q/FQHlu1HnMiKA/aI=", signature, err)
+	}
+}
+
+func TestAzureBlobGetAppsList(t *testing.T) {
+	// emulate the List Blobs operation of Azurite, returning the apps on two pages
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if r.URL.Path != "/devstoreaccount1/apps" || query.Get("comp") != "list" || query.Get("prefix") != "adminApps/" || query.Get("delimiter") != "/" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "SharedKey devstoreaccount1:") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if query.Get("marker") == "" {
+			fmt.Fprint(w, `<?xml version="1.0" encoding="utf-8"?><EnumerationResults ContainerName="apps"><Blobs>`+
+				`<Blob><Name>adminApps/</Name><Properties><Content-Length>0</Content-Length></Properties></Blob>`+
+				`<Blob><Name>adminApps/app1.tgz</Name><Properties><Last-Modified>Mon, 18 Oct 2021 10:00:00 GMT</Last-Modified><Etag>0x8D9922</Etag><Content-Length>1024</Content-Length><AccessTier>Hot</AccessTier></Properties></Blob>`+
+				`<BlobPrefix><Name>adminApps/old/</Name></BlobPrefix>`+
+				`</Blobs><NextMarker>page2</NextMarker></EnumerationResults>`)
+			return
+		}
+		fmt.Fprint(w, `<?xml version="1.0" encoding="utf-8"?><EnumerationResults ContainerName="apps"><Blobs>`+
+			`<Blob><Name>adminApps/app2.tgz</Name><Properties><Last-Modified>Tue, 19 Oct 2021 10:00:00 GMT</Last-Modified><Etag>0x8D9923</Etag><Content-Length>2048</Content-Length><AccessTier>Cool</AccessTier></Properties></Blob>`+
+			`</Blobs><NextMarker /></EnumerationResults>`)
+	}))
+	defer server.Close()
+
+	azureClient, err := NewAzureBlobClient("apps", "devstoreaccount1", azuriteAccountKey, "adminApps/", "adminApps/", server.URL+"/devstoreaccount1", InitAzureBlobClientWrapper)
+	if err != nil {
+		t.Fatalf("NewAzureBlobClient returned %v", err)
+	}
+	resp, err := azureClient.GetAppsList()
+	if err != nil || len(resp.Objects) != 2 {
+		t.Fatalf("GetAppsList() = %v, %v; want 2 apps", resp, err)
+	}
+	if *resp.Objects[0].Key != "adminApps/app1.tgz" || *resp.Objects[0].Etag != "0x8D9922" || *resp.Objects[0].Size != 1024 ||
+		*resp.Objects[0].StorageClass != "Hot" || resp.Objects[0].LastModified.Day() != 18 {
+		t.Errorf("GetAppsList() returned an invalid first app: %+v", resp.Objects[0])
+	}
+	if *resp.Objects[1].Key != "adminApps/app2.tgz" {
+		t.Errorf("GetAppsList() returned an invalid second app: %s", *resp.Objects[1].Key)
+	}
+
+	// unsigned requests are rejected
+	azureClient.(*AzureBlobClient).StorageAccountKey = ""
+	_, err = azureClient.GetAppsList()
+	if err == nil {
+		t.Errorf("GetAppsList() should have returned an error for unsigned requests")
+	}
+}
+
+func TestAzureBlobDownloadApp(t *testing.T) {
+	// emulate the Get Blob operation of Azurite, for the current version of the app only
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/devstoreaccount1/apps/adminApps/app 1.tgz" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "SharedKey devstoreaccount1:") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if r.Header.Get("If-Match") != `"0x8D9922"` {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		fmt.Fprint(w, "app1 content")
+	}))
+	defer server.Close()
+
+	azureClient, err := NewAzureBlobClient("apps", "devstoreaccount1", azuriteAccountKey, "adminApps/", "adminApps/", server.URL+"/devstoreaccount1", InitAzureBlobClientWrapper)
+	if err != nil {
+		t.Fatalf("NewAzureBlobClient returned %v", err)
+	}
+
+	dir, err := ioutil.TempDir("", "azureblobclient")
+	if err != nil {
+		t.Fatalf("Unable to create a temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	localFile := filepath.Join(dir, "adminApps", "app 1.tgz")
+	err = azureClient.DownloadApp("adminApps/app 1.tgz", localFile, "0x8D9922")
+	if err != nil {
+		t.Fatalf("DownloadApp() returned %v", err)
+	}
+	content, err := ioutil.ReadFile(localFile)
+	if err != nil || string(content) != "app1 content" {
+		t.Errorf("DownloadApp() wrote %s, %v; want app1 content", content, err)
+	}
+
+	// the app changed since it was listed
+	err = azureClient.DownloadApp("adminApps/app 1.tgz", filepath.Join(dir, "app2.tgz"), "0x8D9923")
+	if err == nil {
+		t.Errorf("DownloadApp() should have returned an error for a changed etag")
+	}
+	if _, err = os.Stat(filepath.Join(dir, "app2.tgz")); !os.IsNotExist(err) {
+		t.Errorf("DownloadApp() should not have written a file for a failed download")
+	}
+}
+
+func TestAzureBlobWorkloadIdentity(t *testing.T) {
+	dir, err := ioutil.TempDir("", "azureblobclient")
+	if err != nil {
+		t.Fatalf("Unable to create a temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	tokenFile := filepath.Join(dir, "azure-identity-token")
+	if err = ioutil.WriteFile(tokenFile, []byte("federated1\n"), 0644); err != nil {
+		t.Fatalf("Unable to write %s: %v", tokenFile, err)
+	}
+
+	// emulate the token endpoint of Azure AD and the Get Blob operation of the Blob service
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/tenant1/oauth2/v2.0/token" {
+			if r.FormValue("grant_type") != "client_credentials" || r.FormValue("client_id") != "client1" ||
+				r.FormValue("client_assertion") != "federated1" || r.FormValue("scope") != azureStorageScope {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			fmt.Fprint(w, `{"token_type": "Bearer", "expires_in": 3599, "access_token": "token1"}`)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer token1" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		fmt.Fprint(w, "app1 content")
+	}))
+	defer server.Close()
+
+	for name, value := range map[string]string{azureFederatedTokenFileEnv: tokenFile, azureClientIDEnv: "client1", azureTenantIDEnv: "tenant1", azureAuthorityHostEnv: server.URL + "/"} {
+		os.Setenv(name, value)
+		defer os.Unsetenv(name)
+	}
+
+	azureClient, err := NewAzureBlobClient("apps", "account1", "", "adminApps/", "adminApps/", server.URL+"/account1", InitAzureBlobClientWrapper)
+	if err != nil {
+		t.Fatalf("NewAzureBlobClient returned %v", err)
+	}
+	localFile := filepath.Join(dir, "app1.tgz")
+	err = azureClient.DownloadApp("adminApps/app1.tgz", localFile, "")
+	if err != nil {
+		t.Fatalf("DownloadApp() returned %v", err)
+	}
+	content, err := ioutil.ReadFile(localFile)
+	if err != nil || string(content) != "app1 content" {
+		t.Errorf("DownloadApp() wrote %s, %v; want app1 content", content, err)
+	}
+
+	// the federated token is rejected for another client
+	os.Setenv(azureClientIDEnv, "client2")
+	err = azureClient.DownloadApp("adminApps/app1.tgz", filepath.Join(dir, "app1-client2.tgz"), "")
+	if err == nil {
+		t.Errorf("DownloadApp() should have returned an error for a rejected federated token")
+	}
+}
+
+func TestAzureBlobCheckVolumeAccess(t *testing.T) {
+	// emulate the token endpoint of Azure AD and the blob operations of the Blob service, in memory
+	blobs := map[string][]byte{}
+	denied := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/tenant1/oauth2/v2.0/token" {
+			if r.FormValue("client_id") != "client1" || r.FormValue("client_secret") != "secret1" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, `{"token_type": "Bearer", "expires_in": 3599, "access_token": "token1"}`)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer token1" && !strings.HasPrefix(r.Header.Get("Authorization"), "SharedKey devstoreaccount1:") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if !strings.HasPrefix(r.URL.Path, "/devstoreaccount1/smartstore") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Method == denied {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.Method {
+		case http.MethodPut:
+			if r.Header.Get("x-ms-blob-type") != "BlockBlob" || r.Header.Get("Content-Length") == "" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			blobs[r.URL.Path], _ = ioutil.ReadAll(r.Body)
+			w.WriteHeader(http.StatusCreated)
+		case http.MethodGet:
+			if r.URL.Query().Get("comp") == "list" {
+				fmt.Fprint(w, `<?xml version="1.0" encoding="utf-8"?><EnumerationResults ContainerName="smartstore"><Blobs /><NextMarker /></EnumerationResults>`)
+				return
+			}
+			w.Write(blobs[r.URL.Path])
+		case http.MethodDelete:
+			delete(blobs, r.URL.Path)
+			w.WriteHeader(http.StatusAccepted)
+		}
+	}))
+	defer server.Close()
+
+	os.Setenv(azureAuthorityHostEnv, server.URL)
+	defer os.Unsetenv(azureAuthorityHostEnv)
+
+	azureClient, err := NewAzureBlobClient("smartstore", "devstoreaccount1", azuriteAccountKey, "london/", "", server.URL+"/devstoreaccount1", InitAzureBlobClientWrapper)
+	if err != nil {
+		t.Fatalf("NewAzureBlobClient returned %v", err)
+	}
+	err = azureClient.(*AzureBlobClient).CheckVolumeAccess(".splunk-operator-check")
+	if err != nil {
+		t.Errorf("CheckVolumeAccess() returned %v", err)
+	}
+	if len(blobs) != 0 {
+		t.Errorf("CheckVolumeAccess() should have deleted its test blob, got %v", blobs)
+	}
+
+	// service principals get a token with their client secret
+	servicePrincipal := &AzureBlobClient{Endpoint: server.URL + "/devstoreaccount1", ContainerName: "smartstore", Prefix: "london/",
+		TenantID: "tenant1", ClientID: "client1", ClientSecret: "secret1", Client: azureClient.(*AzureBlobClient).Client}
+	err = servicePrincipal.CheckVolumeAccess(".splunk-operator-check")
+	if err != nil {
+		t.Errorf("CheckVolumeAccess() with a service principal returned %v", err)
+	}
+	servicePrincipal.ClientSecret = "secret2"
+	err = servicePrincipal.CheckVolumeAccess(".splunk-operator-check")
+	if err == nil || !strings.Contains(err.Error(), "Unable to list") {
+		t.Errorf("CheckVolumeAccess() should have failed to list with a wrong client secret, got %v", err)
+	}
+
+	for method, want := range map[string]string{
+		http.MethodPut:    "Unable to put",
+		http.MethodDelete: "Unable to delete",
+	} {
+		denied = method
+		err = azureClient.(*AzureBlobClient).CheckVolumeAccess(".splunk-operator-check")
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("CheckVolumeAccess() with %s denied should have returned %q, got %v", method, want, err)
+		}
+	}
+}
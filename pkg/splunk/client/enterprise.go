@@ -482,6 +482,15 @@ type ClusterMasterPeerInfo struct {
 	// The ID of the configuration bundle currently being used by the manager.
 	ActiveBundleID string `json:"active_bundle_id"`
 
+	// Status of the latest configuration bundle applied to this peer.
+	ApplyBundleStatus struct {
+		// Indicates whether the peer must be restarted to apply the bundle.
+		RestartRequired bool `json:"restart_required_for_apply_bundle"`
+
+		// Reasons why the peer must be restarted.
+		ReasonsForRestart []string `json:"reasons_for_restart"`
+	} `json:"apply_bundle_status"`
+
 	// The initial bundle generation ID recognized by this peer. Any searches from previous generations fail.
 	// The initial bundle generation ID is created when a peer first comes online, restarts, or recontacts the manager.
 	// Note that this is reported as a very large number (18446744073709552000) that breaks Go's JSON library, while the peer is being decommissioned.
@@ -611,6 +620,45 @@ func (c *SplunkClient) RemoveIndexerClusterPeer(id string) error {
 	return c.Do(request, expectedStatus, nil)
 }
 
+// InitRollingUpgrade prepares an indexer cluster for a searchable rolling upgrade, so that its peers can be taken
+// offline one after the other while searches keep running. You can only use this on a cluster manager.
+// See https://docs.splunk.com/Documentation/Splunk/latest/Indexer/Searchablerollingupgrade
+func (c *SplunkClient) InitRollingUpgrade() error {
+	endpoint := fmt.Sprintf("%s/services/cluster/master/control/control/rolling_upgrade_init", c.ManagementURI)
+	request, err := http.NewRequest("POST", endpoint, nil)
+	if err != nil {
+		return err
+	}
+	expectedStatus := []int{200}
+	return c.Do(request, expectedStatus, nil)
+}
+
+// FinalizeRollingUpgrade completes the searchable rolling upgrade of an indexer cluster, once all its peers are upgraded.
+// You can only use this on a cluster manager.
+// See https://docs.splunk.com/Documentation/Splunk/latest/Indexer/Searchablerollingupgrade
+func (c *SplunkClient) FinalizeRollingUpgrade() error {
+	endpoint := fmt.Sprintf("%s/services/cluster/master/control/control/rolling_upgrade_finalize", c.ManagementURI)
+	request, err := http.NewRequest("POST", endpoint, nil)
+	if err != nil {
+		return err
+	}
+	expectedStatus := []int{200}
+	return c.Do(request, expectedStatus, nil)
+}
+
+// RollingRestartIndexerCluster starts a searchable rolling restart of an indexer cluster, which restarts its peers
+// one after the other while searches keep running. You can only use this on a cluster manager.
+// See https://docs.splunk.com/Documentation/Splunk/latest/Indexer/Userollingrestart
+func (c *SplunkClient) RollingRestartIndexerCluster() error {
+	endpoint := fmt.Sprintf("%s/services/cluster/master/control/control/rolling_restart?searchable=true", c.ManagementURI)
+	request, err := http.NewRequest("POST", endpoint, nil)
+	if err != nil {
+		return err
+	}
+	expectedStatus := []int{200}
+	return c.Do(request, expectedStatus, nil)
+}
+
 // DecommissionIndexerClusterPeer takes an indexer cluster peer offline using the decommission endpoint.
 // You can use this on any peer in an indexer cluster.
 // See https://docs.splunk.com/Documentation/Splunk/latest/Indexer/Takeapeeroffline
@@ -894,6 +942,8 @@ type ClusterInfo struct {
 	MultiSite             string `json:"multisite"`
 	ReplicationFactor     int32  `json:"replication_factor"`
 	SiteReplicationFactor string `json:"site_replication_factor,omitempty"`
+	SearchFactor          int32  `json:"search_factor"`
+	SiteSearchFactor      string `json:"site_search_factor,omitempty"`
 }
 
 // GetClusterInfo queries the cluster about multi-site or single-site.
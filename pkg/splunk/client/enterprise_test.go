@@ -362,6 +362,30 @@ func TestRemoveIndexerClusterPeer(t *testing.T) {
 	splunkClientTester(t, "TestRemoveIndexerClusterPeer", 200, "", wantRequest, test)
 }
 
+func TestInitRollingUpgrade(t *testing.T) {
+	wantRequest, _ := http.NewRequest("POST", "https://localhost:8089/services/cluster/master/control/control/rolling_upgrade_init", nil)
+	test := func(c SplunkClient) error {
+		return c.InitRollingUpgrade()
+	}
+	splunkClientTester(t, "TestInitRollingUpgrade", 200, "", wantRequest, test)
+}
+
+func TestFinalizeRollingUpgrade(t *testing.T) {
+	wantRequest, _ := http.NewRequest("POST", "https://localhost:8089/services/cluster/master/control/control/rolling_upgrade_finalize", nil)
+	test := func(c SplunkClient) error {
+		return c.FinalizeRollingUpgrade()
+	}
+	splunkClientTester(t, "TestFinalizeRollingUpgrade", 200, "", wantRequest, test)
+}
+
+func TestRollingRestartIndexerCluster(t *testing.T) {
+	wantRequest, _ := http.NewRequest("POST", "https://localhost:8089/services/cluster/master/control/control/rolling_restart?searchable=true", nil)
+	test := func(c SplunkClient) error {
+		return c.RollingRestartIndexerCluster()
+	}
+	splunkClientTester(t, "TestRollingRestartIndexerCluster", 200, "", wantRequest, test)
+}
+
 func TestDecommissionIndexerClusterPeer(t *testing.T) {
 	wantRequest, _ := http.NewRequest("POST", "https://localhost:8089/services/cluster/slave/control/control/decommission?enforce_counts=1", nil)
 	test := func(c SplunkClient) error {
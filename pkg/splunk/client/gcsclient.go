@@ -0,0 +1,442 @@
+// Copyright (c) 2018-2021 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// blank assignment to verify that GCSClient implements S3Client
+var _ S3Client = &GCSClient{}
+
+// gcsReadOnlyScope is the OAuth2 scope requested for the service account of GCSClient
+const gcsReadOnlyScope = "https://www.googleapis.com/auth/devstorage.read_only"
+
+// gcsReadWriteScope is the OAuth2 scope requested for the service account of GCSClient to check the access to a volume
+const gcsReadWriteScope = "https://www.googleapis.com/auth/devstorage.read_write"
+
+// GCSDefaultEndpoint is the endpoint of the GCS JSON API, used by the volumes without endpoint
+const GCSDefaultEndpoint = "https://storage.googleapis.com"
+
+// gcsMetadataHostEnv is the environment variable overriding the host of the metadata server, as in the Google Cloud
+// client libraries
+const gcsMetadataHostEnv = "GCE_METADATA_HOST"
+
+// gcsDefaultMetadataHost is the host of the metadata server of GCE instances and of GKE workload identity
+const gcsDefaultMetadataHost = "metadata.google.internal"
+
+// GCSClient is a client to list the apps of a Google Cloud Storage bucket
+type GCSClient struct {
+	// GCS JSON API endpoint (e.g. "https://storage.googleapis.com", or "http://127.0.0.1:4443" for fake-gcs-server)
+	Endpoint string
+
+	// name of the bucket
+	BucketName string
+
+	// JSON key of the service account; requests are authenticated with the service account of the pod when it is
+	// empty, if there is a metadata server, or else they are not authenticated
+	ServiceAccountKey string
+
+	// only the objects starting with this prefix are listed
+	Prefix string
+
+	// object listed before the apps, which is excluded from the listing
+	StartAfter string
+
+	Client SplunkHTTPClient
+}
+
+// NewGCSClient returns a Google Cloud Storage client. The access key ID is not used, since
+// the service account key holds all the credentials.
+func NewGCSClient(bucketName string, accessKeyID string, serviceAccountKey string, prefix string, startAfter string, endpoint string, fn GetInitFunc) (S3Client, error) {
+	cl := fn(endpoint, accessKeyID, serviceAccountKey)
+	if cl == nil {
+		return nil, fmt.Errorf("Failed to create a GCS client")
+	}
+
+	return &GCSClient{
+		Endpoint:          strings.TrimSuffix(endpoint, "/"),
+		BucketName:        bucketName,
+		ServiceAccountKey: serviceAccountKey,
+		Prefix:            prefix,
+		StartAfter:        startAfter,
+		Client:            cl.(SplunkHTTPClient),
+	}, nil
+}
+
+// RegisterGCSClient will add the corresponding function pointer to the map
+func RegisterGCSClient() {
+	wrapperObject := GetS3ClientWrapper{GetS3Client: NewGCSClient, GetInitFunc: InitGCSClientWrapper}
+	S3Clients["gcp"] = wrapperObject
+}
+
+// InitGCSClientWrapper is a wrapper around InitGCSClientSession
+func InitGCSClientWrapper(endpoint string, accessKeyID string, serviceAccountKey string) interface{} {
+	return InitGCSClientSession(endpoint, serviceAccountKey)
+}
+
+// InitGCSClientSession returns the HTTP client used to send requests to the GCS JSON API
+func InitGCSClientSession(endpoint string, serviceAccountKey string) SplunkHTTPClient {
+	scopedLog := log.WithName("InitGCSClientSession")
+
+	if !strings.HasPrefix(endpoint, "https://") && !strings.HasPrefix(endpoint, "http://") {
+		scopedLog.Info("Unsupported endpoint for GCS client", "endpoint", endpoint)
+		return nil
+	}
+	if serviceAccountKey != "" {
+		if _, err := parseGCSServiceAccountKey(serviceAccountKey); err != nil {
+			scopedLog.Info("Invalid service account key", "err", err)
+			return nil
+		}
+	}
+
+	return &http.Client{Timeout: 30 * time.Second}
+}
+
+// gcsServiceAccountKey contains the fields of a JSON service account key used to get access tokens
+type gcsServiceAccountKey struct {
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+
+	rsaKey *rsa.PrivateKey
+}
+
+// parseGCSServiceAccountKey parses a JSON service account key and its private key
+func parseGCSServiceAccountKey(serviceAccountKey string) (*gcsServiceAccountKey, error) {
+	key := gcsServiceAccountKey{}
+	err := json.Unmarshal([]byte(serviceAccountKey), &key)
+	if err != nil {
+		return nil, err
+	}
+	if key.ClientEmail == "" || key.TokenURI == "" {
+		return nil, fmt.Errorf("client_email or token_uri is missing")
+	}
+
+	block, _ := pem.Decode([]byte(key.PrivateKey))
+	if block == nil {
+		return nil, fmt.Errorf("private_key is not PEM encoded")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var ok bool
+	if key.rsaKey, ok = parsed.(*rsa.PrivateKey); !ok {
+		return nil, fmt.Errorf("private_key is not a RSA key")
+	}
+
+	return &key, nil
+}
+
+// gcsObjectListing is the response of the objects list method of the GCS JSON API
+type gcsObjectListing struct {
+	Items []struct {
+		Name         string    `json:"name"`
+		Etag         string    `json:"etag"`
+		Size         string    `json:"size"`
+		Updated      time.Time `json:"updated"`
+		StorageClass string    `json:"storageClass"`
+	} `json:"items"`
+	NextPageToken string `json:"nextPageToken"`
+}
+
+// GetAppsList get the list of apps from remote storage
+func (client *GCSClient) GetAppsList() (S3Response, error) {
+	scopedLog := log.WithName("GetAppsList")
+
+	scopedLog.Info("Getting Apps list", "GCS Bucket", client.BucketName, "Prefix", client.Prefix)
+	s3Resp := S3Response{}
+
+	token, err := client.getAccessToken(gcsReadOnlyScope)
+	if err != nil {
+		scopedLog.Error(err, "Unable to get an access token", "GCS Bucket", client.BucketName)
+		return s3Resp, err
+	}
+
+	pageToken := ""
+	for {
+		listing, err := client.listObjects(token, pageToken)
+		if err != nil {
+			scopedLog.Error(err, "Unable to list objects in bucket", "GCS Bucket", client.BucketName)
+			return s3Resp, err
+		}
+
+		for _, object := range listing.Items {
+			// exclude the directory itself from listing
+			if object.Name == client.StartAfter {
+				continue
+			}
+			newKey := object.Name
+			newEtag := object.Etag
+			newLastModified := object.Updated
+			newSize, _ := strconv.ParseInt(object.Size, 10, 64)
+			newStorageClass := object.StorageClass
+			newRemoteObject := RemoteObject{Etag: &newEtag, Key: &newKey, LastModified: &newLastModified, Size: &newSize, StorageClass: &newStorageClass}
+			s3Resp.Objects = append(s3Resp.Objects, &newRemoteObject)
+		}
+
+		if listing.NextPageToken == "" {
+			break
+		}
+		pageToken = listing.NextPageToken
+	}
+
+	if len(s3Resp.Objects) == 0 {
+		return s3Resp, fmt.Errorf("Empty objects list in the bucket: %s", client.BucketName)
+	}
+
+	return s3Resp, nil
+}
+
+// listObjects returns one page of the objects of the bucket, limited to 1 level only
+func (client *GCSClient) listObjects(token string, pageToken string) (*gcsObjectListing, error) {
+	query := url.Values{}
+	query.Set("prefix", client.Prefix)
+	query.Set("delimiter", "/")
+	if pageToken != "" {
+		query.Set("pageToken", pageToken)
+	}
+
+	request, err := http.NewRequest("GET", fmt.Sprintf("%s/storage/v1/b/%s/o?%s", client.Endpoint, url.PathEscape(client.BucketName), query.Encode()), nil)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	response, err := client.Client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Unexpected status code %d listing objects of bucket %s", response.StatusCode, client.BucketName)
+	}
+
+	listing := gcsObjectListing{}
+	err = json.NewDecoder(response.Body).Decode(&listing)
+	return &listing, err
+}
+
+// getAccessToken exchanges a JWT signed by the service account for an OAuth2 access token of a scope.
+// It returns the token of the workload identity of the pod when no service account key is used.
+func (client *GCSClient) getAccessToken(scope string) (string, error) {
+	if client.ServiceAccountKey == "" {
+		return client.getWorkloadIdentityToken(scope)
+	}
+	key, err := parseGCSServiceAccountKey(client.ServiceAccountKey)
+	if err != nil {
+		return "", err
+	}
+
+	encode := func(v interface{}) string {
+		data, _ := json.Marshal(v)
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	now := time.Now().Unix()
+	header := encode(map[string]string{"alg": "RS256", "typ": "JWT"})
+	claims := encode(map[string]interface{}{
+		"iss":   key.ClientEmail,
+		"scope": scope,
+		"aud":   key.TokenURI,
+		"iat":   now,
+		"exp":   now + 3600,
+	})
+	digest := sha256.Sum256([]byte(header + "." + claims))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key.rsaKey, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	assertion := header + "." + claims + "." + base64.RawURLEncoding.EncodeToString(signature)
+
+	form := url.Values{}
+	form.Set("grant_type", "urn:ietf:params:oauth:grant-type:jwt-bearer")
+	form.Set("assertion", assertion)
+	request, err := http.NewRequest("POST", key.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	return getOAuth2AccessToken(client.Client, request)
+}
+
+// getWorkloadIdentityToken returns an access token of the service account of the pod from the metadata server, which
+// GKE provides to the pods with workload identity. It returns an empty token when there is no metadata server, e.g.
+// for the emulators, whose requests are not authenticated.
+func (client *GCSClient) getWorkloadIdentityToken(scope string) (string, error) {
+	host := os.Getenv(gcsMetadataHostEnv)
+	if host == "" {
+		host = gcsDefaultMetadataHost
+	}
+	tokenURL := fmt.Sprintf("http://%s/computeMetadata/v1/instance/service-accounts/default/token?scopes=%s", host, url.QueryEscape(scope))
+	request, err := http.NewRequest("GET", tokenURL, nil)
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("Metadata-Flavor", "Google")
+
+	token, err := getOAuth2AccessToken(client.Client, request)
+	if _, unreachable := err.(*url.Error); unreachable {
+		log.WithName("getWorkloadIdentityToken").Info("No metadata server. Sending requests without authentication", "host", host)
+		return "", nil
+	}
+	return token, err
+}
+
+// gcsObjectMetadata is the response of the objects get method of the GCS JSON API, without the alt=media parameter
+type gcsObjectMetadata struct {
+	Etag       string `json:"etag"`
+	Generation string `json:"generation"`
+}
+
+// DownloadApp downloads an app package from remote storage to a local file, failing if its etag changed. Since GCS
+// only supports preconditions on the generation of the objects, the etag is checked against the metadata of the
+// object, and the generation of this metadata is downloaded.
+func (client *GCSClient) DownloadApp(remoteFile string, localFile string, etag string) error {
+	scopedLog := log.WithName("DownloadApp")
+
+	token, err := client.getAccessToken(gcsReadOnlyScope)
+	if err != nil {
+		scopedLog.Error(err, "Unable to get an access token", "GCS Bucket", client.BucketName)
+		return err
+	}
+
+	objectURL := fmt.Sprintf("%s/storage/v1/b/%s/o/%s", client.Endpoint, url.PathEscape(client.BucketName), url.PathEscape(remoteFile))
+	newRequest := func(query string) (*http.Request, error) {
+		request, err := http.NewRequest("GET", objectURL+query, nil)
+		if err == nil && token != "" {
+			request.Header.Set("Authorization", "Bearer "+token)
+		}
+		return request, err
+	}
+
+	query := "?alt=media"
+	if etag != "" {
+		request, err := newRequest("")
+		if err != nil {
+			return err
+		}
+		response, err := client.Client.Do(request)
+		if err != nil {
+			return err
+		}
+		defer response.Body.Close()
+		if response.StatusCode != http.StatusOK {
+			return fmt.Errorf("Unexpected status code %d getting the metadata of %s", response.StatusCode, remoteFile)
+		}
+		metadata := gcsObjectMetadata{}
+		if err = json.NewDecoder(response.Body).Decode(&metadata); err != nil {
+			return err
+		}
+		if metadata.Etag != etag {
+			return fmt.Errorf("The etag of %s is %s instead of %s", remoteFile, metadata.Etag, etag)
+		}
+		query += "&generation=" + url.QueryEscape(metadata.Generation)
+	}
+
+	request, err := newRequest(query)
+	if err != nil {
+		return err
+	}
+	err = downloadHTTPObject(client.Client, request, localFile, "")
+	if err != nil {
+		scopedLog.Error(err, "Unable to download object", "GCS Bucket", client.BucketName, "remoteFile", remoteFile)
+		return err
+	}
+
+	return nil
+}
+
+// CheckVolumeAccess checks that a remote storage volume is usable by Splunk, by listing the objects under the prefix
+// of the client, then putting, getting back and deleting a test object under this prefix
+func (client *GCSClient) CheckVolumeAccess(objectName string) error {
+	token, err := client.getAccessToken(gcsReadWriteScope)
+	if err != nil {
+		return fmt.Errorf("Unable to get an access token for %s: %v", client.BucketName, err)
+	}
+
+	_, err = client.listObjects(token, "")
+	if err != nil {
+		return fmt.Errorf("Unable to list the objects of %s/%s: %v", client.BucketName, client.Prefix, err)
+	}
+
+	name := client.Prefix + objectName
+	objectURL := fmt.Sprintf("%s/storage/v1/b/%s/o/%s", client.Endpoint, url.PathEscape(client.BucketName), url.PathEscape(name))
+	content := []byte(fmt.Sprintf("splunk-operator access check %d", time.Now().UnixNano()))
+	uploadURL := fmt.Sprintf("%s/upload/storage/v1/b/%s/o?uploadType=media&name=%s", client.Endpoint, url.PathEscape(client.BucketName), url.QueryEscape(name))
+	_, err = client.doObjectRequest("POST", uploadURL, token, content, http.StatusOK)
+	if err != nil {
+		return fmt.Errorf("Unable to put the object %s/%s: %v", client.BucketName, name, err)
+	}
+
+	got, err := client.doObjectRequest("GET", objectURL+"?alt=media", token, nil, http.StatusOK)
+	if err != nil {
+		return fmt.Errorf("Unable to get the object %s/%s: %v", client.BucketName, name, err)
+	}
+	if !bytes.Equal(got, content) {
+		return fmt.Errorf("The object %s/%s read back does not match the object put", client.BucketName, name)
+	}
+
+	_, err = client.doObjectRequest("DELETE", objectURL, token, nil, http.StatusNoContent)
+	if err != nil {
+		return fmt.Errorf("Unable to delete the object %s/%s: %v", client.BucketName, name, err)
+	}
+
+	return nil
+}
+
+// doObjectRequest sends a request to the GCS JSON API and returns the body of its response, failing if its status
+// code is not the expected one
+func (client *GCSClient) doObjectRequest(method string, rawURL string, token string, body []byte, expectedStatus int) ([]byte, error) {
+	request, err := http.NewRequest(method, rawURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	response, err := client.Client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != expectedStatus {
+		return nil, fmt.Errorf("Unexpected status code %d", response.StatusCode)
+	}
+	return ioutil.ReadAll(response.Body)
+}
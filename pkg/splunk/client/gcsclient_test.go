@@ -0,0 +1,305 @@
+// Copyright (c) 2018-2021 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTestGCSServiceAccountKey returns a JSON service account key using a token endpoint
+func newTestGCSServiceAccountKey(t *testing.T, tokenURI string) string {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Unable to generate a RSA key: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(rsaKey)
+	if err != nil {
+		t.Fatalf("Unable to marshal the RSA key: %v", err)
+	}
+	key, _ := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"client_email": "apps@splunk.iam.gserviceaccount.com",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"token_uri":    tokenURI,
+	})
+	return string(key)
+}
+
+func TestInitGCSClientWrapper(t *testing.T) {
+	if InitGCSClientWrapper("http://127.0.0.1:4443", "", "") == nil {
+		t.Errorf("We should have got a valid GCS client object")
+	}
+
+	if InitGCSClientWrapper("http://127.0.0.1:4443", "", newTestGCSServiceAccountKey(t, "https://oauth2.googleapis.com/token")) == nil {
+		t.Errorf("We should have got a valid GCS client object for a service account key")
+	}
+
+	if InitGCSClientWrapper("storage.googleapis.com", "", "") != nil {
+		t.Errorf("We should not have got a GCS client object for an endpoint without scheme")
+	}
+
+	if InitGCSClientWrapper("https://storage.googleapis.com", "", `{"client_email": "apps@splunk.iam.gserviceaccount.com"}`) != nil {
+		t.Errorf("We should not have got a GCS client object for an invalid service account key")
+	}
+}
+
+func TestNewGCSClient(t *testing.T) {
+	gcsClient, err := NewGCSClient("apps", "", "", "adminApps/", "adminApps/", "https://storage.googleapis.com/", InitGCSClientWrapper)
+	if gcsClient == nil || err != nil {
+		t.Fatalf("NewGCSClient should have returned a valid GCS client.")
+	}
+	if gcsClient.(*GCSClient).Endpoint != "https://storage.googleapis.com" {
+		t.Errorf("NewGCSClient should have trimmed the endpoint, got %s", gcsClient.(*GCSClient).Endpoint)
+	}
+
+	gcsClient, err = NewGCSClient("apps", "", "", "adminApps/", "adminApps/", "random-endpoint.com", InitGCSClientWrapper)
+	if gcsClient != nil || err == nil {
+		t.Errorf("NewGCSClient should have returned a error.")
+	}
+}
+
+func TestGCSGetAppsList(t *testing.T) {
+	// emulate the token endpoint of Google and the objects list method of fake-gcs-server, returning the apps on two pages
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			if r.FormValue("grant_type") != "urn:ietf:params:oauth:grant-type:jwt-bearer" || len(strings.Split(r.FormValue("assertion"), ".")) != 3 {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			fmt.Fprint(w, `{"access_token": "token1", "token_type": "Bearer", "expires_in": 3600}`)
+			return
+		}
+
+		query := r.URL.Query()
+		if r.URL.Path != "/storage/v1/b/apps/o" || query.Get("prefix") != "adminApps/" || query.Get("delimiter") != "/" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer token1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if query.Get("pageToken") == "" {
+			fmt.Fprint(w, `{"kind": "storage#objects", "prefixes": ["adminApps/old/"], "nextPageToken": "page2", "items": [`+
+				`{"name": "adminApps/", "size": "0"},`+
+				`{"name": "adminApps/app1.tgz", "etag": "CJjWq", "size": "1024", "updated": "2021-10-18T10:00:00.000Z", "storageClass": "STANDARD"}]}`)
+			return
+		}
+		fmt.Fprint(w, `{"kind": "storage#objects", "items": [`+
+			`{"name": "adminApps/app2.tgz", "etag": "CJjWr", "size": "2048", "updated": "2021-10-19T10:00:00.000Z", "storageClass": "NEARLINE"}]}`)
+	}))
+	defer server.Close()
+
+	gcsClient, err := NewGCSClient("apps", "", newTestGCSServiceAccountKey(t, server.URL+"/token"), "adminApps/", "adminApps/", server.URL, InitGCSClientWrapper)
+	if err != nil {
+		t.Fatalf("NewGCSClient returned %v", err)
+	}
+	resp, err := gcsClient.GetAppsList()
+	if err != nil || len(resp.Objects) != 2 {
+		t.Fatalf("GetAppsList() = %v, %v; want 2 apps", resp, err)
+	}
+	if *resp.Objects[0].Key != "adminApps/app1.tgz" || *resp.Objects[0].Etag != "CJjWq" || *resp.Objects[0].Size != 1024 ||
+		*resp.Objects[0].StorageClass != "STANDARD" || resp.Objects[0].LastModified.Day() != 18 {
+		t.Errorf("GetAppsList() returned an invalid first app: %+v", resp.Objects[0])
+	}
+	if *resp.Objects[1].Key != "adminApps/app2.tgz" {
+		t.Errorf("GetAppsList() returned an invalid second app: %s", *resp.Objects[1].Key)
+	}
+
+	// unauthenticated requests are rejected
+	gcsClient.(*GCSClient).ServiceAccountKey = ""
+	_, err = gcsClient.GetAppsList()
+	if err == nil {
+		t.Errorf("GetAppsList() should have returned an error for unauthenticated requests")
+	}
+}
+
+func TestGCSDownloadApp(t *testing.T) {
+	// emulate the token endpoint of Google and the objects get method of fake-gcs-server
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			fmt.Fprint(w, `{"access_token": "token1", "token_type": "Bearer", "expires_in": 3600}`)
+			return
+		}
+		if r.URL.Path != "/storage/v1/b/apps/o/adminApps/app1.tgz" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer token1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Query().Get("alt") != "media" {
+			fmt.Fprint(w, `{"name": "adminApps/app1.tgz", "etag": "CJjWq", "generation": "1623456789"}`)
+			return
+		}
+		if r.URL.Query().Get("generation") != "1623456789" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, "app1 content")
+	}))
+	defer server.Close()
+
+	gcsClient, err := NewGCSClient("apps", "", newTestGCSServiceAccountKey(t, server.URL+"/token"), "adminApps/", "adminApps/", server.URL, InitGCSClientWrapper)
+	if err != nil {
+		t.Fatalf("NewGCSClient returned %v", err)
+	}
+
+	dir, err := ioutil.TempDir("", "gcsclient")
+	if err != nil {
+		t.Fatalf("Unable to create a temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	localFile := filepath.Join(dir, "app1.tgz")
+	err = gcsClient.DownloadApp("adminApps/app1.tgz", localFile, "CJjWq")
+	if err != nil {
+		t.Fatalf("DownloadApp() returned %v", err)
+	}
+	content, err := ioutil.ReadFile(localFile)
+	if err != nil || string(content) != "app1 content" {
+		t.Errorf("DownloadApp() wrote %s, %v; want app1 content", content, err)
+	}
+
+	err = gcsClient.DownloadApp("adminApps/app1.tgz", filepath.Join(dir, "app1-changed.tgz"), "CJjWr")
+	if err == nil {
+		t.Errorf("DownloadApp() should have returned an error for a changed etag")
+	}
+
+	err = gcsClient.DownloadApp("adminApps/app2.tgz", filepath.Join(dir, "app2.tgz"), "CJjWr")
+	if err == nil {
+		t.Errorf("DownloadApp() should have returned an error for a missing object")
+	}
+}
+
+func TestGCSWorkloadIdentity(t *testing.T) {
+	// emulate the metadata server of GKE and the objects list method of the GCS JSON API
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/computeMetadata/v1/instance/service-accounts/default/token" {
+			if r.Header.Get("Metadata-Flavor") != "Google" || r.URL.Query().Get("scopes") != gcsReadOnlyScope {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			fmt.Fprint(w, `{"access_token": "token1", "token_type": "Bearer", "expires_in": 3599}`)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer token1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"kind": "storage#objects", "items": [{"name": "adminApps/app1.tgz", "etag": "CJjWq", "size": "1024"}]}`)
+	}))
+	defer server.Close()
+
+	os.Setenv(gcsMetadataHostEnv, strings.TrimPrefix(server.URL, "http://"))
+	defer os.Unsetenv(gcsMetadataHostEnv)
+
+	gcsClient, err := NewGCSClient("apps", "", "", "adminApps/", "adminApps/", server.URL, InitGCSClientWrapper)
+	if err != nil {
+		t.Fatalf("NewGCSClient returned %v", err)
+	}
+	resp, err := gcsClient.GetAppsList()
+	if err != nil || len(resp.Objects) != 1 {
+		t.Errorf("GetAppsList() = %v, %v; want 1 app", resp, err)
+	}
+
+	// requests are not authenticated without metadata server
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachable.Close()
+	os.Setenv(gcsMetadataHostEnv, strings.TrimPrefix(unreachable.URL, "http://"))
+	token, err := gcsClient.(*GCSClient).getAccessToken(gcsReadOnlyScope)
+	if token != "" || err != nil {
+		t.Errorf("getAccessToken() = %s, %v; want no token without metadata server", token, err)
+	}
+}
+
+func TestGCSCheckVolumeAccess(t *testing.T) {
+	// emulate the token endpoint of Google and the object methods of fake-gcs-server, in memory
+	objects := map[string][]byte{}
+	denied := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			fmt.Fprint(w, `{"access_token": "token1", "token_type": "Bearer", "expires_in": 3600}`)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer token1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Method == denied {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/upload/storage/v1/b/smartstore/o" && r.URL.Query().Get("uploadType") == "media":
+			objects[r.URL.Query().Get("name")], _ = ioutil.ReadAll(r.Body)
+			fmt.Fprint(w, `{"kind": "storage#object"}`)
+		case r.Method == http.MethodGet && r.URL.Path == "/storage/v1/b/smartstore/o":
+			fmt.Fprint(w, `{"kind": "storage#objects"}`)
+		case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/storage/v1/b/smartstore/o/"):
+			w.Write(objects[strings.TrimPrefix(r.URL.Path, "/storage/v1/b/smartstore/o/")])
+		case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/storage/v1/b/smartstore/o/"):
+			delete(objects, strings.TrimPrefix(r.URL.Path, "/storage/v1/b/smartstore/o/"))
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	gcsClient, err := NewGCSClient("smartstore", "", newTestGCSServiceAccountKey(t, server.URL+"/token"), "london/", "", server.URL, InitGCSClientWrapper)
+	if err != nil {
+		t.Fatalf("NewGCSClient returned %v", err)
+	}
+	err = gcsClient.(*GCSClient).CheckVolumeAccess(".splunk-operator-check")
+	if err != nil {
+		t.Errorf("CheckVolumeAccess() returned %v", err)
+	}
+	if len(objects) != 0 {
+		t.Errorf("CheckVolumeAccess() should have deleted its test object, got %v", objects)
+	}
+
+	gcsClient.(*GCSClient).BucketName = "missing-bucket"
+	err = gcsClient.(*GCSClient).CheckVolumeAccess(".splunk-operator-check")
+	if err == nil || !strings.Contains(err.Error(), "Unable to list") {
+		t.Errorf("CheckVolumeAccess() should have failed to list a missing bucket, got %v", err)
+	}
+
+	gcsClient.(*GCSClient).BucketName = "smartstore"
+	for method, want := range map[string]string{
+		http.MethodPost:   "Unable to put",
+		http.MethodDelete: "Unable to delete",
+	} {
+		denied = method
+		err = gcsClient.(*GCSClient).CheckVolumeAccess(".splunk-operator-check")
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("CheckVolumeAccess() with %s denied should have returned %q, got %v", method, want, err)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright (c) 2018-2021 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// blank assignment to verify that HTTPIndexClient implements S3Client
+var _ S3Client = &HTTPIndexClient{}
+
+// HTTPAppIndexFile is the name of the index file listing the apps of an app source served over HTTP(S).
+// It uses the format of the output of sha256sum, so that it can be generated by "sha256sum *.tgz *.spl > SHA256SUMS".
+const HTTPAppIndexFile = "SHA256SUMS"
+
+// HTTPIndexClient is a client to list the apps of a web server from their index file
+type HTTPIndexClient struct {
+	// URL of the web server (e.g. "https://apps.example.com")
+	Endpoint string
+
+	// first directory of the path of the apps on the web server
+	BucketName string
+
+	// path of the apps under the first directory, ending with a "/"
+	Prefix string
+
+	// file listed before the apps, which is excluded from the listing
+	StartAfter string
+
+	Client SplunkHTTPClient
+}
+
+// NewHTTPIndexClient returns a client for the apps of a web server. The web server does not use any credentials.
+func NewHTTPIndexClient(bucketName string, accessKeyID string, secretAccessKey string, prefix string, startAfter string, endpoint string, fn GetInitFunc) (S3Client, error) {
+	cl := fn(endpoint, accessKeyID, secretAccessKey)
+	if cl == nil {
+		return nil, fmt.Errorf("Failed to create a HTTP index client")
+	}
+
+	return &HTTPIndexClient{
+		Endpoint:   strings.TrimSuffix(endpoint, "/"),
+		BucketName: bucketName,
+		Prefix:     prefix,
+		StartAfter: startAfter,
+		Client:     cl.(SplunkHTTPClient),
+	}, nil
+}
+
+// RegisterHTTPIndexClient will add the corresponding function pointer to the map
+func RegisterHTTPIndexClient() {
+	wrapperObject := GetS3ClientWrapper{GetS3Client: NewHTTPIndexClient, GetInitFunc: InitHTTPIndexClientWrapper}
+	S3Clients["http"] = wrapperObject
+}
+
+// InitHTTPIndexClientWrapper is a wrapper around InitHTTPIndexClientSession
+func InitHTTPIndexClientWrapper(endpoint string, accessKeyID string, secretAccessKey string) interface{} {
+	return InitHTTPIndexClientSession(endpoint)
+}
+
+// InitHTTPIndexClientSession returns the HTTP client used to download the index files
+func InitHTTPIndexClientSession(endpoint string) SplunkHTTPClient {
+	scopedLog := log.WithName("InitHTTPIndexClientSession")
+
+	if !strings.HasPrefix(endpoint, "https://") && !strings.HasPrefix(endpoint, "http://") {
+		scopedLog.Info("Unsupported endpoint for HTTP index client", "endpoint", endpoint)
+		return nil
+	}
+
+	return &http.Client{Timeout: 30 * time.Second}
+}
+
+// getAppSrcURL returns the URL of the directory of an app source on the web server, ending with a "/"
+func getAppSrcURL(endpoint string, bucket string, path string) string {
+	return fmt.Sprintf("%s/%s/", strings.TrimSuffix(endpoint, "/"), strings.Trim(filepath.Join(bucket, path), "/"))
+}
+
+// GetAppsList get the list of apps from remote storage
+func (client *HTTPIndexClient) GetAppsList() (S3Response, error) {
+	scopedLog := log.WithName("GetAppsList")
+
+	indexURL := getAppSrcURL(client.Endpoint, client.BucketName, client.Prefix) + HTTPAppIndexFile
+	scopedLog.Info("Getting Apps list", "Index", indexURL)
+	s3Resp := S3Response{}
+
+	request, err := http.NewRequest("GET", indexURL, nil)
+	if err != nil {
+		return s3Resp, err
+	}
+	response, err := client.Client.Do(request)
+	if err != nil {
+		scopedLog.Error(err, "Unable to get the index file", "Index", indexURL)
+		return s3Resp, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return s3Resp, fmt.Errorf("Unexpected status code %d getting the index file %s", response.StatusCode, indexURL)
+	}
+
+	// each line has the sha256 of an app, followed by its name, which starts with a "*" in binary mode
+	scanner := bufio.NewScanner(response.Body)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		name := strings.TrimPrefix(fields[1], "*")
+		// limit the listing to 1 level only
+		if strings.Contains(name, "/") {
+			continue
+		}
+		newKey := client.Prefix + name
+		if newKey == client.StartAfter {
+			continue
+		}
+		newEtag := fields[0]
+		newRemoteObject := RemoteObject{Etag: &newEtag, Key: &newKey}
+		s3Resp.Objects = append(s3Resp.Objects, &newRemoteObject)
+	}
+	if err = scanner.Err(); err != nil {
+		return s3Resp, err
+	}
+
+	if len(s3Resp.Objects) == 0 {
+		return s3Resp, fmt.Errorf("Empty apps list in the index file: %s", indexURL)
+	}
+
+	return s3Resp, nil
+}
+
+// DownloadApp downloads an app package from the web server to a local file, and checks it against the sha256
+// of the index file
+func (client *HTTPIndexClient) DownloadApp(remoteFile string, localFile string, etag string) error {
+	scopedLog := log.WithName("DownloadApp")
+
+	nameAt := strings.LastIndex(remoteFile, "/")
+	appURL := getAppSrcURL(client.Endpoint, client.BucketName, remoteFile[:nameAt+1]) + remoteFile[nameAt+1:]
+	request, err := http.NewRequest("GET", appURL, nil)
+	if err != nil {
+		return err
+	}
+
+	err = downloadHTTPObject(client.Client, request, localFile, etag)
+	if err != nil {
+		scopedLog.Error(err, "Unable to download app", "URL", appURL)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,121 @@
+// Copyright (c) 2018-2021 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewHTTPIndexClient(t *testing.T) {
+	httpClient, err := NewHTTPIndexClient("apps", "", "", "adminApps/", "adminApps/", "http://apps.example.com/", InitHTTPIndexClientWrapper)
+	if httpClient == nil || err != nil {
+		t.Fatalf("NewHTTPIndexClient should have returned a valid HTTP index client.")
+	}
+	if httpClient.(*HTTPIndexClient).Endpoint != "http://apps.example.com" {
+		t.Errorf("NewHTTPIndexClient should have trimmed the endpoint, got %s", httpClient.(*HTTPIndexClient).Endpoint)
+	}
+
+	httpClient, err = NewHTTPIndexClient("apps", "", "", "adminApps/", "adminApps/", "apps.example.com", InitHTTPIndexClientWrapper)
+	if httpClient != nil || err == nil {
+		t.Errorf("NewHTTPIndexClient should have returned a error.")
+	}
+}
+
+func TestHTTPIndexGetAppsList(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/apps/adminApps/SHA256SUMS" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08  app1.tgz\n"+
+			"60303ae22b998861bce3b28f33eec1be758a213c86c93c076dbe9f558c11c752 *app2.spl\n"+
+			"fd61a03af4f77d870fc21e05e7e80678095c92d808cfb3b5c279ee04c74aca13  old/app3.tgz\n")
+	}))
+	defer server.Close()
+
+	httpClient, err := NewHTTPIndexClient("apps", "", "", "adminApps/", "adminApps/", server.URL, InitHTTPIndexClientWrapper)
+	if err != nil {
+		t.Fatalf("NewHTTPIndexClient returned %v", err)
+	}
+	resp, err := httpClient.GetAppsList()
+	if err != nil || len(resp.Objects) != 2 {
+		t.Fatalf("GetAppsList() = %v, %v; want 2 apps", resp, err)
+	}
+	if *resp.Objects[0].Key != "adminApps/app1.tgz" || *resp.Objects[0].Etag != "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08" {
+		t.Errorf("GetAppsList() returned an invalid first app: %+v", resp.Objects[0])
+	}
+	if *resp.Objects[1].Key != "adminApps/app2.spl" {
+		t.Errorf("GetAppsList() returned an invalid second app: %s", *resp.Objects[1].Key)
+	}
+
+	// missing index file
+	httpClient.(*HTTPIndexClient).Prefix = "userApps/"
+	_, err = httpClient.GetAppsList()
+	if err == nil {
+		t.Errorf("GetAppsList() should have returned an error for a missing index file")
+	}
+}
+
+func TestHTTPIndexDownloadApp(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/apps/adminApps/app1.tgz" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, "test")
+	}))
+	defer server.Close()
+
+	httpClient, err := NewHTTPIndexClient("apps", "", "", "adminApps/", "adminApps/", server.URL, InitHTTPIndexClientWrapper)
+	if err != nil {
+		t.Fatalf("NewHTTPIndexClient returned %v", err)
+	}
+
+	dir, err := ioutil.TempDir("", "httpclient")
+	if err != nil {
+		t.Fatalf("Unable to create a temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// sha256 of "test"
+	localFile := filepath.Join(dir, "app1.tgz")
+	err = httpClient.DownloadApp("adminApps/app1.tgz", localFile, "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08")
+	if err != nil {
+		t.Fatalf("DownloadApp() returned %v", err)
+	}
+	content, err := ioutil.ReadFile(localFile)
+	if err != nil || string(content) != "test" {
+		t.Errorf("DownloadApp() wrote %s, %v; want test", content, err)
+	}
+
+	err = httpClient.DownloadApp("adminApps/app1.tgz", filepath.Join(dir, "app2.tgz"), "60303ae22b998861bce3b28f33eec1be758a213c86c93c076dbe9f558c11c752")
+	if err == nil {
+		t.Errorf("DownloadApp() should have returned an error for an invalid sha256")
+	}
+	if _, err = os.Stat(filepath.Join(dir, "app2.tgz")); !os.IsNotExist(err) {
+		t.Errorf("DownloadApp() should not have kept an app with an invalid sha256")
+	}
+
+	err = httpClient.DownloadApp("adminApps/app3.tgz", filepath.Join(dir, "app3.tgz"), "")
+	if err == nil {
+		t.Errorf("DownloadApp() should have returned an error for a missing app")
+	}
+}
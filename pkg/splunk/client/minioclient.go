@@ -17,19 +17,20 @@ package client
 import (
 	"context"
 	"fmt"
-	"path/filepath"
 	"strings"
 
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
 )
 
-// blank assignment to verify that MinioClient implements S3Client
+// blank assignment to verify that MinioClient implements S3Client and S3VersionedClient
 var _ S3Client = &MinioClient{}
+var _ S3VersionedClient = &MinioClient{}
 
 // SplunkMinioClient is an interface to Minio S3 client
 type SplunkMinioClient interface {
 	ListObjects(ctx context.Context, bucketName string, opts minio.ListObjectsOptions) <-chan minio.ObjectInfo
+	FGetObject(ctx context.Context, bucketName, objectName, filePath string, opts minio.GetObjectOptions) error
 }
 
 // MinioClient is a client to implement S3 specific APIs
@@ -156,15 +157,55 @@ func (client *MinioClient) GetAppsList() (S3Response, error) {
 	return s3Resp, nil
 }
 
-// GetInitContainerImage returns the initContainer image to be used with this s3 client
-func (client *MinioClient) GetInitContainerImage() string {
-	return ("amazon/aws-cli")
+// DownloadApp downloads an app package from remote storage to a local file, failing if its etag changed. The
+// etag is not checked when it is empty.
+func (client *MinioClient) DownloadApp(remoteFile string, localFile string, etag string) error {
+	scopedLog := log.WithName("DownloadApp")
+
+	opts := minio.GetObjectOptions{}
+	if etag != "" {
+		err := opts.SetMatchETag(etag)
+		if err != nil {
+			return err
+		}
+	}
+	err := client.Client.FGetObject(context.Background(), client.BucketName, remoteFile, localFile, opts)
+	if err != nil {
+		scopedLog.Error(err, "Unable to download item", "S3 Bucket", client.BucketName, "remoteFile", remoteFile)
+		return err
+	}
+
+	return nil
 }
 
-// GetInitContainerCmd returns the init container command on a per app source basis to be used by the initContainer
-func (client *MinioClient) GetInitContainerCmd(endpoint string, bucket string, path string, appSrcName string, appMnt string) []string {
-	s3AppSrcPath := filepath.Join(bucket, path) + "/"
-	podSyncPath := filepath.Join(appMnt, appSrcName) + "/"
+// DownloadAppVersion downloads the version of an app package matching an etag, which may not be its latest version
+func (client *MinioClient) DownloadAppVersion(remoteFile string, localFile string, etag string) error {
+	scopedLog := log.WithName("DownloadAppVersion")
+
+	// stop the listing once the version is found
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	opts := minio.ListObjectsOptions{
+		Prefix:       remoteFile,
+		WithVersions: true,
+	}
+	for object := range client.Client.ListObjects(ctx, client.BucketName, opts) {
+		if object.Err != nil {
+			scopedLog.Error(object.Err, "Unable to list item versions", "S3 Bucket", client.BucketName, "remoteFile", remoteFile)
+			return object.Err
+		}
+		if object.Key != remoteFile || object.ETag != etag || object.IsDeleteMarker {
+			continue
+		}
+
+		err := client.Client.FGetObject(ctx, client.BucketName, remoteFile, localFile, minio.GetObjectOptions{VersionID: object.VersionID})
+		if err != nil {
+			scopedLog.Error(err, "Unable to download item version", "S3 Bucket", client.BucketName, "remoteFile", remoteFile, "versionId", object.VersionID)
+			return err
+		}
+		return nil
+	}
 
-	return ([]string{fmt.Sprintf("--endpoint-url=%s", endpoint), "s3", "sync", fmt.Sprintf("s3://%s", s3AppSrcPath), podSyncPath})
+	return fmt.Errorf("No version of %s with etag %s in the bucket: %s", remoteFile, etag, client.BucketName)
 }
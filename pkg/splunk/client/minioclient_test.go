@@ -15,8 +15,14 @@
 package client
 
 import (
-	"reflect"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/minio/minio-go/v7"
 )
 
 func TestInitMinioClientWrapper(t *testing.T) {
@@ -50,20 +56,94 @@ func TestNewMinioClient(t *testing.T) {
 	}
 }
 
-func TestMinioGetInitContainerImage(t *testing.T) {
-	minioClient := &MinioClient{}
+// mockMinioClient downloads the objects of a map from object name to etag, as empty files, and the versions of
+// the objects listed with their versions
+type mockMinioClient struct {
+	etags    map[string]string
+	versions []minio.ObjectInfo
+}
 
-	if minioClient.GetInitContainerImage() != "amazon/aws-cli" {
-		t.Errorf("Got invalid init container image for Minio client.")
+// ListObjects lists the versions of the objects, which are the only listing used by the tests of mockMinioClient
+func (c mockMinioClient) ListObjects(ctx context.Context, bucketName string, opts minio.ListObjectsOptions) <-chan minio.ObjectInfo {
+	objects := make(chan minio.ObjectInfo, len(c.versions))
+	if opts.WithVersions {
+		for _, version := range c.versions {
+			if strings.HasPrefix(version.Key, opts.Prefix) {
+				objects <- version
+			}
+		}
 	}
+	close(objects)
+	return objects
 }
 
-func TestGetMinioInitContainerCmd(t *testing.T) {
-	wantCmd := []string{"--endpoint-url=https://s3.us-west-2.amazonaws.com", "s3", "sync", "s3://sample_bucket/admin/", "/mnt/apps-local/admin/"}
+// FGetObject creates an empty file for the objects of the map, if their etag matches, or for their versions
+func (c mockMinioClient) FGetObject(ctx context.Context, bucketName, objectName, filePath string, opts minio.GetObjectOptions) error {
+	if opts.VersionID != "" {
+		for _, version := range c.versions {
+			if version.Key == objectName && version.VersionID == opts.VersionID {
+				return ioutil.WriteFile(filePath, nil, 0644)
+			}
+		}
+		return minio.ErrorResponse{Code: "NoSuchVersion", StatusCode: 404}
+	}
+	etag, ok := c.etags[objectName]
+	if !ok {
+		return minio.ErrorResponse{Code: "NoSuchKey", StatusCode: 404}
+	}
+	if opts.Header().Get("If-Match") != "\""+etag+"\"" {
+		return minio.ErrorResponse{Code: "PreconditionFailed", StatusCode: 412}
+	}
+	return ioutil.WriteFile(filePath, nil, 0644)
+}
+
+func TestMinioDownloadApp(t *testing.T) {
+	minioClient := &MinioClient{BucketName: "sample_bucket", Client: mockMinioClient{etags: map[string]string{"admin/admin_app.tgz": "cc707187b036405f095a8ebb43a782c1"}}}
+
+	dir, err := ioutil.TempDir("", "minioclient")
+	if err != nil {
+		t.Fatalf("Unable to create a temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	localFile := filepath.Join(dir, "admin_app.tgz")
+	err = minioClient.DownloadApp("admin/admin_app.tgz", localFile, "cc707187b036405f095a8ebb43a782c1")
+	if err != nil {
+		t.Fatalf("DownloadApp() returned %v", err)
+	}
+	if _, err = os.Stat(localFile); err != nil {
+		t.Errorf("DownloadApp() should have written %s: %v", localFile, err)
+	}
+
+	err = minioClient.DownloadApp("admin/admin_app.tgz", localFile, "5055a61b3d1b667a4c3279a381a2e7ae")
+	if err == nil {
+		t.Errorf("DownloadApp() should have returned an error for a changed etag")
+	}
+}
+
+func TestMinioDownloadAppVersion(t *testing.T) {
+	minioClient := &MinioClient{BucketName: "sample_bucket", Client: mockMinioClient{versions: []minio.ObjectInfo{
+		{Key: "admin/admin_app.tgz", ETag: "cc707187b036405f095a8ebb43a782c1", VersionID: "v2"},
+		{Key: "admin/admin_app.tgz", ETag: "5055a61b3d1b667a4c3279a381a2e7ae", VersionID: "v1"},
+	}}}
+
+	dir, err := ioutil.TempDir("", "minioclient")
+	if err != nil {
+		t.Fatalf("Unable to create a temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	localFile := filepath.Join(dir, "admin_app.tgz")
+	err = minioClient.DownloadAppVersion("admin/admin_app.tgz", localFile, "5055a61b3d1b667a4c3279a381a2e7ae")
+	if err != nil {
+		t.Fatalf("DownloadAppVersion() returned %v", err)
+	}
+	if _, err = os.Stat(localFile); err != nil {
+		t.Errorf("DownloadAppVersion() should have written %s: %v", localFile, err)
+	}
 
-	minioClient := &MinioClient{}
-	gotCmd := minioClient.GetInitContainerCmd("https://s3.us-west-2.amazonaws.com", "sample_bucket", "admin", "admin", "/mnt/apps-local/")
-	if !reflect.DeepEqual(wantCmd, gotCmd) {
-		t.Errorf("Got incorrect Init container cmd")
+	err = minioClient.DownloadAppVersion("admin/admin_app.tgz", localFile, "a80fb6cd5b6e1a9bd4dbd1c4b4d4e2f8")
+	if err == nil {
+		t.Errorf("DownloadAppVersion() should have returned an error for an unknown etag")
 	}
 }
@@ -15,7 +15,19 @@
 package client
 
 import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 )
 
@@ -60,8 +72,13 @@ var S3Clients = make(map[string]GetS3ClientWrapper)
 // S3Client is an interface to implement different S3 client APIs
 type S3Client interface {
 	GetAppsList() (S3Response, error)
-	GetInitContainerImage() string
-	GetInitContainerCmd(string /* endpoint */, string /* bucket */, string /* path */, string /* app src name */, string /* app mnt */) []string
+	DownloadApp(string /* remote file */, string /* local file */, string /* etag */) error
+}
+
+// S3VersionedClient is implemented by the S3 clients able to download any version of an app package, from the
+// buckets with versioning enabled
+type S3VersionedClient interface {
+	DownloadAppVersion(string /* remote file */, string /* local file */, string /* etag */) error
 }
 
 // SplunkS3Client is a simple object used to connect to S3
@@ -90,7 +107,103 @@ func RegisterS3Client(provider string) {
 		RegisterAWSS3Client()
 	case "minio":
 		RegisterMinioClient()
+	case "azure":
+		RegisterAzureBlobClient()
+	case "gcp":
+		RegisterGCSClient()
+	case "kubernetes":
+		RegisterKubernetesVolumeClient()
+	case "http":
+		RegisterHTTPIndexClient()
 	default:
 		fmt.Println("ERROR: Invalid provider specified: ", provider)
 	}
 }
+
+// writeLocalFile writes the content of a downloaded app to a local file. The content is first written to a temporary
+// file of the same directory, so that a partially downloaded app is never seen under its final name. If a sha256 or
+// MD5 hex digest is given, the file is only kept when it matches its content.
+func writeLocalFile(localFile string, content io.Reader, digestHex string) error {
+	err := os.MkdirAll(filepath.Dir(localFile), 0755)
+	if err != nil {
+		return err
+	}
+	tmpFile, err := ioutil.TempFile(filepath.Dir(localFile), filepath.Base(localFile)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+
+	var digest hash.Hash = sha256.New()
+	if len(digestHex) == 2*md5.Size {
+		digest = md5.New()
+	}
+	_, err = io.Copy(io.MultiWriter(tmpFile, digest), content)
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if digestHex != "" && hex.EncodeToString(digest.Sum(nil)) != strings.ToLower(digestHex) {
+		return fmt.Errorf("The content of %s does not match its digest %s", filepath.Base(localFile), digestHex)
+	}
+	return os.Rename(tmpFile.Name(), localFile)
+}
+
+// getResponseMD5 returns the hex MD5 digest of the content of a response, from its Content-MD5 header, as returned
+// by Azure Blob, or from its x-goog-hash header, as returned by GCS. It is empty when the response has none.
+func getResponseMD5(header http.Header) string {
+	encoded := header.Get("Content-MD5")
+	for _, googHash := range header["X-Goog-Hash"] {
+		for _, value := range strings.Split(googHash, ",") {
+			if strings.HasPrefix(strings.TrimSpace(value), "md5=") {
+				encoded = strings.TrimPrefix(strings.TrimSpace(value), "md5=")
+			}
+		}
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(decoded) != md5.Size {
+		return ""
+	}
+	return hex.EncodeToString(decoded)
+}
+
+// downloadHTTPObject sends a request for the content of a remote file, and writes the response to a local file. The
+// content is checked against the sha256 given, or else against the MD5 digest of the response, if any.
+func downloadHTTPObject(client SplunkHTTPClient, request *http.Request, localFile string, sha256Hex string) error {
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("Unexpected status code %d downloading %s", response.StatusCode, request.URL.Path)
+	}
+
+	digestHex := sha256Hex
+	if digestHex == "" {
+		digestHex = getResponseMD5(response.Header)
+	}
+	return writeLocalFile(localFile, response.Body, digestHex)
+}
+
+// getOAuth2AccessToken sends a request to an OAuth2 token endpoint, or to a metadata server returning tokens in the
+// same format, and returns the access token of its response
+func getOAuth2AccessToken(client SplunkHTTPClient, request *http.Request) (string, error) {
+	response, err := client.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Unexpected status code %d getting an access token from %s", response.StatusCode, request.URL.Host)
+	}
+
+	token := struct {
+		AccessToken string `json:"access_token"`
+	}{}
+	err = json.NewDecoder(response.Body).Decode(&token)
+	return token.AccessToken, err
+}
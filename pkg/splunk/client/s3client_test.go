@@ -15,6 +15,7 @@
 package client
 
 import (
+	"net/http"
 	"testing"
 )
 
@@ -37,10 +38,24 @@ func TestRegisterS3Client(t *testing.T) {
 		t.Errorf("We should have initialized the client for minio as well.")
 	}
 
-	// 3. Test for invalid provider
+	// 3. Test for azure and gcp
+	RegisterS3Client("azure")
+	RegisterS3Client("gcp")
+	if len(S3Clients) != 4 {
+		t.Errorf("We should have initialized the clients for azure and gcp as well.")
+	}
+
+	// 4. Test for kubernetes and http
+	RegisterS3Client("kubernetes")
+	RegisterS3Client("http")
+	if len(S3Clients) != 6 {
+		t.Errorf("We should have initialized the clients for kubernetes and http as well.")
+	}
+
+	// 5. Test for invalid provider
 	RegisterS3Client("invalid")
-	if len(S3Clients) > 2 {
-		t.Errorf("We should only have initialized the client for aws and minio and not for an invalid provider.")
+	if len(S3Clients) > 6 {
+		t.Errorf("We should only have initialized the client for aws, minio, azure, gcp, kubernetes and http and not for an invalid provider.")
 	}
 
 }
@@ -72,3 +87,29 @@ func TestGetSetS3ClientInitFuncPtr(t *testing.T) {
 		t.Errorf("We should have set GetInitFunc func pointer for AWS client.")
 	}
 }
+
+func TestGetResponseMD5(t *testing.T) {
+	// MD5 digest of "app1 content"
+	want := "296202f3911bcb2a16c38459d83c7800"
+
+	header := http.Header{}
+	if got := getResponseMD5(header); got != "" {
+		t.Errorf("getResponseMD5() = %s; want an empty digest without header", got)
+	}
+
+	header.Set("Content-MD5", "KWIC85EbyyoWw4RZ2Dx4AA==")
+	if got := getResponseMD5(header); got != want {
+		t.Errorf("getResponseMD5() = %s; want %s from Content-MD5", got, want)
+	}
+
+	header = http.Header{}
+	header.Add("X-Goog-Hash", "crc32c=n03x6A==,md5=KWIC85EbyyoWw4RZ2Dx4AA==")
+	if got := getResponseMD5(header); got != want {
+		t.Errorf("getResponseMD5() = %s; want %s from x-goog-hash", got, want)
+	}
+
+	header.Set("X-Goog-Hash", "crc32c=n03x6A==")
+	if got := getResponseMD5(header); got != "" {
+		t.Errorf("getResponseMD5() = %s; want an empty digest for a composite object", got)
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright (c) 2018-2021 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	splcommon "github.com/splunk/splunk-operator/pkg/splunk/common"
+)
+
+// blank assignment to verify that KubernetesVolumeClient implements S3Client
+var _ S3Client = &KubernetesVolumeClient{}
+
+// AppRepoVolumeMountDir is the directory where the PersistentVolumeClaims holding apps are mounted in the Splunk
+// containers
+const AppRepoVolumeMountDir = "/mnt/app-repo"
+
+// SplunkPodExecClient is an interface to run a shell script in a pod mounting the app repository volumes, and
+// either return its output or stream it to a writer
+type SplunkPodExecClient interface {
+	Exec(script string) (string, error)
+	ExecStream(script string, stdout io.Writer) error
+}
+
+// KubernetesVolumeClient is a client to list the apps of a PersistentVolumeClaim. Since the operator can not
+// mount the claim itself, the apps are listed by a script running in a Splunk pod which mounts it.
+type KubernetesVolumeClient struct {
+	// name of the PersistentVolumeClaim
+	ClaimName string
+
+	// only the files of this directory of the claim are listed
+	Prefix string
+
+	// file listed before the apps, which is excluded from the listing
+	StartAfter string
+
+	Client SplunkPodExecClient
+}
+
+// NewKubernetesVolumeClient returns a client for the apps of a PersistentVolumeClaim. There are no credentials
+// nor endpoint to access a claim, they are only passed to the init function.
+func NewKubernetesVolumeClient(claimName string, accessKeyID string, secretAccessKey string, prefix string, startAfter string, endpoint string, fn GetInitFunc) (S3Client, error) {
+	var cl interface{}
+	if fn != nil {
+		cl = fn(endpoint, accessKeyID, secretAccessKey)
+	}
+	if cl == nil {
+		return nil, fmt.Errorf("Failed to create a Kubernetes volume client")
+	}
+
+	return &KubernetesVolumeClient{
+		ClaimName:  claimName,
+		Prefix:     prefix,
+		StartAfter: startAfter,
+		Client:     cl.(SplunkPodExecClient),
+	}, nil
+}
+
+// RegisterKubernetesVolumeClient will add the corresponding function pointer to the map. It does not have
+// an init function, since the pods running the listing script are only known by the enterprise package.
+func RegisterKubernetesVolumeClient() {
+	wrapperObject := GetS3ClientWrapper{GetS3Client: NewKubernetesVolumeClient}
+	S3Clients["kubernetes"] = wrapperObject
+}
+
+// GetAppsList get the list of apps from remote storage
+func (client *KubernetesVolumeClient) GetAppsList() (S3Response, error) {
+	scopedLog := log.WithName("GetAppsList")
+
+	scopedLog.Info("Getting Apps list", "PersistentVolumeClaim", client.ClaimName, "Prefix", client.Prefix)
+	s3Resp := S3Response{}
+
+	// one line per file: sha256, size, modification time and name
+	dir := filepath.Join(AppRepoVolumeMountDir, client.ClaimName, client.Prefix)
+	script := fmt.Sprintf(`cd %s && for f in *; do if [ -f "$f" ]; then echo "$(sha256sum "$f" | cut -d' ' -f1) $(stat -c '%%s %%Y' "$f") $f"; fi; done`, splcommon.ShellQuote(dir))
+	stdout, err := client.Client.Exec(script)
+	if err != nil {
+		scopedLog.Error(err, "Unable to list files in volume", "PersistentVolumeClaim", client.ClaimName)
+		return s3Resp, err
+	}
+
+	for _, line := range strings.Split(stdout, "\n") {
+		fields := strings.SplitN(strings.TrimSpace(line), " ", 4)
+		if len(fields) != 4 {
+			continue
+		}
+		newKey := client.Prefix + fields[3]
+		if newKey == client.StartAfter {
+			continue
+		}
+		newEtag := fields[0]
+		newSize, _ := strconv.ParseInt(fields[1], 10, 64)
+		modified, _ := strconv.ParseInt(fields[2], 10, 64)
+		newLastModified := time.Unix(modified, 0)
+		newRemoteObject := RemoteObject{Etag: &newEtag, Key: &newKey, LastModified: &newLastModified, Size: &newSize}
+		s3Resp.Objects = append(s3Resp.Objects, &newRemoteObject)
+	}
+
+	if len(s3Resp.Objects) == 0 {
+		return s3Resp, fmt.Errorf("Empty files list in the volume: %s", client.ClaimName)
+	}
+
+	return s3Resp, nil
+}
+
+// DownloadApp copies an app package from the volume to a local file, through the pod mounting the volume, and checks
+// it against the sha256 of the listing. The package is streamed to the file as the pod outputs it.
+func (client *KubernetesVolumeClient) DownloadApp(remoteFile string, localFile string, etag string) error {
+	scopedLog := log.WithName("DownloadApp")
+
+	script := "cat " + splcommon.ShellQuote(filepath.Join(AppRepoVolumeMountDir, client.ClaimName, remoteFile))
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(client.Client.ExecStream(script, writer))
+	}()
+
+	err := writeLocalFile(localFile, reader, etag)
+	reader.CloseWithError(err)
+	if err != nil {
+		scopedLog.Error(err, "Unable to read file in volume", "PersistentVolumeClaim", client.ClaimName, "remoteFile", remoteFile)
+	}
+	return err
+}
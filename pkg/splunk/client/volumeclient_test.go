@@ -0,0 +1,144 @@
+// Copyright (c) 2018-2021 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// mockPodExecClient returns the same output for every script
+type mockPodExecClient struct {
+	stdout  string
+	err     error
+	scripts []string
+}
+
+// Exec records the script and returns the output of mockPodExecClient
+func (c *mockPodExecClient) Exec(script string) (string, error) {
+	c.scripts = append(c.scripts, script)
+	return c.stdout, c.err
+}
+
+// ExecStream records the script and writes the output of mockPodExecClient
+func (c *mockPodExecClient) ExecStream(script string, stdout io.Writer) error {
+	c.scripts = append(c.scripts, script)
+	io.WriteString(stdout, c.stdout)
+	return c.err
+}
+
+func TestNewKubernetesVolumeClient(t *testing.T) {
+	volumeClient, err := NewKubernetesVolumeClient("apps-claim", "", "", "adminApps/", "adminApps/", "", nil)
+	if volumeClient != nil || err == nil {
+		t.Errorf("NewKubernetesVolumeClient should have returned a error without init function.")
+	}
+
+	fn := func(string, string, string) interface{} { return &mockPodExecClient{} }
+	volumeClient, err = NewKubernetesVolumeClient("apps-claim", "", "", "adminApps/", "adminApps/", "", fn)
+	if volumeClient == nil || err != nil {
+		t.Errorf("NewKubernetesVolumeClient should have returned a valid Kubernetes volume client.")
+	}
+}
+
+func TestKubernetesVolumeGetAppsList(t *testing.T) {
+	execClient := &mockPodExecClient{
+		stdout: "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08 1024 1634551200 app1.tgz\n" +
+			"60303ae22b998861bce3b28f33eec1be758a213c86c93c076dbe9f558c11c752 2048 1634637600 my app2.spl\n",
+	}
+	volumeClient := &KubernetesVolumeClient{ClaimName: "apps-claim", Prefix: "adminApps/", StartAfter: "adminApps/", Client: execClient}
+
+	resp, err := volumeClient.GetAppsList()
+	if err != nil || len(resp.Objects) != 2 {
+		t.Fatalf("GetAppsList() = %v, %v; want 2 apps", resp, err)
+	}
+	if *resp.Objects[0].Key != "adminApps/app1.tgz" || *resp.Objects[0].Etag != "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08" ||
+		*resp.Objects[0].Size != 1024 || resp.Objects[0].LastModified.Unix() != 1634551200 {
+		t.Errorf("GetAppsList() returned an invalid first app: %+v", resp.Objects[0])
+	}
+	if *resp.Objects[1].Key != "adminApps/my app2.spl" {
+		t.Errorf("GetAppsList() returned an invalid second app: %s", *resp.Objects[1].Key)
+	}
+	if !strings.HasPrefix(execClient.scripts[0], "cd '/mnt/app-repo/apps-claim/adminApps' && ") {
+		t.Errorf("GetAppsList() ran an invalid script: %s", execClient.scripts[0])
+	}
+
+	// the prefix is quoted in the script
+	quotedClient := &KubernetesVolumeClient{ClaimName: "apps-claim", Prefix: "admin'Apps/", Client: execClient}
+	quotedClient.GetAppsList()
+	if !strings.HasPrefix(execClient.scripts[1], `cd '/mnt/app-repo/apps-claim/admin'\''Apps' && `) {
+		t.Errorf("GetAppsList() ran an invalid script: %s", execClient.scripts[1])
+	}
+
+	execClient.stdout = ""
+	_, err = volumeClient.GetAppsList()
+	if err == nil {
+		t.Errorf("GetAppsList() should have returned an error for an empty volume")
+	}
+
+	execClient.err = fmt.Errorf("cd: can't cd to /mnt/app-repo/apps-claim/adminApps")
+	_, err = volumeClient.GetAppsList()
+	if err == nil {
+		t.Errorf("GetAppsList() should have returned an error for a failed script")
+	}
+}
+
+func TestKubernetesVolumeDownloadApp(t *testing.T) {
+	execClient := &mockPodExecClient{stdout: "test"}
+	volumeClient := &KubernetesVolumeClient{ClaimName: "apps-claim", Prefix: "adminApps/", StartAfter: "adminApps/", Client: execClient}
+
+	dir, err := ioutil.TempDir("", "volumeclient")
+	if err != nil {
+		t.Fatalf("Unable to create a temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// sha256 of "test"
+	localFile := filepath.Join(dir, "app1.tgz")
+	err = volumeClient.DownloadApp("adminApps/app1.tgz", localFile, "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08")
+	if err != nil {
+		t.Fatalf("DownloadApp() returned %v", err)
+	}
+	if execClient.scripts[0] != "cat '/mnt/app-repo/apps-claim/adminApps/app1.tgz'" {
+		t.Errorf("DownloadApp() ran an invalid script: %s", execClient.scripts[0])
+	}
+	content, err := ioutil.ReadFile(localFile)
+	if err != nil || string(content) != "test" {
+		t.Errorf("DownloadApp() wrote %s, %v; want test", content, err)
+	}
+
+	execClient.stdout = "changed"
+	err = volumeClient.DownloadApp("adminApps/app1.tgz", localFile, "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08")
+	if err == nil {
+		t.Errorf("DownloadApp() should have returned an error for an invalid sha256")
+	}
+
+	execClient.err = fmt.Errorf("cat: can't open '/mnt/app-repo/apps-claim/adminApps/app1.tgz'")
+	err = volumeClient.DownloadApp("adminApps/app1.tgz", localFile, "")
+	if err == nil {
+		t.Errorf("DownloadApp() should have returned an error for a failed script")
+	}
+
+	// the file name is quoted in the script
+	execClient.err = nil
+	volumeClient.DownloadApp("adminApps/app'1.tgz", localFile, "")
+	if script := execClient.scripts[len(execClient.scripts)-1]; script != `cat '/mnt/app-repo/apps-claim/adminApps/app'\''1.tgz'` {
+		t.Errorf("DownloadApp() ran an invalid script: %s", script)
+	}
+}
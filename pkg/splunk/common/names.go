@@ -41,6 +41,9 @@ const (
 	// PvcNamePrefix is a helper string representing prefix for persistent volume claim names
 	PvcNamePrefix = "pvc-%s"
 
+	// MigratedPvcNamePrefix is used instead of PvcNamePrefix for the claims of volumes moved to another StorageClass
+	MigratedPvcNamePrefix = "pvc-%s-migrated"
+
 	// SplunkMountNamePrefix is a helper string representing Splunk Volume mount names
 	SplunkMountNamePrefix = "mnt-splunk-%s"
 
@@ -59,6 +62,9 @@ const (
 	// DefaultVarVolumeStorageCapacity represents default storage capacity for var volume
 	DefaultVarVolumeStorageCapacity = "100Gi"
 
+	// ZoneNodeLabel is the well-known node label holding the availability zone of a node
+	ZoneNodeLabel = "topology.kubernetes.io/zone"
+
 	// SortFieldContainerPort represents field name ContainerPort for sorting
 	SortFieldContainerPort = "ContainerPort"
 
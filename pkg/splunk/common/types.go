@@ -50,6 +50,63 @@ const (
 	PhaseError Phase = "Error"
 )
 
+// ConditionType is used to represent the type of a condition of a custom resource
+type ConditionType string
+
+const (
+	// ConditionReady means a custom resource is ready and up to date
+	ConditionReady ConditionType = "Ready"
+
+	// ConditionProgressing means a custom resource is being created, updated, scaled or removed
+	ConditionProgressing ConditionType = "Progressing"
+
+	// ConditionDegraded means the last reconcile of a custom resource failed
+	ConditionDegraded ConditionType = "Degraded"
+
+	// ConditionAppsDeployed means all the apps of the App Framework repository are deployed
+	ConditionAppsDeployed ConditionType = "AppsDeployed"
+
+	// ConditionSmartStoreConfigured means the SmartStore configuration is applied
+	ConditionSmartStoreConfigured ConditionType = "SmartStoreConfigured"
+
+	// ConditionBundlePushed means the cluster manager bundle was pushed to the indexer cluster peers
+	ConditionBundlePushed ConditionType = "BundlePushed"
+
+	// ConditionSecretsInSync means all the members are using the secrets of the namespace scoped secret
+	ConditionSecretsInSync ConditionType = "SecretsInSync"
+)
+
+// Condition contains details for one aspect of the current state of a custom resource.
+// It follows the layout of metav1.Condition, which is not available in Kubernetes 1.18.
+type Condition struct {
+	// type of the condition
+	// +kubebuilder:validation:Required
+	Type ConditionType `json:"type"`
+
+	// status of the condition, one of True, False, Unknown
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Enum=True;False;Unknown
+	Status corev1.ConditionStatus `json:"status"`
+
+	// generation of the custom resource the condition was set for
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// last time the condition transitioned from one status to another
+	LastTransitionTime metav1.Time `json:"lastTransitionTime"`
+
+	// programmatic identifier, in CamelCase, of the reason for the last transition
+	Reason string `json:"reason"`
+
+	// human readable message with details about the transition
+	Message string `json:"message"`
+}
+
+// DeepCopyInto copies the receiver, writing into out. in must be non-nil.
+func (in *Condition) DeepCopyInto(out *Condition) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+}
+
 // default all fields to being optional
 // +kubebuilder:validation:Optional
 
@@ -104,6 +161,138 @@ func (in *Spec) DeepCopyInto(out *Spec) {
 	return
 }
 
+// RolloutStrategy defines how the pods of a StatefulSet are recycled to apply the updates of their template
+type RolloutStrategy struct {
+	// maximum number of pods that can be prepared for recycling, or recycled, at the same time (defaults to 1)
+	// +kubebuilder:validation:Minimum=0
+	MaxUnavailable int32 `json:"maxUnavailable,omitempty"`
+
+	// only the pods with an ordinal greater than or equal to the partition are updated; lower it to approve the
+	// update of the other pods after checking the first ones (canary)
+	// +kubebuilder:validation:Minimum=0
+	Partition *int32 `json:"partition,omitempty"`
+
+	// no more pods are recycled while the rollout is paused; the pods being recycled are not reverted
+	Paused bool `json:"paused,omitempty"`
+}
+
+// DeepCopyInto copies the receiver, writing into out. in must be non-nil.
+func (in *RolloutStrategy) DeepCopyInto(out *RolloutStrategy) {
+	*out = *in
+	if in.Partition != nil {
+		out.Partition = new(int32)
+		*out.Partition = *in.Partition
+	}
+}
+
+// PvcRetentionPolicy defines whether the PersistentVolumeClaims of the pods removed by a scale down, or of a deleted
+// custom resource, are deleted
+// +kubebuilder:validation:Enum=Delete;Retain;RetainUntilCRDeleted
+type PvcRetentionPolicy string
+
+const (
+	// PvcRetentionPolicyDelete deletes the claims of the pods removed by a scale down
+	PvcRetentionPolicyDelete PvcRetentionPolicy = "Delete"
+
+	// PvcRetentionPolicyRetain never deletes the claims, even when the custom resource is deleted
+	PvcRetentionPolicyRetain PvcRetentionPolicy = "Retain"
+
+	// PvcRetentionPolicyRetainUntilCRDeleted keeps the claims of the pods removed by a scale down, so that they are
+	// reused by a later scale up, until the custom resource is deleted
+	PvcRetentionPolicyRetainUntilCRDeleted PvcRetentionPolicy = "RetainUntilCRDeleted"
+)
+
+// PvcExpansionStatus reports the progress of the expansion of the PersistentVolumeClaims of a StatefulSet, after
+// the storage capacity of its custom resource was increased
+type PvcExpansionStatus struct {
+	// number of claims to expand
+	TotalClaims int32 `json:"totalClaims"`
+
+	// number of claims with the requested storage capacity and a resized file system
+	ExpandedClaims int32 `json:"expandedClaims"`
+
+	// claims being expanded, or waiting for their file system to be resized
+	PendingClaims []string `json:"pendingClaims,omitempty"`
+
+	// reason why the claims can not be expanded, if any
+	Message string `json:"message,omitempty"`
+}
+
+// DeepCopyInto copies the receiver, writing into out. in must be non-nil.
+func (in *PvcExpansionStatus) DeepCopyInto(out *PvcExpansionStatus) {
+	*out = *in
+	if in.PendingClaims != nil {
+		out.PendingClaims = make([]string, len(in.PendingClaims))
+		copy(out.PendingClaims, in.PendingClaims)
+	}
+}
+
+// DeepCopy copies the receiver, creating a new PvcExpansionStatus.
+func (in *PvcExpansionStatus) DeepCopy() *PvcExpansionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PvcExpansionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// StorageMigrationStatus reports the progress of the move of the etc and var volumes of the pods of a custom resource
+// to new storage, after their StorageClass or ephemeral storage setting was changed
+type StorageMigrationStatus struct {
+	// pods whose data is being copied to their new volumes
+	Pods []StorageMigrationPodStatus `json:"pods,omitempty"`
+}
+
+// StorageMigrationPodStatus reports the copy of the data of a pod to its new volumes
+type StorageMigrationPodStatus struct {
+	// name of the pod
+	Name string `json:"name"`
+
+	// volumes moved to new storage
+	Volumes []string `json:"volumes"`
+
+	// step of the copy: Copying while Splunk runs, then Syncing the changes once Splunk is stopped
+	Step string `json:"step"`
+
+	// time the step started
+	StartTime metav1.Time `json:"startTime,omitempty"`
+
+	// reason why the last attempt failed, if any
+	Message string `json:"message,omitempty"`
+}
+
+// DeepCopyInto copies the receiver, writing into out. in must be non-nil.
+func (in *StorageMigrationStatus) DeepCopyInto(out *StorageMigrationStatus) {
+	*out = *in
+	if in.Pods != nil {
+		out.Pods = make([]StorageMigrationPodStatus, len(in.Pods))
+		for i := range in.Pods {
+			in.Pods[i].DeepCopyInto(&out.Pods[i])
+		}
+	}
+}
+
+// DeepCopy copies the receiver, creating a new StorageMigrationStatus.
+func (in *StorageMigrationStatus) DeepCopy() *StorageMigrationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(StorageMigrationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto copies the receiver, writing into out. in must be non-nil.
+func (in *StorageMigrationPodStatus) DeepCopyInto(out *StorageMigrationPodStatus) {
+	*out = *in
+	if in.Volumes != nil {
+		out.Volumes = make([]string, len(in.Volumes))
+		copy(out.Volumes, in.Volumes)
+	}
+	in.StartTime.DeepCopyInto(&out.StartTime)
+}
+
 // MetaObject is used to represent a common interfaces for Kubernetes resources
 type MetaObject interface {
 	metav1.Object
@@ -130,4 +319,11 @@ type StatefulSetPodManager interface {
 
 	// FinishRecycle completes recycle event for pod and returns true, or returns false if nothing to do
 	FinishRecycle(int32) (bool, error)
+
+	// GetRolloutStrategy returns how pods are recycled for updates; PrepareRecycle is called for up to
+	// MaxUnavailable pods before any of them is deleted, so that they can be prepared in parallel
+	GetRolloutStrategy() RolloutStrategy
+
+	// GetPvcRetentionPolicy returns whether the claims of the pods removed by a scale down are deleted
+	GetPvcRetentionPolicy() PvcRetentionPolicy
 }
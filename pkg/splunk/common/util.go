@@ -310,28 +310,110 @@ func AppendPodAntiAffinity(affinity *corev1.Affinity, identifier string, typeLab
 	return affinity
 }
 
-// ValidateImagePullPolicy checks validity of the ImagePullPolicy spec parameter, and returns error if it is invalid.
-func ValidateImagePullPolicy(imagePullPolicy *string) error {
-	// ImagePullPolicy
+// AppendNodeZoneAffinity appends a Kubernetes Affinity object to require pods to be scheduled on nodes of an availability zone, and returns the result.
+func AppendNodeZoneAffinity(affinity *corev1.Affinity, zone string) *corev1.Affinity {
+	if affinity == nil {
+		affinity = &corev1.Affinity{}
+	} else {
+		affinity = affinity.DeepCopy()
+	}
+
+	if affinity.NodeAffinity == nil {
+		affinity.NodeAffinity = &corev1.NodeAffinity{}
+	}
+	if affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution = &corev1.NodeSelector{}
+	}
+	nodeSelector := affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+	if len(nodeSelector.NodeSelectorTerms) == 0 {
+		nodeSelector.NodeSelectorTerms = []corev1.NodeSelectorTerm{{}}
+	}
+
+	// node selector terms are ORed, so the zone requirement must be added to each of them
+	for i := range nodeSelector.NodeSelectorTerms {
+		nodeSelector.NodeSelectorTerms[i].MatchExpressions = append(nodeSelector.NodeSelectorTerms[i].MatchExpressions,
+			corev1.NodeSelectorRequirement{
+				Key:      ZoneNodeLabel,
+				Operator: corev1.NodeSelectorOpIn,
+				Values:   []string{zone},
+			},
+		)
+	}
+
+	return affinity
+}
+
+// SetCondition adds or updates the condition of the same type in a list of conditions. The LastTransitionTime
+// of an existing condition is kept unless its status changes.
+func SetCondition(conditions *[]Condition, condition Condition) {
+	if condition.LastTransitionTime.IsZero() {
+		condition.LastTransitionTime = metav1.Now()
+	}
+	for i := range *conditions {
+		existing := &(*conditions)[i]
+		if existing.Type != condition.Type {
+			continue
+		}
+		if existing.Status == condition.Status {
+			condition.LastTransitionTime = existing.LastTransitionTime
+		}
+		*existing = condition
+		return
+	}
+	*conditions = append(*conditions, condition)
+}
+
+// RemoveCondition removes the condition of a given type from a list of conditions
+func RemoveCondition(conditions *[]Condition, conditionType ConditionType) {
+	for i := range *conditions {
+		if (*conditions)[i].Type == conditionType {
+			*conditions = append((*conditions)[:i], (*conditions)[i+1:]...)
+			return
+		}
+	}
+}
+
+// GetCondition returns the condition of a given type from a list of conditions, or nil if not found
+func GetCondition(conditions []Condition, conditionType ConditionType) *Condition {
+	for i := range conditions {
+		if conditions[i].Type == conditionType {
+			return &conditions[i]
+		}
+	}
+	return nil
+}
+
+// IsConditionTrue returns true if the condition of a given type is present with status True
+func IsConditionTrue(conditions []Condition, conditionType ConditionType) bool {
+	condition := GetCondition(conditions, conditionType)
+	return condition != nil && condition.Status == corev1.ConditionTrue
+}
+
+// SetImagePullPolicyDefault sets the ImagePullPolicy spec parameter to the operator's default, if it is not provided.
+func SetImagePullPolicyDefault(imagePullPolicy *string) {
 	if *imagePullPolicy == "" {
 		*imagePullPolicy = os.Getenv("IMAGE_PULL_POLICY")
 	}
-	switch *imagePullPolicy {
-	case "":
+	if *imagePullPolicy == "" {
 		*imagePullPolicy = "IfNotPresent"
-		break
+	}
+}
+
+// ValidateImagePullPolicy checks validity of the ImagePullPolicy spec parameter, and returns error if it is invalid.
+func ValidateImagePullPolicy(imagePullPolicy string) error {
+	switch imagePullPolicy {
 	case "Always":
 		break
 	case "IfNotPresent":
 		break
 	default:
-		return fmt.Errorf("ImagePullPolicy must be one of \"Always\" or \"IfNotPresent\"; value=\"%s\"", *imagePullPolicy)
+		return fmt.Errorf("ImagePullPolicy must be one of \"Always\" or \"IfNotPresent\"; value=\"%s\"", imagePullPolicy)
 	}
 	return nil
 }
 
-// ValidateResources checks resource requests and limits and sets defaults if not provided
-func ValidateResources(resources *corev1.ResourceRequirements, defaults corev1.ResourceRequirements) {
+// SetResourcesDefaults sets the resource requests and limits that are not provided to their default values
+func SetResourcesDefaults(resources *corev1.ResourceRequirements, defaults corev1.ResourceRequirements) {
 	// check for nil maps
 	if resources.Requests == nil {
 		resources.Requests = make(corev1.ResourceList)
@@ -365,8 +447,8 @@ func ValidateResources(resources *corev1.ResourceRequirements, defaults corev1.R
 	}
 }
 
-// ValidateSpec checks validity and makes default updates to a Spec, and returns error if something is wrong.
-func ValidateSpec(spec *Spec, defaultResources corev1.ResourceRequirements) error {
+// SetSpecDefaults sets default values for the parameters of a Spec that are not provided.
+func SetSpecDefaults(spec *Spec, defaultResources corev1.ResourceRequirements) {
 	// make sure SchedulerName is not empty
 	if spec.SchedulerName == "" {
 		spec.SchedulerName = "default-scheduler"
@@ -376,9 +458,14 @@ func ValidateSpec(spec *Spec, defaultResources corev1.ResourceRequirements) erro
 	setServiceTemplateDefaults(spec)
 
 	// if not provided, set default resource requests and limits
-	ValidateResources(&spec.Resources, defaultResources)
+	SetResourcesDefaults(&spec.Resources, defaultResources)
+
+	SetImagePullPolicyDefault(&spec.ImagePullPolicy)
+}
 
-	return ValidateImagePullPolicy(&spec.ImagePullPolicy)
+// ValidateSpec checks validity of a Spec, and returns error if something is wrong.
+func ValidateSpec(spec *Spec) error {
+	return ValidateImagePullPolicy(spec.ImagePullPolicy)
 }
 
 // setServiceTemplateDefaults sets default values for service templates
@@ -464,3 +551,8 @@ func SortSlice(a interface{}, keyName string) {
 		return sortFunc(a, i, j)
 	})
 }
+
+// ShellQuote returns a string as a single word of a shell script, whatever characters it holds
+func ShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
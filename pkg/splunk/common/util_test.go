@@ -22,6 +22,7 @@ import (
 	"reflect"
 	"sync"
 	"testing"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
@@ -692,6 +693,69 @@ func TestAppendPodAffinity(t *testing.T) {
 	})
 }
 
+func TestAppendNodeZoneAffinity(t *testing.T) {
+	var affinity corev1.Affinity
+
+	test := func(want corev1.Affinity) {
+		got := AppendNodeZoneAffinity(&affinity, "zone-1a")
+		f := func() bool {
+			return CompareByMarshall(got, want)
+		}
+		compareTester(t, "AppendNodeZoneAffinity()", f, got, want, false)
+	}
+
+	wantRequirement := corev1.NodeSelectorRequirement{
+		Key:      "topology.kubernetes.io/zone",
+		Operator: corev1.NodeSelectorOpIn,
+		Values:   []string{"zone-1a"},
+	}
+
+	test(corev1.Affinity{
+		NodeAffinity: &corev1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+				NodeSelectorTerms: []corev1.NodeSelectorTerm{
+					{MatchExpressions: []corev1.NodeSelectorRequirement{wantRequirement}},
+				},
+			},
+		},
+	})
+
+	// existing terms are ANDed with the zone requirement
+	diskRequirement := corev1.NodeSelectorRequirement{
+		Key:      "disktype",
+		Operator: corev1.NodeSelectorOpIn,
+		Values:   []string{"ssd"},
+	}
+	affinity = corev1.Affinity{
+		NodeAffinity: &corev1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+				NodeSelectorTerms: []corev1.NodeSelectorTerm{
+					{MatchExpressions: []corev1.NodeSelectorRequirement{diskRequirement}},
+					{MatchFields: []corev1.NodeSelectorRequirement{{Key: "metadata.name", Operator: corev1.NodeSelectorOpIn, Values: []string{"node1"}}}},
+				},
+			},
+		},
+	}
+	test(corev1.Affinity{
+		NodeAffinity: &corev1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+				NodeSelectorTerms: []corev1.NodeSelectorTerm{
+					{MatchExpressions: []corev1.NodeSelectorRequirement{diskRequirement, wantRequirement}},
+					{
+						MatchExpressions: []corev1.NodeSelectorRequirement{wantRequirement},
+						MatchFields:      []corev1.NodeSelectorRequirement{{Key: "metadata.name", Operator: corev1.NodeSelectorOpIn, Values: []string{"node1"}}},
+					},
+				},
+			},
+		},
+	})
+
+	// original affinity must not be modified
+	if len(affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms[0].MatchExpressions) != 1 {
+		t.Errorf("AppendNodeZoneAffinity() modified its input")
+	}
+}
+
 func TestValidateSpec(t *testing.T) {
 	spec := Spec{}
 	defaultResources := corev1.ResourceRequirements{
@@ -706,18 +770,19 @@ func TestValidateSpec(t *testing.T) {
 	}
 
 	test := func(pullPolicy, scheduler string) {
-		err := ValidateSpec(&spec, defaultResources)
+		SetSpecDefaults(&spec, defaultResources)
+		err := ValidateSpec(&spec)
 		if err != nil {
 			t.Errorf("ValidateSpec() returned %v; want nil", err)
 		}
 		if spec.ImagePullPolicy != pullPolicy {
-			t.Errorf("ValidateSpec() ImagePullPolicy = %s; want %s", spec.ImagePullPolicy, pullPolicy)
+			t.Errorf("SetSpecDefaults() ImagePullPolicy = %s; want %s", spec.ImagePullPolicy, pullPolicy)
 		}
 		if spec.SchedulerName != scheduler {
-			t.Errorf("ValidateSpec() SchedulerName = %s; want %s", spec.SchedulerName, scheduler)
+			t.Errorf("SetSpecDefaults() SchedulerName = %s; want %s", spec.SchedulerName, scheduler)
 		}
 		if !reflect.DeepEqual(spec.Resources, defaultResources) {
-			t.Errorf("ValidateSpec() Resources = %v; want %v", spec.Resources, defaultResources)
+			t.Errorf("SetSpecDefaults() Resources = %v; want %v", spec.Resources, defaultResources)
 		}
 	}
 
@@ -731,10 +796,20 @@ func TestValidateSpec(t *testing.T) {
 	test("IfNotPresent", "blah")
 
 	spec.ImagePullPolicy = "Invalid"
-	err := ValidateSpec(&spec, defaultResources)
+	err := ValidateSpec(&spec)
 	if err == nil {
 		t.Error("ValidateSpec() returned nil; want ERROR")
 	}
+
+	// validation does not set any default
+	spec = Spec{}
+	err = ValidateSpec(&spec)
+	if err == nil {
+		t.Error("ValidateSpec() returned nil for an empty ImagePullPolicy; want ERROR")
+	}
+	if spec.SchedulerName != "" || spec.Resources.Requests != nil {
+		t.Errorf("ValidateSpec() modified its input")
+	}
 }
 
 func TestSetServiceTemplateDefaults(t *testing.T) {
@@ -964,3 +1039,64 @@ func TestSortAndCompareSlices(t *testing.T) {
 		t.Errorf("Expect 2 slices to be equal - (%v, %v)", a, b)
 	}
 }
+
+func TestSetCondition(t *testing.T) {
+	var conditions []Condition
+	past := metav1.NewTime(metav1.Now().Add(-time.Hour))
+
+	SetCondition(&conditions, Condition{Type: ConditionReady, Status: corev1.ConditionFalse, Reason: "NotReady", LastTransitionTime: past})
+	if len(conditions) != 1 || conditions[0].Reason != "NotReady" {
+		t.Errorf("SetCondition() did not add condition: %v", conditions)
+	}
+
+	// same status keeps the transition time but updates the reason and message
+	SetCondition(&conditions, Condition{Type: ConditionReady, Status: corev1.ConditionFalse, Reason: "Pending", Message: "waiting"})
+	if len(conditions) != 1 || conditions[0].Reason != "Pending" || conditions[0].Message != "waiting" {
+		t.Errorf("SetCondition() did not update condition: %v", conditions)
+	}
+	if !conditions[0].LastTransitionTime.Equal(&past) {
+		t.Errorf("SetCondition() changed LastTransitionTime without a status change: got %v; want %v", conditions[0].LastTransitionTime, past)
+	}
+
+	// status change updates the transition time
+	SetCondition(&conditions, Condition{Type: ConditionReady, Status: corev1.ConditionTrue, Reason: "Ready"})
+	if conditions[0].LastTransitionTime.Equal(&past) || conditions[0].LastTransitionTime.IsZero() {
+		t.Errorf("SetCondition() did not update LastTransitionTime on a status change: %v", conditions[0].LastTransitionTime)
+	}
+
+	SetCondition(&conditions, Condition{Type: ConditionDegraded, Status: corev1.ConditionFalse, Reason: "AsExpected"})
+	if len(conditions) != 2 {
+		t.Errorf("SetCondition() did not append condition: %v", conditions)
+	}
+	if !IsConditionTrue(conditions, ConditionReady) {
+		t.Errorf("IsConditionTrue(Ready) = false; want true")
+	}
+	if IsConditionTrue(conditions, ConditionDegraded) || IsConditionTrue(conditions, ConditionProgressing) {
+		t.Errorf("IsConditionTrue() = true for a False or missing condition")
+	}
+	if GetCondition(conditions, ConditionProgressing) != nil {
+		t.Errorf("GetCondition(Progressing) returned a missing condition")
+	}
+
+	RemoveCondition(&conditions, ConditionReady)
+	if len(conditions) != 1 || GetCondition(conditions, ConditionReady) != nil || GetCondition(conditions, ConditionDegraded) == nil {
+		t.Errorf("RemoveCondition() = %v; want only Degraded", conditions)
+	}
+	RemoveCondition(&conditions, ConditionReady)
+	if len(conditions) != 1 {
+		t.Errorf("RemoveCondition() of a missing condition changed the list: %v", conditions)
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	test := func(s string, want string) {
+		if got := ShellQuote(s); got != want {
+			t.Errorf("ShellQuote(%s) = %s; want %s", s, got, want)
+		}
+	}
+
+	test("", "''")
+	test("/mnt/app-repo/apps-claim/app1.tgz", "'/mnt/app-repo/apps-claim/app1.tgz'")
+	test("my app$(id).tgz", "'my app$(id).tgz'")
+	test("app'; rm -rf /; '.tgz", `'app'\''; rm -rf /; '\''.tgz'`)
+}
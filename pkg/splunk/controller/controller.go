@@ -19,6 +19,7 @@ import (
 
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
@@ -38,8 +39,9 @@ type SplunkController interface {
 	// GetWatchTypes returns a list of types owned by the controller that it would like to receive watch events for
 	GetWatchTypes() []runtime.Object
 
-	// Reconcile is used to perform an idempotent reconciliation of the custom resource managed by this controller
-	Reconcile(client.Client, splcommon.MetaObject) (reconcile.Result, error)
+	// Reconcile is used to perform an idempotent reconciliation of the custom resource managed by this controller.
+	// Events about the custom resource may be emitted using the EventRecorder.
+	Reconcile(client.Client, record.EventRecorder, splcommon.MetaObject) (reconcile.Result, error)
 }
 
 // AddToManager adds a specific Splunk Controller to the Manager.
@@ -50,8 +52,9 @@ func AddToManager(mgr manager.Manager, splctrl SplunkController, c client.Client
 	kind := instance.GetObjectKind().GroupVersionKind().Kind
 	opts := controller.Options{
 		Reconciler: splunkReconciler{
-			client:  c,
-			splctrl: splctrl,
+			client:   c,
+			recorder: NewRateLimitedEventRecorder(mgr.GetEventRecorderFor("splunk-operator"), EventRateLimitInterval),
+			splctrl:  splctrl,
 		},
 	}
 	ctrl, err := controller.New(kind, mgr, opts)
@@ -86,7 +89,11 @@ var _ reconcile.Reconciler = &splunkReconciler{}
 type splunkReconciler struct {
 	// This client, initialized using mgr.Client() above, is a split client
 	// that reads objects from the cache and writes to the apiserver
-	client  client.Client
+	client client.Client
+
+	// recorder is used to emit events about the custom resources
+	recorder record.EventRecorder
+
 	splctrl SplunkController
 }
 
@@ -118,7 +125,7 @@ func (r splunkReconciler) Reconcile(request reconcile.Request) (reconcile.Result
 	instance.SetGroupVersionKind(gvk)
 
 	// call Reconcile method defined for the controller
-	result, err := r.splctrl.Reconcile(r.client, instance)
+	result, err := r.splctrl.Reconcile(r.client, r.recorder, instance)
 
 	// log what happens next
 	if err != nil {
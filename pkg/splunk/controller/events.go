@@ -0,0 +1,107 @@
+// Copyright (c) 2018-2021 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+)
+
+// EventRateLimitInterval is the minimum interval between two identical events for the same object
+const EventRateLimitInterval = 5 * time.Minute
+
+// blank assignment to verify that rateLimitedEventRecorder implements record.EventRecorder
+var _ record.EventRecorder = &rateLimitedEventRecorder{}
+
+// rateLimitedEventRecorder is an EventRecorder that drops identical events emitted for the same object
+// within an interval. This keeps reconcile loops that requeue every few seconds from flooding the events.
+type rateLimitedEventRecorder struct {
+	recorder   record.EventRecorder
+	interval   time.Duration
+	now        func() time.Time
+	mutex      sync.Mutex
+	lastSeen   map[string]time.Time
+	lastPruned time.Time
+}
+
+// NewRateLimitedEventRecorder returns an EventRecorder that only emits one of a series of identical events per interval
+func NewRateLimitedEventRecorder(recorder record.EventRecorder, interval time.Duration) record.EventRecorder {
+	return &rateLimitedEventRecorder{
+		recorder: recorder,
+		interval: interval,
+		now:      time.Now,
+		lastSeen: make(map[string]time.Time),
+	}
+}
+
+// Event emits an event unless an identical one was emitted for the object within the interval
+func (r *rateLimitedEventRecorder) Event(object runtime.Object, eventtype, reason, message string) {
+	if r.allow(object, eventtype, reason, message) {
+		r.recorder.Event(object, eventtype, reason, message)
+	}
+}
+
+// Eventf is just like Event, but with Sprintf for the message field
+func (r *rateLimitedEventRecorder) Eventf(object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	r.Event(object, eventtype, reason, fmt.Sprintf(messageFmt, args...))
+}
+
+// AnnotatedEventf is just like Eventf, but with annotations attached
+func (r *rateLimitedEventRecorder) AnnotatedEventf(object runtime.Object, annotations map[string]string, eventtype, reason, messageFmt string, args ...interface{}) {
+	message := fmt.Sprintf(messageFmt, args...)
+	if r.allow(object, eventtype, reason, message) {
+		r.recorder.AnnotatedEventf(object, annotations, eventtype, reason, "%s", message)
+	}
+}
+
+// allow returns true if an event should be emitted, and records the time it was emitted at
+func (r *rateLimitedEventRecorder) allow(object runtime.Object, eventtype, reason, message string) bool {
+	key := fmt.Sprintf("%s/%s/%s/%s", eventtype, reason, message, getEventObjectKey(object))
+	now := r.now()
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	// forget about events that are old enough to be emitted again
+	if now.Sub(r.lastPruned) >= r.interval {
+		for k, t := range r.lastSeen {
+			if now.Sub(t) >= r.interval {
+				delete(r.lastSeen, k)
+			}
+		}
+		r.lastPruned = now
+	}
+
+	if t, ok := r.lastSeen[key]; ok && now.Sub(t) < r.interval {
+		return false
+	}
+	r.lastSeen[key] = now
+	return true
+}
+
+// getEventObjectKey returns a string identifying the object an event is emitted for
+func getEventObjectKey(object runtime.Object) string {
+	kind := object.GetObjectKind().GroupVersionKind().Kind
+	accessor, err := meta.Accessor(object)
+	if err != nil {
+		return kind
+	}
+	return fmt.Sprintf("%s/%s/%s/%s", kind, accessor.GetNamespace(), accessor.GetName(), accessor.GetUID())
+}
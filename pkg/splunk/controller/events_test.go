@@ -0,0 +1,78 @@
+// Copyright (c) 2018-2021 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestRateLimitedEventRecorder(t *testing.T) {
+	fakeRecorder := record.NewFakeRecorder(10)
+	recorder := NewRateLimitedEventRecorder(fakeRecorder, time.Minute).(*rateLimitedEventRecorder)
+	now := time.Now()
+	recorder.now = func() time.Time { return now }
+
+	cm1 := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm1", Namespace: "test"}}
+	cm2 := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm2", Namespace: "test"}}
+
+	test := func(want ...string) {
+		for _, w := range want {
+			select {
+			case got := <-fakeRecorder.Events:
+				if got != w {
+					t.Errorf("TestRateLimitedEventRecorder() event = %s; want %s", got, w)
+				}
+			default:
+				t.Errorf("TestRateLimitedEventRecorder() missing event %s", w)
+			}
+		}
+		select {
+		case got := <-fakeRecorder.Events:
+			t.Errorf("TestRateLimitedEventRecorder() unexpected event %s", got)
+		default:
+		}
+	}
+
+	recorder.Event(cm1, corev1.EventTypeNormal, "ScalingUp", "Scaling up to 3 replicas")
+	recorder.Eventf(cm1, corev1.EventTypeNormal, "ScalingUp", "Scaling up to %d replicas", 3)
+	test("Normal ScalingUp Scaling up to 3 replicas")
+
+	// different object, message or type are not rate limited
+	recorder.Event(cm2, corev1.EventTypeNormal, "ScalingUp", "Scaling up to 3 replicas")
+	recorder.Event(cm1, corev1.EventTypeNormal, "ScalingUp", "Scaling up to 4 replicas")
+	recorder.Event(cm1, corev1.EventTypeWarning, "ScalingUp", "Scaling up to 4 replicas")
+	test("Normal ScalingUp Scaling up to 3 replicas", "Normal ScalingUp Scaling up to 4 replicas", "Warning ScalingUp Scaling up to 4 replicas")
+
+	// identical events are emitted again after the interval
+	now = now.Add(30 * time.Second)
+	recorder.Event(cm1, corev1.EventTypeNormal, "ScalingUp", "Scaling up to 3 replicas")
+	test()
+	now = now.Add(31 * time.Second)
+	recorder.AnnotatedEventf(cm1, nil, corev1.EventTypeNormal, "ScalingUp", "Scaling up to %d replicas", 3)
+	test("Normal ScalingUp Scaling up to 3 replicas")
+
+	// expired events are pruned
+	now = now.Add(2 * time.Minute)
+	recorder.Event(cm1, corev1.EventTypeNormal, "ScalingUp", "Scaling up to 3 replicas")
+	test("Normal ScalingUp Scaling up to 3 replicas")
+	if len(recorder.lastSeen) != 1 {
+		t.Errorf("TestRateLimitedEventRecorder() tracked events = %d; want 1", len(recorder.lastSeen))
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright (c) 2018-2021 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// storageVersionMigrationConfigMap is the name of the ConfigMap recording the storage version of each kind of custom
+// resource rewritten in a namespace, when the operator watches a single namespace
+const storageVersionMigrationConfigMap = "splunk-operator-storage-version-migration"
+
+// MigrateStorageVersion rewrites the custom resources of a kind in a namespace (or in all namespaces, if empty),
+// so that the API server stores them using the storage version of their CustomResourceDefinition.
+// It returns the number of custom resources that could not be rewritten.
+func MigrateStorageVersion(c client.Client, gvk schema.GroupVersionKind, namespace string) (int, error) {
+	scopedLog := log.WithName("MigrateStorageVersion").WithValues("kind", gvk.Kind, "namespace", namespace)
+
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+	err := c.List(context.TODO(), list, client.InNamespace(namespace))
+	if err != nil {
+		return 0, err
+	}
+
+	failed := 0
+	for i := range list.Items {
+		// an update without any change is enough for the API server to encode the object using the storage version
+		item := &list.Items[i]
+		err = c.Update(context.TODO(), item)
+		if err != nil && !k8serrors.IsConflict(err) && !k8serrors.IsNotFound(err) {
+			scopedLog.Error(err, "Failed to rewrite custom resource", "name", item.GetName(), "namespace", item.GetNamespace())
+			failed++
+		}
+	}
+
+	scopedLog.Info("Rewrote custom resources using the storage version", "count", len(list.Items)-failed, "failed", failed)
+	return failed, nil
+}
+
+// UpdateStoredVersions records in the status of a CustomResourceDefinition that all its custom resources are stored
+// using its storage version, so that the other versions can be removed from the CustomResourceDefinition.
+func UpdateStoredVersions(c client.Client, crdName, storageVersion string) error {
+	scopedLog := log.WithName("UpdateStoredVersions").WithValues("name", crdName)
+
+	crd, storedVersions, err := getStoredVersions(c, crdName)
+	if err != nil {
+		return err
+	}
+	if len(storedVersions) == 1 && storedVersions[0] == storageVersion {
+		return nil
+	}
+
+	found := false
+	for _, version := range storedVersions {
+		if version == storageVersion {
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("Storage version %s is not one of the stored versions %v", storageVersion, storedVersions)
+	}
+
+	err = unstructured.SetNestedStringSlice(crd.Object, []string{storageVersion}, "status", "storedVersions")
+	if err != nil {
+		return err
+	}
+	err = c.Status().Update(context.TODO(), crd)
+	if err != nil {
+		return err
+	}
+
+	scopedLog.Info("Updated stored versions", "old", storedVersions, "new", storageVersion)
+	return nil
+}
+
+// getStoredVersions returns a CustomResourceDefinition, with the versions used to store its custom resources
+func getStoredVersions(c client.Client, crdName string) (*unstructured.Unstructured, []string, error) {
+	crd := &unstructured.Unstructured{}
+	crd.SetGroupVersionKind(schema.GroupVersionKind{Group: "apiextensions.k8s.io", Version: "v1", Kind: "CustomResourceDefinition"})
+	err := c.Get(context.TODO(), types.NamespacedName{Name: crdName}, crd)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	storedVersions, _, err := unstructured.NestedStringSlice(crd.Object, "status", "storedVersions")
+	return crd, storedVersions, err
+}
+
+// IsStorageVersionMigrated returns true when the custom resources of a kind in a namespace (or in all namespaces, if
+// empty) were already rewritten using the storage version, so that the migration only runs once. The migration of all
+// namespaces is recorded by the stored versions of the CustomResourceDefinition, and the migration of a namespace by a
+// ConfigMap of this namespace.
+func IsStorageVersionMigrated(c client.Client, gvk schema.GroupVersionKind, crdName, namespace string) (bool, error) {
+	if namespace == "" {
+		_, storedVersions, err := getStoredVersions(c, crdName)
+		if err != nil {
+			return false, err
+		}
+		return len(storedVersions) == 1 && storedVersions[0] == gvk.Version, nil
+	}
+
+	configMap := &corev1.ConfigMap{}
+	err := c.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: storageVersionMigrationConfigMap}, configMap)
+	if k8serrors.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return configMap.Data[gvk.Kind] == gvk.Version, nil
+}
+
+// SetStorageVersionMigrated records that all the custom resources of a kind in a namespace (or in all namespaces, if
+// empty) were rewritten using the storage version
+func SetStorageVersionMigrated(c client.Client, gvk schema.GroupVersionKind, crdName, namespace string) error {
+	if namespace == "" {
+		return UpdateStoredVersions(c, crdName, gvk.Version)
+	}
+
+	configMap := &corev1.ConfigMap{}
+	err := c.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: storageVersionMigrationConfigMap}, configMap)
+	if k8serrors.IsNotFound(err) {
+		configMap = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: storageVersionMigrationConfigMap, Namespace: namespace},
+			Data:       map[string]string{gvk.Kind: gvk.Version},
+		}
+		return c.Create(context.TODO(), configMap)
+	} else if err != nil {
+		return err
+	}
+
+	if configMap.Data == nil {
+		configMap.Data = make(map[string]string)
+	}
+	configMap.Data[gvk.Kind] = gvk.Version
+	return c.Update(context.TODO(), configMap)
+}
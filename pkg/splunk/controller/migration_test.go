@@ -0,0 +1,117 @@
+// Copyright (c) 2018-2021 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"testing"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	spltest "github.com/splunk/splunk-operator/pkg/splunk/test"
+)
+
+func TestMigrateStorageVersion(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "enterprise.splunk.com", Version: "v2", Kind: "Standalone"}
+	list := &unstructured.UnstructuredList{}
+	for _, name := range []string{"stack1", "stack2"} {
+		item := unstructured.Unstructured{}
+		item.SetGroupVersionKind(gvk)
+		item.SetName(name)
+		item.SetNamespace("test")
+		list.Items = append(list.Items, item)
+	}
+
+	c := spltest.NewMockClient()
+	c.ListObj = list
+	failed, err := MigrateStorageVersion(c, gvk, "test")
+	if err != nil || failed != 0 {
+		t.Errorf("MigrateStorageVersion() returned %d, %v; want 0, nil", failed, err)
+	}
+	mockCalls := map[string][]spltest.MockFuncCall{
+		"List": {{ListOpts: []client.ListOption{client.InNamespace("test")}}},
+		"Update": {
+			{MetaName: "*unstructured.Unstructured-test-stack1"},
+			{MetaName: "*unstructured.Unstructured-test-stack2"},
+		},
+	}
+	c.CheckCalls(t, "TestMigrateStorageVersion", mockCalls)
+
+	// listing errors are returned
+	c = spltest.NewMockClient()
+	if _, err = MigrateStorageVersion(c, gvk, "test"); err == nil {
+		t.Errorf("MigrateStorageVersion() returned nil; want error")
+	}
+}
+
+func TestUpdateStoredVersions(t *testing.T) {
+	test := func(storedVersions []string, wantErr bool) {
+		crd := &unstructured.Unstructured{}
+		crd.SetGroupVersionKind(schema.GroupVersionKind{Group: "apiextensions.k8s.io", Version: "v1", Kind: "CustomResourceDefinition"})
+		crd.SetName("standalones.enterprise.splunk.com")
+		_ = unstructured.SetNestedStringSlice(crd.Object, storedVersions, "status", "storedVersions")
+		c := spltest.NewMockClient()
+		c.AddObject(crd)
+		err := UpdateStoredVersions(c, crd.GetName(), "v2")
+		if wantErr && err == nil {
+			t.Errorf("UpdateStoredVersions(%v) returned nil; want error", storedVersions)
+		} else if !wantErr && err != nil {
+			t.Errorf("UpdateStoredVersions(%v) returned %v; want nil", storedVersions, err)
+		}
+	}
+
+	test([]string{"v1", "v2"}, false)
+	test([]string{"v2"}, false)
+	test([]string{"v1beta1"}, true)
+}
+
+func TestStorageVersionMigrated(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "enterprise.splunk.com", Version: "v2", Kind: "Standalone"}
+	crdName := "standalones.enterprise.splunk.com"
+	test := func(c *spltest.MockClient, namespace string, want bool) {
+		t.Helper()
+		migrated, err := IsStorageVersionMigrated(c, gvk, crdName, namespace)
+		if err != nil || migrated != want {
+			t.Errorf("IsStorageVersionMigrated(%q) returned %t, %v; want %t, nil", namespace, migrated, err, want)
+		}
+	}
+
+	// the migration of all namespaces is recorded by the stored versions
+	crd := &unstructured.Unstructured{}
+	crd.SetGroupVersionKind(schema.GroupVersionKind{Group: "apiextensions.k8s.io", Version: "v1", Kind: "CustomResourceDefinition"})
+	crd.SetName(crdName)
+	_ = unstructured.SetNestedStringSlice(crd.Object, []string{"v1", "v2"}, "status", "storedVersions")
+	c := spltest.NewMockClient()
+	c.AddObject(crd)
+	test(c, "", false)
+	_ = unstructured.SetNestedStringSlice(crd.Object, []string{"v2"}, "status", "storedVersions")
+	test(c, "", true)
+
+	// the migration of a namespace is recorded by a ConfigMap
+	c = spltest.NewMockClient()
+	c.NotFoundError = k8serrors.NewNotFound(schema.GroupResource{}, "")
+	test(c, "test", false)
+	if err := SetStorageVersionMigrated(c, gvk, crdName, "test"); err != nil {
+		t.Errorf("SetStorageVersionMigrated() returned %v", err)
+	}
+	c.CheckCalls(t, "SetStorageVersionMigrated", map[string][]spltest.MockFuncCall{
+		"Get":    {{MetaName: "*v1.ConfigMap-test-splunk-operator-storage-version-migration"}, {MetaName: "*v1.ConfigMap-test-splunk-operator-storage-version-migration"}},
+		"Create": {{MetaName: "*v1.ConfigMap-test-splunk-operator-storage-version-migration"}},
+	})
+	test(c, "test", true)
+	test(c, "other", false)
+}
@@ -0,0 +1,175 @@
+// Copyright (c) 2018-2021 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	splcommon "github.com/splunk/splunk-operator/pkg/splunk/common"
+	splutil "github.com/splunk/splunk-operator/pkg/splunk/util"
+)
+
+// defaultStorageClassAnnotation is set to "true" on the StorageClass used by claims without a storageClassName
+const defaultStorageClassAnnotation = "storageclass.kubernetes.io/is-default-class"
+
+// ExpandStatefulSetClaims grows the PersistentVolumeClaims of a StatefulSet to the storage requests of the desired
+// volume claim templates, indexed by template name. The claims are expanded online by the volume plugin; once the
+// file systems of all the claims are resized, the StatefulSet is deleted while orphaning its pods, so that it gets
+// recreated with the new volume claim templates, which are otherwise immutable. It returns nil when no claim needs
+// to grow.
+func ExpandStatefulSetClaims(c splcommon.ControllerClient, statefulSet *appsv1.StatefulSet, requests map[string]resource.Quantity) (*splcommon.PvcExpansionStatus, error) {
+	scopedLog := log.WithName("ExpandStatefulSetClaims").WithValues(
+		"name", statefulSet.GetObjectMeta().GetName(),
+		"namespace", statefulSet.GetObjectMeta().GetNamespace())
+
+	// find the volume claim templates with a larger storage request
+	var templates []corev1.PersistentVolumeClaim
+	for _, template := range statefulSet.Spec.VolumeClaimTemplates {
+		desired, ok := requests[template.GetName()]
+		current := template.Spec.Resources.Requests[corev1.ResourceStorage]
+		if ok && desired.Cmp(current) > 0 {
+			templates = append(templates, template)
+		}
+	}
+	if len(templates) == 0 {
+		return nil, nil
+	}
+
+	replicas := int32(1)
+	if statefulSet.Spec.Replicas != nil {
+		replicas = *statefulSet.Spec.Replicas
+	}
+	status := &splcommon.PvcExpansionStatus{TotalClaims: int32(len(templates)) * replicas}
+
+	for _, template := range templates {
+		allowed, err := isVolumeExpansionAllowed(c, template.Spec.StorageClassName)
+		if err != nil {
+			return nil, err
+		}
+		if !allowed {
+			status.Message = fmt.Sprintf("storage class of %s does not allow volume expansion", template.GetName())
+			scopedLog.Info("Unable to expand claims", "template", template.GetName())
+			return status, nil
+		}
+	}
+
+	for _, template := range templates {
+		desired := requests[template.GetName()]
+		for n := int32(0); n < replicas; n++ {
+			claimName := fmt.Sprintf("%s-%s-%d", template.GetName(), statefulSet.GetName(), n)
+			namespacedName := types.NamespacedName{Namespace: statefulSet.GetNamespace(), Name: claimName}
+			var pvc corev1.PersistentVolumeClaim
+			err := c.Get(context.TODO(), namespacedName, &pvc)
+			if k8serrors.IsNotFound(err) {
+				// the claim will be created by the StatefulSet with the new storage request
+				status.ExpandedClaims++
+				continue
+			} else if err != nil {
+				return nil, err
+			}
+
+			request := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+			if request.Cmp(desired) < 0 {
+				scopedLog.Info("Expanding claim", "claim", claimName, "from", request.String(), "to", desired.String())
+				if pvc.Spec.Resources.Requests == nil {
+					pvc.Spec.Resources.Requests = corev1.ResourceList{}
+				}
+				pvc.Spec.Resources.Requests[corev1.ResourceStorage] = desired
+				err = splutil.UpdateResource(c, &pvc)
+				if err != nil {
+					return nil, err
+				}
+				status.PendingClaims = append(status.PendingClaims, claimName)
+				continue
+			}
+
+			if !isClaimResized(&pvc, desired) {
+				status.PendingClaims = append(status.PendingClaims, claimName)
+				continue
+			}
+			status.ExpandedClaims++
+		}
+	}
+
+	if status.ExpandedClaims < status.TotalClaims {
+		return status, nil
+	}
+
+	// all the claims are expanded: recreate the StatefulSet, leaving its pods running
+	scopedLog.Info("Recreating StatefulSet with expanded volume claim templates")
+	err := c.Delete(context.TODO(), statefulSet, client.PropagationPolicy(metav1.DeletePropagationOrphan))
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return nil, err
+	}
+	return status, nil
+}
+
+// isClaimResized returns true once the volume and the file system of a claim have reached a storage capacity
+func isClaimResized(pvc *corev1.PersistentVolumeClaim, desired resource.Quantity) bool {
+	capacity := pvc.Status.Capacity[corev1.ResourceStorage]
+	if capacity.Cmp(desired) < 0 {
+		return false
+	}
+	for _, condition := range pvc.Status.Conditions {
+		if condition.Status == corev1.ConditionTrue &&
+			(condition.Type == corev1.PersistentVolumeClaimResizing || condition.Type == corev1.PersistentVolumeClaimFileSystemResizePending) {
+			return false
+		}
+	}
+	return true
+}
+
+// isVolumeExpansionAllowed returns true if a StorageClass, or the default one if storageClassName is not set,
+// allows volume expansion
+func isVolumeExpansionAllowed(c splcommon.ControllerClient, storageClassName *string) (bool, error) {
+	var storageClass *storagev1.StorageClass
+	if storageClassName != nil && *storageClassName != "" {
+		storageClass = &storagev1.StorageClass{}
+		err := c.Get(context.TODO(), types.NamespacedName{Name: *storageClassName}, storageClass)
+		if k8serrors.IsNotFound(err) {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+	} else {
+		storageClassList := storagev1.StorageClassList{}
+		err := c.List(context.TODO(), &storageClassList)
+		if k8serrors.IsNotFound(err) {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+		for i := range storageClassList.Items {
+			if storageClassList.Items[i].GetAnnotations()[defaultStorageClassAnnotation] == "true" {
+				storageClass = &storageClassList.Items[i]
+				break
+			}
+		}
+		if storageClass == nil {
+			return false, nil
+		}
+	}
+	return storageClass.AllowVolumeExpansion != nil && *storageClass.AllowVolumeExpansion, nil
+}
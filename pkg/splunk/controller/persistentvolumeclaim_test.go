@@ -0,0 +1,153 @@
+// Copyright (c) 2018-2021 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	spltest "github.com/splunk/splunk-operator/pkg/splunk/test"
+)
+
+func TestExpandStatefulSetClaims(t *testing.T) {
+	c := spltest.NewMockClient()
+	c.NotFoundError = k8serrors.NewNotFound(schema.GroupResource{}, "")
+
+	storageClassName := "gp2"
+	replicas := int32(2)
+	statefulSet := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "splunk-stack1-standalone", Namespace: "test"},
+		Spec: appsv1.StatefulSetSpec{
+			Replicas: &replicas,
+			VolumeClaimTemplates: []corev1.PersistentVolumeClaim{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "pvc-var"},
+					Spec: corev1.PersistentVolumeClaimSpec{
+						StorageClassName: &storageClassName,
+						Resources: corev1.ResourceRequirements{
+							Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("100Gi")},
+						},
+					},
+				},
+			},
+		},
+	}
+	for _, name := range []string{"pvc-var-splunk-stack1-standalone-0", "pvc-var-splunk-stack1-standalone-1"} {
+		pvc := statefulSet.Spec.VolumeClaimTemplates[0].DeepCopy()
+		pvc.ObjectMeta = metav1.ObjectMeta{Name: name, Namespace: "test"}
+		c.AddObject(pvc)
+	}
+
+	// nothing grew
+	requests := map[string]resource.Quantity{"pvc-var": resource.MustParse("100Gi"), "pvc-etc": resource.MustParse("10Gi")}
+	status, err := ExpandStatefulSetClaims(c, statefulSet, requests)
+	if err != nil || status != nil || len(c.Calls["Get"]) != 0 {
+		t.Errorf("ExpandStatefulSetClaims() = %v, %v; want nil without API calls", status, err)
+	}
+
+	// the storage class does not exist
+	requests["pvc-var"] = resource.MustParse("200Gi")
+	status, err = ExpandStatefulSetClaims(c, statefulSet, requests)
+	if err != nil || status == nil || status.Message == "" || len(c.Calls["Update"]) != 0 {
+		t.Errorf("ExpandStatefulSetClaims() = %v, %v; want a message", status, err)
+	}
+
+	// the claims are updated
+	allowed := true
+	c.AddObject(&storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "gp2"}, AllowVolumeExpansion: &allowed})
+	status, err = ExpandStatefulSetClaims(c, statefulSet, requests)
+	want := []string{"pvc-var-splunk-stack1-standalone-0", "pvc-var-splunk-stack1-standalone-1"}
+	if err != nil || status.TotalClaims != 2 || status.ExpandedClaims != 0 || !reflect.DeepEqual(status.PendingClaims, want) {
+		t.Errorf("ExpandStatefulSetClaims() = %v, %v; want pending claims %v", status, err, want)
+	}
+	if len(c.Calls["Update"]) != 2 {
+		t.Errorf("ExpandStatefulSetClaims() updated %d claims; want 2", len(c.Calls["Update"]))
+	}
+	pvc := c.Calls["Update"][0].Obj.(*corev1.PersistentVolumeClaim)
+	if got := pvc.Spec.Resources.Requests[corev1.ResourceStorage]; got.String() != "200Gi" {
+		t.Errorf("ExpandStatefulSetClaims() request = %s; want 200Gi", got.String())
+	}
+
+	// the file system of the first claim is resized, the second is pending
+	resized := pvc.DeepCopy()
+	resized.Status.Capacity = corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("200Gi")}
+	c.AddObject(resized)
+	pending := c.Calls["Update"][1].Obj.(*corev1.PersistentVolumeClaim).DeepCopy()
+	pending.Status.Capacity = corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("200Gi")}
+	pending.Status.Conditions = []corev1.PersistentVolumeClaimCondition{
+		{Type: corev1.PersistentVolumeClaimFileSystemResizePending, Status: corev1.ConditionTrue},
+	}
+	c.AddObject(pending)
+	c.ResetCalls()
+	status, err = ExpandStatefulSetClaims(c, statefulSet, requests)
+	if err != nil || status.ExpandedClaims != 1 || !reflect.DeepEqual(status.PendingClaims, want[1:]) {
+		t.Errorf("ExpandStatefulSetClaims() = %v, %v; want pending claims %v", status, err, want[1:])
+	}
+	if len(c.Calls["Update"]) != 0 || len(c.Calls["Delete"]) != 0 {
+		t.Errorf("ExpandStatefulSetClaims() updated or deleted resources while resizing")
+	}
+
+	// all the claims are expanded: the StatefulSet is deleted to be recreated
+	pending.Status.Conditions = nil
+	c.AddObject(pending)
+	status, err = ExpandStatefulSetClaims(c, statefulSet, requests)
+	if err != nil || status.ExpandedClaims != 2 || len(status.PendingClaims) != 0 {
+		t.Errorf("ExpandStatefulSetClaims() = %v, %v; want all claims expanded", status, err)
+	}
+	if len(c.Calls["Delete"]) != 1 {
+		t.Errorf("ExpandStatefulSetClaims() deleted %d resources; want the StatefulSet", len(c.Calls["Delete"]))
+	}
+}
+
+func TestIsVolumeExpansionAllowed(t *testing.T) {
+	c := spltest.NewMockClient()
+	c.NotFoundError = k8serrors.NewNotFound(schema.GroupResource{}, "")
+
+	// no default storage class
+	allowed, err := isVolumeExpansionAllowed(c, nil)
+	if err != nil || allowed {
+		t.Errorf("isVolumeExpansionAllowed() = %t, %v; want false", allowed, err)
+	}
+
+	enabled := true
+	c.ListObj = &storagev1.StorageClassList{
+		Items: []storagev1.StorageClass{
+			{ObjectMeta: metav1.ObjectMeta{Name: "standard"}},
+			{
+				ObjectMeta:           metav1.ObjectMeta{Name: "gp2", Annotations: map[string]string{defaultStorageClassAnnotation: "true"}},
+				AllowVolumeExpansion: &enabled,
+			},
+		},
+	}
+	allowed, err = isVolumeExpansionAllowed(c, nil)
+	if err != nil || !allowed {
+		t.Errorf("isVolumeExpansionAllowed() = %t, %v; want true", allowed, err)
+	}
+
+	c.AddObject(&storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "standard"}})
+	storageClassName := "standard"
+	allowed, err = isVolumeExpansionAllowed(c, &storageClassName)
+	if err != nil || allowed {
+		t.Errorf("isVolumeExpansionAllowed(standard) = %t, %v; want false", allowed, err)
+	}
+}
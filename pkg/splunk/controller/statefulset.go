@@ -29,7 +29,17 @@ import (
 )
 
 // DefaultStatefulSetPodManager is a simple StatefulSetPodManager that does nothing
-type DefaultStatefulSetPodManager struct{}
+type DefaultStatefulSetPodManager struct {
+	RolloutStrategy    splcommon.RolloutStrategy
+	PvcRetentionPolicy splcommon.PvcRetentionPolicy
+
+	// HoldUpdates, if set, returns true while the pods must not be recycled, as if the rollout was paused
+	HoldUpdates func() bool
+
+	// PrepareVolumes, if set, is called before a pod is recycled, and returns true once its data is ready to be
+	// used by the recycled pod
+	PrepareVolumes func(n int32) (bool, error)
+}
 
 // Update for DefaultStatefulSetPodManager handles all updates for a statefulset of standard pods
 func (mgr *DefaultStatefulSetPodManager) Update(client splcommon.ControllerClient, statefulSet *appsv1.StatefulSet, desiredReplicas int32) (splcommon.Phase, error) {
@@ -45,8 +55,11 @@ func (mgr *DefaultStatefulSetPodManager) PrepareScaleDown(n int32) (bool, error)
 	return true, nil
 }
 
-// PrepareRecycle for DefaultStatefulSetPodManager does nothing and returns true
+// PrepareRecycle for DefaultStatefulSetPodManager prepares the volumes of the pod, if needed, and returns true when ready
 func (mgr *DefaultStatefulSetPodManager) PrepareRecycle(n int32) (bool, error) {
+	if mgr.PrepareVolumes != nil {
+		return mgr.PrepareVolumes(n)
+	}
 	return true, nil
 }
 
@@ -55,6 +68,20 @@ func (mgr *DefaultStatefulSetPodManager) FinishRecycle(n int32) (bool, error) {
 	return true, nil
 }
 
+// GetRolloutStrategy for DefaultStatefulSetPodManager returns its RolloutStrategy, paused while updates are held
+func (mgr *DefaultStatefulSetPodManager) GetRolloutStrategy() splcommon.RolloutStrategy {
+	strategy := mgr.RolloutStrategy
+	if mgr.HoldUpdates != nil && mgr.HoldUpdates() {
+		strategy.Paused = true
+	}
+	return strategy
+}
+
+// GetPvcRetentionPolicy for DefaultStatefulSetPodManager returns its PvcRetentionPolicy
+func (mgr *DefaultStatefulSetPodManager) GetPvcRetentionPolicy() splcommon.PvcRetentionPolicy {
+	return mgr.PvcRetentionPolicy
+}
+
 // ApplyStatefulSet creates or updates a Kubernetes StatefulSet
 func ApplyStatefulSet(c splcommon.ControllerClient, revised *appsv1.StatefulSet) (splcommon.Phase, error) {
 	namespacedName := types.NamespacedName{Namespace: revised.GetNamespace(), Name: revised.GetName()}
@@ -149,6 +176,12 @@ func UpdateStatefulSetPods(c splcommon.ControllerClient, statefulSet *appsv1.Sta
 			return splcommon.PhaseError, err
 		}
 
+		// keep the PVCs used by the pod if they are reused by a future scale up
+		if policy := mgr.GetPvcRetentionPolicy(); policy != "" && policy != splcommon.PvcRetentionPolicyDelete {
+			scopedLog.Info("Retaining PVCs of removed Pod", "podName", podName, "pvcRetentionPolicy", policy)
+			return splcommon.PhaseScalingDown, nil
+		}
+
 		// delete PVCs used by the pod so that a future scale up will have clean state
 		for _, vol := range statefulSet.Spec.VolumeClaimTemplates {
 			namespacedName := types.NamespacedName{
@@ -175,7 +208,30 @@ func UpdateStatefulSetPods(c splcommon.ControllerClient, statefulSet *appsv1.Sta
 	// ready and no StatefulSet scaling is required
 	// readyReplicas == desiredReplicas
 
-	// check existing pods for desired updates
+	// check existing pods for desired updates; the rollout strategy is only needed once a pod is found to be
+	// recycled, since it may have to look at other custom resources
+	var strategy *splcommon.RolloutStrategy
+	var maxUnavailable, partition int32
+	getRolloutStrategy := func() {
+		if strategy != nil {
+			return
+		}
+		s := mgr.GetRolloutStrategy()
+		strategy = &s
+		maxUnavailable = strategy.MaxUnavailable
+		if maxUnavailable < 1 {
+			maxUnavailable = 1
+		}
+		if strategy.Partition != nil {
+			partition = *strategy.Partition
+		}
+	}
+
+	var batch []corev1.Pod               // pods prepared for recycling during this reconcile
+	var batchReady = true                // true if all pods of the batch are ready to be deleted
+	var unavailable int32                // pods recycled but not completed yet
+	var held int32                       // pods not updated because the rollout is paused or they are below the partition
+	heldSecrets := make(map[string]bool) // secrets mounted by the held pods
 	for n := readyReplicas - 1; n >= 0; n-- {
 		// get Pod
 		podName := fmt.Sprintf("%s-%d", statefulSet.GetName(), n)
@@ -193,30 +249,29 @@ func UpdateStatefulSetPods(c splcommon.ControllerClient, statefulSet *appsv1.Sta
 
 		// terminate pod if it has pending updates; k8s will start a new one with revised template
 		if statefulSet.Status.UpdateRevision != "" && statefulSet.Status.UpdateRevision != pod.GetLabels()["controller-revision-hash"] {
+			getRolloutStrategy()
+			if strategy.Paused || n < partition {
+				held++
+				for _, volume := range pod.Spec.Volumes {
+					if volume.Secret != nil {
+						heldSecrets[volume.Secret.SecretName] = true
+					}
+				}
+				continue
+			}
+
 			// pod needs to be updated; first, prepare it to be recycled
 			ready, err := mgr.PrepareRecycle(n)
 			if err != nil {
 				scopedLog.Error(err, "Unable to prepare Pod for recycling", "podName", podName)
 				return splcommon.PhaseError, err
 			}
-			if !ready {
-				// wait until pod quarantine has completed before deleting it
-				return splcommon.PhaseUpdating, nil
+			batch = append(batch, pod)
+			batchReady = batchReady && ready
+			if int32(len(batch))+unavailable >= maxUnavailable {
+				break
 			}
-
-			// deleting pod will cause StatefulSet controller to create a new one with latest template
-			scopedLog.Info("Recycling Pod for updates", "podName", podName,
-				"statefulSetRevision", statefulSet.Status.UpdateRevision,
-				"podRevision", pod.GetLabels()["controller-revision-hash"])
-			preconditions := client.Preconditions{UID: &pod.ObjectMeta.UID, ResourceVersion: &pod.ObjectMeta.ResourceVersion}
-			err = c.Delete(context.Background(), &pod, preconditions)
-			if err != nil {
-				scopedLog.Error(err, "Unable to delete Pod", "podName", podName)
-				return splcommon.PhaseError, err
-			}
-
-			// only delete one at a time
-			return splcommon.PhaseUpdating, nil
+			continue
 		}
 
 		// check if pod was previously prepared for recycling; if so, complete
@@ -226,17 +281,51 @@ func UpdateStatefulSetPods(c splcommon.ControllerClient, statefulSet *appsv1.Sta
 			return splcommon.PhaseError, err
 		}
 		if !complete {
-			// return and wait until next reconcile to let things settle down
+			// wait until next reconcile to let things settle down before recycling more pods than allowed
+			getRolloutStrategy()
+			unavailable++
+			if int32(len(batch))+unavailable >= maxUnavailable {
+				break
+			}
+		}
+	}
+
+	if len(batch) > 0 {
+		if !batchReady {
+			// wait until pod quarantine has completed for the whole batch before deleting it
 			return splcommon.PhaseUpdating, nil
 		}
+
+		for i := range batch {
+			// deleting pod will cause StatefulSet controller to create a new one with latest template
+			pod := &batch[i]
+			scopedLog.Info("Recycling Pod for updates", "podName", pod.GetName(),
+				"statefulSetRevision", statefulSet.Status.UpdateRevision,
+				"podRevision", pod.GetLabels()["controller-revision-hash"])
+			preconditions := client.Preconditions{UID: &pod.ObjectMeta.UID, ResourceVersion: &pod.ObjectMeta.ResourceVersion}
+			err := c.Delete(context.Background(), pod, preconditions)
+			if err != nil {
+				scopedLog.Error(err, "Unable to delete Pod", "podName", pod.GetName())
+				return splcommon.PhaseError, err
+			}
+		}
+		return splcommon.PhaseUpdating, nil
+	}
+	if unavailable > 0 {
+		return splcommon.PhaseUpdating, nil
 	}
 
-	// Remove unwanted owner references
-	err := splutil.RemoveUnwantedSecrets(c, statefulSet.GetName(), statefulSet.GetNamespace())
+	// Remove unwanted owner references; the pods that are not updated may still use older versions of the secrets
+	err := splutil.RemoveUnwantedSecretsExcept(c, statefulSet.GetName(), statefulSet.GetNamespace(), heldSecrets)
 	if err != nil {
 		return splcommon.PhaseReady, err
 	}
 
+	if held > 0 {
+		scopedLog.Info("Rollout is waiting for approval", "pendingPods", held, "paused", strategy.Paused, "partition", partition)
+		return splcommon.PhaseReady, nil
+	}
+
 	// all is good!
 	scopedLog.Info("All pods are ready")
 	return splcommon.PhaseReady, nil
@@ -15,6 +15,8 @@
 package controller
 
 import (
+	"fmt"
+	"reflect"
 	"testing"
 
 	appsv1 "k8s.io/api/apps/v1"
@@ -58,6 +60,27 @@ func TestDefaultStatefulSetPodManager(t *testing.T) {
 	mgr := DefaultStatefulSetPodManager{}
 	method := "DefaultStatefulSetPodManager.Update"
 	spltest.PodManagerTester(t, method, &mgr)
+
+	// updates held off are reported as a paused rollout
+	held := false
+	mgr.RolloutStrategy.MaxUnavailable = 2
+	mgr.HoldUpdates = func() bool { return held }
+	if strategy := mgr.GetRolloutStrategy(); strategy.Paused || strategy.MaxUnavailable != 2 {
+		t.Errorf("GetRolloutStrategy() = %v; want not paused", strategy)
+	}
+	held = true
+	if strategy := mgr.GetRolloutStrategy(); !strategy.Paused || strategy.MaxUnavailable != 2 {
+		t.Errorf("GetRolloutStrategy() = %v; want paused", strategy)
+	}
+
+	// pods are recycled once their volumes are prepared
+	if ready, err := mgr.PrepareRecycle(0); err != nil || !ready {
+		t.Errorf("PrepareRecycle() = %t, %v; want true", ready, err)
+	}
+	mgr.PrepareVolumes = func(n int32) (bool, error) { return n != 1, nil }
+	if ready, err := mgr.PrepareRecycle(1); err != nil || ready {
+		t.Errorf("PrepareRecycle(1) = %t, %v; want false", ready, err)
+	}
 }
 
 func updateStatefulSetPodsTester(t *testing.T, mgr splcommon.StatefulSetPodManager, statefulSet *appsv1.StatefulSet, desiredReplicas int32, initObjects ...runtime.Object) (splcommon.Phase, error) {
@@ -146,6 +169,148 @@ func TestUpdateStatefulSetPods(t *testing.T) {
 	}
 }
 
+func TestUpdateStatefulSetPodsPvcRetention(t *testing.T) {
+	var replicas int32 = 3
+	statefulSet := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "splunk-stack1", Namespace: "test"},
+		Spec: appsv1.StatefulSetSpec{
+			Replicas: &replicas,
+			VolumeClaimTemplates: []corev1.PersistentVolumeClaim{
+				{ObjectMeta: metav1.ObjectMeta{Name: "pvc-etc", Namespace: "test"}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "pvc-var", Namespace: "test"}},
+			},
+		},
+		Status: appsv1.StatefulSetStatus{Replicas: replicas, ReadyReplicas: replicas, UpdatedReplicas: replicas},
+	}
+	pvcs := []runtime.Object{
+		&corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "pvc-etc-splunk-stack1-2", Namespace: "test"}},
+		&corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "pvc-var-splunk-stack1-2", Namespace: "test"}},
+	}
+
+	test := func(policy splcommon.PvcRetentionPolicy, wantDeleted int) {
+		c := spltest.NewMockClient()
+		c.AddObjects(pvcs)
+		replicas = 3
+		mgr := DefaultStatefulSetPodManager{PvcRetentionPolicy: policy}
+		phase, err := UpdateStatefulSetPods(c, statefulSet, &mgr, 2)
+		if err != nil || phase != splcommon.PhaseScalingDown {
+			t.Errorf("UpdateStatefulSetPods(%s) returned %s, %v; want %s, nil", policy, phase, err, splcommon.PhaseScalingDown)
+		}
+		if *statefulSet.Spec.Replicas != 2 {
+			t.Errorf("UpdateStatefulSetPods(%s) replicas = %d; want 2", policy, *statefulSet.Spec.Replicas)
+		}
+		if len(c.Calls["Delete"]) != wantDeleted {
+			t.Errorf("UpdateStatefulSetPods(%s) deleted %d PVCs; want %d", policy, len(c.Calls["Delete"]), wantDeleted)
+		}
+	}
+
+	test("", 2)
+	test(splcommon.PvcRetentionPolicyDelete, 2)
+	test(splcommon.PvcRetentionPolicyRetain, 0)
+	test(splcommon.PvcRetentionPolicyRetainUntilCRDeleted, 0)
+}
+
+// rolloutTestPodManager is a DefaultStatefulSetPodManager with pods that are not ready to be recycled yet
+type rolloutTestPodManager struct {
+	DefaultStatefulSetPodManager
+	notReady map[int32]bool
+}
+
+// PrepareRecycle for rolloutTestPodManager returns false for the pods that are not ready
+func (mgr *rolloutTestPodManager) PrepareRecycle(n int32) (bool, error) {
+	return !mgr.notReady[n], nil
+}
+
+func TestUpdateStatefulSetPodsRollout(t *testing.T) {
+	var replicas int32 = 4
+	statefulSet := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "splunk-stack1",
+			Namespace: "test",
+		},
+		Spec: appsv1.StatefulSetSpec{
+			Replicas: &replicas,
+		},
+		Status: appsv1.StatefulSetStatus{
+			Replicas:       replicas,
+			ReadyReplicas:  replicas,
+			UpdateRevision: "v1",
+		},
+	}
+
+	test := func(mgr splcommon.StatefulSetPodManager, revisions []string, wantPhase splcommon.Phase, wantDeleted []string) {
+		c := spltest.NewMockClient()
+		c.AddObject(statefulSet)
+		for n, revision := range revisions {
+			c.AddObject(&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      fmt.Sprintf("splunk-stack1-%d", n),
+					Namespace: "test",
+					Labels:    map[string]string{"controller-revision-hash": revision},
+				},
+				Status: corev1.PodStatus{
+					Phase:             corev1.PodRunning,
+					ContainerStatuses: []corev1.ContainerStatus{{Ready: true}},
+				},
+			})
+		}
+		phase, err := UpdateStatefulSetPods(c, statefulSet, mgr, replicas)
+		if err != nil {
+			t.Errorf("UpdateStatefulSetPods(%v) returned %v; want nil", mgr.GetRolloutStrategy(), err)
+		}
+		if phase != wantPhase {
+			t.Errorf("UpdateStatefulSetPods(%v) phase = %s; want %s", mgr.GetRolloutStrategy(), phase, wantPhase)
+		}
+		var deleted []string
+		for _, call := range c.Calls["Delete"] {
+			deleted = append(deleted, call.Obj.(*corev1.Pod).GetName())
+		}
+		if !reflect.DeepEqual(deleted, wantDeleted) {
+			t.Errorf("UpdateStatefulSetPods(%v) deleted %v; want %v", mgr.GetRolloutStrategy(), deleted, wantDeleted)
+		}
+		// unwanted secrets are removed once the rollout is done or held
+		if wantPhase == splcommon.PhaseReady {
+			listedSecrets := false
+			for _, call := range c.Calls["List"] {
+				if _, ok := call.Obj.(*corev1.SecretList); ok {
+					listedSecrets = true
+				}
+			}
+			if !listedSecrets {
+				t.Errorf("UpdateStatefulSetPods(%v) didn't remove unwanted secrets", mgr.GetRolloutStrategy())
+			}
+		}
+	}
+	outdated := []string{"v0", "v0", "v0", "v0"}
+	partition := int32(2)
+
+	// one pod at a time by default
+	mgr := &DefaultStatefulSetPodManager{}
+	test(mgr, outdated, splcommon.PhaseUpdating, []string{"splunk-stack1-3"})
+
+	// batches of maxUnavailable pods
+	mgr.RolloutStrategy.MaxUnavailable = 3
+	test(mgr, outdated, splcommon.PhaseUpdating, []string{"splunk-stack1-3", "splunk-stack1-2", "splunk-stack1-1"})
+
+	// the pods below the partition are not updated
+	mgr.RolloutStrategy.Partition = &partition
+	test(mgr, outdated, splcommon.PhaseUpdating, []string{"splunk-stack1-3", "splunk-stack1-2"})
+	test(mgr, []string{"v0", "v0", "v1", "v1"}, splcommon.PhaseReady, nil)
+
+	// no pod is recycled while paused
+	mgr.RolloutStrategy = splcommon.RolloutStrategy{Paused: true}
+	test(mgr, outdated, splcommon.PhaseReady, nil)
+
+	// the pods of a batch are deleted once all of them are ready to be recycled
+	notReadyMgr := &rolloutTestPodManager{notReady: map[int32]bool{2: true}}
+	notReadyMgr.RolloutStrategy.MaxUnavailable = 2
+	test(notReadyMgr, outdated, splcommon.PhaseUpdating, nil)
+	test(notReadyMgr, []string{"v0", "v0", "v0", "v1"}, splcommon.PhaseUpdating, nil)
+	notReadyMgr.notReady = nil
+	test(notReadyMgr, []string{"v0", "v0", "v0", "v1"}, splcommon.PhaseUpdating, []string{"splunk-stack1-2", "splunk-stack1-1"})
+	test(notReadyMgr, []string{"v1", "v1", "v1", "v1"}, splcommon.PhaseReady, nil)
+}
+
 func TestSetStatefulSetOwnerRef(t *testing.T) {
 	cr := enterpriseApi.Standalone{
 		ObjectMeta: metav1.ObjectMeta{
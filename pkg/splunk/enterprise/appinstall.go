@@ -0,0 +1,989 @@
+// Copyright (c) 2018-2021 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enterprise
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	enterpriseApi "github.com/splunk/splunk-operator/pkg/apis/enterprise/v2"
+	splclient "github.com/splunk/splunk-operator/pkg/splunk/client"
+	splcommon "github.com/splunk/splunk-operator/pkg/splunk/common"
+	splutil "github.com/splunk/splunk-operator/pkg/splunk/util"
+)
+
+// appDownloadDir is the directory of the operator where the app packages are downloaded before they are copied to the pods
+var appDownloadDir = filepath.Join(os.TempDir(), "splunk-operator", "apps")
+
+// maxDeployedObjectHashes is the number of versions kept in the deploy history of each app
+const maxDeployedObjectHashes = 5
+
+const (
+	// defaultAppInstallTimeout is the install timeout in seconds of the apps whose app source does not set one
+	defaultAppInstallTimeout = 600
+
+	// defaultPremiumAppSetupTimeout is the setup timeout in seconds of the premium apps whose app source does not
+	// set one, as the setup of Enterprise Security takes much longer than the install of an app
+	defaultPremiumAppSetupTimeout = 3600
+)
+
+const (
+	// appRestartRequiredMarker is printed by appInstallScript when the app needs Splunk to restart once installed
+	appRestartRequiredMarker = "restart_required"
+
+	// splunkRESTFunc defines splunk_rest, which calls the REST API of Splunk in its pod as admin with curl, and prints
+	// the messages of the response when it fails. curl reads the password from its config on stdin, so that it never
+	// shows in the arguments of a process. A call exceeding its max time (-m) exits with code 28. Scripts using it
+	// start with it, once formatted.
+	splunkRESTFunc = `splunk_rest() {
+out=$(mktemp)
+code=$(sed 's/[\\"]/\\&/g;s/^/user = "admin:/;s/$/"/' /mnt/splunk-secrets/password | curl -sSk -K - -o "$out" -w '%{http_code}' "$@") || { rc=$?; rm -f "$out"; return $rc; }
+case "$code" in 2*) rm -f "$out"; return 0;; esac
+echo "HTTP $code: $(sed -n 's:.*<msg type="[A-Z]*">\(.*\)</msg>.*:\1:p' "$out")" >&2
+rm -f "$out"
+return 1
+}
+`
+
+	// splunkRESTURL is the URL of the REST API of Splunk, from its pod
+	splunkRESTURL = "https://127.0.0.1:8089/services"
+
+	// the scripts below are formatted with shell-quoted values, see splcommon.ShellQuote
+
+	// appStageScript copies an app package to the staging volume of a pod, from the stdin of the script
+	appStageScript = `mkdir -p %s && cat > %s`
+
+	// appInstallScript installs an app package staged in a pod, or upgrades it if it is already installed, and tells
+	// whether its app.conf declares that Splunk must restart after an install. The install is killed after its timeout.
+	appInstallScript = `set -e
+pkg=%[1]s
+if tar -xzOf "$pkg" --wildcards '*default/app.conf' 2>/dev/null | grep -Eqi '^ *state_change_requires_restart *= *(true|1)'; then echo ` + appRestartRequiredMarker + `; fi
+splunk_rest -m %[2]d ` + splunkRESTURL + `/apps/local --data-urlencode "name=$pkg" -d filename=true -d update=true || { rc=$?; if [ $rc -eq 28 ]; then echo 'install timed out after %[2]d seconds' >&2; fi; exit $rc; }
+`
+
+	// appBundleScript replaces the directory of an app in the bundle of a cluster manager or a deployer. When the app
+	// is installed locally first, its installed directory is copied, instead of the content of the package.
+	appBundleScript = `set -e
+pkg=%[1]s bundle_dir=%[2]s app_dir=%[3]s
+rm -rf "$bundle_dir/$app_dir"
+if [ %[4]t = true ]; then cp -r "/opt/splunk/etc/apps/$app_dir" "$bundle_dir/"; else tar -xzf "$pkg" -C "$bundle_dir"; fi
+`
+
+	// appRemoveScript removes an installed app from a pod, along with its staged package
+	appRemoveScript = `set -e
+app_dir=%[1]s pkg=%[2]s
+if [ -d "/opt/splunk/etc/apps/$app_dir" ]; then splunk_rest -X DELETE "` + splunkRESTURL + `/apps/local/$app_dir"; fi
+rm -f "$pkg"
+`
+
+	// appBundleRemoveScript removes the directory of an app from the bundle of a cluster manager or a deployer,
+	// along with its staged package
+	appBundleRemoveScript = `rm -rf %s %s`
+
+	// shcBundlePushCmd pushes the bundle of a deployer to the search head cluster of one of its members
+	shcBundlePushCmd = `splunk_rest ` + splunkRESTURL + `/apps/deploy -d target=https://%s:8089 -d action=all`
+
+	// splunkRestartCmd restarts Splunk in its pod, without recycling the pod
+	splunkRestartCmd = "/opt/splunk/bin/splunk restart"
+
+	// esSetupCmd runs the post-install setup of Enterprise Security as a search, with its timeout, its SSL enablement
+	// mode and the arguments of the deployment type of the pod
+	esSetupCmd = `splunk_rest -m %d ` + splunkRESTURL + `/search/jobs -d exec_mode=oneshot --data-urlencode 'search=| essinstall --ssl_enablement %s%s'`
+
+	// premiumAppSetupScript starts the setup command of a premium app in the background of a pod, so that a long
+	// setup does not block the reconcile. The setup directory keeps the pid of the setup, its log, and its exit code
+	// once it ends.
+	premiumAppSetupScript = `set -e
+setup_dir=%[1]s
+rm -rf "$setup_dir"
+mkdir -p "$setup_dir"
+cat > "$setup_dir/setup.sh" <<'EOF'
+%[2]s
+EOF
+nohup sh -c 'sh "$0/setup.sh" > "$0/setup.log" 2>&1; echo $? > "$0/status"' "$setup_dir" </dev/null >/dev/null 2>&1 &
+echo $! > "$setup_dir/pid"
+`
+
+	// premiumAppSetupStatusScript prints the exit code of the setup of a premium app followed by the end of its log
+	// once it ended, "exit lost" if it was interrupted, e.g. by a restart of the container, and nothing while it runs
+	premiumAppSetupStatusScript = `setup_dir=%[1]s
+if [ -f "$setup_dir/status" ]; then echo "exit $(cat "$setup_dir/status")"; tail -n 5 "$setup_dir/setup.log"
+elif ! kill -0 "$(cat "$setup_dir/pid" 2>/dev/null)" 2>/dev/null; then echo 'exit lost'; fi
+`
+)
+
+// appInstaller installs the apps of the App Framework in the running pods of a custom resource. Each app package is
+// downloaded once by the operator, copied to the staging volume of the pods, and installed with the Splunk CLI:
+// locally on every pod for the local scope, or in the bundle of the cluster manager or deployer, which is then
+// pushed, for the cluster scopes. The install status of each app is tracked per pod, so that new pods of a scale up
+// get all the apps, and Splunk is only restarted when an app declares it needs a restart. Premium apps are installed
+// locally on the standalone pods or on the deployer, then set up in the background of the pod. The app sources are
+// processed once the app sources they depend on are installed.
+type appInstaller struct {
+	client             splcommon.ControllerClient
+	cr                 splcommon.MetaObject
+	appFrameworkConfig *enterpriseApi.AppFrameworkSpec
+	appDeployContext   *enterpriseApi.AppDeploymentContext
+
+	// pods where the apps of local scope are installed
+	localPods []string
+
+	// pod holding the bundle of the apps of cluster scope, and its directory; empty when the custom resource
+	// does not support the cluster scope
+	bundlePod string
+	bundleDir string
+
+	// premiumApps is set when the custom resource supports the premiumApps scope, whose apps are installed on the
+	// pod holding the bundle if any, or else on the local pods
+	premiumApps bool
+
+	// pushBundle pushes the bundle of the apps of cluster scope to the cluster
+	pushBundle func() error
+
+	// podExec runs a shell script in a pod, with the content of stdin as its input, and returns its output
+	podExec func(c splcommon.ControllerClient, podName string, namespace string, script string, stdin io.Reader) (string, error)
+
+	// getS3Client returns the remote storage client of an app source
+	getS3Client func(client splcommon.ControllerClient, cr splcommon.MetaObject,
+		appFrameworkRef *enterpriseApi.AppFrameworkSpec, vol *enterpriseApi.VolumeSpec,
+		location string, fp splclient.GetInitFunc) (splclient.SplunkS3Client, error)
+}
+
+// newAppInstaller returns an appInstaller for the pods of a custom resource
+func newAppInstaller(c splcommon.ControllerClient, cr splcommon.MetaObject, appFrameworkConfig *enterpriseApi.AppFrameworkSpec, appDeployContext *enterpriseApi.AppDeploymentContext) *appInstaller {
+	installer := &appInstaller{
+		client:             c,
+		cr:                 cr,
+		appFrameworkConfig: appFrameworkConfig,
+		appDeployContext:   appDeployContext,
+		podExec:            podExecScript,
+		getS3Client:        GetRemoteStorageClient,
+	}
+
+	switch cr := cr.(type) {
+	case *enterpriseApi.Standalone:
+		for n := int32(0); n < cr.Spec.Replicas; n++ {
+			installer.localPods = append(installer.localPods, GetSplunkStatefulsetPodName(SplunkStandalone, cr.GetName(), n))
+		}
+		installer.premiumApps = true
+	case *enterpriseApi.LicenseMaster:
+		installer.localPods = []string{GetSplunkStatefulsetPodName(SplunkLicenseMaster, cr.GetName(), 0)}
+	case *enterpriseApi.ClusterMaster:
+		installer.bundlePod = GetSplunkStatefulsetPodName(SplunkClusterMaster, cr.GetName(), 0)
+		installer.bundleDir = "/opt/splunk/etc/master-apps"
+		installer.localPods = []string{installer.bundlePod}
+		installer.pushBundle = func() error {
+			return PushMasterAppsBundle(c, cr)
+		}
+	case *enterpriseApi.SearchHeadCluster:
+		installer.bundlePod = GetSplunkStatefulsetPodName(SplunkDeployer, cr.GetName(), 0)
+		installer.bundleDir = "/opt/splunk/etc/shcluster/apps"
+		installer.localPods = []string{installer.bundlePod}
+		installer.premiumApps = true
+		installer.pushBundle = func() error {
+			target := GetSplunkStatefulsetURL(cr.GetNamespace(), SplunkSearchHead, cr.GetName(), 0, false)
+			_, err := installer.podExec(c, installer.bundlePod, cr.GetNamespace(), splunkRESTFunc+fmt.Sprintf(shcBundlePushCmd, target), nil)
+			return err
+		}
+	}
+
+	return installer
+}
+
+// podExecScript runs a shell script in a pod using splutil.PodExecStream
+func podExecScript(c splcommon.ControllerClient, podName string, namespace string, script string, stdin io.Reader) (string, error) {
+	var stdout bytes.Buffer
+	err := splutil.PodExecStream(c, podName, namespace, []string{"/bin/sh", "-c", script}, stdin, &stdout)
+	return stdout.String(), err
+}
+
+// install installs the active apps missing on some pods, removes the apps deleted from the remote storage from the
+// pods still carrying them, and pushes the bundle of the cluster manager or deployer if apps of cluster scope changed.
+// It returns true once there are no more apps to install or remove. Failures are marked with an Error status, and
+// retried on the next call.
+func (i *appInstaller) install() (bool, error) {
+	scopedLog := log.WithName("appInstaller").WithValues("name", i.cr.GetName(), "namespace", i.cr.GetNamespace())
+
+	appSrcNames := i.getAppSrcInstallOrder()
+
+	var firstErr error
+	setError := func(err error) {
+		scopedLog.Error(err, "App install failed")
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	restartPods := map[string]bool{}
+	var bundleApps, bundleRemovals []*enterpriseApi.AppDeploymentInfo
+	appSrcScopes := map[string]string{}
+	for _, appSrc := range appSrcNames {
+		appSrcDeploymentInfo := i.appDeployContext.AppsSrcDeployStatus[appSrc]
+		inConfig := CheckIfAppSrcExistsInConfig(i.appFrameworkConfig, appSrc)
+		if inConfig {
+			// keep the scope and the delete policy, to remove the apps once the app source is removed from the config
+			appSrcDeploymentInfo.Scope = getAppSrcScope(i.appFrameworkConfig, appSrc)
+			appSrcDeploymentInfo.DeletePolicy = getAppSrcDeletePolicy(i.appFrameworkConfig, appSrc)
+		}
+		scope := appSrcDeploymentInfo.Scope
+		if !i.supportsScope(scope) {
+			continue
+		}
+		appSrcScopes[appSrc] = scope
+
+		waiting := false
+		for _, dependency := range getAppSrcDependsOn(i.appFrameworkConfig, appSrc) {
+			if !i.isAppSrcInstalled(dependency) {
+				scopedLog.Info("Waiting for the apps of the app source dependency to be installed", "appSource", appSrc, "dependency", dependency)
+				waiting = true
+				break
+			}
+		}
+
+		installTimeout, setupTimeout := defaultAppInstallTimeout, defaultPremiumAppSetupTimeout
+		if timeout := getAppSrcInstallTimeout(i.appFrameworkConfig, appSrc); timeout > 0 {
+			installTimeout, setupTimeout = timeout, timeout
+		}
+
+		var s3Client *splclient.SplunkS3Client
+		appList := appSrcDeploymentInfo.AppDeploymentInfoList
+		for idx := range appList {
+			app := &appList[idx]
+			if app.RepoState == enterpriseApi.RepoStateDeleted {
+				removedFromBundle, err := i.removeApp(appSrc, app, scope, appSrcDeploymentInfo.DeletePolicy)
+				if err != nil {
+					setError(err)
+				}
+				if removedFromBundle {
+					bundleRemovals = append(bundleRemovals, app)
+				}
+				continue
+			}
+			if app.RepoState != enterpriseApi.RepoStateActive || !inConfig {
+				continue
+			}
+			pods := i.getPendingPods(app, scope)
+			if len(pods) == 0 {
+				i.setAppDeployStatus(app, scope)
+				continue
+			}
+			if waiting {
+				app.DeployStatus = enterpriseApi.DeployStatusPending
+				continue
+			}
+			app.DeployStatus = enterpriseApi.DeployStatusInProgress
+
+			// the premium apps whose setup is running are only checked, and are installed again if their setup failed
+			if scope == enterpriseApi.ScopePremiumApps {
+				var installPods []string
+				for _, pod := range pods {
+					if app.SetupStatus[pod] == enterpriseApi.DeployStatusComplete && i.bundlePod != "" {
+						// the setup is done, and only the push of the bundle failed
+						bundleApps = append(bundleApps, app)
+						continue
+					}
+					if app.SetupStatus[pod] != enterpriseApi.DeployStatusInProgress {
+						installPods = append(installPods, pod)
+						continue
+					}
+					status, err := i.getPremiumAppSetupStatus(pod, appSrc, app, setupTimeout)
+					if err != nil {
+						setError(fmt.Errorf("unable to set up app %s of app source %s on pod %s: %v", app.AppName, appSrc, pod, err))
+					}
+					app.SetupStatus[pod] = status
+					switch status {
+					case enterpriseApi.DeployStatusComplete:
+						if i.bundlePod != "" {
+							bundleApps = append(bundleApps, app)
+						} else {
+							app.PodDeployStatus[pod] = enterpriseApi.DeployStatusComplete
+							app.RestartRequired = true
+							restartPods[pod] = true
+						}
+					case enterpriseApi.DeployStatusError:
+						app.PodDeployStatus[pod] = enterpriseApi.DeployStatusError
+					}
+				}
+				pods = installPods
+				if len(pods) == 0 {
+					i.setAppDeployStatus(app, scope)
+					continue
+				}
+			}
+
+			// download the package once for all the pods
+			localFile := i.getLocalAppFile(appSrc, app)
+			if _, err := os.Stat(localFile); err != nil {
+				if s3Client == nil {
+					client, err := i.getAppSrcClient(appSrc)
+					if err != nil {
+						setError(err)
+						app.DeployStatus = enterpriseApi.DeployStatusError
+						continue
+					}
+					s3Client = &client
+				}
+				err = os.MkdirAll(filepath.Dir(localFile), 0755)
+				if err == nil {
+					err = i.downloadApp(s3Client, appSrc, app, localFile)
+				}
+				if err != nil {
+					setError(fmt.Errorf("unable to download app %s of app source %s: %v", app.AppName, appSrc, err))
+					app.DeployStatus = enterpriseApi.DeployStatusError
+					continue
+				}
+
+				// a package failing the verification is never installed, nor kept to be installed later
+				err = i.verifySignature(s3Client, appSrc, app, localFile)
+				if err != nil {
+					os.Remove(localFile)
+					setError(fmt.Errorf("unable to verify the signature of app %s of app source %s: %v", app.AppName, appSrc, err))
+					app.DeployStatus = enterpriseApi.DeployStatusError
+					continue
+				}
+			}
+			appDir, err := getAppPackageDirectory(localFile)
+			if err != nil {
+				setError(fmt.Errorf("unable to read the package of app %s of app source %s: %v", app.AppName, appSrc, err))
+				app.DeployStatus = enterpriseApi.DeployStatusError
+				continue
+			}
+			app.AppDirectory = appDir
+
+			for _, pod := range pods {
+				restart, err := i.installOnPod(pod, appSrc, app, localFile, scope, installTimeout)
+				if err != nil {
+					setError(fmt.Errorf("unable to install app %s of app source %s on pod %s: %v", app.AppName, appSrc, pod, err))
+					app.PodDeployStatus[pod] = enterpriseApi.DeployStatusError
+					continue
+				}
+				if scope == enterpriseApi.ScopePremiumApps {
+					// Splunk is restarted once the setup is complete, as a restart would interrupt it
+					err = i.startPremiumAppSetup(pod, appSrc, app, setupTimeout)
+					if err != nil {
+						setError(fmt.Errorf("unable to set up app %s of app source %s on pod %s: %v", app.AppName, appSrc, pod, err))
+						app.SetupStatus[pod] = enterpriseApi.DeployStatusError
+						app.PodDeployStatus[pod] = enterpriseApi.DeployStatusError
+						continue
+					}
+					app.SetupStatus[pod] = enterpriseApi.DeployStatusInProgress
+					app.PodDeployStatus[pod] = enterpriseApi.DeployStatusInProgress
+					continue
+				}
+				if restart {
+					app.RestartRequired = true
+					restartPods[pod] = true
+				}
+				if scope == enterpriseApi.ScopeLocal {
+					app.PodDeployStatus[pod] = enterpriseApi.DeployStatusComplete
+				} else {
+					bundleApps = append(bundleApps, app)
+				}
+			}
+
+			// the app sources depending on this one can proceed once it is installed on all its pods
+			i.setAppDeployStatus(app, scope)
+		}
+		i.appDeployContext.AppsSrcDeployStatus[appSrc] = appSrcDeploymentInfo
+	}
+
+	// push the bundle once for all the apps of cluster scope
+	if len(bundleApps) > 0 || len(bundleRemovals) > 0 {
+		pushStatus := enterpriseApi.DeployStatusComplete
+		if err := i.pushBundle(); err != nil {
+			setError(fmt.Errorf("unable to push the bundle of pod %s: %v", i.bundlePod, err))
+			pushStatus = enterpriseApi.DeployStatusError
+		}
+		for _, app := range bundleApps {
+			app.PodDeployStatus[i.bundlePod] = pushStatus
+		}
+		for _, app := range bundleRemovals {
+			if pushStatus == enterpriseApi.DeployStatusComplete {
+				delete(app.PodDeployStatus, i.bundlePod)
+			} else {
+				app.PodDeployStatus[i.bundlePod] = pushStatus
+			}
+		}
+	}
+
+	// restart Splunk on the pods where an installed app requires it, one pod per call
+	for pod := range restartPods {
+		i.addPendingRestart(pod)
+	}
+	restarting, err := i.restartPendingPod()
+	if err != nil {
+		setError(err)
+	}
+
+	// update the status of the apps, and remove the packages of the apps installed on, or removed from, all their pods
+	done := !restarting
+	for _, appSrc := range appSrcNames {
+		scope, ok := appSrcScopes[appSrc]
+		if !ok {
+			continue
+		}
+		inConfig := CheckIfAppSrcExistsInConfig(i.appFrameworkConfig, appSrc)
+		appList := i.appDeployContext.AppsSrcDeployStatus[appSrc].AppDeploymentInfoList
+		for idx := range appList {
+			app := &appList[idx]
+			if app.RepoState == enterpriseApi.RepoStateDeleted {
+				if app.DeployStatus == enterpriseApi.DeployStatusInProgress {
+					setAppRemoveStatus(app)
+				}
+			} else if app.RepoState != enterpriseApi.RepoStateActive || !inConfig {
+				continue
+			} else if app.DeployStatus == enterpriseApi.DeployStatusInProgress {
+				i.setAppDeployStatus(app, scope)
+			}
+			switch app.DeployStatus {
+			case enterpriseApi.DeployStatusComplete:
+				if app.RepoState == enterpriseApi.RepoStateActive {
+					addDeployedObjectHash(app)
+				}
+				os.Remove(i.getLocalAppFile(appSrc, app))
+			case enterpriseApi.DeployStatusPending, enterpriseApi.DeployStatusInProgress:
+				done = false
+			}
+		}
+	}
+
+	return done, firstErr
+}
+
+// addPendingRestart adds a pod to the pods where Splunk must be restarted, kept in the deploy context until it is
+func (i *appInstaller) addPendingRestart(pod string) {
+	for _, pending := range i.appDeployContext.PendingRestartPods {
+		if pending == pod {
+			return
+		}
+	}
+	i.appDeployContext.PendingRestartPods = append(i.appDeployContext.PendingRestartPods, pod)
+	sort.Strings(i.appDeployContext.PendingRestartPods)
+}
+
+// restartPendingPod restarts Splunk on the first pod pending a restart, once all the pods where apps are installed
+// are ready, so that the pods are restarted one at a time, each on its own reconcile. Pods where the setup of a
+// premium app runs are restarted once it ends. It returns true while restarts are pending.
+func (i *appInstaller) restartPendingPod() (bool, error) {
+	// forget the pods removed by a scale down
+	var pending []string
+	for _, pod := range i.appDeployContext.PendingRestartPods {
+		for _, localPod := range i.localPods {
+			if pod == localPod {
+				pending = append(pending, pod)
+				break
+			}
+		}
+	}
+	i.appDeployContext.PendingRestartPods = pending
+	if len(pending) == 0 {
+		return false, nil
+	}
+
+	for _, pod := range i.localPods {
+		ready, err := i.isPodReady(pod)
+		if err != nil || !ready {
+			return true, err
+		}
+	}
+
+	scopedLog := log.WithName("appInstaller").WithValues("name", i.cr.GetName(), "namespace", i.cr.GetNamespace())
+	for idx, pod := range pending {
+		if i.isPremiumAppSetupRunning(pod) {
+			continue
+		}
+		scopedLog.Info("Restarting Splunk to complete the install of apps", "pod", pod)
+		if _, err := i.podExec(i.client, pod, i.cr.GetNamespace(), splunkRestartCmd, nil); err != nil {
+			return true, fmt.Errorf("unable to restart Splunk on pod %s: %v", pod, err)
+		}
+		i.appDeployContext.PendingRestartPods = append(pending[:idx:idx], pending[idx+1:]...)
+		break
+	}
+	return len(i.appDeployContext.PendingRestartPods) > 0, nil
+}
+
+// isPodReady returns true if a pod is running and its Splunk container is ready
+func (i *appInstaller) isPodReady(pod string) (bool, error) {
+	var podObj corev1.Pod
+	err := i.client.Get(context.TODO(), types.NamespacedName{Namespace: i.cr.GetNamespace(), Name: pod}, &podObj)
+	if k8serrors.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return podObj.Status.Phase == corev1.PodRunning && len(podObj.Status.ContainerStatuses) > 0 && podObj.Status.ContainerStatuses[0].Ready, nil
+}
+
+// isPremiumAppSetupRunning returns true if the setup of a premium app runs on a pod
+func (i *appInstaller) isPremiumAppSetupRunning(pod string) bool {
+	for _, appSrcDeploymentInfo := range i.appDeployContext.AppsSrcDeployStatus {
+		for _, app := range appSrcDeploymentInfo.AppDeploymentInfoList {
+			if app.SetupStatus[pod] == enterpriseApi.DeployStatusInProgress {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// getAppSrcInstallOrder returns the names of the app sources of the deploy context, sorted by name, with each app
+// source after the app sources it depends on
+func (i *appInstaller) getAppSrcInstallOrder() []string {
+	var appSrcNames []string
+	for appSrc := range i.appDeployContext.AppsSrcDeployStatus {
+		appSrcNames = append(appSrcNames, appSrc)
+	}
+	sort.Strings(appSrcNames)
+
+	var ordered []string
+	visited := map[string]bool{}
+	var visit func(appSrc string)
+	visit = func(appSrc string) {
+		if visited[appSrc] {
+			return
+		}
+		visited[appSrc] = true
+		for _, dependency := range getAppSrcDependsOn(i.appFrameworkConfig, appSrc) {
+			if _, ok := i.appDeployContext.AppsSrcDeployStatus[dependency]; ok {
+				visit(dependency)
+			}
+		}
+		ordered = append(ordered, appSrc)
+	}
+	for _, appSrc := range appSrcNames {
+		visit(appSrc)
+	}
+	return ordered
+}
+
+// isAppSrcInstalled returns true once all the active apps of an app source are installed on all their pods
+func (i *appInstaller) isAppSrcInstalled(appSrc string) bool {
+	appSrcDeploymentInfo, ok := i.appDeployContext.AppsSrcDeployStatus[appSrc]
+	if !ok {
+		return false
+	}
+	for _, app := range appSrcDeploymentInfo.AppDeploymentInfoList {
+		if app.RepoState == enterpriseApi.RepoStateActive && app.DeployStatus != enterpriseApi.DeployStatusComplete {
+			return false
+		}
+	}
+	return true
+}
+
+// supportsScope returns true if the apps of an app source scope can be installed on the pods of the custom resource
+func (i *appInstaller) supportsScope(scope string) bool {
+	switch scope {
+	case enterpriseApi.ScopeLocal:
+		return true
+	case enterpriseApi.ScopePremiumApps:
+		return i.premiumApps
+	case enterpriseApi.ScopeCluster, enterpriseApi.ScopeClusterWithPreConfig:
+		return i.bundlePod != ""
+	}
+	return false
+}
+
+// usesBundle returns true if the apps of an app source scope are deployed through the bundle of the pod holding it
+func (i *appInstaller) usesBundle(scope string) bool {
+	return scope != enterpriseApi.ScopeLocal && i.bundlePod != ""
+}
+
+// getTargetPods returns the pods where the apps of an app source scope are installed
+func (i *appInstaller) getTargetPods(scope string) []string {
+	if !i.usesBundle(scope) {
+		return i.localPods
+	}
+	return []string{i.bundlePod}
+}
+
+// pruneRemovedPods removes the status of the pods which are not targets of an app anymore, e.g. after a scale down
+func (i *appInstaller) pruneRemovedPods(app *enterpriseApi.AppDeploymentInfo, scope string) {
+	targets := i.getTargetPods(scope)
+	for pod := range app.PodDeployStatus {
+		found := false
+		for _, target := range targets {
+			if pod == target {
+				found = true
+				break
+			}
+		}
+		if !found {
+			delete(app.PodDeployStatus, pod)
+			delete(app.SetupStatus, pod)
+		}
+	}
+}
+
+// getPendingPods returns the target pods where an app is not installed yet, after removing the status of the
+// pods which are not targets anymore
+func (i *appInstaller) getPendingPods(app *enterpriseApi.AppDeploymentInfo, scope string) []string {
+	targets := i.getTargetPods(scope)
+	i.pruneRemovedPods(app, scope)
+
+	var pending []string
+	for _, pod := range targets {
+		if app.PodDeployStatus[pod] != enterpriseApi.DeployStatusComplete {
+			pending = append(pending, pod)
+		}
+	}
+	if len(pending) > 0 && app.PodDeployStatus == nil {
+		app.PodDeployStatus = make(map[string]enterpriseApi.AppDeploymentStatus)
+	}
+	if len(pending) > 0 && app.SetupStatus == nil && scope == enterpriseApi.ScopePremiumApps {
+		app.SetupStatus = make(map[string]enterpriseApi.AppDeploymentStatus)
+	}
+	return pending
+}
+
+// setAppDeployStatus sets the status of an app from its status on its target pods: Complete once it is installed
+// on all of them, and Error if it failed on any of them
+func (i *appInstaller) setAppDeployStatus(app *enterpriseApi.AppDeploymentInfo, scope string) {
+	status := enterpriseApi.DeployStatusComplete
+	for _, pod := range i.getTargetPods(scope) {
+		switch app.PodDeployStatus[pod] {
+		case enterpriseApi.DeployStatusComplete:
+		case enterpriseApi.DeployStatusError:
+			app.DeployStatus = enterpriseApi.DeployStatusError
+			return
+		default:
+			status = enterpriseApi.DeployStatusInProgress
+		}
+	}
+	app.DeployStatus = status
+}
+
+// installOnPod copies an app package to the staging volume of a pod, then installs it locally, or adds it to the
+// bundle of the pod for the cluster scopes. It returns true if Splunk must be restarted on the pod.
+func (i *appInstaller) installOnPod(pod string, appSrc string, app *enterpriseApi.AppDeploymentInfo, localFile string, scope string, timeout int) (bool, error) {
+	file, err := os.Open(localFile)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	stagedFile := filepath.Join(appBktMnt, appSrc, app.AppName)
+	_, err = i.podExec(i.client, pod, i.cr.GetNamespace(), fmt.Sprintf(appStageScript, splcommon.ShellQuote(filepath.Dir(stagedFile)), splcommon.ShellQuote(stagedFile)), file)
+	if err != nil {
+		return false, err
+	}
+
+	restart := false
+	if scope != enterpriseApi.ScopeCluster {
+		stdout, err := i.podExec(i.client, pod, i.cr.GetNamespace(), splunkRESTFunc+fmt.Sprintf(appInstallScript, splcommon.ShellQuote(stagedFile), timeout), nil)
+		if err != nil {
+			return false, err
+		}
+		restart = strings.Contains(stdout, appRestartRequiredMarker)
+	}
+	if scope == enterpriseApi.ScopeCluster || scope == enterpriseApi.ScopeClusterWithPreConfig {
+		preConfig := scope == enterpriseApi.ScopeClusterWithPreConfig
+		_, err = i.podExec(i.client, pod, i.cr.GetNamespace(), fmt.Sprintf(appBundleScript, splcommon.ShellQuote(stagedFile), splcommon.ShellQuote(i.bundleDir), splcommon.ShellQuote(app.AppDirectory), preConfig), nil)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return restart, nil
+}
+
+// removeApp removes an app deleted from the remote storage from the pods still carrying it, unless the delete policy
+// of its app source retains it. The pods are removed from the status of the app once the app is removed from them.
+// It returns true if the app was removed from the bundle of the pod for the cluster scopes, which must be pushed.
+func (i *appInstaller) removeApp(appSrc string, app *enterpriseApi.AppDeploymentInfo, scope string, deletePolicy string) (bool, error) {
+	if deletePolicy == enterpriseApi.DeletePolicyRetain {
+		// the status still lists the pods carrying the app
+		app.DeployStatus = enterpriseApi.DeployStatusComplete
+		return false, nil
+	}
+
+	i.pruneRemovedPods(app, scope)
+	if len(app.PodDeployStatus) == 0 {
+		app.DeployStatus = enterpriseApi.DeployStatusComplete
+		return false, nil
+	}
+	app.DeployStatus = enterpriseApi.DeployStatusInProgress
+
+	var pods []string
+	for pod := range app.PodDeployStatus {
+		pods = append(pods, pod)
+	}
+	sort.Strings(pods)
+
+	var firstErr error
+	removedFromBundle := false
+	for _, pod := range pods {
+		if err := i.removeFromPod(pod, appSrc, app, scope); err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("unable to remove app %s of app source %s from pod %s: %v", app.AppName, appSrc, pod, err)
+			}
+			app.PodDeployStatus[pod] = enterpriseApi.DeployStatusError
+			continue
+		}
+		if !i.usesBundle(scope) {
+			delete(app.PodDeployStatus, pod)
+		} else {
+			removedFromBundle = true
+		}
+	}
+
+	return removedFromBundle, firstErr
+}
+
+// removeFromPod removes an installed app from a pod, or from the bundle of the pod for the cluster scopes
+func (i *appInstaller) removeFromPod(pod string, appSrc string, app *enterpriseApi.AppDeploymentInfo, scope string) error {
+	appDir := getAppDirectory(app)
+	if appDir == "" {
+		return fmt.Errorf("unknown directory of app %s", app.AppName)
+	}
+
+	stagedFile := filepath.Join(appBktMnt, appSrc, app.AppName)
+	if scope != enterpriseApi.ScopeCluster {
+		if _, err := i.podExec(i.client, pod, i.cr.GetNamespace(), splunkRESTFunc+fmt.Sprintf(appRemoveScript, splcommon.ShellQuote(appDir), splcommon.ShellQuote(stagedFile)), nil); err != nil {
+			return err
+		}
+	}
+	if i.usesBundle(scope) {
+		if _, err := i.podExec(i.client, pod, i.cr.GetNamespace(), fmt.Sprintf(appBundleRemoveScript, splcommon.ShellQuote(filepath.Join(i.bundleDir, appDir)), splcommon.ShellQuote(stagedFile)), nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getPremiumAppSetupDir returns the directory of the setup of a premium app in the staging volume of its pods
+func getPremiumAppSetupDir(appSrc string, app *enterpriseApi.AppDeploymentInfo) string {
+	return filepath.Join(appBktMnt, appSrc, app.AppName+".setup")
+}
+
+// startPremiumAppSetup starts the setup of a premium app installed on a pod, in the background of the pod
+func (i *appInstaller) startPremiumAppSetup(pod string, appSrc string, app *enterpriseApi.AppDeploymentInfo, timeout int) error {
+	premiumAppsProps := getAppSrcPremiumAppsProps(i.appFrameworkConfig, appSrc)
+	if premiumAppsProps.Type != enterpriseApi.PremiumAppsTypeEs {
+		return fmt.Errorf("unsupported premium apps type %s", premiumAppsProps.Type)
+	}
+
+	sslEnablement := premiumAppsProps.EsDefaults.SslEnablement
+	if sslEnablement == "" {
+		sslEnablement = enterpriseApi.SslEnablementStrict
+	}
+	deploymentArgs := ""
+	if i.bundlePod != "" {
+		deploymentArgs = " --deployment_type shc_deployer"
+	}
+
+	setupCmd := fmt.Sprintf(esSetupCmd, timeout, sslEnablement, deploymentArgs)
+	_, err := i.podExec(i.client, pod, i.cr.GetNamespace(), fmt.Sprintf(premiumAppSetupScript, splcommon.ShellQuote(getPremiumAppSetupDir(appSrc, app)), splunkRESTFunc+setupCmd), nil)
+	return err
+}
+
+// getPremiumAppSetupStatus returns the status of the setup of a premium app on a pod: InProgress while it runs, then
+// Complete, or Error along with the reason of the failure
+func (i *appInstaller) getPremiumAppSetupStatus(pod string, appSrc string, app *enterpriseApi.AppDeploymentInfo, timeout int) (enterpriseApi.AppDeploymentStatus, error) {
+	stdout, err := i.podExec(i.client, pod, i.cr.GetNamespace(), fmt.Sprintf(premiumAppSetupStatusScript, splcommon.ShellQuote(getPremiumAppSetupDir(appSrc, app))), nil)
+	if err != nil {
+		return enterpriseApi.DeployStatusInProgress, err
+	}
+
+	lines := strings.SplitN(strings.TrimSpace(stdout), "\n", 2)
+	switch exitCode := strings.TrimPrefix(lines[0], "exit "); exitCode {
+	case "":
+		return enterpriseApi.DeployStatusInProgress, nil
+	case "0":
+		return enterpriseApi.DeployStatusComplete, nil
+	case "28":
+		return enterpriseApi.DeployStatusError, fmt.Errorf("setup timed out after %d seconds", timeout)
+	case "lost":
+		return enterpriseApi.DeployStatusError, fmt.Errorf("setup was interrupted")
+	default:
+		output := ""
+		if len(lines) > 1 {
+			output = lines[1]
+		}
+		return enterpriseApi.DeployStatusError, fmt.Errorf("setup failed with exit code %s: %s", exitCode, output)
+	}
+}
+
+// setAppRemoveStatus sets the status of an app deleted from the remote storage: Complete once it is removed from
+// all its pods, and Error if it failed on any of them
+func setAppRemoveStatus(app *enterpriseApi.AppDeploymentInfo) {
+	status := enterpriseApi.DeployStatusComplete
+	for _, podStatus := range app.PodDeployStatus {
+		if podStatus == enterpriseApi.DeployStatusError {
+			app.DeployStatus = enterpriseApi.DeployStatusError
+			return
+		}
+		status = enterpriseApi.DeployStatusInProgress
+	}
+	app.DeployStatus = status
+}
+
+// getAppPackageDirectory returns the directory of an app once installed, which is the top directory of its package
+func getAppPackageDirectory(localFile string) (string, error) {
+	file, err := os.Open(localFile)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	gzipReader, err := gzip.NewReader(file)
+	if err != nil {
+		return "", err
+	}
+	defer gzipReader.Close()
+
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return "", fmt.Errorf("no app directory in package %s", filepath.Base(localFile))
+		} else if err != nil {
+			return "", err
+		}
+		dir := strings.SplitN(strings.TrimPrefix(header.Name, "./"), "/", 2)[0]
+		if dir == "" || dir == "." {
+			continue
+		}
+		if !isAppNameValid(dir) {
+			return "", fmt.Errorf("invalid app directory %s in package %s", dir, filepath.Base(localFile))
+		}
+		return dir, nil
+	}
+}
+
+// getAppDirectory returns the directory of an installed app. Apps installed before their directory was tracked
+// are assumed to be packaged in a directory named after their package.
+func getAppDirectory(app *enterpriseApi.AppDeploymentInfo) string {
+	if app.AppDirectory != "" {
+		return app.AppDirectory
+	}
+	appDir := app.AppName
+	for _, ext := range []string{".tgz", ".tar.gz", ".spl"} {
+		appDir = strings.TrimSuffix(appDir, ext)
+	}
+	return appDir
+}
+
+// getAppSrcClient returns the remote storage client of an app source
+func (i *appInstaller) getAppSrcClient(appSrc string) (splclient.SplunkS3Client, error) {
+	for _, appSource := range i.appFrameworkConfig.AppSources {
+		if appSource.Name != appSrc {
+			continue
+		}
+		vol, err := splclient.GetAppSrcVolume(appSource, i.appFrameworkConfig)
+		if err != nil {
+			return splclient.SplunkS3Client{}, err
+		}
+		s3ClientWrapper := splclient.S3Clients[vol.Provider]
+		initFunc := s3ClientWrapper.GetS3ClientInitFuncPtr()
+		return i.getS3Client(i.client, i.cr, i.appFrameworkConfig, &vol, appSource.Location, initFunc)
+	}
+	return splclient.SplunkS3Client{}, fmt.Errorf("app source %s is missing in the config", appSrc)
+}
+
+// downloadApp downloads the package of an app to a local file. The apps pinned to a version are downloaded by
+// version when the remote storage supports it, since the pinned version may not be the latest one.
+func (i *appInstaller) downloadApp(s3Client *splclient.SplunkS3Client, appSrc string, app *enterpriseApi.AppDeploymentInfo, localFile string) error {
+	remoteKey := i.getRemoteAppKey(appSrc, app)
+	if versionedClient, ok := s3Client.Client.(splclient.S3VersionedClient); ok && getPinnedAppObjectHash(i.appFrameworkConfig, appSrc, app.AppName) != "" {
+		return versionedClient.DownloadAppVersion(remoteKey, localFile, app.ObjectHash)
+	}
+	return s3Client.Client.DownloadApp(remoteKey, localFile, app.ObjectHash)
+}
+
+// verifySignature verifies the detached signature of a downloaded app package, when its app source has a public key
+func (i *appInstaller) verifySignature(s3Client *splclient.SplunkS3Client, appSrc string, app *enterpriseApi.AppDeploymentInfo, localFile string) error {
+	secretName := getAppSrcSignatureSecretRef(i.appFrameworkConfig, appSrc)
+	if secretName == "" {
+		return nil
+	}
+	publicKey, err := getAppSignaturePublicKey(i.client, i.cr, secretName)
+	if err != nil {
+		return err
+	}
+
+	// the signature is the latest version of its file, whatever the version of the package
+	signatureFile := localFile + appSignatureExt
+	defer os.Remove(signatureFile)
+	err = s3Client.Client.DownloadApp(i.getRemoteAppKey(appSrc, app)+appSignatureExt, signatureFile, "")
+	if err != nil {
+		return fmt.Errorf("unable to download the signature: %v", err)
+	}
+
+	return verifyAppSignature(publicKey, localFile, signatureFile)
+}
+
+// addDeployedObjectHash adds the object hash of an app deployed on all its pods at the top of its deploy history
+func addDeployedObjectHash(app *enterpriseApi.AppDeploymentInfo) {
+	if len(app.DeployedObjectHashes) > 0 && app.DeployedObjectHashes[0] == app.ObjectHash {
+		return
+	}
+
+	hashes := []string{app.ObjectHash}
+	for _, hash := range app.DeployedObjectHashes {
+		if hash != app.ObjectHash && len(hashes) < maxDeployedObjectHashes {
+			hashes = append(hashes, hash)
+		}
+	}
+	app.DeployedObjectHashes = hashes
+}
+
+// getRemoteAppKey returns the key of an app package on the remote storage of its app source
+func (i *appInstaller) getRemoteAppKey(appSrc string, app *enterpriseApi.AppDeploymentInfo) string {
+	for _, appSource := range i.appFrameworkConfig.AppSources {
+		if appSource.Name == appSrc {
+			vol, err := splclient.GetAppSrcVolume(appSource, i.appFrameworkConfig)
+			if err != nil {
+				break
+			}
+			_, prefix := getRemoteStorageBucketAndPrefix(&vol, appSource.Location)
+			return prefix + app.AppName
+		}
+	}
+	return app.AppName
+}
+
+// getLocalAppFile returns the file where the operator downloads a revision of an app package
+func (i *appInstaller) getLocalAppFile(appSrc string, app *enterpriseApi.AppDeploymentInfo) string {
+	return filepath.Join(appDownloadDir, i.cr.GetNamespace(), i.cr.GetName(), appSrc, url.PathEscape(app.ObjectHash), app.AppName)
+}
+
+// applyAppInstalls installs the apps of a custom resource in its pods, and reports failures as events. It returns true
+// when the reconcile must be requeued, because apps are still being installed, or to pick up the latest app framework
+// config changes once they are all installed.
+func applyAppInstalls(client splcommon.ControllerClient, cr splcommon.MetaObject, appFrameworkConfig *enterpriseApi.AppFrameworkSpec, appDeployContext *enterpriseApi.AppDeploymentContext, eventPublisher *eventPublisher) (bool, error) {
+	done, err := newAppInstaller(client, cr, appFrameworkConfig, appDeployContext).install()
+	if err != nil {
+		eventPublisher.Warning(eventReasonAppInstallFailed, "Failed to install or remove apps: %v", err)
+	}
+	if done && appDeployContext.IsDeploymentInProgress {
+		appDeployContext.IsDeploymentInProgress = false
+		return true, err
+	}
+	return !done, err
+}
@@ -0,0 +1,837 @@
+// Copyright (c) 2018-2021 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enterprise
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	enterpriseApi "github.com/splunk/splunk-operator/pkg/apis/enterprise/v2"
+	splclient "github.com/splunk/splunk-operator/pkg/splunk/client"
+	splcommon "github.com/splunk/splunk-operator/pkg/splunk/common"
+	spltest "github.com/splunk/splunk-operator/pkg/splunk/test"
+)
+
+// fakeAppsClient is a remote storage client downloading app packages whose only file holds their key. The
+// directory of the apps is their name prefixed with "TA-". The files of signatures are downloaded as they are.
+type fakeAppsClient struct {
+	downloads  []string
+	signatures map[string][]byte
+}
+
+// readTestAppPackage returns the content of the only file of an app package written by fakeAppsClient
+func readTestAppPackage(r io.Reader) string {
+	gzipReader, err := gzip.NewReader(r)
+	if err != nil {
+		return err.Error()
+	}
+	tarReader := tar.NewReader(gzipReader)
+	if _, err = tarReader.Next(); err != nil {
+		return err.Error()
+	}
+	content, _ := ioutil.ReadAll(tarReader)
+	return string(content)
+}
+
+func (c *fakeAppsClient) GetAppsList() (splclient.S3Response, error) {
+	return splclient.S3Response{}, nil
+}
+
+// DownloadAppVersion records the etag of the version downloaded after the key
+func (c *fakeAppsClient) DownloadAppVersion(remoteFile string, localFile string, etag string) error {
+	err := c.DownloadApp(remoteFile, localFile, etag)
+	c.downloads[len(c.downloads)-1] += "@" + etag
+	return err
+}
+
+func (c *fakeAppsClient) DownloadApp(remoteFile string, localFile string, etag string) error {
+	c.downloads = append(c.downloads, remoteFile)
+	if signature, ok := c.signatures[remoteFile]; ok {
+		return ioutil.WriteFile(localFile, signature, 0644)
+	}
+	file, err := os.Create(localFile)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	gzipWriter := gzip.NewWriter(file)
+	tarWriter := tar.NewWriter(gzipWriter)
+	appDir := "TA-" + strings.TrimSuffix(filepath.Base(remoteFile), filepath.Ext(remoteFile))
+	err = tarWriter.WriteHeader(&tar.Header{Name: appDir + "/README", Mode: 0644, Size: int64(len(remoteFile))})
+	if err == nil {
+		_, err = tarWriter.Write([]byte(remoteFile))
+	}
+	if err == nil {
+		err = tarWriter.Close()
+	}
+	if err == nil {
+		err = gzipWriter.Close()
+	}
+	return err
+}
+
+// newTestAppInstaller returns an appInstaller recording the scripts run in the pods, and failing them for the
+// pods of failingPods
+func newTestAppInstaller(t *testing.T, cr splcommon.MetaObject, appFrameworkConfig *enterpriseApi.AppFrameworkSpec, appDeployContext *enterpriseApi.AppDeploymentContext, s3Client *fakeAppsClient, scripts *[]string, failingPods map[string]bool) *appInstaller {
+	c := spltest.NewMockClient()
+	for _, pod := range newAppInstaller(c, cr, appFrameworkConfig, appDeployContext).localPods {
+		c.AddObject(newTestReadyPod(pod, cr.GetNamespace(), true))
+	}
+	installer := newAppInstaller(c, cr, appFrameworkConfig, appDeployContext)
+	installer.getS3Client = func(client splcommon.ControllerClient, cr splcommon.MetaObject, appFrameworkRef *enterpriseApi.AppFrameworkSpec, vol *enterpriseApi.VolumeSpec, location string, fp splclient.GetInitFunc) (splclient.SplunkS3Client, error) {
+		return splclient.SplunkS3Client{Client: s3Client}, nil
+	}
+	installer.podExec = func(c splcommon.ControllerClient, podName string, namespace string, script string, stdin io.Reader) (string, error) {
+		if failingPods[podName] {
+			return "", fmt.Errorf("exec failed")
+		}
+		if stdin != nil {
+			script += " < " + readTestAppPackage(stdin)
+		}
+		*scripts = append(*scripts, podName+": "+script)
+		if strings.Contains(script, "restartApp.tgz") && strings.Contains(script, "/apps/local --data-urlencode") {
+			return appRestartRequiredMarker + "\n", nil
+		}
+		return "", nil
+	}
+	return installer
+}
+
+// newTestReadyPod returns a running pod, whose Splunk container is ready or not
+func newTestReadyPod(name string, namespace string, ready bool) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Status: corev1.PodStatus{
+			Phase:             corev1.PodRunning,
+			ContainerStatuses: []corev1.ContainerStatus{{Ready: ready}},
+		},
+	}
+}
+
+// newTestAppDeployContext returns the deploy context of an app source with pending apps
+func newTestAppDeployContext(appSrc string, appNames ...string) *enterpriseApi.AppDeploymentContext {
+	appDeployContext := &enterpriseApi.AppDeploymentContext{AppsSrcDeployStatus: map[string]enterpriseApi.AppSrcDeployInfo{}}
+	var appList []enterpriseApi.AppDeploymentInfo
+	for _, appName := range appNames {
+		appList = append(appList, enterpriseApi.AppDeploymentInfo{AppName: appName, ObjectHash: "abcd", RepoState: enterpriseApi.RepoStateActive, DeployStatus: enterpriseApi.DeployStatusPending})
+	}
+	appDeployContext.AppsSrcDeployStatus[appSrc] = enterpriseApi.AppSrcDeployInfo{AppDeploymentInfoList: appList}
+	return appDeployContext
+}
+
+func TestAppInstallerLocalScope(t *testing.T) {
+	dir, err := ioutil.TempDir("", "appinstall")
+	if err != nil {
+		t.Fatalf("Unable to create a temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(downloadDir string) { appDownloadDir = downloadDir }(appDownloadDir)
+	appDownloadDir = dir
+
+	cr := enterpriseApi.Standalone{ObjectMeta: metav1.ObjectMeta{Name: "stack1", Namespace: "test"}}
+	cr.Spec.Replicas = 2
+	cr.Spec.AppFrameworkConfig = enterpriseApi.AppFrameworkSpec{
+		VolList: []enterpriseApi.VolumeSpec{{Name: "vol1", Endpoint: "https://s3.us-west-2.amazonaws.com", Path: "bucket1/apps", Type: "s3", Provider: "aws"}},
+		AppSources: []enterpriseApi.AppSourceSpec{
+			{Name: "adminApps", Location: "adminAppsRepo", AppSourceDefaultSpec: enterpriseApi.AppSourceDefaultSpec{VolName: "vol1", Scope: enterpriseApi.ScopeLocal}},
+		},
+	}
+	appDeployContext := newTestAppDeployContext("adminApps", "app1.tgz", "restartApp.tgz")
+	appDeployContext.IsDeploymentInProgress = true
+	s3Client := &fakeAppsClient{}
+	var scripts []string
+	failingPods := map[string]bool{"splunk-stack1-standalone-1": true}
+	installer := newTestAppInstaller(t, &cr, &cr.Spec.AppFrameworkConfig, appDeployContext, s3Client, &scripts, failingPods)
+
+	// the apps are installed on the first pod, and fail on the second one
+	done, err := installer.install()
+	if err == nil || !done {
+		t.Errorf("install() = %t, %v; want true and an error", done, err)
+	}
+	if len(s3Client.downloads) != 2 || s3Client.downloads[0] != "apps/adminAppsRepo/app1.tgz" {
+		t.Errorf("install() downloaded %v; want each app once", s3Client.downloads)
+	}
+	want := []string{
+		"splunk-stack1-standalone-0: mkdir -p '/init-apps/adminApps' && cat > '/init-apps/adminApps/app1.tgz' < apps/adminAppsRepo/app1.tgz",
+		"splunk-stack1-standalone-0: " + splunkRESTFunc + fmt.Sprintf(appInstallScript, splcommon.ShellQuote("/init-apps/adminApps/app1.tgz"), defaultAppInstallTimeout),
+		"splunk-stack1-standalone-0: mkdir -p '/init-apps/adminApps' && cat > '/init-apps/adminApps/restartApp.tgz' < apps/adminAppsRepo/restartApp.tgz",
+		"splunk-stack1-standalone-0: " + splunkRESTFunc + fmt.Sprintf(appInstallScript, splcommon.ShellQuote("/init-apps/adminApps/restartApp.tgz"), defaultAppInstallTimeout),
+		"splunk-stack1-standalone-0: " + splunkRestartCmd,
+	}
+	if strings.Join(scripts, "\n") != strings.Join(want, "\n") {
+		t.Errorf("install() ran %v; want %v", scripts, want)
+	}
+	appList := appDeployContext.AppsSrcDeployStatus["adminApps"].AppDeploymentInfoList
+	for _, app := range appList {
+		if app.DeployStatus != enterpriseApi.DeployStatusError || app.PodDeployStatus["splunk-stack1-standalone-0"] != enterpriseApi.DeployStatusComplete ||
+			app.PodDeployStatus["splunk-stack1-standalone-1"] != enterpriseApi.DeployStatusError {
+			t.Errorf("install() app %s status = %v %v; want Error, complete on the first pod only", app.AppName, app.DeployStatus, app.PodDeployStatus)
+		}
+	}
+	if appList[0].AppDirectory != "TA-app1" {
+		t.Errorf("install() app directory = %s; want the top directory of the package", appList[0].AppDirectory)
+	}
+	if appList[0].RestartRequired || !appList[1].RestartRequired {
+		t.Errorf("install() should only require a restart for restartApp.tgz")
+	}
+
+	// the apps are only installed on the second pod once it is back, without downloading them again
+	scripts = nil
+	delete(failingPods, "splunk-stack1-standalone-1")
+	done, err = installer.install()
+	if err != nil || !done {
+		t.Errorf("install() = %t, %v; want true", done, err)
+	}
+	if len(s3Client.downloads) != 2 || len(scripts) != 5 || !strings.HasPrefix(scripts[0], "splunk-stack1-standalone-1: ") {
+		t.Errorf("install() ran %v; want the apps installed on the second pod only", scripts)
+	}
+	for _, app := range appDeployContext.AppsSrcDeployStatus["adminApps"].AppDeploymentInfoList {
+		if app.DeployStatus != enterpriseApi.DeployStatusComplete {
+			t.Errorf("install() app %s status = %v; want Complete", app.AppName, app.DeployStatus)
+		}
+		if _, err := os.Stat(installer.getLocalAppFile("adminApps", &app)); !os.IsNotExist(err) {
+			t.Errorf("install() should have removed the package of app %s", app.AppName)
+		}
+	}
+
+	// nothing is left to install, and the status of the pods removed by a scale down is dropped
+	scripts = nil
+	installer.localPods = installer.localPods[:1]
+	done, err = installer.install()
+	if err != nil || !done || len(scripts) != 0 {
+		t.Errorf("install() = %t, %v, ran %v; want true without running anything", done, err, scripts)
+	}
+	if len(appDeployContext.AppsSrcDeployStatus["adminApps"].AppDeploymentInfoList[0].PodDeployStatus) != 1 {
+		t.Errorf("install() should have dropped the status of the removed pod")
+	}
+}
+
+func TestAppInstallerRestarts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "appinstall")
+	if err != nil {
+		t.Fatalf("Unable to create a temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(downloadDir string) { appDownloadDir = downloadDir }(appDownloadDir)
+	appDownloadDir = dir
+
+	cr := enterpriseApi.Standalone{ObjectMeta: metav1.ObjectMeta{Name: "stack1", Namespace: "test"}}
+	cr.Spec.Replicas = 2
+	cr.Spec.AppFrameworkConfig = enterpriseApi.AppFrameworkSpec{
+		VolList: []enterpriseApi.VolumeSpec{{Name: "vol1", Endpoint: "https://s3.us-west-2.amazonaws.com", Path: "bucket1", Type: "s3", Provider: "aws"}},
+		AppSources: []enterpriseApi.AppSourceSpec{
+			{Name: "adminApps", Location: "adminAppsRepo", AppSourceDefaultSpec: enterpriseApi.AppSourceDefaultSpec{VolName: "vol1", Scope: enterpriseApi.ScopeLocal}},
+		},
+	}
+	appDeployContext := newTestAppDeployContext("adminApps", "restartApp.tgz")
+	var scripts []string
+	installer := newTestAppInstaller(t, &cr, &cr.Spec.AppFrameworkConfig, appDeployContext, &fakeAppsClient{}, &scripts, nil)
+	c := installer.client.(*spltest.MockClient)
+	restarts := func() []string {
+		var restarted []string
+		for _, script := range scripts {
+			if strings.HasSuffix(script, ": "+splunkRestartCmd) {
+				restarted = append(restarted, strings.TrimSuffix(script, ": "+splunkRestartCmd))
+			}
+		}
+		return restarted
+	}
+
+	// the app is installed on both pods, and Splunk only restarted on the first one
+	done, err := installer.install()
+	if err != nil || done {
+		t.Errorf("install() = %t, %v; want false while a restart is pending", done, err)
+	}
+	if restarted := restarts(); len(restarted) != 1 || restarted[0] != "splunk-stack1-standalone-0" {
+		t.Errorf("install() restarted %v; want the first pod only", restarted)
+	}
+	if len(appDeployContext.PendingRestartPods) != 1 || appDeployContext.PendingRestartPods[0] != "splunk-stack1-standalone-1" {
+		t.Errorf("install() pending restarts = %v; want the second pod", appDeployContext.PendingRestartPods)
+	}
+
+	// the second pod waits for the first one to be ready again
+	scripts = nil
+	c.AddObject(newTestReadyPod("splunk-stack1-standalone-0", "test", false))
+	done, err = installer.install()
+	if err != nil || done || len(restarts()) != 0 {
+		t.Errorf("install() = %t, %v, restarted %v; want false without restarting", done, err, restarts())
+	}
+
+	// then it is restarted
+	c.AddObject(newTestReadyPod("splunk-stack1-standalone-0", "test", true))
+	done, err = installer.install()
+	if err != nil || !done || len(appDeployContext.PendingRestartPods) != 0 {
+		t.Errorf("install() = %t, %v, pending %v; want true without pending restarts", done, err, appDeployContext.PendingRestartPods)
+	}
+	if restarted := restarts(); len(restarted) != 1 || restarted[0] != "splunk-stack1-standalone-1" {
+		t.Errorf("install() restarted %v; want the second pod", restarted)
+	}
+
+	// the pods removed by a scale down are not restarted
+	appDeployContext.PendingRestartPods = []string{"splunk-stack1-standalone-1"}
+	installer.localPods = installer.localPods[:1]
+	scripts = nil
+	done, err = installer.install()
+	if err != nil || !done || len(restarts()) != 0 {
+		t.Errorf("install() = %t, %v, restarted %v; want true without restarting", done, err, restarts())
+	}
+}
+
+func TestAppInstallerClusterScope(t *testing.T) {
+	dir, err := ioutil.TempDir("", "appinstall")
+	if err != nil {
+		t.Fatalf("Unable to create a temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(downloadDir string) { appDownloadDir = downloadDir }(appDownloadDir)
+	appDownloadDir = dir
+
+	cr := enterpriseApi.SearchHeadCluster{ObjectMeta: metav1.ObjectMeta{Name: "stack1", Namespace: "test"}}
+	cr.Spec.AppFrameworkConfig = enterpriseApi.AppFrameworkSpec{
+		VolList: []enterpriseApi.VolumeSpec{{Name: "vol1", Endpoint: "https://s3.us-west-2.amazonaws.com", Path: "bucket1", Type: "s3", Provider: "aws"}},
+		AppSources: []enterpriseApi.AppSourceSpec{
+			{Name: "securityApps", Location: "securityAppsRepo", AppSourceDefaultSpec: enterpriseApi.AppSourceDefaultSpec{VolName: "vol1", Scope: enterpriseApi.ScopeCluster}},
+			{Name: "esApps", Location: "esAppsRepo", AppSourceDefaultSpec: enterpriseApi.AppSourceDefaultSpec{VolName: "vol1", Scope: enterpriseApi.ScopeClusterWithPreConfig}},
+		},
+	}
+	appDeployContext := newTestAppDeployContext("securityApps", "app1.tgz", "restartApp.tgz")
+	appDeployContext.AppsSrcDeployStatus["esApps"] = newTestAppDeployContext("esApps", "es.spl").AppsSrcDeployStatus["esApps"]
+	var scripts []string
+	installer := newTestAppInstaller(t, &cr, &cr.Spec.AppFrameworkConfig, appDeployContext, &fakeAppsClient{}, &scripts, nil)
+
+	// the apps are added to the bundle of the deployer, which is pushed once
+	done, err := installer.install()
+	if err != nil || !done {
+		t.Errorf("install() = %t, %v; want true", done, err)
+	}
+	bundleDir := "/opt/splunk/etc/shcluster/apps"
+	want := []string{
+		"splunk-stack1-deployer-0: mkdir -p '/init-apps/esApps' && cat > '/init-apps/esApps/es.spl' < esAppsRepo/es.spl",
+		"splunk-stack1-deployer-0: " + splunkRESTFunc + fmt.Sprintf(appInstallScript, splcommon.ShellQuote("/init-apps/esApps/es.spl"), defaultAppInstallTimeout),
+		"splunk-stack1-deployer-0: " + fmt.Sprintf(appBundleScript, splcommon.ShellQuote("/init-apps/esApps/es.spl"), splcommon.ShellQuote(bundleDir), splcommon.ShellQuote("TA-es"), true),
+		"splunk-stack1-deployer-0: mkdir -p '/init-apps/securityApps' && cat > '/init-apps/securityApps/app1.tgz' < securityAppsRepo/app1.tgz",
+		"splunk-stack1-deployer-0: " + fmt.Sprintf(appBundleScript, splcommon.ShellQuote("/init-apps/securityApps/app1.tgz"), splcommon.ShellQuote(bundleDir), splcommon.ShellQuote("TA-app1"), false),
+		"splunk-stack1-deployer-0: mkdir -p '/init-apps/securityApps' && cat > '/init-apps/securityApps/restartApp.tgz' < securityAppsRepo/restartApp.tgz",
+		"splunk-stack1-deployer-0: " + fmt.Sprintf(appBundleScript, splcommon.ShellQuote("/init-apps/securityApps/restartApp.tgz"), splcommon.ShellQuote(bundleDir), splcommon.ShellQuote("TA-restartApp"), false),
+		"splunk-stack1-deployer-0: " + splunkRESTFunc + fmt.Sprintf(shcBundlePushCmd, "splunk-stack1-search-head-0.splunk-stack1-search-head-headless.test.svc.cluster.local"),
+	}
+	if strings.Join(scripts, "\n") != strings.Join(want, "\n") {
+		t.Errorf("install() ran %v; want %v", scripts, want)
+	}
+	for appSrc, appSrcDeployInfo := range appDeployContext.AppsSrcDeployStatus {
+		for _, app := range appSrcDeployInfo.AppDeploymentInfoList {
+			if app.DeployStatus != enterpriseApi.DeployStatusComplete || app.RestartRequired {
+				t.Errorf("install() app %s of %s status = %v; want Complete without restart", app.AppName, appSrc, app.DeployStatus)
+			}
+		}
+	}
+
+	// a failed bundle push is reported on all the apps of cluster scope
+	appDeployContext = newTestAppDeployContext("securityApps", "app2.tgz")
+	installer = newTestAppInstaller(t, &cr, &cr.Spec.AppFrameworkConfig, appDeployContext, &fakeAppsClient{}, &scripts, nil)
+	installer.pushBundle = func() error {
+		return fmt.Errorf("push failed")
+	}
+	done, err = installer.install()
+	if err == nil || !done {
+		t.Errorf("install() = %t, %v; want true and an error", done, err)
+	}
+	if status := appDeployContext.AppsSrcDeployStatus["securityApps"].AppDeploymentInfoList[0].DeployStatus; status != enterpriseApi.DeployStatusError {
+		t.Errorf("install() app2.tgz status = %v; want Error", status)
+	}
+}
+
+func TestGetAppPackageDirectory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "appinstall")
+	if err != nil {
+		t.Fatalf("Unable to create a temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	test := func(remoteFile string, want string, wantErr bool) {
+		localFile := filepath.Join(dir, "app.tgz")
+		if err := (&fakeAppsClient{}).DownloadApp(remoteFile, localFile, ""); err != nil {
+			t.Fatalf("Unable to write package %s: %v", remoteFile, err)
+		}
+		appDir, err := getAppPackageDirectory(localFile)
+		if appDir != want || (err != nil) != wantErr {
+			t.Errorf("getAppPackageDirectory(%s) = %s, %v; want %s", remoteFile, appDir, err, want)
+		}
+	}
+
+	test("apps/app1.tgz", "TA-app1", false)
+	test("apps/app'1.tgz", "", true)
+	test("apps/$(id).tgz", "", true)
+
+	// the top directory can not leave the apps directory
+	localFile := filepath.Join(dir, "parent.tgz")
+	file, err := os.Create(localFile)
+	if err != nil {
+		t.Fatalf("Unable to create %s: %v", localFile, err)
+	}
+	gzipWriter := gzip.NewWriter(file)
+	tarWriter := tar.NewWriter(gzipWriter)
+	tarWriter.WriteHeader(&tar.Header{Name: "./../etc/passwd", Mode: 0644})
+	tarWriter.Close()
+	gzipWriter.Close()
+	file.Close()
+	if appDir, err := getAppPackageDirectory(localFile); err == nil {
+		t.Errorf("getAppPackageDirectory() = %s; want an error for a parent directory", appDir)
+	}
+}
+
+func TestAppInstallerRemoveApps(t *testing.T) {
+	cr := enterpriseApi.Standalone{ObjectMeta: metav1.ObjectMeta{Name: "stack1", Namespace: "test"}}
+	cr.Spec.Replicas = 2
+	cr.Spec.AppFrameworkConfig = enterpriseApi.AppFrameworkSpec{
+		VolList: []enterpriseApi.VolumeSpec{{Name: "vol1", Endpoint: "https://s3.us-west-2.amazonaws.com", Path: "bucket1", Type: "s3", Provider: "aws"}},
+		AppSources: []enterpriseApi.AppSourceSpec{
+			{Name: "adminApps", Location: "adminAppsRepo", AppSourceDefaultSpec: enterpriseApi.AppSourceDefaultSpec{VolName: "vol1", Scope: enterpriseApi.ScopeLocal}},
+			{Name: "securityApps", Location: "securityAppsRepo", AppSourceDefaultSpec: enterpriseApi.AppSourceDefaultSpec{VolName: "vol1", Scope: enterpriseApi.ScopeLocal, DeletePolicy: enterpriseApi.DeletePolicyRetain}},
+		},
+	}
+	appDeployContext := newTestAppDeployContext("adminApps", "app1.tgz", "app2.spl")
+	appDeployContext.AppsSrcDeployStatus["securityApps"] = newTestAppDeployContext("securityApps", "app3.tgz").AppsSrcDeployStatus["securityApps"]
+	for _, appSrcDeployInfo := range appDeployContext.AppsSrcDeployStatus {
+		for idx := range appSrcDeployInfo.AppDeploymentInfoList {
+			app := &appSrcDeployInfo.AppDeploymentInfoList[idx]
+			app.RepoState = enterpriseApi.RepoStateDeleted
+			app.PodDeployStatus = map[string]enterpriseApi.AppDeploymentStatus{
+				"splunk-stack1-standalone-0": enterpriseApi.DeployStatusComplete,
+				"splunk-stack1-standalone-1": enterpriseApi.DeployStatusComplete,
+			}
+		}
+	}
+	appDeployContext.AppsSrcDeployStatus["adminApps"].AppDeploymentInfoList[0].AppDirectory = "TA-app1"
+	var scripts []string
+	failingPods := map[string]bool{"splunk-stack1-standalone-1": true}
+	installer := newTestAppInstaller(t, &cr, &cr.Spec.AppFrameworkConfig, appDeployContext, &fakeAppsClient{}, &scripts, failingPods)
+
+	// the apps are removed from the first pod, and fail on the second one; the retained app is left installed
+	done, err := installer.install()
+	if err == nil || !done {
+		t.Errorf("install() = %t, %v; want true and an error", done, err)
+	}
+	want := []string{
+		"splunk-stack1-standalone-0: " + splunkRESTFunc + fmt.Sprintf(appRemoveScript, splcommon.ShellQuote("TA-app1"), splcommon.ShellQuote("/init-apps/adminApps/app1.tgz")),
+		"splunk-stack1-standalone-0: " + splunkRESTFunc + fmt.Sprintf(appRemoveScript, splcommon.ShellQuote("app2"), splcommon.ShellQuote("/init-apps/adminApps/app2.spl")),
+	}
+	if strings.Join(scripts, "\n") != strings.Join(want, "\n") {
+		t.Errorf("install() ran %v; want %v", scripts, want)
+	}
+	for _, app := range appDeployContext.AppsSrcDeployStatus["adminApps"].AppDeploymentInfoList {
+		if app.DeployStatus != enterpriseApi.DeployStatusError || len(app.PodDeployStatus) != 1 ||
+			app.PodDeployStatus["splunk-stack1-standalone-1"] != enterpriseApi.DeployStatusError {
+			t.Errorf("install() app %s status = %v %v; want Error, still on the second pod only", app.AppName, app.DeployStatus, app.PodDeployStatus)
+		}
+	}
+	retained := appDeployContext.AppsSrcDeployStatus["securityApps"]
+	if retained.AppDeploymentInfoList[0].DeployStatus != enterpriseApi.DeployStatusComplete || len(retained.AppDeploymentInfoList[0].PodDeployStatus) != 2 {
+		t.Errorf("install() should have kept the retained app on both pods")
+	}
+	if retained.Scope != enterpriseApi.ScopeLocal || retained.DeletePolicy != enterpriseApi.DeletePolicyRetain {
+		t.Errorf("install() should have recorded the scope and the delete policy of the app source")
+	}
+
+	// the apps are removed from the second pod once it is back
+	scripts = nil
+	delete(failingPods, "splunk-stack1-standalone-1")
+	done, err = installer.install()
+	if err != nil || !done || len(scripts) != 2 || !strings.HasPrefix(scripts[0], "splunk-stack1-standalone-1: ") {
+		t.Errorf("install() = %t, %v, ran %v; want the apps removed from the second pod only", done, err, scripts)
+	}
+	for _, app := range appDeployContext.AppsSrcDeployStatus["adminApps"].AppDeploymentInfoList {
+		if app.DeployStatus != enterpriseApi.DeployStatusComplete || len(app.PodDeployStatus) != 0 {
+			t.Errorf("install() app %s status = %v %v; want Complete, without any pod", app.AppName, app.DeployStatus, app.PodDeployStatus)
+		}
+	}
+
+	// the apps of an app source removed from the config are removed from the bundle, using its recorded scope
+	shc := enterpriseApi.SearchHeadCluster{ObjectMeta: metav1.ObjectMeta{Name: "stack1", Namespace: "test"}}
+	appDeployContext = newTestAppDeployContext("esApps", "es.spl")
+	appSrcDeployInfo := appDeployContext.AppsSrcDeployStatus["esApps"]
+	appSrcDeployInfo.Scope = enterpriseApi.ScopeClusterWithPreConfig
+	app := &appSrcDeployInfo.AppDeploymentInfoList[0]
+	app.RepoState = enterpriseApi.RepoStateDeleted
+	app.AppDirectory = "SplunkEnterpriseSecuritySuite"
+	app.PodDeployStatus = map[string]enterpriseApi.AppDeploymentStatus{"splunk-stack1-deployer-0": enterpriseApi.DeployStatusComplete}
+	appDeployContext.AppsSrcDeployStatus["esApps"] = appSrcDeployInfo
+	scripts = nil
+	installer = newTestAppInstaller(t, &shc, &shc.Spec.AppFrameworkConfig, appDeployContext, &fakeAppsClient{}, &scripts, nil)
+	done, err = installer.install()
+	if err != nil || !done {
+		t.Errorf("install() = %t, %v; want true", done, err)
+	}
+	bundleDir := "/opt/splunk/etc/shcluster/apps"
+	want = []string{
+		"splunk-stack1-deployer-0: " + splunkRESTFunc + fmt.Sprintf(appRemoveScript, splcommon.ShellQuote("SplunkEnterpriseSecuritySuite"), splcommon.ShellQuote("/init-apps/esApps/es.spl")),
+		"splunk-stack1-deployer-0: " + fmt.Sprintf(appBundleRemoveScript, splcommon.ShellQuote(bundleDir+"/SplunkEnterpriseSecuritySuite"), splcommon.ShellQuote("/init-apps/esApps/es.spl")),
+		"splunk-stack1-deployer-0: " + splunkRESTFunc + fmt.Sprintf(shcBundlePushCmd, "splunk-stack1-search-head-0.splunk-stack1-search-head-headless.test.svc.cluster.local"),
+	}
+	if strings.Join(scripts, "\n") != strings.Join(want, "\n") {
+		t.Errorf("install() ran %v; want %v", scripts, want)
+	}
+	if app.DeployStatus != enterpriseApi.DeployStatusComplete || len(app.PodDeployStatus) != 0 {
+		t.Errorf("install() es.spl status = %v %v; want Complete, without any pod", app.DeployStatus, app.PodDeployStatus)
+	}
+}
+
+func TestAppInstallerPinnedApps(t *testing.T) {
+	dir, err := ioutil.TempDir("", "appinstall")
+	if err != nil {
+		t.Fatalf("Unable to create a temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(downloadDir string) { appDownloadDir = downloadDir }(appDownloadDir)
+	appDownloadDir = dir
+
+	cr := enterpriseApi.LicenseMaster{ObjectMeta: metav1.ObjectMeta{Name: "stack1", Namespace: "test"}}
+	cr.Spec.AppFrameworkConfig = enterpriseApi.AppFrameworkSpec{
+		VolList: []enterpriseApi.VolumeSpec{{Name: "vol1", Endpoint: "https://s3.us-west-2.amazonaws.com", Path: "bucket1", Type: "s3", Provider: "aws"}},
+		AppSources: []enterpriseApi.AppSourceSpec{
+			{Name: "adminApps", Location: "adminAppsRepo", AppSourceDefaultSpec: enterpriseApi.AppSourceDefaultSpec{VolName: "vol1", Scope: enterpriseApi.ScopeLocal},
+				Apps: []enterpriseApi.AppVersionSpec{{Name: "app1.tgz", ObjectHash: "abcd"}, {Name: "app2.tgz"}}},
+		},
+	}
+	appDeployContext := newTestAppDeployContext("adminApps", "app1.tgz", "app2.tgz")
+	appList := appDeployContext.AppsSrcDeployStatus["adminApps"].AppDeploymentInfoList
+	appList[0].DeployedObjectHashes = []string{"h1", "abcd", "h2", "h3", "h4"}
+	s3Client := &fakeAppsClient{}
+	var scripts []string
+	installer := newTestAppInstaller(t, &cr, &cr.Spec.AppFrameworkConfig, appDeployContext, s3Client, &scripts, nil)
+
+	// the pinned app is downloaded by version, and the other app with its latest version
+	done, err := installer.install()
+	if err != nil || !done {
+		t.Errorf("install() = %t, %v; want true", done, err)
+	}
+	want := []string{"adminAppsRepo/app1.tgz@abcd", "adminAppsRepo/app2.tgz"}
+	if strings.Join(s3Client.downloads, " ") != strings.Join(want, " ") {
+		t.Errorf("install() downloaded %v; want %v", s3Client.downloads, want)
+	}
+
+	// the deployed versions are added at the top of the deploy history, which is capped
+	want = []string{"abcd", "h1", "h2", "h3", "h4"}
+	if strings.Join(appList[0].DeployedObjectHashes, " ") != strings.Join(want, " ") {
+		t.Errorf("install() app1.tgz history = %v; want %v", appList[0].DeployedObjectHashes, want)
+	}
+	if len(appList[1].DeployedObjectHashes) != 1 || appList[1].DeployedObjectHashes[0] != "abcd" {
+		t.Errorf("install() app2.tgz history = %v; want [abcd]", appList[1].DeployedObjectHashes)
+	}
+
+	// an app reinstalled with the same version keeps its history
+	appList[1].DeployStatus = enterpriseApi.DeployStatusPending
+	appList[1].PodDeployStatus = nil
+	if _, err = installer.install(); err != nil || len(appList[1].DeployedObjectHashes) != 1 {
+		t.Errorf("install() app2.tgz history = %v, %v; want [abcd]", appList[1].DeployedObjectHashes, err)
+	}
+}
+
+func TestAppInstallerDependsOn(t *testing.T) {
+	dir, err := ioutil.TempDir("", "appinstall")
+	if err != nil {
+		t.Fatalf("Unable to create a temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(downloadDir string) { appDownloadDir = downloadDir }(appDownloadDir)
+	appDownloadDir = dir
+
+	cr := enterpriseApi.Standalone{ObjectMeta: metav1.ObjectMeta{Name: "stack1", Namespace: "test"}}
+	cr.Spec.Replicas = 1
+	cr.Spec.AppFrameworkConfig = enterpriseApi.AppFrameworkSpec{
+		VolList: []enterpriseApi.VolumeSpec{{Name: "vol1", Endpoint: "https://s3.us-west-2.amazonaws.com", Path: "bucket1", Type: "s3", Provider: "aws"}},
+		AppSources: []enterpriseApi.AppSourceSpec{
+			{Name: "addonApps", Location: "addonAppsRepo", DependsOn: []string{"baseApps"}, AppSourceDefaultSpec: enterpriseApi.AppSourceDefaultSpec{VolName: "vol1", Scope: enterpriseApi.ScopeLocal, InstallTimeoutSeconds: 120}},
+			{Name: "baseApps", Location: "baseAppsRepo", AppSourceDefaultSpec: enterpriseApi.AppSourceDefaultSpec{VolName: "vol1", Scope: enterpriseApi.ScopeLocal}},
+		},
+	}
+	appDeployContext := newTestAppDeployContext("addonApps", "addon.tgz")
+	appDeployContext.AppsSrcDeployStatus["baseApps"] = newTestAppDeployContext("baseApps", "base.tgz").AppsSrcDeployStatus["baseApps"]
+	var scripts []string
+	installer := newTestAppInstaller(t, &cr, &cr.Spec.AppFrameworkConfig, appDeployContext, &fakeAppsClient{}, &scripts, nil)
+	podExec := installer.podExec
+	baseFails := true
+	installer.podExec = func(c splcommon.ControllerClient, podName string, namespace string, script string, stdin io.Reader) (string, error) {
+		if baseFails && strings.Contains(script, "base.tgz") && strings.Contains(script, "/apps/local --data-urlencode") {
+			return "", fmt.Errorf("exec failed")
+		}
+		return podExec(c, podName, namespace, script, stdin)
+	}
+
+	// the apps of an app source are not installed until the apps of its dependencies are
+	done, err := installer.install()
+	if err == nil || done {
+		t.Errorf("install() = %t, %v; want false and an error", done, err)
+	}
+	if strings.Contains(strings.Join(scripts, "\n"), "addon.tgz") {
+		t.Errorf("install() ran %v; want addon.tgz to wait for base.tgz", scripts)
+	}
+	if status := appDeployContext.AppsSrcDeployStatus["addonApps"].AppDeploymentInfoList[0].DeployStatus; status != enterpriseApi.DeployStatusPending {
+		t.Errorf("install() addon.tgz status = %v; want Pending", status)
+	}
+
+	// the dependency is installed first, then the app source depending on it, with its own install timeout
+	scripts = nil
+	baseFails = false
+	done, err = installer.install()
+	if err != nil || !done {
+		t.Errorf("install() = %t, %v; want true", done, err)
+	}
+	want := []string{
+		"splunk-stack1-standalone-0: mkdir -p '/init-apps/baseApps' && cat > '/init-apps/baseApps/base.tgz' < baseAppsRepo/base.tgz",
+		"splunk-stack1-standalone-0: " + splunkRESTFunc + fmt.Sprintf(appInstallScript, splcommon.ShellQuote("/init-apps/baseApps/base.tgz"), defaultAppInstallTimeout),
+		"splunk-stack1-standalone-0: mkdir -p '/init-apps/addonApps' && cat > '/init-apps/addonApps/addon.tgz' < addonAppsRepo/addon.tgz",
+		"splunk-stack1-standalone-0: " + splunkRESTFunc + fmt.Sprintf(appInstallScript, splcommon.ShellQuote("/init-apps/addonApps/addon.tgz"), 120),
+	}
+	if strings.Join(scripts, "\n") != strings.Join(want, "\n") {
+		t.Errorf("install() ran %v; want %v", scripts, want)
+	}
+}
+
+func TestAppInstallerPremiumApps(t *testing.T) {
+	dir, err := ioutil.TempDir("", "appinstall")
+	if err != nil {
+		t.Fatalf("Unable to create a temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(downloadDir string) { appDownloadDir = downloadDir }(appDownloadDir)
+	appDownloadDir = dir
+
+	cr := enterpriseApi.Standalone{ObjectMeta: metav1.ObjectMeta{Name: "stack1", Namespace: "test"}}
+	cr.Spec.Replicas = 1
+	cr.Spec.AppFrameworkConfig = enterpriseApi.AppFrameworkSpec{
+		VolList: []enterpriseApi.VolumeSpec{{Name: "vol1", Endpoint: "https://s3.us-west-2.amazonaws.com", Path: "bucket1", Type: "s3", Provider: "aws"}},
+		AppSources: []enterpriseApi.AppSourceSpec{
+			{Name: "esApp", Location: "esAppRepo", AppSourceDefaultSpec: enterpriseApi.AppSourceDefaultSpec{VolName: "vol1", Scope: enterpriseApi.ScopePremiumApps,
+				PremiumAppsProps: enterpriseApi.PremiumAppsProps{Type: enterpriseApi.PremiumAppsTypeEs, EsDefaults: enterpriseApi.EsDefaults{SslEnablement: enterpriseApi.SslEnablementAuto}}}},
+		},
+	}
+	appDeployContext := newTestAppDeployContext("esApp", "es.spl")
+	app := &appDeployContext.AppsSrcDeployStatus["esApp"].AppDeploymentInfoList[0]
+	var scripts []string
+	installer := newTestAppInstaller(t, &cr, &cr.Spec.AppFrameworkConfig, appDeployContext, &fakeAppsClient{}, &scripts, nil)
+	podExec := installer.podExec
+	setupStatus := ""
+	installer.podExec = func(c splcommon.ControllerClient, podName string, namespace string, script string, stdin io.Reader) (string, error) {
+		stdout, err := podExec(c, podName, namespace, script, stdin)
+		if strings.Contains(script, "kill -0") {
+			return setupStatus, err
+		}
+		return stdout, err
+	}
+
+	// the app is installed, then its setup is started in the background of the pod
+	done, err := installer.install()
+	if err != nil || done {
+		t.Errorf("install() = %t, %v; want false", done, err)
+	}
+	setupDir := "/init-apps/esApp/es.spl.setup"
+	setupCmd := fmt.Sprintf(esSetupCmd, defaultPremiumAppSetupTimeout, enterpriseApi.SslEnablementAuto, "")
+	want := []string{
+		"splunk-stack1-standalone-0: mkdir -p '/init-apps/esApp' && cat > '/init-apps/esApp/es.spl' < esAppRepo/es.spl",
+		"splunk-stack1-standalone-0: " + splunkRESTFunc + fmt.Sprintf(appInstallScript, splcommon.ShellQuote("/init-apps/esApp/es.spl"), defaultAppInstallTimeout),
+		"splunk-stack1-standalone-0: " + fmt.Sprintf(premiumAppSetupScript, splcommon.ShellQuote(setupDir), splunkRESTFunc+setupCmd),
+	}
+	if strings.Join(scripts, "\n") != strings.Join(want, "\n") {
+		t.Errorf("install() ran %v; want %v", scripts, want)
+	}
+	if app.DeployStatus != enterpriseApi.DeployStatusInProgress || app.SetupStatus["splunk-stack1-standalone-0"] != enterpriseApi.DeployStatusInProgress {
+		t.Errorf("install() app status = %v, setup %v; want InProgress", app.DeployStatus, app.SetupStatus)
+	}
+
+	// the setup is only checked while it runs
+	scripts = nil
+	if done, err = installer.install(); err != nil || done || len(scripts) != 1 {
+		t.Errorf("install() = %t, %v, ran %v; want false, only checking the setup", done, err, scripts)
+	}
+
+	// a failed setup is reported with the end of its log, and the app is installed again on the next call
+	setupStatus = "exit 2\nERROR: SSL is not enabled"
+	done, err = installer.install()
+	if err == nil || !strings.Contains(err.Error(), "SSL is not enabled") || !done {
+		t.Errorf("install() = %t, %v; want true and the setup error", done, err)
+	}
+	if app.DeployStatus != enterpriseApi.DeployStatusError || app.SetupStatus["splunk-stack1-standalone-0"] != enterpriseApi.DeployStatusError {
+		t.Errorf("install() app status = %v, setup %v; want Error", app.DeployStatus, app.SetupStatus)
+	}
+	scripts = nil
+	if _, err = installer.install(); err != nil || len(scripts) != 3 {
+		t.Errorf("install() ran %v, %v; want the app installed and set up again", scripts, err)
+	}
+
+	// Splunk is restarted once the setup is complete
+	scripts = nil
+	setupStatus = "exit 0\nInstallation complete"
+	done, err = installer.install()
+	if err != nil || !done {
+		t.Errorf("install() = %t, %v; want true", done, err)
+	}
+	if len(scripts) != 2 || scripts[1] != "splunk-stack1-standalone-0: "+splunkRestartCmd {
+		t.Errorf("install() ran %v; want Splunk restarted", scripts)
+	}
+	if app.DeployStatus != enterpriseApi.DeployStatusComplete || !app.RestartRequired {
+		t.Errorf("install() app status = %v; want Complete with a restart", app.DeployStatus)
+	}
+
+	// on a search head cluster, the app is set up on the deployer, which pushes the bundle once the setup is complete
+	shc := enterpriseApi.SearchHeadCluster{ObjectMeta: metav1.ObjectMeta{Name: "stack1", Namespace: "test"}}
+	shc.Spec.AppFrameworkConfig = cr.Spec.AppFrameworkConfig
+	shc.Spec.AppFrameworkConfig.AppSources[0].InstallTimeoutSeconds = 7200
+	appDeployContext = newTestAppDeployContext("esApp", "es.spl")
+	scripts = nil
+	installer = newTestAppInstaller(t, &shc, &shc.Spec.AppFrameworkConfig, appDeployContext, &fakeAppsClient{}, &scripts, nil)
+	setupStatus = ""
+	if done, err = installer.install(); err != nil || done || len(scripts) != 3 {
+		t.Fatalf("install() = %t, %v, ran %v; want false", done, err, scripts)
+	}
+	setupCmd = fmt.Sprintf(esSetupCmd, 7200, enterpriseApi.SslEnablementAuto, " --deployment_type shc_deployer")
+	if scripts[2] != "splunk-stack1-deployer-0: "+fmt.Sprintf(premiumAppSetupScript, splcommon.ShellQuote(setupDir), splunkRESTFunc+setupCmd) {
+		t.Errorf("install() ran %s; want the setup of the deployer", scripts[2])
+	}
+	scripts = nil
+	installer.podExec = func(c splcommon.ControllerClient, podName string, namespace string, script string, stdin io.Reader) (string, error) {
+		scripts = append(scripts, script)
+		return "exit 0\n", nil
+	}
+	if done, err = installer.install(); err != nil || !done || len(scripts) != 2 || !strings.Contains(scripts[1], "/apps/deploy") {
+		t.Errorf("install() = %t, %v, ran %v; want the bundle pushed", done, err, scripts)
+	}
+}
+
+func TestGetPremiumAppSetupStatus(t *testing.T) {
+	cr := enterpriseApi.Standalone{ObjectMeta: metav1.ObjectMeta{Name: "stack1", Namespace: "test"}}
+	app := &enterpriseApi.AppDeploymentInfo{AppName: "es.spl"}
+	installer := newAppInstaller(spltest.NewMockClient(), &cr, &cr.Spec.AppFrameworkConfig, &enterpriseApi.AppDeploymentContext{})
+
+	tests := []struct {
+		stdout string
+		status enterpriseApi.AppDeploymentStatus
+		err    string
+	}{
+		{"", enterpriseApi.DeployStatusInProgress, ""},
+		{"exit 0\nsetup done\n", enterpriseApi.DeployStatusComplete, ""},
+		{"exit 28\n", enterpriseApi.DeployStatusError, "setup timed out after 60 seconds"},
+		{"exit lost\n", enterpriseApi.DeployStatusError, "setup was interrupted"},
+		{"exit 1\nline1\nline2\n", enterpriseApi.DeployStatusError, "setup failed with exit code 1: line1\nline2"},
+	}
+	for _, test := range tests {
+		installer.podExec = func(c splcommon.ControllerClient, podName string, namespace string, script string, stdin io.Reader) (string, error) {
+			return test.stdout, nil
+		}
+		status, err := installer.getPremiumAppSetupStatus("splunk-stack1-standalone-0", "esApp", app, 60)
+		errMsg := ""
+		if err != nil {
+			errMsg = err.Error()
+		}
+		if status != test.status || errMsg != test.err {
+			t.Errorf("getPremiumAppSetupStatus(%q) = %v, %q; want %v, %q", test.stdout, status, errMsg, test.status, test.err)
+		}
+	}
+
+	// the setup directory is quoted in the script
+	var script string
+	installer.podExec = func(c splcommon.ControllerClient, podName string, namespace string, s string, stdin io.Reader) (string, error) {
+		script = s
+		return "", nil
+	}
+	installer.getPremiumAppSetupStatus("splunk-stack1-standalone-0", "es'App", app, 60)
+	if !strings.HasPrefix(script, `setup_dir='/init-apps/es'\''App/es.spl.setup'`+"\n") {
+		t.Errorf("getPremiumAppSetupStatus() ran an invalid script: %s", script)
+	}
+}
+
+func TestAppInstallerSignedApps(t *testing.T) {
+	dir, err := ioutil.TempDir("", "appinstall")
+	if err != nil {
+		t.Fatalf("Unable to create a temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(downloadDir string) { appDownloadDir = downloadDir }(appDownloadDir)
+	appDownloadDir = dir
+
+	cr := enterpriseApi.LicenseMaster{ObjectMeta: metav1.ObjectMeta{Name: "stack1", Namespace: "test"}}
+	cr.Spec.AppFrameworkConfig = enterpriseApi.AppFrameworkSpec{
+		VolList: []enterpriseApi.VolumeSpec{{Name: "vol1", Endpoint: "https://s3.us-west-2.amazonaws.com", Path: "bucket1", Type: "s3", Provider: "aws"}},
+		AppSources: []enterpriseApi.AppSourceSpec{
+			{Name: "adminApps", Location: "adminAppsRepo", AppSourceDefaultSpec: enterpriseApi.AppSourceDefaultSpec{VolName: "vol1", Scope: enterpriseApi.ScopeLocal, SignatureSecretRef: "app-signing-key"}},
+		},
+	}
+	appDeployContext := newTestAppDeployContext("adminApps", "app1.tgz", "app2.tgz")
+	signer, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Unable to generate a RSA key: %v", err)
+	}
+
+	// app1.tgz is signed, while app2.tgz has the signature of app1.tgz
+	s3Client := &fakeAppsClient{}
+	signedFile := filepath.Join(dir, "signed.tgz")
+	if err = s3Client.DownloadApp("adminAppsRepo/app1.tgz", signedFile, ""); err != nil {
+		t.Fatalf("Unable to write %s: %v", signedFile, err)
+	}
+	signature := signTestFile(t, signer, signedFile)
+	s3Client.downloads = nil
+	s3Client.signatures = map[string][]byte{"adminAppsRepo/app1.tgz.sig": signature, "adminAppsRepo/app2.tgz.sig": signature}
+	var scripts []string
+	installer := newTestAppInstaller(t, &cr, &cr.Spec.AppFrameworkConfig, appDeployContext, s3Client, &scripts, nil)
+	installer.client.(*spltest.MockClient).AddObject(newTestSignatureSecret(t, "app-signing-key", signer))
+
+	done, err := installer.install()
+	if err == nil || !done {
+		t.Errorf("install() = %t, %v; want true and an error", done, err)
+	}
+	want := []string{"adminAppsRepo/app1.tgz", "adminAppsRepo/app1.tgz.sig", "adminAppsRepo/app2.tgz", "adminAppsRepo/app2.tgz.sig"}
+	if strings.Join(s3Client.downloads, " ") != strings.Join(want, " ") {
+		t.Errorf("install() downloaded %v; want %v", s3Client.downloads, want)
+	}
+	if len(scripts) != 2 || strings.Contains(strings.Join(scripts, "\n"), "app2.tgz") {
+		t.Errorf("install() ran %v; want app1.tgz installed only", scripts)
+	}
+	appList := appDeployContext.AppsSrcDeployStatus["adminApps"].AppDeploymentInfoList
+	if appList[0].DeployStatus != enterpriseApi.DeployStatusComplete || appList[1].DeployStatus != enterpriseApi.DeployStatusError {
+		t.Errorf("install() app status = %v %v; want Complete and Error", appList[0].DeployStatus, appList[1].DeployStatus)
+	}
+	files, err := ioutil.ReadDir(filepath.Dir(installer.getLocalAppFile("adminApps", &appList[1])))
+	if err != nil || len(files) != 0 {
+		t.Errorf("install() should have removed the packages and their signatures, found %d files: %v", len(files), err)
+	}
+}
+
+func TestApplyAppInstalls(t *testing.T) {
+	cr := enterpriseApi.LicenseMaster{ObjectMeta: metav1.ObjectMeta{Name: "stack1", Namespace: "test"}}
+	appDeployContext := &enterpriseApi.AppDeploymentContext{IsDeploymentInProgress: true}
+
+	// nothing to install: one more reconcile is scheduled to pick up the latest config changes
+	requeue, err := applyAppInstalls(spltest.NewMockClient(), &cr, &cr.Spec.AppFrameworkConfig, appDeployContext, nil)
+	if err != nil || !requeue || appDeployContext.IsDeploymentInProgress {
+		t.Errorf("applyAppInstalls() = %t, %v; want true and the deployment completed", requeue, err)
+	}
+	requeue, err = applyAppInstalls(spltest.NewMockClient(), &cr, &cr.Spec.AppFrameworkConfig, appDeployContext, nil)
+	if err != nil || requeue {
+		t.Errorf("applyAppInstalls() = %t, %v; want false", requeue, err)
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2018-2021 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enterprise
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"os"
+
+	splcommon "github.com/splunk/splunk-operator/pkg/splunk/common"
+	splutil "github.com/splunk/splunk-operator/pkg/splunk/util"
+)
+
+// appSignatureExt is the extension of the detached signatures of the app packages, next to the packages
+const appSignatureExt = ".sig"
+
+// getAppSignaturePublicKey returns the public key verifying the signatures of the app packages, read from a secret
+func getAppSignaturePublicKey(c splcommon.ControllerClient, cr splcommon.MetaObject, secretName string) (crypto.PublicKey, error) {
+	secret, err := splutil.GetSecretByName(c, cr, secretName)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(secret.Data[appSignaturePublicKey])
+	if block == nil {
+		return nil, fmt.Errorf("%s of secret %s is not a PEM encoded public key", appSignaturePublicKey, secretName)
+	}
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
+
+// verifyAppSignature verifies the detached signature of an app package with a RSA or ECDSA public key. The signature
+// is the one created by "openssl dgst -sha256 -sign private_key.pem -out app.tgz.sig app.tgz".
+func verifyAppSignature(publicKey crypto.PublicKey, localFile string, signatureFile string) error {
+	signature, err := ioutil.ReadFile(signatureFile)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(localFile)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err = io.Copy(hash, file); err != nil {
+		return err
+	}
+	digest := hash.Sum(nil)
+
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		err = rsa.VerifyPKCS1v15(key, crypto.SHA256, digest, signature)
+	case *ecdsa.PublicKey:
+		var ecdsaSignature struct {
+			R, S *big.Int
+		}
+		if _, err = asn1.Unmarshal(signature, &ecdsaSignature); err == nil && !ecdsa.Verify(key, digest, ecdsaSignature.R, ecdsaSignature.S) {
+			err = fmt.Errorf("ecdsa: verification error")
+		}
+	default:
+		return fmt.Errorf("unsupported public key type %T", publicKey)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid signature: %v", err)
+	}
+
+	return nil
+}
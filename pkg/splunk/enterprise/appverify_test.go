@@ -0,0 +1,116 @@
+// Copyright (c) 2018-2021 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enterprise
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	enterpriseApi "github.com/splunk/splunk-operator/pkg/apis/enterprise/v2"
+	spltest "github.com/splunk/splunk-operator/pkg/splunk/test"
+)
+
+// newTestSignatureSecret returns a secret holding the PEM encoded public key of a signer
+func newTestSignatureSecret(t *testing.T, name string, signer crypto.Signer) *corev1.Secret {
+	der, err := x509.MarshalPKIXPublicKey(signer.Public())
+	if err != nil {
+		t.Fatalf("Unable to marshal the public key: %v", err)
+	}
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test"},
+		Data:       map[string][]byte{appSignaturePublicKey: pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})},
+	}
+}
+
+// signTestFile returns the signature of a file, as created by "openssl dgst -sha256 -sign"
+func signTestFile(t *testing.T, signer crypto.Signer, fileName string) []byte {
+	content, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		t.Fatalf("Unable to read %s: %v", fileName, err)
+	}
+	digest := sha256.Sum256(content)
+	signature, err := signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		t.Fatalf("Unable to sign %s: %v", fileName, err)
+	}
+	return signature
+}
+
+func TestVerifyAppSignature(t *testing.T) {
+	dir, err := ioutil.TempDir("", "appverify")
+	if err != nil {
+		t.Fatalf("Unable to create a temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	appFile := filepath.Join(dir, "app1.tgz")
+	otherFile := filepath.Join(dir, "app2.tgz")
+	signatureFile := appFile + appSignatureExt
+	if err = ioutil.WriteFile(appFile, []byte("app1 content"), 0644); err != nil {
+		t.Fatalf("Unable to write %s: %v", appFile, err)
+	}
+	if err = ioutil.WriteFile(otherFile, []byte("app2 content"), 0644); err != nil {
+		t.Fatalf("Unable to write %s: %v", otherFile, err)
+	}
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Unable to generate a RSA key: %v", err)
+	}
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Unable to generate an ECDSA key: %v", err)
+	}
+
+	cr := enterpriseApi.Standalone{ObjectMeta: metav1.ObjectMeta{Name: "stack1", Namespace: "test"}}
+	c := spltest.NewMockClient()
+	for name, signer := range map[string]crypto.Signer{"rsa": rsaKey, "ecdsa": ecdsaKey} {
+		c.AddObject(newTestSignatureSecret(t, name+"-key", signer))
+		publicKey, err := getAppSignaturePublicKey(c, &cr, name+"-key")
+		if err != nil {
+			t.Fatalf("getAppSignaturePublicKey(%s) returned error: %v", name, err)
+		}
+
+		if err = ioutil.WriteFile(signatureFile, signTestFile(t, signer, appFile), 0644); err != nil {
+			t.Fatalf("Unable to write %s: %v", signatureFile, err)
+		}
+		if err = verifyAppSignature(publicKey, appFile, signatureFile); err != nil {
+			t.Errorf("verifyAppSignature(%s) returned error: %v", name, err)
+		}
+		if err = verifyAppSignature(publicKey, otherFile, signatureFile); err == nil {
+			t.Errorf("verifyAppSignature(%s) should fail for the signature of another package", name)
+		}
+	}
+
+	if _, err = getAppSignaturePublicKey(c, &cr, "missing-key"); err == nil {
+		t.Errorf("getAppSignaturePublicKey() should fail for a missing secret")
+	}
+	c.AddObject(&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "invalid-key", Namespace: "test"}, Data: map[string][]byte{appSignaturePublicKey: []byte("invalid")}})
+	if _, err = getAppSignaturePublicKey(c, &cr, "invalid-key"); err == nil {
+		t.Errorf("getAppSignaturePublicKey() should fail for a secret without a PEM encoded key")
+	}
+}
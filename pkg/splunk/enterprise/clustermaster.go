@@ -18,6 +18,7 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"regexp"
 	"time"
 
 	enterpriseApi "github.com/splunk/splunk-operator/pkg/apis/enterprise/v2"
@@ -29,24 +30,38 @@ import (
 	splctrl "github.com/splunk/splunk-operator/pkg/splunk/controller"
 	splutil "github.com/splunk/splunk-operator/pkg/splunk/util"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
 )
 
 // ApplyClusterMaster reconciles the state of a Splunk Enterprise cluster manager.
-func ApplyClusterMaster(client splcommon.ControllerClient, cr *enterpriseApi.ClusterMaster) (reconcile.Result, error) {
+func ApplyClusterMaster(client splcommon.ControllerClient, recorder record.EventRecorder, cr *enterpriseApi.ClusterMaster) (result reconcile.Result, err error) {
 
 	// unless modified, reconcile for this object will be requeued after 5 seconds
-	result := reconcile.Result{
+	result = reconcile.Result{
 		Requeue:      true,
 		RequeueAfter: time.Second * 5,
 	}
 	scopedLog := log.WithName("ApplyClusterMaster").WithValues("name", cr.GetName(), "namespace", cr.GetNamespace())
+	eventPublisher := newEventPublisher(recorder, cr)
+	oldPhase := cr.Status.Phase
 	if cr.Status.ResourceRevMap == nil {
 		cr.Status.ResourceRevMap = make(map[string]string)
 	}
 
-	// validate and updates defaults for CR
-	err := validateClusterMasterSpec(cr)
+	// updates status and conditions after function completes, including validation failures
+	defer func() {
+		setStatusConditions(cr, err)
+		if updateErr := client.Status().Update(context.TODO(), cr); updateErr != nil {
+			scopedLog.Error(updateErr, "Status update failed")
+		}
+	}()
+
+	// updates defaults and validates CR
+	setClusterMasterDefaults(cr)
+	err = validateClusterMasterSpec(cr)
 	if err != nil {
+		cr.Status.Phase = splcommon.PhaseError
+		eventPublisher.Warning(eventReasonValidationFailed, "%v", err)
 		return result, err
 	}
 
@@ -54,13 +69,33 @@ func ApplyClusterMaster(client splcommon.ControllerClient, cr *enterpriseApi.Clu
 	cr.Status.Phase = splcommon.PhaseError
 	cr.Status.Selector = fmt.Sprintf("app.kubernetes.io/instance=splunk-%s-cluster-master", cr.GetName())
 
-	if !reflect.DeepEqual(cr.Status.SmartStore, cr.Spec.SmartStore) ||
-		AreRemoteVolumeKeysChanged(client, cr, SplunkClusterMaster, &cr.Spec.SmartStore, cr.Status.ResourceRevMap, &err) {
+	// the Smartstore configuration is left as is while the CR is deleted, so that its checks do not block the deletion
+	if cr.ObjectMeta.DeletionTimestamp == nil && (!reflect.DeepEqual(cr.Status.SmartStore, cr.Spec.SmartStore) ||
+		AreRemoteVolumeKeysChanged(client, cr, SplunkClusterMaster, &cr.Spec.SmartStore, cr.Status.ResourceRevMap, &err)) {
+
+		// the peers lose track of the buckets of the indexes removed from the bundle
+		var removedIndexes []string
+		removedIndexes, err = checkSmartstoreIndexRemovals(cr, &cr.Spec.SmartStore, &cr.Status.SmartStore)
+		if err != nil {
+			eventPublisher.Warning(eventReasonIndexRemovalBlocked, "%v", err)
+			return result, err
+		}
+
+		// the bundle is not pushed to the peers while a volume fails the access check
+		err = checkSmartstoreVolumes(client, cr, SplunkClusterMaster, &cr.Spec.SmartStore)
+		if err != nil {
+			eventPublisher.Warning(eventReasonVolumeCheckFailed, "%v", err)
+			return result, err
+		}
 
 		_, configMapDataChanged, err := ApplySmartstoreConfigMap(client, cr, &cr.Spec.SmartStore)
 		if err != nil {
 			return result, err
-		} else if configMapDataChanged {
+		}
+		for _, index := range removedIndexes {
+			eventPublisher.Normal(eventReasonIndexRemoved, "Removed index %s from the Smartstore configuration", index)
+		}
+		if configMapDataChanged {
 			// Do not auto populate with configMapDataChanged flag to NeedToPushMasterApps. Set it only  if
 			// configMapDataChanged it true. It mush be reset, only upon initiating the bundle push REST call,
 			// once the CM is in ready state otherwise, we keep retrying
@@ -76,18 +111,11 @@ func ApplyClusterMaster(client splcommon.ControllerClient, cr *enterpriseApi.Clu
 		return result, err
 	}
 
-	defer func() {
-		err = client.Status().Update(context.TODO(), cr)
-		if err != nil {
-			scopedLog.Error(err, "Status update failed")
-		}
-	}()
-
 	// If the app framework is configured then do following things -
 	// 1. Initialize the S3Clients based on providers
 	// 2. Check the status of apps on remote storage.
 	if len(cr.Spec.AppFrameworkConfig.AppSources) != 0 {
-		err := initAndCheckAppInfoStatus(client, cr, &cr.Spec.AppFrameworkConfig, &cr.Status.AppContext)
+		err := initAndCheckAppInfoStatus(client, cr, &cr.Spec.AppFrameworkConfig, &cr.Status.AppContext, eventPublisher)
 		if err != nil {
 			cr.Status.AppContext.IsDeploymentInProgress = false
 			return result, err
@@ -134,20 +162,37 @@ func ApplyClusterMaster(client splcommon.ControllerClient, cr *enterpriseApi.Clu
 	if err != nil {
 		return result, err
 	}
-	clusterMasterManager := splctrl.DefaultStatefulSetPodManager{}
+
+	// create or update a PodDisruptionBudget for the cluster manager
+	err = applySplunkPodDisruptionBudget(client, cr, &cr.Spec.CommonSplunkSpec, SplunkClusterMaster, 1)
+	if err != nil {
+		return result, err
+	}
+	// hold off updates while the license manager is upgraded
+	gate := newUpgradeGate(client, cr, &cr.Spec.CommonSplunkSpec)
+	clusterMasterManager := splctrl.DefaultStatefulSetPodManager{RolloutStrategy: cr.Spec.RolloutStrategy, HoldUpdates: gate.hold}
 	phase, err := clusterMasterManager.Update(client, statefulSet, 1)
 	if err != nil {
 		return result, err
 	}
+	eventPublisher.PhaseChanged(oldPhase, phase, 1)
 	cr.Status.Phase = phase
 
+	// expand the claims of the pods after the storage capacity was increased
+	cr.Status.PvcExpansion, err = applyPvcExpansion(client, cr, &cr.Spec.CommonSplunkSpec, statefulSet)
+	if err != nil {
+		return result, err
+	}
+
 	// no need to requeue if everything is ready
 	if cr.Status.Phase == splcommon.PhaseReady {
 		if cr.Status.AppContext.AppsSrcDeployStatus != nil {
-			markAppsStatusToComplete(client, cr, &cr.Spec.AppFrameworkConfig, cr.Status.AppContext.AppsSrcDeployStatus)
-			// Schedule one more reconcile in next 5 seconds, just to cover any latest app framework config changes
-			if cr.Status.AppContext.IsDeploymentInProgress {
-				cr.Status.AppContext.IsDeploymentInProgress = false
+			requeue, err := applyAppInstalls(client, cr, &cr.Spec.AppFrameworkConfig, &cr.Status.AppContext, eventPublisher)
+			if err != nil {
+				return result, err
+			}
+			// Schedule one more reconcile in next 5 seconds, until the apps are installed and to cover any latest app framework config changes
+			if requeue {
 				return result, nil
 			}
 		}
@@ -159,7 +204,11 @@ func ApplyClusterMaster(client splcommon.ControllerClient, cr *enterpriseApi.Clu
 
 		// Manager apps bundle push requires multiple reconcile iterations in order to reflect the configMap on the CM pod.
 		// So keep PerformCmBundlePush() as the last call in this block of code, so that other functionalities are not blocked
+		needToPushMasterApps := cr.Status.BundlePushTracker.NeedToPushMasterApps
 		err = PerformCmBundlePush(client, cr)
+		if needToPushMasterApps && !cr.Status.BundlePushTracker.NeedToPushMasterApps {
+			eventPublisher.Normal(eventReasonBundlePushed, "Pushed cluster manager bundle to the indexer cluster peers")
+		}
 		if err != nil {
 			return result, err
 		}
@@ -173,10 +222,19 @@ func ApplyClusterMaster(client splcommon.ControllerClient, cr *enterpriseApi.Clu
 			}
 		}
 	}
+	requeueIfExpanding(&result, eventPublisher, cr.Status.PvcExpansion)
+	gate.requeueIfHeld(&result, eventPublisher)
 	return result, nil
 }
 
-// validateClusterMasterSpec checks validity and makes default updates to a ClusterMasterSpec, and returns error if something is wrong.
+// setClusterMasterDefaults sets default values for the parameters of a ClusterMasterSpec that are not provided.
+func setClusterMasterDefaults(cr *enterpriseApi.ClusterMaster) {
+	setMultisiteDefaults(&cr.Spec.Multisite)
+	setAppFrameworkDefaults(&cr.Spec.AppFrameworkConfig)
+	setCommonSplunkSpecDefaults(&cr.Spec.CommonSplunkSpec)
+}
+
+// validateClusterMasterSpec checks validity of a ClusterMasterSpec, and returns error if something is wrong.
 func validateClusterMasterSpec(cr *enterpriseApi.ClusterMaster) error {
 
 	if !reflect.DeepEqual(cr.Status.SmartStore, cr.Spec.SmartStore) {
@@ -187,15 +245,120 @@ func validateClusterMasterSpec(cr *enterpriseApi.ClusterMaster) error {
 	}
 
 	if !reflect.DeepEqual(cr.Status.AppContext.AppFrameworkConfig, cr.Spec.AppFrameworkConfig) {
-		err := ValidateAppFrameworkSpec(&cr.Spec.AppFrameworkConfig, &cr.Status.AppContext, false)
+		err := validatePremiumAppsScope(&cr.Spec.AppFrameworkConfig)
+		if err != nil {
+			return err
+		}
+
+		err = ValidateAppFrameworkSpec(&cr.Spec.AppFrameworkConfig, false)
 		if err != nil {
 			return err
 		}
 	}
 
+	err := validateMultisiteSpec(&cr.Spec.Multisite)
+	if err != nil {
+		return err
+	}
+
 	return validateCommonSplunkSpec(&cr.Spec.CommonSplunkSpec)
 }
 
+// setMultisiteDefaults sets default values for the parameters of a MultisiteSpec that are not provided.
+func setMultisiteDefaults(multisite *enterpriseApi.MultisiteSpec) {
+	// Multisite is not configured through the spec
+	if len(multisite.AvailableSites) == 0 {
+		return
+	}
+
+	// Cluster manager is located in the first site, unless specified
+	if multisite.Site == "" {
+		multisite.Site = multisite.AvailableSites[0]
+	}
+
+	// Use the Splunk defaults: site_replication_factor = origin:2, total:3 and site_search_factor = origin:1, total:2
+	setSiteFactorDefaults(&multisite.SiteReplicationFactor, 2, 3)
+	setSiteFactorDefaults(&multisite.SiteSearchFactor, 1, 2)
+}
+
+// validateMultisiteSpec checks validity of a MultisiteSpec, and returns error if something is wrong.
+func validateMultisiteSpec(multisite *enterpriseApi.MultisiteSpec) error {
+	// Multisite is not configured through the spec
+	if len(multisite.AvailableSites) == 0 {
+		if multisite.Site != "" {
+			return fmt.Errorf("Multisite site %s is configured without any availableSites", multisite.Site)
+		}
+		return nil
+	}
+
+	if len(multisite.AvailableSites) > maxMultisiteSites {
+		return fmt.Errorf("Multisite cluster supports at most %d sites, got %d", maxMultisiteSites, len(multisite.AvailableSites))
+	}
+
+	sites := make(map[string]bool)
+	for _, site := range multisite.AvailableSites {
+		if !isValidSiteName(site) {
+			return fmt.Errorf("Invalid site name %s, site names should be site1 to site%d", site, maxMultisiteSites)
+		}
+		if sites[site] {
+			return fmt.Errorf("Duplicate site %s in availableSites", site)
+		}
+		sites[site] = true
+	}
+
+	if !sites[multisite.Site] {
+		return fmt.Errorf("Cluster master site %s is not one of the availableSites", multisite.Site)
+	}
+
+	err := validateSiteFactor("siteReplicationFactor", &multisite.SiteReplicationFactor)
+	if err != nil {
+		return err
+	}
+	err = validateSiteFactor("siteSearchFactor", &multisite.SiteSearchFactor)
+	if err != nil {
+		return err
+	}
+
+	if multisite.SiteSearchFactor.Origin > multisite.SiteReplicationFactor.Origin ||
+		multisite.SiteSearchFactor.Total > multisite.SiteReplicationFactor.Total {
+		return fmt.Errorf("siteSearchFactor cannot be greater than siteReplicationFactor")
+	}
+
+	return nil
+}
+
+// setSiteFactorDefaults sets the default origin and total values of a site factor, if not set
+func setSiteFactorDefaults(factor *enterpriseApi.SiteFactorSpec, origin int32, total int32) {
+	if factor.Origin == 0 {
+		factor.Origin = origin
+	}
+	if factor.Total == 0 {
+		factor.Total = total
+		if factor.Total < factor.Origin {
+			factor.Total = factor.Origin
+		}
+	}
+}
+
+// validateSiteFactor checks validity of a site replication or search factor
+func validateSiteFactor(name string, factor *enterpriseApi.SiteFactorSpec) error {
+	if factor.Origin < 1 {
+		return fmt.Errorf("%s origin should be at least 1, got %d", name, factor.Origin)
+	}
+	if factor.Total < factor.Origin {
+		return fmt.Errorf("%s total %d should not be less than origin %d", name, factor.Total, factor.Origin)
+	}
+	return nil
+}
+
+// siteNameRegex matches site1 to site63, the names supported for indexer cluster sites
+var siteNameRegex = regexp.MustCompile("^site([1-9]|[1-5][0-9]|6[0-3])$")
+
+// isValidSiteName checks if a site name is one of site1 to site63, the names supported for indexer cluster sites
+func isValidSiteName(site string) bool {
+	return siteNameRegex.MatchString(site)
+}
+
 // getClusterMasterStatefulSet returns a Kubernetes StatefulSet object for a Splunk Enterprise license manager.
 func getClusterMasterStatefulSet(client splcommon.ControllerClient, cr *enterpriseApi.ClusterMaster) (*appsv1.StatefulSet, error) {
 	var extraEnvVar []corev1.EnvVar
@@ -207,11 +370,12 @@ func getClusterMasterStatefulSet(client splcommon.ControllerClient, cr *enterpri
 	smartStoreConfigMap := getSmartstoreConfigMap(client, cr, SplunkClusterMaster)
 
 	if smartStoreConfigMap != nil {
-		setupInitContainer(&ss.Spec.Template, cr.Spec.Image, cr.Spec.ImagePullPolicy, commandForCMSmartstore)
+		setupInitContainer(&ss.Spec.Template, GetSplunkImage(cr.Spec.Image), cr.Spec.ImagePullPolicy, commandForCMSmartstore)
 	}
+	addSmartstoreCredentialFiles(&ss.Spec.Template, &cr.Spec.SmartStore)
 
-	// Setup App framework init containers
-	setupAppInitContainers(client, cr, &ss.Spec.Template, &cr.Spec.AppFrameworkConfig)
+	// Setup App framework staging volume
+	setupAppsStagingVolume(&ss.Spec.Template, &cr.Spec.AppFrameworkConfig)
 
 	return ss, err
 }
@@ -16,6 +16,7 @@ package enterprise
 
 import (
 	"fmt"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -42,8 +43,8 @@ func TestApplyClusterMaster(t *testing.T) {
 		{MetaName: "*v1.Secret-test-splunk-test-secret"},
 		{MetaName: "*v1.Secret-test-splunk-stack1-cluster-master-secret-v1"},
 		{MetaName: "*v1.ConfigMap-test-splunk-stack1-clustermaster-smartstore"},
-		{MetaName: "*v1.ConfigMap-test-splunk-stack1-clustermaster-app-list"},
 		{MetaName: "*v1.ConfigMap-test-splunk-stack1-clustermaster-smartstore"},
+		{MetaName: "*v1beta1.PodDisruptionBudget-test-splunk-stack1-cluster-master"},
 		{MetaName: "*v1.StatefulSet-test-splunk-stack1-cluster-master"},
 	}
 
@@ -57,7 +58,7 @@ func TestApplyClusterMaster(t *testing.T) {
 	}
 	listmockCall := []spltest.MockFuncCall{
 		{ListOpts: listOpts}}
-	createCalls := map[string][]spltest.MockFuncCall{"Get": funcCalls, "Create": {funcCalls[0], funcCalls[2], funcCalls[3], funcCalls[5], funcCalls[9]}, "List": {listmockCall[0]}, "Update": {funcCalls[0]}}
+	createCalls := map[string][]spltest.MockFuncCall{"Get": funcCalls, "Create": {funcCalls[0], funcCalls[2], funcCalls[3], funcCalls[5], funcCalls[8], funcCalls[9]}, "List": {listmockCall[0]}, "Update": {funcCalls[0]}}
 	updateCalls := map[string][]spltest.MockFuncCall{"Get": {funcCalls[0], funcCalls[0], funcCalls[2], funcCalls[3], funcCalls[4], funcCalls[5], funcCalls[6], funcCalls[7], funcCalls[8], funcCalls[9]}, "Update": {funcCalls[9]}, "List": {listmockCall[0]}}
 
 	current := enterpriseApi.ClusterMaster{
@@ -77,7 +78,7 @@ func TestApplyClusterMaster(t *testing.T) {
 	revised := current.DeepCopy()
 	revised.Spec.Image = "splunk/test"
 	reconcile := func(c *spltest.MockClient, cr interface{}) error {
-		_, err := ApplyClusterMaster(c, cr.(*enterpriseApi.ClusterMaster))
+		_, err := ApplyClusterMaster(c, nil, cr.(*enterpriseApi.ClusterMaster))
 		return err
 	}
 	spltest.ReconcileTesterWithoutRedundantCheck(t, "TestApplyClusterMaster", &current, revised, createCalls, updateCalls, reconcile, true)
@@ -87,12 +88,88 @@ func TestApplyClusterMaster(t *testing.T) {
 	revised.ObjectMeta.DeletionTimestamp = &currentTime
 	revised.ObjectMeta.Finalizers = []string{"enterprise.splunk.com/delete-pvc"}
 	deleteFunc := func(cr splcommon.MetaObject, c splcommon.ControllerClient) (bool, error) {
-		_, err := ApplyClusterMaster(c, cr.(*enterpriseApi.ClusterMaster))
+		_, err := ApplyClusterMaster(c, nil, cr.(*enterpriseApi.ClusterMaster))
 		return true, err
 	}
 	splunkDeletionTester(t, revised, deleteFunc)
 }
 
+func TestValidateMultisiteSpec(t *testing.T) {
+	multisite := enterpriseApi.MultisiteSpec{}
+
+	test := func(want enterpriseApi.MultisiteSpec) {
+		setMultisiteDefaults(&multisite)
+		if err := validateMultisiteSpec(&multisite); err != nil {
+			t.Errorf("validateMultisiteSpec() returned error: %v", err)
+		}
+		if !reflect.DeepEqual(multisite, want) {
+			t.Errorf("validateMultisiteSpec() got = %v; want %v", multisite, want)
+		}
+	}
+	testError := func(wantError string) {
+		setMultisiteDefaults(&multisite)
+		err := validateMultisiteSpec(&multisite)
+		if err == nil || err.Error() != wantError {
+			t.Errorf("validateMultisiteSpec() returned error %v; want %s", err, wantError)
+		}
+	}
+
+	// single site
+	test(enterpriseApi.MultisiteSpec{})
+
+	multisite.Site = "site1"
+	testError("Multisite site site1 is configured without any availableSites")
+
+	// defaults
+	multisite.Site = ""
+	multisite.AvailableSites = []string{"site2", "site1"}
+	test(enterpriseApi.MultisiteSpec{
+		Site:                  "site2",
+		AvailableSites:        []string{"site2", "site1"},
+		SiteReplicationFactor: enterpriseApi.SiteFactorSpec{Origin: 2, Total: 3},
+		SiteSearchFactor:      enterpriseApi.SiteFactorSpec{Origin: 1, Total: 2},
+	})
+
+	// total defaults to at least origin
+	multisite.SiteReplicationFactor = enterpriseApi.SiteFactorSpec{Origin: 4}
+	test(enterpriseApi.MultisiteSpec{
+		Site:                  "site2",
+		AvailableSites:        []string{"site2", "site1"},
+		SiteReplicationFactor: enterpriseApi.SiteFactorSpec{Origin: 4, Total: 4},
+		SiteSearchFactor:      enterpriseApi.SiteFactorSpec{Origin: 1, Total: 2},
+	})
+
+	multisite.SiteReplicationFactor = enterpriseApi.SiteFactorSpec{Origin: 2, Total: 1}
+	testError("siteReplicationFactor total 1 should not be less than origin 2")
+
+	multisite.SiteReplicationFactor = enterpriseApi.SiteFactorSpec{Origin: -1, Total: 1}
+	testError("siteReplicationFactor origin should be at least 1, got -1")
+
+	multisite.SiteReplicationFactor = enterpriseApi.SiteFactorSpec{Origin: 1, Total: 2}
+	multisite.SiteSearchFactor = enterpriseApi.SiteFactorSpec{Origin: 2, Total: 2}
+	testError("siteSearchFactor cannot be greater than siteReplicationFactor")
+
+	multisite.SiteSearchFactor = enterpriseApi.SiteFactorSpec{Origin: 1, Total: 2}
+	multisite.Site = "site3"
+	testError("Cluster master site site3 is not one of the availableSites")
+
+	multisite.Site = ""
+	multisite.AvailableSites = []string{"site1", "site1"}
+	testError("Duplicate site site1 in availableSites")
+
+	multisite.AvailableSites = []string{"site1", "site0"}
+	testError("Invalid site name site0, site names should be site1 to site63")
+
+	multisite.AvailableSites = []string{"site1", "zone1"}
+	testError("Invalid site name zone1, site names should be site1 to site63")
+
+	multisite.AvailableSites = []string{}
+	for i := 1; i <= 64; i++ {
+		multisite.AvailableSites = append(multisite.AvailableSites, fmt.Sprintf("site%d", i))
+	}
+	testError("Multisite cluster supports at most 63 sites, got 64")
+}
+
 func TestGetClusterMasterStatefulSet(t *testing.T) {
 	cr := enterpriseApi.ClusterMaster{
 		ObjectMeta: metav1.ObjectMeta{
@@ -109,6 +186,7 @@ func TestGetClusterMasterStatefulSet(t *testing.T) {
 
 	test := func(want string) {
 		f := func() (interface{}, error) {
+			setClusterMasterDefaults(&cr)
 			if err := validateClusterMasterSpec(&cr); err != nil {
 				t.Errorf("validateClusterMasterSpec() returned error: %v", err)
 			}
@@ -152,7 +230,13 @@ func TestGetClusterMasterStatefulSet(t *testing.T) {
 }
 
 func TestApplyClusterMasterWithSmartstore(t *testing.T) {
+	checkS3VolumeAccess = func(endpoint, bucket, prefix, objectName, accessKeyID, secretAccessKey string) error {
+		return nil
+	}
+	defer func() { checkS3VolumeAccess = splclient.CheckS3VolumeAccess }()
+
 	funcCalls := []spltest.MockFuncCall{
+		{MetaName: "*v1.Secret-test-splunk-test-secret"},
 		{MetaName: "*v1.Secret-test-splunk-test-secret"},
 		{MetaName: "*v1.Secret-test-splunk-test-secret"},
 		{MetaName: "*v1.ConfigMap-test-splunk-stack1-clustermaster-smartstore"},
@@ -164,8 +248,8 @@ func TestApplyClusterMasterWithSmartstore(t *testing.T) {
 		{MetaName: "*v1.Secret-test-splunk-test-secret"},
 		{MetaName: "*v1.Secret-test-splunk-stack1-cluster-master-secret-v1"},
 		{MetaName: "*v1.ConfigMap-test-splunk-stack1-clustermaster-smartstore"},
-		{MetaName: "*v1.ConfigMap-test-splunk-stack1-clustermaster-app-list"},
 		{MetaName: "*v1.ConfigMap-test-splunk-stack1-clustermaster-smartstore"},
+		{MetaName: "*v1beta1.PodDisruptionBudget-test-splunk-stack1-cluster-master"},
 		{MetaName: "*v1.StatefulSet-test-splunk-stack1-cluster-master"},
 		{MetaName: "*v1.Pod-test-splunk-stack1-cluster-master-0"},
 		{MetaName: "*v1.Secret-test-splunk-test-secret"},
@@ -187,8 +271,8 @@ func TestApplyClusterMasterWithSmartstore(t *testing.T) {
 	}
 	listmockCall := []spltest.MockFuncCall{
 		{ListOpts: listOpts}}
-	createCalls := map[string][]spltest.MockFuncCall{"Get": funcCalls, "Create": {funcCalls[6], funcCalls[7], funcCalls[9], funcCalls[16], funcCalls[17], funcCalls[18], funcCalls[19], funcCalls[21]}, "List": {listmockCall[0], listmockCall[0], listmockCall[0]}, "Update": {funcCalls[0], funcCalls[3], funcCalls[21]}}
-	updateCalls := map[string][]spltest.MockFuncCall{"Get": {funcCalls[0], funcCalls[1], funcCalls[2], funcCalls[3], funcCalls[5], funcCalls[5], funcCalls[6], funcCalls[7], funcCalls[8], funcCalls[9], funcCalls[10], funcCalls[11], funcCalls[12], funcCalls[13]}, "Update": {funcCalls[10], funcCalls[13]}, "List": {listmockCall[0]}}
+	createCalls := map[string][]spltest.MockFuncCall{"Get": funcCalls, "Create": {funcCalls[7], funcCalls[8], funcCalls[10], funcCalls[13], funcCalls[17], funcCalls[18], funcCalls[19], funcCalls[20], funcCalls[22]}, "List": {listmockCall[0], listmockCall[0], listmockCall[0]}, "Update": {funcCalls[1], funcCalls[4], funcCalls[22]}}
+	updateCalls := map[string][]spltest.MockFuncCall{"Get": {funcCalls[0], funcCalls[1], funcCalls[2], funcCalls[3], funcCalls[4], funcCalls[6], funcCalls[6], funcCalls[7], funcCalls[8], funcCalls[9], funcCalls[10], funcCalls[11], funcCalls[12], funcCalls[13], funcCalls[14]}, "Update": {funcCalls[11], funcCalls[14]}, "List": {listmockCall[0]}}
 
 	current := enterpriseApi.ClusterMaster{
 		TypeMeta: metav1.TypeMeta{
@@ -227,7 +311,7 @@ func TestApplyClusterMasterWithSmartstore(t *testing.T) {
 	client := spltest.NewMockClient()
 
 	// Without S3 keys, ApplyClusterMaster should fail
-	_, err := ApplyClusterMaster(client, &current)
+	_, err := ApplyClusterMaster(client, nil, &current)
 	if err == nil {
 		t.Errorf("ApplyClusterMaster should fail without S3 secrets configured")
 	}
@@ -256,7 +340,7 @@ func TestApplyClusterMasterWithSmartstore(t *testing.T) {
 	revised := current.DeepCopy()
 	revised.Spec.Image = "splunk/test"
 	reconcile := func(c *spltest.MockClient, cr interface{}) error {
-		_, err := ApplyClusterMaster(c, cr.(*enterpriseApi.ClusterMaster))
+		_, err := ApplyClusterMaster(c, nil, cr.(*enterpriseApi.ClusterMaster))
 		return err
 	}
 
@@ -283,12 +367,12 @@ func TestApplyClusterMasterWithSmartstore(t *testing.T) {
 	spltest.ReconcileTesterWithoutRedundantCheck(t, "TestApplyClusterMasterWithSmartstore-0", &current, revised, createCalls, updateCalls, reconcile, true, secret, &smartstoreConfigMap, ss, pod)
 
 	current.Status.BundlePushTracker.NeedToPushMasterApps = true
-	if _, err = ApplyClusterMaster(client, &current); err != nil {
+	if _, err = ApplyClusterMaster(client, nil, &current); err != nil {
 		t.Errorf("ApplyClusterMaster() should not have returned error")
 	}
 
 	current.Spec.CommonSplunkSpec.EtcVolumeStorageConfig.StorageCapacity = "-abcd"
-	if _, err := ApplyClusterMaster(client, &current); err == nil {
+	if _, err := ApplyClusterMaster(client, nil, &current); err == nil {
 		t.Errorf("ApplyClusterMaster() should have returned error")
 	}
 
@@ -298,7 +382,7 @@ func TestApplyClusterMasterWithSmartstore(t *testing.T) {
 	ss.Spec.Replicas = &replicas
 	ss.Spec.Template.Spec.Containers[0].Image = "splunk/splunk"
 	client.AddObject(ss)
-	if result, err := ApplyClusterMaster(client, &current); err == nil && !result.Requeue {
+	if result, err := ApplyClusterMaster(client, nil, &current); err == nil && !result.Requeue {
 		t.Errorf("ApplyClusterMaster() should have returned error or result.requeue should have been false")
 	}
 
@@ -309,7 +393,7 @@ func TestApplyClusterMasterWithSmartstore(t *testing.T) {
 	current.Spec.CommonSplunkSpec.Mock = false
 
 	// This should fail at ApplyMonitoringConsole
-	if _, err := ApplyClusterMaster(client, &current); err == nil {
+	if _, err := ApplyClusterMaster(client, nil, &current); err == nil {
 		t.Errorf("ApplyClusterMaster() should have returned error")
 	}
 }
@@ -488,7 +572,7 @@ func TestAppFrameworkApplyClusterMasterShouldNotFail(t *testing.T) {
 		t.Errorf(err.Error())
 	}
 
-	_, err = ApplyClusterMaster(client, &cm)
+	_, err = ApplyClusterMaster(client, nil, &cm)
 	if err != nil {
 		t.Errorf("ApplyClusterMaster should not have returned error here.")
 	}
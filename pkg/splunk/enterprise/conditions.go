@@ -0,0 +1,252 @@
+// Copyright (c) 2018-2021 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enterprise
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	enterpriseApi "github.com/splunk/splunk-operator/pkg/apis/enterprise/v2"
+	splcommon "github.com/splunk/splunk-operator/pkg/splunk/common"
+)
+
+// reasons used for the status conditions of the custom resources
+const (
+	reasonReady                 = "Ready"
+	reasonNotReady              = "NotReady"
+	reasonIdle                  = "Idle"
+	reasonReconcileError        = "ReconcileError"
+	reasonAsExpected            = "AsExpected"
+	reasonAppsDeployed          = "AppsDeployed"
+	reasonAppsDeploying         = "DeploymentInProgress"
+	reasonAppsDeployFailed      = "DeploymentFailed"
+	reasonSmartStoreApplied     = "ConfigMapApplied"
+	reasonSmartStorePending     = "ConfigMapPending"
+	reasonSmartStoreCheckFailed = "VolumeCheckFailed"
+	reasonIndexRemovalBlocked   = "IndexRemovalBlocked"
+	reasonBundlePushed          = "BundlePushed"
+	reasonBundlePushPending     = "BundlePushPending"
+	reasonSecretsInSync         = "SecretsInSync"
+	reasonSecretChangeProgress  = "SecretChangeInProgress"
+)
+
+// setStatusConditions updates the status conditions of a custom resource, using the outcome of the last reconcile
+func setStatusConditions(cr splcommon.MetaObject, err error) {
+	generation := cr.GetGeneration()
+	switch cr := cr.(type) {
+	case *enterpriseApi.Standalone:
+		setPhaseConditions(&cr.Status.Conditions, generation, cr.Status.Phase, err)
+		setSmartStoreConfiguredCondition(&cr.Status.Conditions, generation, &cr.Spec.SmartStore, &cr.Status.SmartStore, err)
+		setAppsDeployedCondition(&cr.Status.Conditions, generation, &cr.Spec.AppFrameworkConfig, &cr.Status.AppContext)
+	case *enterpriseApi.ClusterMaster:
+		setPhaseConditions(&cr.Status.Conditions, generation, cr.Status.Phase, err)
+		setSmartStoreConfiguredCondition(&cr.Status.Conditions, generation, &cr.Spec.SmartStore, &cr.Status.SmartStore, err)
+		setAppsDeployedCondition(&cr.Status.Conditions, generation, &cr.Spec.AppFrameworkConfig, &cr.Status.AppContext)
+		setBundlePushedCondition(&cr.Status.Conditions, generation, &cr.Status.BundlePushTracker)
+	case *enterpriseApi.IndexerCluster:
+		setPhaseConditions(&cr.Status.Conditions, generation, cr.Status.Phase, err)
+		pending := len(cr.Status.IndexerSecretChanged) > 0 || isAnySecretChanged(cr.Status.IdxcPasswordChangedSecrets)
+		setSecretsInSyncCondition(&cr.Status.Conditions, generation, pending)
+	case *enterpriseApi.SearchHeadCluster:
+		setPhaseConditions(&cr.Status.Conditions, generation, cr.Status.Phase, err)
+		setAppsDeployedCondition(&cr.Status.Conditions, generation, &cr.Spec.AppFrameworkConfig, &cr.Status.AppContext)
+		pending := len(cr.Status.ShcSecretChanged) > 0 || len(cr.Status.AdminSecretChanged) > 0 ||
+			isAnySecretChanged(cr.Status.AdminPasswordChangedSecrets)
+		setSecretsInSyncCondition(&cr.Status.Conditions, generation, pending)
+	case *enterpriseApi.LicenseMaster:
+		setPhaseConditions(&cr.Status.Conditions, generation, cr.Status.Phase, err)
+		setAppsDeployedCondition(&cr.Status.Conditions, generation, &cr.Spec.AppFrameworkConfig, &cr.Status.AppContext)
+	}
+}
+
+// setPhaseConditions sets the Ready, Progressing and Degraded conditions based on the phase and reconcile error
+func setPhaseConditions(conditions *[]splcommon.Condition, generation int64, phase splcommon.Phase, err error) {
+	ready := splcommon.Condition{
+		Type:               splcommon.ConditionReady,
+		Status:             corev1.ConditionFalse,
+		ObservedGeneration: generation,
+		Reason:             reasonNotReady,
+		Message:            fmt.Sprintf("Phase is %s", phase),
+	}
+	if phase == splcommon.PhaseReady && err == nil {
+		ready.Status = corev1.ConditionTrue
+		ready.Reason = reasonReady
+	}
+	splcommon.SetCondition(conditions, ready)
+
+	progressing := splcommon.Condition{
+		Type:               splcommon.ConditionProgressing,
+		Status:             corev1.ConditionFalse,
+		ObservedGeneration: generation,
+		Reason:             reasonIdle,
+		Message:            fmt.Sprintf("Phase is %s", phase),
+	}
+	switch phase {
+	case splcommon.PhasePending, splcommon.PhaseUpdating, splcommon.PhaseScalingUp, splcommon.PhaseScalingDown, splcommon.PhaseTerminating:
+		progressing.Status = corev1.ConditionTrue
+		progressing.Reason = string(phase)
+	}
+	splcommon.SetCondition(conditions, progressing)
+
+	degraded := splcommon.Condition{
+		Type:               splcommon.ConditionDegraded,
+		Status:             corev1.ConditionFalse,
+		ObservedGeneration: generation,
+		Reason:             reasonAsExpected,
+	}
+	if err != nil {
+		degraded.Status = corev1.ConditionTrue
+		degraded.Reason = reasonReconcileError
+		degraded.Message = err.Error()
+	} else if phase == splcommon.PhaseError {
+		degraded.Status = corev1.ConditionTrue
+		degraded.Reason = string(phase)
+		degraded.Message = fmt.Sprintf("Phase is %s", phase)
+	}
+	splcommon.SetCondition(conditions, degraded)
+}
+
+// setAppsDeployedCondition sets the AppsDeployed condition, or removes it when the App Framework is not configured
+func setAppsDeployedCondition(conditions *[]splcommon.Condition, generation int64, appFrameworkConf *enterpriseApi.AppFrameworkSpec, appContext *enterpriseApi.AppDeploymentContext) {
+	if len(appFrameworkConf.AppSources) == 0 {
+		splcommon.RemoveCondition(conditions, splcommon.ConditionAppsDeployed)
+		return
+	}
+
+	var pending, failed []string
+	for _, appSrcDeployInfo := range appContext.AppsSrcDeployStatus {
+		for _, appDeployInfo := range appSrcDeployInfo.AppDeploymentInfoList {
+			if appDeployInfo.RepoState != enterpriseApi.RepoStateActive {
+				continue
+			}
+			switch appDeployInfo.DeployStatus {
+			case enterpriseApi.DeployStatusError:
+				failed = append(failed, appDeployInfo.AppName)
+			case enterpriseApi.DeployStatusComplete:
+			default:
+				pending = append(pending, appDeployInfo.AppName)
+			}
+		}
+	}
+
+	condition := splcommon.Condition{
+		Type:               splcommon.ConditionAppsDeployed,
+		Status:             corev1.ConditionTrue,
+		ObservedGeneration: generation,
+		Reason:             reasonAppsDeployed,
+		Message:            "All apps are deployed",
+	}
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		condition.Status = corev1.ConditionFalse
+		condition.Reason = reasonAppsDeployFailed
+		condition.Message = fmt.Sprintf("Failed to deploy apps: %s", strings.Join(failed, ", "))
+	} else if len(pending) > 0 || appContext.IsDeploymentInProgress || appContext.AppsSrcDeployStatus == nil {
+		sort.Strings(pending)
+		condition.Status = corev1.ConditionFalse
+		condition.Reason = reasonAppsDeploying
+		condition.Message = "Apps deployment is in progress"
+		if len(pending) > 0 {
+			condition.Message = fmt.Sprintf("Waiting for apps: %s", strings.Join(pending, ", "))
+		}
+	}
+	splcommon.SetCondition(conditions, condition)
+}
+
+// setSmartStoreConfiguredCondition sets the SmartStoreConfigured condition, or removes it when SmartStore is not
+// configured. The condition reports the volume blocking the configuration when the reconcile failed its access check,
+// and else the volumes without secretRef, whose access is not verified.
+func setSmartStoreConfiguredCondition(conditions *[]splcommon.Condition, generation int64, specSmartStore, statusSmartStore *enterpriseApi.SmartStoreSpec, err error) {
+	if len(specSmartStore.VolList) == 0 && len(specSmartStore.IndexList) == 0 {
+		splcommon.RemoveCondition(conditions, splcommon.ConditionSmartStoreConfigured)
+		return
+	}
+
+	condition := splcommon.Condition{
+		Type:               splcommon.ConditionSmartStoreConfigured,
+		Status:             corev1.ConditionTrue,
+		ObservedGeneration: generation,
+		Reason:             reasonSmartStoreApplied,
+		Message:            "SmartStore configuration is applied",
+	}
+	if !reflect.DeepEqual(*specSmartStore, *statusSmartStore) {
+		condition.Status = corev1.ConditionFalse
+		condition.Reason = reasonSmartStorePending
+		condition.Message = "SmartStore configuration is not applied yet"
+		var volumeErr *smartstoreVolumeError
+		var indexRemovalErr *smartstoreIndexRemovalError
+		if errors.As(err, &volumeErr) {
+			condition.Reason = reasonSmartStoreCheckFailed
+			condition.Message = volumeErr.Error()
+		} else if errors.As(err, &indexRemovalErr) {
+			condition.Reason = reasonIndexRemovalBlocked
+			condition.Message = indexRemovalErr.Error()
+		}
+	}
+	unverified := getUnverifiedSmartstoreVolumes(specSmartStore)
+	if len(unverified) > 0 && condition.Reason != reasonSmartStoreCheckFailed && condition.Reason != reasonIndexRemovalBlocked {
+		condition.Message += fmt.Sprintf("; volumes not verified: %s", strings.Join(unverified, ", "))
+	}
+	splcommon.SetCondition(conditions, condition)
+}
+
+// setBundlePushedCondition sets the BundlePushed condition of a cluster manager
+func setBundlePushedCondition(conditions *[]splcommon.Condition, generation int64, bundlePushTracker *enterpriseApi.BundlePushInfo) {
+	condition := splcommon.Condition{
+		Type:               splcommon.ConditionBundlePushed,
+		Status:             corev1.ConditionTrue,
+		ObservedGeneration: generation,
+		Reason:             reasonBundlePushed,
+		Message:            "Cluster manager bundle is pushed to the peers",
+	}
+	if bundlePushTracker.NeedToPushMasterApps {
+		condition.Status = corev1.ConditionFalse
+		condition.Reason = reasonBundlePushPending
+		condition.Message = "Cluster manager bundle push is pending"
+	}
+	splcommon.SetCondition(conditions, condition)
+}
+
+// setSecretsInSyncCondition sets the SecretsInSync condition of a cluster
+func setSecretsInSyncCondition(conditions *[]splcommon.Condition, generation int64, changePending bool) {
+	condition := splcommon.Condition{
+		Type:               splcommon.ConditionSecretsInSync,
+		Status:             corev1.ConditionTrue,
+		ObservedGeneration: generation,
+		Reason:             reasonSecretsInSync,
+		Message:            "All members use the namespace scoped secret",
+	}
+	if changePending {
+		condition.Status = corev1.ConditionFalse
+		condition.Reason = reasonSecretChangeProgress
+		condition.Message = "Namespace scoped secret changes are being applied to the members"
+	}
+	splcommon.SetCondition(conditions, condition)
+}
+
+// isAnySecretChanged returns true if any secret in the map is flagged as changed
+func isAnySecretChanged(changedSecrets map[string]bool) bool {
+	for _, changed := range changedSecrets {
+		if changed {
+			return true
+		}
+	}
+	return false
+}
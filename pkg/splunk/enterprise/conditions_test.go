@@ -0,0 +1,209 @@
+// Copyright (c) 2018-2021 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enterprise
+
+import (
+	"errors"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	enterpriseApi "github.com/splunk/splunk-operator/pkg/apis/enterprise/v2"
+	splcommon "github.com/splunk/splunk-operator/pkg/splunk/common"
+	spltest "github.com/splunk/splunk-operator/pkg/splunk/test"
+)
+
+func checkCondition(t *testing.T, method string, conditions []splcommon.Condition, conditionType splcommon.ConditionType, want corev1.ConditionStatus, wantReason string) {
+	condition := splcommon.GetCondition(conditions, conditionType)
+	if condition == nil {
+		if want != "" {
+			t.Errorf("%s: condition %s not found; want %s", method, conditionType, want)
+		}
+		return
+	}
+	if want == "" {
+		t.Errorf("%s: condition %s = %s; want no condition", method, conditionType, condition.Status)
+		return
+	}
+	if condition.Status != want || condition.Reason != wantReason {
+		t.Errorf("%s: condition %s = %s/%s; want %s/%s", method, conditionType, condition.Status, condition.Reason, want, wantReason)
+	}
+}
+
+func TestSetPhaseConditions(t *testing.T) {
+	test := func(phase splcommon.Phase, err error, ready, progressing, degraded corev1.ConditionStatus, degradedReason string) {
+		var conditions []splcommon.Condition
+		setPhaseConditions(&conditions, 3, phase, err)
+		method := "setPhaseConditions(" + string(phase) + ")"
+		if len(conditions) != 3 {
+			t.Errorf("%s: got %d conditions; want 3", method, len(conditions))
+		}
+		for _, condition := range conditions {
+			if condition.ObservedGeneration != 3 {
+				t.Errorf("%s: condition %s ObservedGeneration = %d; want 3", method, condition.Type, condition.ObservedGeneration)
+			}
+		}
+		readyReason := reasonNotReady
+		if ready == corev1.ConditionTrue {
+			readyReason = reasonReady
+		}
+		progressingReason := reasonIdle
+		if progressing == corev1.ConditionTrue {
+			progressingReason = string(phase)
+		}
+		checkCondition(t, method, conditions, splcommon.ConditionReady, ready, readyReason)
+		checkCondition(t, method, conditions, splcommon.ConditionProgressing, progressing, progressingReason)
+		checkCondition(t, method, conditions, splcommon.ConditionDegraded, degraded, degradedReason)
+	}
+
+	test(splcommon.PhaseReady, nil, corev1.ConditionTrue, corev1.ConditionFalse, corev1.ConditionFalse, reasonAsExpected)
+	test(splcommon.PhaseReady, errors.New("boom"), corev1.ConditionFalse, corev1.ConditionFalse, corev1.ConditionTrue, reasonReconcileError)
+	test(splcommon.PhasePending, nil, corev1.ConditionFalse, corev1.ConditionTrue, corev1.ConditionFalse, reasonAsExpected)
+	test(splcommon.PhaseScalingUp, nil, corev1.ConditionFalse, corev1.ConditionTrue, corev1.ConditionFalse, reasonAsExpected)
+	test(splcommon.PhaseError, nil, corev1.ConditionFalse, corev1.ConditionFalse, corev1.ConditionTrue, string(splcommon.PhaseError))
+	test(splcommon.PhaseError, errors.New("boom"), corev1.ConditionFalse, corev1.ConditionFalse, corev1.ConditionTrue, reasonReconcileError)
+
+	// a Degraded condition carries the error message
+	var conditions []splcommon.Condition
+	setPhaseConditions(&conditions, 1, splcommon.PhaseError, errors.New("Failed to apply statefulset"))
+	if got := splcommon.GetCondition(conditions, splcommon.ConditionDegraded).Message; got != "Failed to apply statefulset" {
+		t.Errorf("setPhaseConditions() Degraded message = %s; want %s", got, "Failed to apply statefulset")
+	}
+}
+
+func TestSetAppsDeployedCondition(t *testing.T) {
+	appFrameworkConf := enterpriseApi.AppFrameworkSpec{
+		AppSources: []enterpriseApi.AppSourceSpec{{Name: "adminApps", Location: "adminAppsRepo"}},
+	}
+	appContext := enterpriseApi.AppDeploymentContext{
+		AppsSrcDeployStatus: map[string]enterpriseApi.AppSrcDeployInfo{
+			"adminApps": {
+				AppDeploymentInfoList: []enterpriseApi.AppDeploymentInfo{
+					{AppName: "app1.tgz", RepoState: enterpriseApi.RepoStateActive, DeployStatus: enterpriseApi.DeployStatusComplete},
+					{AppName: "app2.tgz", RepoState: enterpriseApi.RepoStateDeleted, DeployStatus: enterpriseApi.DeployStatusPending},
+				},
+			},
+		},
+	}
+
+	var conditions []splcommon.Condition
+	setAppsDeployedCondition(&conditions, 1, &appFrameworkConf, &appContext)
+	checkCondition(t, "setAppsDeployedCondition(complete)", conditions, splcommon.ConditionAppsDeployed, corev1.ConditionTrue, reasonAppsDeployed)
+
+	appContext.AppsSrcDeployStatus["adminApps"].AppDeploymentInfoList[0].DeployStatus = enterpriseApi.DeployStatusPending
+	setAppsDeployedCondition(&conditions, 1, &appFrameworkConf, &appContext)
+	checkCondition(t, "setAppsDeployedCondition(pending)", conditions, splcommon.ConditionAppsDeployed, corev1.ConditionFalse, reasonAppsDeploying)
+	if got := conditions[0].Message; got != "Waiting for apps: app1.tgz" {
+		t.Errorf("setAppsDeployedCondition(pending) message = %s; want %s", got, "Waiting for apps: app1.tgz")
+	}
+
+	appContext.AppsSrcDeployStatus["adminApps"].AppDeploymentInfoList[0].DeployStatus = enterpriseApi.DeployStatusError
+	setAppsDeployedCondition(&conditions, 1, &appFrameworkConf, &appContext)
+	checkCondition(t, "setAppsDeployedCondition(error)", conditions, splcommon.ConditionAppsDeployed, corev1.ConditionFalse, reasonAppsDeployFailed)
+
+	appFrameworkConf.AppSources = nil
+	setAppsDeployedCondition(&conditions, 1, &appFrameworkConf, &appContext)
+	checkCondition(t, "setAppsDeployedCondition(no apps)", conditions, splcommon.ConditionAppsDeployed, "", "")
+}
+
+func TestSetStatusConditions(t *testing.T) {
+	cm := enterpriseApi.ClusterMaster{
+		ObjectMeta: metav1.ObjectMeta{Name: "stack1", Namespace: "test", Generation: 2},
+	}
+	cm.Spec.SmartStore.VolList = []enterpriseApi.VolumeSpec{{Name: "msos_s2s3_vol", Endpoint: "https://s3-eu-west-2.amazonaws.com", Path: "testbucket-rs-london"}}
+	cm.Status.Phase = splcommon.PhaseReady
+	cm.Status.BundlePushTracker.NeedToPushMasterApps = true
+	setStatusConditions(&cm, nil)
+	checkCondition(t, "setStatusConditions(cm)", cm.Status.Conditions, splcommon.ConditionReady, corev1.ConditionTrue, reasonReady)
+	checkCondition(t, "setStatusConditions(cm)", cm.Status.Conditions, splcommon.ConditionSmartStoreConfigured, corev1.ConditionFalse, reasonSmartStorePending)
+	checkCondition(t, "setStatusConditions(cm)", cm.Status.Conditions, splcommon.ConditionBundlePushed, corev1.ConditionFalse, reasonBundlePushPending)
+	checkCondition(t, "setStatusConditions(cm)", cm.Status.Conditions, splcommon.ConditionAppsDeployed, "", "")
+
+	cm.Status.SmartStore = cm.Spec.SmartStore
+	cm.Status.BundlePushTracker.NeedToPushMasterApps = false
+	setStatusConditions(&cm, nil)
+	checkCondition(t, "setStatusConditions(cm)", cm.Status.Conditions, splcommon.ConditionSmartStoreConfigured, corev1.ConditionTrue, reasonSmartStoreApplied)
+	checkCondition(t, "setStatusConditions(cm)", cm.Status.Conditions, splcommon.ConditionBundlePushed, corev1.ConditionTrue, reasonBundlePushed)
+	// the volume without secretRef is reported as not verified by the access check
+	want := "SmartStore configuration is applied; volumes not verified: msos_s2s3_vol"
+	if got := splcommon.GetCondition(cm.Status.Conditions, splcommon.ConditionSmartStoreConfigured).Message; got != want {
+		t.Errorf("setStatusConditions(cm) SmartStoreConfigured message = %s; want %s", got, want)
+	}
+
+	idxc := enterpriseApi.IndexerCluster{}
+	idxc.Status.Phase = splcommon.PhaseReady
+	idxc.Status.IdxcPasswordChangedSecrets = map[string]bool{"splunk-stack1-indexer-secret-v1": true}
+	setStatusConditions(&idxc, nil)
+	checkCondition(t, "setStatusConditions(idxc)", idxc.Status.Conditions, splcommon.ConditionSecretsInSync, corev1.ConditionFalse, reasonSecretChangeProgress)
+	idxc.Status.IdxcPasswordChangedSecrets = map[string]bool{}
+	setStatusConditions(&idxc, nil)
+	checkCondition(t, "setStatusConditions(idxc)", idxc.Status.Conditions, splcommon.ConditionSecretsInSync, corev1.ConditionTrue, reasonSecretsInSync)
+
+	shc := enterpriseApi.SearchHeadCluster{}
+	shc.Status.Phase = splcommon.PhaseReady
+	shc.Status.AdminSecretChanged = []bool{true}
+	setStatusConditions(&shc, nil)
+	checkCondition(t, "setStatusConditions(shc)", shc.Status.Conditions, splcommon.ConditionSecretsInSync, corev1.ConditionFalse, reasonSecretChangeProgress)
+}
+
+func TestApplyStandaloneConditions(t *testing.T) {
+	c := spltest.NewMockClient()
+	cr := enterpriseApi.Standalone{
+		ObjectMeta: metav1.ObjectMeta{Name: "stack1", Namespace: "test"},
+	}
+
+	// a new standalone is progressing until its pods are ready
+	_, err := ApplyStandalone(c, nil, &cr)
+	if err != nil {
+		t.Errorf("ApplyStandalone() returned %v; want nil", err)
+	}
+	method := "ApplyStandalone(" + string(cr.Status.Phase) + ")"
+	checkCondition(t, method, cr.Status.Conditions, splcommon.ConditionReady, corev1.ConditionFalse, reasonNotReady)
+	checkCondition(t, method, cr.Status.Conditions, splcommon.ConditionProgressing, corev1.ConditionTrue, string(splcommon.PhasePending))
+	checkCondition(t, method, cr.Status.Conditions, splcommon.ConditionDegraded, corev1.ConditionFalse, reasonAsExpected)
+}
+
+func TestValidationFailureConditions(t *testing.T) {
+	c := spltest.NewMockClient()
+	want := "ImagePullPolicy must be one of \"Always\" or \"IfNotPresent\"; value=\"Invalid\""
+
+	standalone := enterpriseApi.Standalone{
+		ObjectMeta: metav1.ObjectMeta{Name: "stack1", Namespace: "test"},
+	}
+	standalone.Spec.ImagePullPolicy = "Invalid"
+	_, err := ApplyStandalone(c, nil, &standalone)
+	if err == nil {
+		t.Errorf("ApplyStandalone() should have returned an error for an invalid spec")
+	}
+	checkCondition(t, "ApplyStandalone", standalone.Status.Conditions, splcommon.ConditionDegraded, corev1.ConditionTrue, reasonReconcileError)
+	checkCondition(t, "ApplyStandalone", standalone.Status.Conditions, splcommon.ConditionReady, corev1.ConditionFalse, reasonNotReady)
+	if condition := splcommon.GetCondition(standalone.Status.Conditions, splcommon.ConditionDegraded); condition == nil || condition.Message != want {
+		t.Errorf("ApplyStandalone() should have set the Degraded condition message to the validation error %s", want)
+	}
+	if standalone.Status.Phase != splcommon.PhaseError {
+		t.Errorf("ApplyStandalone() phase = %s; want %s", standalone.Status.Phase, splcommon.PhaseError)
+	}
+
+	lm := enterpriseApi.LicenseMaster{
+		ObjectMeta: metav1.ObjectMeta{Name: "stack1", Namespace: "test"},
+	}
+	lm.Spec.ImagePullPolicy = "Invalid"
+	_, err = ApplyLicenseMaster(c, nil, &lm)
+	if err == nil {
+		t.Errorf("ApplyLicenseMaster() should have returned an error for an invalid spec")
+	}
+	checkCondition(t, "ApplyLicenseMaster", lm.Status.Conditions, splcommon.ConditionDegraded, corev1.ConditionTrue, reasonReconcileError)
+}
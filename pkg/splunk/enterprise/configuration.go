@@ -17,10 +17,12 @@ package enterprise
 import (
 	"context"
 	"fmt"
-	"sort"
+	"strconv"
+	"strings"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
@@ -194,11 +196,8 @@ func setVolumeDefaults(spec *enterpriseApi.CommonSplunkSpec) {
 	}
 }
 
-// validateCommonSplunkSpec checks validity and makes default updates to a CommonSplunkSpec, and returns error if something is wrong.
-func validateCommonSplunkSpec(spec *enterpriseApi.CommonSplunkSpec) error {
-	// if not specified via spec or env, image defaults to splunk/splunk
-	spec.Spec.Image = GetSplunkImage(spec.Spec.Image)
-
+// setCommonSplunkSpecDefaults sets default values for the parameters of a CommonSplunkSpec that are not provided.
+func setCommonSplunkSpecDefaults(spec *enterpriseApi.CommonSplunkSpec) {
 	defaultResources := corev1.ResourceRequirements{
 		Requests: corev1.ResourceList{
 			corev1.ResourceCPU:    resource.MustParse("0.1"),
@@ -210,6 +209,17 @@ func validateCommonSplunkSpec(spec *enterpriseApi.CommonSplunkSpec) error {
 		},
 	}
 
+	setVolumeDefaults(spec)
+
+	if spec.PvcRetentionPolicy == "" {
+		spec.PvcRetentionPolicy = splcommon.PvcRetentionPolicyDelete
+	}
+
+	splcommon.SetSpecDefaults(&spec.Spec, defaultResources)
+}
+
+// validateCommonSplunkSpec checks validity of a CommonSplunkSpec, and returns error if something is wrong.
+func validateCommonSplunkSpec(spec *enterpriseApi.CommonSplunkSpec) error {
 	if spec.LivenessInitialDelaySeconds < 0 {
 		return fmt.Errorf("Negative value (%d) is not allowed for Liveness probe intial delay", spec.LivenessInitialDelaySeconds)
 	}
@@ -218,9 +228,59 @@ func validateCommonSplunkSpec(spec *enterpriseApi.CommonSplunkSpec) error {
 		return fmt.Errorf("Negative value (%d) is not allowed for Readiness probe intial delay", spec.ReadinessInitialDelaySeconds)
 	}
 
-	setVolumeDefaults(spec)
+	err := validatePodDisruptionBudgetSpec(&spec.PodDisruptionBudget)
+	if err != nil {
+		return err
+	}
+
+	err = validateRolloutStrategy(&spec.RolloutStrategy)
+	if err != nil {
+		return err
+	}
+
+	switch spec.PvcRetentionPolicy {
+	case "", splcommon.PvcRetentionPolicyDelete, splcommon.PvcRetentionPolicyRetain, splcommon.PvcRetentionPolicyRetainUntilCRDeleted:
+	default:
+		return fmt.Errorf("pvcRetentionPolicy must be one of %s, %s or %s (not %s)", splcommon.PvcRetentionPolicyDelete,
+			splcommon.PvcRetentionPolicyRetain, splcommon.PvcRetentionPolicyRetainUntilCRDeleted, spec.PvcRetentionPolicy)
+	}
+
+	return splcommon.ValidateSpec(&spec.Spec)
+}
+
+// validatePodDisruptionBudgetSpec checks the disruptions allowed for the pods of a Splunk Enterprise resource.
+func validatePodDisruptionBudgetSpec(pdb *enterpriseApi.PodDisruptionBudgetSpec) error {
+	if pdb.MaxUnavailable != nil && pdb.MinAvailable != nil {
+		return fmt.Errorf("podDisruptionBudget maxUnavailable and minAvailable can not be used together")
+	}
 
-	return splcommon.ValidateSpec(&spec.Spec, defaultResources)
+	for name, value := range map[string]*intstr.IntOrString{"maxUnavailable": pdb.MaxUnavailable, "minAvailable": pdb.MinAvailable} {
+		if value == nil {
+			continue
+		}
+		n, err := intstr.GetValueFromIntOrPercent(value, 100, true)
+		if err != nil {
+			return fmt.Errorf("Invalid podDisruptionBudget %s: %v", name, err)
+		}
+		if n < 0 {
+			return fmt.Errorf("Negative value (%s) is not allowed for podDisruptionBudget %s", value.String(), name)
+		}
+	}
+
+	return nil
+}
+
+// validateRolloutStrategy checks how the pods of a Splunk Enterprise resource are recycled for updates.
+func validateRolloutStrategy(strategy *splcommon.RolloutStrategy) error {
+	if strategy.MaxUnavailable < 0 {
+		return fmt.Errorf("Negative value (%d) is not allowed for rolloutStrategy maxUnavailable", strategy.MaxUnavailable)
+	}
+
+	if strategy.Partition != nil && *strategy.Partition < 0 {
+		return fmt.Errorf("Negative value (%d) is not allowed for rolloutStrategy partition", *strategy.Partition)
+	}
+
+	return nil
 }
 
 // getSplunkDefaults returns a Kubernetes ConfigMap containing defaults for a Splunk Enterprise resource.
@@ -236,6 +296,38 @@ func getSplunkDefaults(identifier, namespace string, instanceType InstanceType,
 	}
 }
 
+// getMultisiteDefaults returns the ansible defaults rendered from the multisite spec of a ClusterMaster or an IndexerCluster.
+// An empty string is returned when multisite clustering is not configured through the spec.
+func getMultisiteDefaults(cr splcommon.MetaObject) string {
+	switch cr := cr.(type) {
+	case *enterpriseApi.ClusterMaster:
+		multisite := &cr.Spec.Multisite
+		if len(multisite.AvailableSites) == 0 {
+			return ""
+		}
+		return fmt.Sprintf(`splunk:
+  site: %s
+  multisite_master: localhost
+  all_sites: %s
+  multisite_replication_factor_origin: %d
+  multisite_replication_factor_total: %d
+  multisite_search_factor_origin: %d
+  multisite_search_factor_total: %d
+`, multisite.Site, strings.Join(multisite.AvailableSites, ","),
+			multisite.SiteReplicationFactor.Origin, multisite.SiteReplicationFactor.Total,
+			multisite.SiteSearchFactor.Origin, multisite.SiteSearchFactor.Total)
+	case *enterpriseApi.IndexerCluster:
+		if cr.Spec.Site == "" {
+			return ""
+		}
+		return fmt.Sprintf(`splunk:
+  site: %s
+  multisite_master: %s
+`, cr.Spec.Site, GetSplunkServiceName(SplunkClusterMaster, cr.Spec.ClusterMasterRef.Name, false))
+	}
+	return ""
+}
+
 // getSplunkPorts returns a map of ports to use for Splunk instances.
 func getSplunkPorts(instanceType InstanceType) map[string]int {
 	result := map[string]int{
@@ -417,7 +509,7 @@ func getSplunkStatefulSet(client splcommon.ControllerClient, cr splcommon.MetaOb
 					SchedulerName: spec.SchedulerName,
 					Containers: []corev1.Container{
 						{
-							Image:           spec.Image,
+							Image:           GetSplunkImage(spec.Image),
 							ImagePullPolicy: corev1.PullPolicy(spec.ImagePullPolicy),
 							Name:            "splunk",
 							Ports:           ports,
@@ -462,18 +554,50 @@ func getSplunkStatefulSet(client splcommon.ControllerClient, cr splcommon.MetaOb
 	return statefulSet, nil
 }
 
-// getAppListingConfigMap returns the App listing configMap, if it exists and applicable for that instanceType
-func getAppListingConfigMap(client splcommon.ControllerClient, cr splcommon.MetaObject, instanceType InstanceType) *corev1.ConfigMap {
-	var configMap *corev1.ConfigMap
+// getSplunkPodDisruptionBudget returns a Kubernetes PodDisruptionBudget object for the pods of a Splunk Enterprise StatefulSet.
+// The number of pods that can be evicted at the same time defaults to defaultMaxUnavailable, unless provided in the spec.
+func getSplunkPodDisruptionBudget(cr splcommon.MetaObject, spec *enterpriseApi.CommonSplunkSpec, instanceType InstanceType, defaultMaxUnavailable int32) *policyv1beta1.PodDisruptionBudget {
+	pdb := &policyv1beta1.PodDisruptionBudget{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "PodDisruptionBudget",
+			APIVersion: "policy/v1beta1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      GetSplunkStatefulsetName(instanceType, cr.GetName()),
+			Namespace: cr.GetNamespace(),
+		},
+		Spec: policyv1beta1.PodDisruptionBudgetSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: getSplunkLabels(cr.GetName(), instanceType, spec.ClusterMasterRef.Name),
+			},
+		},
+	}
 
-	// ToDo: Exclude MC, once it's own CR is available
-	if instanceType != SplunkIndexer && instanceType != SplunkSearchHead && instanceType != SplunkMonitoringConsole {
-		appsConfigMapName := GetSplunkAppsConfigMapName(cr.GetName(), cr.GetObjectKind().GroupVersionKind().Kind)
-		namespacedName := types.NamespacedName{Namespace: cr.GetNamespace(), Name: appsConfigMapName}
-		configMap, _ = splctrl.GetConfigMap(client, namespacedName)
+	if spec.PodDisruptionBudget.MinAvailable != nil {
+		minAvailable := *spec.PodDisruptionBudget.MinAvailable
+		pdb.Spec.MinAvailable = &minAvailable
+	} else if spec.PodDisruptionBudget.MaxUnavailable != nil {
+		maxUnavailable := *spec.PodDisruptionBudget.MaxUnavailable
+		pdb.Spec.MaxUnavailable = &maxUnavailable
+	} else {
+		maxUnavailable := intstr.FromInt(int(defaultMaxUnavailable))
+		pdb.Spec.MaxUnavailable = &maxUnavailable
 	}
 
-	return configMap
+	// make Splunk Enterprise object the owner
+	pdb.SetOwnerReferences(append(pdb.GetOwnerReferences(), splcommon.AsOwner(cr, true)))
+
+	return pdb
+}
+
+// applySplunkPodDisruptionBudget creates or updates the PodDisruptionBudget of a Splunk Enterprise StatefulSet,
+// or deletes it if it has been disabled in the spec.
+func applySplunkPodDisruptionBudget(client splcommon.ControllerClient, cr splcommon.MetaObject, spec *enterpriseApi.CommonSplunkSpec, instanceType InstanceType, defaultMaxUnavailable int32) error {
+	if spec.PodDisruptionBudget.Disabled {
+		namespacedName := types.NamespacedName{Namespace: cr.GetNamespace(), Name: GetSplunkStatefulsetName(instanceType, cr.GetName())}
+		return splctrl.DeletePodDisruptionBudget(client, namespacedName)
+	}
+	return splctrl.ApplyPodDisruptionBudget(client, getSplunkPodDisruptionBudget(cr, spec, instanceType, defaultMaxUnavailable))
 }
 
 // getSmartstoreConfigMap returns the smartstore configMap, if it exists and applicable for that instanceType
@@ -489,6 +613,40 @@ func getSmartstoreConfigMap(client splcommon.ControllerClient, cr splcommon.Meta
 	return configMap
 }
 
+// addSmartstoreCredentialFiles mounts the service account keys of the GCS volumes of a SmartStore spec in the
+// etc/auth directory of the Splunk containers, where the remote.gs.credential_file of the volumes is looked up
+func addSmartstoreCredentialFiles(podTemplateSpec *corev1.PodTemplateSpec, smartstore *enterpriseApi.SmartStoreSpec) {
+	secretVolDefaultMode := int32(corev1.SecretVolumeSourceDefaultMode)
+	for i, volume := range smartstore.VolList {
+		if volume.Type != "gcs" || volume.SecretRef == "" {
+			continue
+		}
+
+		// volume names of SmartStore may not be valid pod volume names
+		name := fmt.Sprintf("mnt-splunk-smartstore-%d", i)
+		fileName := fmt.Sprintf(smartstoreCredentialFileTemplate, volume.Name)
+		podTemplateSpec.Spec.Volumes = append(podTemplateSpec.Spec.Volumes, corev1.Volume{
+			Name: name,
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName:  volume.SecretRef,
+					DefaultMode: &secretVolDefaultMode,
+					Items:       []corev1.KeyToPath{{Key: gcsServiceAccountKey, Path: fileName}},
+				},
+			},
+		})
+		for idx := range podTemplateSpec.Spec.Containers {
+			containerSpec := &podTemplateSpec.Spec.Containers[idx]
+			containerSpec.VolumeMounts = append(containerSpec.VolumeMounts, corev1.VolumeMount{
+				Name:      name,
+				MountPath: "/opt/splunk/etc/auth/" + fileName,
+				SubPath:   fileName,
+				ReadOnly:  true,
+			})
+		}
+	}
+}
+
 // updateSplunkPodTemplateWithConfig modifies the podTemplateSpec object based on configuration of the Splunk Enterprise resource.
 func updateSplunkPodTemplateWithConfig(client splcommon.ControllerClient, podTemplateSpec *corev1.PodTemplateSpec, cr splcommon.MetaObject, spec *enterpriseApi.CommonSplunkSpec, instanceType InstanceType, extraEnv []corev1.EnvVar, secretToMount string) {
 
@@ -532,8 +690,9 @@ func updateSplunkPodTemplateWithConfig(client splcommon.ControllerClient, podTem
 	// Explicitly set the default value here so we can compare for changes correctly with current statefulset.
 	configMapVolDefaultMode := int32(corev1.ConfigMapVolumeSourceDefaultMode)
 
-	// add inline defaults to all splunk containers other than MC(where CR spec defaults are not needed)
-	if spec.Defaults != "" && instanceType != SplunkMonitoringConsole {
+	// add inline and multisite defaults to all splunk containers other than MC(where CR spec defaults are not needed)
+	multisiteDefaults := getMultisiteDefaults(cr)
+	if (spec.Defaults != "" || multisiteDefaults != "") && instanceType != SplunkMonitoringConsole {
 		configMapName := GetSplunkDefaultsName(cr.GetName(), instanceType)
 		addSplunkVolumeToTemplate(podTemplateSpec, "mnt-splunk-defaults", "/mnt/splunk-defaults", corev1.VolumeSource{
 			ConfigMap: &corev1.ConfigMapVolumeSource{
@@ -581,19 +740,6 @@ func updateSplunkPodTemplateWithConfig(client splcommon.ControllerClient, podTem
 		}
 	}
 
-	appListingConfigMap := getAppListingConfigMap(client, cr, instanceType)
-	if appListingConfigMap != nil {
-		appVolumeSource := getVolumeSourceMountFromConfigMapData(appListingConfigMap, &configMapVolDefaultMode)
-		addSplunkVolumeToTemplate(podTemplateSpec, "mnt-app-listing", appConfLocationOnPod, appVolumeSource)
-
-		// ToDo: for Phase-2, to install the new apps, always reset the pod.(need to change the behavior for phase-3)
-		// Once the apps are installed, and on a reconcile entry triggered by polling interval expiry, if there is no new
-		// App changes on remote store, then the config map data is erased. In such case, no need to reset the Pod
-		if len(appListingConfigMap.Data) > 0 {
-			podTemplateSpec.ObjectMeta.Annotations[appListingRev] = appListingConfigMap.ResourceVersion
-		}
-	}
-
 	// update security context
 	runAsUser := int64(41812)
 	fsGroup := int64(41812)
@@ -602,28 +748,15 @@ func updateSplunkPodTemplateWithConfig(client splcommon.ControllerClient, podTem
 		FSGroup:   &fsGroup,
 	}
 
-	var additionalDelayForAppInstallation int32
-	var appListingFiles []string
-
-	if appListingConfigMap != nil {
-		for key := range appListingConfigMap.Data {
-			if key != appsUpdateToken {
-				appListingFiles = append(appListingFiles, key)
-			}
-		}
-		// Always sort the slice, so that map entries are ordered, to avoid pod resets
-		sort.Strings(appListingFiles)
-
-		if instanceType == SplunkDeployer || instanceType == SplunkClusterMaster || instanceType == SplunkStandalone || instanceType == SplunkLicenseMaster {
-			additionalDelayForAppInstallation = int32(maxSplunkAppsInstallationDelaySecs)
-		}
-	}
-
-	livenessProbe := getLivenessProbe(cr, instanceType, spec, additionalDelayForAppInstallation)
+	livenessProbe := getLivenessProbe(cr, instanceType, spec, 0)
 	readinessProbe := getReadinessProbe(cr, instanceType, spec, 0)
 
 	// prepare defaults variable
 	splunkDefaults := "/mnt/splunk-secrets/default.yml"
+	// Typed multisite settings take precedence over the free-form defaults
+	if multisiteDefaults != "" && instanceType != SplunkMonitoringConsole {
+		splunkDefaults = fmt.Sprintf("%s%s,%s", "/mnt/splunk-defaults/", multisiteDefaultsFile, splunkDefaults)
+	}
 	// Check for apps defaults and add it to only the standalone or deployer/cm instances
 	if spec.DefaultsURLApps != "" &&
 		(instanceType == SplunkDeployer ||
@@ -639,12 +772,6 @@ func updateSplunkPodTemplateWithConfig(client splcommon.ControllerClient, podTem
 		splunkDefaults = fmt.Sprintf("%s,%s", "/mnt/splunk-defaults/default.yml", splunkDefaults)
 	}
 
-	if appListingConfigMap != nil {
-		for _, fileName := range appListingFiles {
-			splunkDefaults = fmt.Sprintf("%s%s,%s", appConfLocationOnPod, fileName, splunkDefaults)
-		}
-	}
-
 	// prepare container env variables
 	role := instanceType.ToRole()
 	if instanceType == SplunkStandalone && len(spec.ClusterMasterRef.Name) > 0 {
@@ -885,6 +1012,9 @@ func initAppFrameWorkContext(appFrameworkConf *enterpriseApi.AppFrameworkSpec, a
 		appStatusContext.AppsSrcDeployStatus = make(map[string]enterpriseApi.AppSrcDeployInfo)
 	}
 
+	// Set the value in status field to be same as that in spec.
+	appStatusContext.AppsRepoStatusPollInterval = appFrameworkConf.AppsRepoPollInterval
+
 	for _, vol := range appFrameworkConf.VolList {
 		if _, ok := splclient.S3Clients[vol.Provider]; !ok {
 			splclient.RegisterS3Client(vol.Provider)
@@ -907,6 +1037,97 @@ func getAppSrcScope(appFrameworkConf *enterpriseApi.AppFrameworkSpec, appSrcName
 	return appFrameworkConf.Defaults.Scope
 }
 
+// getAppSrcDeletePolicy returns the delete policy of a given appSource, which defaults to Delete
+func getAppSrcDeletePolicy(appFrameworkConf *enterpriseApi.AppFrameworkSpec, appSrcName string) string {
+	for _, appSrc := range appFrameworkConf.AppSources {
+		if appSrc.Name == appSrcName {
+			if appSrc.DeletePolicy != "" {
+				return appSrc.DeletePolicy
+			}
+
+			break
+		}
+	}
+
+	if appFrameworkConf.Defaults.DeletePolicy != "" {
+		return appFrameworkConf.Defaults.DeletePolicy
+	}
+	return enterpriseApi.DeletePolicyDelete
+}
+
+// getAppSrcSignatureSecretRef returns the name of the secret verifying the signatures of the apps of a given appSource
+func getAppSrcSignatureSecretRef(appFrameworkConf *enterpriseApi.AppFrameworkSpec, appSrcName string) string {
+	for _, appSrc := range appFrameworkConf.AppSources {
+		if appSrc.Name == appSrcName {
+			if appSrc.SignatureSecretRef != "" {
+				return appSrc.SignatureSecretRef
+			}
+
+			break
+		}
+	}
+
+	return appFrameworkConf.Defaults.SignatureSecretRef
+}
+
+// getAppSrcPremiumAppsProps returns the properties of the premium Apps of a given appSource
+func getAppSrcPremiumAppsProps(appFrameworkConf *enterpriseApi.AppFrameworkSpec, appSrcName string) enterpriseApi.PremiumAppsProps {
+	for _, appSrc := range appFrameworkConf.AppSources {
+		if appSrc.Name == appSrcName {
+			if appSrc.PremiumAppsProps.Type != "" {
+				return appSrc.PremiumAppsProps
+			}
+
+			break
+		}
+	}
+
+	return appFrameworkConf.Defaults.PremiumAppsProps
+}
+
+// getAppSrcInstallTimeout returns the install timeout in seconds of the Apps of a given appSource, 0 when not set
+func getAppSrcInstallTimeout(appFrameworkConf *enterpriseApi.AppFrameworkSpec, appSrcName string) int {
+	for _, appSrc := range appFrameworkConf.AppSources {
+		if appSrc.Name == appSrcName {
+			if appSrc.InstallTimeoutSeconds != 0 {
+				return appSrc.InstallTimeoutSeconds
+			}
+
+			break
+		}
+	}
+
+	return appFrameworkConf.Defaults.InstallTimeoutSeconds
+}
+
+// getAppSrcDependsOn returns the names of the appSources a given appSource depends on
+func getAppSrcDependsOn(appFrameworkConf *enterpriseApi.AppFrameworkSpec, appSrcName string) []string {
+	for _, appSrc := range appFrameworkConf.AppSources {
+		if appSrc.Name == appSrcName {
+			return appSrc.DependsOn
+		}
+	}
+
+	return nil
+}
+
+// getPinnedAppObjectHash returns the object hash an App of a given appSource is pinned to, if any
+func getPinnedAppObjectHash(appFrameworkConf *enterpriseApi.AppFrameworkSpec, appSrcName string, appName string) string {
+	for _, appSrc := range appFrameworkConf.AppSources {
+		if appSrc.Name != appSrcName {
+			continue
+		}
+		for _, app := range appSrc.Apps {
+			if app.Name == appName {
+				return app.ObjectHash
+			}
+		}
+		break
+	}
+
+	return ""
+}
+
 // CheckIfAppSrcExistsInConfig returns if the given appSource is available in the configuration or not
 func CheckIfAppSrcExistsInConfig(appFrameworkConf *enterpriseApi.AppFrameworkSpec, appSrcName string) bool {
 	for _, appSrc := range appFrameworkConf.AppSources {
@@ -953,17 +1174,43 @@ func validateSplunkAppSources(appFramework *enterpriseApi.AppFrameworkSpec, loca
 		}
 
 		if appSrc.Scope != "" {
-			if localScope && appSrc.Scope != enterpriseApi.ScopeLocal {
+			if localScope && appSrc.Scope != enterpriseApi.ScopeLocal && appSrc.Scope != enterpriseApi.ScopePremiumApps {
 				return fmt.Errorf("Invalid scope for App Source: %s. Only local scope is supported for this kind of CR", appSrc.Name)
 			}
 
-			if !(appSrc.Scope == enterpriseApi.ScopeLocal || appSrc.Scope == enterpriseApi.ScopeCluster || appSrc.Scope == enterpriseApi.ScopeClusterWithPreConfig) {
-				return fmt.Errorf("Scope for App Source: %s should be either local or cluster or clusterWithPreConfig", appSrc.Name)
+			if !(appSrc.Scope == enterpriseApi.ScopeLocal || appSrc.Scope == enterpriseApi.ScopeCluster || appSrc.Scope == enterpriseApi.ScopeClusterWithPreConfig || appSrc.Scope == enterpriseApi.ScopePremiumApps) {
+				return fmt.Errorf("Scope for App Source: %s should be either local or cluster or clusterWithPreConfig or premiumApps", appSrc.Name)
 			}
 		} else if appFramework.Defaults.Scope == "" {
 			return fmt.Errorf("App Source scope is missing for: %s", appSrc.Name)
 		}
 
+		if getAppSrcScope(appFramework, appSrc.Name) == enterpriseApi.ScopePremiumApps {
+			err := validatePremiumAppsProps(getAppSrcPremiumAppsProps(appFramework, appSrc.Name))
+			if err != nil {
+				return fmt.Errorf("Invalid premium Apps properties for App Source: %s. %s", appSrc.Name, err)
+			}
+		}
+
+		if appSrc.InstallTimeoutSeconds < 0 {
+			return fmt.Errorf("Install timeout for App Source: %s should not be negative", appSrc.Name)
+		}
+
+		if appSrc.DeletePolicy != "" && appSrc.DeletePolicy != enterpriseApi.DeletePolicyDelete && appSrc.DeletePolicy != enterpriseApi.DeletePolicyRetain {
+			return fmt.Errorf("Delete policy for App Source: %s should be either Delete or Retain", appSrc.Name)
+		}
+
+		duplicateAppNameChecker := make(map[string]bool)
+		for _, app := range appSrc.Apps {
+			if !isAppExtentionValid(app.Name) || strings.Contains(app.Name, "/") {
+				return fmt.Errorf("Invalid App name: %s for App Source: %s. It should be the name of a .spl or .tgz package of the location", app.Name, appSrc.Name)
+			}
+			if _, ok := duplicateAppNameChecker[app.Name]; ok {
+				return fmt.Errorf("Multiple apps with the name %s is not allowed for App Source: %s", app.Name, appSrc.Name)
+			}
+			duplicateAppNameChecker[app.Name] = true
+		}
+
 		if _, ok := duplicateAppSourceStorageChecker[vol+appSrc.Location]; ok {
 			return fmt.Errorf("Duplicate App Source configured for Volume: %s, and Location: %s combo. Remove the duplicate entry and reapply the configuration", vol, appSrc.Location)
 		}
@@ -971,14 +1218,22 @@ func validateSplunkAppSources(appFramework *enterpriseApi.AppFrameworkSpec, loca
 
 	}
 
-	if localScope && appFramework.Defaults.Scope != "" && appFramework.Defaults.Scope != enterpriseApi.ScopeLocal {
+	if localScope && appFramework.Defaults.Scope != "" && appFramework.Defaults.Scope != enterpriseApi.ScopeLocal && appFramework.Defaults.Scope != enterpriseApi.ScopePremiumApps {
 		return fmt.Errorf("Invalid scope for defaults config. Only local scope is supported for this kind of CR")
 	}
 
-	if appFramework.Defaults.Scope != "" && appFramework.Defaults.Scope != enterpriseApi.ScopeLocal && appFramework.Defaults.Scope != enterpriseApi.ScopeCluster && appFramework.Defaults.Scope != enterpriseApi.ScopeClusterWithPreConfig {
+	if appFramework.Defaults.Scope != "" && appFramework.Defaults.Scope != enterpriseApi.ScopeLocal && appFramework.Defaults.Scope != enterpriseApi.ScopeCluster && appFramework.Defaults.Scope != enterpriseApi.ScopeClusterWithPreConfig && appFramework.Defaults.Scope != enterpriseApi.ScopePremiumApps {
 		return fmt.Errorf("Scope for defaults should be either local Or cluster, but configured as: %s", appFramework.Defaults.Scope)
 	}
 
+	if appFramework.Defaults.InstallTimeoutSeconds < 0 {
+		return fmt.Errorf("Install timeout for defaults should not be negative, but configured as: %d", appFramework.Defaults.InstallTimeoutSeconds)
+	}
+
+	if appFramework.Defaults.DeletePolicy != "" && appFramework.Defaults.DeletePolicy != enterpriseApi.DeletePolicyDelete && appFramework.Defaults.DeletePolicy != enterpriseApi.DeletePolicyRetain {
+		return fmt.Errorf("Delete policy for defaults should be either Delete or Retain, but configured as: %s", appFramework.Defaults.DeletePolicy)
+	}
+
 	if appFramework.Defaults.VolName != "" {
 		_, err := splclient.CheckIfVolumeExists(appFramework.VolList, appFramework.Defaults.VolName)
 		if err != nil {
@@ -986,6 +1241,75 @@ func validateSplunkAppSources(appFramework *enterpriseApi.AppFrameworkSpec, loca
 		}
 	}
 
+	return validateAppSrcDependencies(appFramework)
+}
+
+// validatePremiumAppsProps validates the properties of the premium Apps of an App source
+func validatePremiumAppsProps(premiumAppsProps enterpriseApi.PremiumAppsProps) error {
+	if premiumAppsProps.Type != enterpriseApi.PremiumAppsTypeEs {
+		return fmt.Errorf("Type should be %s, but configured as: %s", enterpriseApi.PremiumAppsTypeEs, premiumAppsProps.Type)
+	}
+
+	switch premiumAppsProps.EsDefaults.SslEnablement {
+	case "", enterpriseApi.SslEnablementStrict, enterpriseApi.SslEnablementAuto, enterpriseApi.SslEnablementIgnore:
+		return nil
+	default:
+		return fmt.Errorf("sslEnablement should be either strict or auto or ignore, but configured as: %s", premiumAppsProps.EsDefaults.SslEnablement)
+	}
+}
+
+// validateAppSrcDependencies checks that the App sources only depend on other App sources of the config, without cycles
+func validateAppSrcDependencies(appFramework *enterpriseApi.AppFrameworkSpec) error {
+	dependsOn := make(map[string][]string)
+	for _, appSrc := range appFramework.AppSources {
+		dependsOn[appSrc.Name] = appSrc.DependsOn
+	}
+	for _, appSrc := range appFramework.AppSources {
+		for _, dependency := range appSrc.DependsOn {
+			if _, ok := dependsOn[dependency]; !ok {
+				return fmt.Errorf("App Source: %s depends on App Source: %s, which is not configured", appSrc.Name, dependency)
+			}
+		}
+	}
+
+	// a dependency is visiting while its own dependencies are visited, so that reaching it again is a cycle
+	const visiting, visited = 1, 2
+	state := make(map[string]int)
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visiting:
+			return fmt.Errorf("App Source: %s has a circular dependency", name)
+		case visited:
+			return nil
+		}
+		state[name] = visiting
+		for _, dependency := range dependsOn[name] {
+			if err := visit(dependency); err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+		return nil
+	}
+	for _, appSrc := range appFramework.AppSources {
+		if err := visit(appSrc.Name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validatePremiumAppsScope checks that no App source has the premiumApps scope, for the kinds of CR which do not
+// run premium Apps
+func validatePremiumAppsScope(appFramework *enterpriseApi.AppFrameworkSpec) error {
+	for _, appSrc := range appFramework.AppSources {
+		if getAppSrcScope(appFramework, appSrc.Name) == enterpriseApi.ScopePremiumApps {
+			return fmt.Errorf("Invalid scope for App Source: %s. The premiumApps scope is not supported for this kind of CR", appSrc.Name)
+		}
+	}
+
 	return nil
 }
 
@@ -995,8 +1319,23 @@ func isAppFrameworkConfigured(appFramework *enterpriseApi.AppFrameworkSpec) bool
 	return !(appFramework == nil || appFramework.AppSources == nil)
 }
 
+// setAppFrameworkDefaults sets the default polling interval of the app repositories, and keeps it within the supported range
+func setAppFrameworkDefaults(appFramework *enterpriseApi.AppFrameworkSpec) {
+	if !isAppFrameworkConfigured(appFramework) {
+		return
+	}
+
+	if appFramework.AppsRepoPollInterval == 0 {
+		appFramework.AppsRepoPollInterval = splcommon.DefaultAppsRepoPollInterval
+	} else if appFramework.AppsRepoPollInterval < splcommon.MinAppsRepoPollInterval {
+		appFramework.AppsRepoPollInterval = splcommon.MinAppsRepoPollInterval
+	} else if appFramework.AppsRepoPollInterval > splcommon.MaxAppsRepoPollInterval {
+		appFramework.AppsRepoPollInterval = splcommon.MaxAppsRepoPollInterval
+	}
+}
+
 // ValidateAppFrameworkSpec checks and validates the Apps Frame Work config
-func ValidateAppFrameworkSpec(appFramework *enterpriseApi.AppFrameworkSpec, appContext *enterpriseApi.AppDeploymentContext, localScope bool) error {
+func ValidateAppFrameworkSpec(appFramework *enterpriseApi.AppFrameworkSpec, localScope bool) error {
 	var err error
 	if !isAppFrameworkConfigured(appFramework) {
 		return nil
@@ -1006,20 +1345,6 @@ func ValidateAppFrameworkSpec(appFramework *enterpriseApi.AppFrameworkSpec, appC
 
 	scopedLog.Info("configCheck", "scope", localScope)
 
-	// Set the value in status field to be same as that in spec.
-	appContext.AppsRepoStatusPollInterval = appFramework.AppsRepoPollInterval
-
-	if appContext.AppsRepoStatusPollInterval == 0 {
-		scopedLog.Error(err, "appsRepoPollIntervalSeconds is not configured", "Setting it to the default value(seconds)", splcommon.DefaultAppsRepoPollInterval)
-		appContext.AppsRepoStatusPollInterval = splcommon.DefaultAppsRepoPollInterval
-	} else if appFramework.AppsRepoPollInterval < splcommon.MinAppsRepoPollInterval {
-		scopedLog.Error(err, "configured appsRepoPollIntervalSeconds is too small", "configured value", appFramework.AppsRepoPollInterval, "Setting it to the default min. value(seconds)", splcommon.MinAppsRepoPollInterval)
-		appContext.AppsRepoStatusPollInterval = splcommon.MinAppsRepoPollInterval
-	} else if appFramework.AppsRepoPollInterval > splcommon.MaxAppsRepoPollInterval {
-		scopedLog.Error(err, "configured appsRepoPollIntervalSeconds is too large", "configured value", appFramework.AppsRepoPollInterval, "Setting it to the default max. value(seconds)", splcommon.MaxAppsRepoPollInterval, "seconds", nil)
-		appContext.AppsRepoStatusPollInterval = splcommon.MaxAppsRepoPollInterval
-	}
-
 	err = validateRemoteVolumeSpec(appFramework.VolList, true)
 	if err != nil {
 		return err
@@ -1050,7 +1375,8 @@ func validateRemoteVolumeSpec(volList []enterpriseApi.VolumeSpec, isAppFramework
 		if volume.Name == "" {
 			return fmt.Errorf("Volume name is missing for volume at : %d", i)
 		}
-		if volume.Endpoint == "" {
+		// apps of a PersistentVolumeClaim are not accessed through an endpoint, nor are the GCS volumes of Smartstore
+		if volume.Endpoint == "" && !(isAppFramework && volume.Type == "pvc") && !(!isAppFramework && volume.Type == "gcs") {
 			return fmt.Errorf("Volume Endpoint URI is missing")
 		}
 		if volume.Path == "" {
@@ -1061,16 +1387,18 @@ func validateRemoteVolumeSpec(volList []enterpriseApi.VolumeSpec, isAppFramework
 			scopedLog.Info("No valid SecretRef for volume.", "volumeName", volume.Name)
 		}
 
-		// provider is used in App framework to pick the S3 client(aws, minio), and is not applicable to Smartstore
-		// For now, Smartstore supports only S3, which is by default.
+		// provider is used in App framework to pick the remote storage client(aws, minio, azure, gcp, kubernetes, http), and is not applicable to Smartstore
+		// Smartstore supports S3, which is by default, Azure Blob and GCS.
 		if isAppFramework {
 			if !isValidStorageType(volume.Type) {
-				return fmt.Errorf("Remote volume type is invalid. Only storageType=s3 is supported")
+				return fmt.Errorf("Remote volume type is invalid. Only storageType=s3, azure-blob (or blob), gcs, pvc or http is supported")
 			}
 
-			if !isValidProvider(volume.Provider) {
-				return fmt.Errorf("S3 Provider is invalid")
+			if !isValidProvider(volume.Type, volume.Provider) {
+				return fmt.Errorf("Provider %s is invalid for storageType=%s", volume.Provider, volume.Type)
 			}
+		} else if volume.Type != "" && volume.Type != "s3" && !isAzureBlobStorageType(volume.Type) && volume.Type != "gcs" {
+			return fmt.Errorf("Remote volume type is invalid. Only storageType=s3, azure-blob (or blob) or gcs is supported by Smartstore")
 		}
 	}
 	return nil
@@ -1078,12 +1406,29 @@ func validateRemoteVolumeSpec(volList []enterpriseApi.VolumeSpec, isAppFramework
 
 // isValidStorageType checks if the storage type specified is valid and supported
 func isValidStorageType(storage string) bool {
-	return storage != "" && storage == "s3"
+	return storage == "s3" || isAzureBlobStorageType(storage) || storage == "gcs" || storage == "pvc" || storage == "http"
+}
+
+// isAzureBlobStorageType checks if the storage type is Azure Blob, named azure-blob, or blob for short
+func isAzureBlobStorageType(storage string) bool {
+	return storage == "azure-blob" || storage == "blob"
 }
 
-// isValidProvider checks if the provider specified is valid and supported
-func isValidProvider(provider string) bool {
-	return provider != "" && (provider == "aws" || provider == "minio")
+// isValidProvider checks if the provider specified is valid and supported by the storage type
+func isValidProvider(storage string, provider string) bool {
+	switch storage {
+	case "s3":
+		return provider == "aws" || provider == "minio"
+	case "blob", "azure-blob":
+		return provider == "azure"
+	case "gcs":
+		return provider == "gcp"
+	case "pvc":
+		return provider == "kubernetes"
+	case "http":
+		return provider == "http"
+	}
+	return false
 }
 
 // validateSplunkIndexesSpec validates the smartstore index spec
@@ -1101,18 +1446,57 @@ func validateSplunkIndexesSpec(smartstore *enterpriseApi.SmartStoreSpec) error {
 			return fmt.Errorf("Duplicate index name detected: %s.Remove the duplicate entry and reapply the configuration", index.Name)
 		}
 		duplicateChecker[index.Name] = true
-		if index.VolName == "" && smartstore.Defaults.VolName == "" {
-			return fmt.Errorf("volumeName is missing for index: %s", index.Name)
-		}
 
-		if index.VolName != "" {
-			_, err := splclient.CheckIfVolumeExists(smartstore.VolList, index.VolName)
-			if err != nil {
-				return fmt.Errorf("Invalid configuration for index: %s. %s", index.Name, err)
+		if index.Local {
+			if index.VolName != "" || index.RemotePath != "" {
+				return fmt.Errorf("Invalid configuration for index: %s. A local index can not have a volumeName or remotePath", index.Name)
+			}
+			if index.MaxGlobalDataSizeMB != 0 || index.MaxGlobalRawDataSizeMB != 0 || index.HotlistRecencySecs != 0 || index.HotlistBloomFilterRecencyHours != 0 {
+				return fmt.Errorf("Invalid configuration for index: %s. maxGlobalDataSizeMB, maxGlobalRawDataSizeMB, hotlistRecencySecs and hotlistBloomFilterRecencyHours only apply to Smartstore indexes", index.Name)
+			}
+		} else {
+			if index.VolName == "" && smartstore.Defaults.VolName == "" {
+				return fmt.Errorf("volumeName is missing for index: %s", index.Name)
 			}
+
+			if index.VolName != "" {
+				_, err := splclient.CheckIfVolumeExists(smartstore.VolList, index.VolName)
+				if err != nil {
+					return fmt.Errorf("Invalid configuration for index: %s. %s", index.Name, err)
+				}
+			}
+
+			// the buckets of the Smartstore indexes are evicted from the cache, instead of rolled by their local size
+			if index.MaxTotalDataSizeMB != 0 || index.MaxWarmDBCount != 0 || index.HomePathMaxDataSizeMB != 0 || index.ColdPathMaxDataSizeMB != 0 {
+				return fmt.Errorf("Invalid configuration for index: %s. maxTotalDataSizeMB, maxWarmDBCount, homePathMaxDataSizeMB and coldPathMaxDataSizeMB only apply to local indexes", index.Name)
+			}
+		}
+
+		err := validateIndexSettings(&index)
+		if err != nil {
+			return fmt.Errorf("Invalid configuration for index: %s. %s", index.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// validateIndexSettings checks the values of the settings of an index, which apply to both local and Smartstore indexes
+func validateIndexSettings(index *enterpriseApi.IndexSpec) error {
+	if index.DataType != "" && index.DataType != "event" && index.DataType != "metric" {
+		return fmt.Errorf("datatype %s is invalid. Only datatype=event or metric is supported", index.DataType)
+	}
+
+	if index.MaxDataSize != "" && index.MaxDataSize != "auto" && index.MaxDataSize != "auto_high_volume" {
+		if size, err := strconv.ParseUint(index.MaxDataSize, 10, 32); err != nil || size == 0 {
+			return fmt.Errorf("maxDataSize %s is invalid. It must be auto, auto_high_volume or a size in MB", index.MaxDataSize)
 		}
 	}
 
+	if index.ColdToFrozenDir != "" && index.ColdToFrozenScript != "" {
+		return fmt.Errorf("coldToFrozenDir and coldToFrozenScript can not be both configured")
+	}
+
 	return nil
 }
 
@@ -1126,7 +1510,12 @@ func ValidateSplunkSmartstoreSpec(smartstore *enterpriseApi.SmartStoreSpec) erro
 	}
 
 	numVolumes := len(smartstore.VolList)
-	numIndexes := len(smartstore.IndexList)
+	numIndexes := 0
+	for _, index := range smartstore.IndexList {
+		if !index.Local {
+			numIndexes++
+		}
+	}
 	if numIndexes > 0 && numVolumes == 0 {
 		return fmt.Errorf("Volume configuration is missing. Num. of indexes = %d. Num. of Volumes = %d", numIndexes, numVolumes)
 	}
@@ -1157,7 +1546,19 @@ func GetSmartstoreVolumesConfig(client splcommon.ControllerClient, cr splcommon.
 
 	volumes := smartstore.VolList
 	for i := 0; i < len(volumes); i++ {
-		if volumes[i].SecretRef != "" {
+		if isAzureBlobStorageType(volumes[i].Type) || volumes[i].Type == "gcs" {
+			var volumeConf string
+			var err error
+			if isAzureBlobStorageType(volumes[i].Type) {
+				volumeConf, err = getSmartstoreAzureVolumeConfig(client, cr, volumes[i])
+			} else {
+				volumeConf, err = getSmartstoreGCSVolumeConfig(client, cr, volumes[i])
+			}
+			if err != nil {
+				return "", fmt.Errorf("Unable to read the secrets for volume = %s. %s", volumes[i].Name, err)
+			}
+			volumesConf += volumeConf
+		} else if volumes[i].SecretRef != "" {
 			s3AccessKey, s3SecretKey, _, err := GetSmartstoreRemoteVolumeSecrets(volumes[i], client, cr, smartstore)
 			if err != nil {
 				return "", fmt.Errorf("Unable to read the secrets for volume = %s. %s", volumes[i].Name, err)
@@ -1185,6 +1586,237 @@ remote.s3.endpoint = %s
 	return volumesConf, nil
 }
 
+// checkS3VolumeAccess checks the access to a remote storage volume of the S3 API, and is replaced by the tests
+var checkS3VolumeAccess = splclient.CheckS3VolumeAccess
+
+// checkAzureBlobVolumeAccess checks the access to a remote storage volume of Azure Blob, and is replaced by the tests
+var checkAzureBlobVolumeAccess = (*splclient.AzureBlobClient).CheckVolumeAccess
+
+// checkGCSVolumeAccess checks the access to a remote storage volume of GCS, and is replaced by the tests
+var checkGCSVolumeAccess = (*splclient.GCSClient).CheckVolumeAccess
+
+// smartstoreVolumeError is returned when the access check of a Smartstore volume fails
+type smartstoreVolumeError struct {
+	volume string
+	err    error
+}
+
+func (e *smartstoreVolumeError) Error() string {
+	return fmt.Sprintf("Smartstore volume %s failed the access check: %v", e.volume, e.err)
+}
+
+// checkSmartstoreVolumes checks that each Smartstore volume can list, put, get and delete objects with the
+// credentials of its secret, so that a wrong endpoint or key is caught before its configuration reaches the peers.
+// The volumes without secretRef are not checked, since the Splunk pods access them with their own identity, which
+// the operator pod does not have.
+func checkSmartstoreVolumes(client splcommon.ControllerClient, cr splcommon.MetaObject, instanceType InstanceType, smartstore *enterpriseApi.SmartStoreSpec) error {
+	scopedLog := log.WithName("checkSmartstoreVolumes").WithValues("name", cr.GetName(), "namespace", cr.GetNamespace())
+
+	// each custom resource uses its own test object, as several of them may share a volume
+	objectName := fmt.Sprintf(".splunk-operator-check-%s-%s", cr.GetNamespace(), GetSplunkStatefulsetName(instanceType, cr.GetName()))
+	for i := range smartstore.VolList {
+		volume := &smartstore.VolList[i]
+		if volume.SecretRef == "" {
+			scopedLog.Info("Skipping the access check of the volume without secretRef", "volumeName", volume.Name)
+			continue
+		}
+
+		secret, err := splutil.GetSecretByName(client, cr, volume.SecretRef)
+		if err != nil {
+			return &smartstoreVolumeError{volume: volume.Name, err: err}
+		}
+		bucket, prefix := getRemoteStorageBucketAndPrefix(volume, "")
+		if prefix == "/" {
+			prefix = ""
+		}
+		switch {
+		case isAzureBlobStorageType(volume.Type):
+			err = checkSmartstoreAzureBlobVolume(volume, secret, bucket, prefix, objectName)
+		case volume.Type == "gcs":
+			err = checkSmartstoreGCSVolume(secret, bucket, prefix, objectName)
+		default:
+			var accessKey, secretKey string
+			accessKey, secretKey, err = getRemoteStorageCredentials(secret, "s3")
+			if err == nil {
+				err = checkS3VolumeAccess(volume.Endpoint, bucket, prefix, objectName, accessKey, secretKey)
+			}
+		}
+		if err != nil {
+			return &smartstoreVolumeError{volume: volume.Name, err: err}
+		}
+	}
+
+	return nil
+}
+
+// checkSmartstoreAzureBlobVolume checks the access to an Azure Blob volume with the storage account key or the
+// service principal of its secret
+func checkSmartstoreAzureBlobVolume(volume *enterpriseApi.VolumeSpec, secret *corev1.Secret, container, prefix, blobName string) error {
+	tenantID, clientID, clientSecret, err := getAzureServicePrincipal(secret)
+	if err != nil {
+		return err
+	}
+	var accountName, accountKey string
+	if clientID == "" {
+		accountName, accountKey, err = getRemoteStorageCredentials(secret, volume.Type)
+		if err != nil {
+			return err
+		}
+	}
+
+	azureClient, err := splclient.NewAzureBlobClient(container, accountName, accountKey, prefix, "", volume.Endpoint, splclient.InitAzureBlobClientWrapper)
+	if err != nil {
+		return err
+	}
+	blobClient := azureClient.(*splclient.AzureBlobClient)
+	blobClient.TenantID = tenantID
+	blobClient.ClientID = clientID
+	blobClient.ClientSecret = clientSecret
+	return checkAzureBlobVolumeAccess(blobClient, blobName)
+}
+
+// checkSmartstoreGCSVolume checks the access to a GCS volume with the service account key of its secret. Splunk
+// does not use the endpoint of the GCS volumes, so neither does the check.
+func checkSmartstoreGCSVolume(secret *corev1.Secret, bucket, prefix, objectName string) error {
+	_, serviceAccountKey, err := getRemoteStorageCredentials(secret, "gcs")
+	if err != nil {
+		return err
+	}
+
+	gcsClient, err := splclient.NewGCSClient(bucket, "", serviceAccountKey, prefix, "", splclient.GCSDefaultEndpoint, splclient.InitGCSClientWrapper)
+	if err != nil {
+		return err
+	}
+	return checkGCSVolumeAccess(gcsClient.(*splclient.GCSClient), objectName)
+}
+
+// getUnverifiedSmartstoreVolumes returns the names of the Smartstore volumes skipped by the access check
+func getUnverifiedSmartstoreVolumes(smartstore *enterpriseApi.SmartStoreSpec) []string {
+	var volumes []string
+	for _, volume := range smartstore.VolList {
+		if volume.SecretRef == "" {
+			volumes = append(volumes, volume.Name)
+		}
+	}
+	return volumes
+}
+
+// smartstoreIndexRemovalError is returned when indexes are removed from the Smartstore spec without confirmation
+type smartstoreIndexRemovalError struct {
+	indexes []string
+}
+
+func (e *smartstoreIndexRemovalError) Error() string {
+	return fmt.Sprintf("Removal of the Smartstore indexes %s is not confirmed: disable them first, or list them in the %s annotation",
+		strings.Join(e.indexes, ", "), confirmIndexRemovalAnnotation)
+}
+
+// checkSmartstoreIndexRemovals returns the indexes of the last applied Smartstore configuration that are removed from
+// the spec, which includes the old name of a renamed index. The peers stop tracking the buckets of the indexes
+// missing from indexes.conf, so each removal must be confirmed, either by the confirm-index-removal annotation, or
+// by an applied configuration disabling the index. A cluster manager applies it once the bundle is pushed.
+func checkSmartstoreIndexRemovals(cr splcommon.MetaObject, specSmartStore, statusSmartStore *enterpriseApi.SmartStoreSpec) ([]string, error) {
+	disabledApplied := true
+	if cm, ok := cr.(*enterpriseApi.ClusterMaster); ok {
+		disabledApplied = !cm.Status.BundlePushTracker.NeedToPushMasterApps
+	}
+
+	confirmed := make(map[string]bool)
+	for _, name := range strings.Split(cr.GetAnnotations()[confirmIndexRemovalAnnotation], ",") {
+		confirmed[strings.TrimSpace(name)] = true
+	}
+
+	specIndexes := make(map[string]bool)
+	for _, index := range specSmartStore.IndexList {
+		specIndexes[index.Name] = true
+	}
+
+	var removed, unconfirmed []string
+	for _, index := range statusSmartStore.IndexList {
+		if specIndexes[index.Name] {
+			continue
+		}
+		removed = append(removed, index.Name)
+		if !confirmed[index.Name] && !(index.Disabled && disabledApplied) {
+			unconfirmed = append(unconfirmed, index.Name)
+		}
+	}
+
+	if len(unconfirmed) > 0 {
+		return nil, &smartstoreIndexRemovalError{indexes: unconfirmed}
+	}
+	return removed, nil
+}
+
+// getSmartstoreAzureVolumeConfig returns the configuration of a Smartstore volume in an Azure Blob container, in INI
+// format. The volume authenticates with the service principal or the storage account key of its secret, or else
+// with the managed identity of the Splunk pods. Splunk does not support SAS tokens for Smartstore volumes.
+func getSmartstoreAzureVolumeConfig(client splcommon.ControllerClient, cr splcommon.MetaObject, volume enterpriseApi.VolumeSpec) (string, error) {
+	volumeConf := fmt.Sprintf(`
+[volume:%s]
+storageType = remote
+path = azure://%s
+remote.azure.endpoint = %s
+`, volume.Name, volume.Path, volume.Endpoint)
+
+	if volume.SecretRef == "" {
+		log.WithName("getSmartstoreAzureVolumeConfig").Info("No valid secretRef configured.  Configure volume with the managed identity", "volumeName", volume.Name)
+		return volumeConf, nil
+	}
+
+	namespaceScopedSecret, err := splutil.GetSecretByName(client, cr, volume.SecretRef)
+	if err != nil {
+		return "", err
+	}
+	splutil.SetSecretOwnerRef(client, volume.SecretRef, cr)
+
+	tenantID, clientID, clientSecret, err := getAzureServicePrincipal(namespaceScopedSecret)
+	if err != nil {
+		return "", err
+	}
+	if clientID != "" {
+		return volumeConf + fmt.Sprintf(`remote.azure.tenant_id = %s
+remote.azure.client_id = %s
+remote.azure.client_secret = %s
+`, tenantID, clientID, clientSecret), nil
+	}
+
+	accountName, accountKey, err := getRemoteStorageCredentials(namespaceScopedSecret, volume.Type)
+	if err != nil {
+		return "", err
+	}
+	return volumeConf + fmt.Sprintf(`remote.azure.access_key = %s
+remote.azure.secret_key = %s
+`, accountName, accountKey), nil
+}
+
+// getSmartstoreGCSVolumeConfig returns the configuration of a Smartstore volume in a GCS bucket, in INI format. The
+// service account key of the secret of the volume is mounted in the Splunk pods as its credential file, or else the
+// volume authenticates with the service account of the pods.
+func getSmartstoreGCSVolumeConfig(client splcommon.ControllerClient, cr splcommon.MetaObject, volume enterpriseApi.VolumeSpec) (string, error) {
+	volumeConf := fmt.Sprintf(`
+[volume:%s]
+storageType = remote
+path = gs://%s
+`, volume.Name, volume.Path)
+
+	if volume.SecretRef == "" {
+		log.WithName("getSmartstoreGCSVolumeConfig").Info("No valid secretRef configured.  Configure volume with the service account of the pods", "volumeName", volume.Name)
+		return volumeConf, nil
+	}
+
+	namespaceScopedSecret, err := splutil.GetSecretByName(client, cr, volume.SecretRef)
+	if err != nil {
+		return "", err
+	}
+	splutil.SetSecretOwnerRef(client, volume.SecretRef, cr)
+
+	if _, _, err = getRemoteStorageCredentials(namespaceScopedSecret, "gcs"); err != nil {
+		return "", err
+	}
+	return volumeConf + fmt.Sprintf("remote.gs.credential_file = %s\n", fmt.Sprintf(smartstoreCredentialFileTemplate, volume.Name)), nil
+}
+
 // GetSmartstoreIndexesConfig returns the list of indexes configuration in INI format
 func GetSmartstoreIndexesConfig(indexes []enterpriseApi.IndexSpec) string {
 
@@ -1203,6 +1835,20 @@ remotePath = volume:%s/%s`, indexesConf, indexes[i].VolName, indexes[i].RemotePa
 		} else if indexes[i].VolName != "" {
 			indexesConf = fmt.Sprintf(`%s
 remotePath = volume:%s/%s`, indexesConf, indexes[i].VolName, defaultRemotePath)
+		} else if indexes[i].Local {
+			// Clear the remotePath of the defaults, if any
+			indexesConf = fmt.Sprintf(`%s
+remotePath =`, indexesConf)
+		}
+
+		if indexes[i].DataType != "" {
+			indexesConf = fmt.Sprintf(`%s
+datatype = %s`, indexesConf, indexes[i].DataType)
+		}
+
+		if indexes[i].Disabled {
+			indexesConf = fmt.Sprintf(`%s
+disabled = true`, indexesConf)
 		}
 
 		if indexes[i].HotlistBloomFilterRecencyHours != 0 {
@@ -1225,6 +1871,46 @@ maxGlobalDataSizeMB = %d`, indexesConf, indexes[i].MaxGlobalDataSizeMB)
 maxGlobalRawDataSizeMB = %d`, indexesConf, indexes[i].MaxGlobalRawDataSizeMB)
 		}
 
+		if indexes[i].FrozenTimePeriodInSecs != 0 {
+			indexesConf = fmt.Sprintf(`%s
+frozenTimePeriodInSecs = %d`, indexesConf, indexes[i].FrozenTimePeriodInSecs)
+		}
+
+		if indexes[i].MaxDataSize != "" {
+			indexesConf = fmt.Sprintf(`%s
+maxDataSize = %s`, indexesConf, indexes[i].MaxDataSize)
+		}
+
+		if indexes[i].MaxTotalDataSizeMB != 0 {
+			indexesConf = fmt.Sprintf(`%s
+maxTotalDataSizeMB = %d`, indexesConf, indexes[i].MaxTotalDataSizeMB)
+		}
+
+		if indexes[i].MaxWarmDBCount != 0 {
+			indexesConf = fmt.Sprintf(`%s
+maxWarmDBCount = %d`, indexesConf, indexes[i].MaxWarmDBCount)
+		}
+
+		if indexes[i].HomePathMaxDataSizeMB != 0 {
+			indexesConf = fmt.Sprintf(`%s
+homePath.maxDataSizeMB = %d`, indexesConf, indexes[i].HomePathMaxDataSizeMB)
+		}
+
+		if indexes[i].ColdPathMaxDataSizeMB != 0 {
+			indexesConf = fmt.Sprintf(`%s
+coldPath.maxDataSizeMB = %d`, indexesConf, indexes[i].ColdPathMaxDataSizeMB)
+		}
+
+		if indexes[i].ColdToFrozenDir != "" {
+			indexesConf = fmt.Sprintf(`%s
+coldToFrozenDir = %s`, indexesConf, indexes[i].ColdToFrozenDir)
+		}
+
+		if indexes[i].ColdToFrozenScript != "" {
+			indexesConf = fmt.Sprintf(`%s
+coldToFrozenScript = %s`, indexesConf, indexes[i].ColdToFrozenScript)
+		}
+
 		// Add a new line in betwen index stanzas
 		// Do not add config beyond here
 		indexesConf = fmt.Sprintf(`%s
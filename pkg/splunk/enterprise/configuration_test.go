@@ -15,11 +15,17 @@
 package enterprise
 
 import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
 	"encoding/json"
+	"encoding/pem"
 	"fmt"
+	"strings"
 	"testing"
 
 	enterpriseApi "github.com/splunk/splunk-operator/pkg/apis/enterprise/v2"
+	splclient "github.com/splunk/splunk-operator/pkg/splunk/client"
 	splcommon "github.com/splunk/splunk-operator/pkg/splunk/common"
 	splctrl "github.com/splunk/splunk-operator/pkg/splunk/controller"
 	spltest "github.com/splunk/splunk-operator/pkg/splunk/test"
@@ -81,6 +87,71 @@ func TestGetSplunkService(t *testing.T) {
 	test(SplunkSearchHead, true, `{"kind":"Service","apiVersion":"v1","metadata":{"name":"splunk-stack1-search-head-headless","namespace":"test","creationTimestamp":null,"labels":{"app.kubernetes.io/component":"search-head","app.kubernetes.io/instance":"splunk-stack1-search-head","app.kubernetes.io/managed-by":"splunk-operator","app.kubernetes.io/name":"search-head","app.kubernetes.io/part-of":"splunk-stack1-search-head","one":"two"},"annotations":{"a":"b"},"ownerReferences":[{"apiVersion":"","kind":"","name":"stack1","uid":"","controller":true}]},"spec":{"ports":[{"name":"http-splunkweb","protocol":"TCP","port":8000,"targetPort":8000},{"name":"https-splunkd","protocol":"TCP","port":8089,"targetPort":8089}],"selector":{"app.kubernetes.io/component":"search-head","app.kubernetes.io/instance":"splunk-stack1-search-head","app.kubernetes.io/managed-by":"splunk-operator","app.kubernetes.io/name":"search-head","app.kubernetes.io/part-of":"splunk-stack1-search-head"},"clusterIP":"None","type":"ClusterIP","publishNotReadyAddresses":true},"status":{"loadBalancer":{}}}`)
 }
 
+func TestGetSplunkPodDisruptionBudget(t *testing.T) {
+	cr := enterpriseApi.IndexerCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "stack1",
+			Namespace: "test",
+		},
+	}
+
+	test := func(want string) {
+		f := func() (interface{}, error) {
+			return getSplunkPodDisruptionBudget(&cr, &cr.Spec.CommonSplunkSpec, SplunkIndexer, 2), nil
+		}
+		configTester(t, "getSplunkPodDisruptionBudget()", f, want)
+	}
+
+	test(`{"kind":"PodDisruptionBudget","apiVersion":"policy/v1beta1","metadata":{"name":"splunk-stack1-indexer","namespace":"test","creationTimestamp":null,"ownerReferences":[{"apiVersion":"","kind":"","name":"stack1","uid":"","controller":true}]},"spec":{"selector":{"matchLabels":{"app.kubernetes.io/component":"indexer","app.kubernetes.io/instance":"splunk-stack1-indexer","app.kubernetes.io/managed-by":"splunk-operator","app.kubernetes.io/name":"indexer","app.kubernetes.io/part-of":"splunk-stack1-indexer"}},"maxUnavailable":2},"status":{"disruptionsAllowed":0,"currentHealthy":0,"desiredHealthy":0,"expectedPods":0}}`)
+
+	// the spec overrides the default
+	minAvailable := intstr.FromString("50%")
+	cr.Spec.PodDisruptionBudget.MinAvailable = &minAvailable
+	cr.Spec.ClusterMasterRef.Name = "cluster1"
+	test(`{"kind":"PodDisruptionBudget","apiVersion":"policy/v1beta1","metadata":{"name":"splunk-stack1-indexer","namespace":"test","creationTimestamp":null,"ownerReferences":[{"apiVersion":"","kind":"","name":"stack1","uid":"","controller":true}]},"spec":{"minAvailable":"50%","selector":{"matchLabels":{"app.kubernetes.io/component":"indexer","app.kubernetes.io/instance":"splunk-stack1-indexer","app.kubernetes.io/managed-by":"splunk-operator","app.kubernetes.io/name":"indexer","app.kubernetes.io/part-of":"splunk-cluster1-indexer"}}},"status":{"disruptionsAllowed":0,"currentHealthy":0,"desiredHealthy":0,"expectedPods":0}}`)
+}
+
+func TestValidatePodDisruptionBudgetSpec(t *testing.T) {
+	test := func(maxUnavailable, minAvailable *intstr.IntOrString, wantErr bool) {
+		pdb := enterpriseApi.PodDisruptionBudgetSpec{MaxUnavailable: maxUnavailable, MinAvailable: minAvailable}
+		err := validatePodDisruptionBudgetSpec(&pdb)
+		if wantErr && err == nil {
+			t.Errorf("validatePodDisruptionBudgetSpec(%v, %v) returned nil; want error", maxUnavailable, minAvailable)
+		} else if !wantErr && err != nil {
+			t.Errorf("validatePodDisruptionBudgetSpec(%v, %v) returned %v; want nil", maxUnavailable, minAvailable, err)
+		}
+	}
+
+	one := intstr.FromInt(1)
+	negative := intstr.FromInt(-1)
+	percent := intstr.FromString("25%")
+	invalid := intstr.FromString("two")
+	test(nil, nil, false)
+	test(&one, nil, false)
+	test(nil, &percent, false)
+	test(&one, &percent, true)
+	test(&negative, nil, true)
+	test(nil, &invalid, true)
+}
+
+func TestValidateRolloutStrategy(t *testing.T) {
+	test := func(strategy splcommon.RolloutStrategy, wantErr bool) {
+		err := validateRolloutStrategy(&strategy)
+		if wantErr && err == nil {
+			t.Errorf("validateRolloutStrategy(%v) returned nil; want error", strategy)
+		} else if !wantErr && err != nil {
+			t.Errorf("validateRolloutStrategy(%v) returned %v; want nil", strategy, err)
+		}
+	}
+
+	partition := int32(2)
+	negative := int32(-1)
+	test(splcommon.RolloutStrategy{}, false)
+	test(splcommon.RolloutStrategy{MaxUnavailable: 2, Partition: &partition, Paused: true}, false)
+	test(splcommon.RolloutStrategy{MaxUnavailable: -1}, true)
+	test(splcommon.RolloutStrategy{Partition: &negative}, true)
+}
+
 func TestGetSplunkDefaults(t *testing.T) {
 	cr := enterpriseApi.IndexerCluster{
 		ObjectMeta: metav1.ObjectMeta{
@@ -102,6 +173,55 @@ func TestGetSplunkDefaults(t *testing.T) {
 	test(`{"metadata":{"name":"splunk-stack1-indexer-defaults","namespace":"test","creationTimestamp":null},"data":{"default.yml":"defaults_string"}}`)
 }
 
+func TestGetMultisiteDefaults(t *testing.T) {
+	cm := enterpriseApi.ClusterMaster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "master1",
+			Namespace: "test",
+		},
+	}
+	idxc := enterpriseApi.IndexerCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "stack1",
+			Namespace: "test",
+		},
+	}
+	idxc.Spec.ClusterMasterRef.Name = "master1"
+
+	test := func(cr splcommon.MetaObject, want string) {
+		if got := getMultisiteDefaults(cr); got != want {
+			t.Errorf("getMultisiteDefaults() got = %s; want %s", got, want)
+		}
+	}
+
+	// single site
+	test(&cm, "")
+	test(&idxc, "")
+	test(&enterpriseApi.Standalone{}, "")
+
+	cm.Spec.Multisite = enterpriseApi.MultisiteSpec{
+		Site:                  "site2",
+		AvailableSites:        []string{"site1", "site2", "site3"},
+		SiteReplicationFactor: enterpriseApi.SiteFactorSpec{Origin: 1, Total: 2},
+		SiteSearchFactor:      enterpriseApi.SiteFactorSpec{Origin: 1, Total: 2},
+	}
+	test(&cm, `splunk:
+  site: site2
+  multisite_master: localhost
+  all_sites: site1,site2,site3
+  multisite_replication_factor_origin: 1
+  multisite_replication_factor_total: 2
+  multisite_search_factor_origin: 1
+  multisite_search_factor_total: 2
+`)
+
+	idxc.Spec.Site = "site3"
+	test(&idxc, `splunk:
+  site: site3
+  multisite_master: splunk-master1-cluster-master-service
+`)
+}
+
 func TestGetService(t *testing.T) {
 	cr := enterpriseApi.IndexerCluster{
 		ObjectMeta: metav1.ObjectMeta{
@@ -220,9 +340,9 @@ func TestSmartstoreApplyClusterMasterFailsOnInvalidSmartStoreConfig(t *testing.T
 		},
 	}
 
-	var client splcommon.ControllerClient
+	client := spltest.NewMockClient()
 
-	_, err := ApplyClusterMaster(client, &cr)
+	_, err := ApplyClusterMaster(client, nil, &cr)
 	if err == nil {
 		t.Errorf("ApplyClusterMaster should fail on invalid smartstore config")
 	}
@@ -252,9 +372,9 @@ func TestSmartstoreApplyStandaloneFailsOnInvalidSmartStoreConfig(t *testing.T) {
 		},
 	}
 
-	var client splcommon.ControllerClient
+	client := spltest.NewMockClient()
 
-	_, err := ApplyStandalone(client, &cr)
+	_, err := ApplyStandalone(client, nil, &cr)
 	if err == nil {
 		t.Errorf("ApplyStandalone should fail on invalid smartstore config")
 	}
@@ -287,6 +407,7 @@ func TestSmartStoreConfigDoesNotFailOnClusterMasterCR(t *testing.T) {
 		},
 	}
 
+	setClusterMasterDefaults(&cr)
 	err := validateClusterMasterSpec(&cr)
 
 	if err != nil {
@@ -594,6 +715,231 @@ func TestValidateSplunkSmartstoreSpec(t *testing.T) {
 	if err == nil {
 		t.Errorf("Index with an invalid volume name should return error")
 	}
+
+	// Local indexes do not need any volume, and only take the settings of the local indexes
+	SmartStoreLocalIndexes := enterpriseApi.SmartStoreSpec{
+		IndexList: []enterpriseApi.IndexSpec{
+			{Name: "local1", Local: true, DataType: "metric", MaxDataSize: "auto", MaxTotalDataSizeMB: 10000, MaxWarmDBCount: 100},
+		},
+	}
+
+	err = ValidateSplunkSmartstoreSpec(&SmartStoreLocalIndexes)
+	if err != nil {
+		t.Errorf("Local indexes without volume should not cause error: %v", err)
+	}
+
+	invalidIndexes := []enterpriseApi.IndexSpec{
+		{Name: "local1", Local: true, RemotePath: "remotepath1"},
+		{Name: "local1", Local: true, IndexAndGlobalCommonSpec: enterpriseApi.IndexAndGlobalCommonSpec{VolName: "msos_s2s3_vol"}},
+		{Name: "local1", Local: true, IndexAndGlobalCommonSpec: enterpriseApi.IndexAndGlobalCommonSpec{MaxGlobalDataSizeMB: 6000}},
+		{Name: "local1", Local: true, IndexAndCacheManagerCommonSpec: enterpriseApi.IndexAndCacheManagerCommonSpec{HotlistRecencySecs: 86400}},
+		{Name: "local1", Local: true, DataType: "logs"},
+		{Name: "local1", Local: true, MaxDataSize: "0"},
+		{Name: "local1", Local: true, MaxDataSize: "10GB"},
+		{Name: "local1", Local: true, ColdToFrozenDir: "/frozen", ColdToFrozenScript: "archive.sh"},
+		{Name: "salesdata1", MaxTotalDataSizeMB: 10000, IndexAndGlobalCommonSpec: enterpriseApi.IndexAndGlobalCommonSpec{VolName: "msos_s2s3_vol"}},
+		{Name: "salesdata1", MaxWarmDBCount: 100, IndexAndGlobalCommonSpec: enterpriseApi.IndexAndGlobalCommonSpec{VolName: "msos_s2s3_vol"}},
+		{Name: "salesdata1", HomePathMaxDataSizeMB: 100, IndexAndGlobalCommonSpec: enterpriseApi.IndexAndGlobalCommonSpec{VolName: "msos_s2s3_vol"}},
+		{Name: "salesdata1", ColdPathMaxDataSizeMB: 100, IndexAndGlobalCommonSpec: enterpriseApi.IndexAndGlobalCommonSpec{VolName: "msos_s2s3_vol"}},
+	}
+	for _, index := range invalidIndexes {
+		SmartStoreInvalidIndex := enterpriseApi.SmartStoreSpec{
+			VolList: []enterpriseApi.VolumeSpec{
+				{Name: "msos_s2s3_vol", Endpoint: "https://s3-eu-west-2.amazonaws.com", Path: "testbucket-rs-london", SecretRef: "s3-secret"},
+			},
+			IndexList: []enterpriseApi.IndexSpec{index},
+		}
+		err = ValidateSplunkSmartstoreSpec(&SmartStoreInvalidIndex)
+		if err == nil {
+			t.Errorf("Conflicting settings of index %+v should return error", index)
+		}
+	}
+
+	// Azure Blob and GCS volumes are supported, GCS volumes do not need an endpoint
+	SmartStoreAzureAndGCSVolumes := enterpriseApi.SmartStoreSpec{
+		VolList: []enterpriseApi.VolumeSpec{
+			{Name: "azure_vol", Endpoint: "https://mystorageaccount.blob.core.windows.net", Path: "smartstore-container", SecretRef: "azure-secret", Type: "blob"},
+			{Name: "gcs_vol", Path: "smartstore-bucket", SecretRef: "gcs-secret", Type: "gcs"},
+		},
+	}
+
+	err = ValidateSplunkSmartstoreSpec(&SmartStoreAzureAndGCSVolumes)
+	if err != nil {
+		t.Errorf("Azure Blob and GCS volumes should not cause error: %v", err)
+	}
+
+	SmartStoreAzureAndGCSVolumes.VolList[0].Type = "azure-blob"
+	err = ValidateSplunkSmartstoreSpec(&SmartStoreAzureAndGCSVolumes)
+	if err != nil {
+		t.Errorf("Azure Blob volumes of the azure-blob storage type should not cause error: %v", err)
+	}
+
+	SmartStoreAzureAndGCSVolumes.VolList[0].Endpoint = ""
+	err = ValidateSplunkSmartstoreSpec(&SmartStoreAzureAndGCSVolumes)
+	if err == nil {
+		t.Errorf("Azure Blob volume without an endpoint should return error")
+	}
+
+	SmartStoreAzureAndGCSVolumes.VolList[0].Endpoint = "https://mystorageaccount.blob.core.windows.net"
+	SmartStoreAzureAndGCSVolumes.VolList[0].Type = "pvc"
+	err = ValidateSplunkSmartstoreSpec(&SmartStoreAzureAndGCSVolumes)
+	if err == nil {
+		t.Errorf("Volume types of the App Framework only should return error")
+	}
+}
+
+func TestGetSmartstoreVolumesConfig(t *testing.T) {
+	cr := enterpriseApi.Standalone{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "stack1",
+			Namespace: "test",
+		},
+	}
+	smartstore := enterpriseApi.SmartStoreSpec{
+		VolList: []enterpriseApi.VolumeSpec{
+			{Name: "azure_vol", Endpoint: "https://mystorageaccount.blob.core.windows.net", Path: "smartstore-container/prefix", SecretRef: "azure-secret", Type: "blob"},
+			{Name: "gcs_vol", Path: "smartstore-bucket", SecretRef: "gcs-secret", Type: "gcs"},
+		},
+	}
+
+	c := spltest.NewMockClient()
+	c.AddObject(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "azure-secret", Namespace: "test"},
+		Data:       map[string][]byte{azureBlobAccountName: []byte("mystorageaccount"), azureBlobAccountKey: []byte("YWNjb3VudGtleQ==")},
+	})
+	c.AddObject(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "gcs-secret", Namespace: "test"},
+		Data:       map[string][]byte{gcsServiceAccountKey: []byte(`{"type": "service_account"}`)},
+	})
+
+	test := func(want string) {
+		t.Helper()
+		got, err := GetSmartstoreVolumesConfig(c, &cr, &smartstore, map[string]string{})
+		if err != nil {
+			t.Errorf("GetSmartstoreVolumesConfig() returned error: %v", err)
+		}
+		if got != want {
+			t.Errorf("GetSmartstoreVolumesConfig() = %q; want %q", got, want)
+		}
+	}
+
+	// account key of the Azure storage account, and service account key of GCS
+	test(`
+[volume:azure_vol]
+storageType = remote
+path = azure://smartstore-container/prefix
+remote.azure.endpoint = https://mystorageaccount.blob.core.windows.net
+remote.azure.access_key = mystorageaccount
+remote.azure.secret_key = YWNjb3VudGtleQ==
+
+[volume:gcs_vol]
+storageType = remote
+path = gs://smartstore-bucket
+remote.gs.credential_file = splunk-smartstore-gcs_vol.json
+`)
+
+	// service principal of Azure, and service account of the pods for GCS
+	c.AddObject(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "azure-sp-secret", Namespace: "test"},
+		Data:       map[string][]byte{azureClientID: []byte("myclient"), azureClientSecret: []byte("myclientsecret"), azureTenantID: []byte("mytenant")},
+	})
+	smartstore.VolList[0].SecretRef = "azure-sp-secret"
+	smartstore.VolList[1].SecretRef = ""
+	test(`
+[volume:azure_vol]
+storageType = remote
+path = azure://smartstore-container/prefix
+remote.azure.endpoint = https://mystorageaccount.blob.core.windows.net
+remote.azure.tenant_id = mytenant
+remote.azure.client_id = myclient
+remote.azure.client_secret = myclientsecret
+
+[volume:gcs_vol]
+storageType = remote
+path = gs://smartstore-bucket
+`)
+
+	// managed identity of the pods for Azure
+	smartstore.VolList[0].SecretRef = ""
+	test(`
+[volume:azure_vol]
+storageType = remote
+path = azure://smartstore-container/prefix
+remote.azure.endpoint = https://mystorageaccount.blob.core.windows.net
+
+[volume:gcs_vol]
+storageType = remote
+path = gs://smartstore-bucket
+`)
+
+	// IAM role of the pods for S3
+	s3Volume := enterpriseApi.VolumeSpec{Name: "s3_vol", Endpoint: "https://s3-us-west-2.amazonaws.com", Path: "smartstore-bucket"}
+	if err := ValidateSplunkSmartstoreSpec(&enterpriseApi.SmartStoreSpec{VolList: []enterpriseApi.VolumeSpec{s3Volume}}); err != nil {
+		t.Errorf("S3 volume without secretRef should not cause error: %v", err)
+	}
+	got, err := GetSmartstoreVolumesConfig(c, &cr, &enterpriseApi.SmartStoreSpec{VolList: []enterpriseApi.VolumeSpec{s3Volume}}, map[string]string{})
+	if want := "\n[volume:s3_vol]\nstorageType = remote\npath = s3://smartstore-bucket\nremote.s3.endpoint = https://s3-us-west-2.amazonaws.com\n"; got != want || err != nil {
+		t.Errorf("GetSmartstoreVolumesConfig() = %q, %v; want %q", got, err, want)
+	}
+
+	// secrets missing their keys
+	c.AddObject(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "s3-secret", Namespace: "test"},
+		Data:       map[string][]byte{s3AccessKey: []byte("accesskey"), s3SecretKey: []byte("secretkey")},
+	})
+	for _, volume := range smartstore.VolList {
+		volume.SecretRef = "s3-secret"
+		if _, err := GetSmartstoreVolumesConfig(c, &cr, &enterpriseApi.SmartStoreSpec{VolList: []enterpriseApi.VolumeSpec{volume}}, map[string]string{}); err == nil {
+			t.Errorf("GetSmartstoreVolumesConfig() should fail for a %s volume without its keys", volume.Type)
+		}
+	}
+	c.AddObject(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "azure-sp-no-tenant-secret", Namespace: "test"},
+		Data:       map[string][]byte{azureClientID: []byte("myclient"), azureClientSecret: []byte("myclientsecret")},
+	})
+	smartstore.VolList[0].SecretRef = "azure-sp-no-tenant-secret"
+	if _, err := GetSmartstoreVolumesConfig(c, &cr, &smartstore, map[string]string{}); err == nil {
+		t.Errorf("GetSmartstoreVolumesConfig() should fail for a service principal without its tenant")
+	}
+
+	// SAS tokens are not supported, whatever the name of the Azure Blob storage type
+	c.AddObject(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "azure-sas-secret", Namespace: "test"},
+		Data:       map[string][]byte{azureBlobAccountName: []byte("mystorageaccount"), azureSASToken: []byte("sv=2020-08-04&ss=b&sig=abcd")},
+	})
+	smartstore.VolList[0].SecretRef = "azure-sas-secret"
+	for _, storageType := range []string{"azure-blob", "blob"} {
+		smartstore.VolList[0].Type = storageType
+		_, err = GetSmartstoreVolumesConfig(c, &cr, &smartstore, map[string]string{})
+		if err == nil || !strings.Contains(err.Error(), "SAS tokens are not supported") {
+			t.Errorf("GetSmartstoreVolumesConfig() = %v; want an error for the SAS token of a %s volume", err, storageType)
+		}
+	}
+}
+
+func TestAddSmartstoreCredentialFiles(t *testing.T) {
+	smartstore := enterpriseApi.SmartStoreSpec{
+		VolList: []enterpriseApi.VolumeSpec{
+			{Name: "s3_vol", Endpoint: "https://s3-eu-west-2.amazonaws.com", Path: "testbucket", SecretRef: "s3-secret"},
+			{Name: "gcs_vol", Path: "smartstore-bucket", SecretRef: "gcs-secret", Type: "gcs"},
+			{Name: "gcs_vol_2", Path: "smartstore-bucket-2", Type: "gcs"},
+		},
+	}
+	podTemplateSpec := corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "splunk"}}}}
+	addSmartstoreCredentialFiles(&podTemplateSpec, &smartstore)
+
+	if len(podTemplateSpec.Spec.Volumes) != 1 || len(podTemplateSpec.Spec.Containers[0].VolumeMounts) != 1 {
+		t.Fatalf("addSmartstoreCredentialFiles() should only mount the key of the GCS volume with a secret, got %v", podTemplateSpec.Spec.Volumes)
+	}
+	volume := podTemplateSpec.Spec.Volumes[0]
+	if volume.Name != "mnt-splunk-smartstore-1" || volume.Secret == nil || volume.Secret.SecretName != "gcs-secret" ||
+		len(volume.Secret.Items) != 1 || volume.Secret.Items[0].Key != gcsServiceAccountKey || volume.Secret.Items[0].Path != "splunk-smartstore-gcs_vol.json" {
+		t.Errorf("addSmartstoreCredentialFiles() volume = %v", volume)
+	}
+	mount := podTemplateSpec.Spec.Containers[0].VolumeMounts[0]
+	if mount.Name != volume.Name || mount.MountPath != "/opt/splunk/etc/auth/splunk-smartstore-gcs_vol.json" || mount.SubPath != "splunk-smartstore-gcs_vol.json" {
+		t.Errorf("addSmartstoreCredentialFiles() volume mount = %v", mount)
+	}
 }
 
 func TestValidateAppFrameworkSpec(t *testing.T) {
@@ -625,17 +971,13 @@ func TestValidateAppFrameworkSpec(t *testing.T) {
 		},
 	}
 
-	appFrameworkContext := enterpriseApi.AppDeploymentContext{
-		AppsRepoStatusPollInterval: 60,
-	}
-
-	err = ValidateAppFrameworkSpec(&AppFramework, &appFrameworkContext, false)
+	err = ValidateAppFrameworkSpec(&AppFramework, false)
 	if err != nil {
 		t.Errorf("Valid App Framework configuration should not cause error: %v", err)
 	}
 
 	AppFramework.VolList[0].SecretRef = ""
-	err = ValidateAppFrameworkSpec(&AppFramework, &appFrameworkContext, false)
+	err = ValidateAppFrameworkSpec(&AppFramework, false)
 	if err != nil {
 		t.Errorf("Missing Secret Object reference is a valid config that should not cause error: %v", err)
 	}
@@ -644,7 +986,7 @@ func TestValidateAppFrameworkSpec(t *testing.T) {
 	// App Framework config with missing App Source name
 	AppFramework.AppSources[0].Name = ""
 
-	err = ValidateAppFrameworkSpec(&AppFramework, &appFrameworkContext, false)
+	err = ValidateAppFrameworkSpec(&AppFramework, false)
 	if err == nil {
 		t.Errorf("Should not accept an app source with missing name ")
 	}
@@ -652,7 +994,7 @@ func TestValidateAppFrameworkSpec(t *testing.T) {
 	//App Framework config app source config with missing location(withot default location) should errro out
 	AppFramework.AppSources[0].Name = "adminApps"
 	AppFramework.AppSources[0].Location = ""
-	err = ValidateAppFrameworkSpec(&AppFramework, &appFrameworkContext, false)
+	err = ValidateAppFrameworkSpec(&AppFramework, false)
 	if err == nil {
 		t.Errorf("An App Source with missing location should cause an error, when there is no default location configured")
 	}
@@ -663,14 +1005,14 @@ func TestValidateAppFrameworkSpec(t *testing.T) {
 	AppFramework.Defaults.VolName = "msos_s2s3_vol"
 	AppFramework.AppSources[0].Scope = ""
 
-	err = ValidateAppFrameworkSpec(&AppFramework, &appFrameworkContext, false)
+	err = ValidateAppFrameworkSpec(&AppFramework, false)
 	if err != nil {
 		t.Errorf("Should accept an App Source with missing scope, when default scope is configured. But, got the error: %v", err)
 	}
 	AppFramework.AppSources[0].Location = "adminAppsRepo"
 
 	// Empty App Repo config should not cause an error
-	err = ValidateAppFrameworkSpec(nil, &appFrameworkContext, false)
+	err = ValidateAppFrameworkSpec(nil, false)
 	if err != nil {
 		t.Errorf("App Repo config is optional, should not cause an error. But, got the error: %v", err)
 	}
@@ -699,7 +1041,7 @@ func TestValidateAppFrameworkSpec(t *testing.T) {
 		},
 	}
 
-	err = ValidateAppFrameworkSpec(&AppFrameworkWithoutVolumeSpec, &appFrameworkContext, false)
+	err = ValidateAppFrameworkSpec(&AppFrameworkWithoutVolumeSpec, false)
 	if err == nil {
 		t.Errorf("App Repo config without volume details should return error")
 	}
@@ -707,7 +1049,7 @@ func TestValidateAppFrameworkSpec(t *testing.T) {
 	// Defaults with invalid volume reference should return error
 	AppFramework.Defaults.VolName = "UnknownVolume"
 
-	err = ValidateAppFrameworkSpec(&AppFramework, &appFrameworkContext, false)
+	err = ValidateAppFrameworkSpec(&AppFramework, false)
 	if err == nil {
 		t.Errorf("Volume referred in the defaults should be a valid volume")
 	}
@@ -719,7 +1061,7 @@ func TestValidateAppFrameworkSpec(t *testing.T) {
 	AppFramework.AppSources[1].VolName = AppFramework.AppSources[0].VolName
 	AppFramework.AppSources[1].Location = AppFramework.AppSources[0].Location
 
-	err = ValidateAppFrameworkSpec(&AppFramework, &appFrameworkContext, false)
+	err = ValidateAppFrameworkSpec(&AppFramework, false)
 	if err == nil {
 		t.Errorf("Duplicate app sources should return an error")
 	}
@@ -731,7 +1073,7 @@ func TestValidateAppFrameworkSpec(t *testing.T) {
 	tmpAppSourceName := AppFramework.AppSources[1].Name
 	AppFramework.AppSources[1].Name = AppFramework.AppSources[0].Name
 
-	err = ValidateAppFrameworkSpec(&AppFramework, &appFrameworkContext, false)
+	err = ValidateAppFrameworkSpec(&AppFramework, false)
 	if err == nil {
 		t.Errorf("Failed to detect duplicate app source names")
 	}
@@ -742,14 +1084,14 @@ func TestValidateAppFrameworkSpec(t *testing.T) {
 	AppFramework.AppSources[0].VolName = ""
 	AppFramework.Defaults.VolName = ""
 
-	err = ValidateAppFrameworkSpec(&AppFramework, &appFrameworkContext, false)
+	err = ValidateAppFrameworkSpec(&AppFramework, false)
 	if err == nil {
 		t.Errorf("If no default volume, App Source with missing volume info should return an error")
 	}
 
 	// If the AppSource doesn't have VolName, and if the defaults have it, shouldn't cause an error
 	AppFramework.Defaults.VolName = "msos_s2s3_vol"
-	err = ValidateAppFrameworkSpec(&AppFramework, &appFrameworkContext, false)
+	err = ValidateAppFrameworkSpec(&AppFramework, false)
 	if err != nil {
 		t.Errorf("If default volume, App Source with missing volume should not return an error, but got erros %v", err)
 	}
@@ -757,7 +1099,7 @@ func TestValidateAppFrameworkSpec(t *testing.T) {
 	// Volume referenced from an index must be a valid volume
 	AppFramework.AppSources[0].VolName = "UnknownVolume"
 
-	err = ValidateAppFrameworkSpec(&AppFramework, &appFrameworkContext, false)
+	err = ValidateAppFrameworkSpec(&AppFramework, false)
 	if err == nil {
 		t.Errorf("Index with an invalid volume name should return error")
 	}
@@ -765,14 +1107,14 @@ func TestValidateAppFrameworkSpec(t *testing.T) {
 
 	// if the CR supports only local apps, and if the app source scope is not local, should return error
 	AppFramework.AppSources[0].Scope = enterpriseApi.ScopeCluster
-	err = ValidateAppFrameworkSpec(&AppFramework, &appFrameworkContext, true)
+	err = ValidateAppFrameworkSpec(&AppFramework, true)
 	if err == nil {
 		t.Errorf("When called with App scope local, any app sources with the cluster scope should return an error")
 	}
 
 	// If the app scope value other than "local" or "cluster" should return an error
 	AppFramework.AppSources[0].Scope = "unknown"
-	err = ValidateAppFrameworkSpec(&AppFramework, &appFrameworkContext, false)
+	err = ValidateAppFrameworkSpec(&AppFramework, false)
 	if err == nil {
 		t.Errorf("Unsupported app scope should be cause error, but failed to detect")
 	}
@@ -782,7 +1124,7 @@ func TestValidateAppFrameworkSpec(t *testing.T) {
 
 	AppFramework.Defaults.Scope = enterpriseApi.ScopeCluster
 
-	err = ValidateAppFrameworkSpec(&AppFramework, &appFrameworkContext, true)
+	err = ValidateAppFrameworkSpec(&AppFramework, true)
 	if err == nil {
 		t.Errorf("When called with App scope local, defaults with the cluster scope should return an error")
 	}
@@ -790,7 +1132,7 @@ func TestValidateAppFrameworkSpec(t *testing.T) {
 
 	// Default scope should be either "local" OR "cluster"
 	AppFramework.Defaults.Scope = "unknown"
-	err = ValidateAppFrameworkSpec(&AppFramework, &appFrameworkContext, false)
+	err = ValidateAppFrameworkSpec(&AppFramework, false)
 	if err == nil {
 		t.Errorf("Unsupported default scope should be cause error, but failed to detect")
 	}
@@ -799,7 +1141,7 @@ func TestValidateAppFrameworkSpec(t *testing.T) {
 	// Missing scope, if the default scope is not specified should return error
 	AppFramework.Defaults.Scope = ""
 	AppFramework.AppSources[0].Scope = ""
-	err = ValidateAppFrameworkSpec(&AppFramework, &appFrameworkContext, false)
+	err = ValidateAppFrameworkSpec(&AppFramework, false)
 	if err == nil {
 		t.Errorf("Missing scope should be detected, but failed")
 	}
@@ -810,66 +1152,224 @@ func TestValidateAppFrameworkSpec(t *testing.T) {
 
 	AppFramework.Defaults.Scope = ""
 	AppFramework.AppSources[0].Scope = "clusterWithPreConfig"
-	err = ValidateAppFrameworkSpec(&AppFramework, &appFrameworkContext, false)
+	err = ValidateAppFrameworkSpec(&AppFramework, false)
 	if err != nil {
 		t.Errorf("Valid scope clusterWithPreConfig should not cause an error")
 	}
 	AppFramework.Defaults.Scope = enterpriseApi.ScopeLocal
 	AppFramework.AppSources[0].Scope = enterpriseApi.ScopeLocal
 
+	// Delete policy should default to Delete, and can be overridden per App Source
+	if getAppSrcDeletePolicy(&AppFramework, AppFramework.AppSources[0].Name) != enterpriseApi.DeletePolicyDelete {
+		t.Errorf("Delete policy should default to Delete")
+	}
+	AppFramework.Defaults.DeletePolicy = enterpriseApi.DeletePolicyRetain
+	AppFramework.AppSources[1].DeletePolicy = enterpriseApi.DeletePolicyDelete
+	if getAppSrcDeletePolicy(&AppFramework, AppFramework.AppSources[0].Name) != enterpriseApi.DeletePolicyRetain ||
+		getAppSrcDeletePolicy(&AppFramework, AppFramework.AppSources[1].Name) != enterpriseApi.DeletePolicyDelete {
+		t.Errorf("Delete policy of the App Source should override the one of the defaults")
+	}
+	err = ValidateAppFrameworkSpec(&AppFramework, false)
+	if err != nil {
+		t.Errorf("Valid delete policies should not cause an error. Error: %v", err)
+	}
+
+	AppFramework.AppSources[1].DeletePolicy = "Keep"
+	err = ValidateAppFrameworkSpec(&AppFramework, false)
+	if err == nil {
+		t.Errorf("Invalid delete policy for App Source should return an error")
+	}
+	AppFramework.AppSources[1].DeletePolicy = ""
+
+	AppFramework.Defaults.DeletePolicy = "Keep"
+	err = ValidateAppFrameworkSpec(&AppFramework, false)
+	if err == nil {
+		t.Errorf("Invalid delete policy for defaults should return an error")
+	}
+	AppFramework.Defaults.DeletePolicy = ""
+
+	// Signature secret of the defaults is used, unless the App Source has its own
+	AppFramework.Defaults.SignatureSecretRef = "app-signing-key"
+	AppFramework.AppSources[1].SignatureSecretRef = "other-signing-key"
+	if getAppSrcSignatureSecretRef(&AppFramework, AppFramework.AppSources[0].Name) != "app-signing-key" ||
+		getAppSrcSignatureSecretRef(&AppFramework, AppFramework.AppSources[1].Name) != "other-signing-key" {
+		t.Errorf("Signature secret of the App Source should override the one of the defaults")
+	}
+	AppFramework.Defaults.SignatureSecretRef = ""
+	AppFramework.AppSources[1].SignatureSecretRef = ""
+	if getAppSrcSignatureSecretRef(&AppFramework, AppFramework.AppSources[0].Name) != "" {
+		t.Errorf("Signature secret should be empty when not configured")
+	}
+
+	// premiumApps scope needs the type of the premium Apps, and a valid SSL enablement mode
+	AppFramework.AppSources[0].Scope = enterpriseApi.ScopePremiumApps
+	err = ValidateAppFrameworkSpec(&AppFramework, true)
+	if err == nil {
+		t.Errorf("premiumApps scope without the type of the premium Apps should return an error")
+	}
+	AppFramework.Defaults.PremiumAppsProps = enterpriseApi.PremiumAppsProps{Type: enterpriseApi.PremiumAppsTypeEs}
+	err = ValidateAppFrameworkSpec(&AppFramework, true)
+	if err != nil {
+		t.Errorf("Valid premium Apps properties should not cause an error. Error: %v", err)
+	}
+	AppFramework.AppSources[0].PremiumAppsProps = enterpriseApi.PremiumAppsProps{Type: enterpriseApi.PremiumAppsTypeEs, EsDefaults: enterpriseApi.EsDefaults{SslEnablement: "none"}}
+	err = ValidateAppFrameworkSpec(&AppFramework, true)
+	if err == nil {
+		t.Errorf("Invalid sslEnablement should return an error")
+	}
+	AppFramework.AppSources[0].PremiumAppsProps.EsDefaults.SslEnablement = enterpriseApi.SslEnablementAuto
+	if getAppSrcPremiumAppsProps(&AppFramework, AppFramework.AppSources[0].Name).EsDefaults.SslEnablement != enterpriseApi.SslEnablementAuto {
+		t.Errorf("Premium Apps properties of the App Source should override the ones of the defaults")
+	}
+	err = validatePremiumAppsScope(&AppFramework)
+	if err == nil {
+		t.Errorf("premiumApps scope should return an error for the CRs which do not support it")
+	}
+	AppFramework.AppSources[0].Scope = enterpriseApi.ScopeLocal
+	AppFramework.AppSources[0].PremiumAppsProps = enterpriseApi.PremiumAppsProps{}
+	AppFramework.Defaults.PremiumAppsProps = enterpriseApi.PremiumAppsProps{}
+	err = validatePremiumAppsScope(&AppFramework)
+	if err != nil {
+		t.Errorf("App Sources without the premiumApps scope should not cause an error. Error: %v", err)
+	}
+
+	// Install timeout should not be negative, and the one of the App Source overrides the one of the defaults
+	AppFramework.Defaults.InstallTimeoutSeconds = 1200
+	AppFramework.AppSources[1].InstallTimeoutSeconds = 300
+	if getAppSrcInstallTimeout(&AppFramework, AppFramework.AppSources[0].Name) != 1200 ||
+		getAppSrcInstallTimeout(&AppFramework, AppFramework.AppSources[1].Name) != 300 {
+		t.Errorf("Install timeout of the App Source should override the one of the defaults")
+	}
+	AppFramework.AppSources[1].InstallTimeoutSeconds = -1
+	err = ValidateAppFrameworkSpec(&AppFramework, false)
+	if err == nil {
+		t.Errorf("Negative install timeout should return an error")
+	}
+	AppFramework.AppSources[1].InstallTimeoutSeconds = 0
+	AppFramework.Defaults.InstallTimeoutSeconds = 0
+
+	// App Sources can only depend on other App Sources of the config, without cycles
+	AppFramework.AppSources[1].DependsOn = []string{AppFramework.AppSources[0].Name}
+	err = ValidateAppFrameworkSpec(&AppFramework, false)
+	if err != nil {
+		t.Errorf("Valid dependencies should not cause an error. Error: %v", err)
+	}
+	AppFramework.AppSources[0].DependsOn = []string{AppFramework.AppSources[1].Name}
+	err = ValidateAppFrameworkSpec(&AppFramework, false)
+	if err == nil {
+		t.Errorf("Circular dependencies should return an error")
+	}
+	AppFramework.AppSources[0].DependsOn = []string{"unknownApps"}
+	err = ValidateAppFrameworkSpec(&AppFramework, false)
+	if err == nil {
+		t.Errorf("Dependency on an unknown App Source should return an error")
+	}
+	AppFramework.AppSources[0].DependsOn = nil
+	AppFramework.AppSources[1].DependsOn = nil
+
+	// Selected Apps should be unique packages of the location
+	AppFramework.AppSources[0].Apps = []enterpriseApi.AppVersionSpec{{Name: "app1.tgz", ObjectHash: "d41d8cd98f00"}, {Name: "app2.spl"}}
+	err = ValidateAppFrameworkSpec(&AppFramework, false)
+	if err != nil {
+		t.Errorf("Valid selected apps should not cause an error. Error: %v", err)
+	}
+	if getPinnedAppObjectHash(&AppFramework, AppFramework.AppSources[0].Name, "app1.tgz") != "d41d8cd98f00" ||
+		getPinnedAppObjectHash(&AppFramework, AppFramework.AppSources[0].Name, "app2.spl") != "" {
+		t.Errorf("getPinnedAppObjectHash() should return the object hash of the pinned apps only")
+	}
+
+	AppFramework.AppSources[0].Apps = append(AppFramework.AppSources[0].Apps, enterpriseApi.AppVersionSpec{Name: "app1.tgz"})
+	err = ValidateAppFrameworkSpec(&AppFramework, false)
+	if err == nil {
+		t.Errorf("Duplicate selected apps should return an error")
+	}
+
+	AppFramework.AppSources[0].Apps = []enterpriseApi.AppVersionSpec{{Name: "apps/app1.tgz"}}
+	err = ValidateAppFrameworkSpec(&AppFramework, false)
+	if err == nil {
+		t.Errorf("Selected apps outside of the location should return an error")
+	}
+
+	AppFramework.AppSources[0].Apps = []enterpriseApi.AppVersionSpec{{Name: "app1.zip"}}
+	err = ValidateAppFrameworkSpec(&AppFramework, false)
+	if err == nil {
+		t.Errorf("Selected apps with an invalid extension should return an error")
+	}
+	AppFramework.AppSources[0].Apps = nil
+
 	// AppsRepoPollInterval should be in between the minAppsRepoPollInterval and maxAppsRepoPollInterval
 	// Default Poll interval
 	if splcommon.DefaultAppsRepoPollInterval < splcommon.MinAppsRepoPollInterval || splcommon.DefaultAppsRepoPollInterval > splcommon.MaxAppsRepoPollInterval {
 		t.Errorf("defaultAppsRepoPollInterval should be within the range [%d - %d]", splcommon.MinAppsRepoPollInterval, splcommon.MaxAppsRepoPollInterval)
 	}
 
-	appFrameworkContext.AppsRepoStatusPollInterval = 0
-	err = ValidateAppFrameworkSpec(&AppFramework, &appFrameworkContext, false)
-	if err != nil {
-		t.Errorf("Got error on valid App Framework configuration. Error: %v", err)
-	} else if appFrameworkContext.AppsRepoStatusPollInterval != splcommon.DefaultAppsRepoPollInterval {
-		t.Errorf("Spec validation failed to set the Repo poll interval to the default value: %d", splcommon.DefaultAppsRepoPollInterval)
+	AppFramework.AppsRepoPollInterval = 0
+	setAppFrameworkDefaults(&AppFramework)
+	if AppFramework.AppsRepoPollInterval != splcommon.DefaultAppsRepoPollInterval {
+		t.Errorf("setAppFrameworkDefaults() failed to set the Repo poll interval to the default value: %d", splcommon.DefaultAppsRepoPollInterval)
 	}
 
 	// Check for minAppsRepoPollInterval
-	appFrameworkContext.AppsRepoStatusPollInterval = splcommon.MinAppsRepoPollInterval - 1
-	err = ValidateAppFrameworkSpec(&AppFramework, &appFrameworkContext, false)
-	if err != nil {
-		t.Errorf("Got error on valid App Framework configuration. Error: %v", err)
-	} else if appFrameworkContext.AppsRepoStatusPollInterval < splcommon.MinAppsRepoPollInterval {
-		t.Errorf("Spec validation is not able to set the the AppsRepoPollInterval to minAppsRepoPollInterval")
+	AppFramework.AppsRepoPollInterval = splcommon.MinAppsRepoPollInterval - 1
+	setAppFrameworkDefaults(&AppFramework)
+	if AppFramework.AppsRepoPollInterval != splcommon.MinAppsRepoPollInterval {
+		t.Errorf("setAppFrameworkDefaults() is not able to set the the AppsRepoPollInterval to minAppsRepoPollInterval")
 	}
 
 	// Check for maxAppsRepoPollInterval
-	appFrameworkContext.AppsRepoStatusPollInterval = splcommon.MaxAppsRepoPollInterval + 1
-	err = ValidateAppFrameworkSpec(&AppFramework, &appFrameworkContext, false)
+	AppFramework.AppsRepoPollInterval = splcommon.MaxAppsRepoPollInterval + 1
+	setAppFrameworkDefaults(&AppFramework)
+	if AppFramework.AppsRepoPollInterval != splcommon.MaxAppsRepoPollInterval {
+		t.Errorf("setAppFrameworkDefaults() is not able to set the the AppsRepoPollInterval to maxAppsRepoPollInterval")
+	}
+
+	err = ValidateAppFrameworkSpec(&AppFramework, false)
 	if err != nil {
 		t.Errorf("Got error on valid App Framework configuration. Error: %v", err)
-	} else if appFrameworkContext.AppsRepoStatusPollInterval > splcommon.MaxAppsRepoPollInterval {
-		t.Errorf("Spec validation is not able to set the the AppsRepoPollInterval to maxAppsRepoPollInterval")
 	}
 
 	// Invalid volume name in defaults should return an error
 	AppFramework.Defaults.VolName = "unknownVolume"
-	err = ValidateAppFrameworkSpec(&AppFramework, &appFrameworkContext, false)
+	err = ValidateAppFrameworkSpec(&AppFramework, false)
 	if err == nil {
 		t.Errorf("Configuring Defaults with invalid volume name should return an error, but failed to detect")
 	}
 
 	// Invalid remote volume type should return error.
 	AppFramework.VolList[0].Type = "s4"
-	err = ValidateAppFrameworkSpec(&AppFramework, &appFrameworkContext, false)
+	err = ValidateAppFrameworkSpec(&AppFramework, false)
 	if err == nil {
 		t.Errorf("ValidateAppFrameworkSpec with invalid remote volume type should have returned error.")
 	}
 
 	AppFramework.VolList[0].Provider = "invalid-provider"
-	err = ValidateAppFrameworkSpec(&AppFramework, &appFrameworkContext, false)
+	err = ValidateAppFrameworkSpec(&AppFramework, false)
 	if err == nil {
 		t.Errorf("ValidateAppFrameworkSpec with invalid provider should have returned error.")
 	}
 }
 
+func TestIsValidProvider(t *testing.T) {
+	test := func(storageType string, provider string, want bool) {
+		if got := isValidStorageType(storageType) && isValidProvider(storageType, provider); got != want {
+			t.Errorf("storageType=%s provider=%s valid = %t; want %t", storageType, provider, got, want)
+		}
+	}
+
+	test("s3", "aws", true)
+	test("s3", "minio", true)
+	test("blob", "azure", true)
+	test("azure-blob", "azure", true)
+	test("gcs", "gcp", true)
+	test("pvc", "kubernetes", true)
+	test("http", "http", true)
+	test("pvc", "aws", false)
+	test("s3", "azure", false)
+	test("blob", "aws", false)
+	test("gcs", "minio", false)
+	test("", "", false)
+}
+
 func TestGetSmartstoreIndexesConfig(t *testing.T) {
 	SmartStoreIndexes := enterpriseApi.SmartStoreSpec{
 		IndexList: []enterpriseApi.IndexSpec{
@@ -901,6 +1401,14 @@ func TestGetSmartstoreIndexesConfig(t *testing.T) {
 					HotlistBloomFilterRecencyHours: 24,
 					HotlistRecencySecs:             24 * 60 * 60},
 			},
+			{Name: "metrics1", DataType: "metric", Disabled: true, FrozenTimePeriodInSecs: 2592000, MaxDataSize: "auto_high_volume",
+				ColdToFrozenDir: "/opt/splunk/var/lib/splunk/frozen/metrics1",
+				IndexAndGlobalCommonSpec: enterpriseApi.IndexAndGlobalCommonSpec{
+					VolName: "msos_s2s3_vol"},
+			},
+			{Name: "local1", Local: true, FrozenTimePeriodInSecs: 604800, MaxDataSize: "750", MaxTotalDataSizeMB: 10000,
+				MaxWarmDBCount: 100, HomePathMaxDataSizeMB: 4000, ColdPathMaxDataSizeMB: 6000, ColdToFrozenScript: `"$SPLUNK_HOME/bin/python" "$SPLUNK_HOME/bin/coldToFrozenExample.py"`,
+			},
 		},
 	}
 
@@ -926,6 +1434,24 @@ hotlist_bloom_filter_recency_hours = 24
 hotlist_recency_secs = 86400
 maxGlobalDataSizeMB = 4000
 maxGlobalRawDataSizeMB = 5000
+
+[metrics1]
+remotePath = volume:msos_s2s3_vol/$_index_name
+datatype = metric
+disabled = true
+frozenTimePeriodInSecs = 2592000
+maxDataSize = auto_high_volume
+coldToFrozenDir = /opt/splunk/var/lib/splunk/frozen/metrics1
+
+[local1]
+remotePath =
+frozenTimePeriodInSecs = 604800
+maxDataSize = 750
+maxTotalDataSizeMB = 10000
+maxWarmDBCount = 100
+homePath.maxDataSizeMB = 4000
+coldPath.maxDataSizeMB = 6000
+coldToFrozenScript = "$SPLUNK_HOME/bin/python" "$SPLUNK_HOME/bin/coldToFrozenExample.py"
 `)
 
 	indexesConfIni := GetSmartstoreIndexesConfig(SmartStoreIndexes.IndexList)
@@ -1312,3 +1838,146 @@ func TestGetProbe(t *testing.T) {
 
 	test(command, 100, 10, 10, `{"exec":{"command":["grep","ready","file.txt"]},"initialDelaySeconds":100,"timeoutSeconds":10,"periodSeconds":10}`)
 }
+
+func TestCheckSmartstoreVolumes(t *testing.T) {
+	var checked []string
+	var checkErr error
+	checkS3VolumeAccess = func(endpoint, bucket, prefix, objectName, accessKeyID, secretAccessKey string) error {
+		checked = append(checked, fmt.Sprintf("%s %s %s %s %s %s", endpoint, bucket, prefix, objectName, accessKeyID, secretAccessKey))
+		return checkErr
+	}
+	checkAzureBlobVolumeAccess = func(client *splclient.AzureBlobClient, blobName string) error {
+		checked = append(checked, fmt.Sprintf("%s %s %s %s %s %s %s", client.Endpoint, client.ContainerName, client.Prefix, blobName,
+			client.StorageAccountName, client.ClientID, client.TenantID))
+		return nil
+	}
+	checkGCSVolumeAccess = func(client *splclient.GCSClient, objectName string) error {
+		checked = append(checked, fmt.Sprintf("%s %s %s %s %t", client.Endpoint, client.BucketName, client.Prefix, objectName, client.ServiceAccountKey != ""))
+		return nil
+	}
+	defer func() {
+		checkS3VolumeAccess = splclient.CheckS3VolumeAccess
+		checkAzureBlobVolumeAccess = (*splclient.AzureBlobClient).CheckVolumeAccess
+		checkGCSVolumeAccess = (*splclient.GCSClient).CheckVolumeAccess
+	}()
+
+	cr := enterpriseApi.ClusterMaster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "stack1",
+			Namespace: "test",
+		},
+	}
+	smartstore := enterpriseApi.SmartStoreSpec{
+		VolList: []enterpriseApi.VolumeSpec{
+			{Name: "msos_s2s3_vol", Endpoint: "https://s3-eu-west-2.amazonaws.com", Path: "testbucket-rs-london", SecretRef: "s3-secret"},
+			{Name: "minio_vol", Endpoint: "http://minio.test.svc:9000", Path: "smartstore/indexes/", Type: "s3"},
+			{Name: "azure_vol", Endpoint: "https://mystorageaccount.blob.core.windows.net", Path: "smartstore-container", Type: "blob", SecretRef: "azure-secret"},
+			{Name: "azure_sp_vol", Endpoint: "https://mystorageaccount.blob.core.windows.net", Path: "smartstore-container/sp", Type: "azure-blob", SecretRef: "azure-sp-secret"},
+			{Name: "gcs_vol", Path: "smartstore-bucket/indexes", Type: "gcs", SecretRef: "gcs-secret"},
+			{Name: "gcs_wi_vol", Path: "smartstore-bucket", Type: "gcs"},
+		},
+	}
+
+	c := spltest.NewMockClient()
+	err := checkSmartstoreVolumes(c, &cr, SplunkClusterMaster, &smartstore)
+	if err == nil {
+		t.Errorf("checkSmartstoreVolumes() should have returned an error for a missing secret")
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "s3-secret", Namespace: "test"},
+		Data:       map[string][]byte{s3AccessKey: []byte("abcd")},
+	}
+	c.AddObject(secret)
+	err = checkSmartstoreVolumes(c, &cr, SplunkClusterMaster, &smartstore)
+	if err == nil {
+		t.Errorf("checkSmartstoreVolumes() should have returned an error for a missing secret key")
+	}
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Unable to generate a RSA key: %v", err)
+	}
+	serviceAccountKey, _ := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"client_email": "smartstore@splunk.iam.gserviceaccount.com",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)})),
+		"token_uri":    "https://oauth2.googleapis.com/token",
+	})
+	c.AddObject(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "azure-secret", Namespace: "test"},
+		Data:       map[string][]byte{azureBlobAccountName: []byte("mystorageaccount"), azureBlobAccountKey: []byte("MTIzNA==")},
+	})
+	c.AddObject(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "azure-sp-secret", Namespace: "test"},
+		Data:       map[string][]byte{azureClientID: []byte("client1"), azureClientSecret: []byte("secret1"), azureTenantID: []byte("tenant1")},
+	})
+	c.AddObject(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "gcs-secret", Namespace: "test"},
+		Data:       map[string][]byte{gcsServiceAccountKey: serviceAccountKey},
+	})
+
+	// the volumes without secretRef are not checked
+	checked = nil
+	secret.Data[s3SecretKey] = []byte("1234")
+	err = checkSmartstoreVolumes(c, &cr, SplunkClusterMaster, &smartstore)
+	if err != nil {
+		t.Errorf("checkSmartstoreVolumes() returned %v", err)
+	}
+	want := []string{
+		"https://s3-eu-west-2.amazonaws.com testbucket-rs-london  .splunk-operator-check-test-splunk-stack1-cluster-master abcd 1234",
+		"https://mystorageaccount.blob.core.windows.net smartstore-container  .splunk-operator-check-test-splunk-stack1-cluster-master mystorageaccount  ",
+		"https://mystorageaccount.blob.core.windows.net smartstore-container sp/ .splunk-operator-check-test-splunk-stack1-cluster-master  client1 tenant1",
+		"https://storage.googleapis.com smartstore-bucket indexes/ .splunk-operator-check-test-splunk-stack1-cluster-master true",
+	}
+	if fmt.Sprint(checked) != fmt.Sprint(want) {
+		t.Errorf("checkSmartstoreVolumes() checked %q; want %q", checked, want)
+	}
+	if unverified := getUnverifiedSmartstoreVolumes(&smartstore); fmt.Sprint(unverified) != "[minio_vol gcs_wi_vol]" {
+		t.Errorf("getUnverifiedSmartstoreVolumes() = %v; want [minio_vol gcs_wi_vol]", unverified)
+	}
+
+	checkErr = fmt.Errorf("NoSuchBucket: The specified bucket does not exist")
+	err = checkSmartstoreVolumes(c, &cr, SplunkClusterMaster, &smartstore)
+	if _, ok := err.(*smartstoreVolumeError); !ok || err.Error() != "Smartstore volume msos_s2s3_vol failed the access check: NoSuchBucket: The specified bucket does not exist" {
+		t.Errorf("checkSmartstoreVolumes() = %v; want the error of the volume msos_s2s3_vol", err)
+	}
+}
+
+func TestCheckSmartstoreIndexRemovals(t *testing.T) {
+	cr := enterpriseApi.ClusterMaster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "stack1",
+			Namespace: "test",
+		},
+	}
+	cr.Status.SmartStore.IndexList = []enterpriseApi.IndexSpec{{Name: "salesdata1"}, {Name: "salesdata2", Disabled: true}, {Name: "salesdata3"}}
+	cr.Spec.SmartStore.IndexList = []enterpriseApi.IndexSpec{{Name: "salesdata1"}, {Name: "sales_data3"}}
+
+	test := func(wantRemoved []string, wantErr string) {
+		t.Helper()
+		removed, err := checkSmartstoreIndexRemovals(&cr, &cr.Spec.SmartStore, &cr.Status.SmartStore)
+		if fmt.Sprint(removed) != fmt.Sprint(wantRemoved) {
+			t.Errorf("checkSmartstoreIndexRemovals() removed %v; want %v", removed, wantRemoved)
+		}
+		if wantErr == "" && err != nil {
+			t.Errorf("checkSmartstoreIndexRemovals() returned %v", err)
+		} else if _, ok := err.(*smartstoreIndexRemovalError); wantErr != "" && (!ok || err.Error() != wantErr) {
+			t.Errorf("checkSmartstoreIndexRemovals() = %v; want %s", err, wantErr)
+		}
+	}
+
+	// renaming salesdata3 removes it, and the disabled salesdata2 waits for its bundle push
+	cr.Status.BundlePushTracker.NeedToPushMasterApps = true
+	test(nil, "Removal of the Smartstore indexes salesdata2, salesdata3 is not confirmed: disable them first, or list them in the enterprise.splunk.com/confirm-index-removal annotation")
+
+	cr.Status.BundlePushTracker.NeedToPushMasterApps = false
+	test(nil, "Removal of the Smartstore indexes salesdata3 is not confirmed: disable them first, or list them in the enterprise.splunk.com/confirm-index-removal annotation")
+
+	cr.Annotations = map[string]string{confirmIndexRemovalAnnotation: "salesdata0, salesdata3"}
+	test([]string{"salesdata2", "salesdata3"}, "")
+
+	// nothing is removed before the first configuration is applied
+	cr.Status.SmartStore.IndexList = nil
+	test(nil, "")
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2018-2021 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enterprise
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+
+	splcommon "github.com/splunk/splunk-operator/pkg/splunk/common"
+)
+
+// reasons used for the events emitted about the custom resources
+const (
+	eventReasonValidationFailed        = "ValidationFailed"
+	eventReasonScalingUp               = "ScalingUp"
+	eventReasonScalingDown             = "ScalingDown"
+	eventReasonUpdating                = "Updating"
+	eventReasonPodRecycle              = "PodRecycle"
+	eventReasonRollingUpgrade          = "RollingUpgrade"
+	eventReasonRollingRestart          = "RollingRestart"
+	eventReasonUpgradeHeld             = "UpgradeHeld"
+	eventReasonVolumeExpansion         = "VolumeExpansion"
+	eventReasonStorageMigration        = "StorageMigration"
+	eventReasonDecommission            = "Decommission"
+	eventReasonBundlePushed            = "BundlePushed"
+	eventReasonMaintenanceModeEnabled  = "MaintenanceModeEnabled"
+	eventReasonMaintenanceModeDisabled = "MaintenanceModeDisabled"
+	eventReasonSecretRotated           = "SecretRotated"
+	eventReasonAppRepoChanged          = "AppRepoChanged"
+	eventReasonAppInstallFailed        = "AppInstallFailed"
+	eventReasonVolumeCheckFailed       = "VolumeCheckFailed"
+	eventReasonIndexRemovalBlocked     = "IndexRemovalBlocked"
+	eventReasonIndexRemoved            = "IndexRemoved"
+)
+
+// eventPublisher emits events about a custom resource. A nil eventPublisher, or one without a recorder, drops all events.
+type eventPublisher struct {
+	recorder record.EventRecorder
+	instance splcommon.MetaObject
+}
+
+// newEventPublisher returns an eventPublisher for a custom resource
+func newEventPublisher(recorder record.EventRecorder, instance splcommon.MetaObject) *eventPublisher {
+	return &eventPublisher{
+		recorder: recorder,
+		instance: instance,
+	}
+}
+
+// Normal emits an event of type Normal
+func (p *eventPublisher) Normal(reason, messageFmt string, args ...interface{}) {
+	p.publish(corev1.EventTypeNormal, reason, fmt.Sprintf(messageFmt, args...))
+}
+
+// Warning emits an event of type Warning
+func (p *eventPublisher) Warning(reason, messageFmt string, args ...interface{}) {
+	p.publish(corev1.EventTypeWarning, reason, fmt.Sprintf(messageFmt, args...))
+}
+
+// publish emits an event using the recorder, if there is one
+func (p *eventPublisher) publish(eventType, reason, message string) {
+	if p == nil || p.recorder == nil {
+		return
+	}
+	p.recorder.Event(p.instance, eventType, reason, message)
+}
+
+// PhaseChanged emits an event when the pods of a custom resource start scaling or being updated
+func (p *eventPublisher) PhaseChanged(oldPhase, newPhase splcommon.Phase, replicas int32) {
+	if oldPhase == newPhase {
+		return
+	}
+	switch newPhase {
+	case splcommon.PhaseScalingUp:
+		p.Normal(eventReasonScalingUp, "Scaling up to %d replicas", replicas)
+	case splcommon.PhaseScalingDown:
+		p.Normal(eventReasonScalingDown, "Scaling down to %d replicas", replicas)
+	case splcommon.PhaseUpdating:
+		p.Normal(eventReasonUpdating, "Updating pods to the latest revision")
+	}
+}
@@ -0,0 +1,188 @@
+// Copyright (c) 2018-2021 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enterprise
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+
+	enterpriseApi "github.com/splunk/splunk-operator/pkg/apis/enterprise/v2"
+	splclient "github.com/splunk/splunk-operator/pkg/splunk/client"
+	splcommon "github.com/splunk/splunk-operator/pkg/splunk/common"
+	spltest "github.com/splunk/splunk-operator/pkg/splunk/test"
+)
+
+func checkEvents(t *testing.T, method string, recorder *record.FakeRecorder, want ...string) {
+	for _, w := range want {
+		select {
+		case got := <-recorder.Events:
+			if got != w {
+				t.Errorf("%s: event = %s; want %s", method, got, w)
+			}
+		default:
+			t.Errorf("%s: missing event %s", method, w)
+		}
+	}
+	select {
+	case got := <-recorder.Events:
+		t.Errorf("%s: unexpected event %s", method, got)
+	default:
+	}
+}
+
+func TestEventPublisher(t *testing.T) {
+	cr := enterpriseApi.Standalone{
+		ObjectMeta: metav1.ObjectMeta{Name: "stack1", Namespace: "test"},
+	}
+	recorder := record.NewFakeRecorder(10)
+	p := newEventPublisher(recorder, &cr)
+
+	p.Normal(eventReasonSecretRotated, "Changed idxc_secret on %s", "splunk-stack1-indexer-0")
+	p.Warning(eventReasonValidationFailed, "%v", "invalid spec")
+	checkEvents(t, "eventPublisher", recorder, "Normal SecretRotated Changed idxc_secret on splunk-stack1-indexer-0", "Warning ValidationFailed invalid spec")
+
+	p.PhaseChanged(splcommon.PhaseReady, splcommon.PhaseScalingUp, 3)
+	p.PhaseChanged(splcommon.PhaseScalingUp, splcommon.PhaseScalingUp, 3)
+	p.PhaseChanged(splcommon.PhaseReady, splcommon.PhaseScalingDown, 2)
+	p.PhaseChanged(splcommon.PhaseReady, splcommon.PhaseUpdating, 2)
+	p.PhaseChanged(splcommon.PhaseUpdating, splcommon.PhaseReady, 2)
+	checkEvents(t, "eventPublisher.PhaseChanged", recorder,
+		"Normal ScalingUp Scaling up to 3 replicas",
+		"Normal ScalingDown Scaling down to 2 replicas",
+		"Normal Updating Updating pods to the latest revision")
+
+	// publishers without a recorder drop the events
+	var nilPublisher *eventPublisher
+	nilPublisher.Normal(eventReasonBundlePushed, "Pushed cluster manager bundle to the indexer cluster peers")
+	newEventPublisher(nil, &cr).Warning(eventReasonValidationFailed, "%v", "invalid spec")
+}
+
+func TestApplyStandaloneEvents(t *testing.T) {
+	c := spltest.NewMockClient()
+	recorder := record.NewFakeRecorder(10)
+	cr := enterpriseApi.Standalone{
+		ObjectMeta: metav1.ObjectMeta{Name: "stack1", Namespace: "test"},
+	}
+	cr.Spec.ImagePullPolicy = "Invalid"
+	_, err := ApplyStandalone(c, recorder, &cr)
+	if err == nil {
+		t.Errorf("ApplyStandalone() should have returned an error for an invalid spec")
+	}
+	checkEvents(t, "ApplyStandalone", recorder, "Warning ValidationFailed ImagePullPolicy must be one of \"Always\" or \"IfNotPresent\"; value=\"Invalid\"")
+}
+
+func TestIndexerClusterPodManagerEvents(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	cr := enterpriseApi.IndexerCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "stack1", Namespace: "test"},
+	}
+	cr.Status.Peers = []enterpriseApi.IndexerClusterMemberStatus{{ID: "peer0", Status: "Decommissioning"}}
+	mgr := indexerClusterPodManager{log: log, cr: &cr, eventPublisher: newEventPublisher(recorder, &cr)}
+
+	// waiting on a peer that is already decommissioning does not emit events
+	if ready, err := mgr.PrepareRecycle(0); ready || err != nil {
+		t.Errorf("PrepareRecycle() = %t, %v; want false, nil", ready, err)
+	}
+	if ready, err := mgr.PrepareScaleDown(0); ready || err != nil {
+		t.Errorf("PrepareScaleDown() = %t, %v; want false, nil", ready, err)
+	}
+	checkEvents(t, "indexerClusterPodManager", recorder)
+}
+
+func TestApplyClusterMasterVolumeCheckEvents(t *testing.T) {
+	checkS3VolumeAccess = func(endpoint, bucket, prefix, objectName, accessKeyID, secretAccessKey string) error {
+		return fmt.Errorf("AccessDenied: Access Denied")
+	}
+	defer func() { checkS3VolumeAccess = splclient.CheckS3VolumeAccess }()
+
+	c := spltest.NewMockClient()
+	recorder := record.NewFakeRecorder(10)
+	cr := enterpriseApi.ClusterMaster{
+		TypeMeta:   metav1.TypeMeta{Kind: "ClusterMaster"},
+		ObjectMeta: metav1.ObjectMeta{Name: "stack1", Namespace: "test"},
+	}
+	cr.Spec.SmartStore.VolList = []enterpriseApi.VolumeSpec{{Name: "msos_s2s3_vol", Endpoint: "https://s3-eu-west-2.amazonaws.com", Path: "testbucket-rs-london", SecretRef: "s3-secret"}}
+	c.AddObject(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "s3-secret", Namespace: "test"},
+		Data:       map[string][]byte{s3AccessKey: []byte("abcd"), s3SecretKey: []byte("1234")},
+	})
+	_, err := ApplyClusterMaster(c, recorder, &cr)
+	if err == nil {
+		t.Errorf("ApplyClusterMaster() should have returned an error for a volume failing the access check")
+	}
+	checkEvents(t, "ApplyClusterMaster", recorder, "Warning VolumeCheckFailed Smartstore volume msos_s2s3_vol failed the access check: AccessDenied: Access Denied")
+
+	// the configuration of the failing volume is neither applied nor pushed to the peers
+	configMap := corev1.ConfigMap{}
+	namespacedName := types.NamespacedName{Namespace: "test", Name: GetSplunkSmartstoreConfigMapName("stack1", "ClusterMaster")}
+	if c.Get(context.TODO(), namespacedName, &configMap) == nil {
+		t.Errorf("ApplyClusterMaster() should not have applied the Smartstore ConfigMap")
+	}
+	if cr.Status.BundlePushTracker.NeedToPushMasterApps {
+		t.Errorf("ApplyClusterMaster() should not have requested a bundle push")
+	}
+	checkCondition(t, "ApplyClusterMaster", cr.Status.Conditions, splcommon.ConditionSmartStoreConfigured, corev1.ConditionFalse, reasonSmartStoreCheckFailed)
+
+	// the failing volume does not block the deletion
+	currentTime := metav1.NewTime(time.Now())
+	cr.ObjectMeta.DeletionTimestamp = &currentTime
+	cr.Spec.Mock = true
+	_, err = ApplyClusterMaster(c, recorder, &cr)
+	if err != nil {
+		t.Errorf("ApplyClusterMaster() returned %v; want the CR deleted", err)
+	}
+	checkEvents(t, "ApplyClusterMaster", recorder)
+}
+
+func TestApplyStandaloneIndexRemovalEvents(t *testing.T) {
+	c := spltest.NewMockClient()
+	recorder := record.NewFakeRecorder(10)
+	cr := enterpriseApi.Standalone{
+		TypeMeta:   metav1.TypeMeta{Kind: "Standalone"},
+		ObjectMeta: metav1.ObjectMeta{Name: "stack1", Namespace: "test"},
+	}
+	cr.Spec.SmartStore.VolList = []enterpriseApi.VolumeSpec{{Name: "msos_s2s3_vol", Endpoint: "https://s3-eu-west-2.amazonaws.com", Path: "testbucket-rs-london"}}
+	cr.Spec.SmartStore.IndexList = []enterpriseApi.IndexSpec{
+		{Name: "salesdata1", IndexAndGlobalCommonSpec: enterpriseApi.IndexAndGlobalCommonSpec{VolName: "msos_s2s3_vol"}},
+	}
+	cr.Status.SmartStore = *cr.Spec.SmartStore.DeepCopy()
+	cr.Spec.SmartStore.IndexList = nil
+
+	_, err := ApplyStandalone(c, recorder, &cr)
+	if err == nil {
+		t.Errorf("ApplyStandalone() should have returned an error for an index removal without confirmation")
+	}
+	checkEvents(t, "ApplyStandalone", recorder, "Warning IndexRemovalBlocked Removal of the Smartstore indexes salesdata1 is not confirmed: disable them first, or list them in the enterprise.splunk.com/confirm-index-removal annotation")
+	checkCondition(t, "ApplyStandalone", cr.Status.Conditions, splcommon.ConditionSmartStoreConfigured, corev1.ConditionFalse, reasonIndexRemovalBlocked)
+	if len(cr.Status.SmartStore.IndexList) != 1 {
+		t.Errorf("ApplyStandalone() should have kept the Smartstore configuration of the index removed without confirmation")
+	}
+
+	// the removal without confirmation does not block the deletion
+	currentTime := metav1.NewTime(time.Now())
+	cr.ObjectMeta.DeletionTimestamp = &currentTime
+	_, err = ApplyStandalone(c, recorder, &cr)
+	if err != nil {
+		t.Errorf("ApplyStandalone() returned %v; want the CR deleted", err)
+	}
+	checkEvents(t, "ApplyStandalone", recorder)
+}
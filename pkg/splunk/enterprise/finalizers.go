@@ -17,10 +17,14 @@ package enterprise
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 
 	corev1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	enterpriseApi "github.com/splunk/splunk-operator/pkg/apis/enterprise/v2"
 	splcommon "github.com/splunk/splunk-operator/pkg/splunk/common"
 	splctrl "github.com/splunk/splunk-operator/pkg/splunk/controller"
 )
@@ -54,17 +58,16 @@ func DeleteSplunkPvc(cr splcommon.MetaObject, c splcommon.ControllerClient) erro
 		return nil
 	}
 
+	spec, err := getCommonSplunkSpec(cr)
+	if err == nil && spec.PvcRetentionPolicy == splcommon.PvcRetentionPolicyRetain {
+		scopedLog.Info("Retaining PVCs", "pvcRetentionPolicy", spec.PvcRetentionPolicy)
+		return nil
+	}
+
 	for _, component := range components {
 		// get list of PVCs associated with this CR
-		labels := map[string]string{
-			"app.kubernetes.io/instance": fmt.Sprintf("splunk-%s-%s", cr.GetName(), component),
-		}
-		listOpts := []client.ListOption{
-			client.InNamespace(cr.GetNamespace()),
-			client.MatchingLabels(labels),
-		}
-		pvclist := corev1.PersistentVolumeClaimList{}
-		if err := c.List(context.Background(), &pvclist, listOpts...); err != nil {
+		pvclist, err := getSplunkPvcs(c, cr, component)
+		if err != nil {
 			return err
 		}
 
@@ -84,3 +87,42 @@ func DeleteSplunkPvc(cr splcommon.MetaObject, c splcommon.ControllerClient) erro
 	}
 	return nil
 }
+
+// getSplunkPvcs returns the PersistentVolumeClaims used by a component of a custom resource
+func getSplunkPvcs(c splcommon.ControllerClient, cr splcommon.MetaObject, component string) (*corev1.PersistentVolumeClaimList, error) {
+	labels := map[string]string{
+		"app.kubernetes.io/instance": fmt.Sprintf("splunk-%s-%s", cr.GetName(), component),
+	}
+	listOpts := []client.ListOption{
+		client.InNamespace(cr.GetNamespace()),
+		client.MatchingLabels(labels),
+	}
+	pvclist := corev1.PersistentVolumeClaimList{}
+	err := c.List(context.Background(), &pvclist, listOpts...)
+	return &pvclist, err
+}
+
+// getOrphanedClaims returns the PersistentVolumeClaims retained for the pods of a custom resource that were removed
+// by a scale down, if its pvcRetentionPolicy keeps them
+func getOrphanedClaims(c splcommon.ControllerClient, cr splcommon.MetaObject, spec *enterpriseApi.CommonSplunkSpec, instanceType InstanceType, replicas int32) ([]string, error) {
+	if spec.PvcRetentionPolicy == "" || spec.PvcRetentionPolicy == splcommon.PvcRetentionPolicyDelete {
+		return nil, nil
+	}
+
+	pvclist, err := getSplunkPvcs(c, cr, instanceType.ToString())
+	if err != nil {
+		return nil, err
+	}
+
+	var orphans []string
+	for _, pvc := range pvclist.Items {
+		// the claims are named <template>-<statefulset>-<ordinal>
+		name := pvc.GetName()
+		ordinal, err := strconv.Atoi(name[strings.LastIndex(name, "-")+1:])
+		if err == nil && int32(ordinal) >= replicas {
+			orphans = append(orphans, name)
+		}
+	}
+	sort.Strings(orphans)
+	return orphans, nil
+}
@@ -16,6 +16,7 @@ package enterprise
 
 import (
 	"fmt"
+	"reflect"
 	"testing"
 	"time"
 
@@ -285,3 +286,52 @@ func TestDeleteSplunkClusterMasterPvc(t *testing.T) {
 		t.Errorf("splctrl.CheckForDeletion() returned %t, %v; want false, (error)", deleted, err)
 	}
 }
+
+func TestDeleteSplunkPvcRetain(t *testing.T) {
+	cr := enterpriseApi.Standalone{
+		TypeMeta:   metav1.TypeMeta{Kind: "Standalone"},
+		ObjectMeta: metav1.ObjectMeta{Name: "stack1", Namespace: "test"},
+	}
+	pvclist := corev1.PersistentVolumeClaimList{
+		Items: []corev1.PersistentVolumeClaim{{ObjectMeta: metav1.ObjectMeta{Name: "pvc-etc-splunk-stack1-standalone-0", Namespace: "test"}}},
+	}
+	c := spltest.NewMockClient()
+	c.ListObj = &pvclist
+
+	// the claims are still deleted with the custom resource
+	cr.Spec.PvcRetentionPolicy = splcommon.PvcRetentionPolicyRetainUntilCRDeleted
+	if err := DeleteSplunkPvc(&cr, c); err != nil || len(c.Calls["Delete"]) != 1 {
+		t.Errorf("DeleteSplunkPvc() returned %v and deleted %d PVCs; want nil and 1", err, len(c.Calls["Delete"]))
+	}
+
+	c.ResetCalls()
+	cr.Spec.PvcRetentionPolicy = splcommon.PvcRetentionPolicyRetain
+	if err := DeleteSplunkPvc(&cr, c); err != nil || len(c.Calls["Delete"]) != 0 {
+		t.Errorf("DeleteSplunkPvc() returned %v and deleted %d PVCs; want nil and 0", err, len(c.Calls["Delete"]))
+	}
+}
+
+func TestGetOrphanedClaims(t *testing.T) {
+	cr := enterpriseApi.SearchHeadCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "stack1", Namespace: "test"},
+	}
+	pvclist := corev1.PersistentVolumeClaimList{}
+	for _, name := range []string{"pvc-var-splunk-stack1-search-head-3", "pvc-etc-splunk-stack1-search-head-0", "pvc-etc-splunk-stack1-search-head-3", "pvc-etc-splunk-stack1-search-head-4"} {
+		pvclist.Items = append(pvclist.Items, corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test"}})
+	}
+	c := spltest.NewMockClient()
+	c.ListObj = &pvclist
+
+	// the claims are deleted by a scale down
+	orphans, err := getOrphanedClaims(c, &cr, &cr.Spec.CommonSplunkSpec, SplunkSearchHead, 3)
+	if err != nil || orphans != nil || len(c.Calls["List"]) != 0 {
+		t.Errorf("getOrphanedClaims() = %v, %v; want nil, nil without listing PVCs", orphans, err)
+	}
+
+	cr.Spec.PvcRetentionPolicy = splcommon.PvcRetentionPolicyRetainUntilCRDeleted
+	orphans, err = getOrphanedClaims(c, &cr, &cr.Spec.CommonSplunkSpec, SplunkSearchHead, 3)
+	want := []string{"pvc-etc-splunk-stack1-search-head-3", "pvc-etc-splunk-stack1-search-head-4", "pvc-var-splunk-stack1-search-head-3"}
+	if err != nil || !reflect.DeepEqual(orphans, want) {
+		t.Errorf("getOrphanedClaims() = %v, %v; want %v, nil", orphans, err, want)
+	}
+}
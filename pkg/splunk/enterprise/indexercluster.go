@@ -19,12 +19,15 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"sort"
 	"strconv"
 	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"github.com/go-logr/logr"
@@ -36,18 +39,31 @@ import (
 )
 
 // ApplyIndexerCluster reconciles the state of a Splunk Enterprise indexer cluster.
-func ApplyIndexerCluster(client splcommon.ControllerClient, cr *enterpriseApi.IndexerCluster) (reconcile.Result, error) {
+func ApplyIndexerCluster(client splcommon.ControllerClient, recorder record.EventRecorder, cr *enterpriseApi.IndexerCluster) (result reconcile.Result, err error) {
 
 	// unless modified, reconcile for this object will be requeued after 5 seconds
-	result := reconcile.Result{
+	result = reconcile.Result{
 		Requeue:      true,
 		RequeueAfter: time.Second * 5,
 	}
 	scopedLog := log.WithName("ApplyIndexerCluster").WithValues("name", cr.GetName(), "namespace", cr.GetNamespace())
+	eventPublisher := newEventPublisher(recorder, cr)
+	oldPhase := cr.Status.Phase
 
-	// validate and updates defaults for CR
-	err := validateIndexerClusterSpec(cr)
+	// updates status and conditions after function completes, including validation failures
+	defer func() {
+		setStatusConditions(cr, err)
+		if updateErr := client.Status().Update(context.TODO(), cr); updateErr != nil {
+			scopedLog.Error(updateErr, "Status update failed")
+		}
+	}()
+
+	// updates defaults and validates CR
+	setIndexerClusterDefaults(cr)
+	err = validateIndexerClusterSpec(cr)
 	if err != nil {
+		cr.Status.Phase = splcommon.PhaseError
+		eventPublisher.Warning(eventReasonValidationFailed, "%v", err)
 		return result, err
 	}
 
@@ -65,12 +81,6 @@ func ApplyIndexerCluster(client splcommon.ControllerClient, cr *enterpriseApi.In
 	if cr.Status.IdxcPasswordChangedSecrets == nil {
 		cr.Status.IdxcPasswordChangedSecrets = make(map[string]bool)
 	}
-	defer func() {
-		err = client.Status().Update(context.TODO(), cr)
-		if err != nil {
-			scopedLog.Error(err, "Status update failed")
-		}
-	}()
 
 	// create or update general config resources
 	namespaceScopedSecret, err := ApplySplunkConfig(client, cr, cr.Spec.CommonSplunkSpec, SplunkIndexer)
@@ -86,13 +96,19 @@ func ApplyIndexerCluster(client splcommon.ControllerClient, cr *enterpriseApi.In
 	err = client.Get(context.TODO(), namespacedName, masterIdxCluster)
 	if err == nil {
 		cr.Status.ClusterMasterPhase = masterIdxCluster.Status.Phase
+		err = validateIndexerClusterSite(cr, masterIdxCluster)
+		if err != nil {
+			return result, err
+		}
 	} else {
 		cr.Status.ClusterMasterPhase = splcommon.PhaseError
 	}
-	mgr := indexerClusterPodManager{log: scopedLog, cr: cr, secrets: namespaceScopedSecret, newSplunkClient: splclient.NewSplunkClient}
+	// hold off updates while the license and cluster managers, and the search head clusters, are upgraded
+	gate := newUpgradeGate(client, cr, &cr.Spec.CommonSplunkSpec)
+	mgr := indexerClusterPodManager{log: scopedLog, cr: cr, secrets: namespaceScopedSecret, newSplunkClient: splclient.NewSplunkClient, eventPublisher: eventPublisher, holdUpdates: gate.hold}
 	// Check if we have configured enough number(<= RF) of replicas
 	if mgr.cr.Status.ClusterMasterPhase == splcommon.PhaseReady {
-		err = mgr.verifyRFPeers(client)
+		err = mgr.verifyRFPeers(client, masterIdxCluster)
 		if err != nil {
 			return result, err
 		}
@@ -128,12 +144,33 @@ func ApplyIndexerCluster(client splcommon.ControllerClient, cr *enterpriseApi.In
 		return result, err
 	}
 
+	// create or update a PodDisruptionBudget that keeps at least one copy of each bucket searchable
+	err = applySplunkPodDisruptionBudget(client, cr, &cr.Spec.CommonSplunkSpec, SplunkIndexer, getIndexerMaxUnavailable(mgr.replicationFactor))
+	if err != nil {
+		return result, err
+	}
+
 	phase, err := mgr.Update(client, statefulSet, cr.Spec.Replicas)
 	if err != nil {
 		return result, err
 	}
+	eventPublisher.PhaseChanged(oldPhase, phase, cr.Spec.Replicas)
 	cr.Status.Phase = phase
 
+	// report the claims kept after a scale down
+	orphanedClaims, listErr := getOrphanedClaims(client, cr, &cr.Spec.CommonSplunkSpec, SplunkIndexer, cr.Spec.Replicas)
+	if listErr != nil {
+		scopedLog.Error(listErr, "Unable to list the PVCs retained after scale down")
+	} else {
+		cr.Status.OrphanedClaims = orphanedClaims
+	}
+
+	// expand the claims of the pods after the storage capacity was increased
+	cr.Status.PvcExpansion, err = applyPvcExpansion(client, cr, &cr.Spec.CommonSplunkSpec, statefulSet)
+	if err != nil {
+		return result, err
+	}
+
 	// no need to requeue if everything is ready
 	if cr.Status.Phase == splcommon.PhaseReady {
 		err = ApplyMonitoringConsole(client, cr, cr.Spec.CommonSplunkSpec, getIndexerExtraEnv(cr, cr.Spec.Replicas))
@@ -146,6 +183,7 @@ func ApplyIndexerCluster(client splcommon.ControllerClient, cr *enterpriseApi.In
 			if err != nil {
 				return result, err
 			}
+			eventPublisher.Normal(eventReasonMaintenanceModeDisabled, "Disabled maintenance mode on cluster manager %s", cr.Spec.ClusterMasterRef.Name)
 		}
 
 		// Reset idxc secret changed and namespace secret revision
@@ -163,6 +201,8 @@ func ApplyIndexerCluster(client splcommon.ControllerClient, cr *enterpriseApi.In
 			return result, err
 		}
 	}
+	requeueIfExpanding(&result, eventPublisher, cr.Status.PvcExpansion)
+	gate.requeueIfHeld(&result, eventPublisher)
 	return result, nil
 }
 
@@ -173,6 +213,17 @@ type indexerClusterPodManager struct {
 	cr              *enterpriseApi.IndexerCluster
 	secrets         *corev1.Secret
 	newSplunkClient func(managementURI, username, password string) *splclient.SplunkClient
+	eventPublisher  *eventPublisher
+
+	// replication and search factors of the indexers, as checked by verifyRFPeers
+	replicationFactor int32
+	searchFactor      int32
+
+	// holdUpdates, if set, returns true while the peers must not be recycled
+	holdUpdates func() bool
+
+	// rollingRestart is true while the cluster manager is restarting the peers, as reported by updateStatus
+	rollingRestart bool
 }
 
 // SetClusterMaintenanceMode enables/disables cluster maintenance mode
@@ -268,6 +319,7 @@ func ApplyIdxcSecret(mgr *indexerClusterPodManager, replicas int32, mock bool) e
 					return err
 				}
 				scopedLog.Info("Set Cm in maintenance mode")
+				mgr.eventPublisher.Normal(eventReasonMaintenanceModeEnabled, "Enabled maintenance mode on cluster manager %s to change idxc_secret", mgr.cr.Spec.ClusterMasterRef.Name)
 			}
 
 			// If idxc secret already changed, ignore
@@ -286,6 +338,7 @@ func ApplyIdxcSecret(mgr *indexerClusterPodManager, replicas int32, mock bool) e
 				return err
 			}
 			scopedLog.Info("Changed idxc secret")
+			mgr.eventPublisher.Normal(eventReasonSecretRotated, "Changed idxc_secret on %s", indexerPodName)
 
 			// Restart splunk instance on pod
 			err = idxcClient.RestartSplunk()
@@ -378,8 +431,133 @@ func (mgr *indexerClusterPodManager) Update(c splcommon.ControllerClient, statef
 		return splcommon.PhasePending, nil
 	}
 
+	// recycle the peers within a searchable rolling upgrade of the cluster
+	err = mgr.applyRollingUpgrade(statefulSet)
+	if err != nil {
+		return splcommon.PhaseError, err
+	}
+
 	// manage scaling and updates
-	return splctrl.UpdateStatefulSetPods(c, statefulSet, mgr, desiredReplicas)
+	phase, err := splctrl.UpdateStatefulSetPods(c, statefulSet, mgr, desiredReplicas)
+	if err == nil && phase == splcommon.PhaseReady {
+		err = mgr.finishRollingUpgrade(statefulSet)
+	}
+
+	// restart the peers within a searchable rolling restart when the configuration bundle requires it
+	if err == nil && phase == splcommon.PhaseReady {
+		err = mgr.applyRollingRestart()
+	}
+	return phase, err
+}
+
+// isRolloutHeld returns true if the rollout strategy holds the update of some peers, until the rollout is resumed
+// or the partition is lowered
+func isRolloutHeld(strategy splcommon.RolloutStrategy) bool {
+	return strategy.Paused || (strategy.Partition != nil && *strategy.Partition > 0)
+}
+
+// applyRollingUpgrade starts a searchable rolling upgrade on the cluster manager when some peers are not running the
+// latest revision of the indexer StatefulSet, and tracks the progress of the peers in the IndexerCluster status
+func (mgr *indexerClusterPodManager) applyRollingUpgrade(statefulSet *appsv1.StatefulSet) error {
+	if statefulSet.Status.UpdateRevision == "" || statefulSet.Status.UpdatedReplicas >= statefulSet.Status.Replicas {
+		return nil
+	}
+
+	upgrade := mgr.cr.Status.RollingUpgrade
+	if upgrade == nil {
+		// the rolling upgrade is only started once all the peers can be recycled, so that it is finalized; the peers
+		// updated while the rollout is held, e.g. canaries, are taken offline one after the other
+		if isRolloutHeld(mgr.GetRolloutStrategy()) {
+			return nil
+		}
+		c := mgr.getClusterMasterClient()
+		err := c.InitRollingUpgrade()
+		if err != nil {
+			return err
+		}
+		upgrade = &enterpriseApi.IndexerClusterRollingUpgradeStatus{StartTime: metav1.Now()}
+		mgr.cr.Status.RollingUpgrade = upgrade
+		mgr.eventPublisher.Normal(eventReasonRollingUpgrade, "Started a searchable rolling upgrade of the indexer cluster peers on cluster manager %s", mgr.cr.Spec.ClusterMasterRef.Name)
+	}
+
+	// the revision changes if the StatefulSet is updated again before all the peers are recycled
+	upgrade.Revision = statefulSet.Status.UpdateRevision
+	if len(statefulSet.Spec.Template.Spec.Containers) > 0 {
+		upgrade.Image = statefulSet.Spec.Template.Spec.Containers[0].Image
+	}
+	upgrade.UpdatedPeers = statefulSet.Status.UpdatedReplicas
+	upgrade.TotalPeers = statefulSet.Status.Replicas
+	return nil
+}
+
+// finishRollingUpgrade finalizes the searchable rolling upgrade on the cluster manager once all the peers are running
+// the latest revision of the indexer StatefulSet, or once the rollout is held by the rollout strategy, so that the
+// cluster manager does not stay in rolling upgrade mode until the rollout is resumed
+func (mgr *indexerClusterPodManager) finishRollingUpgrade(statefulSet *appsv1.StatefulSet) error {
+	upgrade := mgr.cr.Status.RollingUpgrade
+	if upgrade == nil {
+		return nil
+	}
+	held := isRolloutHeld(mgr.GetRolloutStrategy())
+	if statefulSet.Status.UpdatedReplicas < statefulSet.Status.Replicas && !held {
+		return nil
+	}
+
+	c := mgr.getClusterMasterClient()
+	err := c.FinalizeRollingUpgrade()
+	if err != nil {
+		return err
+	}
+	mgr.log.Info("Finalized rolling upgrade", "revision", upgrade.Revision, "peers", statefulSet.Status.UpdatedReplicas, "held", held)
+	if held {
+		mgr.eventPublisher.Normal(eventReasonRollingUpgrade, "Finalized the rolling upgrade of indexer cluster peers to %s, the rollout is held with %d of %d peers updated", upgrade.Image, statefulSet.Status.UpdatedReplicas, statefulSet.Status.Replicas)
+	} else {
+		mgr.eventPublisher.Normal(eventReasonRollingUpgrade, "Finalized the rolling upgrade of %d indexer cluster peers to %s", statefulSet.Status.Replicas, upgrade.Image)
+	}
+	mgr.cr.Status.RollingUpgrade = nil
+	return nil
+}
+
+// applyRollingRestart starts a searchable rolling restart on the cluster manager when some peers must be restarted to
+// apply the latest configuration bundle, and tracks the peers left to restart in the IndexerCluster status. Rolling
+// restarts started by the cluster manager itself, e.g. after a bundle push, are tracked as well.
+func (mgr *indexerClusterPodManager) applyRollingRestart() error {
+	var pending int32
+	for _, peer := range mgr.cr.Status.Peers {
+		if peer.RestartRequired {
+			pending++
+		}
+	}
+
+	restart := mgr.cr.Status.RollingRestart
+	if restart != nil || mgr.rollingRestart {
+		if !mgr.rollingRestart && pending == 0 {
+			mgr.log.Info("Completed rolling restart")
+			mgr.eventPublisher.Normal(eventReasonRollingRestart, "Completed the rolling restart of the indexer cluster peers")
+			mgr.cr.Status.RollingRestart = nil
+			return nil
+		}
+		if restart == nil {
+			restart = &enterpriseApi.IndexerClusterRollingRestartStatus{StartTime: metav1.Now()}
+			mgr.cr.Status.RollingRestart = restart
+		}
+		restart.PendingPeers = pending
+		return nil
+	}
+
+	// the peers are not restarted while they are upgraded, nor while the rollout is paused
+	if pending == 0 || mgr.cr.Status.RollingUpgrade != nil || mgr.GetRolloutStrategy().Paused {
+		return nil
+	}
+
+	c := mgr.getClusterMasterClient()
+	err := c.RollingRestartIndexerCluster()
+	if err != nil {
+		return err
+	}
+	mgr.cr.Status.RollingRestart = &enterpriseApi.IndexerClusterRollingRestartStatus{PendingPeers: pending, StartTime: metav1.Now()}
+	mgr.eventPublisher.Normal(eventReasonRollingRestart, "Started a searchable rolling restart of %d indexer cluster peers on cluster manager %s", pending, mgr.cr.Spec.ClusterMasterRef.Name)
+	return nil
 }
 
 // PrepareScaleDown for indexerClusterPodManager prepares indexer pod to be removed via scale down event; it returns true when ready
@@ -400,6 +578,9 @@ func (mgr *indexerClusterPodManager) PrepareScaleDown(n int32) (bool, error) {
 
 // PrepareRecycle for indexerClusterPodManager prepares indexer pod to be recycled for updates; it returns true when ready
 func (mgr *indexerClusterPodManager) PrepareRecycle(n int32) (bool, error) {
+	if mgr.cr.Status.Peers[n].Status == "Up" {
+		mgr.eventPublisher.Normal(eventReasonPodRecycle, "Taking indexer cluster peer %s offline before it is recycled", GetSplunkStatefulsetPodName(SplunkIndexer, mgr.cr.GetName(), n))
+	}
 	return mgr.decommission(n, false)
 }
 
@@ -411,6 +592,28 @@ func (mgr *indexerClusterPodManager) FinishRecycle(n int32) (bool, error) {
 	return mgr.cr.Status.Peers[n].Status == "Up", nil
 }
 
+// GetRolloutStrategy for indexerClusterPodManager returns the rollout strategy of the IndexerCluster, recycling
+// no more than search factor - 1 peers at the same time, so that all the buckets remain searchable
+func (mgr *indexerClusterPodManager) GetRolloutStrategy() splcommon.RolloutStrategy {
+	strategy := mgr.cr.Spec.RolloutStrategy
+	factor := mgr.replicationFactor
+	if mgr.searchFactor < factor {
+		factor = mgr.searchFactor
+	}
+	if limit := getIndexerMaxUnavailable(factor); strategy.MaxUnavailable > limit {
+		strategy.MaxUnavailable = limit
+	}
+	if mgr.holdUpdates != nil && mgr.holdUpdates() {
+		strategy.Paused = true
+	}
+	return strategy
+}
+
+// GetPvcRetentionPolicy for indexerClusterPodManager returns the PvcRetentionPolicy of the IndexerCluster
+func (mgr *indexerClusterPodManager) GetPvcRetentionPolicy() splcommon.PvcRetentionPolicy {
+	return mgr.cr.Spec.PvcRetentionPolicy
+}
+
 // decommission for indexerClusterPodManager decommissions an indexer pod; it returns true when ready
 func (mgr *indexerClusterPodManager) decommission(n int32, enforceCounts bool) (bool, error) {
 	peerName := GetSplunkStatefulsetPodName(SplunkIndexer, mgr.cr.GetName(), n)
@@ -418,6 +621,9 @@ func (mgr *indexerClusterPodManager) decommission(n int32, enforceCounts bool) (
 	switch mgr.cr.Status.Peers[n].Status {
 	case "Up":
 		mgr.log.Info("Decommissioning indexer cluster peer", "peerName", peerName, "enforceCounts", enforceCounts)
+		if enforceCounts {
+			mgr.eventPublisher.Normal(eventReasonDecommission, "Decommissioning indexer cluster peer %s", peerName)
+		}
 		c := mgr.getClient(n)
 		return false, c.DecommissionIndexerClusterPeer(enforceCounts)
 
@@ -491,6 +697,16 @@ func (mgr *indexerClusterPodManager) getClusterMasterClient() *splclient.SplunkC
 	return mgr.newSplunkClient(fmt.Sprintf("https://%s:8089", fqdnName), "admin", adminPwd)
 }
 
+// getIndexerMaxUnavailable returns the number of indexers that can be evicted at the same time by default.
+// Up to replicationFactor - 1 peers can be down without losing any bucket, and the replication factor is
+// not known until the cluster manager is ready.
+func getIndexerMaxUnavailable(replicationFactor int32) int32 {
+	if replicationFactor > 1 {
+		return replicationFactor - 1
+	}
+	return 1
+}
+
 // getSiteRepFactorOriginCount gets the origin count of the site_replication_factor
 func getSiteRepFactorOriginCount(siteRepFactor string) int32 {
 	re := regexp.MustCompile(".*origin:(?P<rf>.*),.*")
@@ -501,23 +717,41 @@ func getSiteRepFactorOriginCount(siteRepFactor string) int32 {
 
 // verifyRFPeers verifies the number of peers specified in the replicas section
 // of IndexerClsuster CR. If it is less than RF, than we set it to RF.
-func (mgr *indexerClusterPodManager) verifyRFPeers(c splcommon.ControllerClient) error {
+func (mgr *indexerClusterPodManager) verifyRFPeers(c splcommon.ControllerClient, masterIdxCluster *enterpriseApi.ClusterMaster) error {
 	if mgr.c == nil {
 		mgr.c = c
 	}
-	cm := mgr.getClusterMasterClient()
-	clusterInfo, err := cm.GetClusterInfo(false)
-	if err != nil {
-		return fmt.Errorf("Could not get cluster info from cluster master")
-	}
-	var replicationFactor int32
-	// if it is a multisite indexer cluster, check site_replication_factor
-	if clusterInfo.MultiSite == "true" {
-		replicationFactor = getSiteRepFactorOriginCount(clusterInfo.SiteReplicationFactor)
-	} else { // for single site, check replication factor
-		replicationFactor = clusterInfo.ReplicationFactor
+	var replicationFactor, searchFactor int32
+	if len(masterIdxCluster.Spec.Multisite.AvailableSites) > 0 {
+		// multisite configured through the ClusterMaster spec, check the origin of its site replication factor
+		multisite := masterIdxCluster.Spec.Multisite.DeepCopy()
+		setMultisiteDefaults(multisite)
+		err := validateMultisiteSpec(multisite)
+		if err != nil {
+			return err
+		}
+		replicationFactor = multisite.SiteReplicationFactor.Origin
+		searchFactor = multisite.SiteSearchFactor.Origin
+	} else {
+		cm := mgr.getClusterMasterClient()
+		clusterInfo, err := cm.GetClusterInfo(false)
+		if err != nil {
+			return fmt.Errorf("Could not get cluster info from cluster master")
+		}
+		// if it is a multisite indexer cluster, check site_replication_factor
+		if clusterInfo.MultiSite == "true" {
+			replicationFactor = getSiteRepFactorOriginCount(clusterInfo.SiteReplicationFactor)
+			if clusterInfo.SiteSearchFactor != "" {
+				searchFactor = getSiteRepFactorOriginCount(clusterInfo.SiteSearchFactor)
+			}
+		} else { // for single site, check replication factor
+			replicationFactor = clusterInfo.ReplicationFactor
+			searchFactor = clusterInfo.SearchFactor
+		}
 	}
 
+	mgr.replicationFactor = replicationFactor
+	mgr.searchFactor = searchFactor
 	if mgr.cr.Spec.Replicas < replicationFactor {
 		mgr.log.Info("Changing number of replicas as it is less than RF number of peers", "replicas", mgr.cr.Spec.Replicas)
 		mgr.cr.Spec.Replicas = replicationFactor
@@ -547,6 +781,7 @@ func (mgr *indexerClusterPodManager) updateStatus(statefulSet *appsv1.StatefulSe
 	mgr.cr.Status.IndexingReady = clusterInfo.IndexingReady
 	mgr.cr.Status.ServiceReady = clusterInfo.ServiceReady
 	mgr.cr.Status.MaintenanceMode = clusterInfo.MaintenanceMode
+	mgr.rollingRestart = clusterInfo.RollingRestart
 
 	// get peer information from cluster manager
 	peers, err := c.GetClusterMasterPeers()
@@ -563,6 +798,8 @@ func (mgr *indexerClusterPodManager) updateStatus(statefulSet *appsv1.StatefulSe
 			peerStatus.ActiveBundleID = peerInfo.ActiveBundleID
 			peerStatus.BucketCount = peerInfo.BucketCount
 			peerStatus.Searchable = peerInfo.Searchable
+			peerStatus.Site = peerInfo.Site
+			peerStatus.RestartRequired = peerInfo.ApplyBundleStatus.RestartRequired
 		} else {
 			mgr.log.Info("Peer is not known by cluster master", "peerName", peerName)
 		}
@@ -578,9 +815,42 @@ func (mgr *indexerClusterPodManager) updateStatus(statefulSet *appsv1.StatefulSe
 		mgr.cr.Status.Peers = mgr.cr.Status.Peers[:statefulSet.Status.Replicas]
 	}
 
+	mgr.cr.Status.Sites = getIndexerClusterSiteStatus(peers)
+
 	return nil
 }
 
+// getIndexerClusterSiteStatus counts the peers of each site known by the cluster manager, including the peers
+// of the other parts of a multisite cluster. It returns nil for single site clusters.
+func getIndexerClusterSiteStatus(peers map[string]splclient.ClusterMasterPeerInfo) []enterpriseApi.IndexerClusterSiteStatus {
+	sites := make(map[string]*enterpriseApi.IndexerClusterSiteStatus)
+	for _, peerInfo := range peers {
+		// peers of a single site cluster report the "default" site
+		if peerInfo.Site == "" || peerInfo.Site == "default" {
+			continue
+		}
+		site, ok := sites[peerInfo.Site]
+		if !ok {
+			site = &enterpriseApi.IndexerClusterSiteStatus{Name: peerInfo.Site}
+			sites[peerInfo.Site] = site
+		}
+		site.Peers++
+		if peerInfo.Status == "Up" {
+			site.UpPeers++
+		}
+	}
+	if len(sites) == 0 {
+		return nil
+	}
+
+	result := make([]enterpriseApi.IndexerClusterSiteStatus, 0, len(sites))
+	for _, site := range sites {
+		result = append(result, *site)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
 // getIndexerStatefulSet returns a Kubernetes StatefulSet object for Splunk Enterprise indexers.
 func getIndexerStatefulSet(client splcommon.ControllerClient, cr *enterpriseApi.IndexerCluster) (*appsv1.StatefulSet, error) {
 	// Note: SPLUNK_INDEXER_URL is not used by the indexer pod containers,
@@ -589,16 +859,38 @@ func getIndexerStatefulSet(client splcommon.ControllerClient, cr *enterpriseApi.
 	// 1. Introduce the new env variables in the function getIndexerExtraEnv
 	// 2. Avoid SPLUNK_INDEXER_URL in getIndexerExtraEnv for idxc CR
 	// 3. Re-introduce the call to getIndexerExtraEnv here.
-	return getSplunkStatefulSet(client, cr, &cr.Spec.CommonSplunkSpec, SplunkIndexer, cr.Spec.Replicas, make([]corev1.EnvVar, 0))
+	ss, err := getSplunkStatefulSet(client, cr, &cr.Spec.CommonSplunkSpec, SplunkIndexer, cr.Spec.Replicas, make([]corev1.EnvVar, 0))
+	if err != nil {
+		return ss, err
+	}
+
+	// Constrain the peers of a site to its availability zone
+	if cr.Spec.Zone != "" {
+		ss.Spec.Template.Spec.Affinity = splcommon.AppendNodeZoneAffinity(ss.Spec.Template.Spec.Affinity, cr.Spec.Zone)
+	}
+
+	// The peers get the Smartstore config of their cluster manager, along with the credential files of its volumes
+	namespacedName := types.NamespacedName{Namespace: cr.GetNamespace(), Name: cr.Spec.ClusterMasterRef.Name}
+	masterIdxCluster := &enterpriseApi.ClusterMaster{}
+	if client.Get(context.TODO(), namespacedName, masterIdxCluster) == nil {
+		addSmartstoreCredentialFiles(&ss.Spec.Template, &masterIdxCluster.Spec.SmartStore)
+	}
+
+	return ss, nil
 }
 
-// validateIndexerClusterSpec checks validity and makes default updates to a IndexerClusterSpec, and returns error if something is wrong.
-func validateIndexerClusterSpec(cr *enterpriseApi.IndexerCluster) error {
+// setIndexerClusterDefaults sets default values for the parameters of an IndexerClusterSpec that are not provided.
+func setIndexerClusterDefaults(cr *enterpriseApi.IndexerCluster) {
 	// We cannot have 0 replicas in IndexerCluster spec, since this refers to number of indexers in an indexer cluster
 	if cr.Spec.Replicas == 0 {
 		cr.Spec.Replicas = 1
 	}
 
+	setCommonSplunkSpecDefaults(&cr.Spec.CommonSplunkSpec)
+}
+
+// validateIndexerClusterSpec checks validity of an IndexerClusterSpec, and returns error if something is wrong.
+func validateIndexerClusterSpec(cr *enterpriseApi.IndexerCluster) error {
 	// Cannot leave clusterMasterRef field empty or else we cannot connect to CM
 	if len(cr.Spec.ClusterMasterRef.Name) == 0 {
 		return fmt.Errorf("IndexerCluster spec should refer to ClusterMaster via clusterMasterRef")
@@ -608,5 +900,34 @@ func validateIndexerClusterSpec(cr *enterpriseApi.IndexerCluster) error {
 	if len(cr.Spec.ClusterMasterRef.Namespace) > 0 && cr.Spec.ClusterMasterRef.Namespace != cr.GetNamespace() {
 		return fmt.Errorf("Multisite cluster does not support cluster master to be located in a different namespace")
 	}
+
+	if len(cr.Spec.Site) > 0 && !isValidSiteName(cr.Spec.Site) {
+		return fmt.Errorf("Invalid site name %s, site names should be site1 to site%d", cr.Spec.Site, maxMultisiteSites)
+	}
+
+	// A zone is only used to map the site of a multisite cluster to an availability zone
+	if len(cr.Spec.Zone) > 0 && len(cr.Spec.Site) == 0 {
+		return fmt.Errorf("IndexerCluster zone %s requires a site to be configured", cr.Spec.Zone)
+	}
 	return validateCommonSplunkSpec(&cr.Spec.CommonSplunkSpec)
 }
+
+// validateIndexerClusterSite checks that the site of an IndexerCluster is one of the sites of its ClusterMaster
+func validateIndexerClusterSite(cr *enterpriseApi.IndexerCluster, masterIdxCluster *enterpriseApi.ClusterMaster) error {
+	availableSites := masterIdxCluster.Spec.Multisite.AvailableSites
+
+	// multisite is either disabled or configured through the defaults of the ClusterMaster
+	if len(availableSites) == 0 {
+		return nil
+	}
+
+	if len(cr.Spec.Site) == 0 {
+		return fmt.Errorf("IndexerCluster should set a site as ClusterMaster %s is multisite", masterIdxCluster.GetName())
+	}
+	for _, site := range availableSites {
+		if site == cr.Spec.Site {
+			return nil
+		}
+	}
+	return fmt.Errorf("IndexerCluster site %s is not one of the availableSites of ClusterMaster %s", cr.Spec.Site, masterIdxCluster.GetName())
+}
@@ -16,6 +16,7 @@ package enterprise
 
 import (
 	"fmt"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -43,6 +44,8 @@ func TestApplyIndexerCluster(t *testing.T) {
 		{MetaName: "*v1.Secret-test-splunk-test-secret"},
 		{MetaName: "*v1.Secret-test-splunk-stack1-indexer-secret-v1"},
 		{MetaName: "*v2.ClusterMaster-test-master1"},
+		{MetaName: "*v2.ClusterMaster-test-master1"},
+		{MetaName: "*v1beta1.PodDisruptionBudget-test-splunk-stack1-indexer"},
 		{MetaName: "*v1.Secret-test-splunk-test-secret"},
 	}
 
@@ -56,7 +59,7 @@ func TestApplyIndexerCluster(t *testing.T) {
 	}
 	listmockCall := []spltest.MockFuncCall{
 		{ListOpts: listOpts}}
-	createCalls := map[string][]spltest.MockFuncCall{"Get": funcCalls, "Create": {funcCalls[0], funcCalls[3], funcCalls[4], funcCalls[6]}, "Update": {funcCalls[0]}, "List": {listmockCall[0]}}
+	createCalls := map[string][]spltest.MockFuncCall{"Get": funcCalls, "Create": {funcCalls[0], funcCalls[3], funcCalls[4], funcCalls[6], funcCalls[9]}, "Update": {funcCalls[0]}, "List": {listmockCall[0]}}
 	updateCalls := map[string][]spltest.MockFuncCall{"Get": funcCalls, "List": {listmockCall[0]}}
 
 	current := enterpriseApi.IndexerCluster{
@@ -82,7 +85,7 @@ func TestApplyIndexerCluster(t *testing.T) {
 	revised := current.DeepCopy()
 	revised.Spec.Image = "splunk/test"
 	reconcile := func(c *spltest.MockClient, cr interface{}) error {
-		_, err := ApplyIndexerCluster(c, cr.(*enterpriseApi.IndexerCluster))
+		_, err := ApplyIndexerCluster(c, nil, cr.(*enterpriseApi.IndexerCluster))
 		return err
 	}
 	spltest.ReconcileTesterWithoutRedundantCheck(t, "TestApplyIndexerCluster", &current, revised, createCalls, updateCalls, reconcile, true)
@@ -92,7 +95,7 @@ func TestApplyIndexerCluster(t *testing.T) {
 	revised.ObjectMeta.DeletionTimestamp = &currentTime
 	revised.ObjectMeta.Finalizers = []string{"enterprise.splunk.com/delete-pvc"}
 	deleteFunc := func(cr splcommon.MetaObject, c splcommon.ControllerClient) (bool, error) {
-		_, err := ApplyIndexerCluster(c, cr.(*enterpriseApi.IndexerCluster))
+		_, err := ApplyIndexerCluster(c, nil, cr.(*enterpriseApi.IndexerCluster))
 		return true, err
 	}
 	splunkDeletionTester(t, revised, deleteFunc)
@@ -196,14 +199,14 @@ func getIndexerClusterPodManager(method string, mockHandlers []spltest.MockHTTPH
 }
 
 // indexerClusterpodManagerVerifyRFPeersTester is used to verify replicas against RF using a indexerClusterPodManager
-func indexerClusterPodManagerVerifyRFPeersTester(t *testing.T, method string, mgr *indexerClusterPodManager,
+func indexerClusterPodManagerVerifyRFPeersTester(t *testing.T, method string, mgr *indexerClusterPodManager, cm *enterpriseApi.ClusterMaster,
 	desiredReplicas int32, wantPhase splcommon.Phase, wantCalls map[string][]spltest.MockFuncCall, wantError error) {
 
 	// initialize client
 	c := spltest.NewMockClient()
 
 	// test update
-	err := mgr.verifyRFPeers(c)
+	err := mgr.verifyRFPeers(c, cm)
 	if (err == nil && wantError != nil) ||
 		(err != nil && wantError == nil) ||
 		(err != nil && wantError != nil && err.Error() != wantError.Error()) {
@@ -217,7 +220,7 @@ func indexerClusterPodManagerVerifyRFPeersTester(t *testing.T, method string, mg
 	c.CheckCalls(t, method, wantCalls)
 }
 
-func indexerClusterPodManagerReplicasTester(t *testing.T, method string, mockHandlers []spltest.MockHTTPHandler,
+func indexerClusterPodManagerReplicasTester(t *testing.T, method string, mockHandlers []spltest.MockHTTPHandler, cm *enterpriseApi.ClusterMaster,
 	replicas int32, desiredReplicas int32, wantPhase splcommon.Phase,
 	wantCalls map[string][]spltest.MockFuncCall, wantError error) {
 
@@ -225,7 +228,7 @@ func indexerClusterPodManagerReplicasTester(t *testing.T, method string, mockHan
 	mockSplunkClient.AddHandlers(mockHandlers...)
 
 	mgr := getIndexerClusterPodManager(method, mockHandlers, mockSplunkClient, replicas)
-	indexerClusterPodManagerVerifyRFPeersTester(t, method, mgr, desiredReplicas, wantPhase, wantCalls, wantError)
+	indexerClusterPodManagerVerifyRFPeersTester(t, method, mgr, cm, desiredReplicas, wantPhase, wantCalls, wantError)
 	mockSplunkClient.CheckRequests(t, method)
 }
 
@@ -249,20 +252,36 @@ func TestVerifyRFPeers(t *testing.T) {
 	}
 
 	method := "indexerClusterPodManager.verifyRFPeers(All pods ready)"
+	cm := &enterpriseApi.ClusterMaster{}
 	// test for singlesite i.e. with replication_factor=3(on ClusterMaster) and replicas=3(on IndexerCluster)
-	indexerClusterPodManagerReplicasTester(t, method, mockHandlers, 3 /*replicas*/, 3 /*desired replicas*/, splcommon.PhaseReady, wantCalls, nil)
+	indexerClusterPodManagerReplicasTester(t, method, mockHandlers, cm, 3 /*replicas*/, 3 /*desired replicas*/, splcommon.PhaseReady, wantCalls, nil)
 
 	// test for singlesite i.e. with replication_factor=3(on ClusterMaster) and replicas=1(on IndexerCluster)
-	indexerClusterPodManagerReplicasTester(t, method, mockHandlers, 1 /*replicas*/, 3 /*desired replicas*/, splcommon.PhaseReady, wantCalls, nil)
+	indexerClusterPodManagerReplicasTester(t, method, mockHandlers, cm, 1 /*replicas*/, 3 /*desired replicas*/, splcommon.PhaseReady, wantCalls, nil)
 
 	// Now test for multi-site too
 	mockHandlers[0].Body = `{"links":{"_reload":"/services/cluster/config/_reload","_acl":"/services/cluster/config/_acl"},"origin":"https://localhost:8089/services/cluster/config","updated":"2020-10-28T21:37:07+00:00","generator":{"build":"152fb4b2bb96","version":"8.0.6"},"entry":[{"name":"config","id":"https://localhost:8089/services/cluster/config/config","updated":"1970-01-01T00:00:00+00:00","links":{"alternate":"/services/cluster/config/config","list":"/services/cluster/config/config","_reload":"/services/cluster/config/config/_reload","edit":"/services/cluster/config/config","disable":"/services/cluster/config/config/disable"},"author":"system","acl":{"app":"","can_list":true,"can_write":true,"modifiable":false,"owner":"system","perms":{"read":["admin","splunk-system-role"],"write":["admin","splunk-system-role"]},"removable":false,"sharing":"system"},"content":{"access_logging_for_heartbeats":false,"auto_rebalance_primaries":true,"buckets_to_summarize":"primaries","cluster_label":"idxc_label","cxn_timeout":60,"decommission_force_finish_idle_time":0,"decommission_force_timeout":180,"disabled":false,"eai:acl":null,"forwarderdata_rcv_port":0,"forwarderdata_use_ssl":false,"frozen_notifications_per_batch":10,"guid":"F643BA71-0D3C-4D63-A0BC-A1604AC928E3","heartbeat_period":18446744073709552000,"heartbeat_timeout":60,"master_uri":"https://127.0.0.1:8089","max_auto_service_interval":30,"max_fixup_time_ms":5000,"max_peer_build_load":2,"max_peer_rep_load":5,"max_peer_sum_rep_load":5,"max_peers_to_download_bundle":5,"max_primary_backups_per_service":10,"mode":"master","multisite":"true","notify_buckets_period":10,"notify_scan_min_period":10,"notify_scan_period":10,"percent_peers_to_restart":10,"ping_flag":true,"quiet_period":60,"rcv_timeout":60,"rebalance_primaries_execution_limit_ms":0,"rebalance_threshold":0.9,"register_forwarder_address":"","register_replication_address":"","register_search_address":"","remote_storage_upload_timeout":60,"rep_cxn_timeout":60,"rep_max_rcv_timeout":180,"rep_max_send_timeout":180,"rep_rcv_timeout":60,"rep_send_timeout":60,"replication_factor":3,"replication_port":null,"replication_use_ssl":false,"report_remote_storage_bucket_upload_to_targets":false,"reporting_delay_period":30,"restart_inactivity_timeout":600,"restart_timeout":60,"rolling_restart":"restart","search_factor":3,"search_files_retry_timeout":600,"secret":"********","send_timeout":60,"service_interval":0,"site":"site1","site_by_site":true,"site_replication_factor":"{ origin:2, total:2 }","site_search_factor":"{ origin:2, total:2 }","summary_replication":"false","use_batch_discard":"true","use_batch_mask_changes":"true","use_batch_remote_rep_changes":"false"}}],"paging":{"total":1,"perPage":10000000,"offset":0},"messages":[]}`
 
 	//test for multisite i.e. with site_replication_factor=origin:2,total:2(on ClusterMaster) and replicas=2(on IndexerCluster)
-	indexerClusterPodManagerReplicasTester(t, method, mockHandlers, 2 /*replicas*/, 2 /*desired replicas*/, splcommon.PhaseReady, wantCalls, nil)
+	indexerClusterPodManagerReplicasTester(t, method, mockHandlers, cm, 2 /*replicas*/, 2 /*desired replicas*/, splcommon.PhaseReady, wantCalls, nil)
 
 	//test for multisite i.e. with site_replication_factor=origin:2,total:2(on ClusterMaster) and replicas=1(on IndexerCluster)
-	indexerClusterPodManagerReplicasTester(t, method, mockHandlers, 1 /*replicas*/, 2 /*desired replicas*/, splcommon.PhaseReady, wantCalls, nil)
+	indexerClusterPodManagerReplicasTester(t, method, mockHandlers, cm, 1 /*replicas*/, 2 /*desired replicas*/, splcommon.PhaseReady, wantCalls, nil)
+
+	// multisite configured through the ClusterMaster spec does not need to query the cluster manager
+	cm.Spec.Multisite = enterpriseApi.MultisiteSpec{
+		AvailableSites:        []string{"site1", "site2"},
+		SiteReplicationFactor: enterpriseApi.SiteFactorSpec{Origin: 3, Total: 4},
+	}
+	indexerClusterPodManagerReplicasTester(t, method, []spltest.MockHTTPHandler{}, cm, 1 /*replicas*/, 3 /*desired replicas*/, splcommon.PhaseReady, map[string][]spltest.MockFuncCall{}, nil)
+
+	// default site_replication_factor origin is 2
+	cm.Spec.Multisite.SiteReplicationFactor = enterpriseApi.SiteFactorSpec{}
+	indexerClusterPodManagerReplicasTester(t, method, []spltest.MockHTTPHandler{}, cm, 1 /*replicas*/, 2 /*desired replicas*/, splcommon.PhaseReady, map[string][]spltest.MockFuncCall{}, nil)
+
+	// invalid multisite spec
+	cm.Spec.Multisite.AvailableSites = []string{"site0"}
+	indexerClusterPodManagerReplicasTester(t, method, []spltest.MockHTTPHandler{}, cm, 1 /*replicas*/, 1 /*desired replicas*/, splcommon.PhaseReady, map[string][]spltest.MockFuncCall{}, fmt.Errorf("Invalid site name site0, site names should be site1 to site63"))
 }
 
 func checkResponseFromUpdateStatus(t *testing.T, method string, mockHandlers []spltest.MockHTTPHandler, replicas int32, statefulSet *appsv1.StatefulSet, retry bool) error {
@@ -464,6 +483,163 @@ func TestInvalidPeerInFinishRecycle(t *testing.T) {
 	}
 }
 
+func TestIndexerClusterRollingUpgrade(t *testing.T) {
+	var replicas int32 = 3
+	statefulSet := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "splunk-stack1-indexer",
+			Namespace: "test",
+		},
+		Spec: appsv1.StatefulSetSpec{
+			Replicas: &replicas,
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "splunk", Image: "splunk/splunk:8.2.0"}}},
+			},
+		},
+		Status: appsv1.StatefulSetStatus{
+			Replicas:        replicas,
+			ReadyReplicas:   replicas,
+			UpdatedReplicas: 1,
+			UpdateRevision:  "v1",
+		},
+	}
+	test := func(method string, url string, call func(mgr *indexerClusterPodManager) error, mgr *indexerClusterPodManager) {
+		mockSplunkClient := &spltest.MockHTTPClient{}
+		if url != "" {
+			mockSplunkClient.AddHandlers(spltest.MockHTTPHandler{Method: "POST", URL: url, Status: 200})
+		}
+		mgr.newSplunkClient = func(managementURI, username, password string) *splclient.SplunkClient {
+			c := splclient.NewSplunkClient(managementURI, username, password)
+			c.Client = mockSplunkClient
+			return c
+		}
+		if err := call(mgr); err != nil {
+			t.Errorf("%s returned %v; want nil", method, err)
+		}
+		mockSplunkClient.CheckRequests(t, method)
+	}
+	applyRollingUpgrade := func(mgr *indexerClusterPodManager) error { return mgr.applyRollingUpgrade(statefulSet) }
+	finishRollingUpgrade := func(mgr *indexerClusterPodManager) error { return mgr.finishRollingUpgrade(statefulSet) }
+	cmURL := "https://splunk-master1-cluster-master-service.test.svc.cluster.local:8089/services/cluster/master/control/control/"
+
+	mgr := getIndexerClusterPodManager("TestIndexerClusterRollingUpgrade", nil, nil, replicas)
+	mgr.c = spltest.NewMockClient()
+
+	// the rolling upgrade is started once, when the first peers are recycled
+	test("applyRollingUpgrade(start)", cmURL+"rolling_upgrade_init", applyRollingUpgrade, mgr)
+	upgrade := mgr.cr.Status.RollingUpgrade
+	if upgrade == nil {
+		t.Fatalf("applyRollingUpgrade() did not set the rolling upgrade status")
+	}
+	if upgrade.Revision != "v1" || upgrade.Image != "splunk/splunk:8.2.0" || upgrade.UpdatedPeers != 1 || upgrade.TotalPeers != 3 {
+		t.Errorf("applyRollingUpgrade() status = %v; want revision v1 with 1 of 3 peers updated", upgrade)
+	}
+	statefulSet.Status.UpdatedReplicas = 2
+	test("applyRollingUpgrade(progress)", "", applyRollingUpgrade, mgr)
+	if mgr.cr.Status.RollingUpgrade.UpdatedPeers != 2 {
+		t.Errorf("applyRollingUpgrade() UpdatedPeers = %d; want 2", mgr.cr.Status.RollingUpgrade.UpdatedPeers)
+	}
+
+	// the rolling upgrade is finalized once all the peers are updated
+	test("finishRollingUpgrade(in progress)", "", finishRollingUpgrade, mgr)
+	statefulSet.Status.UpdatedReplicas = 3
+	test("applyRollingUpgrade(all updated)", "", applyRollingUpgrade, mgr)
+	test("finishRollingUpgrade(all updated)", cmURL+"rolling_upgrade_finalize", finishRollingUpgrade, mgr)
+	if mgr.cr.Status.RollingUpgrade != nil {
+		t.Errorf("finishRollingUpgrade() status = %v; want nil", mgr.cr.Status.RollingUpgrade)
+	}
+	test("finishRollingUpgrade(none)", "", finishRollingUpgrade, mgr)
+
+	// the rolling upgrade is not started while a partition holds some peers, e.g. to update canaries first
+	partition := int32(2)
+	mgr.cr.Spec.RolloutStrategy.Partition = &partition
+	statefulSet.Status.UpdatedReplicas = 0
+	test("applyRollingUpgrade(partition)", "", applyRollingUpgrade, mgr)
+	statefulSet.Status.UpdatedReplicas = 1
+	test("finishRollingUpgrade(partition)", "", finishRollingUpgrade, mgr)
+	if mgr.cr.Status.RollingUpgrade != nil {
+		t.Errorf("applyRollingUpgrade() with partition %d status = %v; want nil", partition, mgr.cr.Status.RollingUpgrade)
+	}
+
+	// the rolling upgrade is started once the partition is lowered, and finalized once the rollout is paused
+	partition = 0
+	test("applyRollingUpgrade(partition lowered)", cmURL+"rolling_upgrade_init", applyRollingUpgrade, mgr)
+	if mgr.cr.Status.RollingUpgrade == nil {
+		t.Fatalf("applyRollingUpgrade() did not set the rolling upgrade status once the partition was lowered")
+	}
+	mgr.cr.Spec.RolloutStrategy.Paused = true
+	statefulSet.Status.UpdatedReplicas = 2
+	test("applyRollingUpgrade(paused)", "", applyRollingUpgrade, mgr)
+	test("finishRollingUpgrade(paused)", cmURL+"rolling_upgrade_finalize", finishRollingUpgrade, mgr)
+	if mgr.cr.Status.RollingUpgrade != nil {
+		t.Errorf("finishRollingUpgrade() while paused status = %v; want nil", mgr.cr.Status.RollingUpgrade)
+	}
+	test("applyRollingUpgrade(paused)", "", applyRollingUpgrade, mgr)
+	if mgr.cr.Status.RollingUpgrade != nil {
+		t.Errorf("applyRollingUpgrade() while paused status = %v; want nil", mgr.cr.Status.RollingUpgrade)
+	}
+}
+
+func TestIndexerClusterRollingRestart(t *testing.T) {
+	test := func(method string, url string, mgr *indexerClusterPodManager, wantPending int32) {
+		mockSplunkClient := &spltest.MockHTTPClient{}
+		if url != "" {
+			mockSplunkClient.AddHandlers(spltest.MockHTTPHandler{Method: "POST", URL: url, Status: 200})
+		}
+		mgr.newSplunkClient = func(managementURI, username, password string) *splclient.SplunkClient {
+			c := splclient.NewSplunkClient(managementURI, username, password)
+			c.Client = mockSplunkClient
+			return c
+		}
+		if err := mgr.applyRollingRestart(); err != nil {
+			t.Errorf("%s returned %v; want nil", method, err)
+		}
+		mockSplunkClient.CheckRequests(t, method)
+		restart := mgr.cr.Status.RollingRestart
+		if wantPending < 0 {
+			if restart != nil {
+				t.Errorf("%s status = %v; want nil", method, restart)
+			}
+		} else if restart == nil || restart.PendingPeers != wantPending {
+			t.Errorf("%s status = %v; want %d pending peers", method, restart, wantPending)
+		}
+	}
+	restartURL := "https://splunk-master1-cluster-master-service.test.svc.cluster.local:8089/services/cluster/master/control/control/rolling_restart?searchable=true"
+
+	mgr := getIndexerClusterPodManager("TestIndexerClusterRollingRestart", nil, nil, 3)
+	mgr.c = spltest.NewMockClient()
+	mgr.cr.Status.Peers = []enterpriseApi.IndexerClusterMemberStatus{
+		{Name: "splunk-stack1-indexer-0"},
+		{Name: "splunk-stack1-indexer-1"},
+		{Name: "splunk-stack1-indexer-2"},
+	}
+	test("applyRollingRestart(none)", "", mgr, -1)
+
+	// the peers are not restarted while the rollout is paused, nor during a rolling upgrade
+	mgr.cr.Status.Peers[1].RestartRequired = true
+	mgr.cr.Status.Peers[2].RestartRequired = true
+	mgr.cr.Spec.RolloutStrategy.Paused = true
+	test("applyRollingRestart(paused)", "", mgr, -1)
+	mgr.cr.Spec.RolloutStrategy.Paused = false
+	mgr.cr.Status.RollingUpgrade = &enterpriseApi.IndexerClusterRollingUpgradeStatus{}
+	test("applyRollingRestart(rolling upgrade)", "", mgr, -1)
+	mgr.cr.Status.RollingUpgrade = nil
+
+	// the rolling restart is started once, and tracked until all the peers are restarted
+	test("applyRollingRestart(start)", restartURL, mgr, 2)
+	mgr.rollingRestart = true
+	mgr.cr.Status.Peers[2].RestartRequired = false
+	test("applyRollingRestart(progress)", "", mgr, 1)
+	mgr.rollingRestart = false
+	mgr.cr.Status.Peers[1].RestartRequired = false
+	test("applyRollingRestart(complete)", "", mgr, -1)
+
+	// a rolling restart started by the cluster manager is tracked as well
+	mgr.rollingRestart = true
+	mgr.cr.Status.Peers[0].RestartRequired = true
+	test("applyRollingRestart(cluster manager)", "", mgr, 1)
+}
+
 func indexerClusterPodManagerUpdateTester(t *testing.T, method string, mockHandlers []spltest.MockHTTPHandler,
 	desiredReplicas int32, wantPhase splcommon.Phase, statefulSet *appsv1.StatefulSet,
 	wantCalls map[string][]spltest.MockFuncCall, wantError error, initObjects ...runtime.Object) {
@@ -981,21 +1157,102 @@ func TestInvalidIndexerClusterSpec(t *testing.T) {
 	cm.Status.Phase = splcommon.PhaseReady
 	// Empty ClusterMasterRef should return an error
 	cr.Spec.ClusterMasterRef.Name = ""
-	if _, err := ApplyIndexerCluster(c, &cr); err == nil {
+	if _, err := ApplyIndexerCluster(c, nil, &cr); err == nil {
 		t.Errorf("ApplyIndxerCluster() should have returned error")
 	}
 
 	cr.Spec.ClusterMasterRef.Name = "master1"
 	// verifyRFPeers should return err here
-	if _, err := ApplyIndexerCluster(c, &cr); err == nil {
+	if _, err := ApplyIndexerCluster(c, nil, &cr); err == nil {
 		t.Errorf("ApplyIndxerCluster() should have returned error")
 	}
 
 	cm.Status.Phase = splcommon.PhaseError
 	cr.Spec.CommonSplunkSpec.EtcVolumeStorageConfig.StorageCapacity = "-abcd"
-	if _, err := ApplyIndexerCluster(c, &cr); err == nil {
+	if _, err := ApplyIndexerCluster(c, nil, &cr); err == nil {
 		t.Errorf("ApplyIndxerCluster() should have returned error")
 	}
+
+	// Site should be one of site1 to site63
+	cr.Spec.CommonSplunkSpec.EtcVolumeStorageConfig.StorageCapacity = ""
+	cr.Spec.Site = "site64"
+	if err := validateIndexerClusterSpec(&cr); err == nil {
+		t.Errorf("validateIndexerClusterSpec() should have returned error on invalid site")
+	}
+
+	// Zone cannot be set without a site
+	cr.Spec.Site = ""
+	cr.Spec.Zone = "zone-1a"
+	if err := validateIndexerClusterSpec(&cr); err == nil {
+		t.Errorf("validateIndexerClusterSpec() should have returned error on zone without site")
+	}
+
+	cr.Spec.Site = "site1"
+	setIndexerClusterDefaults(&cr)
+	if err := validateIndexerClusterSpec(&cr); err != nil {
+		t.Errorf("validateIndexerClusterSpec() returned error: %v", err)
+	}
+}
+
+func TestValidateIndexerClusterSite(t *testing.T) {
+	cr := enterpriseApi.IndexerCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "stack1",
+			Namespace: "test",
+		},
+	}
+	cm := enterpriseApi.ClusterMaster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "master1",
+			Namespace: "test",
+		},
+	}
+
+	// single site or multisite configured through defaults
+	if err := validateIndexerClusterSite(&cr, &cm); err != nil {
+		t.Errorf("validateIndexerClusterSite() returned error: %v", err)
+	}
+
+	// site is required by a multisite ClusterMaster
+	cm.Spec.Multisite.AvailableSites = []string{"site1", "site2"}
+	if err := validateIndexerClusterSite(&cr, &cm); err == nil {
+		t.Errorf("validateIndexerClusterSite() should have returned error on missing site")
+	}
+
+	cr.Spec.Site = "site3"
+	if err := validateIndexerClusterSite(&cr, &cm); err == nil {
+		t.Errorf("validateIndexerClusterSite() should have returned error on unknown site")
+	}
+
+	cr.Spec.Site = "site2"
+	if err := validateIndexerClusterSite(&cr, &cm); err != nil {
+		t.Errorf("validateIndexerClusterSite() returned error: %v", err)
+	}
+}
+
+func TestGetIndexerClusterSiteStatus(t *testing.T) {
+	// single site peers report the default site
+	peers := map[string]splclient.ClusterMasterPeerInfo{
+		"splunk-stack1-indexer-0": {Site: "default", Status: "Up"},
+	}
+	if got := getIndexerClusterSiteStatus(peers); got != nil {
+		t.Errorf("getIndexerClusterSiteStatus() = %v; want nil", got)
+	}
+
+	peers = map[string]splclient.ClusterMasterPeerInfo{
+		"splunk-site2-indexer-0": {Site: "site2", Status: "Up"},
+		"splunk-site1-indexer-0": {Site: "site1", Status: "Up"},
+		"splunk-site1-indexer-1": {Site: "site1", Status: "Down"},
+		"splunk-site2-indexer-1": {Site: "site2", Status: "Up"},
+		"splunk-site2-indexer-2": {Site: "site2", Status: "Decommissioning"},
+	}
+	want := []enterpriseApi.IndexerClusterSiteStatus{
+		{Name: "site1", Peers: 2, UpPeers: 1},
+		{Name: "site2", Peers: 3, UpPeers: 2},
+	}
+	if got := getIndexerClusterSiteStatus(peers); !reflect.DeepEqual(got, want) {
+		t.Errorf("getIndexerClusterSiteStatus() = %v; want %v", got, want)
+	}
 }
 
 func TestGetIndexerStatefulSet(t *testing.T) {
@@ -1015,6 +1272,7 @@ func TestGetIndexerStatefulSet(t *testing.T) {
 	cr.Spec.ClusterMasterRef.Name = "master1"
 	test := func(want string) {
 		f := func() (interface{}, error) {
+			setIndexerClusterDefaults(&cr)
 			if err := validateIndexerClusterSpec(&cr); err != nil {
 				t.Errorf("validateIndexerClusterSpec() returned error: %v", err)
 			}
@@ -1057,8 +1315,39 @@ func TestGetIndexerStatefulSet(t *testing.T) {
 	}
 	test(`{"kind":"StatefulSet","apiVersion":"apps/v1","metadata":{"name":"splunk-stack1-indexer","namespace":"test","creationTimestamp":null,"ownerReferences":[{"apiVersion":"","kind":"","name":"stack1","uid":"","controller":true}]},"spec":{"replicas":1,"selector":{"matchLabels":{"app.kubernetes.io/component":"indexer","app.kubernetes.io/instance":"splunk-stack1-indexer","app.kubernetes.io/managed-by":"splunk-operator","app.kubernetes.io/name":"indexer","app.kubernetes.io/part-of":"splunk-master1-indexer"}},"template":{"metadata":{"creationTimestamp":null,"labels":{"app.kubernetes.io/component":"indexer","app.kubernetes.io/instance":"splunk-stack1-indexer","app.kubernetes.io/managed-by":"splunk-operator","app.kubernetes.io/name":"indexer","app.kubernetes.io/part-of":"splunk-master1-indexer"},"annotations":{"traffic.sidecar.istio.io/excludeOutboundPorts":"8089,8191,9997","traffic.sidecar.istio.io/includeInboundPorts":"8000,8088"}},"spec":{"volumes":[{"name":"mnt-splunk-secrets","secret":{"secretName":"splunk-stack1-indexer-secret-v1","defaultMode":420}}],"containers":[{"name":"splunk","image":"splunk/splunk","ports":[{"name":"http-splunkweb","containerPort":8000,"protocol":"TCP"},{"name":"http-hec","containerPort":8088,"protocol":"TCP"},{"name":"https-splunkd","containerPort":8089,"protocol":"TCP"},{"name":"tcp-s2s","containerPort":9997,"protocol":"TCP"},{"name":"user-defined","containerPort":32000,"protocol":"UDP"}],"env":[{"name":"SPLUNK_HOME","value":"/opt/splunk"},{"name":"SPLUNK_START_ARGS","value":"--accept-license"},{"name":"SPLUNK_DEFAULTS_URL","value":"/mnt/splunk-secrets/default.yml"},{"name":"SPLUNK_HOME_OWNERSHIP_ENFORCEMENT","value":"false"},{"name":"SPLUNK_ROLE","value":"splunk_indexer"},{"name":"SPLUNK_DECLARATIVE_ADMIN_PASSWORD","value":"true"},{"name":"SPLUNK_CLUSTER_MASTER_URL","value":"splunk-master1-cluster-master-service"},{"name":"TEST_ENV_VAR","value":"test_value"}],"resources":{"limits":{"cpu":"4","memory":"8Gi"},"requests":{"cpu":"100m","memory":"512Mi"}},"volumeMounts":[{"name":"pvc-etc","mountPath":"/opt/splunk/etc"},{"name":"pvc-var","mountPath":"/opt/splunk/var"},{"name":"mnt-splunk-secrets","mountPath":"/mnt/splunk-secrets"}],"livenessProbe":{"exec":{"command":["/sbin/checkstate.sh"]},"initialDelaySeconds":300,"timeoutSeconds":30,"periodSeconds":30},"readinessProbe":{"exec":{"command":["/bin/grep","started","/opt/container_artifact/splunk-container.state"]},"initialDelaySeconds":10,"timeoutSeconds":5,"periodSeconds":5},"imagePullPolicy":"IfNotPresent"}],"serviceAccountName":"defaults","securityContext":{"runAsUser":41812,"fsGroup":41812},"affinity":{"podAntiAffinity":{"preferredDuringSchedulingIgnoredDuringExecution":[{"weight":100,"podAffinityTerm":{"labelSelector":{"matchExpressions":[{"key":"app.kubernetes.io/instance","operator":"In","values":["splunk-stack1-indexer"]}]},"topologyKey":"kubernetes.io/hostname"}}]}},"schedulerName":"default-scheduler"}},"volumeClaimTemplates":[{"metadata":{"name":"pvc-etc","namespace":"test","creationTimestamp":null,"labels":{"app.kubernetes.io/component":"indexer","app.kubernetes.io/instance":"splunk-stack1-indexer","app.kubernetes.io/managed-by":"splunk-operator","app.kubernetes.io/name":"indexer","app.kubernetes.io/part-of":"splunk-master1-indexer"}},"spec":{"accessModes":["ReadWriteOnce"],"resources":{"requests":{"storage":"10Gi"}}},"status":{}},{"metadata":{"name":"pvc-var","namespace":"test","creationTimestamp":null,"labels":{"app.kubernetes.io/component":"indexer","app.kubernetes.io/instance":"splunk-stack1-indexer","app.kubernetes.io/managed-by":"splunk-operator","app.kubernetes.io/name":"indexer","app.kubernetes.io/part-of":"splunk-master1-indexer"}},"spec":{"accessModes":["ReadWriteOnce"],"resources":{"requests":{"storage":"100Gi"}}},"status":{}}],"serviceName":"splunk-stack1-indexer-headless","podManagementPolicy":"Parallel","updateStrategy":{"type":"OnDelete"}},"status":{"replicas":0}}`)
 
+	// Multisite peers are constrained to the zone of their site
+	cr.Spec.Site = "site1"
+	cr.Spec.Zone = "zone-1a"
+	test(`{"kind":"StatefulSet","apiVersion":"apps/v1","metadata":{"name":"splunk-stack1-indexer","namespace":"test","creationTimestamp":null,"ownerReferences":[{"apiVersion":"","kind":"","name":"stack1","uid":"","controller":true}]},"spec":{"replicas":1,"selector":{"matchLabels":{"app.kubernetes.io/component":"indexer","app.kubernetes.io/instance":"splunk-stack1-indexer","app.kubernetes.io/managed-by":"splunk-operator","app.kubernetes.io/name":"indexer","app.kubernetes.io/part-of":"splunk-master1-indexer"}},"template":{"metadata":{"creationTimestamp":null,"labels":{"app.kubernetes.io/component":"indexer","app.kubernetes.io/instance":"splunk-stack1-indexer","app.kubernetes.io/managed-by":"splunk-operator","app.kubernetes.io/name":"indexer","app.kubernetes.io/part-of":"splunk-master1-indexer"},"annotations":{"traffic.sidecar.istio.io/excludeOutboundPorts":"8089,8191,9997","traffic.sidecar.istio.io/includeInboundPorts":"8000,8088"}},"spec":{"volumes":[{"name":"mnt-splunk-secrets","secret":{"secretName":"splunk-stack1-indexer-secret-v1","defaultMode":420}},{"name":"mnt-splunk-defaults","configMap":{"name":"splunk-stack1-indexer-defaults","defaultMode":420}}],"containers":[{"name":"splunk","image":"splunk/splunk","ports":[{"name":"http-splunkweb","containerPort":8000,"protocol":"TCP"},{"name":"http-hec","containerPort":8088,"protocol":"TCP"},{"name":"https-splunkd","containerPort":8089,"protocol":"TCP"},{"name":"tcp-s2s","containerPort":9997,"protocol":"TCP"},{"name":"user-defined","containerPort":32000,"protocol":"UDP"}],"env":[{"name":"SPLUNK_HOME","value":"/opt/splunk"},{"name":"SPLUNK_START_ARGS","value":"--accept-license"},{"name":"SPLUNK_DEFAULTS_URL","value":"/mnt/splunk-defaults/multisite.yml,/mnt/splunk-secrets/default.yml"},{"name":"SPLUNK_HOME_OWNERSHIP_ENFORCEMENT","value":"false"},{"name":"SPLUNK_ROLE","value":"splunk_indexer"},{"name":"SPLUNK_DECLARATIVE_ADMIN_PASSWORD","value":"true"},{"name":"SPLUNK_CLUSTER_MASTER_URL","value":"splunk-master1-cluster-master-service"},{"name":"TEST_ENV_VAR","value":"test_value"}],"resources":{"limits":{"cpu":"4","memory":"8Gi"},"requests":{"cpu":"100m","memory":"512Mi"}},"volumeMounts":[{"name":"pvc-etc","mountPath":"/opt/splunk/etc"},{"name":"pvc-var","mountPath":"/opt/splunk/var"},{"name":"mnt-splunk-secrets","mountPath":"/mnt/splunk-secrets"},{"name":"mnt-splunk-defaults","mountPath":"/mnt/splunk-defaults"}],"livenessProbe":{"exec":{"command":["/sbin/checkstate.sh"]},"initialDelaySeconds":300,"timeoutSeconds":30,"periodSeconds":30},"readinessProbe":{"exec":{"command":["/bin/grep","started","/opt/container_artifact/splunk-container.state"]},"initialDelaySeconds":10,"timeoutSeconds":5,"periodSeconds":5},"imagePullPolicy":"IfNotPresent"}],"serviceAccountName":"defaults","securityContext":{"runAsUser":41812,"fsGroup":41812},"affinity":{"nodeAffinity":{"requiredDuringSchedulingIgnoredDuringExecution":{"nodeSelectorTerms":[{"matchExpressions":[{"key":"topology.kubernetes.io/zone","operator":"In","values":["zone-1a"]}]}]}},"podAntiAffinity":{"preferredDuringSchedulingIgnoredDuringExecution":[{"weight":100,"podAffinityTerm":{"labelSelector":{"matchExpressions":[{"key":"app.kubernetes.io/instance","operator":"In","values":["splunk-stack1-indexer"]}]},"topologyKey":"kubernetes.io/hostname"}}]}},"schedulerName":"default-scheduler"}},"volumeClaimTemplates":[{"metadata":{"name":"pvc-etc","namespace":"test","creationTimestamp":null,"labels":{"app.kubernetes.io/component":"indexer","app.kubernetes.io/instance":"splunk-stack1-indexer","app.kubernetes.io/managed-by":"splunk-operator","app.kubernetes.io/name":"indexer","app.kubernetes.io/part-of":"splunk-master1-indexer"}},"spec":{"accessModes":["ReadWriteOnce"],"resources":{"requests":{"storage":"10Gi"}}},"status":{}},{"metadata":{"name":"pvc-var","namespace":"test","creationTimestamp":null,"labels":{"app.kubernetes.io/component":"indexer","app.kubernetes.io/instance":"splunk-stack1-indexer","app.kubernetes.io/managed-by":"splunk-operator","app.kubernetes.io/name":"indexer","app.kubernetes.io/part-of":"splunk-master1-indexer"}},"spec":{"accessModes":["ReadWriteOnce"],"resources":{"requests":{"storage":"100Gi"}}},"status":{}}],"serviceName":"splunk-stack1-indexer-headless","podManagementPolicy":"Parallel","updateStrategy":{"type":"OnDelete"}},"status":{"replicas":0}}`)
+
 	cr.Spec.ClusterMasterRef.Namespace = "other"
 	if err := validateIndexerClusterSpec(&cr); err == nil {
 		t.Errorf("validateIndexerClusterSpec() error expected on multisite IndexerCluster referencing a cluster master located in a different namespace")
 	}
 }
+
+func TestGetIndexerMaxUnavailable(t *testing.T) {
+	for replicationFactor, want := range map[int32]int32{0: 1, 1: 1, 2: 1, 3: 2, 5: 4} {
+		if got := getIndexerMaxUnavailable(replicationFactor); got != want {
+			t.Errorf("getIndexerMaxUnavailable(%d) = %d; want %d", replicationFactor, got, want)
+		}
+	}
+}
+
+func TestIndexerClusterGetRolloutStrategy(t *testing.T) {
+	cr := enterpriseApi.IndexerCluster{}
+	cr.Spec.RolloutStrategy.MaxUnavailable = 3
+	mgr := indexerClusterPodManager{cr: &cr, replicationFactor: 3, searchFactor: 2}
+	if got := mgr.GetRolloutStrategy().MaxUnavailable; got != 1 {
+		t.Errorf("GetRolloutStrategy() MaxUnavailable = %d; want 1", got)
+	}
+	mgr.searchFactor = 3
+	if got := mgr.GetRolloutStrategy().MaxUnavailable; got != 2 {
+		t.Errorf("GetRolloutStrategy() MaxUnavailable = %d; want 2", got)
+	}
+
+	// the custom resource is not changed
+	if cr.Spec.RolloutStrategy.MaxUnavailable != 3 {
+		t.Errorf("GetRolloutStrategy() changed MaxUnavailable to %d; want 3", cr.Spec.RolloutStrategy.MaxUnavailable)
+	}
+}
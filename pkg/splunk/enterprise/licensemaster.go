@@ -21,6 +21,7 @@ import (
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	enterpriseApi "github.com/splunk/splunk-operator/pkg/apis/enterprise/v2"
@@ -29,19 +30,32 @@ import (
 )
 
 // ApplyLicenseMaster reconciles the state for the Splunk Enterprise license manager.
-func ApplyLicenseMaster(client splcommon.ControllerClient, cr *enterpriseApi.LicenseMaster) (reconcile.Result, error) {
+func ApplyLicenseMaster(client splcommon.ControllerClient, recorder record.EventRecorder, cr *enterpriseApi.LicenseMaster) (result reconcile.Result, err error) {
 
 	// unless modified, reconcile for this object will be requeued after 5 seconds
-	result := reconcile.Result{
+	result = reconcile.Result{
 		Requeue:      true,
 		RequeueAfter: time.Second * 5,
 	}
 
 	scopedLog := log.WithName("ApplyLicenseMaster").WithValues("name", cr.GetName(), "namespace", cr.GetNamespace())
+	eventPublisher := newEventPublisher(recorder, cr)
+	oldPhase := cr.Status.Phase
 
-	// validate and updates defaults for CR
-	err := validateLicenseMasterSpec(cr)
+	// updates status and conditions after function completes, including validation failures
+	defer func() {
+		setStatusConditions(cr, err)
+		if updateErr := client.Status().Update(context.TODO(), cr); updateErr != nil {
+			scopedLog.Error(updateErr, "Status update failed")
+		}
+	}()
+
+	// updates defaults and validates CR
+	setLicenseMasterDefaults(cr)
+	err = validateLicenseMasterSpec(cr)
 	if err != nil {
+		cr.Status.Phase = splcommon.PhaseError
+		eventPublisher.Warning(eventReasonValidationFailed, "%v", err)
 		scopedLog.Error(err, "Failed to validate license master spec")
 		return result, err
 	}
@@ -50,7 +64,7 @@ func ApplyLicenseMaster(client splcommon.ControllerClient, cr *enterpriseApi.Lic
 	// 1. Initialize the S3Clients based on providers
 	// 2. Check the status of apps on remote storage.
 	if len(cr.Spec.AppFrameworkConfig.AppSources) != 0 {
-		err := initAndCheckAppInfoStatus(client, cr, &cr.Spec.AppFrameworkConfig, &cr.Status.AppContext)
+		err := initAndCheckAppInfoStatus(client, cr, &cr.Spec.AppFrameworkConfig, &cr.Status.AppContext, eventPublisher)
 		if err != nil {
 			cr.Status.AppContext.IsDeploymentInProgress = false
 			return result, err
@@ -59,9 +73,6 @@ func ApplyLicenseMaster(client splcommon.ControllerClient, cr *enterpriseApi.Lic
 
 	// updates status after function completes
 	cr.Status.Phase = splcommon.PhaseError
-	defer func() {
-		client.Status().Update(context.TODO(), cr)
-	}()
 
 	// create or update general config resources
 	_, err = ApplySplunkConfig(client, cr, cr.Spec.CommonSplunkSpec, SplunkLicenseMaster)
@@ -96,20 +107,35 @@ func ApplyLicenseMaster(client splcommon.ControllerClient, cr *enterpriseApi.Lic
 	if err != nil {
 		return result, err
 	}
-	mgr := splctrl.DefaultStatefulSetPodManager{}
+
+	// create or update a PodDisruptionBudget for the license manager
+	err = applySplunkPodDisruptionBudget(client, cr, &cr.Spec.CommonSplunkSpec, SplunkLicenseMaster, 1)
+	if err != nil {
+		return result, err
+	}
+	mgr := splctrl.DefaultStatefulSetPodManager{RolloutStrategy: cr.Spec.RolloutStrategy}
 	phase, err := mgr.Update(client, statefulSet, 1)
 	if err != nil {
 		return result, err
 	}
+	eventPublisher.PhaseChanged(oldPhase, phase, 1)
 	cr.Status.Phase = phase
 
+	// expand the claims of the pods after the storage capacity was increased
+	cr.Status.PvcExpansion, err = applyPvcExpansion(client, cr, &cr.Spec.CommonSplunkSpec, statefulSet)
+	if err != nil {
+		return result, err
+	}
+
 	// no need to requeue if everything is ready
 	if cr.Status.Phase == splcommon.PhaseReady {
 		if cr.Status.AppContext.AppsSrcDeployStatus != nil {
-			markAppsStatusToComplete(client, cr, &cr.Spec.AppFrameworkConfig, cr.Status.AppContext.AppsSrcDeployStatus)
-			// Schedule one more reconcile in next 5 seconds, just to cover any latest app framework config changes
-			if cr.Status.AppContext.IsDeploymentInProgress {
-				cr.Status.AppContext.IsDeploymentInProgress = false
+			requeue, err := applyAppInstalls(client, cr, &cr.Spec.AppFrameworkConfig, &cr.Status.AppContext, eventPublisher)
+			if err != nil {
+				return result, err
+			}
+			// Schedule one more reconcile in next 5 seconds, until the apps are installed and to cover any latest app framework config changes
+			if requeue {
 				return result, nil
 			}
 		}
@@ -126,6 +152,7 @@ func ApplyLicenseMaster(client splcommon.ControllerClient, cr *enterpriseApi.Lic
 			result.Requeue = false
 		}
 	}
+	requeueIfExpanding(&result, eventPublisher, cr.Status.PvcExpansion)
 	return result, nil
 }
 
@@ -136,17 +163,28 @@ func getLicenseMasterStatefulSet(client splcommon.ControllerClient, cr *enterpri
 		return ss, err
 	}
 
-	// Setup App framework init containers
-	setupAppInitContainers(client, cr, &ss.Spec.Template, &cr.Spec.AppFrameworkConfig)
+	// Setup App framework staging volume
+	setupAppsStagingVolume(&ss.Spec.Template, &cr.Spec.AppFrameworkConfig)
 
 	return ss, err
 }
 
-// validateLicenseMasterSpec checks validity and makes default updates to a LicenseMasterSpec, and returns error if something is wrong.
+// setLicenseMasterDefaults sets default values for the parameters of a LicenseMasterSpec that are not provided.
+func setLicenseMasterDefaults(cr *enterpriseApi.LicenseMaster) {
+	setAppFrameworkDefaults(&cr.Spec.AppFrameworkConfig)
+	setCommonSplunkSpecDefaults(&cr.Spec.CommonSplunkSpec)
+}
+
+// validateLicenseMasterSpec checks validity of a LicenseMasterSpec, and returns error if something is wrong.
 func validateLicenseMasterSpec(cr *enterpriseApi.LicenseMaster) error {
 
 	if !reflect.DeepEqual(cr.Status.AppContext.AppFrameworkConfig, cr.Spec.AppFrameworkConfig) {
-		err := ValidateAppFrameworkSpec(&cr.Spec.AppFrameworkConfig, &cr.Status.AppContext, true)
+		err := validatePremiumAppsScope(&cr.Spec.AppFrameworkConfig)
+		if err != nil {
+			return err
+		}
+
+		err = ValidateAppFrameworkSpec(&cr.Spec.AppFrameworkConfig, true)
 		if err != nil {
 			return err
 		}
@@ -36,7 +36,7 @@ func TestApplyLicenseMaster(t *testing.T) {
 		{MetaName: "*v1.Service-test-splunk-stack1-license-master-service"},
 		{MetaName: "*v1.Secret-test-splunk-test-secret"},
 		{MetaName: "*v1.Secret-test-splunk-stack1-license-master-secret-v1"},
-		{MetaName: "*v1.ConfigMap-test-splunk-stack1-licensemaster-app-list"},
+		{MetaName: "*v1beta1.PodDisruptionBudget-test-splunk-stack1-license-master"},
 		{MetaName: "*v1.StatefulSet-test-splunk-stack1-license-master"},
 	}
 	labels := map[string]string{
@@ -49,7 +49,7 @@ func TestApplyLicenseMaster(t *testing.T) {
 	}
 	listmockCall := []spltest.MockFuncCall{
 		{ListOpts: listOpts}}
-	createCalls := map[string][]spltest.MockFuncCall{"Get": funcCalls, "Create": {funcCalls[0], funcCalls[2], funcCalls[4], funcCalls[6]}, "Update": {funcCalls[0]}, "List": {listmockCall[0]}}
+	createCalls := map[string][]spltest.MockFuncCall{"Get": funcCalls, "Create": {funcCalls[0], funcCalls[2], funcCalls[4], funcCalls[5], funcCalls[6]}, "Update": {funcCalls[0]}, "List": {listmockCall[0]}}
 	updateCalls := map[string][]spltest.MockFuncCall{"Get": funcCalls, "Update": {funcCalls[6]}, "List": {listmockCall[0]}}
 	current := enterpriseApi.LicenseMaster{
 		TypeMeta: metav1.TypeMeta{
@@ -63,7 +63,7 @@ func TestApplyLicenseMaster(t *testing.T) {
 	revised := current.DeepCopy()
 	revised.Spec.Image = "splunk/test"
 	reconcile := func(c *spltest.MockClient, cr interface{}) error {
-		_, err := ApplyLicenseMaster(c, cr.(*enterpriseApi.LicenseMaster))
+		_, err := ApplyLicenseMaster(c, nil, cr.(*enterpriseApi.LicenseMaster))
 		return err
 	}
 	spltest.ReconcileTesterWithoutRedundantCheck(t, "TestApplyLicenseMaster", &current, revised, createCalls, updateCalls, reconcile, true)
@@ -73,7 +73,7 @@ func TestApplyLicenseMaster(t *testing.T) {
 	revised.ObjectMeta.DeletionTimestamp = &currentTime
 	revised.ObjectMeta.Finalizers = []string{"enterprise.splunk.com/delete-pvc"}
 	deleteFunc := func(cr splcommon.MetaObject, c splcommon.ControllerClient) (bool, error) {
-		_, err := ApplyLicenseMaster(c, cr.(*enterpriseApi.LicenseMaster))
+		_, err := ApplyLicenseMaster(c, nil, cr.(*enterpriseApi.LicenseMaster))
 		return true, err
 	}
 	splunkDeletionTester(t, revised, deleteFunc)
@@ -95,6 +95,7 @@ func TestGetLicenseMasterStatefulSet(t *testing.T) {
 
 	test := func(want string) {
 		f := func() (interface{}, error) {
+			setLicenseMasterDefaults(&cr)
 			if err := validateLicenseMasterSpec(&cr); err != nil {
 				t.Errorf("validateLicenseMasterSpec() returned error: %v", err)
 			}
@@ -182,7 +183,7 @@ func TestAppFrameworkApplyLicenseMasterShouldNotFail(t *testing.T) {
 
 	client.AddObject(&s3Secret)
 
-	_, err = ApplyLicenseMaster(client, &cr)
+	_, err = ApplyLicenseMaster(client, nil, &cr)
 	if err != nil {
 		t.Errorf("ApplyLicenseMaster should be successful")
 	}
@@ -50,7 +50,12 @@ func ApplyMonitoringConsole(client splcommon.ControllerClient, cr splcommon.Meta
 		mgr := monitoringConsolePodManager{cr: &cr, spec: &spec, secrets: secrets, newSplunkClient: splclient.NewSplunkClient}
 		c := mgr.getMonitoringConsoleClient(cr)
 		err := c.AutomateMCApplyChanges(spec.Mock)
-		return err
+		if err != nil {
+			return err
+		}
+
+		// the monitoring console is upgraded after the indexer clusters, which are the last to become ready
+		return updateMonitoringConsolePods(client, cr, &spec)
 	}
 
 	// create or update a regular monitoring console service
@@ -96,7 +101,7 @@ func ApplyMonitoringConsole(client splcommon.ControllerClient, cr splcommon.Meta
 		return err
 	}
 
-	mgr := splctrl.DefaultStatefulSetPodManager{}
+	mgr := splctrl.DefaultStatefulSetPodManager{HoldUpdates: newMonitoringConsoleUpgradeGate(client, cr, &spec).hold}
 	_, err = mgr.Update(client, statefulset, 1)
 	if err != nil {
 		return err
@@ -109,6 +114,22 @@ func ApplyMonitoringConsole(client splcommon.ControllerClient, cr splcommon.Meta
 	return err
 }
 
+// updateMonitoringConsolePods recycles the monitoring console pods that do not run the latest revision of its
+// StatefulSet, once all the other custom resources of the namespace are upgraded
+func updateMonitoringConsolePods(client splcommon.ControllerClient, cr splcommon.MetaObject, spec *enterpriseApi.CommonSplunkSpec) error {
+	namespacedName := types.NamespacedName{Namespace: cr.GetNamespace(), Name: GetSplunkStatefulsetName(SplunkMonitoringConsole, cr.GetNamespace())}
+	statefulSet := &appsv1.StatefulSet{}
+	err := client.Get(context.TODO(), namespacedName, statefulSet)
+	if err != nil || statefulSet.Status.UpdatedReplicas >= statefulSet.Status.Replicas {
+		// the monitoring console is created by the other custom resources
+		return nil
+	}
+
+	mgr := splctrl.DefaultStatefulSetPodManager{HoldUpdates: newMonitoringConsoleUpgradeGate(client, cr, spec).hold}
+	_, err = splctrl.UpdateStatefulSetPods(client, statefulSet, &mgr, 1)
+	return err
+}
+
 // getMonitoringConsoleClient for monitoringConsolePodManager returns a SplunkClient for monitoring console
 func (mgr *monitoringConsolePodManager) getMonitoringConsoleClient(cr splcommon.MetaObject) *splclient.SplunkClient {
 	fqdnName := splcommon.GetServiceFQDN(cr.GetNamespace(), GetSplunkServiceName(SplunkMonitoringConsole, cr.GetNamespace(), false))
@@ -183,7 +204,7 @@ func getMonitoringConsoleStatefulSet(client splcommon.ControllerClient, cr splco
 					SchedulerName: spec.SchedulerName,
 					Containers: []corev1.Container{
 						{
-							Image:           spec.Image,
+							Image:           GetSplunkImage(spec.Image),
 							ImagePullPolicy: corev1.PullPolicy(spec.ImagePullPolicy),
 							Name:            "splunk",
 							Ports:           ports,
@@ -240,6 +240,7 @@ func TestGetMonitoringConsoleStatefulSet(t *testing.T) {
 
 	test := func(want string) {
 		f := func() (interface{}, error) {
+			setSearchHeadClusterDefaults(&cr)
 			if err := validateSearchHeadClusterSpec(&cr); err != nil {
 				t.Errorf("validateSearchHeadClusterSpec() returned error: %v", err)
 			}
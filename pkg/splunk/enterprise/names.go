@@ -41,11 +41,11 @@ const (
 	// identifier
 	smartstoreTemplateStr = "splunk-%s-%s-smartstore"
 
-	// identifier
-	appListingTemplateStr = "splunk-%s-%s-app-list"
+	// file name, within the defaults ConfigMap, of the ansible defaults rendered from the multisite spec
+	multisiteDefaultsFile = "multisite.yml"
 
-	// init container name
-	initContainerTemplate = "%s-init-%d-%s"
+	// maximum number of sites supported by a multisite indexer cluster
+	maxMultisiteSites = 63
 
 	// default docker image used for Splunk instances
 	defaultSplunkImage = "splunk/splunk"
@@ -56,18 +56,41 @@ const (
 	// identifier used for S3 secret key
 	s3SecretKey = "s3_secret_key"
 
+	// identifier used for the Azure storage account name
+	azureBlobAccountName = "azure_sa_name"
+
+	// identifier used for the Azure storage account key
+	azureBlobAccountKey = "azure_sa_secret_key"
+
+	// identifier used for a shared access signature of an Azure storage account, which is not supported
+	azureSASToken = "azure_sas_token"
+
+	// identifier used for the GCS service account key
+	gcsServiceAccountKey = "key.json"
+
+	// identifiers used for the service principal of the Azure SmartStore volumes
+	azureClientID     = "azure_client_id"
+	azureClientSecret = "azure_client_secret"
+	azureTenantID     = "azure_tenant_id"
+
+	// file name, in the etc/auth directory of the Splunk pods, of the service account key of a GCS SmartStore volume
+	smartstoreCredentialFileTemplate = "splunk-smartstore-%s.json"
+
+	// annotation of a Standalone or ClusterMaster confirming the removal of the comma separated SmartStore indexes
+	confirmIndexRemovalAnnotation = "enterprise.splunk.com/confirm-index-removal"
+
+	// identifier used for the public key verifying the signatures of the app packages
+	appSignaturePublicKey = "public_key"
+
+	// name of the pod volumes of the PersistentVolumeClaims holding apps
+	appRepoVolumeTemplate = "app-repo-%s"
+
 	//identifier for monitoring console configMap revision
 	monitoringConsoleConfigRev = "monitoringConsoleConfigRev"
 
 	// identifier to track the smartstore config rev. on Pod
 	smartStoreConfigRev = "SmartStoreConfigRev"
 
-	// ToDo: Used only for Phase-2, to be removed later
-	appListingRev = "appListingRev"
-
-	// Pod location for app related config
-	appConfLocationOnPod = "/mnt/app-listing/"
-
 	// command merger
 	commandMerger = " && "
 
@@ -80,9 +103,6 @@ const (
 	// configToken used to track if the config is reflecting on Pod or not
 	configToken = "conftoken"
 
-	// appsUpdateToken used to track if the if the latest app list is reflecting on pod or not
-	appsUpdateToken = "appsUpdateToken"
-
 	// port names and templates and protocols
 	portNameTemplateStr = "%s-%s"
 
@@ -95,19 +115,15 @@ const (
 	protoHTTPS = "https"
 	protoTCP   = "tcp"
 
-	// Volume name for shared volume between init and splunk containers
+	// Volume name for the app packages copied to the Splunk containers before they are installed
 	appVolumeMntName = "init-apps"
 
-	// Mount location for the shared app package volume
+	// Mount location for the app packages copied to the Splunk containers
 	appBktMnt = "/init-apps/"
 
 	// Average amount of time an app installation takes
 	avgAppInstallationTime = 5
 
-	// Time delay involved in installating the Splunk Apps.
-	// Apps like Splunk ES will take as high as 20 minutes for completeing the installation
-	maxSplunkAppsInstallationDelaySecs = 1500
-
 	// Readiness probe time values
 	readinessProbeDefaultDelaySec = 10
 	readinessProbeTimeoutSec      = 5
@@ -162,11 +178,6 @@ func GetSplunkSmartstoreConfigMapName(identifier string, crKind string) string {
 	return fmt.Sprintf(smartstoreTemplateStr, identifier, strings.ToLower(crKind))
 }
 
-// GetSplunkAppsConfigMapName uses a template to name a Kubernetes ConfigMap for a SplunkEnterprise resource.
-func GetSplunkAppsConfigMapName(identifier string, crKind string) string {
-	return fmt.Sprintf(appListingTemplateStr, identifier, strings.ToLower(crKind))
-}
-
 // GetSplunkStatefulsetUrls returns a list of fully qualified domain names for all pods within a Splunk StatefulSet.
 func GetSplunkStatefulsetUrls(namespace string, instanceType InstanceType, identifier string, replicas int32, hostnameOnly bool) string {
 	urls := make([]string, replicas)
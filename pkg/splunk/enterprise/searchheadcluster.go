@@ -23,6 +23,7 @@ import (
 	"github.com/go-logr/logr"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	enterpriseApi "github.com/splunk/splunk-operator/pkg/apis/enterprise/v2"
@@ -33,17 +34,30 @@ import (
 )
 
 // ApplySearchHeadCluster reconciles the state for a Splunk Enterprise search head cluster.
-func ApplySearchHeadCluster(client splcommon.ControllerClient, cr *enterpriseApi.SearchHeadCluster) (reconcile.Result, error) {
+func ApplySearchHeadCluster(client splcommon.ControllerClient, recorder record.EventRecorder, cr *enterpriseApi.SearchHeadCluster) (result reconcile.Result, err error) {
 	// unless modified, reconcile for this object will be requeued after 5 seconds
-	result := reconcile.Result{
+	result = reconcile.Result{
 		Requeue:      true,
 		RequeueAfter: time.Second * 5,
 	}
 	scopedLog := log.WithName("ApplySearchHeadCluster").WithValues("name", cr.GetName(), "namespace", cr.GetNamespace())
+	eventPublisher := newEventPublisher(recorder, cr)
+	oldPhase := cr.Status.Phase
 
-	// validate and updates defaults for CR
-	err := validateSearchHeadClusterSpec(cr)
+	// updates status and conditions after function completes, including validation failures
+	defer func() {
+		setStatusConditions(cr, err)
+		if updateErr := client.Status().Update(context.TODO(), cr); updateErr != nil {
+			scopedLog.Error(updateErr, "Status update failed")
+		}
+	}()
+
+	// updates defaults and validates CR
+	setSearchHeadClusterDefaults(cr)
+	err = validateSearchHeadClusterSpec(cr)
 	if err != nil {
+		cr.Status.Phase = splcommon.PhaseError
+		eventPublisher.Warning(eventReasonValidationFailed, "%v", err)
 		return result, err
 	}
 
@@ -51,7 +65,7 @@ func ApplySearchHeadCluster(client splcommon.ControllerClient, cr *enterpriseApi
 	// 1. Initialize the S3Clients based on providers
 	// 2. Check the status of apps on remote storage.
 	if len(cr.Spec.AppFrameworkConfig.AppSources) != 0 {
-		err := initAndCheckAppInfoStatus(client, cr, &cr.Spec.AppFrameworkConfig, &cr.Status.AppContext)
+		err := initAndCheckAppInfoStatus(client, cr, &cr.Spec.AppFrameworkConfig, &cr.Status.AppContext, eventPublisher)
 		if err != nil {
 			cr.Status.AppContext.IsDeploymentInProgress = false
 			return result, err
@@ -76,12 +90,6 @@ func ApplySearchHeadCluster(client splcommon.ControllerClient, cr *enterpriseApi
 	if cr.Status.AdminPasswordChangedSecrets == nil {
 		cr.Status.AdminPasswordChangedSecrets = make(map[string]bool)
 	}
-	defer func() {
-		err = client.Status().Update(context.TODO(), cr)
-		if err != nil {
-			scopedLog.Error(err, "Status update failed")
-		}
-	}()
 
 	// create or update general config resources
 	namespaceScopedSecret, err := ApplySplunkConfig(client, cr, cr.Spec.CommonSplunkSpec, SplunkSearchHead)
@@ -129,30 +137,94 @@ func ApplySearchHeadCluster(client splcommon.ControllerClient, cr *enterpriseApi
 	if err != nil {
 		return result, err
 	}
-	deployerManager := splctrl.DefaultStatefulSetPodManager{}
+
+	// create or update a PodDisruptionBudget for the deployer
+	err = applySplunkPodDisruptionBudget(client, cr, getDeployerSplunkSpec(cr), SplunkDeployer, 1)
+	if err != nil {
+		return result, err
+	}
+	// move the volumes of the deployer to new storage, if it changed
+	deployerMigration := newStorageMigration(client, statefulSet)
+	recreating, err := deployerMigration.apply()
+	if err != nil {
+		return result, err
+	}
+	if recreating {
+		eventPublisher.Normal(eventReasonStorageMigration, "Recreating StatefulSet %s to move its volumes to new storage", statefulSet.GetName())
+		return result, nil
+	}
+
+	// hold off updates while the license and cluster managers are upgraded, and the deployer for the members
+	gate := newUpgradeGate(client, cr, &cr.Spec.CommonSplunkSpec)
+	deployerManager := splctrl.DefaultStatefulSetPodManager{HoldUpdates: gate.hold, PrepareVolumes: deployerMigration.migratePod}
 	phase, err := deployerManager.Update(client, statefulSet, 1)
+	cr.Status.StorageMigration = mergeStorageMigration(deployerMigration)
 	if err != nil {
 		return result, err
 	}
 	cr.Status.DeployerPhase = phase
 
+	// expand the claims of the deployer after the storage capacity was increased
+	deployerExpansion, err := applyPvcExpansion(client, cr, &cr.Spec.CommonSplunkSpec, statefulSet)
+	if err != nil {
+		return result, err
+	}
+
 	// create or update statefulset for the search heads
 	statefulSet, err = getSearchHeadStatefulSet(client, cr)
 	if err != nil {
 		return result, err
 	}
-	mgr := searchHeadClusterPodManager{c: client, log: scopedLog, cr: cr, secrets: namespaceScopedSecret, newSplunkClient: splclient.NewSplunkClient}
+
+	// create or update a PodDisruptionBudget that preserves the majority required to elect a captain
+	err = applySplunkPodDisruptionBudget(client, cr, &cr.Spec.CommonSplunkSpec, SplunkSearchHead, getSearchHeadMaxUnavailable(cr.Spec.Replicas))
+	if err != nil {
+		return result, err
+	}
+	// move the volumes of the members to new storage, if it changed
+	membersMigration := newStorageMigration(client, statefulSet)
+	recreating, err = membersMigration.apply()
+	if err != nil {
+		return result, err
+	}
+	if recreating {
+		eventPublisher.Normal(eventReasonStorageMigration, "Recreating StatefulSet %s to move its volumes to new storage", statefulSet.GetName())
+		return result, nil
+	}
+
+	membersGate := newUpgradeGate(client, cr, &cr.Spec.CommonSplunkSpec).waitFor(GetSplunkStatefulsetName(SplunkDeployer, cr.GetName()))
+	mgr := searchHeadClusterPodManager{c: client, log: scopedLog, cr: cr, secrets: namespaceScopedSecret, newSplunkClient: splclient.NewSplunkClient, eventPublisher: eventPublisher, holdUpdates: membersGate.hold, prepareVolumes: membersMigration.migratePod}
 	phase, err = mgr.Update(client, statefulSet, cr.Spec.Replicas)
+	cr.Status.StorageMigration = mergeStorageMigration(deployerMigration, membersMigration)
 	if err != nil {
 		return result, err
 	}
+	eventPublisher.PhaseChanged(oldPhase, phase, cr.Spec.Replicas)
 	cr.Status.Phase = phase
 
-	if cr.Status.AppContext.AppsSrcDeployStatus != nil && cr.Status.DeployerPhase == splcommon.PhaseReady {
-		markAppsStatusToComplete(client, cr, &cr.Spec.AppFrameworkConfig, cr.Status.AppContext.AppsSrcDeployStatus)
-		// Schedule one more reconcile in next 5 seconds, just to cover any latest app framework config changes
-		if cr.Status.AppContext.IsDeploymentInProgress {
-			cr.Status.AppContext.IsDeploymentInProgress = false
+	// report the claims kept after a scale down
+	orphanedClaims, listErr := getOrphanedClaims(client, cr, &cr.Spec.CommonSplunkSpec, SplunkSearchHead, cr.Spec.Replicas)
+	if listErr != nil {
+		scopedLog.Error(listErr, "Unable to list the PVCs retained after scale down")
+	} else {
+		cr.Status.OrphanedClaims = orphanedClaims
+	}
+
+	// expand the claims of the members, reporting the progress of both StatefulSets
+	membersExpansion, err := applyPvcExpansion(client, cr, &cr.Spec.CommonSplunkSpec, statefulSet)
+	if err != nil {
+		return result, err
+	}
+	cr.Status.PvcExpansion = mergePvcExpansion(deployerExpansion, membersExpansion)
+
+	// the bundle of the deployer is pushed to the members, so they must be ready too
+	if cr.Status.AppContext.AppsSrcDeployStatus != nil && cr.Status.DeployerPhase == splcommon.PhaseReady && cr.Status.Phase == splcommon.PhaseReady {
+		requeue, err := applyAppInstalls(client, cr, &cr.Spec.AppFrameworkConfig, &cr.Status.AppContext, eventPublisher)
+		if err != nil {
+			return result, err
+		}
+		// Schedule one more reconcile in next 5 seconds, until the apps are installed and to cover any latest app framework config changes
+		if requeue {
 			return result, nil
 		}
 	}
@@ -177,6 +249,10 @@ func ApplySearchHeadCluster(client splcommon.ControllerClient, cr *enterpriseApi
 		cr.Status.AdminPasswordChangedSecrets = make(map[string]bool)
 		cr.Status.NamespaceSecretResourceVersion = namespaceScopedSecret.ObjectMeta.ResourceVersion
 	}
+	requeueIfExpanding(&result, eventPublisher, cr.Status.PvcExpansion)
+	if !gate.requeueIfHeld(&result, eventPublisher) {
+		membersGate.requeueIfHeld(&result, eventPublisher)
+	}
 	return result, nil
 }
 
@@ -187,6 +263,13 @@ type searchHeadClusterPodManager struct {
 	cr              *enterpriseApi.SearchHeadCluster
 	secrets         *corev1.Secret
 	newSplunkClient func(managementURI, username, password string) *splclient.SplunkClient
+	eventPublisher  *eventPublisher
+
+	// holdUpdates, if set, returns true while the members must not be recycled
+	holdUpdates func() bool
+
+	// prepareVolumes, if set, returns true once the data of a detained member is ready for its recycled pod
+	prepareVolumes func(n int32) (bool, error)
 }
 
 // ApplyShcSecret checks if any of the search heads have a different shc_secret from namespace scoped secret and changes it
@@ -255,6 +338,7 @@ func ApplyShcSecret(mgr *searchHeadClusterPodManager, replicas int32, mock bool)
 				}
 			}
 			scopedLog.Info("shcSecret changed")
+			mgr.eventPublisher.Normal(eventReasonSecretRotated, "Changed shc_secret on %s", shPodName)
 
 			// Get client for Pod and restart splunk instance on pod
 			shClient := mgr.getClient(i)
@@ -291,6 +375,7 @@ func ApplyShcSecret(mgr *searchHeadClusterPodManager, replicas int32, mock bool)
 				}
 			}
 			scopedLog.Info("admin password changed on the splunk instance of pod")
+			mgr.eventPublisher.Normal(eventReasonSecretRotated, "Changed admin password on %s", shPodName)
 
 			// Get client for Pod and restart splunk instance on pod
 			shClient := mgr.getClient(i)
@@ -377,7 +462,7 @@ func (mgr *searchHeadClusterPodManager) Update(c splcommon.ControllerClient, sta
 // PrepareScaleDown for searchHeadClusterPodManager prepares search head pod to be removed via scale down event; it returns true when ready
 func (mgr *searchHeadClusterPodManager) PrepareScaleDown(n int32) (bool, error) {
 	// start by quarantining the pod
-	result, err := mgr.PrepareRecycle(n)
+	result, err := mgr.detainMember(n)
 	if err != nil || !result {
 		return result, err
 	}
@@ -385,6 +470,7 @@ func (mgr *searchHeadClusterPodManager) PrepareScaleDown(n int32) (bool, error)
 	// pod is quarantined; decommission it
 	memberName := GetSplunkStatefulsetPodName(SplunkSearchHead, mgr.cr.GetName(), n)
 	mgr.log.Info("Removing member from search head cluster", "memberName", memberName)
+	mgr.eventPublisher.Normal(eventReasonDecommission, "Removing member %s from search head cluster", memberName)
 	c := mgr.getClient(n)
 	err = c.RemoveSearchHeadClusterMember()
 	if err != nil {
@@ -397,12 +483,22 @@ func (mgr *searchHeadClusterPodManager) PrepareScaleDown(n int32) (bool, error)
 
 // PrepareRecycle for searchHeadClusterPodManager prepares search head pod to be recycled for updates; it returns true when ready
 func (mgr *searchHeadClusterPodManager) PrepareRecycle(n int32) (bool, error) {
+	result, err := mgr.detainMember(n)
+	if err != nil || !result || mgr.prepareVolumes == nil {
+		return result, err
+	}
+	return mgr.prepareVolumes(n)
+}
+
+// detainMember puts a search head in manual detention; it returns true once its active searches have completed
+func (mgr *searchHeadClusterPodManager) detainMember(n int32) (bool, error) {
 	memberName := GetSplunkStatefulsetPodName(SplunkSearchHead, mgr.cr.GetName(), n)
 
 	switch mgr.cr.Status.Members[n].Status {
 	case "Up":
 		// Detain search head
 		mgr.log.Info("Detaining search head cluster member", "memberName", memberName)
+		mgr.eventPublisher.Normal(eventReasonPodRecycle, "Detaining search head cluster member %s before it is recycled", memberName)
 		c := mgr.getClient(n)
 		return false, c.SetSearchHeadDetention(true)
 
@@ -445,6 +541,24 @@ func (mgr *searchHeadClusterPodManager) FinishRecycle(n int32) (bool, error) {
 	return false, fmt.Errorf("Status=%s", mgr.cr.Status.Members[n].Status)
 }
 
+// GetRolloutStrategy for searchHeadClusterPodManager returns the rollout strategy of the SearchHeadCluster, recycling
+// no more than a minority of the members at the same time, so that the cluster can still elect a captain
+func (mgr *searchHeadClusterPodManager) GetRolloutStrategy() splcommon.RolloutStrategy {
+	strategy := mgr.cr.Spec.RolloutStrategy
+	if limit := getSearchHeadMaxUnavailable(mgr.cr.Spec.Replicas); strategy.MaxUnavailable > limit {
+		strategy.MaxUnavailable = limit
+	}
+	if mgr.holdUpdates != nil && mgr.holdUpdates() {
+		strategy.Paused = true
+	}
+	return strategy
+}
+
+// GetPvcRetentionPolicy for searchHeadClusterPodManager returns the PvcRetentionPolicy of the SearchHeadCluster
+func (mgr *searchHeadClusterPodManager) GetPvcRetentionPolicy() splcommon.PvcRetentionPolicy {
+	return mgr.cr.Spec.PvcRetentionPolicy
+}
+
 // getClient for searchHeadClusterPodManager returns a SplunkClient for the member n
 func (mgr *searchHeadClusterPodManager) getClient(n int32) *splclient.SplunkClient {
 	scopedLog := log.WithName("searchHeadClusterPodManager.getClient").WithValues("name", mgr.cr.GetName(), "namespace", mgr.cr.GetNamespace())
@@ -520,6 +634,23 @@ func (mgr *searchHeadClusterPodManager) updateStatus(statefulSet *appsv1.Statefu
 	return nil
 }
 
+// getSearchHeadMaxUnavailable returns the number of search heads that can be evicted at the same time by default,
+// so that the remaining members are still a majority of the search head cluster.
+func getSearchHeadMaxUnavailable(replicas int32) int32 {
+	if replicas > 2 {
+		return (replicas - 1) / 2
+	}
+	return 1
+}
+
+// getDeployerSplunkSpec returns the spec used for the PodDisruptionBudget of the deployer. The disruptions allowed in the
+// spec are those of the search heads, so the single deployer pod keeps the default, unless the budgets are disabled.
+func getDeployerSplunkSpec(cr *enterpriseApi.SearchHeadCluster) *enterpriseApi.CommonSplunkSpec {
+	spec := cr.Spec.CommonSplunkSpec
+	spec.PodDisruptionBudget = enterpriseApi.PodDisruptionBudgetSpec{Disabled: cr.Spec.PodDisruptionBudget.Disabled}
+	return &spec
+}
+
 // getSearchHeadStatefulSet returns a Kubernetes StatefulSet object for Splunk Enterprise search heads.
 func getSearchHeadStatefulSet(client splcommon.ControllerClient, cr *enterpriseApi.SearchHeadCluster) (*appsv1.StatefulSet, error) {
 
@@ -542,20 +673,27 @@ func getDeployerStatefulSet(client splcommon.ControllerClient, cr *enterpriseApi
 		return ss, err
 	}
 
-	// Setup App framework init containers
-	setupAppInitContainers(client, cr, &ss.Spec.Template, &cr.Spec.AppFrameworkConfig)
+	// Setup App framework staging volume
+	setupAppsStagingVolume(&ss.Spec.Template, &cr.Spec.AppFrameworkConfig)
 
 	return ss, err
 }
 
-// validateSearchHeadClusterSpec checks validity and makes default updates to a SearchHeadClusterSpec, and returns error if something is wrong.
-func validateSearchHeadClusterSpec(cr *enterpriseApi.SearchHeadCluster) error {
+// setSearchHeadClusterDefaults sets default values for the parameters of a SearchHeadClusterSpec that are not provided.
+func setSearchHeadClusterDefaults(cr *enterpriseApi.SearchHeadCluster) {
+	// a search head cluster needs at least 3 members
 	if cr.Spec.Replicas < 3 {
 		cr.Spec.Replicas = 3
 	}
 
+	setAppFrameworkDefaults(&cr.Spec.AppFrameworkConfig)
+	setCommonSplunkSpecDefaults(&cr.Spec.CommonSplunkSpec)
+}
+
+// validateSearchHeadClusterSpec checks validity of a SearchHeadClusterSpec, and returns error if something is wrong.
+func validateSearchHeadClusterSpec(cr *enterpriseApi.SearchHeadCluster) error {
 	if !reflect.DeepEqual(cr.Status.AppContext.AppFrameworkConfig, cr.Spec.AppFrameworkConfig) {
-		err := ValidateAppFrameworkSpec(&cr.Spec.AppFrameworkConfig, &cr.Status.AppContext, false)
+		err := ValidateAppFrameworkSpec(&cr.Spec.AppFrameworkConfig, false)
 		if err != nil {
 			return err
 		}
@@ -24,6 +24,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	enterpriseApi "github.com/splunk/splunk-operator/pkg/apis/enterprise/v2"
@@ -42,10 +43,13 @@ func TestApplySearchHeadCluster(t *testing.T) {
 		{MetaName: "*v1.Service-test-splunk-stack1-deployer-service"},
 		{MetaName: "*v1.Secret-test-splunk-test-secret"},
 		{MetaName: "*v1.Secret-test-splunk-stack1-deployer-secret-v1"},
-		{MetaName: "*v1.ConfigMap-test-splunk-stack1-searchheadcluster-app-list"},
+		{MetaName: "*v1beta1.PodDisruptionBudget-test-splunk-stack1-deployer"},
+		{MetaName: "*v1.StatefulSet-test-splunk-stack1-deployer"},
 		{MetaName: "*v1.StatefulSet-test-splunk-stack1-deployer"},
 		{MetaName: "*v1.Secret-test-splunk-test-secret"},
 		{MetaName: "*v1.Secret-test-splunk-stack1-search-head-secret-v1"},
+		{MetaName: "*v1beta1.PodDisruptionBudget-test-splunk-stack1-search-head"},
+		{MetaName: "*v1.StatefulSet-test-splunk-stack1-search-head"},
 		{MetaName: "*v1.StatefulSet-test-splunk-stack1-search-head"},
 		{MetaName: "*v1.Secret-test-splunk-test-secret"},
 	}
@@ -60,8 +64,8 @@ func TestApplySearchHeadCluster(t *testing.T) {
 	listmockCall := []spltest.MockFuncCall{
 		{ListOpts: listOpts}}
 
-	createCalls := map[string][]spltest.MockFuncCall{"Get": funcCalls, "Create": {funcCalls[0], funcCalls[2], funcCalls[3], funcCalls[4], funcCalls[6], funcCalls[8], funcCalls[10], funcCalls[11]}, "Update": {funcCalls[0]}, "List": {listmockCall[0], listmockCall[0]}}
-	updateCalls := map[string][]spltest.MockFuncCall{"Get": funcCalls, "Update": {funcCalls[8], funcCalls[11]}, "List": {listmockCall[0], listmockCall[0]}}
+	createCalls := map[string][]spltest.MockFuncCall{"Get": funcCalls, "Create": {funcCalls[0], funcCalls[2], funcCalls[3], funcCalls[4], funcCalls[6], funcCalls[7], funcCalls[9], funcCalls[11], funcCalls[12], funcCalls[14]}, "Update": {funcCalls[0]}, "List": {listmockCall[0], listmockCall[0]}}
+	updateCalls := map[string][]spltest.MockFuncCall{"Get": funcCalls, "Update": {funcCalls[9], funcCalls[14]}, "List": {listmockCall[0], listmockCall[0]}}
 	statefulSet := enterpriseApi.SearchHeadCluster{
 		TypeMeta: metav1.TypeMeta{
 			Kind: "SearchHeadCluster",
@@ -80,7 +84,7 @@ func TestApplySearchHeadCluster(t *testing.T) {
 	revised := statefulSet.DeepCopy()
 	revised.Spec.Image = "splunk/test"
 	reconcile := func(c *spltest.MockClient, cr interface{}) error {
-		_, err := ApplySearchHeadCluster(c, cr.(*enterpriseApi.SearchHeadCluster))
+		_, err := ApplySearchHeadCluster(c, nil, cr.(*enterpriseApi.SearchHeadCluster))
 		return err
 	}
 	spltest.ReconcileTesterWithoutRedundantCheck(t, "TestApplySearchHeadCluster", &statefulSet, revised, createCalls, updateCalls, reconcile, true)
@@ -90,7 +94,7 @@ func TestApplySearchHeadCluster(t *testing.T) {
 	revised.ObjectMeta.DeletionTimestamp = &currentTime
 	revised.ObjectMeta.Finalizers = []string{"enterprise.splunk.com/delete-pvc"}
 	deleteFunc := func(cr splcommon.MetaObject, c splcommon.ControllerClient) (bool, error) {
-		_, err := ApplySearchHeadCluster(c, cr.(*enterpriseApi.SearchHeadCluster))
+		_, err := ApplySearchHeadCluster(c, nil, cr.(*enterpriseApi.SearchHeadCluster))
 		return true, err
 	}
 	splunkDeletionTester(t, revised, deleteFunc)
@@ -533,6 +537,7 @@ func TestGetSearchHeadStatefulSet(t *testing.T) {
 
 	test := func(want string) {
 		f := func() (interface{}, error) {
+			setSearchHeadClusterDefaults(&cr)
 			if err := validateSearchHeadClusterSpec(&cr); err != nil {
 				t.Errorf("validateSearchHeadClusterSpec() returned error: %v", err)
 			}
@@ -599,6 +604,7 @@ func TestGetDeployerStatefulSet(t *testing.T) {
 
 	test := func(want string) {
 		f := func() (interface{}, error) {
+			setSearchHeadClusterDefaults(&cr)
 			if err := validateSearchHeadClusterSpec(&cr); err != nil {
 				t.Errorf("validateSearchHeadClusterSpec() returned error: %v", err)
 			}
@@ -675,7 +681,7 @@ func TestAppFrameworkSearchHeadClusterShouldNotFail(t *testing.T) {
 
 	client.AddObject(&s3Secret)
 
-	_, err = ApplySearchHeadCluster(client, &cr)
+	_, err = ApplySearchHeadCluster(client, nil, &cr)
 	if err != nil {
 		t.Errorf("ApplySearchHeadCluster should be successful")
 	}
@@ -1007,3 +1013,45 @@ func TestSHCGetAppsListForAWSS3ClientShouldFail(t *testing.T) {
 		t.Errorf("GetAppsList should have returned error as we have empty objects in MockAWSS3Client")
 	}
 }
+
+func TestGetSearchHeadMaxUnavailable(t *testing.T) {
+	for replicas, want := range map[int32]int32{1: 1, 3: 1, 4: 1, 5: 2, 8: 3} {
+		if got := getSearchHeadMaxUnavailable(replicas); got != want {
+			t.Errorf("getSearchHeadMaxUnavailable(%d) = %d; want %d", replicas, got, want)
+		}
+	}
+}
+
+func TestGetDeployerSplunkSpec(t *testing.T) {
+	cr := enterpriseApi.SearchHeadCluster{ObjectMeta: metav1.ObjectMeta{Name: "stack1", Namespace: "test"}}
+	minAvailable := intstr.FromInt(3)
+	cr.Spec.PodDisruptionBudget.MinAvailable = &minAvailable
+
+	// the disruptions allowed for the search heads do not apply to the deployer
+	pdb := getSplunkPodDisruptionBudget(&cr, getDeployerSplunkSpec(&cr), SplunkDeployer, 1)
+	if pdb.Spec.MinAvailable != nil || pdb.Spec.MaxUnavailable == nil || pdb.Spec.MaxUnavailable.IntValue() != 1 {
+		t.Errorf("getSplunkPodDisruptionBudget() of the deployer = %v; want maxUnavailable 1", pdb.Spec)
+	}
+	if cr.Spec.PodDisruptionBudget.MinAvailable == nil {
+		t.Errorf("getDeployerSplunkSpec() changed the spec of the search heads")
+	}
+
+	cr.Spec.PodDisruptionBudget.Disabled = true
+	if !getDeployerSplunkSpec(&cr).PodDisruptionBudget.Disabled {
+		t.Errorf("getDeployerSplunkSpec() should keep the PodDisruptionBudgets disabled")
+	}
+}
+
+func TestSearchHeadClusterGetRolloutStrategy(t *testing.T) {
+	cr := enterpriseApi.SearchHeadCluster{}
+	cr.Spec.Replicas = 5
+	cr.Spec.RolloutStrategy.MaxUnavailable = 3
+	mgr := searchHeadClusterPodManager{cr: &cr}
+	if got := mgr.GetRolloutStrategy().MaxUnavailable; got != 2 {
+		t.Errorf("GetRolloutStrategy() MaxUnavailable = %d; want 2", got)
+	}
+	cr.Spec.RolloutStrategy.MaxUnavailable = 1
+	if got := mgr.GetRolloutStrategy().MaxUnavailable; got != 1 {
+		t.Errorf("GetRolloutStrategy() MaxUnavailable = %d; want 1", got)
+	}
+}
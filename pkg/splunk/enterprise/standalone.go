@@ -22,6 +22,7 @@ import (
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	enterpriseApi "github.com/splunk/splunk-operator/pkg/apis/enterprise/v2"
@@ -30,22 +31,35 @@ import (
 )
 
 // ApplyStandalone reconciles the StatefulSet for N standalone instances of Splunk Enterprise.
-func ApplyStandalone(client splcommon.ControllerClient, cr *enterpriseApi.Standalone) (reconcile.Result, error) {
+func ApplyStandalone(client splcommon.ControllerClient, recorder record.EventRecorder, cr *enterpriseApi.Standalone) (result reconcile.Result, err error) {
 
 	// unless modified, reconcile for this object will be requeued after 5 seconds
-	result := reconcile.Result{
+	result = reconcile.Result{
 		Requeue:      true,
 		RequeueAfter: time.Second * 5,
 	}
 
 	scopedLog := log.WithName("ApplyStandalone").WithValues("name", cr.GetName(), "namespace", cr.GetNamespace())
+	eventPublisher := newEventPublisher(recorder, cr)
+	oldPhase := cr.Status.Phase
 	if cr.Status.ResourceRevMap == nil {
 		cr.Status.ResourceRevMap = make(map[string]string)
 	}
 
-	// validate and updates defaults for CR
-	err := validateStandaloneSpec(cr)
+	// updates status and conditions after function completes, including validation failures
+	defer func() {
+		setStatusConditions(cr, err)
+		if updateErr := client.Status().Update(context.TODO(), cr); updateErr != nil {
+			scopedLog.Error(updateErr, "Status update failed")
+		}
+	}()
+
+	// updates defaults and validates CR
+	setStandaloneDefaults(cr)
+	err = validateStandaloneSpec(cr)
 	if err != nil {
+		cr.Status.Phase = splcommon.PhaseError
+		eventPublisher.Warning(eventReasonValidationFailed, "%v", err)
 		scopedLog.Error(err, "Failed to validate standalone spec")
 		return result, err
 	}
@@ -54,17 +68,35 @@ func ApplyStandalone(client splcommon.ControllerClient, cr *enterpriseApi.Standa
 	cr.Status.Phase = splcommon.PhaseError
 	cr.Status.Replicas = cr.Spec.Replicas
 
-	if !reflect.DeepEqual(cr.Status.SmartStore, cr.Spec.SmartStore) ||
-		AreRemoteVolumeKeysChanged(client, cr, SplunkStandalone, &cr.Spec.SmartStore, cr.Status.ResourceRevMap, &err) {
+	// the Smartstore configuration is left as is while the CR is deleted, so that its checks do not block the deletion
+	if cr.ObjectMeta.DeletionTimestamp == nil && (!reflect.DeepEqual(cr.Status.SmartStore, cr.Spec.SmartStore) ||
+		AreRemoteVolumeKeysChanged(client, cr, SplunkStandalone, &cr.Spec.SmartStore, cr.Status.ResourceRevMap, &err)) {
 
 		if err != nil {
 			return result, err
 		}
 
+		var removedIndexes []string
+		removedIndexes, err = checkSmartstoreIndexRemovals(cr, &cr.Spec.SmartStore, &cr.Status.SmartStore)
+		if err != nil {
+			eventPublisher.Warning(eventReasonIndexRemovalBlocked, "%v", err)
+			return result, err
+		}
+
+		// a volume failing the access check blocks the configuration of all the volumes
+		err = checkSmartstoreVolumes(client, cr, SplunkStandalone, &cr.Spec.SmartStore)
+		if err != nil {
+			eventPublisher.Warning(eventReasonVolumeCheckFailed, "%v", err)
+			return result, err
+		}
+
 		_, _, err := ApplySmartstoreConfigMap(client, cr, &cr.Spec.SmartStore)
 		if err != nil {
 			return result, err
 		}
+		for _, index := range removedIndexes {
+			eventPublisher.Normal(eventReasonIndexRemoved, "Removed index %s from the Smartstore configuration", index)
+		}
 
 		cr.Status.SmartStore = cr.Spec.SmartStore
 	}
@@ -73,7 +105,7 @@ func ApplyStandalone(client splcommon.ControllerClient, cr *enterpriseApi.Standa
 	// 1. Initialize the S3Clients based on providers
 	// 2. Check the status of apps on remote storage.
 	if len(cr.Spec.AppFrameworkConfig.AppSources) != 0 {
-		err := initAndCheckAppInfoStatus(client, cr, &cr.Spec.AppFrameworkConfig, &cr.Status.AppContext)
+		err := initAndCheckAppInfoStatus(client, cr, &cr.Spec.AppFrameworkConfig, &cr.Status.AppContext, eventPublisher)
 		if err != nil {
 			cr.Status.AppContext.IsDeploymentInProgress = false
 			return result, err
@@ -81,12 +113,6 @@ func ApplyStandalone(client splcommon.ControllerClient, cr *enterpriseApi.Standa
 	}
 
 	cr.Status.Selector = fmt.Sprintf("app.kubernetes.io/instance=splunk-%s-standalone", cr.GetName())
-	defer func() {
-		client.Status().Update(context.TODO(), cr)
-		if err != nil {
-			scopedLog.Error(err, "Status update failed")
-		}
-	}()
 
 	// create or update general config resources
 	_, err = ApplySplunkConfig(client, cr, cr.Spec.CommonSplunkSpec, SplunkStandalone)
@@ -124,57 +150,64 @@ func ApplyStandalone(client splcommon.ControllerClient, cr *enterpriseApi.Standa
 		return result, err
 	}
 
-	// If we are using appFramework and are scaling up, we should re-populate the
-	// configMap with all the appSource entries. This is done so that the new pods
-	// that come up now will have the complete list of all the apps and then can
-	// download and install all the apps.
-	// TODO: Improve this logic so that we only recycle the new pod/replica
-	// and not all the existing pods.
-	if len(cr.Spec.AppFrameworkConfig.AppSources) != 0 && cr.Status.ReadyReplicas > 0 {
-
-		statefulsetName := GetSplunkStatefulsetName(SplunkStandalone, cr.GetName())
-
-		isScalingUp, err := splctrl.IsStatefulSetScalingUp(client, cr, statefulsetName, cr.Spec.Replicas)
-		if err != nil {
-			return result, err
-		} else if isScalingUp {
-			// if we are indeed scaling up, then mark the deploy status to Pending
-			// for all the app sources so that we add all the app sources in configMap.
-			cr.Status.AppContext.IsDeploymentInProgress = true
-			appStatusContext := cr.Status.AppContext
-			for appSrc := range appStatusContext.AppsSrcDeployStatus {
-				changeAppSrcDeployInfoStatus(appSrc, appStatusContext.AppsSrcDeployStatus, enterpriseApi.RepoStateActive, enterpriseApi.DeployStatusComplete, enterpriseApi.DeployStatusPending)
-			}
+	// create or update statefulset
+	statefulSet, err := getStandaloneStatefulSet(client, cr)
+	if err != nil {
+		return result, err
+	}
 
-			// Now apply the configMap will full app listing.
-			_, _, err = ApplyAppListingConfigMap(client, cr, &cr.Spec.AppFrameworkConfig, appStatusContext.AppsSrcDeployStatus, false)
-			if err != nil {
-				return result, err
-			}
-		}
+	// create or update a PodDisruptionBudget for the standalone instances
+	err = applySplunkPodDisruptionBudget(client, cr, &cr.Spec.CommonSplunkSpec, SplunkStandalone, 1)
+	if err != nil {
+		return result, err
 	}
 
-	// create or update statefulset
-	statefulSet, err := getStandaloneStatefulSet(client, cr)
+	// move the volumes of the standalone instances to new storage, if it changed
+	migration := newStorageMigration(client, statefulSet)
+	recreating, err := migration.apply()
 	if err != nil {
 		return result, err
 	}
+	if recreating {
+		eventPublisher.Normal(eventReasonStorageMigration, "Recreating StatefulSet %s to move its volumes to new storage", statefulSet.GetName())
+		return result, nil
+	}
 
-	mgr := splctrl.DefaultStatefulSetPodManager{}
+	// hold off updates while the license and cluster managers are upgraded
+	gate := newUpgradeGate(client, cr, &cr.Spec.CommonSplunkSpec)
+	mgr := splctrl.DefaultStatefulSetPodManager{RolloutStrategy: cr.Spec.RolloutStrategy, PvcRetentionPolicy: cr.Spec.PvcRetentionPolicy, HoldUpdates: gate.hold, PrepareVolumes: migration.migratePod}
 	phase, err := mgr.Update(client, statefulSet, cr.Spec.Replicas)
 	cr.Status.ReadyReplicas = statefulSet.Status.ReadyReplicas
+	cr.Status.StorageMigration = mergeStorageMigration(migration)
 	if err != nil {
 		return result, err
 	}
+	eventPublisher.PhaseChanged(oldPhase, phase, cr.Spec.Replicas)
 	cr.Status.Phase = phase
 
+	// report the claims kept after a scale down
+	orphanedClaims, listErr := getOrphanedClaims(client, cr, &cr.Spec.CommonSplunkSpec, SplunkStandalone, cr.Spec.Replicas)
+	if listErr != nil {
+		scopedLog.Error(listErr, "Unable to list the PVCs retained after scale down")
+	} else {
+		cr.Status.OrphanedClaims = orphanedClaims
+	}
+
+	// expand the claims of the pods after the storage capacity was increased
+	cr.Status.PvcExpansion, err = applyPvcExpansion(client, cr, &cr.Spec.CommonSplunkSpec, statefulSet)
+	if err != nil {
+		return result, err
+	}
+
 	// no need to requeue if everything is ready
 	if cr.Status.Phase == splcommon.PhaseReady {
 		if cr.Status.AppContext.AppsSrcDeployStatus != nil {
-			markAppsStatusToComplete(client, cr, &cr.Spec.AppFrameworkConfig, cr.Status.AppContext.AppsSrcDeployStatus)
-			// Schedule one more reconcile in next 5 seconds, just to cover any latest app framework config changes
-			if cr.Status.AppContext.IsDeploymentInProgress {
-				cr.Status.AppContext.IsDeploymentInProgress = false
+			requeue, err := applyAppInstalls(client, cr, &cr.Spec.AppFrameworkConfig, &cr.Status.AppContext, eventPublisher)
+			if err != nil {
+				return result, err
+			}
+			// Schedule one more reconcile in next 5 seconds, until the apps are installed and to cover any latest app framework config changes
+			if requeue {
 				return result, nil
 			}
 		}
@@ -191,6 +224,8 @@ func ApplyStandalone(client splcommon.ControllerClient, cr *enterpriseApi.Standa
 			result.Requeue = false
 		}
 	}
+	requeueIfExpanding(&result, eventPublisher, cr.Status.PvcExpansion)
+	gate.requeueIfHeld(&result, eventPublisher)
 	return result, nil
 }
 
@@ -205,21 +240,28 @@ func getStandaloneStatefulSet(client splcommon.ControllerClient, cr *enterpriseA
 	smartStoreConfigMap := getSmartstoreConfigMap(client, cr, SplunkStandalone)
 
 	if smartStoreConfigMap != nil {
-		setupInitContainer(&ss.Spec.Template, cr.Spec.Image, cr.Spec.ImagePullPolicy, commandForStandaloneSmartstore)
+		setupInitContainer(&ss.Spec.Template, GetSplunkImage(cr.Spec.Image), cr.Spec.ImagePullPolicy, commandForStandaloneSmartstore)
 	}
+	addSmartstoreCredentialFiles(&ss.Spec.Template, &cr.Spec.SmartStore)
 
-	// Setup App framework init containers
-	setupAppInitContainers(client, cr, &ss.Spec.Template, &cr.Spec.AppFrameworkConfig)
+	// Setup App framework staging volume
+	setupAppsStagingVolume(&ss.Spec.Template, &cr.Spec.AppFrameworkConfig)
 
 	return ss, nil
 }
 
-// validateStandaloneSpec checks validity and makes default updates to a StandaloneSpec, and returns error if something is wrong.
-func validateStandaloneSpec(cr *enterpriseApi.Standalone) error {
+// setStandaloneDefaults sets default values for the parameters of a StandaloneSpec that are not provided.
+func setStandaloneDefaults(cr *enterpriseApi.Standalone) {
 	if cr.Spec.Replicas == 0 {
 		cr.Spec.Replicas = 1
 	}
 
+	setAppFrameworkDefaults(&cr.Spec.AppFrameworkConfig)
+	setCommonSplunkSpecDefaults(&cr.Spec.CommonSplunkSpec)
+}
+
+// validateStandaloneSpec checks validity of a StandaloneSpec, and returns error if something is wrong.
+func validateStandaloneSpec(cr *enterpriseApi.Standalone) error {
 	if !reflect.DeepEqual(cr.Status.SmartStore, cr.Spec.SmartStore) {
 		err := ValidateSplunkSmartstoreSpec(&cr.Spec.SmartStore)
 		if err != nil {
@@ -228,7 +270,7 @@ func validateStandaloneSpec(cr *enterpriseApi.Standalone) error {
 	}
 
 	if !reflect.DeepEqual(cr.Status.AppContext.AppFrameworkConfig, cr.Spec.AppFrameworkConfig) {
-		err := ValidateAppFrameworkSpec(&cr.Spec.AppFrameworkConfig, &cr.Status.AppContext, true)
+		err := ValidateAppFrameworkSpec(&cr.Spec.AppFrameworkConfig, true)
 		if err != nil {
 			return err
 		}
@@ -39,8 +39,9 @@ func TestApplyStandalone(t *testing.T) {
 		{MetaName: "*v1.Secret-test-splunk-test-secret"},
 		{MetaName: "*v1.Secret-test-splunk-stack1-standalone-secret-v1"},
 		{MetaName: "*v1.ConfigMap-test-splunk-stack1-standalone-smartstore"},
-		{MetaName: "*v1.ConfigMap-test-splunk-stack1-standalone-app-list"},
 		{MetaName: "*v1.ConfigMap-test-splunk-stack1-standalone-smartstore"},
+		{MetaName: "*v1beta1.PodDisruptionBudget-test-splunk-stack1-standalone"},
+		{MetaName: "*v1.StatefulSet-test-splunk-stack1-standalone"},
 		{MetaName: "*v1.StatefulSet-test-splunk-stack1-standalone"},
 	}
 	labels := map[string]string{
@@ -54,8 +55,8 @@ func TestApplyStandalone(t *testing.T) {
 	listmockCall := []spltest.MockFuncCall{
 		{ListOpts: listOpts}}
 
-	createCalls := map[string][]spltest.MockFuncCall{"Get": funcCalls, "Create": {funcCalls[0], funcCalls[2], funcCalls[3], funcCalls[5], funcCalls[9]}, "Update": {funcCalls[0]}, "List": {listmockCall[0]}}
-	updateCalls := map[string][]spltest.MockFuncCall{"Get": funcCalls, "Update": {funcCalls[9]}, "List": {listmockCall[0]}}
+	createCalls := map[string][]spltest.MockFuncCall{"Get": funcCalls, "Create": {funcCalls[0], funcCalls[2], funcCalls[3], funcCalls[5], funcCalls[8], funcCalls[10]}, "Update": {funcCalls[0]}, "List": {listmockCall[0]}}
+	updateCalls := map[string][]spltest.MockFuncCall{"Get": funcCalls, "Update": {funcCalls[10]}, "List": {listmockCall[0]}}
 	current := enterpriseApi.Standalone{
 		TypeMeta: metav1.TypeMeta{
 			Kind: "Standalone",
@@ -68,7 +69,7 @@ func TestApplyStandalone(t *testing.T) {
 	revised := current.DeepCopy()
 	revised.Spec.Image = "splunk/test"
 	reconcile := func(c *spltest.MockClient, cr interface{}) error {
-		_, err := ApplyStandalone(c, cr.(*enterpriseApi.Standalone))
+		_, err := ApplyStandalone(c, nil, cr.(*enterpriseApi.Standalone))
 		return err
 	}
 	spltest.ReconcileTesterWithoutRedundantCheck(t, "TestApplyStandalone", &current, revised, createCalls, updateCalls, reconcile, true)
@@ -78,14 +79,20 @@ func TestApplyStandalone(t *testing.T) {
 	revised.ObjectMeta.DeletionTimestamp = &currentTime
 	revised.ObjectMeta.Finalizers = []string{"enterprise.splunk.com/delete-pvc"}
 	deleteFunc := func(cr splcommon.MetaObject, c splcommon.ControllerClient) (bool, error) {
-		_, err := ApplyStandalone(c, cr.(*enterpriseApi.Standalone))
+		_, err := ApplyStandalone(c, nil, cr.(*enterpriseApi.Standalone))
 		return true, err
 	}
 	splunkDeletionTester(t, revised, deleteFunc)
 }
 
 func TestApplyStandaloneWithSmartstore(t *testing.T) {
+	checkS3VolumeAccess = func(endpoint, bucket, prefix, objectName, accessKeyID, secretAccessKey string) error {
+		return nil
+	}
+	defer func() { checkS3VolumeAccess = splclient.CheckS3VolumeAccess }()
+
 	funcCalls := []spltest.MockFuncCall{
+		{MetaName: "*v1.Secret-test-splunk-test-secret"},
 		{MetaName: "*v1.Secret-test-splunk-test-secret"},
 		{MetaName: "*v1.Secret-test-splunk-test-secret"},
 		{MetaName: "*v1.ConfigMap-test-splunk-stack1-standalone-smartstore"},
@@ -97,8 +104,9 @@ func TestApplyStandaloneWithSmartstore(t *testing.T) {
 		{MetaName: "*v1.Secret-test-splunk-test-secret"},
 		{MetaName: "*v1.Secret-test-splunk-stack1-standalone-secret-v1"},
 		{MetaName: "*v1.ConfigMap-test-splunk-stack1-standalone-smartstore"},
-		{MetaName: "*v1.ConfigMap-test-splunk-stack1-standalone-app-list"},
 		{MetaName: "*v1.ConfigMap-test-splunk-stack1-standalone-smartstore"},
+		{MetaName: "*v1beta1.PodDisruptionBudget-test-splunk-stack1-standalone"},
+		{MetaName: "*v1.StatefulSet-test-splunk-stack1-standalone"},
 		{MetaName: "*v1.StatefulSet-test-splunk-stack1-standalone"},
 	}
 	labels := map[string]string{
@@ -112,8 +120,8 @@ func TestApplyStandaloneWithSmartstore(t *testing.T) {
 	listmockCall := []spltest.MockFuncCall{
 		{ListOpts: listOpts}}
 
-	createCalls := map[string][]spltest.MockFuncCall{"Get": funcCalls, "Create": {funcCalls[2], funcCalls[6], funcCalls[7], funcCalls[9], funcCalls[13]}, "Update": {funcCalls[0]}, "List": {listmockCall[0]}}
-	updateCalls := map[string][]spltest.MockFuncCall{"Get": funcCalls, "Update": {funcCalls[12], funcCalls[13]}, "List": {listmockCall[0]}}
+	createCalls := map[string][]spltest.MockFuncCall{"Get": funcCalls, "Create": {funcCalls[3], funcCalls[7], funcCalls[8], funcCalls[10], funcCalls[13], funcCalls[15]}, "Update": {funcCalls[1]}, "List": {listmockCall[0]}}
+	updateCalls := map[string][]spltest.MockFuncCall{"Get": funcCalls, "Update": {funcCalls[12], funcCalls[15]}, "List": {listmockCall[0]}}
 
 	current := enterpriseApi.Standalone{
 		TypeMeta: metav1.TypeMeta{
@@ -150,7 +158,7 @@ func TestApplyStandaloneWithSmartstore(t *testing.T) {
 	client := spltest.NewMockClient()
 
 	// Without S3 keys, ApplyStandalone should fail
-	_, err := ApplyStandalone(client, &current)
+	_, err := ApplyStandalone(client, nil, &current)
 	if err == nil {
 		t.Errorf("ApplyStandalone should fail without S3 secrets configured")
 	}
@@ -171,7 +179,7 @@ func TestApplyStandaloneWithSmartstore(t *testing.T) {
 	revised := current.DeepCopy()
 	revised.Spec.Image = "splunk/test"
 	reconcile := func(c *spltest.MockClient, cr interface{}) error {
-		_, err := ApplyStandalone(c, cr.(*enterpriseApi.Standalone))
+		_, err := ApplyStandalone(c, nil, cr.(*enterpriseApi.Standalone))
 		return err
 	}
 	spltest.ReconcileTesterWithoutRedundantCheck(t, "TestApplyStandaloneWithSmartstore", &current, revised, createCalls, updateCalls, reconcile, true, secret)
@@ -193,6 +201,7 @@ func TestGetStandaloneStatefulSet(t *testing.T) {
 
 	test := func(want string) {
 		f := func() (interface{}, error) {
+			setStandaloneDefaults(&cr)
 			if err := validateStandaloneSpec(&cr); err != nil {
 				t.Errorf("validateStandaloneSpec() returned error: %v", err)
 			}
@@ -247,6 +256,11 @@ func TestGetStandaloneStatefulSet(t *testing.T) {
 }
 
 func TestApplyStandaloneSmartstoreKeyChangeDetection(t *testing.T) {
+	checkS3VolumeAccess = func(endpoint, bucket, prefix, objectName, accessKeyID, secretAccessKey string) error {
+		return nil
+	}
+	defer func() { checkS3VolumeAccess = splclient.CheckS3VolumeAccess }()
+
 	current := enterpriseApi.Standalone{
 		TypeMeta: metav1.TypeMeta{
 			Kind: "Standalone",
@@ -285,7 +299,7 @@ func TestApplyStandaloneSmartstoreKeyChangeDetection(t *testing.T) {
 		t.Errorf(err.Error())
 	}
 
-	_, err = ApplyStandalone(client, &current)
+	_, err = ApplyStandalone(client, nil, &current)
 	if err != nil {
 		t.Errorf("ApplyStandalone should not fail with full configuration")
 	}
@@ -355,7 +369,7 @@ func TestAppFrameworkApplyStandaloneShouldNotFail(t *testing.T) {
 
 	client.AddObject(&s3Secret)
 
-	_, err = ApplyStandalone(client, &cr)
+	_, err = ApplyStandalone(client, nil, &cr)
 	if err != nil {
 		t.Errorf("ApplyStandalone should be successful")
 	}
@@ -410,14 +424,14 @@ func TestAppFrameworkApplyStandaloneScalingUpShouldNotFail(t *testing.T) {
 
 	client.AddObject(&s3Secret)
 
-	_, err = ApplyStandalone(client, &cr)
+	_, err = ApplyStandalone(client, nil, &cr)
 	if err != nil {
 		t.Errorf("ApplyStandalone should be successful")
 	}
 
 	// now scale up
 	cr.Spec.Replicas = 2
-	_, err = ApplyStandalone(client, &cr)
+	_, err = ApplyStandalone(client, nil, &cr)
 	if err != nil {
 		t.Errorf("ApplyStandalone should be successful")
 	}
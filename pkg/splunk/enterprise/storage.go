@@ -0,0 +1,82 @@
+// Copyright (c) 2018-2021 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enterprise
+
+import (
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	enterpriseApi "github.com/splunk/splunk-operator/pkg/apis/enterprise/v2"
+	splcommon "github.com/splunk/splunk-operator/pkg/splunk/common"
+	splctrl "github.com/splunk/splunk-operator/pkg/splunk/controller"
+)
+
+// applyPvcExpansion expands the claims of the pods of a StatefulSet after the storage capacity of the etc or var
+// volumes of a custom resource was increased. It returns the progress of the expansion, or nil if no claim needs to grow.
+func applyPvcExpansion(c splcommon.ControllerClient, cr splcommon.MetaObject, spec *enterpriseApi.CommonSplunkSpec, statefulSet *appsv1.StatefulSet) (*splcommon.PvcExpansionStatus, error) {
+	requests := make(map[string]resource.Quantity)
+	for _, volumeType := range []string{splcommon.EtcVolumeStorage, splcommon.VarVolumeStorage} {
+		volumeClaim, err := getSplunkVolumeClaims(cr, spec, nil, volumeType)
+		if err != nil {
+			return nil, err
+		}
+		requests[volumeClaim.GetName()] = volumeClaim.Spec.Resources.Requests[corev1.ResourceStorage]
+		// claims moved to another StorageClass use the name of their migration
+		requests[fmt.Sprintf(splcommon.MigratedPvcNamePrefix, volumeType)] = volumeClaim.Spec.Resources.Requests[corev1.ResourceStorage]
+	}
+	return splctrl.ExpandStatefulSetClaims(c, statefulSet, requests)
+}
+
+// mergePvcExpansion sums the progress of the expansion of the claims of several StatefulSets of a custom resource
+func mergePvcExpansion(statuses ...*splcommon.PvcExpansionStatus) *splcommon.PvcExpansionStatus {
+	var merged *splcommon.PvcExpansionStatus
+	for _, status := range statuses {
+		if status == nil {
+			continue
+		}
+		if merged == nil {
+			merged = &splcommon.PvcExpansionStatus{}
+		}
+		merged.TotalClaims += status.TotalClaims
+		merged.ExpandedClaims += status.ExpandedClaims
+		merged.PendingClaims = append(merged.PendingClaims, status.PendingClaims...)
+		if merged.Message == "" {
+			merged.Message = status.Message
+		}
+	}
+	return merged
+}
+
+// requeueIfExpanding makes the reconcile requeue while claims are being expanded, since the completion of the
+// resize of their file systems does not trigger one. Expansions which can not proceed are reported by an event.
+func requeueIfExpanding(result *reconcile.Result, eventPublisher *eventPublisher, status *splcommon.PvcExpansionStatus) {
+	if status == nil {
+		return
+	}
+	if status.Message != "" {
+		eventPublisher.Warning(eventReasonVolumeExpansion, "Unable to expand claims: %s", status.Message)
+		return
+	}
+	eventPublisher.Normal(eventReasonVolumeExpansion, "Expanded %d of %d claims", status.ExpandedClaims, status.TotalClaims)
+	result.Requeue = true
+	if result.RequeueAfter == 0 {
+		result.RequeueAfter = time.Second * 5
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright (c) 2018-2021 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enterprise
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	enterpriseApi "github.com/splunk/splunk-operator/pkg/apis/enterprise/v2"
+	splcommon "github.com/splunk/splunk-operator/pkg/splunk/common"
+	spltest "github.com/splunk/splunk-operator/pkg/splunk/test"
+)
+
+func TestApplyPvcExpansion(t *testing.T) {
+	c := spltest.NewMockClient()
+	c.NotFoundError = k8serrors.NewNotFound(schema.GroupResource{Group: "storage.k8s.io"}, "")
+	cr := enterpriseApi.Standalone{ObjectMeta: metav1.ObjectMeta{Name: "stack1", Namespace: "test"}}
+	statefulSet := &appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "splunk-stack1-standalone", Namespace: "test"}}
+	for _, volumeType := range []string{splcommon.EtcVolumeStorage, splcommon.VarVolumeStorage} {
+		volumeClaim, err := getSplunkVolumeClaims(&cr, &cr.Spec.CommonSplunkSpec, nil, volumeType)
+		if err != nil {
+			t.Fatalf("getSplunkVolumeClaims() returned %v", err)
+		}
+		statefulSet.Spec.VolumeClaimTemplates = append(statefulSet.Spec.VolumeClaimTemplates, volumeClaim)
+	}
+
+	// the default storage capacity does not need any expansion
+	status, err := applyPvcExpansion(c, &cr, &cr.Spec.CommonSplunkSpec, statefulSet)
+	if err != nil || status != nil || len(c.Calls["Get"]) != 0 {
+		t.Errorf("applyPvcExpansion() = %v, %v; want nil without API calls", status, err)
+	}
+
+	// growing the var volume looks for the default storage class, which does not exist
+	cr.Spec.VarVolumeStorageConfig.StorageCapacity = "200Gi"
+	status, err = applyPvcExpansion(c, &cr, &cr.Spec.CommonSplunkSpec, statefulSet)
+	if err != nil || status == nil || status.TotalClaims != 1 || status.Message == "" || len(c.Calls["List"]) != 1 {
+		t.Errorf("applyPvcExpansion() = %v, %v; want one claim to expand", status, err)
+	}
+
+	cr.Spec.VarVolumeStorageConfig.StorageCapacity = "1Gi"
+	status, err = applyPvcExpansion(c, &cr, &cr.Spec.CommonSplunkSpec, statefulSet)
+	if err != nil || status != nil {
+		t.Errorf("applyPvcExpansion() = %v, %v; want nil when shrinking", status, err)
+	}
+
+	cr.Spec.VarVolumeStorageConfig.StorageCapacity = "invalid"
+	_, err = applyPvcExpansion(c, &cr, &cr.Spec.CommonSplunkSpec, statefulSet)
+	if err == nil {
+		t.Errorf("applyPvcExpansion() returned nil; want an error for an invalid storage capacity")
+	}
+}
+
+func TestMergePvcExpansion(t *testing.T) {
+	if got := mergePvcExpansion(nil, nil); got != nil {
+		t.Errorf("mergePvcExpansion(nil, nil) = %v; want nil", got)
+	}
+
+	got := mergePvcExpansion(
+		&splcommon.PvcExpansionStatus{TotalClaims: 1, ExpandedClaims: 1},
+		nil,
+		&splcommon.PvcExpansionStatus{TotalClaims: 3, ExpandedClaims: 1, PendingClaims: []string{"a", "b"}, Message: "failed"},
+	)
+	want := &splcommon.PvcExpansionStatus{TotalClaims: 4, ExpandedClaims: 2, PendingClaims: []string{"a", "b"}, Message: "failed"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("mergePvcExpansion() = %v; want %v", got, want)
+	}
+}
+
+func TestRequeueIfExpanding(t *testing.T) {
+	result := reconcile.Result{}
+	requeueIfExpanding(&result, nil, nil)
+	if result.Requeue {
+		t.Errorf("requeueIfExpanding() result = %v; want no requeue", result)
+	}
+
+	requeueIfExpanding(&result, nil, &splcommon.PvcExpansionStatus{Message: "not allowed"})
+	if result.Requeue {
+		t.Errorf("requeueIfExpanding() result = %v; want no requeue for a blocked expansion", result)
+	}
+
+	requeueIfExpanding(&result, nil, &splcommon.PvcExpansionStatus{TotalClaims: 1, PendingClaims: []string{"a"}})
+	if !result.Requeue || result.RequeueAfter != time.Second*5 {
+		t.Errorf("requeueIfExpanding() result = %v; want requeue after 5s", result)
+	}
+}
@@ -0,0 +1,591 @@
+// Copyright (c) 2018-2021 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enterprise
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	splcommon "github.com/splunk/splunk-operator/pkg/splunk/common"
+	splutil "github.com/splunk/splunk-operator/pkg/splunk/util"
+)
+
+const (
+	// storageMigrationMountDir is where the current (src) and new (dst) volumes of a Splunk pod are mounted in the
+	// migration pods copying its data
+	storageMigrationMountDir = "/mnt/storage-migration"
+
+	// storageMigrationDir keeps the scripts sending the data of the ephemeral volumes of a Splunk pod, and the marker
+	// touched before their first copy, to only send the files changed since then once Splunk is stopped
+	storageMigrationDir = "/tmp/splunk-storage-migration"
+
+	// storageMigrationPort is the port of the migration pods receiving the data of ephemeral volumes
+	storageMigrationPort = 9779
+
+	// storageMigrationSenderAnnotation is set on a migration pod once the Splunk pod is sending it its data
+	storageMigrationSenderAnnotation = "enterprise.splunk.com/storage-migration-sender"
+
+	// storageMigrationStepCopying copies the data while Splunk runs
+	storageMigrationStepCopying = "Copying"
+
+	// storageMigrationStepSyncing copies the files changed during the first copy, and removes the files deleted since,
+	// once Splunk is stopped
+	storageMigrationStepSyncing = "Syncing"
+
+	// splunkStopCmd stops Splunk in its pod before its data is synced
+	splunkStopCmd = "/opt/splunk/bin/splunk stop"
+
+	// splunkStartCmd starts Splunk again in the background of its pod, when the sync of its data failed
+	splunkStartCmd = "nohup /opt/splunk/bin/splunk start --accept-license --answer-yes --no-prompt >/dev/null 2>&1 </dev/null &"
+
+	// storageMigrationReceiver receives the data sent by storageMigrationSender on a port, and pipes it to a command.
+	// The sender ends its data with "OK", so that an interrupted transfer fails instead of looking complete.
+	storageMigrationReceiver = `import socket, subprocess, sys
+s = socket.socket()
+s.setsockopt(socket.SOL_SOCKET, socket.SO_REUSEADDR, 1)
+s.bind(("", int(sys.argv[1])))
+s.listen(1)
+s.settimeout(900)
+c, _ = s.accept()
+s.close()
+c.settimeout(900)
+p = subprocess.Popen(sys.argv[2:], stdin=subprocess.PIPE)
+tail = b""
+while True:
+    b = c.recv(1048576)
+    if not b:
+        break
+    b = tail + b
+    p.stdin.write(b[:-2])
+    tail = b[-2:]
+p.stdin.close()
+rc = p.wait()
+c.close()
+if tail != b"OK":
+    sys.exit("the transfer of the data was interrupted")
+sys.exit(rc)
+`
+
+	// storageMigrationSender sends its input to storageMigrationReceiver, waiting for it to listen
+	storageMigrationSender = `import socket, sys, time
+for i in range(60):
+    try:
+        c = socket.create_connection((sys.argv[1], int(sys.argv[2])), 30)
+        break
+    except OSError:
+        time.sleep(5)
+else:
+    sys.exit("unable to connect to the storage migration pod")
+c.settimeout(900)
+while True:
+    b = sys.stdin.buffer.read(1048576)
+    if not b:
+        break
+    c.sendall(b)
+c.shutdown(socket.SHUT_WR)
+c.recv(1)
+`
+)
+
+// storageMigration moves the data of the pods of a StatefulSet to new volumes, when their /opt/splunk/etc or
+// /opt/splunk/var volume moves from ephemeral to persistent storage, or to another StorageClass. Since the volume
+// claim templates of a StatefulSet are immutable, the StatefulSet is recreated with the new volumes while its pods
+// keep running. Before each pod is recycled, its data is copied to its new claims by migration pods, which mount the
+// current claims next to the new ones, or receive the data of the ephemeral volumes over the network. The copy is
+// polled by each reconcile, so that it never blocks the operator, and the pod restarts with all of its data.
+type storageMigration struct {
+	client      splcommon.ControllerClient
+	statefulSet *appsv1.StatefulSet
+
+	// podExec runs a shell script in a pod, with the content of stdin as its input, and returns its output
+	podExec func(c splcommon.ControllerClient, podName string, namespace string, script string, stdin io.Reader) (string, error)
+
+	// status of the pods whose data was being copied during this reconcile, if any
+	status *splcommon.StorageMigrationStatus
+}
+
+// storageMigrationVolume is a volume of a Splunk pod moving to new storage
+type storageMigrationVolume struct {
+	volumeType string
+
+	// new claim of the volume
+	claim corev1.PersistentVolumeClaim
+
+	// claim used by the volume of the running pod, or "" for ephemeral storage
+	sourceClaim string
+}
+
+// newStorageMigration returns a storageMigration for the pods of a desired StatefulSet
+func newStorageMigration(c splcommon.ControllerClient, statefulSet *appsv1.StatefulSet) *storageMigration {
+	return &storageMigration{client: c, statefulSet: statefulSet, podExec: podExecScript}
+}
+
+// apply names the volume claim templates of the desired StatefulSet after those of the current one, or after new
+// claims for the volumes moving to persistent storage or to another StorageClass. In the latter case, the current
+// StatefulSet is deleted while orphaning its pods, and apply returns true until it is gone; the StatefulSet is then
+// recreated with the new volume claim templates by the pod manager.
+func (m *storageMigration) apply() (bool, error) {
+	var current appsv1.StatefulSet
+	namespacedName := types.NamespacedName{Namespace: m.statefulSet.GetNamespace(), Name: m.statefulSet.GetName()}
+	err := m.client.Get(context.TODO(), namespacedName, &current)
+	if err != nil {
+		// no StatefulSet exists yet, or it will be handled by ApplyStatefulSet
+		return false, nil
+	}
+
+	var moved []string
+	for _, volumeType := range []string{splcommon.EtcVolumeStorage, splcommon.VarVolumeStorage} {
+		currentClaim := getVolumeClaimTemplate(&current, volumeType)
+		desiredClaim := getVolumeClaimTemplate(m.statefulSet, volumeType)
+		switch {
+		case desiredClaim == nil && currentClaim != nil:
+			return false, fmt.Errorf("unable to move the %s volume of StatefulSet %s from persistent to ephemeral storage without losing its data", volumeType, current.GetName())
+		case desiredClaim == nil:
+			// ephemeral storage is kept
+		case currentClaim == nil:
+			moved = append(moved, volumeType)
+		case getStorageClassName(currentClaim) == getStorageClassName(desiredClaim):
+			renameVolumeClaimTemplate(m.statefulSet, volumeType, currentClaim.GetName())
+		default:
+			newName := fmt.Sprintf(splcommon.MigratedPvcNamePrefix, volumeType)
+			if currentClaim.GetName() == newName {
+				newName = fmt.Sprintf(splcommon.PvcNamePrefix, volumeType)
+			}
+			renameVolumeClaimTemplate(m.statefulSet, volumeType, newName)
+			moved = append(moved, volumeType)
+		}
+	}
+	if len(moved) == 0 {
+		return false, nil
+	}
+
+	scopedLog := log.WithName("storageMigration").WithValues("name", current.GetName(), "namespace", current.GetNamespace())
+	if current.GetDeletionTimestamp() == nil {
+		scopedLog.Info("Recreating StatefulSet to move volumes to new storage", "volumes", moved)
+		err = m.client.Delete(context.TODO(), &current, client.PropagationPolicy(metav1.DeletePropagationOrphan))
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// migratePod copies the data of the running pod n to its new claims, if it does not use them yet. It returns true
+// once the pod can be recycled to start using them. The data is first copied while Splunk runs; Splunk is then
+// stopped to copy the files changed since, and restarted if that copy fails.
+func (m *storageMigration) migratePod(n int32) (bool, error) {
+	podName := fmt.Sprintf("%s-%d", m.statefulSet.GetName(), n)
+	scopedLog := log.WithName("storageMigration").WithValues("name", podName, "namespace", m.statefulSet.GetNamespace())
+
+	var pod corev1.Pod
+	err := m.client.Get(context.TODO(), types.NamespacedName{Namespace: m.statefulSet.GetNamespace(), Name: podName}, &pod)
+	if k8serrors.IsNotFound(err) {
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	// find the claims the pod does not use yet
+	var volumes []storageMigrationVolume
+	for _, volumeType := range []string{splcommon.EtcVolumeStorage, splcommon.VarVolumeStorage} {
+		template := getVolumeClaimTemplate(m.statefulSet, volumeType)
+		if template == nil {
+			continue
+		}
+		claimName := fmt.Sprintf("%s-%s-%d", template.GetName(), m.statefulSet.GetName(), n)
+		if !isClaimUsedByPod(&pod, claimName) {
+			claim := template.DeepCopy()
+			claim.ObjectMeta = metav1.ObjectMeta{Name: claimName, Namespace: m.statefulSet.GetNamespace(), Labels: template.GetLabels()}
+			volumes = append(volumes, storageMigrationVolume{volumeType: volumeType, claim: *claim, sourceClaim: getPodClaimName(&pod, volumeType)})
+		}
+	}
+	if len(volumes) == 0 {
+		return true, nil
+	}
+
+	// provision the new claims, as the StatefulSet would
+	podStatus := splcommon.StorageMigrationPodStatus{Name: podName}
+	for i := range volumes {
+		claim := &volumes[i].claim
+		var existing corev1.PersistentVolumeClaim
+		err = m.client.Get(context.TODO(), types.NamespacedName{Namespace: claim.GetNamespace(), Name: claim.GetName()}, &existing)
+		if k8serrors.IsNotFound(err) {
+			err = splutil.CreateResource(m.client, claim)
+		} else if err == nil && getStorageClassName(&existing) != getStorageClassName(claim) {
+			err = fmt.Errorf("claim %s already exists with another StorageClass; delete it to move pod %s to new storage", existing.GetName(), podName)
+		}
+		if err != nil {
+			return false, err
+		}
+		podStatus.Volumes = append(podStatus.Volumes, volumes[i].volumeType)
+	}
+
+	// Splunk is stopped once the sync starts: it is recycled once the sync succeeds, or started again if it fails
+	syncPod, err := m.getMigrationPod(podName, storageMigrationStepSyncing)
+	if err != nil {
+		return false, err
+	}
+	if syncPod != nil {
+		done, err := m.checkMigrationPod(&pod, syncPod, volumes, &podStatus)
+		if err != nil {
+			scopedLog.Error(err, "Unable to sync the data to the new volumes, starting Splunk again")
+			if _, startErr := m.podExec(m.client, podName, pod.GetNamespace(), splunkStartCmd, nil); startErr != nil {
+				scopedLog.Error(startErr, "Unable to start Splunk")
+			}
+		}
+		if done {
+			scopedLog.Info("Data copied to the new volumes", "volumes", podStatus.Volumes)
+			err = m.client.Delete(context.TODO(), syncPod)
+			if err == nil || k8serrors.IsNotFound(err) {
+				return true, nil
+			}
+		}
+		m.addPodStatus(podStatus)
+		return false, err
+	}
+
+	copyPod, err := m.getMigrationPod(podName, storageMigrationStepCopying)
+	if err != nil {
+		return false, err
+	}
+	if copyPod == nil {
+		scopedLog.Info("Copying data to the new volumes", "volumes", podStatus.Volumes)
+		copyPod, err = m.createMigrationPod(&pod, storageMigrationStepCopying, volumes)
+		if err != nil {
+			return false, err
+		}
+	}
+	done, err := m.checkMigrationPod(&pod, copyPod, volumes, &podStatus)
+	if done {
+		scopedLog.Info("Stopping Splunk to sync the data changed during the copy")
+		_, err = m.podExec(m.client, podName, pod.GetNamespace(), splunkStopCmd, nil)
+		if err == nil {
+			err = m.client.Delete(context.TODO(), copyPod)
+		}
+		if err == nil || k8serrors.IsNotFound(err) {
+			syncPod, err = m.createMigrationPod(&pod, storageMigrationStepSyncing, volumes)
+		}
+		if err == nil {
+			podStatus.Step = storageMigrationStepSyncing
+			podStatus.StartTime = syncPod.GetCreationTimestamp()
+		}
+	}
+	m.addPodStatus(podStatus)
+	return false, err
+}
+
+// checkMigrationPod reports the progress of a migration pod in the status of the migration of a Splunk pod, and
+// starts sending the data of the ephemeral volumes once the migration pod runs. It returns true once the migration
+// pod succeeded, and deletes it if it failed, so that it is created again.
+func (m *storageMigration) checkMigrationPod(pod *corev1.Pod, migrationPod *corev1.Pod, volumes []storageMigrationVolume, podStatus *splcommon.StorageMigrationPodStatus) (bool, error) {
+	step := migrationPod.GetLabels()["step"]
+	podStatus.Step = step
+	podStatus.StartTime = migrationPod.GetCreationTimestamp()
+
+	switch migrationPod.Status.Phase {
+	case corev1.PodSucceeded:
+		return true, nil
+	case corev1.PodFailed:
+		podStatus.Message = getMigrationPodFailure(migrationPod)
+		err := m.client.Delete(context.TODO(), migrationPod)
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return false, err
+		}
+		return false, fmt.Errorf("unable to copy the data of pod %s to its new volumes: %s", pod.GetName(), podStatus.Message)
+	case corev1.PodRunning:
+		ephemeral := getEphemeralVolumeTypes(volumes)
+		if len(ephemeral) == 0 || migrationPod.Status.PodIP == "" || migrationPod.GetAnnotations()[storageMigrationSenderAnnotation] != "" {
+			return false, nil
+		}
+		script := getStorageMigrationSenderScript(step, ephemeral, migrationPod.Status.PodIP)
+		_, err := m.podExec(m.client, pod.GetName(), pod.GetNamespace(), script, nil)
+		if err != nil {
+			return false, err
+		}
+		if migrationPod.Annotations == nil {
+			migrationPod.Annotations = make(map[string]string)
+		}
+		migrationPod.Annotations[storageMigrationSenderAnnotation] = pod.GetName()
+		return false, m.client.Update(context.TODO(), migrationPod)
+	}
+	return false, nil
+}
+
+// getMigrationPod returns the migration pod running a step of the copy of the data of a Splunk pod, or nil
+func (m *storageMigration) getMigrationPod(podName string, step string) (*corev1.Pod, error) {
+	var migrationPod corev1.Pod
+	namespacedName := types.NamespacedName{Namespace: m.statefulSet.GetNamespace(), Name: getMigrationPodName(podName, step)}
+	err := m.client.Get(context.TODO(), namespacedName, &migrationPod)
+	if k8serrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return &migrationPod, nil
+}
+
+// createMigrationPod creates the pod running a step of the copy of the data of a Splunk pod. It mounts the new
+// claims, and the current claims on the node of the Splunk pod, or else receives the data of its ephemeral volumes.
+func (m *storageMigration) createMigrationPod(pod *corev1.Pod, step string, volumes []storageMigrationVolume) (*corev1.Pod, error) {
+	migrationPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            getMigrationPodName(pod.GetName(), step),
+			Namespace:       pod.GetNamespace(),
+			Labels:          map[string]string{"step": step},
+			OwnerReferences: m.statefulSet.GetOwnerReferences(),
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{
+					Name:                     "storage-migration",
+					Image:                    pod.Spec.Containers[0].Image,
+					Command:                  []string{"/bin/sh", "-c", getStorageMigrationScript(step, volumes)},
+					TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
+				},
+			},
+			SecurityContext:  pod.Spec.SecurityContext,
+			ImagePullSecrets: pod.Spec.ImagePullSecrets,
+			RestartPolicy:    corev1.RestartPolicyNever,
+		},
+	}
+	addClaim := func(name string, claimName string, mountPath string, readOnly bool) {
+		migrationPod.Spec.Volumes = append(migrationPod.Spec.Volumes, corev1.Volume{
+			Name: name,
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: claimName, ReadOnly: readOnly},
+			},
+		})
+		migrationPod.Spec.Containers[0].VolumeMounts = append(migrationPod.Spec.Containers[0].VolumeMounts, corev1.VolumeMount{
+			Name:      name,
+			MountPath: mountPath,
+			ReadOnly:  readOnly,
+		})
+	}
+	for _, volume := range volumes {
+		addClaim("dst-"+volume.volumeType, volume.claim.GetName(), fmt.Sprintf("%s/dst/%s", storageMigrationMountDir, volume.volumeType), false)
+		if volume.sourceClaim != "" {
+			// the claims of the running pod can only be mounted on its node
+			addClaim("src-"+volume.volumeType, volume.sourceClaim, fmt.Sprintf("%s/src/%s", storageMigrationMountDir, volume.volumeType), true)
+			migrationPod.Spec.NodeName = pod.Spec.NodeName
+		}
+	}
+	if len(getEphemeralVolumeTypes(volumes)) > 0 {
+		migrationPod.Spec.Containers[0].Ports = []corev1.ContainerPort{{Name: "migration", ContainerPort: storageMigrationPort, Protocol: corev1.ProtocolTCP}}
+	}
+	return migrationPod, splutil.CreateResource(m.client, migrationPod)
+}
+
+// addPodStatus adds the status of the migration of a Splunk pod to the status of the migration
+func (m *storageMigration) addPodStatus(podStatus splcommon.StorageMigrationPodStatus) {
+	if m.status == nil {
+		m.status = &splcommon.StorageMigrationStatus{}
+	}
+	m.status.Pods = append(m.status.Pods, podStatus)
+}
+
+// mergeStorageMigration merges the status of the migrations of several StatefulSets of a custom resource
+func mergeStorageMigration(migrations ...*storageMigration) *splcommon.StorageMigrationStatus {
+	var merged *splcommon.StorageMigrationStatus
+	for _, migration := range migrations {
+		if migration.status == nil {
+			continue
+		}
+		if merged == nil {
+			merged = &splcommon.StorageMigrationStatus{}
+		}
+		merged.Pods = append(merged.Pods, migration.status.Pods...)
+	}
+	return merged
+}
+
+// getStorageMigrationScript returns the script of a migration pod: it copies the data of the current claims, and
+// receives the data of the ephemeral volumes. The sync only copies the files changed since the copy, and removes
+// the files deleted since, except the lost+found directories of the new volumes.
+func getStorageMigrationScript(step string, volumes []storageMigrationVolume) string {
+	dst := storageMigrationMountDir + "/dst"
+	src := storageMigrationMountDir + "/src"
+	var script strings.Builder
+	script.WriteString("set -e\n")
+	ephemeral := getEphemeralVolumeTypes(volumes)
+	receive := fmt.Sprintf("python3 /tmp/receive.py %d", storageMigrationPort)
+	if len(ephemeral) > 0 {
+		fmt.Fprintf(&script, "cat > /tmp/receive.py <<'EOF'\n%sEOF\n", storageMigrationReceiver)
+	}
+
+	copyFlags := "-a"
+	if step == storageMigrationStepSyncing {
+		copyFlags = "-a -u"
+	}
+	var volumeTypes []string
+	for _, volume := range volumes {
+		volumeTypes = append(volumeTypes, volume.volumeType)
+		if volume.sourceClaim != "" {
+			fmt.Fprintf(&script, "cp %s %s/%s/. %s/%s/\n", copyFlags, src, volume.volumeType, dst, volume.volumeType)
+		}
+	}
+	if len(ephemeral) > 0 {
+		fmt.Fprintf(&script, "%s tar -xpf - -C %s\n", receive, dst)
+	}
+	if step != storageMigrationStepSyncing {
+		return script.String()
+	}
+
+	script.WriteString(": > /tmp/src-files\n")
+	for _, volume := range volumes {
+		if volume.sourceClaim != "" {
+			fmt.Fprintf(&script, "(cd %s && find %s) >> /tmp/src-files\n", src, volume.volumeType)
+		}
+	}
+	if len(ephemeral) > 0 {
+		fmt.Fprintf(&script, "%s sh -c 'cat >> /tmp/src-files'\n", receive)
+	}
+	fmt.Fprintf(&script, "sort -o /tmp/src-files /tmp/src-files\n")
+	fmt.Fprintf(&script, "cd %s && find %s | sort | comm -13 /tmp/src-files - | grep -v '^[^/]*/lost+found' | while read -r f; do rm -rf \"$f\"; done\n", dst, strings.Join(volumeTypes, " "))
+	return script.String()
+}
+
+// getStorageMigrationSenderScript returns the script starting to send the data of the ephemeral volumes of a Splunk
+// pod to a migration pod, in the background of the Splunk pod. The sync sends the files changed since the copy, then
+// the list of all the files, to remove the files deleted since.
+func getStorageMigrationSenderScript(step string, volumeTypes []string, ip string) string {
+	dirs := strings.Join(volumeTypes, " ")
+	send := fmt.Sprintf("python3 %s/send.py %s %d", storageMigrationDir, ip, storageMigrationPort)
+	var sendScript string
+	if step == storageMigrationStepSyncing {
+		sendScript = fmt.Sprintf("{ find %[1]s -newer %[2]s/marker | tar --no-recursion -cf - -T - && printf OK; } | %[3]s\n{ find %[1]s && printf OK; } | %[3]s\n", dirs, storageMigrationDir, send)
+	} else {
+		sendScript = fmt.Sprintf("touch %s/marker\n{ tar -cf - %s && printf OK; } | %s\n", storageMigrationDir, dirs, send)
+	}
+	return fmt.Sprintf(`set -e
+mkdir -p '%[1]s'
+cat > '%[1]s/send.py' <<'EOF'
+%[2]sEOF
+cat > '%[1]s/send.sh' <<'EOF'
+set -e
+cd /opt/splunk
+%[3]sEOF
+nohup sh '%[1]s/send.sh' > '%[1]s/send.log' 2>&1 </dev/null &
+`, storageMigrationDir, storageMigrationSender, sendScript)
+}
+
+// getMigrationPodName returns the name of the migration pod running a step of the copy of the data of a Splunk pod
+func getMigrationPodName(podName string, step string) string {
+	return fmt.Sprintf("%s-storage-%s", podName, strings.ToLower(step))
+}
+
+// getMigrationPodFailure returns the reason why a migration pod failed, from the end of its logs
+func getMigrationPodFailure(migrationPod *corev1.Pod) string {
+	for _, status := range migrationPod.Status.ContainerStatuses {
+		if status.State.Terminated != nil && status.State.Terminated.Message != "" {
+			return strings.TrimSpace(status.State.Terminated.Message)
+		}
+	}
+	if migrationPod.Status.Message != "" {
+		return migrationPod.Status.Message
+	}
+	return fmt.Sprintf("pod %s failed", migrationPod.GetName())
+}
+
+// getEphemeralVolumeTypes returns the volumes moving from ephemeral storage, whose data is sent over the network
+func getEphemeralVolumeTypes(volumes []storageMigrationVolume) []string {
+	var volumeTypes []string
+	for _, volume := range volumes {
+		if volume.sourceClaim == "" {
+			volumeTypes = append(volumeTypes, volume.volumeType)
+		}
+	}
+	return volumeTypes
+}
+
+// getPodClaimName returns the claim mounted at /opt/splunk/etc or /opt/splunk/var by the Splunk container of a pod,
+// or "" if the volume uses ephemeral storage
+func getPodClaimName(pod *corev1.Pod, volumeType string) string {
+	if len(pod.Spec.Containers) == 0 {
+		return ""
+	}
+	mountPath := fmt.Sprintf(splcommon.SplunkMountDirecPrefix, volumeType)
+	for _, volumeMount := range pod.Spec.Containers[0].VolumeMounts {
+		if volumeMount.MountPath != mountPath {
+			continue
+		}
+		for _, volume := range pod.Spec.Volumes {
+			if volume.Name == volumeMount.Name && volume.PersistentVolumeClaim != nil {
+				return volume.PersistentVolumeClaim.ClaimName
+			}
+		}
+	}
+	return ""
+}
+
+// getVolumeClaimTemplate returns the volume claim template mounted at /opt/splunk/etc or /opt/splunk/var by the Splunk
+// container of a StatefulSet, or nil if the volume uses ephemeral storage
+func getVolumeClaimTemplate(statefulSet *appsv1.StatefulSet, volumeType string) *corev1.PersistentVolumeClaim {
+	if len(statefulSet.Spec.Template.Spec.Containers) == 0 {
+		return nil
+	}
+	mountPath := fmt.Sprintf(splcommon.SplunkMountDirecPrefix, volumeType)
+	for _, volumeMount := range statefulSet.Spec.Template.Spec.Containers[0].VolumeMounts {
+		if volumeMount.MountPath != mountPath {
+			continue
+		}
+		for i := range statefulSet.Spec.VolumeClaimTemplates {
+			if statefulSet.Spec.VolumeClaimTemplates[i].GetName() == volumeMount.Name {
+				return &statefulSet.Spec.VolumeClaimTemplates[i]
+			}
+		}
+	}
+	return nil
+}
+
+// renameVolumeClaimTemplate renames the volume claim template of a volume of a StatefulSet, and its volume mount
+func renameVolumeClaimTemplate(statefulSet *appsv1.StatefulSet, volumeType string, name string) {
+	template := getVolumeClaimTemplate(statefulSet, volumeType)
+	if template == nil || template.GetName() == name {
+		return
+	}
+	for i := range statefulSet.Spec.Template.Spec.Containers[0].VolumeMounts {
+		if statefulSet.Spec.Template.Spec.Containers[0].VolumeMounts[i].Name == template.GetName() {
+			statefulSet.Spec.Template.Spec.Containers[0].VolumeMounts[i].Name = name
+		}
+	}
+	template.SetName(name)
+}
+
+// getStorageClassName returns the StorageClass of a claim, or "" for the default one
+func getStorageClassName(claim *corev1.PersistentVolumeClaim) string {
+	if claim.Spec.StorageClassName == nil {
+		return ""
+	}
+	return *claim.Spec.StorageClassName
+}
+
+// isClaimUsedByPod returns true if a pod has a volume for a claim
+func isClaimUsedByPod(pod *corev1.Pod, claimName string) bool {
+	for _, volume := range pod.Spec.Volumes {
+		if volume.PersistentVolumeClaim != nil && volume.PersistentVolumeClaim.ClaimName == claimName {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,268 @@
+// Copyright (c) 2018-2021 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enterprise
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	enterpriseApi "github.com/splunk/splunk-operator/pkg/apis/enterprise/v2"
+	splcommon "github.com/splunk/splunk-operator/pkg/splunk/common"
+	spltest "github.com/splunk/splunk-operator/pkg/splunk/test"
+)
+
+// newTestStorageStatefulSet returns the StatefulSet of a standalone using some storage configuration
+func newTestStorageStatefulSet(t *testing.T, etc, vars enterpriseApi.StorageClassSpec) *appsv1.StatefulSet {
+	cr := enterpriseApi.Standalone{ObjectMeta: metav1.ObjectMeta{Name: "stack1", Namespace: "test"}}
+	cr.Spec.EtcVolumeStorageConfig = etc
+	cr.Spec.VarVolumeStorageConfig = vars
+	replicas := int32(1)
+	statefulSet := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "splunk-stack1-standalone", Namespace: "test"},
+		Spec: appsv1.StatefulSetSpec{
+			Replicas: &replicas,
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "splunk", Image: "splunk/splunk"}}},
+			},
+		},
+	}
+	err := addStorageVolumes(&cr, &cr.Spec.CommonSplunkSpec, statefulSet, map[string]string{"app": "splunk"})
+	if err != nil {
+		t.Fatalf("addStorageVolumes() returned %v", err)
+	}
+	return statefulSet
+}
+
+func TestStorageMigrationApply(t *testing.T) {
+	ephemeral := enterpriseApi.StorageClassSpec{EphemeralStorage: true}
+	gp2 := enterpriseApi.StorageClassSpec{StorageClassName: "gp2"}
+	io1 := enterpriseApi.StorageClassSpec{StorageClassName: "io1"}
+
+	test := func(current, desired *appsv1.StatefulSet, wantRecreating bool, wantEtc, wantVar string, wantErr bool) {
+		c := spltest.NewMockClient()
+		if current != nil {
+			c.AddObject(current)
+		}
+		recreating, err := newStorageMigration(c, desired).apply()
+		if (err != nil) != wantErr || recreating != wantRecreating {
+			t.Errorf("apply() = %t, %v; want %t, error %t", recreating, err, wantRecreating, wantErr)
+		}
+		if wantRecreating != (len(c.Calls["Delete"]) == 1) {
+			t.Errorf("apply() deleted %d StatefulSets; want recreating %t", len(c.Calls["Delete"]), wantRecreating)
+		}
+		for volumeType, want := range map[string]string{splcommon.EtcVolumeStorage: wantEtc, splcommon.VarVolumeStorage: wantVar} {
+			got := ""
+			if template := getVolumeClaimTemplate(desired, volumeType); template != nil {
+				got = template.GetName()
+			}
+			if got != want {
+				t.Errorf("apply() %s claim template = %s; want %s", volumeType, got, want)
+			}
+		}
+	}
+
+	// no StatefulSet yet
+	test(nil, newTestStorageStatefulSet(t, gp2, gp2), false, "pvc-etc", "pvc-var", false)
+
+	// unchanged storage
+	test(newTestStorageStatefulSet(t, gp2, ephemeral), newTestStorageStatefulSet(t, gp2, ephemeral), false, "pvc-etc", "", false)
+
+	// ephemeral to persistent storage
+	test(newTestStorageStatefulSet(t, gp2, ephemeral), newTestStorageStatefulSet(t, gp2, io1), true, "pvc-etc", "pvc-var", false)
+
+	// another StorageClass, and back again
+	test(newTestStorageStatefulSet(t, gp2, gp2), newTestStorageStatefulSet(t, gp2, io1), true, "pvc-etc", "pvc-var-migrated", false)
+	migrated := newTestStorageStatefulSet(t, gp2, io1)
+	renameVolumeClaimTemplate(migrated, splcommon.VarVolumeStorage, "pvc-var-migrated")
+	test(migrated, newTestStorageStatefulSet(t, gp2, io1), false, "pvc-etc", "pvc-var-migrated", false)
+	test(migrated, newTestStorageStatefulSet(t, gp2, gp2), true, "pvc-etc", "pvc-var", false)
+
+	// persistent to ephemeral storage loses the data
+	test(newTestStorageStatefulSet(t, gp2, gp2), newTestStorageStatefulSet(t, ephemeral, gp2), false, "", "pvc-var", true)
+}
+
+func TestStorageMigrationMigratePod(t *testing.T) {
+	c := spltest.NewMockClient()
+	c.NotFoundError = k8serrors.NewNotFound(schema.GroupResource{}, "")
+	statefulSet := newTestStorageStatefulSet(t, enterpriseApi.StorageClassSpec{EphemeralStorage: true}, enterpriseApi.StorageClassSpec{StorageClassName: "gp2"})
+	var scripts []string
+	migration := newStorageMigration(c, statefulSet)
+	migration.podExec = func(c splcommon.ControllerClient, podName string, namespace string, script string, stdin io.Reader) (string, error) {
+		scripts = append(scripts, podName+": "+script)
+		return "", nil
+	}
+	migratePod := func(wantReady bool, wantErr bool, wantStep string) {
+		migration.status = nil
+		ready, err := migration.migratePod(0)
+		if ready != wantReady || (err != nil) != wantErr {
+			t.Errorf("migratePod() = %t, %v; want %t, error %t", ready, err, wantReady, wantErr)
+		}
+		status := mergeStorageMigration(migration)
+		if wantStep == "" && status != nil {
+			t.Errorf("migratePod() status = %v; want none", status)
+		} else if wantStep != "" && (status == nil || len(status.Pods) != 1 || status.Pods[0].Step != wantStep) {
+			t.Errorf("migratePod() status = %v; want step %s", status, wantStep)
+		}
+	}
+	getMigrationPod := func(step string) *corev1.Pod {
+		migrationPod, err := migration.getMigrationPod("splunk-stack1-standalone-0", step)
+		if err != nil || migrationPod == nil {
+			t.Fatalf("getMigrationPod(%s) = %v, %v; want a pod", step, migrationPod, err)
+		}
+		return migrationPod
+	}
+
+	// the pod does not exist anymore
+	migratePod(true, false, "")
+
+	// the var volume moves from ephemeral storage
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "splunk-stack1-standalone-0", Namespace: "test"},
+		Spec: corev1.PodSpec{
+			NodeName: "node1",
+			Containers: []corev1.Container{
+				{
+					Name:  "splunk",
+					Image: "splunk/splunk:8.2.1",
+					VolumeMounts: []corev1.VolumeMount{
+						{Name: "mnt-splunk-etc", MountPath: "/opt/splunk/etc"},
+						{Name: "mnt-splunk-var", MountPath: "/opt/splunk/var"},
+					},
+				},
+			},
+			Volumes: []corev1.Volume{
+				{Name: "mnt-splunk-etc", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}},
+				{Name: "mnt-splunk-var", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}},
+			},
+		},
+	}
+	c.AddObject(pod)
+	migratePod(false, false, storageMigrationStepCopying)
+	if len(c.Calls["Create"]) != 2 {
+		t.Fatalf("migratePod() created %d resources; want a claim and a pod", len(c.Calls["Create"]))
+	}
+	claim := c.Calls["Create"][0].Obj.(*corev1.PersistentVolumeClaim)
+	if claim.GetName() != "pvc-var-splunk-stack1-standalone-0" || getStorageClassName(claim) != "gp2" {
+		t.Errorf("migratePod() created claim %s of class %s; want pvc-var-splunk-stack1-standalone-0 of class gp2", claim.GetName(), getStorageClassName(claim))
+	}
+	copyPod := getMigrationPod(storageMigrationStepCopying)
+	if copyPod.GetName() != "splunk-stack1-standalone-0-storage-copying" || copyPod.Spec.Containers[0].Image != "splunk/splunk:8.2.1" ||
+		len(copyPod.Spec.Volumes) != 1 || copyPod.Spec.Volumes[0].PersistentVolumeClaim.ClaimName != claim.GetName() ||
+		copyPod.Spec.Containers[0].VolumeMounts[0].MountPath != "/mnt/storage-migration/dst/var" || len(copyPod.Spec.Containers[0].Ports) != 1 {
+		t.Errorf("migratePod() created pod %v; want a pod mounting %s and receiving the data", copyPod, claim.GetName())
+	}
+	if !strings.Contains(copyPod.Spec.Containers[0].Command[2], "tar -xpf - -C /mnt/storage-migration/dst") {
+		t.Errorf("migratePod() created pod running %s; want it to receive the data", copyPod.Spec.Containers[0].Command[2])
+	}
+	if len(scripts) != 0 {
+		t.Errorf("migratePod() ran %v; want nothing before the migration pod runs", scripts)
+	}
+
+	// the data is sent once the migration pod runs, only once
+	copyPod.Status.Phase = corev1.PodRunning
+	copyPod.Status.PodIP = "10.0.0.1"
+	c.AddObject(copyPod)
+	migratePod(false, false, storageMigrationStepCopying)
+	migratePod(false, false, storageMigrationStepCopying)
+	if len(scripts) != 1 || !strings.Contains(scripts[0], "tar -cf - var") || !strings.Contains(scripts[0], "10.0.0.1") || !strings.Contains(scripts[0], "nohup") {
+		t.Errorf("migratePod() ran %v; want to send the data in the background once", scripts)
+	}
+
+	// Splunk is stopped to sync the changes once the copy succeeded
+	copyPod = getMigrationPod(storageMigrationStepCopying)
+	copyPod.Status.Phase = corev1.PodSucceeded
+	c.AddObject(copyPod)
+	scripts = nil
+	c.ResetCalls()
+	migratePod(false, false, storageMigrationStepSyncing)
+	if len(scripts) != 1 || !strings.Contains(scripts[0], "splunk stop") {
+		t.Errorf("migratePod() ran %v; want to stop Splunk", scripts)
+	}
+	if len(c.Calls["Delete"]) != 1 || c.Calls["Delete"][0].Obj.(*corev1.Pod).GetName() != copyPod.GetName() {
+		t.Errorf("migratePod() deleted %v; want the copy pod", c.Calls["Delete"])
+	}
+	syncPod := getMigrationPod(storageMigrationStepSyncing)
+	if !strings.Contains(syncPod.Spec.Containers[0].Command[2], "rm -rf") {
+		t.Errorf("migratePod() created pod running %s; want it to remove the deleted files", syncPod.Spec.Containers[0].Command[2])
+	}
+
+	// Splunk is started again if the sync fails, and the copy starts over
+	syncPod.Status.Phase = corev1.PodFailed
+	syncPod.Status.ContainerStatuses = []corev1.ContainerStatus{{State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Message: "the transfer of the data was interrupted"}}}}
+	c.AddObject(syncPod)
+	scripts = nil
+	migratePod(false, true, storageMigrationStepSyncing)
+	if migration.status.Pods[0].Message != "the transfer of the data was interrupted" {
+		t.Errorf("migratePod() status message = %s; want the failure of the sync pod", migration.status.Pods[0].Message)
+	}
+	if len(scripts) != 1 || !strings.Contains(scripts[0], "splunk start") {
+		t.Errorf("migratePod() ran %v; want to start Splunk", scripts)
+	}
+	migratePod(false, false, storageMigrationStepCopying)
+
+	// the pod is recycled once the sync succeeded
+	copyPod = getMigrationPod(storageMigrationStepCopying)
+	copyPod.Status.Phase = corev1.PodSucceeded
+	c.AddObject(copyPod)
+	migratePod(false, false, storageMigrationStepSyncing)
+	syncPod = getMigrationPod(storageMigrationStepSyncing)
+	syncPod.Status.Phase = corev1.PodSucceeded
+	c.AddObject(syncPod)
+	c.ResetCalls()
+	migratePod(true, false, "")
+	if len(c.Calls["Delete"]) != 1 {
+		t.Errorf("migratePod() deleted %d resources; want the sync pod", len(c.Calls["Delete"]))
+	}
+
+	// the recycled pod uses its new claim
+	pod.Spec.Volumes[1].VolumeSource = corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: claim.GetName()}}
+	c.AddObject(pod)
+	c.ResetCalls()
+	migratePod(true, false, "")
+	if len(c.Calls["Create"]) != 0 {
+		t.Errorf("migratePod() created %d resources; want none", len(c.Calls["Create"]))
+	}
+
+	// the current claim is mounted next to the claim of another StorageClass, on the node of the pod
+	renameVolumeClaimTemplate(statefulSet, splcommon.VarVolumeStorage, "pvc-var-migrated")
+	migratePod(false, false, storageMigrationStepCopying)
+	copyPod = getMigrationPod(storageMigrationStepCopying)
+	if len(copyPod.Spec.Volumes) != 2 || copyPod.Spec.Volumes[1].PersistentVolumeClaim.ClaimName != claim.GetName() ||
+		!copyPod.Spec.Containers[0].VolumeMounts[1].ReadOnly || copyPod.Spec.NodeName != "node1" || len(copyPod.Spec.Containers[0].Ports) != 0 {
+		t.Errorf("migratePod() created pod %v; want a pod mounting %s on node1", copyPod, claim.GetName())
+	}
+	if !strings.Contains(copyPod.Spec.Containers[0].Command[2], "cp -a /mnt/storage-migration/src/var/. /mnt/storage-migration/dst/var/") {
+		t.Errorf("migratePod() created pod running %s; want it to copy the current claim", copyPod.Spec.Containers[0].Command[2])
+	}
+	renameVolumeClaimTemplate(statefulSet, splcommon.VarVolumeStorage, "pvc-var")
+
+	// an existing claim of another StorageClass is not reused
+	pod.Spec.Volumes[1].VolumeSource = corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}
+	c.AddObject(pod)
+	standard := "standard"
+	claim.Spec.StorageClassName = &standard
+	c.AddObject(claim)
+	_, err := migration.migratePod(0)
+	if err == nil {
+		t.Errorf("migratePod() returned nil; want an error for a claim of another StorageClass")
+	}
+}
@@ -0,0 +1,290 @@
+// Copyright (c) 2018-2021 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enterprise
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	enterpriseApi "github.com/splunk/splunk-operator/pkg/apis/enterprise/v2"
+	splcommon "github.com/splunk/splunk-operator/pkg/splunk/common"
+)
+
+// upgradeGate holds off the recycling of the pods of a custom resource while the custom resources it depends on
+// are being upgraded to the same image, so that a whole deployment is upgraded in the order supported by Splunk:
+// license manager, cluster manager, search head cluster deployer and members, indexer cluster, monitoring console.
+type upgradeGate struct {
+	client splcommon.ControllerClient
+	cr     splcommon.MetaObject
+	image  string
+
+	// upstreams returns the custom resources to upgrade first
+	upstreams func() ([]splcommon.MetaObject, error)
+
+	// statefulSets of the custom resource itself to upgrade first
+	statefulSets []string
+
+	checked bool
+	blocker string
+}
+
+// newUpgradeGate returns an upgradeGate for the pods of a custom resource, depending on the custom resources
+// referenced by its spec
+func newUpgradeGate(c splcommon.ControllerClient, cr splcommon.MetaObject, spec *enterpriseApi.CommonSplunkSpec) *upgradeGate {
+	gate := &upgradeGate{client: c, cr: cr, image: GetSplunkImage(spec.Image)}
+	gate.upstreams = func() ([]splcommon.MetaObject, error) {
+		return getUpgradeUpstreams(c, cr, spec)
+	}
+	return gate
+}
+
+// newMonitoringConsoleUpgradeGate returns an upgradeGate for the monitoring console pods, depending on all the other
+// custom resources of the namespace of a custom resource, which is known to be ready
+func newMonitoringConsoleUpgradeGate(c splcommon.ControllerClient, cr splcommon.MetaObject, spec *enterpriseApi.CommonSplunkSpec) *upgradeGate {
+	gate := &upgradeGate{client: c, cr: cr, image: GetSplunkImage(spec.Image)}
+	gate.upstreams = func() ([]splcommon.MetaObject, error) {
+		return getMonitoringConsoleUpstreams(c, cr)
+	}
+	return gate
+}
+
+// waitFor makes the gate also hold off updates until a StatefulSet of the same custom resource is upgraded
+func (g *upgradeGate) waitFor(statefulSetName string) *upgradeGate {
+	g.statefulSets = append(g.statefulSets, statefulSetName)
+	return g
+}
+
+// hold returns true while the pods must not be recycled. The upstreams are only looked at once per reconcile.
+func (g *upgradeGate) hold() bool {
+	if g.checked {
+		return g.blocker != ""
+	}
+	g.checked = true
+
+	scopedLog := log.WithName("upgradeGate").WithValues("name", g.cr.GetName(), "namespace", g.cr.GetNamespace(), "image", g.image)
+	for _, name := range g.statefulSets {
+		upgraded, err := isStatefulSetUpgraded(g.client, g.cr.GetNamespace(), name, g.image)
+		if err != nil || !upgraded {
+			g.blocker = fmt.Sprintf("StatefulSet/%s", name)
+			scopedLog.Info("Holding off pod updates until upstream is upgraded", "upstream", g.blocker, "error", err)
+			return true
+		}
+	}
+
+	upstreams, err := g.upstreams()
+	if err != nil {
+		g.blocker = "unknown"
+		scopedLog.Error(err, "Unable to find the custom resources to upgrade first")
+		return true
+	}
+	for _, upstream := range upstreams {
+		upgraded, err := isUpgraded(g.client, upstream, g.image)
+		if err != nil || !upgraded {
+			g.blocker = fmt.Sprintf("%s/%s", getKind(upstream), upstream.GetName())
+			scopedLog.Info("Holding off pod updates until upstream is upgraded", "upstream", g.blocker, "error", err)
+			return true
+		}
+	}
+	return false
+}
+
+// requeueIfHeld makes the reconcile requeue while updates are held off, since nothing else would trigger it once
+// the upstreams are upgraded; it returns true if updates were held off
+func (g *upgradeGate) requeueIfHeld(result *reconcile.Result, eventPublisher *eventPublisher) bool {
+	if g.blocker == "" {
+		return false
+	}
+	eventPublisher.Normal(eventReasonUpgradeHeld, "Waiting for %s to be upgraded to %s", g.blocker, g.image)
+	result.Requeue = true
+	if result.RequeueAfter == 0 {
+		result.RequeueAfter = time.Second * 5
+	}
+	return true
+}
+
+// getUpgradeUpstreams returns the custom resources of the same namespace that must be upgraded before a custom resource
+func getUpgradeUpstreams(c splcommon.ControllerClient, cr splcommon.MetaObject, spec *enterpriseApi.CommonSplunkSpec) ([]splcommon.MetaObject, error) {
+	var upstreams []splcommon.MetaObject
+	getRef := func(name string, upstream splcommon.MetaObject) error {
+		if name == "" {
+			return nil
+		}
+		err := c.Get(context.TODO(), types.NamespacedName{Namespace: cr.GetNamespace(), Name: name}, upstream)
+		if k8serrors.IsNotFound(err) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		upstreams = append(upstreams, upstream)
+		return nil
+	}
+
+	switch cr.(type) {
+	case *enterpriseApi.LicenseMaster:
+		return nil, nil
+	case *enterpriseApi.ClusterMaster:
+		err := getRef(spec.LicenseMasterRef.Name, &enterpriseApi.LicenseMaster{})
+		return upstreams, err
+	}
+
+	err := getRef(spec.LicenseMasterRef.Name, &enterpriseApi.LicenseMaster{})
+	if err != nil {
+		return nil, err
+	}
+	err = getRef(spec.ClusterMasterRef.Name, &enterpriseApi.ClusterMaster{})
+	if err != nil {
+		return nil, err
+	}
+
+	// the search heads of an indexer cluster are upgraded before its peers
+	if _, ok := cr.(*enterpriseApi.IndexerCluster); ok {
+		shcList := enterpriseApi.SearchHeadClusterList{}
+		err = c.List(context.TODO(), &shcList, client.InNamespace(cr.GetNamespace()))
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return nil, err
+		}
+		for i := range shcList.Items {
+			if shcList.Items[i].Spec.ClusterMasterRef.Name == spec.ClusterMasterRef.Name {
+				upstreams = append(upstreams, &shcList.Items[i])
+			}
+		}
+	}
+	return upstreams, nil
+}
+
+// getMonitoringConsoleUpstreams returns all the other custom resources of a namespace, since the monitoring console
+// is upgraded last
+func getMonitoringConsoleUpstreams(c splcommon.ControllerClient, cr splcommon.MetaObject) ([]splcommon.MetaObject, error) {
+	var upstreams []splcommon.MetaObject
+	lists := []runtime.Object{
+		&enterpriseApi.LicenseMasterList{},
+		&enterpriseApi.ClusterMasterList{},
+		&enterpriseApi.SearchHeadClusterList{},
+		&enterpriseApi.IndexerClusterList{},
+		&enterpriseApi.StandaloneList{},
+	}
+	for _, list := range lists {
+		err := c.List(context.TODO(), list, client.InNamespace(cr.GetNamespace()))
+		if k8serrors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			upstream, ok := item.(splcommon.MetaObject)
+			if ok && (getKind(upstream) != getKind(cr) || upstream.GetName() != cr.GetName()) {
+				upstreams = append(upstreams, upstream)
+			}
+		}
+	}
+	return upstreams, nil
+}
+
+// isUpgraded returns true unless a custom resource uses an image and has not completed its upgrade to it yet.
+// Custom resources using another image are not upgraded along with the custom resources depending on them.
+func isUpgraded(c splcommon.ControllerClient, cr splcommon.MetaObject, image string) (bool, error) {
+	var spec *enterpriseApi.CommonSplunkSpec
+	var ready bool
+	var statefulSets []string
+	switch upstream := cr.(type) {
+	case *enterpriseApi.LicenseMaster:
+		spec = &upstream.Spec.CommonSplunkSpec
+		ready = upstream.Status.Phase == splcommon.PhaseReady
+		statefulSets = []string{GetSplunkStatefulsetName(SplunkLicenseMaster, upstream.GetName())}
+	case *enterpriseApi.ClusterMaster:
+		spec = &upstream.Spec.CommonSplunkSpec
+		ready = upstream.Status.Phase == splcommon.PhaseReady
+		statefulSets = []string{GetSplunkStatefulsetName(SplunkClusterMaster, upstream.GetName())}
+	case *enterpriseApi.SearchHeadCluster:
+		spec = &upstream.Spec.CommonSplunkSpec
+		ready = upstream.Status.Phase == splcommon.PhaseReady && upstream.Status.DeployerPhase == splcommon.PhaseReady
+		statefulSets = []string{GetSplunkStatefulsetName(SplunkDeployer, upstream.GetName()), GetSplunkStatefulsetName(SplunkSearchHead, upstream.GetName())}
+	case *enterpriseApi.IndexerCluster:
+		spec = &upstream.Spec.CommonSplunkSpec
+		ready = upstream.Status.Phase == splcommon.PhaseReady
+		statefulSets = []string{GetSplunkStatefulsetName(SplunkIndexer, upstream.GetName())}
+	case *enterpriseApi.Standalone:
+		spec = &upstream.Spec.CommonSplunkSpec
+		ready = upstream.Status.Phase == splcommon.PhaseReady
+		statefulSets = []string{GetSplunkStatefulsetName(SplunkStandalone, upstream.GetName())}
+	default:
+		return true, nil
+	}
+
+	if GetSplunkImage(spec.Image) != image {
+		return true, nil
+	}
+	if !ready {
+		return false, nil
+	}
+	for _, name := range statefulSets {
+		upgraded, err := isStatefulSetUpgraded(c, cr.GetNamespace(), name, image)
+		if err != nil || !upgraded {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// isStatefulSetUpgraded returns true if all the pods of a StatefulSet run its latest revision using an image, and are ready
+func isStatefulSetUpgraded(c splcommon.ControllerClient, namespace, name, image string) (bool, error) {
+	statefulSet := &appsv1.StatefulSet{}
+	err := c.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: name}, statefulSet)
+	if k8serrors.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	containers := statefulSet.Spec.Template.Spec.Containers
+	if len(containers) == 0 || containers[0].Image != image {
+		return false, nil
+	}
+	replicas := int32(1)
+	if statefulSet.Spec.Replicas != nil {
+		replicas = *statefulSet.Spec.Replicas
+	}
+	return statefulSet.Status.UpdatedReplicas == replicas && statefulSet.Status.ReadyReplicas == replicas, nil
+}
+
+// getKind returns the kind of a Splunk custom resource, even if its TypeMeta is not set
+func getKind(cr splcommon.MetaObject) string {
+	switch cr.(type) {
+	case *enterpriseApi.LicenseMaster:
+		return "LicenseMaster"
+	case *enterpriseApi.ClusterMaster:
+		return "ClusterMaster"
+	case *enterpriseApi.SearchHeadCluster:
+		return "SearchHeadCluster"
+	case *enterpriseApi.IndexerCluster:
+		return "IndexerCluster"
+	case *enterpriseApi.Standalone:
+		return "Standalone"
+	}
+	return cr.GetObjectKind().GroupVersionKind().Kind
+}
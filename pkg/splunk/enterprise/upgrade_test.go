@@ -0,0 +1,156 @@
+// Copyright (c) 2018-2021 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enterprise
+
+import (
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	enterpriseApi "github.com/splunk/splunk-operator/pkg/apis/enterprise/v2"
+	splcommon "github.com/splunk/splunk-operator/pkg/splunk/common"
+	spltest "github.com/splunk/splunk-operator/pkg/splunk/test"
+)
+
+// newTestUpgradedStatefulSet returns a StatefulSet with all its replicas running an image
+func newTestUpgradedStatefulSet(name, image string, replicas int32) *appsv1.StatefulSet {
+	return &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test"},
+		Spec: appsv1.StatefulSetSpec{
+			Replicas: &replicas,
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "splunk", Image: image}}},
+			},
+		},
+		Status: appsv1.StatefulSetStatus{Replicas: replicas, ReadyReplicas: replicas, UpdatedReplicas: replicas},
+	}
+}
+
+func TestUpgradeGate(t *testing.T) {
+	c := spltest.NewMockClient()
+	c.NotFoundError = k8serrors.NewNotFound(schema.GroupResource{Group: "enterprise.splunk.com"}, "")
+	image := "splunk/splunk:8.2.1"
+
+	lm := &enterpriseApi.LicenseMaster{ObjectMeta: metav1.ObjectMeta{Name: "stack1", Namespace: "test"}}
+	lm.Spec.Image = image
+	lm.Status.Phase = splcommon.PhaseUpdating
+	lmStatefulSet := newTestUpgradedStatefulSet("splunk-stack1-license-master", "splunk/splunk:8.1.3", 1)
+	c.AddObject(lm)
+	c.AddObject(lmStatefulSet)
+
+	cm := &enterpriseApi.ClusterMaster{ObjectMeta: metav1.ObjectMeta{Name: "stack1", Namespace: "test"}}
+	cm.Spec.Image = image
+	cm.Spec.LicenseMasterRef.Name = "stack1"
+	cm.Status.Phase = splcommon.PhaseReady
+	cmStatefulSet := newTestUpgradedStatefulSet("splunk-stack1-cluster-master", image, 1)
+	c.AddObject(cm)
+	c.AddObject(cmStatefulSet)
+
+	idxc := &enterpriseApi.IndexerCluster{ObjectMeta: metav1.ObjectMeta{Name: "stack1", Namespace: "test"}}
+	idxc.Spec.Image = image
+	idxc.Spec.ClusterMasterRef.Name = "stack1"
+	idxc.Spec.LicenseMasterRef.Name = "stack1"
+
+	test := func(cr splcommon.MetaObject, spec *enterpriseApi.CommonSplunkSpec, want string) {
+		gate := newUpgradeGate(c, cr, spec)
+		if got := gate.hold(); got != (want != "") {
+			t.Errorf("hold(%s) = %t; want %t", getKind(cr), got, want != "")
+		}
+		if gate.blocker != want {
+			t.Errorf("hold(%s) blocker = %s; want %s", getKind(cr), gate.blocker, want)
+		}
+	}
+
+	// the license manager does not depend on anything
+	test(lm, &lm.Spec.CommonSplunkSpec, "")
+
+	// the license manager is still being upgraded
+	test(cm, &cm.Spec.CommonSplunkSpec, "LicenseMaster/stack1")
+	test(idxc, &idxc.Spec.CommonSplunkSpec, "LicenseMaster/stack1")
+
+	// the license manager is ready, but its pod has not been recycled yet
+	lm.Status.Phase = splcommon.PhaseReady
+	c.AddObject(lm)
+	test(cm, &cm.Spec.CommonSplunkSpec, "LicenseMaster/stack1")
+
+	lmStatefulSet.Spec.Template.Spec.Containers[0].Image = image
+	c.AddObject(lmStatefulSet)
+	test(cm, &cm.Spec.CommonSplunkSpec, "")
+	test(idxc, &idxc.Spec.CommonSplunkSpec, "")
+
+	// the cluster manager is updated again
+	cmStatefulSet.Status.UpdatedReplicas = 0
+	c.AddObject(cmStatefulSet)
+	test(idxc, &idxc.Spec.CommonSplunkSpec, "ClusterMaster/stack1")
+
+	// upstreams using another image are not waited for
+	idxc.Spec.Image = "splunk/splunk:8.2.2"
+	test(idxc, &idxc.Spec.CommonSplunkSpec, "")
+
+	// the search head cluster members wait for their deployer
+	cmStatefulSet.Status.UpdatedReplicas = 1
+	c.AddObject(cmStatefulSet)
+	shc := &enterpriseApi.SearchHeadCluster{ObjectMeta: metav1.ObjectMeta{Name: "stack1", Namespace: "test"}}
+	shc.Spec.Image = image
+	shc.Spec.ClusterMasterRef.Name = "stack1"
+	gate := newUpgradeGate(c, shc, &shc.Spec.CommonSplunkSpec).waitFor("splunk-stack1-deployer")
+	if !gate.hold() || gate.blocker != "StatefulSet/splunk-stack1-deployer" {
+		t.Errorf("hold() blocker = %s; want StatefulSet/splunk-stack1-deployer", gate.blocker)
+	}
+
+	// the reconcile is requeued while updates are held off
+	result := reconcile.Result{}
+	if !gate.requeueIfHeld(&result, nil) || !result.Requeue || result.RequeueAfter != time.Second*5 {
+		t.Errorf("requeueIfHeld() result = %v; want requeue after 5s", result)
+	}
+	result = reconcile.Result{}
+	gate = newUpgradeGate(c, cm, &cm.Spec.CommonSplunkSpec)
+	gate.hold()
+	if gate.requeueIfHeld(&result, nil) || result.Requeue {
+		t.Errorf("requeueIfHeld() result = %v; want no requeue", result)
+	}
+}
+
+func TestGetUpgradeUpstreams(t *testing.T) {
+	c := spltest.NewMockClient()
+	c.NotFoundError = k8serrors.NewNotFound(schema.GroupResource{Group: "enterprise.splunk.com"}, "")
+	shc := enterpriseApi.SearchHeadCluster{ObjectMeta: metav1.ObjectMeta{Name: "shc", Namespace: "test"}}
+	shc.Spec.ClusterMasterRef.Name = "cm"
+	c.ListObj = &enterpriseApi.SearchHeadClusterList{Items: []enterpriseApi.SearchHeadCluster{shc}}
+
+	idxc := &enterpriseApi.IndexerCluster{ObjectMeta: metav1.ObjectMeta{Name: "idxc", Namespace: "test"}}
+	idxc.Spec.ClusterMasterRef.Name = "cm"
+	upstreams, err := getUpgradeUpstreams(c, idxc, &idxc.Spec.CommonSplunkSpec)
+	if err != nil {
+		t.Errorf("getUpgradeUpstreams() returned %v; want nil", err)
+	}
+
+	// the cluster manager does not exist, and is skipped
+	if len(upstreams) != 1 || upstreams[0].GetName() != "shc" {
+		t.Errorf("getUpgradeUpstreams() = %v; want SearchHeadCluster shc", upstreams)
+	}
+
+	idxc.Spec.ClusterMasterRef.Name = "cm2"
+	upstreams, _ = getUpgradeUpstreams(c, idxc, &idxc.Spec.CommonSplunkSpec)
+	if len(upstreams) != 0 {
+		t.Errorf("getUpgradeUpstreams() = %v; want none", upstreams)
+	}
+}
@@ -16,9 +16,10 @@ package enterprise
 
 import (
 	"fmt"
+	"io"
 	"path/filepath"
 	"reflect"
-	"sort"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -63,20 +64,36 @@ func GetRemoteStorageClient(client splcommon.ControllerClient, cr splcommon.Meta
 		}
 
 		// Get access keys
-		accessKeyID = string(s3ClientSecret.Data["s3_access_key"])
-		secretAccessKey = string(s3ClientSecret.Data["s3_secret_key"])
-
-		// Do we need to handle if IAM_ROLE is set in the secret as well?
-		if accessKeyID == "" {
-			err = fmt.Errorf("accessKey missing")
+		accessKeyID, secretAccessKey, err = getRemoteStorageCredentials(s3ClientSecret, vol.Type)
+		if err != nil {
 			return s3Client, err
 		}
-		if secretAccessKey == "" {
-			err = fmt.Errorf("S3 Secret Key is missing")
-			return s3Client, err
+	}
+
+	bucket, prefix := getRemoteStorageBucketAndPrefix(vol, location)
+
+	scopedLog.Info("Creating the client", "volume", vol.Name, "bucket", bucket, "bucket path", prefix)
+
+	// The apps of a PersistentVolumeClaim are listed from the Splunk pod mounting it
+	if fn == nil && vol.Type == "pvc" {
+		fn = func(string, string, string) interface{} {
+			return &appRepoPodExecClient{client: client, podName: getAppFrameworkPodName(cr), namespace: cr.GetNamespace()}
 		}
 	}
 
+	var err error
+	s3Client.Client, err = getClient(bucket, accessKeyID, secretAccessKey, prefix, prefix /* startAfter*/, vol.Endpoint, fn)
+	if err != nil {
+		scopedLog.Error(err, "Failed to get the S3 client")
+		return s3Client, err
+	}
+
+	return s3Client, nil
+}
+
+// getRemoteStorageBucketAndPrefix returns the bucket of a remote storage volume, and the prefix of the objects of a
+// location of the volume, ending with a "/"
+func getRemoteStorageBucketAndPrefix(vol *enterpriseApi.VolumeSpec, location string) (string, string) {
 	// Get the bucket name form the "path" field
 	bucket := strings.Split(vol.Path, "/")[0]
 
@@ -90,18 +107,95 @@ func GetRemoteStorageClient(client splcommon.ControllerClient, cr splcommon.Meta
 
 	// Join takes care of merging two paths and returns a clean result
 	// Ex. ("a/b" + "c"),  ("a/b/" + "c"),  ("a/b/" + "/c"),  ("a/b/" + "/c"), ("a/b//", + "c/././") ("a/b/../b", + "c/../c") all are joined as "a/b/c"
-	prefix := filepath.Join(basePrefix, location) + "/"
+	return bucket, filepath.Join(basePrefix, location) + "/"
+}
 
-	scopedLog.Info("Creating the client", "volume", vol.Name, "bucket", bucket, "bucket path", prefix)
+// getRemoteStorageCredentials returns the credentials of a remote storage type from its secret. For
+// GCS, the service account key is returned as the secret key, since it does not use any access key.
+func getRemoteStorageCredentials(secret *corev1.Secret, storageType string) (string, string, error) {
+	switch {
+	case isAzureBlobStorageType(storageType):
+		accountName := string(secret.Data[azureBlobAccountName])
+		accountKey := string(secret.Data[azureBlobAccountKey])
+		if accountKey == "" && len(secret.Data[azureSASToken]) > 0 {
+			return "", "", fmt.Errorf("Azure SAS tokens are not supported: set the %s and %s of the storage account in the secret instead of %s", azureBlobAccountName, azureBlobAccountKey, azureSASToken)
+		}
+		if accountName == "" {
+			return "", "", fmt.Errorf("Azure storage account name is missing")
+		}
+		if accountKey == "" {
+			return "", "", fmt.Errorf("Azure storage account key is missing")
+		}
+		return accountName, accountKey, nil
+	case storageType == "gcs":
+		serviceAccountKey := string(secret.Data[gcsServiceAccountKey])
+		if serviceAccountKey == "" {
+			return "", "", fmt.Errorf("GCS service account key is missing")
+		}
+		return "", serviceAccountKey, nil
+	}
 
-	var err error
-	s3Client.Client, err = getClient(bucket, accessKeyID, secretAccessKey, prefix, prefix /* startAfter*/, vol.Endpoint, fn)
+	// Do we need to handle if IAM_ROLE is set in the secret as well?
+	accessKeyID := string(secret.Data[s3AccessKey])
+	secretAccessKey := string(secret.Data[s3SecretKey])
+	if accessKeyID == "" {
+		return "", "", fmt.Errorf("accessKey missing")
+	}
+	if secretAccessKey == "" {
+		return "", "", fmt.Errorf("S3 Secret Key is missing")
+	}
+	return accessKeyID, secretAccessKey, nil
+}
+
+// getAzureServicePrincipal returns the tenant, client id and client secret of the Azure service principal of a
+// secret, which are empty when the secret holds a storage account key instead
+func getAzureServicePrincipal(secret *corev1.Secret) (string, string, string, error) {
+	clientID := string(secret.Data[azureClientID])
+	if clientID == "" {
+		return "", "", "", nil
+	}
+	clientSecret := string(secret.Data[azureClientSecret])
+	tenantID := string(secret.Data[azureTenantID])
+	if clientSecret == "" {
+		return "", "", "", fmt.Errorf("Azure client secret is missing")
+	} else if tenantID == "" {
+		return "", "", "", fmt.Errorf("Azure tenant id is missing")
+	}
+	return tenantID, clientID, clientSecret, nil
+}
+
+// appRepoPodExecClient runs the scripts of the Kubernetes volume client in the Splunk pod mounting the claims of the App Framework
+type appRepoPodExecClient struct {
+	client    splcommon.ControllerClient
+	podName   string
+	namespace string
+}
+
+// Exec runs a shell script in the pod, and returns its output
+func (c *appRepoPodExecClient) Exec(script string) (string, error) {
+	stdout, stderr, err := splutil.PodExecCommand(c.client, c.podName, c.namespace, []string{"/bin/sh"}, script, false, false)
 	if err != nil {
-		scopedLog.Error(err, "Failed to get the S3 client")
-		return s3Client, err
+		return "", fmt.Errorf("%s: %s", err, stderr)
 	}
+	return stdout, nil
+}
 
-	return s3Client, nil
+// ExecStream runs a shell script in the pod, and writes its output to stdout as it comes
+func (c *appRepoPodExecClient) ExecStream(script string, stdout io.Writer) error {
+	return splutil.PodExecStream(c.client, c.podName, c.namespace, []string{"/bin/sh", "-c", script}, nil, stdout)
+}
+
+// getAppFrameworkPodName returns the name of the first pod of a custom resource whose StatefulSet mounts the claims of the App Framework
+func getAppFrameworkPodName(cr splcommon.MetaObject) string {
+	switch cr.(type) {
+	case *enterpriseApi.LicenseMaster:
+		return GetSplunkStatefulsetPodName(SplunkLicenseMaster, cr.GetName(), 0)
+	case *enterpriseApi.ClusterMaster:
+		return GetSplunkStatefulsetPodName(SplunkClusterMaster, cr.GetName(), 0)
+	case *enterpriseApi.SearchHeadCluster:
+		return GetSplunkStatefulsetPodName(SplunkDeployer, cr.GetName(), 0)
+	}
+	return GetSplunkStatefulsetPodName(SplunkStandalone, cr.GetName(), 0)
 }
 
 // ApplySplunkConfig reconciles the state of Kubernetes Secrets, ConfigMaps and other general settings for Splunk Enterprise instances.
@@ -120,9 +214,13 @@ func ApplySplunkConfig(client splcommon.ControllerClient, cr splcommon.MetaObjec
 		return nil, err
 	}
 
-	// create splunk defaults (for inline config)
-	if spec.Defaults != "" {
+	// create splunk defaults (for inline and multisite config)
+	multisiteDefaults := getMultisiteDefaults(cr)
+	if spec.Defaults != "" || multisiteDefaults != "" {
 		defaultsMap := getSplunkDefaults(cr.GetName(), cr.GetNamespace(), instanceType, spec.Defaults)
+		if multisiteDefaults != "" {
+			defaultsMap.Data[multisiteDefaultsFile] = multisiteDefaults
+		}
 		defaultsMap.SetOwnerReferences(append(defaultsMap.GetOwnerReferences(), splcommon.AsOwner(cr, true)))
 		_, err = splctrl.ApplyConfigMap(client, defaultsMap)
 		if err != nil {
@@ -232,140 +330,6 @@ func GetSmartstoreRemoteVolumeSecrets(volume enterpriseApi.VolumeSpec, client sp
 	return accessKey, secretKey, namespaceScopedSecret.ResourceVersion, nil
 }
 
-// ApplyAppListingConfigMap creates the configMap  with two entries:
-// (1) app-list-local.yaml
-// (2) app-list-cluster.yaml
-// Once the configMap is mounted on the Pod, Ansible handles the apps listed in these files
-// ToDo: Deletes to be handled for phase-3
-func ApplyAppListingConfigMap(client splcommon.ControllerClient, cr splcommon.MetaObject,
-	appConf *enterpriseApi.AppFrameworkSpec, appsSrcDeployStatus map[string]enterpriseApi.AppSrcDeployInfo, appsModified bool) (*corev1.ConfigMap, bool, error) {
-
-	var err error
-	var crKind string
-	var configMapDataChanged bool
-	crKind = cr.GetObjectKind().GroupVersionKind().Kind
-
-	scopedLog := log.WithName("ApplyAppListingConfigMap").WithValues("kind", crKind, "name", cr.GetName(), "namespace", cr.GetNamespace())
-
-	mapAppListing := make(map[string]string)
-
-	// Locally scoped apps for CM/Deployer require the latest splunk-ansible with apps_location_local.  Prior to this,
-	// there was no method to install local apps for these roles.  If the apps_location_local variable is not available,
-	// it will be ignored and revert back to no locally scoped apps for CM/Deployer.
-	yamlConfIdcHeader := fmt.Sprintf(`splunk:
-  app_paths_install:
-    idxc:`)
-
-	yamlConfShcHeader := fmt.Sprintf(`splunk:
-  app_paths_install:
-    shc:`)
-
-	yamlConfLocalHeader := fmt.Sprintf(`splunk:
-  app_paths_install:
-    default:`)
-
-	// Used for apps requiring pre-configuration before installing on the cluster
-	// Example: Splunk Enterprise Security App
-	yamlClusterAppsWithPreConfHeader := fmt.Sprintf(`splunk:
-  apps_location:`)
-
-	var localAppsConf, clusterAppsConf string
-	if crKind == "ClusterMaster" {
-		clusterAppsConf = yamlConfIdcHeader
-	} else if crKind == "SearchHeadCluster" {
-		clusterAppsConf = yamlConfShcHeader
-	} else {
-		clusterAppsConf = ""
-	}
-
-	localAppsConf = yamlConfLocalHeader
-	clusterAppsWithPreConf := yamlClusterAppsWithPreConfHeader
-
-	var mapKeys []string
-
-	// Map order is not guaranteed, so use the sorted keys to go through the map entries
-	for appSrc := range appsSrcDeployStatus {
-		mapKeys = append(mapKeys, appSrc)
-	}
-	sort.Strings(mapKeys)
-
-	for _, appSrc := range mapKeys {
-		appDeployList := appsSrcDeployStatus[appSrc].AppDeploymentInfoList
-
-		switch scope := getAppSrcScope(appConf, appSrc); scope {
-		case enterpriseApi.ScopeLocal:
-			for idx := range appDeployList {
-				if appDeployList[idx].DeployStatus == enterpriseApi.DeployStatusPending &&
-					appDeployList[idx].RepoState == enterpriseApi.RepoStateActive {
-					localAppsConf = fmt.Sprintf(`%s
-      - "/init-apps/%s/%s"`, localAppsConf, appSrc, appDeployList[idx].AppName)
-				}
-			}
-
-		case enterpriseApi.ScopeCluster:
-			for idx := range appDeployList {
-				if appDeployList[idx].DeployStatus == enterpriseApi.DeployStatusPending &&
-					appDeployList[idx].RepoState == enterpriseApi.RepoStateActive {
-					clusterAppsConf = fmt.Sprintf(`%s
-      - "/init-apps/%s/%s"`, clusterAppsConf, appSrc, appDeployList[idx].AppName)
-				}
-			}
-
-		case enterpriseApi.ScopeClusterWithPreConfig:
-			for idx := range appDeployList {
-				if appDeployList[idx].DeployStatus == enterpriseApi.DeployStatusPending &&
-					appDeployList[idx].RepoState == enterpriseApi.RepoStateActive {
-					clusterAppsWithPreConf = fmt.Sprintf(`%s
-      - "/init-apps/%s/%s"`, clusterAppsWithPreConf, appSrc, appDeployList[idx].AppName)
-				}
-			}
-
-		default:
-			scopedLog.Error(nil, "Invalid scope detected")
-		}
-	}
-
-	// Don't update the configMap if there is nothing to write.
-	if localAppsConf != yamlConfLocalHeader {
-		mapAppListing["app-list-local.yaml"] = localAppsConf
-	}
-
-	if clusterAppsConf != yamlConfIdcHeader && clusterAppsConf != yamlConfShcHeader && clusterAppsConf != "" {
-		mapAppListing["app-list-cluster.yaml"] = clusterAppsConf
-	}
-
-	if clusterAppsWithPreConf != yamlClusterAppsWithPreConfHeader {
-		mapAppListing["app-list-cluster-with-pre-config.yaml"] = clusterAppsWithPreConf
-	}
-	// Create App list config map
-	configMapName := GetSplunkAppsConfigMapName(cr.GetName(), crKind)
-	appListingConfigMap := splctrl.PrepareConfigMap(configMapName, cr.GetNamespace(), mapAppListing)
-
-	appListingConfigMap.SetOwnerReferences(append(appListingConfigMap.GetOwnerReferences(), splcommon.AsOwner(cr, true)))
-
-	if len(appListingConfigMap.Data) > 0 {
-		if appsModified {
-			// App packages are modified, reset configmap to ensure a new resourceVersion
-			scopedLog.Info("Resetting App ConfigMap to force new resourceVersion", "configMapName", configMapName)
-			savedData := appListingConfigMap.Data
-			appListingConfigMap.Data = make(map[string]string)
-			_, err = splctrl.ApplyConfigMap(client, appListingConfigMap)
-			if err != nil {
-				scopedLog.Error(err, "failed reset of configmap", "configMapName", configMapName)
-			}
-			appListingConfigMap.Data = savedData
-		}
-
-		configMapDataChanged, err = splctrl.ApplyConfigMap(client, appListingConfigMap)
-
-		if err != nil {
-			return nil, configMapDataChanged, err
-		}
-	}
-
-	return appListingConfigMap, configMapDataChanged, nil
-}
-
 // ApplySmartstoreConfigMap creates the configMap with Smartstore config in INI format
 func ApplySmartstoreConfigMap(client splcommon.ControllerClient, cr splcommon.MetaObject,
 	smartstore *enterpriseApi.SmartStoreSpec) (*corev1.ConfigMap, bool, error) {
@@ -570,7 +534,7 @@ func GetAppListFromS3Bucket(client splcommon.ControllerClient, cr splcommon.Meta
 			continue
 		}
 
-		sourceToAppListMap[appSource.Name] = s3Response
+		sourceToAppListMap[appSource.Name] = splclient.S3Response{Objects: pinAppSrcApps(appSource, s3Response.Objects)}
 	}
 
 	if allSuccess == false {
@@ -580,6 +544,33 @@ func GetAppListFromS3Bucket(client splcommon.ControllerClient, cr splcommon.Meta
 	return sourceToAppListMap, err
 }
 
+// pinAppSrcApps limits the remote listing of an appSource to its selected Apps, if any. The Apps pinned to another
+// version than the one listed get the object hash of their pinned version.
+func pinAppSrcApps(appSource enterpriseApi.AppSourceSpec, objects []*splclient.RemoteObject) []*splclient.RemoteObject {
+	if len(appSource.Apps) == 0 {
+		return objects
+	}
+
+	var pinnedObjects []*splclient.RemoteObject
+	for _, object := range objects {
+		appName := (*object.Key)[strings.LastIndex(*object.Key, "/")+1:]
+		for _, app := range appSource.Apps {
+			if app.Name != appName {
+				continue
+			}
+			if app.ObjectHash != "" && app.ObjectHash != *object.Etag {
+				// the modification time and size of the listing are those of the latest version
+				pinnedHash := app.ObjectHash
+				object = &splclient.RemoteObject{Etag: &pinnedHash, Key: object.Key, StorageClass: object.StorageClass}
+			}
+			pinnedObjects = append(pinnedObjects, object)
+			break
+		}
+	}
+
+	return pinnedObjects
+}
+
 // checkIfAnAppIsActiveOnRemoteStore checks if the App is listed as part of the AppSrc listing
 func checkIfAnAppIsActiveOnRemoteStore(appName string, list []*splclient.RemoteObject) bool {
 	for i := range list {
@@ -712,6 +703,15 @@ func handleAppRepoChanges(client splcommon.ControllerClient, cr splcommon.MetaOb
 	return appsModified, err
 }
 
+// appNameRegex matches the names of the app packages and of their top directory, which are used in the paths and
+// the scripts of the pods
+var appNameRegex = regexp.MustCompile("^[A-Za-z0-9._-]+$")
+
+// isAppNameValid checks if the name of an app package, or of its top directory, is a plain file name
+func isAppNameValid(name string) bool {
+	return appNameRegex.MatchString(name) && !strings.Contains(name, "..")
+}
+
 // isAppExtentionValid checks if an app extention is supported or not
 func isAppExtentionValid(receivedKey string) bool {
 	appExtIdx := strings.LastIndex(receivedKey, ".")
@@ -750,6 +750,10 @@ func AddOrUpdateAppSrcDeploymentInfoList(appSrcDeploymentInfo *enterpriseApi.App
 
 		nameAt := strings.LastIndex(receivedKey, "/")
 		appName = receivedKey[nameAt+1:]
+		if !isAppNameValid(appName) {
+			scopedLog.Error(nil, "App name Parsing: Ignoring the key with invalid characters", "receivedKey", receivedKey)
+			continue
+		}
 
 		// Now update App status as seen in the remote listing
 		found = false
@@ -761,6 +765,9 @@ func AddOrUpdateAppSrcDeploymentInfoList(appSrcDeploymentInfo *enterpriseApi.App
 					scopedLog.Info("App change detected.  Marking for an update.", "appName", appName)
 					appList[idx].ObjectHash = *remoteObj.Etag
 					appList[idx].DeployStatus = enterpriseApi.DeployStatusPending
+					appList[idx].PodDeployStatus = nil
+					appList[idx].RestartRequired = false
+					appList[idx].SetupStatus = nil
 
 					// Make the state active for an app that was deleted earlier, and got activated again
 					if appList[idx].RepoState == enterpriseApi.RepoStateDeleted {
@@ -795,145 +802,66 @@ func AddOrUpdateAppSrcDeploymentInfoList(appSrcDeploymentInfo *enterpriseApi.App
 	return appChangesDetected
 }
 
-// markAppsStatusToComplete sets the required status for a given state.
-// Gets called from glue logic based on how we want to hand-off to init/side car, and look for the return status
-// For now, two possible cases:
-// 1. Completing the changes for Deletes. Called with state=AppStateDeleted, and status=DeployStatusPending
-// 2. Completing the changes for Active(Apps newly added, apps modified, Apps previously deleted, and now active).
-// Note:- Used in only for Phase-2
-func markAppsStatusToComplete(client splcommon.ControllerClient, cr splcommon.MetaObject, appConf *enterpriseApi.AppFrameworkSpec, appSrcDeploymentStatus map[string]enterpriseApi.AppSrcDeployInfo) error {
-	var err error
-	scopedLog := log.WithName("markAppsStatusToComplete")
-
-	// ToDo: Passing appSrcDeploymentStatus is redundant, but this function will go away in phase-3, so ok for now.
-	for appSrc := range appSrcDeploymentStatus {
-		changeAppSrcDeployInfoStatus(appSrc, appSrcDeploymentStatus, enterpriseApi.RepoStateActive, enterpriseApi.DeployStatusPending, enterpriseApi.DeployStatusComplete)
-		changeAppSrcDeployInfoStatus(appSrc, appSrcDeploymentStatus, enterpriseApi.RepoStateDeleted, enterpriseApi.DeployStatusPending, enterpriseApi.DeployStatusComplete)
+// setupAppsStagingVolume creates the volume where the app packages of the appSources configured are copied before
+// they are installed, and mounts the PersistentVolumeClaims of the app repository volumes, if any.
+func setupAppsStagingVolume(podTemplateSpec *corev1.PodTemplateSpec, appFrameworkConfig *enterpriseApi.AppFrameworkSpec) {
+	scopedLog := log.WithName("setupAppsStagingVolume")
+	if len(appFrameworkConfig.AppSources) == 0 {
+		return
 	}
 
-	// ToDo: For now disabling the configMap reset, as it causes unnecessary pod resets due to annotations change
-	// Now that all the apps are deployed. Update the same in the App listing configMap
-	// _, _, err = ApplyAppListingConfigMap(client, cr, appConf, appSrcDeploymentStatus)
-	// if err != nil {
-	// 	return err
-	// }
-
-	scopedLog.Info("Marked the App deployment status to complete")
-	// ToDo: Caller of this API also needs to set "IsDeploymentInProgress = false" once after completing this function call for all the app sources
-
-	return err
-}
-
-// setupAppInitContainers creates the necessary shared volume and init containers to download all
-// app packages in the appSources configured and make them locally available to the Splunk instance.
-func setupAppInitContainers(client splcommon.ControllerClient, cr splcommon.MetaObject, podTemplateSpec *corev1.PodTemplateSpec, appFrameworkConfig *enterpriseApi.AppFrameworkSpec) {
-	scopedLog := log.WithName("setupAppInitContainers")
-	// Create shared volume and init containers for App Framework
-	if len(appFrameworkConfig.AppSources) > 0 {
-		// Create volume to shared between init and Splunk container to contain downloaded apps
-		emptyVolumeSource := corev1.VolumeSource{
+	podTemplateSpec.Spec.Volumes = append(podTemplateSpec.Spec.Volumes, corev1.Volume{
+		Name: appVolumeMntName,
+		VolumeSource: corev1.VolumeSource{
 			EmptyDir: &corev1.EmptyDirVolumeSource{},
-		}
-
-		initVol := corev1.Volume{
-			Name:         appVolumeMntName,
-			VolumeSource: emptyVolumeSource,
-		}
+		},
+	})
 
-		podTemplateSpec.Spec.Volumes = append(podTemplateSpec.Spec.Volumes, initVol)
+	// This assumes the Splunk instance container is Containers[0], which I *believe* is valid
+	podTemplateSpec.Spec.Containers[0].VolumeMounts = append(podTemplateSpec.Spec.Containers[0].VolumeMounts, corev1.VolumeMount{
+		Name:      appVolumeMntName,
+		MountPath: appBktMnt,
+	})
 
-		// Add init apps mount to Splunk container
-		initVolumeSpec := corev1.VolumeMount{
-			Name:      appVolumeMntName,
-			MountPath: appBktMnt,
+	for _, appSrc := range appFrameworkConfig.AppSources {
+		appRepoVol, err := splclient.GetAppSrcVolume(appSrc, appFrameworkConfig)
+		if err != nil {
+			// Invalid appFramework config.  This shouldn't happen
+			scopedLog.Info("Invalid appSrc volume spec, moving to the next one", "appSrc.VolName", appSrc.VolName, "err", err)
+			continue
 		}
+		if appRepoVol.Type == "pvc" {
+			addAppRepoVolume(podTemplateSpec, appRepoVol.Path)
+		}
+	}
+}
 
-		// This assumes the Splunk instance container is Containers[0], which I *believe* is valid
-		podTemplateSpec.Spec.Containers[0].VolumeMounts = append(podTemplateSpec.Spec.Containers[0].VolumeMounts, initVolumeSpec)
-
-		// Add app framework init containers per app source and attach the init volume
-		for i, appSrc := range appFrameworkConfig.AppSources {
-			// Get volume info from appSrc
-
-			var volSpecPos int
-			var err error
-			if appSrc.VolName != "" {
-				volSpecPos, err = splclient.CheckIfVolumeExists(appFrameworkConfig.VolList, appSrc.VolName)
-			} else {
-				volSpecPos, err = splclient.CheckIfVolumeExists(appFrameworkConfig.VolList, appFrameworkConfig.Defaults.VolName)
-			}
-
-			if err != nil {
-				// Invalid appFramework config.  This shouldn't happen
-				scopedLog.Info("Invalid appSrc volume spec, moving to the next one", "appSrc.VolName", appSrc.VolName, "err", err)
-				continue
-			}
-			appRepoVol := appFrameworkConfig.VolList[volSpecPos]
-
-			s3ClientWrapper := splclient.S3Clients[appRepoVol.Provider]
-			initFunc := s3ClientWrapper.GetS3ClientInitFuncPtr()
-			// Use the provider name to get the corresponding function pointer
-			s3Client, err := GetRemoteStorageClient(client, cr, appFrameworkConfig, &appRepoVol, appSrc.Location, initFunc)
-			if err != nil {
-				// move on to the next appSource if we are not able to get the required client
-				scopedLog.Info("Invalid Remote Storage Client", "appRepoVol.Name", appRepoVol.Name, "err", err)
-				continue
-			}
-
-			// Prepare app source/repo values
-			appBkt := appRepoVol.Path
-			appS3Endpoint := appRepoVol.Endpoint
-			appSecretRef := appRepoVol.SecretRef
-			appSrcName := appSrc.Name
-			appSrcPath := appSrc.Location
-			appSrcScope := getAppSrcScope(appFrameworkConfig, appSrc.Name)
-			initContainerName := strings.ToLower(fmt.Sprintf(initContainerTemplate, appSrcName, i, appSrcScope))
-
-			initEnv := []corev1.EnvVar{}
-			if appSecretRef != "" {
-				initEnv = append(initEnv, corev1.EnvVar{
-					Name: "AWS_ACCESS_KEY_ID",
-					ValueFrom: &corev1.EnvVarSource{
-						SecretKeyRef: &corev1.SecretKeySelector{
-							LocalObjectReference: corev1.LocalObjectReference{
-								Name: appSecretRef,
-							},
-							Key: s3AccessKey,
-						},
-					},
-				})
-				initEnv = append(initEnv, corev1.EnvVar{
-					Name: "AWS_SECRET_ACCESS_KEY",
-					ValueFrom: &corev1.EnvVarSource{
-						SecretKeyRef: &corev1.SecretKeySelector{
-							LocalObjectReference: corev1.LocalObjectReference{
-								Name: appSecretRef,
-							},
-							Key: s3SecretKey,
-						},
-					},
-				})
-			}
-
-			// Setup init container
-			initContainerSpec := corev1.Container{
-				Image:           s3Client.Client.GetInitContainerImage(),
-				ImagePullPolicy: "IfNotPresent",
-				Name:            initContainerName,
-				Args:            s3Client.Client.GetInitContainerCmd(appS3Endpoint, appBkt, appSrcPath, appSrcName, appBktMnt),
-				Env:             initEnv,
-			}
+// addAppRepoVolume adds the PersistentVolumeClaim of an app repository volume path to a pod template, unless it
+// was already added for another app source, and mounts it read-only in the Splunk container so that its apps can
+// be listed and downloaded.
+func addAppRepoVolume(podTemplateSpec *corev1.PodTemplateSpec, path string) {
+	claimName := strings.Split(path, "/")[0]
+	volumeMount := corev1.VolumeMount{
+		Name:      fmt.Sprintf(appRepoVolumeTemplate, claimName),
+		MountPath: filepath.Join(splclient.AppRepoVolumeMountDir, claimName),
+		ReadOnly:  true,
+	}
 
-			// Add mount to initContainer, same mount used for Splunk instance container as well
-			initContainerSpec.VolumeMounts = []corev1.VolumeMount{
-				{
-					Name:      appVolumeMntName,
-					MountPath: appBktMnt,
-				},
-			}
-			podTemplateSpec.Spec.InitContainers = append(podTemplateSpec.Spec.InitContainers, initContainerSpec)
+	for _, volume := range podTemplateSpec.Spec.Volumes {
+		if volume.Name == volumeMount.Name {
+			return
 		}
 	}
+	podTemplateSpec.Spec.Volumes = append(podTemplateSpec.Spec.Volumes, corev1.Volume{
+		Name: volumeMount.Name,
+		VolumeSource: corev1.VolumeSource{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+				ClaimName: claimName,
+				ReadOnly:  true,
+			},
+		},
+	})
+	podTemplateSpec.Spec.Containers[0].VolumeMounts = append(podTemplateSpec.Spec.Containers[0].VolumeMounts, volumeMount)
 }
 
 // SetLastAppInfoCheckTime sets the last check time to current time
@@ -979,7 +907,7 @@ func GetNextRequeueTime(appRepoPollInterval, lastCheckTime int64) time.Duration
 }
 
 // initAndCheckAppInfoStatus initializes the S3Clients and checks the status of apps on remote storage.
-func initAndCheckAppInfoStatus(client splcommon.ControllerClient, cr splcommon.MetaObject, appFrameworkConf *enterpriseApi.AppFrameworkSpec, appStatusContext *enterpriseApi.AppDeploymentContext) error {
+func initAndCheckAppInfoStatus(client splcommon.ControllerClient, cr splcommon.MetaObject, appFrameworkConf *enterpriseApi.AppFrameworkSpec, appStatusContext *enterpriseApi.AppDeploymentContext, eventPublisher *eventPublisher) error {
 	scopedLog := log.WithName("initAndCheckAppInfoStatus").WithValues("name", cr.GetName(), "namespace", cr.GetNamespace())
 
 	var err error
@@ -1003,9 +931,8 @@ func initAndCheckAppInfoStatus(client splcommon.ControllerClient, cr splcommon.M
 		scopedLog.Info("Checking status of apps on remote storage...")
 
 		sourceToAppsList, err = GetAppListFromS3Bucket(client, cr, appFrameworkConf)
-		// TODO: gaurav, we need to handle this case better in Phase-3. There can be a possibility
-		// where if an appSource is missing in remote store, we mark it for deletion. But if it comes up
-		// next time, we will recycle the pod to install the app. We need to find a way to reduce the pod recycles.
+		// If an appSource is missing in remote store, its apps are marked for deletion. When it comes
+		// back, its apps are installed again in the running pods.
 		if len(sourceToAppsList) != len(appFrameworkConf.AppSources) {
 			scopedLog.Error(err, "Unable to get apps list, will retry in next reconcile...")
 		} else {
@@ -1021,9 +948,8 @@ func initAndCheckAppInfoStatus(client splcommon.ControllerClient, cr splcommon.M
 				return err
 			}
 
-			_, _, err = ApplyAppListingConfigMap(client, cr, appFrameworkConf, appStatusContext.AppsSrcDeployStatus, appsModified)
-			if err != nil {
-				return err
+			if appsModified {
+				eventPublisher.Normal(eventReasonAppRepoChanged, "Detected app changes on remote storage, deploying the apps")
 			}
 
 			appStatusContext.AppFrameworkConfig = *appFrameworkConf
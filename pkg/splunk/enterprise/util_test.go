@@ -17,6 +17,7 @@ package enterprise
 import (
 	"fmt"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -194,118 +195,6 @@ func TestApplySmartstoreConfigMap(t *testing.T) {
 	}
 }
 
-func TestApplyAppListingConfigMap(t *testing.T) {
-	cr := enterpriseApi.ClusterMaster{
-		TypeMeta: metav1.TypeMeta{
-			Kind: "ClusterMaster",
-		},
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      "example",
-			Namespace: "test",
-		},
-		Spec: enterpriseApi.ClusterMasterSpec{
-			AppFrameworkConfig: enterpriseApi.AppFrameworkSpec{
-				VolList: []enterpriseApi.VolumeSpec{
-					{Name: "msos_s2s3_vol",
-						Endpoint:  "https://s3-eu-west-2.amazonaws.com",
-						Path:      "testbucket-rs-london",
-						SecretRef: "s3-secret",
-						Type:      "s3",
-						Provider:  "aws"},
-				},
-				AppSources: []enterpriseApi.AppSourceSpec{
-					{Name: "adminApps",
-						Location: "adminAppsRepo",
-						AppSourceDefaultSpec: enterpriseApi.AppSourceDefaultSpec{
-							VolName: "msos_s2s3_vol",
-							Scope:   enterpriseApi.ScopeLocal},
-					},
-					{Name: "securityApps",
-						Location: "securityAppsRepo",
-						AppSourceDefaultSpec: enterpriseApi.AppSourceDefaultSpec{
-							VolName: "msos_s2s3_vol",
-							Scope:   enterpriseApi.ScopeCluster},
-					},
-					{Name: "appsWithPreConfigRequired",
-						Location: "repoForAppsWithPreConfigRequired",
-						AppSourceDefaultSpec: enterpriseApi.AppSourceDefaultSpec{
-							VolName: "msos_s2s3_vol",
-							Scope:   "clusterWithPreConfig"},
-					},
-				},
-			},
-		},
-	}
-
-	client := spltest.NewMockClient()
-
-	var S3Response splclient.S3Response
-
-	remoteObjListMap := make(map[string]splclient.S3Response)
-
-	// Fill appSrc adminApps
-	startAppPathAndName := "adminCategoryOne.tgz"
-	S3Response.Objects = createRemoteObjectList("b41d8cd98f00", startAppPathAndName, 2322, nil, 10)
-	remoteObjListMap[cr.Spec.AppFrameworkConfig.AppSources[0].Name] = S3Response
-
-	startAppPathAndName = "securityCategoryOne.tgz"
-	S3Response.Objects = createRemoteObjectList("c41d8cd98f00", startAppPathAndName, 3322, nil, 10)
-	remoteObjListMap[cr.Spec.AppFrameworkConfig.AppSources[1].Name] = S3Response
-
-	startAppPathAndName = "appWithPreConfigReqOne.tgz"
-	S3Response.Objects = createRemoteObjectList("d41d8cd98f00", startAppPathAndName, 4322, nil, 10)
-	remoteObjListMap[cr.Spec.AppFrameworkConfig.AppSources[2].Name] = S3Response
-
-	// set the status context
-	initAppFrameWorkContext(&cr.Spec.AppFrameworkConfig, &cr.Status.AppContext)
-
-	appsModified, err := handleAppRepoChanges(client, &cr, &cr.Status.AppContext, remoteObjListMap, &cr.Spec.AppFrameworkConfig)
-
-	if err != nil {
-		t.Errorf("Empty remote Object list should not trigger an error, but got error : %v", err)
-	}
-
-	testAppListingConfigMap := func(client *spltest.MockClient, cr splcommon.MetaObject, appConf *enterpriseApi.AppFrameworkSpec, appsSrcDeployStatus map[string]enterpriseApi.AppSrcDeployInfo, want string) {
-		f := func() (interface{}, error) {
-			configMap, _, err := ApplyAppListingConfigMap(client, cr, appConf, appsSrcDeployStatus, appsModified)
-			// Make the config token as predictable
-			configMap.Data[appsUpdateToken] = "1601945361"
-			return configMap, err
-		}
-		configTester(t, "(ApplyAppListingConfigMap)", f, want)
-	}
-
-	testAppListingConfigMap(client, &cr, &cr.Spec.AppFrameworkConfig, cr.Status.AppContext.AppsSrcDeployStatus, `{"metadata":{"name":"splunk-example-clustermaster-app-list","namespace":"test","creationTimestamp":null,"ownerReferences":[{"apiVersion":"","kind":"ClusterMaster","name":"example","uid":"","controller":true}]},"data":{"app-list-cluster-with-pre-config.yaml":"splunk:\n  apps_location:\n      - \"/init-apps/appsWithPreConfigRequired/1_appWithPreConfigReqOne.tgz\"\n      - \"/init-apps/appsWithPreConfigRequired/2_appWithPreConfigReqOne.tgz\"\n      - \"/init-apps/appsWithPreConfigRequired/3_appWithPreConfigReqOne.tgz\"\n      - \"/init-apps/appsWithPreConfigRequired/4_appWithPreConfigReqOne.tgz\"\n      - \"/init-apps/appsWithPreConfigRequired/5_appWithPreConfigReqOne.tgz\"\n      - \"/init-apps/appsWithPreConfigRequired/6_appWithPreConfigReqOne.tgz\"\n      - \"/init-apps/appsWithPreConfigRequired/7_appWithPreConfigReqOne.tgz\"\n      - \"/init-apps/appsWithPreConfigRequired/8_appWithPreConfigReqOne.tgz\"\n      - \"/init-apps/appsWithPreConfigRequired/9_appWithPreConfigReqOne.tgz\"\n      - \"/init-apps/appsWithPreConfigRequired/10_appWithPreConfigReqOne.tgz\"","app-list-cluster.yaml":"splunk:\n  app_paths_install:\n    idxc:\n      - \"/init-apps/securityApps/1_securityCategoryOne.tgz\"\n      - \"/init-apps/securityApps/2_securityCategoryOne.tgz\"\n      - \"/init-apps/securityApps/3_securityCategoryOne.tgz\"\n      - \"/init-apps/securityApps/4_securityCategoryOne.tgz\"\n      - \"/init-apps/securityApps/5_securityCategoryOne.tgz\"\n      - \"/init-apps/securityApps/6_securityCategoryOne.tgz\"\n      - \"/init-apps/securityApps/7_securityCategoryOne.tgz\"\n      - \"/init-apps/securityApps/8_securityCategoryOne.tgz\"\n      - \"/init-apps/securityApps/9_securityCategoryOne.tgz\"\n      - \"/init-apps/securityApps/10_securityCategoryOne.tgz\"","app-list-local.yaml":"splunk:\n  app_paths_install:\n    default:\n      - \"/init-apps/adminApps/1_adminCategoryOne.tgz\"\n      - \"/init-apps/adminApps/2_adminCategoryOne.tgz\"\n      - \"/init-apps/adminApps/3_adminCategoryOne.tgz\"\n      - \"/init-apps/adminApps/4_adminCategoryOne.tgz\"\n      - \"/init-apps/adminApps/5_adminCategoryOne.tgz\"\n      - \"/init-apps/adminApps/6_adminCategoryOne.tgz\"\n      - \"/init-apps/adminApps/7_adminCategoryOne.tgz\"\n      - \"/init-apps/adminApps/8_adminCategoryOne.tgz\"\n      - \"/init-apps/adminApps/9_adminCategoryOne.tgz\"\n      - \"/init-apps/adminApps/10_adminCategoryOne.tgz\"","appsUpdateToken":"1601945361"}}`)
-
-	// Make sure that the App Listing configMap works fine for SearchHeadCluster
-	cr.Kind = "SearchHeadCluster"
-	testAppListingConfigMap(client, &cr, &cr.Spec.AppFrameworkConfig, cr.Status.AppContext.AppsSrcDeployStatus, `{"metadata":{"name":"splunk-example-searchheadcluster-app-list","namespace":"test","creationTimestamp":null,"ownerReferences":[{"apiVersion":"","kind":"SearchHeadCluster","name":"example","uid":"","controller":true}]},"data":{"app-list-cluster-with-pre-config.yaml":"splunk:\n  apps_location:\n      - \"/init-apps/appsWithPreConfigRequired/1_appWithPreConfigReqOne.tgz\"\n      - \"/init-apps/appsWithPreConfigRequired/2_appWithPreConfigReqOne.tgz\"\n      - \"/init-apps/appsWithPreConfigRequired/3_appWithPreConfigReqOne.tgz\"\n      - \"/init-apps/appsWithPreConfigRequired/4_appWithPreConfigReqOne.tgz\"\n      - \"/init-apps/appsWithPreConfigRequired/5_appWithPreConfigReqOne.tgz\"\n      - \"/init-apps/appsWithPreConfigRequired/6_appWithPreConfigReqOne.tgz\"\n      - \"/init-apps/appsWithPreConfigRequired/7_appWithPreConfigReqOne.tgz\"\n      - \"/init-apps/appsWithPreConfigRequired/8_appWithPreConfigReqOne.tgz\"\n      - \"/init-apps/appsWithPreConfigRequired/9_appWithPreConfigReqOne.tgz\"\n      - \"/init-apps/appsWithPreConfigRequired/10_appWithPreConfigReqOne.tgz\"","app-list-cluster.yaml":"splunk:\n  app_paths_install:\n    shc:\n      - \"/init-apps/securityApps/1_securityCategoryOne.tgz\"\n      - \"/init-apps/securityApps/2_securityCategoryOne.tgz\"\n      - \"/init-apps/securityApps/3_securityCategoryOne.tgz\"\n      - \"/init-apps/securityApps/4_securityCategoryOne.tgz\"\n      - \"/init-apps/securityApps/5_securityCategoryOne.tgz\"\n      - \"/init-apps/securityApps/6_securityCategoryOne.tgz\"\n      - \"/init-apps/securityApps/7_securityCategoryOne.tgz\"\n      - \"/init-apps/securityApps/8_securityCategoryOne.tgz\"\n      - \"/init-apps/securityApps/9_securityCategoryOne.tgz\"\n      - \"/init-apps/securityApps/10_securityCategoryOne.tgz\"","app-list-local.yaml":"splunk:\n  app_paths_install:\n    default:\n      - \"/init-apps/adminApps/1_adminCategoryOne.tgz\"\n      - \"/init-apps/adminApps/2_adminCategoryOne.tgz\"\n      - \"/init-apps/adminApps/3_adminCategoryOne.tgz\"\n      - \"/init-apps/adminApps/4_adminCategoryOne.tgz\"\n      - \"/init-apps/adminApps/5_adminCategoryOne.tgz\"\n      - \"/init-apps/adminApps/6_adminCategoryOne.tgz\"\n      - \"/init-apps/adminApps/7_adminCategoryOne.tgz\"\n      - \"/init-apps/adminApps/8_adminCategoryOne.tgz\"\n      - \"/init-apps/adminApps/9_adminCategoryOne.tgz\"\n      - \"/init-apps/adminApps/10_adminCategoryOne.tgz\"","appsUpdateToken":"1601945361"}}`)
-
-	// Now test the Cluster manager stateful set, to validate the Pod updates with the app listing config map
-	cr.Kind = "ClusterMaster"
-	_, err = splutil.ApplyNamespaceScopedSecretObject(client, "test")
-	if err != nil {
-		t.Errorf("Failed to create namespace scoped object")
-	}
-
-	testStsWithAppListVolMounts := func(want string) {
-		f := func() (interface{}, error) {
-			if err := validateClusterMasterSpec(&cr); err != nil {
-				t.Errorf("validateClusterMasterSpec() returned error: %v", err)
-			}
-			return getClusterMasterStatefulSet(client, &cr)
-		}
-		configTester(t, fmt.Sprintf("getClusterMasterStatefulSet"), f, want)
-	}
-
-	testStsWithAppListVolMounts(`{"kind":"StatefulSet","apiVersion":"apps/v1","metadata":{"name":"splunk-example-cluster-master","namespace":"test","creationTimestamp":null,"ownerReferences":[{"apiVersion":"","kind":"ClusterMaster","name":"example","uid":"","controller":true}]},"spec":{"replicas":1,"selector":{"matchLabels":{"app.kubernetes.io/component":"indexer","app.kubernetes.io/instance":"splunk-example-cluster-master","app.kubernetes.io/managed-by":"splunk-operator","app.kubernetes.io/name":"cluster-master","app.kubernetes.io/part-of":"splunk-example-indexer"}},"template":{"metadata":{"creationTimestamp":null,"labels":{"app.kubernetes.io/component":"indexer","app.kubernetes.io/instance":"splunk-example-cluster-master","app.kubernetes.io/managed-by":"splunk-operator","app.kubernetes.io/name":"cluster-master","app.kubernetes.io/part-of":"splunk-example-indexer"},"annotations":{"appListingRev":"","traffic.sidecar.istio.io/excludeOutboundPorts":"8089,8191,9997","traffic.sidecar.istio.io/includeInboundPorts":"8000"}},"spec":{"volumes":[{"name":"mnt-splunk-secrets","secret":{"secretName":"splunk-example-cluster-master-secret-v1","defaultMode":420}},{"name":"mnt-app-listing","configMap":{"name":"splunk-example-clustermaster-app-list","items":[{"key":"app-list-cluster-with-pre-config.yaml","path":"app-list-cluster-with-pre-config.yaml","mode":420},{"key":"app-list-cluster.yaml","path":"app-list-cluster.yaml","mode":420},{"key":"app-list-local.yaml","path":"app-list-local.yaml","mode":420},{"key":"appsUpdateToken","path":"appsUpdateToken","mode":420}],"defaultMode":420}},{"name":"init-apps","emptyDir":{}}],"containers":[{"name":"splunk","image":"splunk/splunk","ports":[{"name":"http-splunkweb","containerPort":8000,"protocol":"TCP"},{"name":"https-splunkd","containerPort":8089,"protocol":"TCP"}],"env":[{"name":"SPLUNK_HOME","value":"/opt/splunk"},{"name":"SPLUNK_START_ARGS","value":"--accept-license"},{"name":"SPLUNK_DEFAULTS_URL","value":"/mnt/app-listing/app-list-local.yaml,/mnt/app-listing/app-list-cluster.yaml,/mnt/app-listing/app-list-cluster-with-pre-config.yaml,/mnt/splunk-secrets/default.yml"},{"name":"SPLUNK_HOME_OWNERSHIP_ENFORCEMENT","value":"false"},{"name":"SPLUNK_ROLE","value":"splunk_cluster_master"},{"name":"SPLUNK_DECLARATIVE_ADMIN_PASSWORD","value":"true"},{"name":"SPLUNK_CLUSTER_MASTER_URL","value":"localhost"}],"resources":{"limits":{"cpu":"4","memory":"8Gi"},"requests":{"cpu":"100m","memory":"512Mi"}},"volumeMounts":[{"name":"pvc-etc","mountPath":"/opt/splunk/etc"},{"name":"pvc-var","mountPath":"/opt/splunk/var"},{"name":"mnt-splunk-secrets","mountPath":"/mnt/splunk-secrets"},{"name":"mnt-app-listing","mountPath":"/mnt/app-listing/"},{"name":"init-apps","mountPath":"/init-apps/"}],"livenessProbe":{"exec":{"command":["/sbin/checkstate.sh"]},"initialDelaySeconds":1800,"timeoutSeconds":30,"periodSeconds":30},"readinessProbe":{"exec":{"command":["/bin/grep","started","/opt/container_artifact/splunk-container.state"]},"initialDelaySeconds":10,"timeoutSeconds":5,"periodSeconds":5},"imagePullPolicy":"IfNotPresent"}],"securityContext":{"runAsUser":41812,"fsGroup":41812},"affinity":{"podAntiAffinity":{"preferredDuringSchedulingIgnoredDuringExecution":[{"weight":100,"podAffinityTerm":{"labelSelector":{"matchExpressions":[{"key":"app.kubernetes.io/instance","operator":"In","values":["splunk-example-cluster-master"]}]},"topologyKey":"kubernetes.io/hostname"}}]}},"schedulerName":"default-scheduler"}},"volumeClaimTemplates":[{"metadata":{"name":"pvc-etc","namespace":"test","creationTimestamp":null,"labels":{"app.kubernetes.io/component":"indexer","app.kubernetes.io/instance":"splunk-example-cluster-master","app.kubernetes.io/managed-by":"splunk-operator","app.kubernetes.io/name":"cluster-master","app.kubernetes.io/part-of":"splunk-example-indexer"}},"spec":{"accessModes":["ReadWriteOnce"],"resources":{"requests":{"storage":"10Gi"}}},"status":{}},{"metadata":{"name":"pvc-var","namespace":"test","creationTimestamp":null,"labels":{"app.kubernetes.io/component":"indexer","app.kubernetes.io/instance":"splunk-example-cluster-master","app.kubernetes.io/managed-by":"splunk-operator","app.kubernetes.io/name":"cluster-master","app.kubernetes.io/part-of":"splunk-example-indexer"}},"spec":{"accessModes":["ReadWriteOnce"],"resources":{"requests":{"storage":"100Gi"}}},"status":{}}],"serviceName":"splunk-example-cluster-master-headless","podManagementPolicy":"Parallel","updateStrategy":{"type":"OnDelete"}},"status":{"replicas":0}}`)
-
-	// Test to ensure that the Applisting config map is empty after the apps are installed successfully
-	markAppsStatusToComplete(client, &cr, &cr.Spec.AppFrameworkConfig, cr.Status.AppContext.AppsSrcDeployStatus)
-	testAppListingConfigMap(client, &cr, &cr.Spec.AppFrameworkConfig, cr.Status.AppContext.AppsSrcDeployStatus, `{"metadata":{"name":"splunk-example-clustermaster-app-list","namespace":"test","creationTimestamp":null,"ownerReferences":[{"apiVersion":"","kind":"ClusterMaster","name":"example","uid":"","controller":true}]},"data":{"appsUpdateToken":"1601945361"}}`)
-
-}
-
 func TestRemoveOwenerReferencesForSecretObjectsReferredBySmartstoreVolumes(t *testing.T) {
 	cr := enterpriseApi.ClusterMaster{
 		ObjectMeta: metav1.ObjectMeta{
@@ -448,6 +337,94 @@ func TestGetSmartstoreRemoteVolumeSecrets(t *testing.T) {
 	}
 }
 
+func TestGetRemoteStorageCredentials(t *testing.T) {
+	secret := &corev1.Secret{Data: map[string][]byte{}}
+
+	for _, storageType := range []string{"s3", "blob", "gcs"} {
+		if _, _, err := getRemoteStorageCredentials(secret, storageType); err == nil {
+			t.Errorf("Missing %s credentials should return an error", storageType)
+		}
+	}
+
+	secret.Data[s3AccessKey] = []byte("abcd")
+	secret.Data[s3SecretKey] = []byte("1234")
+	secret.Data[azureBlobAccountName] = []byte("devstoreaccount1")
+	secret.Data[azureBlobAccountKey] = []byte("Eby8vdM02xNOcqFlqUwJPLlmEtlCDXJ1OUzFT50uSRZ6IFsuFq2UVErCz4I6tq/K1SZFPTOtr/KBHBeksoGMGw==")
+	secret.Data[gcsServiceAccountKey] = []byte(`{"type": "service_account"}`)
+	test := func(storageType string, wantAccessKey string, wantSecretKey string) {
+		accessKey, secretKey, err := getRemoteStorageCredentials(secret, storageType)
+		if err != nil || accessKey != wantAccessKey || secretKey != wantSecretKey {
+			t.Errorf("getRemoteStorageCredentials(%s) = %s, %s, %v; want %s, %s", storageType, accessKey, secretKey, err, wantAccessKey, wantSecretKey)
+		}
+	}
+	test("s3", "abcd", "1234")
+	test("blob", "devstoreaccount1", string(secret.Data[azureBlobAccountKey]))
+	test("azure-blob", "devstoreaccount1", string(secret.Data[azureBlobAccountKey]))
+
+	// a SAS token does not replace the storage account key
+	delete(secret.Data, azureBlobAccountKey)
+	secret.Data[azureSASToken] = []byte("sv=2020-08-04&ss=b&sig=abcd")
+	if _, _, err := getRemoteStorageCredentials(secret, "azure-blob"); err == nil || !strings.Contains(err.Error(), azureSASToken) {
+		t.Errorf("getRemoteStorageCredentials() = %v; want an error for the SAS token", err)
+	}
+	test("gcs", "", `{"type": "service_account"}`)
+}
+
+func TestSetupAppsStagingVolume(t *testing.T) {
+	appFrameworkConfig := enterpriseApi.AppFrameworkSpec{
+		VolList: []enterpriseApi.VolumeSpec{
+			{Name: "pvc_vol", Path: "apps-claim/splunk-apps", Type: "pvc", Provider: "kubernetes"},
+			{Name: "http_vol", Endpoint: "https://apps.example.com", Path: "splunk-apps", Type: "http", Provider: "http"},
+		},
+		AppSources: []enterpriseApi.AppSourceSpec{
+			{Name: "adminApps", Location: "adminAppsRepo", AppSourceDefaultSpec: enterpriseApi.AppSourceDefaultSpec{VolName: "pvc_vol", Scope: enterpriseApi.ScopeLocal}},
+			{Name: "securityApps", Location: "securityAppsRepo", AppSourceDefaultSpec: enterpriseApi.AppSourceDefaultSpec{VolName: "pvc_vol", Scope: enterpriseApi.ScopeLocal}},
+			{Name: "authenticationApps", Location: "authenticationAppsRepo", AppSourceDefaultSpec: enterpriseApi.AppSourceDefaultSpec{VolName: "http_vol", Scope: enterpriseApi.ScopeLocal}},
+		},
+	}
+	podTemplateSpec := corev1.PodTemplateSpec{
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "splunk", Image: "splunk/splunk:8.2.1"}}},
+	}
+
+	setupAppsStagingVolume(&podTemplateSpec, &appFrameworkConfig)
+
+	// the staging volume is added with the claim, which is added once and mounted read-only by the Splunk container
+	volumes := podTemplateSpec.Spec.Volumes
+	if len(volumes) != 2 || volumes[0].EmptyDir == nil || volumes[1].PersistentVolumeClaim.ClaimName != "apps-claim" {
+		t.Errorf("setupAppsStagingVolume() volumes = %v; want the init apps volume and the apps-claim claim", volumes)
+	}
+	splunkMounts := podTemplateSpec.Spec.Containers[0].VolumeMounts
+	if len(splunkMounts) != 2 || splunkMounts[0].MountPath != "/init-apps/" || splunkMounts[1].MountPath != "/mnt/app-repo/apps-claim" || !splunkMounts[1].ReadOnly {
+		t.Errorf("setupAppsStagingVolume() Splunk container mounts = %v; want the staging volume and the claim mounted read-only", splunkMounts)
+	}
+	if len(podTemplateSpec.Spec.InitContainers) != 0 {
+		t.Errorf("setupAppsStagingVolume() should not add any init container")
+	}
+
+	// nothing is added without app sources
+	podTemplateSpec = corev1.PodTemplateSpec{
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "splunk", Image: "splunk/splunk:8.2.1"}}},
+	}
+	setupAppsStagingVolume(&podTemplateSpec, &enterpriseApi.AppFrameworkSpec{})
+	if len(podTemplateSpec.Spec.Volumes) != 0 {
+		t.Errorf("setupAppsStagingVolume() volumes = %v; want none without app sources", podTemplateSpec.Spec.Volumes)
+	}
+}
+
+func TestGetAppFrameworkPodName(t *testing.T) {
+	meta := metav1.ObjectMeta{Name: "stack1", Namespace: "test"}
+	test := func(cr splcommon.MetaObject, want string) {
+		if got := getAppFrameworkPodName(cr); got != want {
+			t.Errorf("getAppFrameworkPodName() = %s; want %s", got, want)
+		}
+	}
+
+	test(&enterpriseApi.Standalone{ObjectMeta: meta}, "splunk-stack1-standalone-0")
+	test(&enterpriseApi.LicenseMaster{ObjectMeta: meta}, "splunk-stack1-license-master-0")
+	test(&enterpriseApi.ClusterMaster{ObjectMeta: meta}, "splunk-stack1-cluster-master-0")
+	test(&enterpriseApi.SearchHeadCluster{ObjectMeta: meta}, "splunk-stack1-deployer-0")
+}
+
 func TestCheckIfAnAppIsActiveOnRemoteStore(t *testing.T) {
 	var remoteObjList []*splclient.RemoteObject
 	var entry *splclient.RemoteObject
@@ -467,6 +444,33 @@ func TestCheckIfAnAppIsActiveOnRemoteStore(t *testing.T) {
 
 }
 
+func TestPinAppSrcApps(t *testing.T) {
+	remoteObjList := []*splclient.RemoteObject{
+		allocateRemoteObject("d41d8cd98f00", "adminAppsRepo/app1.tgz", 2322, nil),
+		allocateRemoteObject("e2fc714c4727", "adminAppsRepo/app2.tgz", 1024, nil),
+		allocateRemoteObject("9e107d9d372b", "adminAppsRepo/app3.spl", 512, nil),
+	}
+
+	// without selected Apps, the listing is left unchanged
+	appSource := enterpriseApi.AppSourceSpec{Name: "adminApps", Location: "adminAppsRepo"}
+	if pinned := pinAppSrcApps(appSource, remoteObjList); len(pinned) != 3 {
+		t.Errorf("pinAppSrcApps() returned %d apps; want 3", len(pinned))
+	}
+
+	// only the selected Apps are listed, with the hash of their pinned version
+	appSource.Apps = []enterpriseApi.AppVersionSpec{{Name: "app1.tgz"}, {Name: "app3.spl", ObjectHash: "0cc175b9c0f1"}, {Name: "app4.tgz"}}
+	pinned := pinAppSrcApps(appSource, remoteObjList)
+	if len(pinned) != 2 || *pinned[0].Key != "adminAppsRepo/app1.tgz" || *pinned[0].Etag != "d41d8cd98f00" {
+		t.Fatalf("pinAppSrcApps() returned %v; want app1.tgz with its listed hash and app3.spl", pinned)
+	}
+	if *pinned[1].Key != "adminAppsRepo/app3.spl" || *pinned[1].Etag != "0cc175b9c0f1" || pinned[1].Size != nil {
+		t.Errorf("pinAppSrcApps() returned app3.spl with hash %s; want its pinned hash", *pinned[1].Etag)
+	}
+	if *remoteObjList[2].Etag != "9e107d9d372b" {
+		t.Errorf("pinAppSrcApps() should not modify the remote listing")
+	}
+}
+
 func TestHandleAppRepoChanges(t *testing.T) {
 	cr := enterpriseApi.Standalone{
 		ObjectMeta: metav1.ObjectMeta{
@@ -630,50 +634,6 @@ func TestHandleAppRepoChanges(t *testing.T) {
 	}
 	delete(remoteObjListMap, invalidAppSourceName)
 
-	// Test-10: Setting  all apps in AppSrc to complete should mark all the apps status as complete irrespective of their state
-	// 10.1 Check for state=Active and status=Complete
-	for appSrc, appSrcDeployStatus := range appDeployContext.AppsSrcDeployStatus {
-		// ToDo: Enable for Phase-3
-		//setStateAndStatusForAppDeployInfoList(appSrcDeployStatus.AppDeploymentInfoList, enterpriseApi.RepoStateActive, enterpriseApi.DeployStatusInProgress)
-		setStateAndStatusForAppDeployInfoList(appSrcDeployStatus.AppDeploymentInfoList, enterpriseApi.RepoStateActive, enterpriseApi.DeployStatusPending)
-		appDeployContext.AppsSrcDeployStatus[appSrc] = appSrcDeployStatus
-
-		// ToDo: Enable for Phase-3
-		//expectedMatchCount := getAppSrcDeployInfoCountByStateAndStatus(appSrc, appDeployContext.AppsSrcDeployStatus, enterpriseApi.RepoStateActive, enterpriseApi.DeployStatusInProgress)
-		expectedMatchCount := getAppSrcDeployInfoCountByStateAndStatus(appSrc, appDeployContext.AppsSrcDeployStatus, enterpriseApi.RepoStateActive, enterpriseApi.DeployStatusPending)
-
-		markAppsStatusToComplete(client, &cr, &cr.Spec.AppFrameworkConfig, appDeployContext.AppsSrcDeployStatus)
-
-		matchCount, err := validateAppSrcDeployInfoByStateAndStatus(appSrc, appDeployContext.AppsSrcDeployStatus, enterpriseApi.RepoStateActive, enterpriseApi.DeployStatusComplete)
-		if err != nil {
-			t.Errorf("Unable to change the Apps status to complete, once the changes are reflecting on the Pod. Error: %v", err)
-		}
-		if expectedMatchCount != matchCount {
-			t.Errorf("App status change failed. Expected count %v, returned count %v", expectedMatchCount, matchCount)
-		}
-	}
-
-	// 10.2 Check for state=Deleted status=Complete
-	for appSrc, appSrcDeployStatus := range appDeployContext.AppsSrcDeployStatus {
-		// ToDo: Enable for Phase-3
-		//setStateAndStatusForAppDeployInfoList(appSrcDeployStatus.AppDeploymentInfoList, enterpriseApi.RepoStateActive, enterpriseApi.DeployStatusInProgress)
-		setStateAndStatusForAppDeployInfoList(appSrcDeployStatus.AppDeploymentInfoList, enterpriseApi.RepoStateDeleted, enterpriseApi.DeployStatusPending)
-		appDeployContext.AppsSrcDeployStatus[appSrc] = appSrcDeployStatus
-
-		// ToDo: Enable for Phase-3
-		//expectedMatchCount := getAppSrcDeployInfoCountByStateAndStatus(appSrc, appDeployContext.AppsSrcDeployStatus, enterpriseApi.RepoStateDeleted, enterpriseApi.DeployStatusInProgress)
-		expectedMatchCount := getAppSrcDeployInfoCountByStateAndStatus(appSrc, appDeployContext.AppsSrcDeployStatus, enterpriseApi.RepoStateDeleted, enterpriseApi.DeployStatusPending)
-
-		markAppsStatusToComplete(client, &cr, &cr.Spec.AppFrameworkConfig, appDeployContext.AppsSrcDeployStatus)
-
-		matchCount, err := validateAppSrcDeployInfoByStateAndStatus(appSrc, appDeployContext.AppsSrcDeployStatus, enterpriseApi.RepoStateDeleted, enterpriseApi.DeployStatusComplete)
-		if err != nil {
-			t.Errorf("Unable to delete/disable an app when the App is deleted from remote store. Error: %v", err)
-		}
-		if expectedMatchCount != matchCount {
-			t.Errorf("App status change failed. Expected count %v, returned count %v", expectedMatchCount, matchCount)
-		}
-	}
 }
 
 func TestIsAppExtentionValid(t *testing.T) {
@@ -686,6 +646,19 @@ func TestIsAppExtentionValid(t *testing.T) {
 	}
 }
 
+func TestIsAppNameValid(t *testing.T) {
+	for _, name := range []string{"testapp.spl", "TA-test_app-1.2.3.tgz", "SplunkEnterpriseSecuritySuite"} {
+		if !isAppNameValid(name) {
+			t.Errorf("isAppNameValid(%s) = false; want true", name)
+		}
+	}
+	for _, name := range []string{"", "..", "test..app.tgz", "test app.tgz", "test'app.tgz", "$(id).tgz", "apps/testapp.tgz"} {
+		if isAppNameValid(name) {
+			t.Errorf("isAppNameValid(%s) = true; want false", name)
+		}
+	}
+}
+
 func TestHasAppRepoCheckTimerExpired(t *testing.T) {
 
 	// Case 1. This is the case when we first enter the reconcile loop.
@@ -0,0 +1,175 @@
+// Copyright (c) 2018-2021 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enterprise
+
+import (
+	"fmt"
+	"reflect"
+
+	enterpriseApi "github.com/splunk/splunk-operator/pkg/apis/enterprise/v2"
+	splcommon "github.com/splunk/splunk-operator/pkg/splunk/common"
+)
+
+// SetDefaults sets default values for the parameters of a Splunk Enterprise custom resource that are not provided.
+func SetDefaults(cr splcommon.MetaObject) error {
+	switch cr := cr.(type) {
+	case *enterpriseApi.Standalone:
+		setStandaloneDefaults(cr)
+	case *enterpriseApi.LicenseMaster:
+		setLicenseMasterDefaults(cr)
+	case *enterpriseApi.SearchHeadCluster:
+		setSearchHeadClusterDefaults(cr)
+	case *enterpriseApi.ClusterMaster:
+		setClusterMasterDefaults(cr)
+	case *enterpriseApi.IndexerCluster:
+		setIndexerClusterDefaults(cr)
+	default:
+		return fmt.Errorf("Unsupported custom resource kind %s", cr.GetObjectKind().GroupVersionKind().Kind)
+	}
+	return nil
+}
+
+// ValidateSpec checks validity of the spec of a Splunk Enterprise custom resource, and returns error if something is wrong.
+// Parameters that are not provided are validated using their default values, but the custom resource is left unchanged.
+func ValidateSpec(cr splcommon.MetaObject) error {
+	cr = cr.DeepCopyObject().(splcommon.MetaObject)
+	if err := SetDefaults(cr); err != nil {
+		return err
+	}
+
+	switch cr := cr.(type) {
+	case *enterpriseApi.Standalone:
+		return validateStandaloneSpec(cr)
+	case *enterpriseApi.LicenseMaster:
+		return validateLicenseMasterSpec(cr)
+	case *enterpriseApi.SearchHeadCluster:
+		return validateSearchHeadClusterSpec(cr)
+	case *enterpriseApi.ClusterMaster:
+		return validateClusterMasterSpec(cr)
+	case *enterpriseApi.IndexerCluster:
+		return validateIndexerClusterSpec(cr)
+	}
+	return nil
+}
+
+// ValidateSpecUpdate checks that a Splunk Enterprise custom resource can be updated from its old version, and returns error
+// if the update is invalid or can not be applied safely.
+func ValidateSpecUpdate(cr, old splcommon.MetaObject) error {
+	// changes that leave the spec untouched, like removing finalizers, are never blocked
+	if reflect.DeepEqual(getSpec(cr), getSpec(old)) {
+		return nil
+	}
+
+	spec, err := getCommonSplunkSpec(cr)
+	if err != nil {
+		return err
+	}
+	oldSpec, err := getCommonSplunkSpec(old)
+	if err != nil {
+		return err
+	}
+
+	// the volumes of standalones and search heads can be moved to new storage, see storageMigration
+	var migratable bool
+	switch cr.(type) {
+	case *enterpriseApi.Standalone, *enterpriseApi.SearchHeadCluster:
+		migratable = true
+	}
+
+	err = validateStorageUpdate("etcVolumeStorageConfig", &spec.EtcVolumeStorageConfig, &oldSpec.EtcVolumeStorageConfig, splcommon.DefaultEtcVolumeStorageCapacity, migratable)
+	if err != nil {
+		return err
+	}
+
+	err = validateStorageUpdate("varVolumeStorageConfig", &spec.VarVolumeStorageConfig, &oldSpec.VarVolumeStorageConfig, splcommon.DefaultVarVolumeStorageCapacity, migratable)
+	if err != nil {
+		return err
+	}
+
+	return ValidateSpec(cr)
+}
+
+// validateStorageUpdate checks that the storage of the existing volumes is not changed in a way that loses or orphans data.
+// Migratable volumes can move from ephemeral to persistent storage, or to another storage class, as their data is copied.
+func validateStorageUpdate(name string, storage, oldStorage *enterpriseApi.StorageClassSpec, defaultCapacity string, migratable bool) error {
+	if storage.EphemeralStorage && !oldStorage.EphemeralStorage {
+		return fmt.Errorf("%s: ephemeralStorage can not be changed from false to true without losing the data of the volumes", name)
+	}
+
+	// ephemeral storage does not use a storage class nor a capacity
+	if storage.EphemeralStorage {
+		return nil
+	}
+
+	if oldStorage.EphemeralStorage {
+		if !migratable {
+			return fmt.Errorf("%s: ephemeralStorage can not be changed from true to false", name)
+		}
+		return nil
+	}
+
+	if storage.StorageClassName != oldStorage.StorageClassName && !migratable {
+		return fmt.Errorf("%s: storageClassName can not be changed from \"%s\" to \"%s\"", name, oldStorage.StorageClassName, storage.StorageClassName)
+	}
+
+	capacity, err := splcommon.ParseResourceQuantity(storage.StorageCapacity, defaultCapacity)
+	if err != nil {
+		return fmt.Errorf("%s: %s", name, err)
+	}
+	oldCapacity, err := splcommon.ParseResourceQuantity(oldStorage.StorageCapacity, defaultCapacity)
+	if err != nil {
+		// the old capacity was never applied, so there is nothing to compare with
+		return nil
+	}
+	if capacity.Cmp(oldCapacity) < 0 {
+		return fmt.Errorf("%s: storageCapacity can not be reduced from %s to %s", name, oldCapacity.String(), capacity.String())
+	}
+
+	return nil
+}
+
+// getCommonSplunkSpec returns the CommonSplunkSpec of a Splunk Enterprise custom resource
+func getCommonSplunkSpec(cr splcommon.MetaObject) (*enterpriseApi.CommonSplunkSpec, error) {
+	switch cr := cr.(type) {
+	case *enterpriseApi.Standalone:
+		return &cr.Spec.CommonSplunkSpec, nil
+	case *enterpriseApi.LicenseMaster:
+		return &cr.Spec.CommonSplunkSpec, nil
+	case *enterpriseApi.SearchHeadCluster:
+		return &cr.Spec.CommonSplunkSpec, nil
+	case *enterpriseApi.ClusterMaster:
+		return &cr.Spec.CommonSplunkSpec, nil
+	case *enterpriseApi.IndexerCluster:
+		return &cr.Spec.CommonSplunkSpec, nil
+	}
+	return nil, fmt.Errorf("Unsupported custom resource kind %s", cr.GetObjectKind().GroupVersionKind().Kind)
+}
+
+// getSpec returns the spec of a Splunk Enterprise custom resource
+func getSpec(cr splcommon.MetaObject) interface{} {
+	switch cr := cr.(type) {
+	case *enterpriseApi.Standalone:
+		return cr.Spec
+	case *enterpriseApi.LicenseMaster:
+		return cr.Spec
+	case *enterpriseApi.SearchHeadCluster:
+		return cr.Spec
+	case *enterpriseApi.ClusterMaster:
+		return cr.Spec
+	case *enterpriseApi.IndexerCluster:
+		return cr.Spec
+	}
+	return nil
+}
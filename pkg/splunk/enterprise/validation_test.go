@@ -0,0 +1,197 @@
+// Copyright (c) 2018-2021 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enterprise
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	enterpriseApi "github.com/splunk/splunk-operator/pkg/apis/enterprise/v2"
+	splcommon "github.com/splunk/splunk-operator/pkg/splunk/common"
+)
+
+func TestValidateSpec(t *testing.T) {
+	test := func(cr splcommon.MetaObject, wantErr bool) {
+		err := ValidateSpec(cr)
+		if wantErr && err == nil {
+			t.Errorf("ValidateSpec(%s) returned nil; want error", cr.GetName())
+		} else if !wantErr && err != nil {
+			t.Errorf("ValidateSpec(%s) returned %v; want nil", cr.GetName(), err)
+		}
+	}
+
+	standalone := enterpriseApi.Standalone{
+		ObjectMeta: metav1.ObjectMeta{Name: "standalone", Namespace: "test"},
+	}
+	test(&standalone, false)
+
+	// the custom resource must not be defaulted
+	if standalone.Spec.Replicas != 0 {
+		t.Errorf("ValidateSpec() changed Replicas to %d; want 0", standalone.Spec.Replicas)
+	}
+
+	standalone.Spec.ImagePullPolicy = "Sometimes"
+	test(&standalone, true)
+
+	idxc := enterpriseApi.IndexerCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "idxc", Namespace: "test"},
+	}
+	test(&idxc, true)
+	idxc.Spec.ClusterMasterRef.Name = "cm"
+	test(&idxc, false)
+
+	cm := enterpriseApi.ClusterMaster{
+		ObjectMeta: metav1.ObjectMeta{Name: "cm", Namespace: "test"},
+	}
+	cm.Spec.SmartStore.VolList = []enterpriseApi.VolumeSpec{{Name: "msos_s2s3_vol", Path: "testbucket-rs-london"}}
+	test(&cm, true)
+
+	shc := enterpriseApi.SearchHeadCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "shc", Namespace: "test"},
+	}
+	test(&shc, false)
+	shc.Spec.PvcRetentionPolicy = "Keep"
+	test(&shc, true)
+
+	lm := enterpriseApi.LicenseMaster{
+		ObjectMeta: metav1.ObjectMeta{Name: "lm", Namespace: "test"},
+	}
+	lm.Spec.LivenessInitialDelaySeconds = -1
+	test(&lm, true)
+}
+
+func TestValidateSpecUpdate(t *testing.T) {
+	old := enterpriseApi.Standalone{
+		ObjectMeta: metav1.ObjectMeta{Name: "standalone", Namespace: "test"},
+	}
+	old.Spec.EtcVolumeStorageConfig.StorageClassName = "gp2"
+	old.Spec.VarVolumeStorageConfig.StorageCapacity = "50Gi"
+
+	test := func(update func(cr *enterpriseApi.Standalone), wantErr bool) {
+		cr := old.DeepCopy()
+		update(cr)
+		err := ValidateSpecUpdate(cr, &old)
+		if wantErr && err == nil {
+			t.Errorf("ValidateSpecUpdate() returned nil; want error")
+		} else if !wantErr && err != nil {
+			t.Errorf("ValidateSpecUpdate() returned %v; want nil", err)
+		}
+	}
+
+	test(func(cr *enterpriseApi.Standalone) {}, false)
+	test(func(cr *enterpriseApi.Standalone) { cr.Spec.Replicas = 3 }, false)
+	test(func(cr *enterpriseApi.Standalone) { cr.Spec.EtcVolumeStorageConfig.EphemeralStorage = true }, true)
+	test(func(cr *enterpriseApi.Standalone) { cr.Spec.VarVolumeStorageConfig.StorageCapacity = "100Gi" }, false)
+	test(func(cr *enterpriseApi.Standalone) { cr.Spec.VarVolumeStorageConfig.StorageCapacity = "20Gi" }, true)
+	test(func(cr *enterpriseApi.Standalone) { cr.Spec.EtcVolumeStorageConfig.StorageCapacity = "10Gi" }, false)
+	test(func(cr *enterpriseApi.Standalone) { cr.Spec.EtcVolumeStorageConfig.StorageCapacity = "5Gi" }, true)
+	test(func(cr *enterpriseApi.Standalone) { cr.Spec.ImagePullPolicy = "Sometimes" }, true)
+
+	// metadata changes are accepted even if the spec is not valid
+	old.Spec.ImagePullPolicy = "Sometimes"
+	test(func(cr *enterpriseApi.Standalone) { cr.ObjectMeta.Finalizers = nil }, false)
+	old.Spec.ImagePullPolicy = ""
+
+	// the volumes of a standalone move to another storage class, or from ephemeral to persistent storage
+	test(func(cr *enterpriseApi.Standalone) { cr.Spec.EtcVolumeStorageConfig.StorageClassName = "gp3" }, false)
+	test(func(cr *enterpriseApi.Standalone) { cr.Spec.VarVolumeStorageConfig.StorageClassName = "gp2" }, false)
+
+	// the storage class and capacity of ephemeral storage are not used
+	old.Spec.EtcVolumeStorageConfig.EphemeralStorage = true
+	test(func(cr *enterpriseApi.Standalone) { cr.Spec.EtcVolumeStorageConfig.StorageClassName = "" }, false)
+	test(func(cr *enterpriseApi.Standalone) {
+		cr.Spec.EtcVolumeStorageConfig.EphemeralStorage = false
+		cr.Spec.EtcVolumeStorageConfig.StorageClassName = "gp2"
+	}, false)
+}
+
+func TestValidateSpecUpdateStorageMigration(t *testing.T) {
+	ephemeral := enterpriseApi.StorageClassSpec{EphemeralStorage: true}
+	gp2 := enterpriseApi.StorageClassSpec{StorageClassName: "gp2"}
+	gp3 := enterpriseApi.StorageClassSpec{StorageClassName: "gp3"}
+
+	test := func(old splcommon.MetaObject, update func(spec *enterpriseApi.CommonSplunkSpec), wantErr bool) {
+		cr := old.DeepCopyObject().(splcommon.MetaObject)
+		spec, _ := getCommonSplunkSpec(cr)
+		update(spec)
+		err := ValidateSpecUpdate(cr, old)
+		if wantErr && err == nil {
+			t.Errorf("ValidateSpecUpdate(%T) returned nil; want error", cr)
+		} else if !wantErr && err != nil {
+			t.Errorf("ValidateSpecUpdate(%T) returned %v; want nil", cr, err)
+		}
+	}
+	toEphemeral := func(spec *enterpriseApi.CommonSplunkSpec) { spec.VarVolumeStorageConfig = ephemeral }
+	toPersistent := func(spec *enterpriseApi.CommonSplunkSpec) { spec.VarVolumeStorageConfig = gp2 }
+	toGp3 := func(spec *enterpriseApi.CommonSplunkSpec) { spec.VarVolumeStorageConfig = gp3 }
+
+	// standalones and search head clusters move their volumes to new storage
+	standalone := &enterpriseApi.Standalone{ObjectMeta: metav1.ObjectMeta{Name: "standalone", Namespace: "test"}}
+	shc := &enterpriseApi.SearchHeadCluster{ObjectMeta: metav1.ObjectMeta{Name: "shc", Namespace: "test"}}
+	for _, cr := range []splcommon.MetaObject{standalone, shc} {
+		spec, _ := getCommonSplunkSpec(cr)
+		spec.VarVolumeStorageConfig = ephemeral
+		test(cr, toPersistent, false)
+		spec.VarVolumeStorageConfig = gp2
+		test(cr, toGp3, false)
+		test(cr, toEphemeral, true)
+	}
+
+	// the other custom resources keep the storage of their volumes
+	cm := &enterpriseApi.ClusterMaster{ObjectMeta: metav1.ObjectMeta{Name: "cm", Namespace: "test"}}
+	idxc := &enterpriseApi.IndexerCluster{ObjectMeta: metav1.ObjectMeta{Name: "idxc", Namespace: "test"}}
+	idxc.Spec.ClusterMasterRef.Name = "cm"
+	for _, cr := range []splcommon.MetaObject{cm, idxc} {
+		spec, _ := getCommonSplunkSpec(cr)
+		spec.VarVolumeStorageConfig = ephemeral
+		test(cr, func(spec *enterpriseApi.CommonSplunkSpec) { spec.ImagePullPolicy = "Always" }, false)
+		test(cr, toPersistent, true)
+		spec.VarVolumeStorageConfig = gp2
+		test(cr, toGp3, true)
+		test(cr, toEphemeral, true)
+	}
+}
+
+func TestSetDefaults(t *testing.T) {
+	shc := enterpriseApi.SearchHeadCluster{}
+	shc.Spec.AppFrameworkConfig.AppSources = []enterpriseApi.AppSourceSpec{{Name: "adminApps", Location: "adminAppsRepo"}}
+	if err := SetDefaults(&shc); err != nil {
+		t.Errorf("SetDefaults() returned %v; want nil", err)
+	}
+	if shc.Spec.Replicas != 3 {
+		t.Errorf("SetDefaults() Replicas = %d; want 3", shc.Spec.Replicas)
+	}
+	if shc.Spec.ImagePullPolicy != "IfNotPresent" {
+		t.Errorf("SetDefaults() ImagePullPolicy = %s; want IfNotPresent", shc.Spec.ImagePullPolicy)
+	}
+	if shc.Spec.AppFrameworkConfig.AppsRepoPollInterval != splcommon.DefaultAppsRepoPollInterval {
+		t.Errorf("SetDefaults() AppsRepoPollInterval = %d; want %d", shc.Spec.AppFrameworkConfig.AppsRepoPollInterval, splcommon.DefaultAppsRepoPollInterval)
+	}
+
+	// the image is resolved when the pods are created, so that it follows the operator's default
+	if shc.Spec.Image != "" {
+		t.Errorf("SetDefaults() Image = %s; want empty", shc.Spec.Image)
+	}
+
+	cm := enterpriseApi.ClusterMaster{}
+	cm.Spec.Multisite.AvailableSites = []string{"site2", "site1"}
+	if err := SetDefaults(&cm); err != nil {
+		t.Errorf("SetDefaults() returned %v; want nil", err)
+	}
+	if cm.Spec.Multisite.Site != "site2" || cm.Spec.Multisite.SiteReplicationFactor.Origin != 2 {
+		t.Errorf("SetDefaults() Multisite = %v; want site2 with origin 2", cm.Spec.Multisite)
+	}
+}
@@ -16,10 +16,13 @@ package test
 
 import (
 	"encoding/json"
+	"io/ioutil"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/s3"
 	enterpriseApi "github.com/splunk/splunk-operator/pkg/apis/enterprise/v2"
 )
@@ -31,11 +34,17 @@ type MockAWSS3Object struct {
 	LastModified *time.Time
 	Size         *int64
 	StorageClass *string
+
+	// content returned by GetObject, which is empty by default
+	Body string `json:"-"`
 }
 
 // MockAWSS3Client is used to store all the objects for an app source
 type MockAWSS3Client struct {
 	Objects []*MockAWSS3Object
+
+	// previous versions of the objects, for a bucket with versioning enabled
+	PreviousVersions []*MockAWSS3Object
 }
 
 // MockAWSS3Handler is used for checking response received
@@ -101,3 +110,37 @@ func (mockClient MockAWSS3Client) ListObjectsV2(options *s3.ListObjectsV2Input)
 
 	return output, nil
 }
+
+// ListObjectVersions is a mock call to ListObjectVersions, listing the objects and their previous versions. The
+// version ID of a version is its etag.
+func (mockClient MockAWSS3Client) ListObjectVersions(options *s3.ListObjectVersionsInput) (*s3.ListObjectVersionsOutput, error) {
+	output := &s3.ListObjectVersionsOutput{}
+	for _, object := range append(mockClient.Objects, mockClient.PreviousVersions...) {
+		if options.Prefix != nil && !strings.HasPrefix(*object.Key, *options.Prefix) {
+			continue
+		}
+		output.Versions = append(output.Versions, &s3.ObjectVersion{ETag: object.Etag, Key: object.Key, VersionId: object.Etag})
+	}
+
+	return output, nil
+}
+
+// GetObject is a mock call to GetObject, returning the body of the objects of the mock client, or of their previous
+// versions when a version ID is given
+func (mockClient MockAWSS3Client) GetObject(options *s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+	objects := mockClient.Objects
+	if options.VersionId != nil {
+		objects = append(objects, mockClient.PreviousVersions...)
+	}
+	for _, object := range objects {
+		if *object.Key != *options.Key || (options.VersionId != nil && *options.VersionId != *object.Etag) {
+			continue
+		}
+		if options.IfMatch != nil && *options.IfMatch != *object.Etag {
+			return nil, awserr.New("PreconditionFailed", "At least one of the pre-conditions you specified did not hold", nil)
+		}
+		return &s3.GetObjectOutput{Body: ioutil.NopCloser(strings.NewReader(object.Body)), ETag: object.Etag}, nil
+	}
+
+	return nil, awserr.New(s3.ErrCodeNoSuchKey, "The specified key does not exist.", nil)
+}
@@ -24,7 +24,10 @@ import (
 	enterpriseApi "github.com/splunk/splunk-operator/pkg/apis/enterprise/v2"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	storagev1 "k8s.io/api/storage/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -32,7 +35,7 @@ import (
 )
 
 func init() {
-	MockObjectCopiers = append(MockObjectCopiers, coreObjectCopier, appsObjectCopier, enterpriseObjCopier)
+	MockObjectCopiers = append(MockObjectCopiers, coreObjectCopier, appsObjectCopier, enterpriseObjCopier, policyObjectCopier, storageObjectCopier, unstructuredObjectCopier)
 }
 
 // MockObjectCopiers is a slice of MockObjectCopier methods that MockClient uses to copy runtime.Objects
@@ -46,6 +49,9 @@ type MockObjectCopier func(dst, src *runtime.Object) bool
 func enterpriseObjCopier(dst, src *runtime.Object) bool {
 	dstP := *dst
 	srcP := *src
+	if reflect.TypeOf(dstP) != reflect.TypeOf(srcP) {
+		return false
+	}
 	switch srcP.(type) {
 	case *enterpriseApi.ClusterMaster:
 		*dstP.(*enterpriseApi.ClusterMaster) = *srcP.(*enterpriseApi.ClusterMaster)
@@ -57,6 +63,16 @@ func enterpriseObjCopier(dst, src *runtime.Object) bool {
 		*dstP.(*enterpriseApi.SearchHeadCluster) = *srcP.(*enterpriseApi.SearchHeadCluster)
 	case *enterpriseApi.Standalone:
 		*dstP.(*enterpriseApi.Standalone) = *srcP.(*enterpriseApi.Standalone)
+	case *enterpriseApi.ClusterMasterList:
+		*dstP.(*enterpriseApi.ClusterMasterList) = *srcP.(*enterpriseApi.ClusterMasterList)
+	case *enterpriseApi.IndexerClusterList:
+		*dstP.(*enterpriseApi.IndexerClusterList) = *srcP.(*enterpriseApi.IndexerClusterList)
+	case *enterpriseApi.LicenseMasterList:
+		*dstP.(*enterpriseApi.LicenseMasterList) = *srcP.(*enterpriseApi.LicenseMasterList)
+	case *enterpriseApi.SearchHeadClusterList:
+		*dstP.(*enterpriseApi.SearchHeadClusterList) = *srcP.(*enterpriseApi.SearchHeadClusterList)
+	case *enterpriseApi.StandaloneList:
+		*dstP.(*enterpriseApi.StandaloneList) = *srcP.(*enterpriseApi.StandaloneList)
 	default:
 		return false
 	}
@@ -107,6 +123,49 @@ func appsObjectCopier(dst, src *runtime.Object) bool {
 	return true
 }
 
+// policyObjectCopier is used to copy policyv1beta1 runtime.Objects
+func policyObjectCopier(dst, src *runtime.Object) bool {
+	srcP := *src
+	dstP := *dst
+	switch srcP.(type) {
+	case *policyv1beta1.PodDisruptionBudget:
+		*dstP.(*policyv1beta1.PodDisruptionBudget) = *srcP.(*policyv1beta1.PodDisruptionBudget)
+	default:
+		return false
+	}
+	return true
+}
+
+// storageObjectCopier is used to copy storagev1 runtime.Objects
+func storageObjectCopier(dst, src *runtime.Object) bool {
+	srcP := *src
+	dstP := *dst
+	switch srcP.(type) {
+	case *storagev1.StorageClass:
+		*dstP.(*storagev1.StorageClass) = *srcP.(*storagev1.StorageClass)
+	case *storagev1.StorageClassList:
+		*dstP.(*storagev1.StorageClassList) = *srcP.(*storagev1.StorageClassList)
+	default:
+		return false
+	}
+	return true
+}
+
+// unstructuredObjectCopier is used to copy unstructured runtime.Objects
+func unstructuredObjectCopier(dst, src *runtime.Object) bool {
+	srcP := *src
+	dstP := *dst
+	switch srcP.(type) {
+	case *unstructured.Unstructured:
+		srcP.(*unstructured.Unstructured).DeepCopyInto(dstP.(*unstructured.Unstructured))
+	case *unstructured.UnstructuredList:
+		srcP.(*unstructured.UnstructuredList).DeepCopyInto(dstP.(*unstructured.UnstructuredList))
+	default:
+		return false
+	}
+	return true
+}
+
 // copyMockObject uses the global MockObjectCopiers to perform the typed copy of a runtime.Object from src to dst
 func copyMockObject(dst, src *runtime.Object) {
 	for n := range MockObjectCopiers {
@@ -347,8 +406,8 @@ func NewMockClient() *MockClient {
 // getStateKeyFromObject returns a lookup key for the MockClient's state map
 func getStateKey(obj runtime.Object) string {
 	key := client.ObjectKey{
-		Name:      obj.(splcommon.MetaObject).GetName(),
-		Namespace: obj.(splcommon.MetaObject).GetNamespace(),
+		Name:      obj.(metav1.Object).GetName(),
+		Namespace: obj.(metav1.Object).GetNamespace(),
 	}
 	return getStateKeyWithKey(key, obj)
 }
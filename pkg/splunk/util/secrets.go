@@ -167,6 +167,12 @@ func RemoveSecretOwnerRef(client splcommon.ControllerClient, secretObjectName st
 
 // RemoveUnwantedSecrets deletes all secrets whose version preceeds (latestVersion - MinimumVersionedSecrets)
 func RemoveUnwantedSecrets(c splcommon.ControllerClient, versionedSecretIdentifier, namespace string) error {
+	return RemoveUnwantedSecretsExcept(c, versionedSecretIdentifier, namespace, nil)
+}
+
+// RemoveUnwantedSecretsExcept deletes all secrets whose version preceeds (latestVersion - MinimumVersionedSecrets),
+// except the secrets that are still in use, e.g. by pods whose update is held
+func RemoveUnwantedSecretsExcept(c splcommon.ControllerClient, versionedSecretIdentifier, namespace string, inUse map[string]bool) error {
 	// retrieve the list of versioned namespace scoped secrets
 	_, latestVersion, list := GetExistingLatestVersionedSecret(c, namespace, versionedSecretIdentifier, true)
 	if latestVersion != -1 {
@@ -177,7 +183,7 @@ func RemoveUnwantedSecrets(c splcommon.ControllerClient, versionedSecretIdentifi
 
 		// Atleast one exists
 		for version, secret := range list {
-			if (latestVersion - version) >= splcommon.MinimumVersionedSecrets {
+			if (latestVersion-version) >= splcommon.MinimumVersionedSecrets && !inUse[secret.GetName()] {
 				// Delete secret
 				err := DeleteResource(c, &secret)
 				if err != nil {
@@ -445,6 +445,17 @@ func TestRemoveUnwantedSecrets(t *testing.T) {
 	}
 	secretList.Items = append(secretList.Items, current)
 
+	// Remove unwanted secrets except the ones in use, keeps v1
+	v1 := splcommon.GetVersionedSecretName(versionedSecretIdentifier, "1")
+	err = RemoveUnwantedSecretsExcept(c, versionedSecretIdentifier, "test", map[string]bool{v1: true})
+	if err != nil {
+		t.Errorf("Failed to remove unwanted secrets")
+	}
+	err = c.Get(context.TODO(), types.NamespacedName{Namespace: "test", Name: v1}, &current)
+	if err != nil {
+		t.Errorf("Didn't find secret %s, deleted a secret in use", v1)
+	}
+
 	// Remove unwanted secrets, removes v1
 	err = RemoveUnwantedSecrets(c, versionedSecretIdentifier, "test")
 	if err != nil {
@@ -17,6 +17,8 @@ package util
 import (
 	"bytes"
 	"context"
+	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"strings"
@@ -199,3 +201,48 @@ func PodExecCommand(c splcommon.ControllerClient, podName string, namespace stri
 	}
 	return stdout.String(), stderr.String(), nil
 }
+
+// PodExecStream executes a command in the specified pod, streaming its standard input and output instead of
+// buffering them, for commands transferring large amounts of data
+func PodExecStream(c splcommon.ControllerClient, podName string, namespace string, cmd []string, stdin io.Reader, stdout io.Writer) error {
+	var pod corev1.Pod
+	namespacedName := types.NamespacedName{Namespace: namespace, Name: podName}
+	err := c.Get(context.TODO(), namespacedName, &pod)
+	if err != nil {
+		return err
+	}
+
+	gvk, _ := apiutil.GVKForObject(&pod, scheme.Scheme)
+	restConfig, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	restClient, err := apiutil.RESTClientForGVK(gvk, restConfig, serializer.NewCodecFactory(scheme.Scheme))
+	if err != nil {
+		return err
+	}
+	execReq := restClient.Post().Resource("pods").Name(podName).Namespace(namespace).SubResource("exec")
+	execReq.VersionedParams(
+		&corev1.PodExecOptions{
+			Command: cmd,
+			Stdin:   stdin != nil,
+			Stdout:  true,
+			Stderr:  true,
+		},
+		scheme.ParameterCodec,
+	)
+	exec, err := remotecommand.NewSPDYExecutor(restConfig, http.MethodPost, execReq.URL())
+	if err != nil {
+		return err
+	}
+	stderr := new(bytes.Buffer)
+	err = exec.Stream(remotecommand.StreamOptions{
+		Stdin:  stdin,
+		Stdout: stdout,
+		Stderr: stderr,
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %s", err, stderr.String())
+	}
+	return nil
+}
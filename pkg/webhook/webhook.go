@@ -0,0 +1,153 @@
+// Copyright (c) 2018-2021 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/conversion"
+
+	"github.com/splunk/splunk-operator/pkg/controller"
+	splcommon "github.com/splunk/splunk-operator/pkg/splunk/common"
+	enterprise "github.com/splunk/splunk-operator/pkg/splunk/enterprise"
+)
+
+// ConversionWebhookPath is the path used to serve the conversion webhook of all the Splunk custom resources
+const ConversionWebhookPath = "/convert"
+
+// AddToManager adds a defaulting and a validating webhook for each of the Splunk custom resources to the Manager,
+// along with a conversion webhook between their API versions
+func AddToManager(mgr manager.Manager) error {
+	server := mgr.GetWebhookServer()
+	server.Register(ConversionWebhookPath, &conversion.Webhook{})
+	for _, ctrl := range controller.SplunkControllersToAdd {
+		server.Register(GetMutatingWebhookPath(ctrl.GetInstance()), &webhook.Admission{
+			Handler: &splunkDefaulter{newInstance: ctrl.GetInstance},
+		})
+		server.Register(GetValidatingWebhookPath(ctrl.GetInstance()), &webhook.Admission{
+			Handler: &splunkValidator{newInstance: ctrl.GetInstance},
+		})
+	}
+	return nil
+}
+
+// GetMutatingWebhookPath returns the path used to serve the defaulting webhook of a custom resource
+func GetMutatingWebhookPath(instance splcommon.MetaObject) string {
+	return getWebhookPath("mutate", instance)
+}
+
+// GetValidatingWebhookPath returns the path used to serve the validating webhook of a custom resource
+func GetValidatingWebhookPath(instance splcommon.MetaObject) string {
+	return getWebhookPath("validate", instance)
+}
+
+// getWebhookPath returns the path used to serve a type of webhook for a custom resource
+func getWebhookPath(webhookType string, instance splcommon.MetaObject) string {
+	gvk := instance.GetObjectKind().GroupVersionKind()
+	return fmt.Sprintf("/%s-%s-%s-%s", webhookType, strings.ReplaceAll(gvk.Group, ".", "-"), gvk.Version, strings.ToLower(gvk.Kind))
+}
+
+// blank assignments to verify that splunkDefaulter implements admission.Handler and admission.DecoderInjector
+var _ admission.Handler = &splunkDefaulter{}
+var _ admission.DecoderInjector = &splunkDefaulter{}
+
+// splunkDefaulter stores the default values of the parameters that are not provided in Splunk custom resources
+type splunkDefaulter struct {
+	decoder     *admission.Decoder
+	newInstance func() splcommon.MetaObject
+}
+
+// InjectDecoder is used by the webhook server to set the decoder of admission requests
+func (d *splunkDefaulter) InjectDecoder(decoder *admission.Decoder) error {
+	d.decoder = decoder
+	return nil
+}
+
+// Handle returns a patch setting the default values of the custom resource of an admission request
+func (d *splunkDefaulter) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Operation != admissionv1beta1.Create && req.Operation != admissionv1beta1.Update {
+		return admission.Allowed("")
+	}
+
+	cr := d.newInstance()
+	if err := d.decoder.Decode(req, cr); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	// custom resources being deleted are left as they are
+	if cr.GetDeletionTimestamp() != nil {
+		return admission.Allowed("")
+	}
+
+	if err := enterprise.SetDefaults(cr); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	defaulted, err := json.Marshal(cr)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	return admission.PatchResponseFromRaw(req.Object.Raw, defaulted)
+}
+
+// blank assignments to verify that splunkValidator implements admission.Handler and admission.DecoderInjector
+var _ admission.Handler = &splunkValidator{}
+var _ admission.DecoderInjector = &splunkValidator{}
+
+// splunkValidator rejects the creation of, or the updates to, Splunk custom resources that the operator can not apply
+type splunkValidator struct {
+	decoder     *admission.Decoder
+	newInstance func() splcommon.MetaObject
+}
+
+// InjectDecoder is used by the webhook server to set the decoder of admission requests
+func (v *splunkValidator) InjectDecoder(decoder *admission.Decoder) error {
+	v.decoder = decoder
+	return nil
+}
+
+// Handle validates the custom resource of an admission request
+func (v *splunkValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Operation != admissionv1beta1.Create && req.Operation != admissionv1beta1.Update {
+		return admission.Allowed("")
+	}
+
+	cr := v.newInstance()
+	if err := v.decoder.Decode(req, cr); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	var err error
+	if req.Operation == admissionv1beta1.Create {
+		err = enterprise.ValidateSpec(cr)
+	} else {
+		old := v.newInstance()
+		if err := v.decoder.DecodeRaw(req.OldObject, old); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		err = enterprise.ValidateSpecUpdate(cr, old)
+	}
+	if err != nil {
+		return admission.Denied(err.Error())
+	}
+	return admission.Allowed("")
+}
@@ -0,0 +1,145 @@
+// Copyright (c) 2018-2021 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/splunk/splunk-operator/pkg/apis"
+	enterpriseApi "github.com/splunk/splunk-operator/pkg/apis/enterprise/v2"
+	"github.com/splunk/splunk-operator/pkg/controller"
+	splcommon "github.com/splunk/splunk-operator/pkg/splunk/common"
+)
+
+func TestGetWebhookPath(t *testing.T) {
+	instance := controller.StandaloneController{}.GetInstance()
+	want := "/validate-enterprise-splunk-com-v2-standalone"
+	if got := GetValidatingWebhookPath(instance); got != want {
+		t.Errorf("GetValidatingWebhookPath() = %s; want %s", got, want)
+	}
+	want = "/mutate-enterprise-splunk-com-v2-standalone"
+	if got := GetMutatingWebhookPath(instance); got != want {
+		t.Errorf("GetMutatingWebhookPath() = %s; want %s", got, want)
+	}
+}
+
+func newTestDecoder(t *testing.T) *admission.Decoder {
+	scheme := runtime.NewScheme()
+	if err := apis.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme() returned %v", err)
+	}
+	decoder, err := admission.NewDecoder(scheme)
+	if err != nil {
+		t.Fatalf("NewDecoder() returned %v", err)
+	}
+	return decoder
+}
+
+func marshalTestObject(t *testing.T, cr splcommon.MetaObject) runtime.RawExtension {
+	raw, err := json.Marshal(cr)
+	if err != nil {
+		t.Fatalf("Marshal() returned %v", err)
+	}
+	return runtime.RawExtension{Raw: raw}
+}
+
+func TestSplunkDefaulter(t *testing.T) {
+	defaulter := &splunkDefaulter{newInstance: controller.SearchHeadClusterController{}.GetInstance}
+	defaulter.InjectDecoder(newTestDecoder(t))
+
+	cr := enterpriseApi.SearchHeadCluster{
+		TypeMeta:   metav1.TypeMeta{APIVersion: enterpriseApi.APIVersion, Kind: "SearchHeadCluster"},
+		ObjectMeta: metav1.ObjectMeta{Name: "stack1", Namespace: "test"},
+	}
+	req := admission.Request{AdmissionRequest: admissionv1beta1.AdmissionRequest{
+		Operation: admissionv1beta1.Create,
+		Object:    marshalTestObject(t, &cr),
+	}}
+	resp := defaulter.Handle(context.TODO(), req)
+	if !resp.Allowed {
+		t.Fatalf("Handle() Allowed = false; want true (%s)", resp.Result.Message)
+	}
+
+	want := map[string]interface{}{
+		"/spec/replicas":        float64(3),
+		"/spec/imagePullPolicy": "IfNotPresent",
+		"/spec/schedulerName":   "default-scheduler",
+	}
+	for _, patch := range resp.Patches {
+		if value, ok := want[patch.Path]; ok {
+			if patch.Value != value {
+				t.Errorf("Handle() patch %s = %v; want %v", patch.Path, patch.Value, value)
+			}
+			delete(want, patch.Path)
+		}
+	}
+	for path := range want {
+		t.Errorf("Handle() returned no patch for %s", path)
+	}
+
+	// custom resources being deleted are not changed
+	now := metav1.Now()
+	cr.ObjectMeta.DeletionTimestamp = &now
+	req.Operation = admissionv1beta1.Update
+	req.Object = marshalTestObject(t, &cr)
+	resp = defaulter.Handle(context.TODO(), req)
+	if !resp.Allowed || len(resp.Patches) != 0 {
+		t.Errorf("Handle() returned %d patches for a custom resource being deleted; want 0", len(resp.Patches))
+	}
+}
+
+func TestSplunkValidator(t *testing.T) {
+	validator := &splunkValidator{newInstance: controller.IndexerClusterController{}.GetInstance}
+	validator.InjectDecoder(newTestDecoder(t))
+
+	test := func(operation admissionv1beta1.Operation, cr, old *enterpriseApi.IndexerCluster, want bool) {
+		req := admission.Request{AdmissionRequest: admissionv1beta1.AdmissionRequest{Operation: operation}}
+		if cr != nil {
+			req.Object = marshalTestObject(t, cr)
+		}
+		if old != nil {
+			req.OldObject = marshalTestObject(t, old)
+		}
+		resp := validator.Handle(context.TODO(), req)
+		if resp.Allowed != want {
+			t.Errorf("Handle(%s) Allowed = %t; want %t (%s)", operation, resp.Allowed, want, resp.Result.Message)
+		}
+	}
+
+	cr := enterpriseApi.IndexerCluster{
+		TypeMeta:   metav1.TypeMeta{APIVersion: enterpriseApi.APIVersion, Kind: "IndexerCluster"},
+		ObjectMeta: metav1.ObjectMeta{Name: "stack1", Namespace: "test"},
+	}
+	test(admissionv1beta1.Create, &cr, nil, false)
+	test(admissionv1beta1.Delete, nil, &cr, true)
+
+	cr.Spec.ClusterMasterRef.Name = "stack1"
+	cr.Spec.VarVolumeStorageConfig.StorageCapacity = "100Gi"
+	test(admissionv1beta1.Create, &cr, nil, true)
+
+	update := cr.DeepCopy()
+	update.Spec.Replicas = 5
+	test(admissionv1beta1.Update, update, &cr, true)
+
+	update.Spec.VarVolumeStorageConfig.StorageCapacity = "50Gi"
+	test(admissionv1beta1.Update, update, &cr, false)
+}